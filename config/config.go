@@ -40,6 +40,15 @@ const (
 // LogLevel allows different types of verbosity in the log file
 type LogLevel string
 
+// List of possible persistence backends
+const (
+	DatabaseBackendMongoDB DatabaseBackend = "mongodb" // MongoDB database (default)
+	DatabaseBackendFile    DatabaseBackend = "file"    // Embedded database stored in a local file
+)
+
+// DatabaseBackend identifies where the objects of the system are persisted
+type DatabaseBackend string
+
 // Config structure describes all the configuration variables used in the Shelter system
 type Config struct {
 	// Base path of the system, all other paths will prepend this path. This is useful to
@@ -58,9 +67,17 @@ type Config struct {
 	// web client languages)
 	Languages []string
 
-	// Stores the database information to start a connection. For now we are using MongoDB
-	// for the persistence layer
+	// Stores the database information to start a connection. MongoDB is the default
+	// persistence layer, but small installations can store the data in a local file
 	Database struct {
+		// Persistence backend of the system, that can be "mongodb" or "file". When empty
+		// MongoDB is used. The file backend stores only domains and scans, so the domain
+		// history and the nameservers summary are not available with it
+		Backend DatabaseBackend
+
+		// Path of the file used by the file backend. It's relative to the base path
+		Path string
+
 		// Name of the database
 		Name string
 
@@ -121,8 +138,17 @@ type Config struct {
 		// After that will consider a timeout problem
 		ConnectionRetries int
 
+		// Flag to enable the EDNS and TCP compliance checks. When enabled, the scan sends
+		// extra queries to each nameserver (without EDNS0, with EDNS0, with the DNSSEC OK
+		// bit, with an unknown EDNS0 option and only over TCP) and stores a compliance
+		// report in the nameserver. Nameservers that fail these tests can answer the basic
+		// DNS queries and still cause resolution problems
+		ComplianceChecks bool
+
 		// Information about the recursive DNS server for specific services of the scan. Like
-		// QueryDomain, that retrieves the nameservers and DS records from a domain name
+		// QueryDomain, that retrieves the nameservers and DS records from a domain name, and
+		// the parent zone checks, that retrieve the authoritative nameservers of the parent
+		// zone to ask for the delegation and the DS records
 		Resolver struct {
 			// IP address from the resolver
 			Address string
@@ -227,6 +253,15 @@ type Config struct {
 
 		// Store the shared secret keys used by the clients to sign the requests
 		Secrets map[string]string
+
+		// Role and domains of the principal that owns each secret id. The role can be
+		// read-only, domain-writer or admin, and the domains are FQDNs or suffixes (like
+		// "com.br") that restrict the domains visible to the principal. A secret id without
+		// principal is an admin of all domains
+		Principals map[string]struct {
+			Role    string
+			Domains []string
+		}
 	}
 
 	// Store all necessary information for the web client
@@ -275,6 +310,12 @@ type Config struct {
 		// Number of hours between each notification
 		IntervalHours int
 
+		// Number of hours that an alert with the same problems will not be sent again to a
+		// domain. When the problems of the domain change a new alert is sent immediately. Zero
+		// sends the alert on every notification. The alerts are only tracked when using
+		// MongoDB
+		RenotifyIntervalHours int
+
 		// How many days we will wait with a DNS misconfigured nameserver until we notify the
 		// domain's owners
 		NameserverErrorAlertDays int
@@ -336,6 +377,24 @@ type Config struct {
 		//       {{else if nsStatusEq $nameserver.LastStatus "NOTSYNCH"}}
 		//         Error description.
 		//
+		//       {{else if nsStatusEq $nameserver.LastStatus "SOAMISMATCH"}}
+		//         Error description.
+		//
+		//       {{else if nsStatusEq $nameserver.LastStatus "NSMISMATCH"}}
+		//         Error description.
+		//
+		//       {{else if nsStatusEq $nameserver.LastStatus "DNSKEYMISMATCH"}}
+		//         Error description.
+		//
+		//       {{else if nsStatusEq $nameserver.LastStatus "NOTINPARENT"}}
+		//         Error description.
+		//
+		//       {{else if nsStatusEq $nameserver.LastStatus "NOGLUE"}}
+		//         Error description.
+		//
+		//       {{else if nsStatusEq $nameserver.LastStatus "GLUEDIFF"}}
+		//         Error description.
+		//
 		//       {{else if nsStatusEq $nameserver.LastStatus "ERROR"}}
 		//         Error description.
 		//
@@ -364,6 +423,21 @@ type Config struct {
 		//       {{else if dsStatusEq $ds.LastStatus "DNSERR"}}
 		//         Error description.
 		//
+		//       {{else if dsStatusEq $ds.LastStatus "DIGESTERR"}}
+		//         Error description.
+		//
+		//       {{else if dsStatusEq $ds.LastStatus "NOTPUBLISHED"}}
+		//         Error description.
+		//
+		//       {{else if dsStatusEq $ds.LastStatus "NODENIAL"}}
+		//         Error description.
+		//
+		//       {{else if dsStatusEq $ds.LastStatus "DENIALNOSIG"}}
+		//         Error description.
+		//
+		//       {{else if dsStatusEq $ds.LastStatus "NSEC3PARAM"}}
+		//         Error description.
+		//
 		//       {{else if isNearExpiration $ds}}
 		//         Error description.
 		//
@@ -373,7 +447,56 @@ type Config struct {
 		//     Goodbye message.
 		//
 		// You can also use other variables in the template file, like {{$nameserver.Host}} or
-		// {{$ds.Keytag}} to create better user messages for the current scenario.
+		// {{$ds.Keytag}} to create better user messages for the current scenario. For signatures
+		// near the expiration date, {{$ds.ExpiresFrom}} informs the RRset (DNSKEY, SOA or NS)
+		// of the signature that expires first. The function nsAddressStatus describes the
+		// result of the checks over each address family of the nameserver (IPv4 and IPv6),
+		// like {{nsAddressStatus $nameserver}}.
+		//
+		// After a scan, the owners are informed about the problems that were solved using the
+		// "recovery" block of the same template file. The nameservers and DS records of the
+		// block have the state before the scan, and the functions nsStatus and dsStatus
+		// convert the previous status into text.
+		//
+		//     {{define "recovery"}}{{$domain := .Domain}}
+		//
+		//     Date: {{.Date}}
+		//     From: {{.From}}
+		//     To: {{.To}}
+		//     Subject: Problems solved on domain {{$domain.FQDN}}
+		//
+		//     {{range $nameserver := .Nameservers}}
+		//       Nameserver {{$nameserver.Host}} was {{nsStatus $nameserver.LastStatus}}.
+		//     {{end}}
+		//
+		//     {{range $ds := .DSSet}}
+		//       DS {{$ds.Keytag}} was {{dsStatus $ds.LastStatus}}.
+		//     {{end}}
+		//
+		//     Goodbye message.
+		//     {{end}}
+		//
+		// Owners that chose a daily or weekly digest receive all their domains with problems
+		// in a single e-mail, built with the "digest" block. Each domain has only the
+		// problems that the owner wants to receive. When the alerts aren't tracked (backends
+		// other than MongoDB) the digest is sent on every notification.
+		//
+		//     {{define "digest"}}
+		//
+		//     Date: {{.Date}}
+		//     From: {{.From}}
+		//     To: {{.To}}
+		//     Subject: Misconfiguration on {{len .Domains}} domain(s)
+		//
+		//     {{range $domain := .Domains}}
+		//       {{$domain.FQDN}}
+		//       {{range $nameserver := $domain.Nameservers}}
+		//         Nameserver {{$nameserver.Host}} is {{nsStatus $nameserver.LastStatus}}.
+		//       {{end}}
+		//     {{end}}
+		//
+		//     Goodbye message.
+		//     {{end}}
 		TemplatesPath string
 
 		// Store all necessary information to send notification e-mails using an SMTP server
@@ -396,6 +519,42 @@ type Config struct {
 				Password string
 			}
 		}
+
+		// Store all necessary information to send the notifications as JSON to HTTP
+		// endpoints (chat bots, ticketing systems, etc). Each request is signed with the
+		// same algorithm used to authenticate the REST server clients, so the receiver can
+		// check the Authorization HTTP header
+		Webhook struct {
+			// Flag to enable or disable the webhook notifications. When disabled the owners'
+			// webhooks are also ignored
+			Enabled bool
+
+			// URL that receives the notifications of all domains. It's optional, because the
+			// owners can have their own webhook
+			URL string
+
+			// Identification of the secret sent in the Authorization HTTP header
+			SecretId string
+
+			// Secret used to sign the requests to the URL, encrypted with the same tool used
+			// for the SMTP password. The owners' webhooks are signed with their own secrets
+			Secret string
+
+			// Hosts that can be reached even when they resolve to loopback, private or
+			// link-local addresses. By default the webhooks can only reach public addresses,
+			// so the owners can't use them to send requests to the internal network
+			AllowedHosts []string
+
+			// Number of new attempts when the delivery fails. We only try again on network
+			// problems or server errors (HTTP 5xx and 429)
+			Retries int
+
+			// Seconds to wait before the first new attempt. The time doubles on each attempt
+			BackoffSeconds int
+
+			// Maximum number of seconds to wait for the endpoint response
+			TimeoutSeconds int
+		}
 	}
 }
 
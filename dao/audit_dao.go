@@ -0,0 +1,299 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"errors"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2/bson"
+	"github.com/rafaeljusto/shelter/database/mongodb"
+	"github.com/rafaeljusto/shelter/model"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// List of possible errors that can occur in this DAO. There can be also other errors from
+// low level drivers.
+var (
+	// Programmer must set the Database attribute from AuditDAO with a valid connection
+	// before using this object
+	ErrAuditDAOUndefinedDatabase = errors.New("No database defined for AuditDAO")
+
+	// Pagination attribute is mandatory, and it's a pointer only to fill some query
+	// informations in it. For the user that wants all records without pagination for a B2B
+	// integration need to pass zero in the page size
+	ErrAuditDAOPaginationUndefined = errors.New("Pagination was not defined")
+
+	// An invalid order by field was given to be converted in one of the known order by
+	// fields of the Audit DAO
+	ErrAuditDAOOrderByFieldUnknown = errors.New("Unknown order by field")
+)
+
+const (
+	auditDAOCollection = "audit" // Collection used to store all audit entries in the MongoDB database
+)
+
+// List of possible fields that can be used to order a result set
+const (
+	AuditDAOOrderByFieldDate      AuditDAOOrderByField = 0 // Order by the date of the change
+	AuditDAOOrderByFieldFQDN      AuditDAOOrderByField = 1 // Order by the changed domain
+	AuditDAOOrderByFieldPrincipal AuditDAOOrderByField = 2 // Order by the secret id that changed the domain
+)
+
+// Enumerate definition for the OrderBy so that we can limit the fields that the user can
+// use in a query
+type AuditDAOOrderByField int
+
+// Convert the AuditDAO order by field from string into enum. If the string is unknown an
+// error will be returned. The string is case insensitive and spaces around it are ignored
+func AuditDAOOrderByFieldFromString(value string) (AuditDAOOrderByField, error) {
+	value = strings.ToLower(value)
+	value = strings.TrimSpace(value)
+
+	switch value {
+	case "date":
+		return AuditDAOOrderByFieldDate, nil
+	case "fqdn":
+		return AuditDAOOrderByFieldFQDN, nil
+	case "principal":
+		return AuditDAOOrderByFieldPrincipal, nil
+	}
+
+	return AuditDAOOrderByFieldDate, ErrAuditDAOOrderByFieldUnknown
+}
+
+// Convert the AuditDAO order by field from enum into string. If the enum is unknown this
+// method will return an empty string
+func AuditDAOOrderByFieldToString(value AuditDAOOrderByField) string {
+	switch value {
+	case AuditDAOOrderByFieldDate:
+		return "date"
+
+	case AuditDAOOrderByFieldFQDN:
+		return "fqdn"
+
+	case AuditDAOOrderByFieldPrincipal:
+		return "principal"
+	}
+
+	return ""
+}
+
+// Default values when the user don't define pagination. The audit log is usually
+// analyzed from the most recent change to the oldest one, so the default ordering is
+// descending
+var (
+	auditDAODefaultPaginationOrderBy = []AuditDAOSort{
+		{
+			Field:     AuditDAOOrderByFieldDate,      // Default ordering is by change date
+			Direction: DAOOrderByDirectionDescending, // Default ordering is descending
+		},
+	}
+)
+
+func init() {
+	// Add indexes on FQDN and principal with the change date, because the audit log is
+	// searched by domain or by principal, and always ordered by time
+	mongodb.RegisterIndexFunction(func(database *mgo.Database) error {
+		index := mgo.Index{
+			Name: "fqdn_date",
+			Key:  []string{"fqdn", "-date"},
+		}
+
+		if err := database.C(auditDAOCollection).EnsureIndex(index); err != nil {
+			return err
+		}
+
+		index = mgo.Index{
+			Name: "principal_date",
+			Key:  []string{"principal", "-date"},
+		}
+
+		return database.C(auditDAOCollection).EnsureIndex(index)
+	})
+}
+
+// AuditDAO is the structure responsable for keeping the database connection to store
+// the changes made in the domains
+type AuditDAO struct {
+	Database *mgo.Database // MongoDB Database
+}
+
+// Save the audit entry in the database. The audit log is append-only, so the entry is
+// always inserted receiving a new id, and there's no method to update or remove a
+// specific entry
+func (dao AuditDAO) Save(entry *model.AuditEntry) error {
+	// Check if the programmer forgot to set the database in AuditDAO object
+	if dao.Database == nil {
+		return ErrAuditDAOUndefinedDatabase
+	}
+
+	entry.Id = bson.NewObjectId()
+	return dao.Database.C(auditDAOCollection).Insert(entry)
+}
+
+// Retrieve the audit entries that match the filter using pagination control. When
+// pagination values are not informed, default values are adopted, returning the most
+// recent changes first
+func (dao AuditDAO) FindAll(filter AuditDAOFilter,
+	pagination *AuditDAOPagination) ([]model.AuditEntry, error) {
+
+	// Check if the programmer forgot to set the database in AuditDAO object
+	if dao.Database == nil {
+		return nil, ErrAuditDAOUndefinedDatabase
+	}
+
+	if pagination == nil {
+		return nil, ErrAuditDAOPaginationUndefined
+	}
+
+	if len(pagination.OrderBy) == 0 {
+		pagination.OrderBy = auditDAODefaultPaginationOrderBy
+	}
+
+	if pagination.PageSize == 0 {
+		pagination.PageSize = defaultPaginationPageSize
+	}
+
+	if pagination.Page == 0 {
+		pagination.Page = defaultPaginationPage
+	}
+
+	var sortList []string
+	for _, sort := range pagination.OrderBy {
+		var sortTmp string
+
+		if sort.Direction == DAOOrderByDirectionDescending {
+			sortTmp = "-"
+		}
+
+		switch sort.Field {
+		case AuditDAOOrderByFieldDate:
+			sortTmp += "date"
+		case AuditDAOOrderByFieldFQDN:
+			sortTmp += "fqdn"
+		case AuditDAOOrderByFieldPrincipal:
+			sortTmp += "principal"
+		}
+
+		sortList = append(sortList, sortTmp)
+	}
+
+	query := dao.Database.C(auditDAOCollection).Find(filter.query())
+
+	// We store the number of items before applying pagination, if we do this after we get
+	// only the number of items of a page size
+	var err error
+	if pagination.NumberOfItems, err = query.Count(); err != nil {
+		return nil, err
+	}
+
+	// Safety check to don't allow to set a page higher than the number of pages
+	maxNumberOfPages := pagination.NumberOfItems / pagination.PageSize
+	if pagination.NumberOfItems%pagination.PageSize > 0 {
+		maxNumberOfPages++
+	}
+
+	if maxNumberOfPages == 0 {
+		// When there's no item, we should stay on the first page (don't skip)
+		pagination.Page = 1
+
+	} else if pagination.Page > maxNumberOfPages {
+		pagination.Page = maxNumberOfPages
+	}
+
+	query.
+		Sort(sortList...).
+		Skip(pagination.PageSize * (pagination.Page - 1)).
+		Limit(pagination.PageSize)
+
+	var entries []model.AuditEntry
+	if err := query.All(&entries); err != nil {
+		return nil, err
+	}
+
+	if pagination.PageSize > 0 {
+		pagination.NumberOfPages = pagination.NumberOfItems / pagination.PageSize
+		if pagination.NumberOfItems%pagination.PageSize > 0 {
+			pagination.NumberOfPages += 1
+		}
+	}
+
+	return entries, nil
+}
+
+// Remove all audit entries from the database. This is a DANGEROUS method, use with
+// caution. For now is used only by the integration test enviroments to clear the
+// database before starting a new test
+func (dao AuditDAO) RemoveAll() error {
+	_, err := dao.Database.C(auditDAOCollection).RemoveAll(bson.M{})
+	return err
+}
+
+// AuditDAOFilter stores the conditions to select the audit entries. Empty fields are
+// ignored
+type AuditDAOFilter struct {
+	FQDN      string    // Changes of this domain
+	Principal string    // Changes made by this secret id
+	Since     time.Time // Changes made at or after this date
+	Until     time.Time // Changes made before this date
+	Suffixes  []string  // Changes of domains equal or under one of these FQDNs
+}
+
+// Build the MongoDB query with the conditions of the filter
+func (f AuditDAOFilter) query() bson.M {
+	query := bson.M{}
+
+	if len(f.FQDN) > 0 {
+		query["fqdn"] = f.FQDN
+	}
+
+	if len(f.Principal) > 0 {
+		query["principal"] = f.Principal
+	}
+
+	if !f.Since.IsZero() || !f.Until.IsZero() {
+		date := bson.M{}
+		if !f.Since.IsZero() {
+			date["$gte"] = f.Since
+		}
+		if !f.Until.IsZero() {
+			date["$lt"] = f.Until
+		}
+		query["date"] = date
+	}
+
+	if len(f.Suffixes) > 0 {
+		var suffixes []bson.M
+		for _, suffix := range f.Suffixes {
+			suffixes = append(suffixes,
+				bson.M{"fqdn": suffix},
+				bson.M{"fqdn": bson.RegEx{Pattern: `\.` + regexp.QuoteMeta(suffix) + "$"}},
+			)
+		}
+		query["$or"] = suffixes
+	}
+
+	return query
+}
+
+// AuditDAOPagination was created as a necessity for big result sets that needs to be
+// sent for an end-user. The audit log grows with every change and is never cleaned
+type AuditDAOPagination struct {
+	OrderBy       []AuditDAOSort // Sort the list before the pagination
+	PageSize      int            // Number of items that are going to be considered in one page
+	Page          int            // Current page that will be returned
+	NumberOfItems int            // Total number of items in the result set
+	NumberOfPages int            // Total number of pages calculated for the current result set
+}
+
+// AuditDAOSort is an object responsable to relate the order by field and direction. Each
+// field used for sort, can be sorted in both directions
+type AuditDAOSort struct {
+	Field     AuditDAOOrderByField // Field to be sorted
+	Direction DAOOrderByDirection  // Direction used in the sort
+}
@@ -0,0 +1,65 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2/bson"
+	"testing"
+	"time"
+)
+
+func TestAuditDAOOrderByFieldFromString(t *testing.T) {
+	if _, err := AuditDAOOrderByFieldFromString("xxx"); err == nil {
+		t.Error("Accepting an invalid order by field")
+	}
+
+	for field := AuditDAOOrderByFieldDate; field <= AuditDAOOrderByFieldPrincipal; field++ {
+		text := "  " + AuditDAOOrderByFieldToString(field) + "  "
+		if converted, err := AuditDAOOrderByFieldFromString(text); err != nil || converted != field {
+			t.Errorf("Not accepting a valid order by field %s", text)
+		}
+	}
+}
+
+func TestAuditDAOOrderByFieldToString(t *testing.T) {
+	if field := AuditDAOOrderByFieldToString(AuditDAOOrderByField(9999)); len(field) > 0 {
+		t.Error("Not returning empty string when is an unknown order by field")
+	}
+
+	if field := AuditDAOOrderByFieldToString(AuditDAOOrderByFieldDate); field != "date" {
+		t.Error("Not returning the correct order by field for Date")
+	}
+}
+
+func TestAuditDAOFilterQuery(t *testing.T) {
+	if query := (AuditDAOFilter{}).query(); len(query) > 0 {
+		t.Error("Adding conditions for an empty filter")
+	}
+
+	since := time.Now().Add(-24 * time.Hour)
+
+	query := AuditDAOFilter{
+		FQDN:      "example.com.br.",
+		Principal: "1",
+		Since:     since,
+		Suffixes:  []string{"com.br."},
+	}.query()
+
+	if query["fqdn"] != "example.com.br." || query["principal"] != "1" {
+		t.Error("Not filtering by domain and principal")
+	}
+
+	if date, ok := query["date"].(bson.M); !ok || date["$gte"] != since || date["$lt"] != nil {
+		t.Error("Not filtering by the date range")
+	}
+
+	suffixes, ok := query["$or"].([]bson.M)
+	if !ok || len(suffixes) != 2 || suffixes[0]["fqdn"] != "com.br." ||
+		suffixes[1]["fqdn"] != (bson.RegEx{Pattern: `\.com\.br\.$`}) {
+
+		t.Error("Not filtering by the domain suffixes")
+	}
+}
@@ -8,12 +8,17 @@ package dao
 import (
 	"errors"
 	"strings"
+	"time"
 )
 
 var (
 	// An invalid order by direction was given to be converted in one of the known order by
 	// fields of the DAO
 	ErrDAOOrderByDirectionUnknown = errors.New("Unknown order by direction")
+
+	// The object was modified by someone else since it was loaded. MongoDB detects this
+	// with a duplicated key error, the other backends must return this error
+	ErrDAORevisionConflict = errors.New("Object was modified by another process")
 )
 
 var (
@@ -60,3 +65,60 @@ func DAOOrderByDirectionToString(value DAOOrderByDirection) string {
 
 	return ""
 }
+
+// Calculate the pagination of a result set that is already in memory. The current page
+// is adjusted to be inside the limits of the result set, and the start and end indexes
+// of the page are returned to slice the result set
+func paginate(numberOfItems, pageSize, page int) (currentPage, numberOfPages, start, end int) {
+	numberOfPages = numberOfItems / pageSize
+	if numberOfItems%pageSize > 0 {
+		numberOfPages++
+	}
+
+	currentPage = page
+	if numberOfPages == 0 {
+		// When there's no item, we should stay on the first page
+		currentPage = 1
+
+	} else if currentPage > numberOfPages {
+		currentPage = numberOfPages
+	}
+
+	start = pageSize * (currentPage - 1)
+	if start > numberOfItems {
+		start = numberOfItems
+	}
+
+	end = start + pageSize
+	if end > numberOfItems {
+		end = numberOfItems
+	}
+
+	return
+}
+
+// Compare two dates returning a negative number when the first is older, zero when they
+// are equal and a positive number when the first is newer
+func compareTimes(a, b time.Time) int {
+	if a.Before(b) {
+		return -1
+
+	} else if a.After(b) {
+		return 1
+	}
+
+	return 0
+}
+
+// Compare two numbers returning a negative number when the first is lower, zero when
+// they are equal and a positive number when the first is higher
+func compareUint64(a, b uint64) int {
+	if a < b {
+		return -1
+
+	} else if a > b {
+		return 1
+	}
+
+	return 0
+}
@@ -36,3 +36,33 @@ func TestDAOOrderByDirectionToString(t *testing.T) {
 		t.Error("Not returning the correct order by direction for DESC")
 	}
 }
+
+func TestPaginate(t *testing.T) {
+	data := []struct {
+		numberOfItems int
+		pageSize      int
+		page          int
+		currentPage   int
+		numberOfPages int
+		start         int
+		end           int
+	}{
+		{numberOfItems: 0, pageSize: 10, page: 1, currentPage: 1, numberOfPages: 0, start: 0, end: 0},
+		{numberOfItems: 5, pageSize: 10, page: 1, currentPage: 1, numberOfPages: 1, start: 0, end: 5},
+		{numberOfItems: 25, pageSize: 10, page: 2, currentPage: 2, numberOfPages: 3, start: 10, end: 20},
+		{numberOfItems: 25, pageSize: 10, page: 3, currentPage: 3, numberOfPages: 3, start: 20, end: 25},
+		{numberOfItems: 25, pageSize: 10, page: 10, currentPage: 3, numberOfPages: 3, start: 20, end: 25},
+	}
+
+	for i, item := range data {
+		currentPage, numberOfPages, start, end := paginate(item.numberOfItems, item.pageSize, item.page)
+
+		if currentPage != item.currentPage || numberOfPages != item.numberOfPages ||
+			start != item.start || end != item.end {
+
+			t.Errorf("Item %d: Wrong pagination. Expected %d/%d [%d:%d] and got %d/%d [%d:%d]",
+				i, item.currentPage, item.numberOfPages, item.start, item.end,
+				currentPage, numberOfPages, start, end)
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2/bson"
 	"github.com/rafaeljusto/shelter/database/mongodb"
 	"github.com/rafaeljusto/shelter/model"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -39,8 +40,14 @@ const (
 
 // List of possible fields that can be used to order a result set
 const (
-	DomainDAOOrderByFieldFQDN           DomainDAOOrderByField = 0 // Order by domain's FQDN
-	DomainDAOOrderByFieldLastModifiedAt DomainDAOOrderByField = 1 // Order by the last modification date of the domain object
+	DomainDAOOrderByFieldFQDN             DomainDAOOrderByField = 0 // Order by domain's FQDN
+	DomainDAOOrderByFieldLastModifiedAt   DomainDAOOrderByField = 1 // Order by the last modification date of the domain object
+	DomainDAOOrderByFieldNameserverStatus DomainDAOOrderByField = 2 // Order by the status of the nameservers
+	DomainDAOOrderByFieldDSStatus         DomainDAOOrderByField = 3 // Order by the status of the DS set
+	DomainDAOOrderByFieldDSExpiresAt      DomainDAOOrderByField = 4 // Order by the expiration date of the DS set signatures
+	DomainDAOOrderByFieldLastCheckAt      DomainDAOOrderByField = 5 // Order by the last check date of the nameservers
+	DomainDAOOrderByFieldOwner            DomainDAOOrderByField = 6 // Order by the owners' e-mails
+	DomainDAOOrderByFieldNameserverHost   DomainDAOOrderByField = 7 // Order by the nameservers' names
 )
 
 // Enumerate definition for the OrderBy so that we can limit the fields that the user can
@@ -58,6 +65,18 @@ func DomainDAOOrderByFieldFromString(value string) (DomainDAOOrderByField, error
 		return DomainDAOOrderByFieldFQDN, nil
 	case "lastmodified":
 		return DomainDAOOrderByFieldLastModifiedAt, nil
+	case "nameserverstatus":
+		return DomainDAOOrderByFieldNameserverStatus, nil
+	case "dsstatus":
+		return DomainDAOOrderByFieldDSStatus, nil
+	case "dsexpiresat":
+		return DomainDAOOrderByFieldDSExpiresAt, nil
+	case "lastcheck":
+		return DomainDAOOrderByFieldLastCheckAt, nil
+	case "owner":
+		return DomainDAOOrderByFieldOwner, nil
+	case "nameserver":
+		return DomainDAOOrderByFieldNameserverHost, nil
 	}
 
 	return DomainDAOOrderByFieldFQDN, ErrDomainDAOOrderByFieldUnknown
@@ -72,6 +91,24 @@ func DomainDAOOrderByFieldToString(value DomainDAOOrderByField) string {
 
 	case DomainDAOOrderByFieldLastModifiedAt:
 		return "lastmodified"
+
+	case DomainDAOOrderByFieldNameserverStatus:
+		return "nameserverstatus"
+
+	case DomainDAOOrderByFieldDSStatus:
+		return "dsstatus"
+
+	case DomainDAOOrderByFieldDSExpiresAt:
+		return "dsexpiresat"
+
+	case DomainDAOOrderByFieldLastCheckAt:
+		return "lastcheck"
+
+	case DomainDAOOrderByFieldOwner:
+		return "owner"
+
+	case DomainDAOOrderByFieldNameserverHost:
+		return "nameserver"
 	}
 
 	return ""
@@ -184,8 +221,9 @@ func (dao DomainDAO) SaveMany(domains []*model.Domain) []DomainResult {
 // pagination to analyze the data in amounts. When pagination values are not informed,
 // default values are adopted. There's also an expand flag that can control if each domain
 // object from the list will have only the FQDN, last modification, nameserver and DS
-// status or the full information
-func (dao DomainDAO) FindAll(pagination *DomainDAOPagination, expand bool, filter string) ([]model.Domain, error) {
+// status or the full information. Only the domains that match all conditions of the
+// filter are returned
+func (dao DomainDAO) FindAll(pagination *DomainDAOPagination, expand bool, filter DomainDAOFilter) ([]model.Domain, error) {
 	// Check if the programmer forgot to set the database in DomainDAO object
 	if dao.Database == nil {
 		return nil, ErrDomainDAOUndefinedDatabase
@@ -217,24 +255,31 @@ func (dao DomainDAO) FindAll(pagination *DomainDAOPagination, expand bool, filte
 			sortTmp = "-"
 		}
 
+		// When sorting by a field inside a list, MongoDB uses the lowest value of the list
+		// in ascending order and the highest value in descending order
 		switch sort.Field {
 		case DomainDAOOrderByFieldFQDN:
 			sortTmp += "fqdn"
 		case DomainDAOOrderByFieldLastModifiedAt:
 			sortTmp += "lastModifiedAt"
+		case DomainDAOOrderByFieldNameserverStatus:
+			sortTmp += "nameservers.laststatus"
+		case DomainDAOOrderByFieldDSStatus:
+			sortTmp += "dsset.laststatus"
+		case DomainDAOOrderByFieldDSExpiresAt:
+			sortTmp += "dsset.expiresat"
+		case DomainDAOOrderByFieldLastCheckAt:
+			sortTmp += "nameservers.lastcheckat"
+		case DomainDAOOrderByFieldOwner:
+			sortTmp += "owners.email.address"
+		case DomainDAOOrderByFieldNameserverHost:
+			sortTmp += "nameservers.host"
 		}
 
 		sortList = append(sortList, sortTmp)
 	}
 
-	if len(filter) == 0 {
-		query = dao.Database.C(domainDAOCollection).Find(bson.M{})
-
-	} else {
-		query = dao.Database.C(domainDAOCollection).Find(bson.M{
-			"fqdn": bson.RegEx{Pattern: filter, Options: "i"},
-		})
-	}
+	query = dao.Database.C(domainDAOCollection).Find(filter.query(time.Now()))
 
 	// We store the number of items before applying pagination, if we do this after we get only the
 	// number of items of a page size
@@ -274,26 +319,8 @@ func (dao DomainDAO) FindAll(pagination *DomainDAOPagination, expand bool, filte
 		}
 	}
 
-	// When the expand flag if not defined, we should compress the domain object so the
-	// network data isn't too big. For now the compressed object will have the FQDN, last
-	// modification and the status of the nameservers and DS set, this is useful to detect
-	// quickly the domains that have some issue
 	if !expand {
-		for i := range domains {
-			for j := range domains[i].Nameservers {
-				domains[i].Nameservers[j] = model.Nameserver{
-					LastStatus: domains[i].Nameservers[j].LastStatus,
-				}
-			}
-
-			for j := range domains[i].DSSet {
-				domains[i].DSSet[j] = model.DS{
-					LastStatus: domains[i].DSSet[j].LastStatus,
-				}
-			}
-
-			domains[i].Owners = []model.Owner{}
-		}
+		compressDomains(domains)
 	}
 
 	return domains, nil
@@ -411,7 +438,10 @@ func (dao DomainDAO) FindAllAsyncToBeNotified(
 					},
 				},
 				{
+					// DS records without a signature expiration date weren't checked
+					// successfully yet, so they can't be near the expiration
 					"dsset": bson.M{"$elemMatch": bson.M{"expiresat": bson.M{
+						"$gt":  time.Time{},
 						"$lte": time.Now().Add(time.Duration(maxExpirationAlertDays*24) * time.Hour),
 					},
 					},
@@ -458,6 +488,22 @@ func (dao DomainDAO) FindByFQDN(fqdn string) (model.Domain, error) {
 	return domain, err
 }
 
+// Retrieve the domains of the given FQDNs with a single query. Domains that aren't in the
+// database are ignored, so the result can be smaller than the list of FQDNs
+func (dao DomainDAO) FindByFQDNs(fqdns []string) ([]model.Domain, error) {
+	// Check if the programmer forgot to set the database in DomainDAO object
+	if dao.Database == nil {
+		return nil, ErrDomainDAOUndefinedDatabase
+	}
+
+	var domains []model.Domain
+	err := dao.Database.C(domainDAOCollection).Find(bson.M{
+		"fqdn": bson.M{"$in": fqdns},
+	}).All(&domains)
+
+	return domains, err
+}
+
 // Remove a database entry based on a given domain id. This method is useful as a
 // RemoveMany auxiliar method, because it's faster that RemoveByFQDN
 func (dao DomainDAO) Remove(domain *model.Domain) error {
@@ -502,6 +548,28 @@ func (dao DomainDAO) RemoveAll() error {
 	return err
 }
 
+// When the expand flag if not defined, we should compress the domain object so the
+// network data isn't too big. For now the compressed object will have the FQDN, last
+// modification and the status of the nameservers and DS set, this is useful to detect
+// quickly the domains that have some issue
+func compressDomains(domains []model.Domain) {
+	for i := range domains {
+		for j := range domains[i].Nameservers {
+			domains[i].Nameservers[j] = model.Nameserver{
+				LastStatus: domains[i].Nameservers[j].LastStatus,
+			}
+		}
+
+		for j := range domains[i].DSSet {
+			domains[i].DSSet[j] = model.DS{
+				LastStatus: domains[i].DSSet[j].LastStatus,
+			}
+		}
+
+		domains[i].Owners = []model.Owner{}
+	}
+}
+
 // Method used to execute an operation over domains concurrently. It was created because
 // the SaveMany and RemoveMany methods were exactly the same, except for the database
 // operation
@@ -584,6 +652,74 @@ type DomainDAOPagination struct {
 	NumberOfPages int             // Total number of pages calculated for the current result set
 }
 
+// DomainDAOFilter stores the conditions that a domain must match to be returned in a
+// list. Empty attributes are ignored, so an empty filter returns all domains
+type DomainDAOFilter struct {
+	FQDN             string                   // Regular expression (case insensitive) applied over the FQDN
+	NameserverStatus []model.NameserverStatus // At least one nameserver with one of these status
+	DSStatus         []model.DSStatus         // At least one DS with one of these status
+	DNSSEC           *bool                    // Domains with (true) or without (false) DS records
+	DSExpiresIn      int                      // Number of days until a DS signature expires (or already expired)
+	NotCheckedSince  time.Time                // No nameserver checked after this date
+	OwnerEmail       string                   // Domains with this owner's e-mail
+	NameserverHost   string                   // Domains with this nameserver's name
+	Suffixes         []string                 // Domains equal or under one of these FQDNs
+}
+
+// Build the MongoDB query with the conditions of the filter. The current time is used to
+// calculate the expiration limit of the DS signatures
+func (f DomainDAOFilter) query(now time.Time) bson.M {
+	query := bson.M{}
+
+	if len(f.FQDN) > 0 {
+		query["fqdn"] = bson.RegEx{Pattern: f.FQDN, Options: "i"}
+	}
+
+	if len(f.NameserverStatus) > 0 {
+		query["nameservers.laststatus"] = bson.M{"$in": f.NameserverStatus}
+	}
+
+	if len(f.DSStatus) > 0 {
+		query["dsset.laststatus"] = bson.M{"$in": f.DSStatus}
+	}
+
+	if f.DNSSEC != nil {
+		query["dsset.0"] = bson.M{"$exists": *f.DNSSEC}
+	}
+
+	if f.DSExpiresIn > 0 {
+		query["dsset"] = bson.M{"$elemMatch": bson.M{"expiresat": bson.M{
+			"$gt":  time.Time{},
+			"$lte": now.Add(time.Duration(f.DSExpiresIn*24) * time.Hour),
+		}}}
+	}
+
+	if !f.NotCheckedSince.IsZero() {
+		query["nameservers.lastcheckat"] = bson.M{"$not": bson.M{"$gte": f.NotCheckedSince}}
+	}
+
+	if len(f.OwnerEmail) > 0 {
+		query["owners.email.address"] = f.OwnerEmail
+	}
+
+	if len(f.NameserverHost) > 0 {
+		query["nameservers.host"] = f.NameserverHost
+	}
+
+	if len(f.Suffixes) > 0 {
+		var suffixes []bson.M
+		for _, suffix := range f.Suffixes {
+			suffixes = append(suffixes,
+				bson.M{"fqdn": suffix},
+				bson.M{"fqdn": bson.RegEx{Pattern: `\.` + regexp.QuoteMeta(suffix) + "$"}},
+			)
+		}
+		query["$or"] = suffixes
+	}
+
+	return query
+}
+
 // DomainDAOSort is an object responsable to relate the order by field and direction. Each
 // field used for sort, can be sorted in both directions
 type DomainDAOSort struct {
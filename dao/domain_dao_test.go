@@ -21,6 +21,20 @@ func TestDomainDAOOrderByFieldFromString(t *testing.T) {
 	if field, err := DomainDAOOrderByFieldFromString("lastModified"); err != nil || field != DomainDAOOrderByFieldLastModifiedAt {
 		t.Error("Not accepting a valid order by field LastModified")
 	}
+
+	for _, field := range []DomainDAOOrderByField{
+		DomainDAOOrderByFieldNameserverStatus,
+		DomainDAOOrderByFieldDSStatus,
+		DomainDAOOrderByFieldDSExpiresAt,
+		DomainDAOOrderByFieldLastCheckAt,
+		DomainDAOOrderByFieldOwner,
+		DomainDAOOrderByFieldNameserverHost,
+	} {
+		converted, err := DomainDAOOrderByFieldFromString(DomainDAOOrderByFieldToString(field))
+		if err != nil || converted != field {
+			t.Errorf("Not accepting a valid order by field %s", DomainDAOOrderByFieldToString(field))
+		}
+	}
 }
 
 func TestDomainDAOOrderByFieldToString(t *testing.T) {
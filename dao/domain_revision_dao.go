@@ -0,0 +1,287 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"errors"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2/bson"
+	"github.com/rafaeljusto/shelter/database/mongodb"
+	"github.com/rafaeljusto/shelter/model"
+	"strings"
+)
+
+// List of possible errors that can occur in this DAO. There can be also other errors from
+// low level drivers.
+var (
+	// Programmer must set the Database attribute from DomainRevisionDAO with a valid
+	// connection before using this object
+	ErrDomainRevisionDAOUndefinedDatabase = errors.New("No database defined for DomainRevisionDAO")
+
+	// Pagination attribute is mandatory, and it's a pointer only to fill some query
+	// informations in it. For the user that wants all records without pagination for a B2B
+	// integration need to pass zero in the page size
+	ErrDomainRevisionDAOPaginationUndefined = errors.New("Pagination was not defined")
+
+	// An invalid order by field was given to be converted in one of the known order by
+	// fields of the DomainRevision DAO
+	ErrDomainRevisionDAOOrderByFieldUnknown = errors.New("Unknown order by field")
+)
+
+const (
+	domainRevisionDAOCollection = "domainrevision" // Collection used to store all domain revisions in the MongoDB database
+)
+
+// List of possible fields that can be used to order a result set
+const (
+	DomainRevisionDAOOrderByFieldRevision DomainRevisionDAOOrderByField = 0 // Order by the revision number
+	DomainRevisionDAOOrderByFieldSavedAt  DomainRevisionDAOOrderByField = 1 // Order by the date that the revision was stored
+)
+
+// Enumerate definition for the OrderBy so that we can limit the fields that the user can
+// use in a query
+type DomainRevisionDAOOrderByField int
+
+// Convert the DomainRevisionDAO order by field from string into enum. If the string is
+// unknown an error will be returned. The string is case insensitive and spaces around it
+// are ignored
+func DomainRevisionDAOOrderByFieldFromString(value string) (DomainRevisionDAOOrderByField, error) {
+	value = strings.ToLower(value)
+	value = strings.TrimSpace(value)
+
+	switch value {
+	case "revision":
+		return DomainRevisionDAOOrderByFieldRevision, nil
+	case "savedat":
+		return DomainRevisionDAOOrderByFieldSavedAt, nil
+	}
+
+	return DomainRevisionDAOOrderByFieldRevision, ErrDomainRevisionDAOOrderByFieldUnknown
+}
+
+// Convert the DomainRevisionDAO order by field from enum into string. If the enum is
+// unknown this method will return an empty string
+func DomainRevisionDAOOrderByFieldToString(value DomainRevisionDAOOrderByField) string {
+	switch value {
+	case DomainRevisionDAOOrderByFieldRevision:
+		return "revision"
+
+	case DomainRevisionDAOOrderByFieldSavedAt:
+		return "savedat"
+	}
+
+	return ""
+}
+
+// Default values when the user don't define pagination. The revisions are usually
+// analyzed from the most recent to the oldest one, so the default ordering is descending
+var (
+	domainRevisionDAODefaultPaginationOrderBy = []DomainRevisionDAOSort{
+		{
+			Field:     DomainRevisionDAOOrderByFieldRevision, // Default ordering is by revision number
+			Direction: DAOOrderByDirectionDescending,         // Default ordering is descending
+		},
+	}
+)
+
+func init() {
+	// Add unique index on FQDN and revision, because a revision is always retrieved by the
+	// domain and its number, and the same revision cannot be stored twice
+	mongodb.RegisterIndexFunction(func(database *mgo.Database) error {
+		index := mgo.Index{
+			Name:   "fqdn_revision",
+			Key:    []string{"fqdn", "-revision"},
+			Unique: true,
+		}
+
+		return database.C(domainRevisionDAOCollection).EnsureIndex(index)
+	})
+}
+
+// DomainRevisionDAO is the structure responsable for keeping the database connection to
+// store the configuration changes of the domains
+type DomainRevisionDAO struct {
+	Database *mgo.Database // MongoDB Database
+}
+
+// Save the domain revision in the database. A revision represents the state of the domain
+// in a moment, so the object is always inserted receiving a new id
+func (dao DomainRevisionDAO) Save(revision *model.DomainRevision) error {
+	// Check if the programmer forgot to set the database in DomainRevisionDAO object
+	if dao.Database == nil {
+		return ErrDomainRevisionDAOUndefinedDatabase
+	}
+
+	revision.Id = bson.NewObjectId()
+	return dao.Database.C(domainRevisionDAOCollection).Insert(revision)
+}
+
+// Retrieve the stored revisions of a domain using pagination control. When pagination
+// values are not informed, default values are adopted, returning the most recent
+// revisions first
+func (dao DomainRevisionDAO) FindByFQDN(fqdn string,
+	pagination *DomainRevisionDAOPagination) ([]model.DomainRevision, error) {
+
+	// Check if the programmer forgot to set the database in DomainRevisionDAO object
+	if dao.Database == nil {
+		return nil, ErrDomainRevisionDAOUndefinedDatabase
+	}
+
+	if pagination == nil {
+		return nil, ErrDomainRevisionDAOPaginationUndefined
+	}
+
+	if len(pagination.OrderBy) == 0 {
+		pagination.OrderBy = domainRevisionDAODefaultPaginationOrderBy
+	}
+
+	if pagination.PageSize == 0 {
+		pagination.PageSize = defaultPaginationPageSize
+	}
+
+	if pagination.Page == 0 {
+		pagination.Page = defaultPaginationPage
+	}
+
+	var sortList []string
+	for _, sort := range pagination.OrderBy {
+		var sortTmp string
+
+		if sort.Direction == DAOOrderByDirectionDescending {
+			sortTmp = "-"
+		}
+
+		switch sort.Field {
+		case DomainRevisionDAOOrderByFieldRevision:
+			sortTmp += "revision"
+		case DomainRevisionDAOOrderByFieldSavedAt:
+			sortTmp += "savedat"
+		}
+
+		sortList = append(sortList, sortTmp)
+	}
+
+	query := dao.Database.C(domainRevisionDAOCollection).Find(bson.M{
+		"fqdn": fqdn,
+	})
+
+	// We store the number of items before applying pagination, if we do this after we get
+	// only the number of items of a page size
+	var err error
+	if pagination.NumberOfItems, err = query.Count(); err != nil {
+		return nil, err
+	}
+
+	// Safety check to don't allow to set a page higher than the number of pages
+	maxNumberOfPages := pagination.NumberOfItems / pagination.PageSize
+	if pagination.NumberOfItems%pagination.PageSize > 0 {
+		maxNumberOfPages++
+	}
+
+	if maxNumberOfPages == 0 {
+		// When there's no item, we should stay on the first page (don't skip)
+		pagination.Page = 1
+
+	} else if pagination.Page > maxNumberOfPages {
+		pagination.Page = maxNumberOfPages
+	}
+
+	query.
+		Sort(sortList...).
+		Skip(pagination.PageSize * (pagination.Page - 1)).
+		Limit(pagination.PageSize)
+
+	var revisions []model.DomainRevision
+	if err := query.All(&revisions); err != nil {
+		return nil, err
+	}
+
+	if pagination.PageSize > 0 {
+		pagination.NumberOfPages = pagination.NumberOfItems / pagination.PageSize
+		if pagination.NumberOfItems%pagination.PageSize > 0 {
+			pagination.NumberOfPages += 1
+		}
+	}
+
+	return revisions, nil
+}
+
+// Retrieve a specific revision of a domain. If the revision wasn't stored mgo.ErrNotFound
+// is returned
+func (dao DomainRevisionDAO) FindByRevision(fqdn string, revision int) (model.DomainRevision, error) {
+	var domainRevision model.DomainRevision
+
+	// Check if the programmer forgot to set the database in DomainRevisionDAO object
+	if dao.Database == nil {
+		return domainRevision, ErrDomainRevisionDAOUndefinedDatabase
+	}
+
+	err := dao.Database.C(domainRevisionDAOCollection).Find(bson.M{
+		"fqdn":     fqdn,
+		"revision": revision,
+	}).One(&domainRevision)
+
+	return domainRevision, err
+}
+
+// Retrieve the most recent revision stored for a domain. The revisions are kept after the
+// domain removal, so this is used to continue the numbering when the domain is created
+// again. If there's no revision mgo.ErrNotFound is returned
+func (dao DomainRevisionDAO) FindLast(fqdn string) (model.DomainRevision, error) {
+	var domainRevision model.DomainRevision
+
+	// Check if the programmer forgot to set the database in DomainRevisionDAO object
+	if dao.Database == nil {
+		return domainRevision, ErrDomainRevisionDAOUndefinedDatabase
+	}
+
+	err := dao.Database.C(domainRevisionDAOCollection).Find(bson.M{
+		"fqdn": fqdn,
+	}).Sort("-revision").One(&domainRevision)
+
+	return domainRevision, err
+}
+
+// Remove all the revisions of a domain. The revisions aren't removed with the domain, so
+// that a domain created again can restore an old configuration
+func (dao DomainRevisionDAO) RemoveByFQDN(fqdn string) error {
+	// Check if the programmer forgot to set the database in DomainRevisionDAO object
+	if dao.Database == nil {
+		return ErrDomainRevisionDAOUndefinedDatabase
+	}
+
+	_, err := dao.Database.C(domainRevisionDAOCollection).RemoveAll(bson.M{
+		"fqdn": fqdn,
+	})
+
+	return err
+}
+
+// Remove all domain revision entries from the database. This is a DANGEROUS method, use
+// with caution. For now is used only by the integration test enviroments to clear the
+// database before starting a new test
+func (dao DomainRevisionDAO) RemoveAll() error {
+	_, err := dao.Database.C(domainRevisionDAOCollection).RemoveAll(bson.M{})
+	return err
+}
+
+// DomainRevisionDAOPagination was created as a necessity for big result sets that needs
+// to be sent for an end-user. Domains that are updated by automated systems can have many
+// revisions
+type DomainRevisionDAOPagination struct {
+	OrderBy       []DomainRevisionDAOSort // Sort the list before the pagination
+	PageSize      int                     // Number of items that are going to be considered in one page
+	Page          int                     // Current page that will be returned
+	NumberOfItems int                     // Total number of items in the result set
+	NumberOfPages int                     // Total number of pages calculated for the current result set
+}
+
+// DomainRevisionDAOSort is an object responsable to relate the order by field and
+// direction. Each field used for sort, can be sorted in both directions
+type DomainRevisionDAOSort struct {
+	Field     DomainRevisionDAOOrderByField // Field to be sorted
+	Direction DAOOrderByDirection           // Direction used in the sort
+}
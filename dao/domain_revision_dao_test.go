@@ -0,0 +1,38 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"testing"
+)
+
+func TestDomainRevisionDAOOrderByFieldFromString(t *testing.T) {
+	if _, err := DomainRevisionDAOOrderByFieldFromString("xxx"); err == nil {
+		t.Error("Accepting an invalid order by field")
+	}
+
+	if field, err := DomainRevisionDAOOrderByFieldFromString("  REVISION  "); err != nil || field != DomainRevisionDAOOrderByFieldRevision {
+		t.Error("Not accepting a valid order by field Revision")
+	}
+
+	if field, err := DomainRevisionDAOOrderByFieldFromString("savedAt"); err != nil || field != DomainRevisionDAOOrderByFieldSavedAt {
+		t.Error("Not accepting a valid order by field SavedAt")
+	}
+}
+
+func TestDomainRevisionDAOOrderByFieldToString(t *testing.T) {
+	if field := DomainRevisionDAOOrderByFieldToString(DomainRevisionDAOOrderByField(9999)); len(field) > 0 {
+		t.Error("Not returning empty string when is an unknown order by field")
+	}
+
+	if field := DomainRevisionDAOOrderByFieldToString(DomainRevisionDAOOrderByFieldRevision); field != "revision" {
+		t.Error("Not returning the correct order by field for Revision")
+	}
+
+	if field := DomainRevisionDAOOrderByFieldToString(DomainRevisionDAOOrderByFieldSavedAt); field != "savedat" {
+		t.Error("Not returning the correct order by field for SavedAt")
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"errors"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2/bson"
+	"github.com/rafaeljusto/shelter/database/mongodb"
+	"github.com/rafaeljusto/shelter/model"
+	"strings"
+)
+
+// List of possible errors that can occur in this DAO. There can be also other errors from
+// low level drivers.
+var (
+	// Programmer must set the Database attribute from DomainSnapshotDAO with a valid
+	// connection before using this object
+	ErrDomainSnapshotDAOUndefinedDatabase = errors.New("No database defined for DomainSnapshotDAO")
+
+	// Pagination attribute is mandatory, and it's a pointer only to fill some query
+	// informations in it. For the user that wants all records without pagination for a B2B
+	// integration need to pass zero in the page size
+	ErrDomainSnapshotDAOPaginationUndefined = errors.New("Pagination was not defined")
+
+	// An invalid order by field was given to be converted in one of the known order by
+	// fields of the DomainSnapshot DAO
+	ErrDomainSnapshotDAOOrderByFieldUnknown = errors.New("Unknown order by field")
+)
+
+const (
+	domainSnapshotDAOCollection = "domainsnapshot" // Collection used to store all domain snapshots in the MongoDB database
+)
+
+// List of possible fields that can be used to order a result set
+const (
+	DomainSnapshotDAOOrderByFieldCheckedAt     DomainSnapshotDAOOrderByField = 0 // Order by the date that the domain was checked
+	DomainSnapshotDAOOrderByFieldScanStartedAt DomainSnapshotDAOOrderByField = 1 // Order by the begin time of the scan
+)
+
+// Enumerate definition for the OrderBy so that we can limit the fields that the user can
+// use in a query
+type DomainSnapshotDAOOrderByField int
+
+// Convert the DomainSnapshotDAO order by field from string into enum. If the string is
+// unknown an error will be returned. The string is case insensitive and spaces around it
+// are ignored
+func DomainSnapshotDAOOrderByFieldFromString(value string) (DomainSnapshotDAOOrderByField, error) {
+	value = strings.ToLower(value)
+	value = strings.TrimSpace(value)
+
+	switch value {
+	case "checkedat":
+		return DomainSnapshotDAOOrderByFieldCheckedAt, nil
+	case "scanstartedat":
+		return DomainSnapshotDAOOrderByFieldScanStartedAt, nil
+	}
+
+	return DomainSnapshotDAOOrderByFieldCheckedAt, ErrDomainSnapshotDAOOrderByFieldUnknown
+}
+
+// Convert the DomainSnapshotDAO order by field from enum into string. If the enum is
+// unknown this method will return an empty string
+func DomainSnapshotDAOOrderByFieldToString(value DomainSnapshotDAOOrderByField) string {
+	switch value {
+	case DomainSnapshotDAOOrderByFieldCheckedAt:
+		return "checkedat"
+
+	case DomainSnapshotDAOOrderByFieldScanStartedAt:
+		return "scanstartedat"
+	}
+
+	return ""
+}
+
+// Default values when the user don't define pagination. The history of a domain is
+// usually analyzed from the most recent check to the oldest one, so the default ordering
+// is descending
+var (
+	domainSnapshotDAODefaultPaginationOrderBy = []DomainSnapshotDAOSort{
+		{
+			Field:     DomainSnapshotDAOOrderByFieldCheckedAt, // Default ordering is by check date
+			Direction: DAOOrderByDirectionDescending,          // Default ordering is descending
+		},
+	}
+)
+
+func init() {
+	// Add index on FQDN and check date to speed up the retrieval of the history of a
+	// domain, that is always ordered by time
+	mongodb.RegisterIndexFunction(func(database *mgo.Database) error {
+		index := mgo.Index{
+			Name: "fqdn_checkedat",
+			Key:  []string{"fqdn", "-checkedat"},
+		}
+
+		return database.C(domainSnapshotDAOCollection).EnsureIndex(index)
+	})
+}
+
+// DomainSnapshotDAO is the structure responsable for keeping the database connection to
+// store the result of each scan for the domains
+type DomainSnapshotDAO struct {
+	Database *mgo.Database // MongoDB Database
+}
+
+// Save the domain snapshot in the database. Snapshots are never updated, they represent
+// the state of the domain in a moment, so the object is always inserted receiving a new id
+func (dao DomainSnapshotDAO) Save(snapshot *model.DomainSnapshot) error {
+	// Check if the programmer forgot to set the database in DomainSnapshotDAO object
+	if dao.Database == nil {
+		return ErrDomainSnapshotDAOUndefinedDatabase
+	}
+
+	snapshot.Id = bson.NewObjectId()
+	return dao.Database.C(domainSnapshotDAOCollection).Insert(snapshot)
+}
+
+// Save many domain snapshots at once. Different from the domains, the snapshots are only
+// inserted, so we can send all of them to the database in a single operation
+func (dao DomainSnapshotDAO) SaveMany(snapshots []*model.DomainSnapshot) error {
+	// Check if the programmer forgot to set the database in DomainSnapshotDAO object
+	if dao.Database == nil {
+		return ErrDomainSnapshotDAOUndefinedDatabase
+	}
+
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		snapshot.Id = bson.NewObjectId()
+		documents = append(documents, snapshot)
+	}
+
+	return dao.Database.C(domainSnapshotDAOCollection).Insert(documents...)
+}
+
+// Retrieve the history of a domain using pagination control. When pagination values are
+// not informed, default values are adopted, returning the most recent snapshots first
+func (dao DomainSnapshotDAO) FindByFQDN(fqdn string,
+	pagination *DomainSnapshotDAOPagination) ([]model.DomainSnapshot, error) {
+
+	// Check if the programmer forgot to set the database in DomainSnapshotDAO object
+	if dao.Database == nil {
+		return nil, ErrDomainSnapshotDAOUndefinedDatabase
+	}
+
+	if pagination == nil {
+		return nil, ErrDomainSnapshotDAOPaginationUndefined
+	}
+
+	if len(pagination.OrderBy) == 0 {
+		pagination.OrderBy = domainSnapshotDAODefaultPaginationOrderBy
+	}
+
+	if pagination.PageSize == 0 {
+		pagination.PageSize = defaultPaginationPageSize
+	}
+
+	if pagination.Page == 0 {
+		pagination.Page = defaultPaginationPage
+	}
+
+	var sortList []string
+	for _, sort := range pagination.OrderBy {
+		var sortTmp string
+
+		if sort.Direction == DAOOrderByDirectionDescending {
+			sortTmp = "-"
+		}
+
+		switch sort.Field {
+		case DomainSnapshotDAOOrderByFieldCheckedAt:
+			sortTmp += "checkedat"
+		case DomainSnapshotDAOOrderByFieldScanStartedAt:
+			sortTmp += "scanstartedat"
+		}
+
+		sortList = append(sortList, sortTmp)
+	}
+
+	query := dao.Database.C(domainSnapshotDAOCollection).Find(bson.M{
+		"fqdn": fqdn,
+	})
+
+	// We store the number of items before applying pagination, if we do this after we get
+	// only the number of items of a page size
+	var err error
+	if pagination.NumberOfItems, err = query.Count(); err != nil {
+		return nil, err
+	}
+
+	// Safety check to don't allow to set a page higher than the number of pages
+	maxNumberOfPages := pagination.NumberOfItems / pagination.PageSize
+	if pagination.NumberOfItems%pagination.PageSize > 0 {
+		maxNumberOfPages++
+	}
+
+	if maxNumberOfPages == 0 {
+		// When there's no item, we should stay on the first page (don't skip)
+		pagination.Page = 1
+
+	} else if pagination.Page > maxNumberOfPages {
+		pagination.Page = maxNumberOfPages
+	}
+
+	query.
+		Sort(sortList...).
+		Skip(pagination.PageSize * (pagination.Page - 1)).
+		Limit(pagination.PageSize)
+
+	var snapshots []model.DomainSnapshot
+	if err := query.All(&snapshots); err != nil {
+		return nil, err
+	}
+
+	if pagination.PageSize > 0 {
+		pagination.NumberOfPages = pagination.NumberOfItems / pagination.PageSize
+		if pagination.NumberOfItems%pagination.PageSize > 0 {
+			pagination.NumberOfPages += 1
+		}
+	}
+
+	return snapshots, nil
+}
+
+// Remove all the history of a domain. Should be called when the domain is removed from
+// the system, because the snapshots are useless without the domain
+func (dao DomainSnapshotDAO) RemoveByFQDN(fqdn string) error {
+	// Check if the programmer forgot to set the database in DomainSnapshotDAO object
+	if dao.Database == nil {
+		return ErrDomainSnapshotDAOUndefinedDatabase
+	}
+
+	_, err := dao.Database.C(domainSnapshotDAOCollection).RemoveAll(bson.M{
+		"fqdn": fqdn,
+	})
+
+	return err
+}
+
+// Remove all domain snapshot entries from the database. This is a DANGEROUS method, use
+// with caution. For now is used only by the integration test enviroments to clear the
+// database before starting a new test
+func (dao DomainSnapshotDAO) RemoveAll() error {
+	_, err := dao.Database.C(domainSnapshotDAOCollection).RemoveAll(bson.M{})
+	return err
+}
+
+// DomainSnapshotDAOPagination was created as a necessity for big result sets that needs
+// to be sent for an end-user. A domain that is scanned every day will have hundreds of
+// snapshots in a year
+type DomainSnapshotDAOPagination struct {
+	OrderBy       []DomainSnapshotDAOSort // Sort the list before the pagination
+	PageSize      int                     // Number of items that are going to be considered in one page
+	Page          int                     // Current page that will be returned
+	NumberOfItems int                     // Total number of items in the result set
+	NumberOfPages int                     // Total number of pages calculated for the current result set
+}
+
+// DomainSnapshotDAOSort is an object responsable to relate the order by field and
+// direction. Each field used for sort, can be sorted in both directions
+type DomainSnapshotDAOSort struct {
+	Field     DomainSnapshotDAOOrderByField // Field to be sorted
+	Direction DAOOrderByDirection           // Direction used in the sort
+}
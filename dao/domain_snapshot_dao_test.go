@@ -0,0 +1,38 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"testing"
+)
+
+func TestDomainSnapshotDAOOrderByFieldFromString(t *testing.T) {
+	if _, err := DomainSnapshotDAOOrderByFieldFromString("xxx"); err == nil {
+		t.Error("Accepting an invalid order by field")
+	}
+
+	if field, err := DomainSnapshotDAOOrderByFieldFromString("  CHECKEDAT  "); err != nil || field != DomainSnapshotDAOOrderByFieldCheckedAt {
+		t.Error("Not accepting a valid order by field CheckedAt")
+	}
+
+	if field, err := DomainSnapshotDAOOrderByFieldFromString("scanStartedAt"); err != nil || field != DomainSnapshotDAOOrderByFieldScanStartedAt {
+		t.Error("Not accepting a valid order by field ScanStartedAt")
+	}
+}
+
+func TestDomainSnapshotDAOOrderByFieldToString(t *testing.T) {
+	if field := DomainSnapshotDAOOrderByFieldToString(DomainSnapshotDAOOrderByField(9999)); len(field) > 0 {
+		t.Error("Not returning empty string when is an unknown order by field")
+	}
+
+	if field := DomainSnapshotDAOOrderByFieldToString(DomainSnapshotDAOOrderByFieldCheckedAt); field != "checkedat" {
+		t.Error("Not returning the correct order by field for CheckedAt")
+	}
+
+	if field := DomainSnapshotDAOOrderByFieldToString(DomainSnapshotDAOOrderByFieldScanStartedAt); field != "scanstartedat" {
+		t.Error("Not returning the correct order by field for ScanStartedAt")
+	}
+}
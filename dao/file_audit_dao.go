@@ -0,0 +1,167 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"encoding/json"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2/bson"
+	"github.com/rafaeljusto/shelter/database/file"
+	"github.com/rafaeljusto/shelter/model"
+	"sort"
+	"strings"
+)
+
+// FileAuditDAO is the structure responsable for keeping the audit log in the embedded
+// file database. The entries are indexed by id, as the log is append-only
+type FileAuditDAO struct {
+	Database *file.Database // Embedded file database
+}
+
+// Save the audit entry in the file database. The entry always receives a new id, to be
+// compatible with the MongoDB backend
+func (dao FileAuditDAO) Save(entry *model.AuditEntry) error {
+	// Check if the programmer forgot to set the database in FileAuditDAO object
+	if dao.Database == nil {
+		return ErrAuditDAOUndefinedDatabase
+	}
+
+	entry.Id = bson.NewObjectId()
+	return dao.Database.Put(auditDAOCollection, entry.Id.Hex(), entry)
+}
+
+// Retrieve the audit entries that match the filter using pagination control. The filter
+// conditions are checked in memory, following the same rules of the MongoDB backend
+func (dao FileAuditDAO) FindAll(filter AuditDAOFilter,
+	pagination *AuditDAOPagination) ([]model.AuditEntry, error) {
+
+	// Check if the programmer forgot to set the database in FileAuditDAO object
+	if dao.Database == nil {
+		return nil, ErrAuditDAOUndefinedDatabase
+	}
+
+	if pagination == nil {
+		return nil, ErrAuditDAOPaginationUndefined
+	}
+
+	if len(pagination.OrderBy) == 0 {
+		pagination.OrderBy = auditDAODefaultPaginationOrderBy
+	}
+
+	if pagination.PageSize == 0 {
+		pagination.PageSize = defaultPaginationPageSize
+	}
+
+	if pagination.Page == 0 {
+		pagination.Page = defaultPaginationPage
+	}
+
+	var entries []model.AuditEntry
+	err := dao.Database.ForEach(auditDAOCollection, func(key string, content []byte) error {
+		var entry model.AuditEntry
+		if err := json.Unmarshal(content, &entry); err != nil {
+			return err
+		}
+
+		if filter.match(entry) {
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Sort(auditEntriesSorter{entries: entries, orderBy: pagination.OrderBy})
+
+	var start, end int
+	pagination.NumberOfItems = len(entries)
+	pagination.Page, pagination.NumberOfPages, start, end =
+		paginate(pagination.NumberOfItems, pagination.PageSize, pagination.Page)
+
+	return entries[start:end], nil
+}
+
+// Remove all audit entries from the file database. This is a DANGEROUS method, use with
+// caution
+func (dao FileAuditDAO) RemoveAll() error {
+	// Check if the programmer forgot to set the database in FileAuditDAO object
+	if dao.Database == nil {
+		return ErrAuditDAOUndefinedDatabase
+	}
+
+	return dao.Database.RemoveAll(auditDAOCollection)
+}
+
+// Check if the audit entry matches all conditions of the filter
+func (f AuditDAOFilter) match(entry model.AuditEntry) bool {
+	if len(f.FQDN) > 0 && entry.FQDN != f.FQDN {
+		return false
+	}
+
+	if len(f.Principal) > 0 && entry.Principal != f.Principal {
+		return false
+	}
+
+	if !f.Since.IsZero() && entry.Date.Before(f.Since) {
+		return false
+	}
+
+	if !f.Until.IsZero() && !entry.Date.Before(f.Until) {
+		return false
+	}
+
+	if len(f.Suffixes) > 0 {
+		found := false
+		for _, suffix := range f.Suffixes {
+			if entry.FQDN == suffix || strings.HasSuffix(entry.FQDN, "."+suffix) {
+				found = true
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// auditEntriesSorter sorts a list of audit entries using the order by fields of the
+// pagination
+type auditEntriesSorter struct {
+	entries []model.AuditEntry
+	orderBy []AuditDAOSort
+}
+
+func (s auditEntriesSorter) Len() int {
+	return len(s.entries)
+}
+
+func (s auditEntriesSorter) Swap(i, j int) {
+	s.entries[i], s.entries[j] = s.entries[j], s.entries[i]
+}
+
+func (s auditEntriesSorter) Less(i, j int) bool {
+	for _, orderBy := range s.orderBy {
+		var comparison int
+
+		switch orderBy.Field {
+		case AuditDAOOrderByFieldDate:
+			comparison = compareTimes(s.entries[i].Date, s.entries[j].Date)
+		case AuditDAOOrderByFieldFQDN:
+			comparison = strings.Compare(s.entries[i].FQDN, s.entries[j].FQDN)
+		case AuditDAOOrderByFieldPrincipal:
+			comparison = strings.Compare(s.entries[i].Principal, s.entries[j].Principal)
+		}
+
+		if comparison != 0 {
+			return comparison*int(orderBy.Direction) < 0
+		}
+	}
+
+	return false
+}
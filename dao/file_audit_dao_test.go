@@ -0,0 +1,91 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"fmt"
+	"github.com/rafaeljusto/shelter/database/file"
+	"github.com/rafaeljusto/shelter/model"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileAuditDAO(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shelter-file-audit-dao")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	database, err := file.Open(filepath.Join(dir, "shelter.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auditDAO := FileAuditDAO{Database: database}
+
+	date := time.Now().Add(-10 * time.Hour)
+	for i := 0; i < 5; i++ {
+		entry := model.AuditEntry{
+			FQDN:      fmt.Sprintf("example%d.com.br.", i%2),
+			Principal: "key01",
+			Date:      date.Add(time.Duration(i) * time.Hour),
+		}
+
+		if err := auditDAO.Save(&entry); err != nil {
+			t.Fatal("Error saving audit entry. Details:", err)
+		}
+
+		if len(entry.Id.Hex()) == 0 {
+			t.Error("Not setting the audit entry id")
+		}
+	}
+
+	var pagination AuditDAOPagination
+	entries, err := auditDAO.FindAll(AuditDAOFilter{}, &pagination)
+	if err != nil {
+		t.Fatal("Error retrieving audit entries. Details:", err)
+	}
+
+	if pagination.NumberOfItems != 5 || len(entries) != 5 {
+		t.Error("Not retrieving all audit entries")
+	}
+
+	if len(entries) > 0 && !entries[0].Date.After(entries[len(entries)-1].Date) {
+		t.Error("Not sorting the most recent audit entries first")
+	}
+
+	pagination = AuditDAOPagination{}
+	entries, err = auditDAO.FindAll(AuditDAOFilter{
+		Suffixes: []string{"com.br."},
+		FQDN:     "example1.com.br.",
+		Since:    date.Add(2 * time.Hour),
+	}, &pagination)
+
+	if err != nil {
+		t.Fatal("Error retrieving audit entries. Details:", err)
+	}
+
+	if len(entries) != 1 || entries[0].FQDN != "example1.com.br." {
+		t.Error("Not filtering the audit entries properly")
+	}
+
+	if _, err := auditDAO.FindAll(AuditDAOFilter{}, nil); err != ErrAuditDAOPaginationUndefined {
+		t.Error("Not detecting undefined pagination")
+	}
+
+	if err := auditDAO.RemoveAll(); err != nil {
+		t.Fatal("Error removing audit entries. Details:", err)
+	}
+
+	pagination = AuditDAOPagination{}
+	if entries, err := auditDAO.FindAll(AuditDAOFilter{}, &pagination); err != nil || len(entries) > 0 {
+		t.Error("Not removing all audit entries")
+	}
+}
@@ -0,0 +1,758 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"encoding/json"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2/bson"
+	"github.com/rafaeljusto/shelter/database/file"
+	"github.com/rafaeljusto/shelter/model"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileDomainDAO is the structure responsable for keeping the domains in the embedded file
+// database. The domains are indexed by FQDN, that is unique in the system
+type FileDomainDAO struct {
+	Database *file.Database // Embedded file database
+}
+
+// Save the domain object in the file database. On creation the domain object is going to
+// receive an id, to be compatible with the MongoDB backend
+func (dao FileDomainDAO) Save(domain *model.Domain) error {
+	// Check if the programmer forgot to set the database in FileDomainDAO object
+	if dao.Database == nil {
+		return ErrDomainDAOUndefinedDatabase
+	}
+
+	prepareFileDomain(domain)
+
+	return dao.Database.Update(domainDAOCollection, domain.FQDN,
+		func(current []byte) (interface{}, error) {
+			return checkFileDomain(domain, current)
+		})
+}
+
+// Save many domains at once. As all domains are stored in the same file, the file is
+// written only once for all domains, and each domain gets its own result
+func (dao FileDomainDAO) SaveMany(domains []*model.Domain) []DomainResult {
+	domainResults := make([]DomainResult, len(domains))
+
+	// Check if the programmer forgot to set the database in FileDomainDAO object
+	if dao.Database == nil {
+		for i, domain := range domains {
+			domainResults[i] = DomainResult{
+				Domain: domain,
+				Error:  ErrDomainDAOUndefinedDatabase,
+			}
+		}
+		return domainResults
+	}
+
+	keys := make([]string, len(domains))
+	for i, domain := range domains {
+		prepareFileDomain(domain)
+		keys[i] = domain.FQDN
+	}
+
+	errs := dao.Database.UpdateMany(domainDAOCollection, keys,
+		func(index int, current []byte) (interface{}, error) {
+			return checkFileDomain(domains[index], current)
+		})
+
+	for i, domain := range domains {
+		domainResults[i] = DomainResult{
+			Domain: domain,
+			Error:  errs[i],
+		}
+	}
+	return domainResults
+}
+
+// Retrieve all domains using pagination control. The filter conditions are checked in
+// memory, following the same rules of the MongoDB backend
+func (dao FileDomainDAO) FindAll(pagination *DomainDAOPagination, expand bool, filter DomainDAOFilter) ([]model.Domain, error) {
+	// Check if the programmer forgot to set the database in FileDomainDAO object
+	if dao.Database == nil {
+		return nil, ErrDomainDAOUndefinedDatabase
+	}
+
+	if pagination == nil {
+		return nil, ErrDomainDAOPaginationUndefined
+	}
+
+	if len(pagination.OrderBy) == 0 {
+		pagination.OrderBy = domainDAODefaultPaginationOrderBy
+	}
+
+	if pagination.PageSize == 0 {
+		pagination.PageSize = defaultPaginationPageSize
+	}
+
+	if pagination.Page == 0 {
+		pagination.Page = defaultPaginationPage
+	}
+
+	var fqdnRegexp *regexp.Regexp
+	if len(filter.FQDN) > 0 {
+		var err error
+		if fqdnRegexp, err = regexp.Compile("(?i)" + filter.FQDN); err != nil {
+			return nil, err
+		}
+	}
+
+	domains, err := dao.findAll()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	var filteredDomains []model.Domain
+	for _, domain := range domains {
+		if filter.match(domain, fqdnRegexp, now) {
+			filteredDomains = append(filteredDomains, domain)
+		}
+	}
+	domains = filteredDomains
+
+	sort.Sort(domainsSorter{domains: domains, orderBy: pagination.OrderBy})
+
+	var start, end int
+	pagination.NumberOfItems = len(domains)
+	pagination.Page, pagination.NumberOfPages, start, end =
+		paginate(pagination.NumberOfItems, pagination.PageSize, pagination.Page)
+
+	domains = domains[start:end]
+
+	if !expand {
+		compressDomains(domains)
+	}
+
+	return domains, nil
+}
+
+// Retrieve all domains for a scan. All domains are loaded from the file database before
+// sending them to the channel, so that the caller can save the domains while reading them
+func (dao FileDomainDAO) FindAllAsync() (chan DomainResult, error) {
+	// Check if the programmer forgot to set the database in FileDomainDAO object
+	if dao.Database == nil {
+		return nil, ErrDomainDAOUndefinedDatabase
+	}
+
+	return dao.findAllAsync(func(domain model.Domain) bool {
+		return true
+	}), nil
+}
+
+// Return all domains that need to be notified due to the error tolerancy policy. It
+// follows the same rules of the MongoDB query, but the conditions are checked in memory
+func (dao FileDomainDAO) FindAllAsyncToBeNotified(
+	nameserverErrorAlertDays,
+	nameserverTimeoutAlertDays,
+	dsErrorAlertDays,
+	dsTimeoutAlertDays,
+	maxExpirationAlertDays int,
+) (chan DomainResult, error) {
+
+	// Check if the programmer forgot to set the database in FileDomainDAO object
+	if dao.Database == nil {
+		return nil, ErrDomainDAOUndefinedDatabase
+	}
+
+	now := time.Now()
+	nameserverErrorLimit := now.Add(time.Duration(-nameserverErrorAlertDays*24) * time.Hour)
+	nameserverTimeoutLimit := now.Add(time.Duration(-nameserverTimeoutAlertDays*24) * time.Hour)
+	dsErrorLimit := now.Add(time.Duration(-dsErrorAlertDays*24) * time.Hour)
+	dsTimeoutLimit := now.Add(time.Duration(-dsTimeoutAlertDays*24) * time.Hour)
+	expirationLimit := now.Add(time.Duration(maxExpirationAlertDays*24) * time.Hour)
+
+	return dao.findAllAsync(func(domain model.Domain) bool {
+		for _, nameserver := range domain.Nameservers {
+			switch nameserver.LastStatus {
+			case model.NameserverStatusNotChecked, model.NameserverStatusOK:
+				// Nothing to notify
+			case model.NameserverStatusTimeout:
+				if !nameserver.LastOKAt.After(nameserverTimeoutLimit) {
+					return true
+				}
+			default:
+				if !nameserver.LastOKAt.After(nameserverErrorLimit) {
+					return true
+				}
+			}
+		}
+
+		for _, ds := range domain.DSSet {
+			switch ds.LastStatus {
+			case model.DSStatusNotChecked, model.DSStatusOK:
+				// Nothing to notify
+			case model.DSStatusTimeout:
+				if !ds.LastOKAt.After(dsTimeoutLimit) {
+					return true
+				}
+			default:
+				if !ds.LastOKAt.After(dsErrorLimit) {
+					return true
+				}
+			}
+
+			if ds.ExpiresUntil(expirationLimit) {
+				return true
+			}
+		}
+
+		return false
+	}), nil
+}
+
+// Try to find the domain using the FQDN attribute. If the domain doesn't exist
+// mgo.ErrNotFound is returned, to be compatible with the MongoDB backend
+func (dao FileDomainDAO) FindByFQDN(fqdn string) (model.Domain, error) {
+	var domain model.Domain
+
+	// Check if the programmer forgot to set the database in FileDomainDAO object
+	if dao.Database == nil {
+		return domain, ErrDomainDAOUndefinedDatabase
+	}
+
+	err := dao.Database.Get(domainDAOCollection, fqdn, &domain)
+
+	if err == file.ErrNotFound {
+		err = mgo.ErrNotFound
+	}
+
+	return domain, err
+}
+
+// Retrieve the domains of the given FQDNs. Domains that aren't in the database are
+// ignored, as it works in the MongoDB backend
+func (dao FileDomainDAO) FindByFQDNs(fqdns []string) ([]model.Domain, error) {
+	// Check if the programmer forgot to set the database in FileDomainDAO object
+	if dao.Database == nil {
+		return nil, ErrDomainDAOUndefinedDatabase
+	}
+
+	var domains []model.Domain
+	for _, fqdn := range fqdns {
+		var domain model.Domain
+		if err := dao.Database.Get(domainDAOCollection, fqdn, &domain); err == file.ErrNotFound {
+			continue
+
+		} else if err != nil {
+			return nil, err
+		}
+
+		domains = append(domains, domain)
+	}
+
+	return domains, nil
+}
+
+// Retrieve all owners of the domains using pagination control. The filter is a regular
+// expression (case insensitive) applied over the e-mail, as it works in the MongoDB
+// backend
+func (dao FileDomainDAO) FindOwners(pagination *OwnerDAOPagination, filter string) ([]model.OwnerSummary, error) {
+	// Check if the programmer forgot to set the database in FileDomainDAO object
+	if dao.Database == nil {
+		return nil, ErrDomainDAOUndefinedDatabase
+	}
+
+	if pagination == nil {
+		return nil, ErrDomainDAOPaginationUndefined
+	}
+
+	pagination.defaults()
+
+	var filterRegexp *regexp.Regexp
+	if len(filter) > 0 {
+		var err error
+		if filterRegexp, err = regexp.Compile("(?i)" + filter); err != nil {
+			return nil, err
+		}
+	}
+
+	domains, err := dao.findAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var owners []model.OwnerSummary
+	ownersIndex := make(map[string]int)
+
+	for _, domain := range domains {
+		for _, owner := range domain.Owners {
+			if owner.Email == nil ||
+				(filterRegexp != nil && !filterRegexp.MatchString(owner.Email.Address)) {
+				continue
+			}
+
+			index, found := ownersIndex[owner.Email.Address]
+			if !found {
+				index = len(owners)
+				ownersIndex[owner.Email.Address] = index
+				owners = append(owners, model.OwnerSummary{Email: owner.Email.Address})
+			}
+
+			owners[index].Domains++
+			if domain.LastModifiedAt.After(owners[index].LastModifiedAt) {
+				owners[index].LastModifiedAt = domain.LastModifiedAt
+			}
+		}
+	}
+
+	sort.Sort(ownersSorter{owners: owners, orderBy: pagination.OrderBy})
+
+	var start, end int
+	pagination.NumberOfItems = len(owners)
+	pagination.Page, pagination.NumberOfPages, start, end =
+		paginate(pagination.NumberOfItems, pagination.PageSize, pagination.Page)
+
+	return owners[start:end], nil
+}
+
+// Retrieve all domains that have the owner identified by the e-mail address, sorted by
+// FQDN
+func (dao FileDomainDAO) FindByOwner(email string) ([]model.Domain, error) {
+	// Check if the programmer forgot to set the database in FileDomainDAO object
+	if dao.Database == nil {
+		return nil, ErrDomainDAOUndefinedDatabase
+	}
+
+	domains, err := dao.findAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var ownerDomains []model.Domain
+	for _, domain := range domains {
+		for _, owner := range domain.Owners {
+			if owner.Email != nil && owner.Email.Address == email {
+				ownerDomains = append(ownerDomains, domain)
+				break
+			}
+		}
+	}
+
+	sort.Sort(domainsSorter{domains: ownerDomains, orderBy: domainDAODefaultPaginationOrderBy})
+	return ownerDomains, nil
+}
+
+// Remove the given domain from the file database
+func (dao FileDomainDAO) Remove(domain *model.Domain) error {
+	return dao.RemoveByFQDN(domain.FQDN)
+}
+
+// Remove the domain that have the given FQDN from the file database
+func (dao FileDomainDAO) RemoveByFQDN(fqdn string) error {
+	// Check if the programmer forgot to set the database in FileDomainDAO object
+	if dao.Database == nil {
+		return ErrDomainDAOUndefinedDatabase
+	}
+
+	return dao.Database.Remove(domainDAOCollection, fqdn)
+}
+
+// Remove many domain objects from the file database, writing the file only once
+func (dao FileDomainDAO) RemoveMany(domains []*model.Domain) []DomainResult {
+	domainResults := make([]DomainResult, len(domains))
+
+	// Check if the programmer forgot to set the database in FileDomainDAO object
+	if dao.Database == nil {
+		for i, domain := range domains {
+			domainResults[i] = DomainResult{
+				Domain: domain,
+				Error:  ErrDomainDAOUndefinedDatabase,
+			}
+		}
+		return domainResults
+	}
+
+	keys := make([]string, len(domains))
+	for i, domain := range domains {
+		keys[i] = domain.FQDN
+	}
+
+	errs := dao.Database.RemoveMany(domainDAOCollection, keys)
+
+	for i, domain := range domains {
+		domainResults[i] = DomainResult{
+			Domain: domain,
+			Error:  errs[i],
+		}
+	}
+	return domainResults
+}
+
+// Remove all domain entries from the file database. This is a DANGEROUS method, use with
+// caution
+func (dao FileDomainDAO) RemoveAll() error {
+	// Check if the programmer forgot to set the database in FileDomainDAO object
+	if dao.Database == nil {
+		return ErrDomainDAOUndefinedDatabase
+	}
+
+	return dao.Database.RemoveAll(domainDAOCollection)
+}
+
+// Set the control fields of the domain (id, revision and modification date) before
+// storing it
+func prepareFileDomain(domain *model.Domain) {
+	if len(domain.Id.Hex()) == 0 {
+		domain.Id = bson.NewObjectId()
+	}
+
+	domain.Revision += 1
+	domain.LastModifiedAt = time.Now().UTC()
+}
+
+// Check the stored version of the domain before replacing it, returning the domain to be
+// stored
+func checkFileDomain(domain *model.Domain, current []byte) (interface{}, error) {
+	if current == nil {
+		return domain, nil
+	}
+
+	var storedDomain model.Domain
+	if err := json.Unmarshal(current, &storedDomain); err != nil {
+		return nil, err
+	}
+
+	// Avoid concurrency problems in the same way that we do with MongoDB, checking if the
+	// object wasn't modified since it was loaded
+	if storedDomain.Id != domain.Id || storedDomain.Revision != domain.Revision-1 {
+		return nil, ErrDAORevisionConflict
+	}
+
+	return domain, nil
+}
+
+// Load all domains from the file database
+func (dao FileDomainDAO) findAll() ([]model.Domain, error) {
+	var domains []model.Domain
+	err := dao.Database.ForEach(domainDAOCollection, func(key string, content []byte) error {
+		var domain model.Domain
+		if err := json.Unmarshal(content, &domain); err != nil {
+			return err
+		}
+
+		domains = append(domains, domain)
+		return nil
+	})
+
+	return domains, err
+}
+
+// Send all domains that match the given condition to a channel. The method ends when it
+// returns a nil domain or an error in the channel result, as it works in the MongoDB
+// backend
+func (dao FileDomainDAO) findAllAsync(match func(domain model.Domain) bool) chan DomainResult {
+	domainChannel := make(chan DomainResult)
+
+	go func() {
+		domains, err := dao.findAll()
+
+		if err == nil {
+			for i := range domains {
+				if match(domains[i]) {
+					domainChannel <- DomainResult{
+						Domain: &domains[i],
+						Error:  nil,
+					}
+				}
+			}
+		}
+
+		domainChannel <- DomainResult{
+			Domain: nil,
+			Error:  err,
+		}
+	}()
+
+	return domainChannel
+}
+
+// domainsSorter sorts a list of domains using the order by fields of the pagination
+type domainsSorter struct {
+	domains []model.Domain
+	orderBy []DomainDAOSort
+}
+
+func (s domainsSorter) Len() int {
+	return len(s.domains)
+}
+
+func (s domainsSorter) Swap(i, j int) {
+	s.domains[i], s.domains[j] = s.domains[j], s.domains[i]
+}
+
+func (s domainsSorter) Less(i, j int) bool {
+	for _, orderBy := range s.orderBy {
+		var comparison int
+
+		switch orderBy.Field {
+		case DomainDAOOrderByFieldFQDN:
+			comparison = strings.Compare(s.domains[i].FQDN, s.domains[j].FQDN)
+		case DomainDAOOrderByFieldLastModifiedAt:
+			comparison = compareTimes(s.domains[i].LastModifiedAt, s.domains[j].LastModifiedAt)
+		default:
+			comparison = compareDomainLists(s.domains[i], s.domains[j], orderBy)
+		}
+
+		if comparison != 0 {
+			return comparison*int(orderBy.Direction) < 0
+		}
+	}
+
+	return false
+}
+
+// Compare two domains using a field inside a list (nameservers, DS set or owners). As
+// MongoDB does, the lowest value of the list is used in ascending order and the highest
+// value in descending order
+func compareDomainLists(a, b model.Domain, orderBy DomainDAOSort) int {
+	switch orderBy.Field {
+	case DomainDAOOrderByFieldNameserverStatus:
+		return compareUint64(nameserverStatusBound(a, orderBy.Direction),
+			nameserverStatusBound(b, orderBy.Direction))
+
+	case DomainDAOOrderByFieldDSStatus:
+		return compareUint64(dsStatusBound(a, orderBy.Direction),
+			dsStatusBound(b, orderBy.Direction))
+
+	case DomainDAOOrderByFieldDSExpiresAt:
+		var aDates, bDates []time.Time
+		for _, ds := range a.DSSet {
+			aDates = append(aDates, ds.ExpiresAt)
+		}
+		for _, ds := range b.DSSet {
+			bDates = append(bDates, ds.ExpiresAt)
+		}
+		return compareTimes(timeBound(aDates, orderBy.Direction), timeBound(bDates, orderBy.Direction))
+
+	case DomainDAOOrderByFieldLastCheckAt:
+		var aDates, bDates []time.Time
+		for _, nameserver := range a.Nameservers {
+			aDates = append(aDates, nameserver.LastCheckAt)
+		}
+		for _, nameserver := range b.Nameservers {
+			bDates = append(bDates, nameserver.LastCheckAt)
+		}
+		return compareTimes(timeBound(aDates, orderBy.Direction), timeBound(bDates, orderBy.Direction))
+
+	case DomainDAOOrderByFieldOwner:
+		var aEmails, bEmails []string
+		for _, owner := range a.Owners {
+			aEmails = append(aEmails, owner.Email.Address)
+		}
+		for _, owner := range b.Owners {
+			bEmails = append(bEmails, owner.Email.Address)
+		}
+		return strings.Compare(stringBound(aEmails, orderBy.Direction),
+			stringBound(bEmails, orderBy.Direction))
+
+	case DomainDAOOrderByFieldNameserverHost:
+		var aHosts, bHosts []string
+		for _, nameserver := range a.Nameservers {
+			aHosts = append(aHosts, nameserver.Host)
+		}
+		for _, nameserver := range b.Nameservers {
+			bHosts = append(bHosts, nameserver.Host)
+		}
+		return strings.Compare(stringBound(aHosts, orderBy.Direction),
+			stringBound(bHosts, orderBy.Direction))
+	}
+
+	return 0
+}
+
+// Return the lowest (ascending) or highest (descending) status of the nameservers
+func nameserverStatusBound(domain model.Domain, direction DAOOrderByDirection) uint64 {
+	var bound uint64
+	for i, nameserver := range domain.Nameservers {
+		status := uint64(nameserver.LastStatus)
+		if i == 0 || compareUint64(status, bound)*int(direction) < 0 {
+			bound = status
+		}
+	}
+	return bound
+}
+
+// Return the lowest (ascending) or highest (descending) status of the DS set
+func dsStatusBound(domain model.Domain, direction DAOOrderByDirection) uint64 {
+	var bound uint64
+	for i, ds := range domain.DSSet {
+		status := uint64(ds.LastStatus)
+		if i == 0 || compareUint64(status, bound)*int(direction) < 0 {
+			bound = status
+		}
+	}
+	return bound
+}
+
+// Return the oldest (ascending) or newest (descending) date of the list
+func timeBound(dates []time.Time, direction DAOOrderByDirection) time.Time {
+	var bound time.Time
+	for i, date := range dates {
+		if i == 0 || compareTimes(date, bound)*int(direction) < 0 {
+			bound = date
+		}
+	}
+	return bound
+}
+
+// Return the lowest (ascending) or highest (descending) text of the list
+func stringBound(values []string, direction DAOOrderByDirection) string {
+	var bound string
+	for i, value := range values {
+		if i == 0 || strings.Compare(value, bound)*int(direction) < 0 {
+			bound = value
+		}
+	}
+	return bound
+}
+
+// Check if the domain matches all conditions of the filter. The regular expression of the
+// FQDN is compiled only once by the caller
+func (f DomainDAOFilter) match(domain model.Domain, fqdnRegexp *regexp.Regexp, now time.Time) bool {
+	if fqdnRegexp != nil && !fqdnRegexp.MatchString(domain.FQDN) {
+		return false
+	}
+
+	if len(f.NameserverStatus) > 0 {
+		found := false
+		for _, nameserver := range domain.Nameservers {
+			for _, status := range f.NameserverStatus {
+				if nameserver.LastStatus == status {
+					found = true
+				}
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.DSStatus) > 0 {
+		found := false
+		for _, ds := range domain.DSSet {
+			for _, status := range f.DSStatus {
+				if ds.LastStatus == status {
+					found = true
+				}
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if f.DNSSEC != nil && *f.DNSSEC != (len(domain.DSSet) > 0) {
+		return false
+	}
+
+	if f.DSExpiresIn > 0 {
+		expirationLimit := now.Add(time.Duration(f.DSExpiresIn*24) * time.Hour)
+
+		found := false
+		for _, ds := range domain.DSSet {
+			if !ds.ExpiresAt.IsZero() && !ds.ExpiresAt.After(expirationLimit) {
+				found = true
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if !f.NotCheckedSince.IsZero() {
+		for _, nameserver := range domain.Nameservers {
+			if !nameserver.LastCheckAt.Before(f.NotCheckedSince) {
+				return false
+			}
+		}
+	}
+
+	if len(f.OwnerEmail) > 0 {
+		found := false
+		for _, owner := range domain.Owners {
+			if owner.Email.Address == f.OwnerEmail {
+				found = true
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.NameserverHost) > 0 {
+		found := false
+		for _, nameserver := range domain.Nameservers {
+			if nameserver.Host == f.NameserverHost {
+				found = true
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.Suffixes) > 0 {
+		found := false
+		for _, suffix := range f.Suffixes {
+			if domain.FQDN == suffix || strings.HasSuffix(domain.FQDN, "."+suffix) {
+				found = true
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ownersSorter sorts a list of owners using the order by fields of the pagination
+type ownersSorter struct {
+	owners  []model.OwnerSummary
+	orderBy []OwnerDAOSort
+}
+
+func (s ownersSorter) Len() int {
+	return len(s.owners)
+}
+
+func (s ownersSorter) Swap(i, j int) {
+	s.owners[i], s.owners[j] = s.owners[j], s.owners[i]
+}
+
+func (s ownersSorter) Less(i, j int) bool {
+	for _, orderBy := range s.orderBy {
+		var comparison int
+
+		switch orderBy.Field {
+		case OwnerDAOOrderByFieldEmail:
+			comparison = strings.Compare(s.owners[i].Email, s.owners[j].Email)
+		case OwnerDAOOrderByFieldDomains:
+			comparison = compareUint64(uint64(s.owners[i].Domains), uint64(s.owners[j].Domains))
+		}
+
+		if comparison != 0 {
+			return comparison*int(orderBy.Direction) < 0
+		}
+	}
+
+	return false
+}
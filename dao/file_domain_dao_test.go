@@ -0,0 +1,480 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"fmt"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/database/file"
+	"github.com/rafaeljusto/shelter/model"
+	"io/ioutil"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileDomainDAOLifeCycle(t *testing.T) {
+	domainDAO, dir := newFileDomainDAO(t)
+	defer os.RemoveAll(dir)
+
+	domain := model.Domain{
+		FQDN: "example.com.br.",
+		Nameservers: []model.Nameserver{
+			{Host: "ns1.example.com.br."},
+		},
+	}
+
+	if err := domainDAO.Save(&domain); err != nil {
+		t.Fatal("Error saving domain. Details:", err)
+	}
+
+	if len(domain.Id.Hex()) == 0 || domain.Revision != 1 {
+		t.Error("Not initializing the domain identification and revision")
+	}
+
+	domainRetrieved, err := domainDAO.FindByFQDN(domain.FQDN)
+	if err != nil {
+		t.Fatal("Error retrieving domain. Details:", err)
+	}
+
+	if domainRetrieved.Id != domain.Id || domainRetrieved.Revision != domain.Revision ||
+		len(domainRetrieved.Nameservers) != 1 ||
+		domainRetrieved.Nameservers[0].Host != "ns1.example.com.br." {
+
+		t.Error("Domain is being persisted wrongly")
+	}
+
+	// Simulate another process that loaded the domain before the last change
+	oldDomain := domainRetrieved
+
+	if err := domainDAO.Save(&domainRetrieved); err != nil {
+		t.Fatal("Error updating domain. Details:", err)
+	}
+
+	if err := domainDAO.Save(&oldDomain); err != ErrDAORevisionConflict {
+		t.Error("Not detecting concurrent changes in the domain")
+	}
+
+	if err := domainDAO.RemoveByFQDN(domain.FQDN); err != nil {
+		t.Error("Error removing domain. Details:", err)
+	}
+
+	if _, err := domainDAO.FindByFQDN(domain.FQDN); err != mgo.ErrNotFound {
+		t.Error("Domain was not removed")
+	}
+}
+
+func TestFileDomainDAOMany(t *testing.T) {
+	domainDAO, dir := newFileDomainDAO(t)
+	defer os.RemoveAll(dir)
+
+	domains := []*model.Domain{
+		{FQDN: "example1.com.br."},
+		{FQDN: "example2.com.br."},
+	}
+
+	for _, result := range domainDAO.SaveMany(domains) {
+		if result.Error != nil {
+			t.Fatal("Error saving domains. Details:", result.Error)
+		}
+	}
+
+	// Simulate another process that loaded the first domain before the last change
+	oldDomain := *domains[0]
+
+	results := domainDAO.SaveMany([]*model.Domain{domains[0], &oldDomain, domains[1]})
+	if len(results) != 3 || results[0].Error != nil || results[1].Error != ErrDAORevisionConflict ||
+		results[2].Error != nil {
+
+		t.Error("Not returning the result of each domain in the batch")
+	}
+
+	if domain, err := domainDAO.FindByFQDN("example2.com.br."); err != nil || domain.Revision != 2 {
+		t.Error("Not storing all domains of the batch")
+	}
+
+	found, err := domainDAO.FindByFQDNs([]string{"example1.com.br.", "example2.com.br.", "example3.com.br."})
+	if err != nil {
+		t.Fatal("Error retrieving domains. Details:", err)
+	}
+
+	if len(found) != 2 || found[0].FQDN != "example1.com.br." || found[1].FQDN != "example2.com.br." {
+		t.Errorf("Not retrieving the stored domains of the list: %#v", found)
+	}
+
+	results = domainDAO.RemoveMany([]*model.Domain{domains[0], {FQDN: "example3.com.br."}})
+	if len(results) != 2 || results[0].Error != nil || results[1].Error != file.ErrNotFound {
+		t.Error("Not returning the result of each removed domain in the batch")
+	}
+
+	if _, err := domainDAO.FindByFQDN("example1.com.br."); err == nil {
+		t.Error("Domain was not removed")
+	}
+}
+
+func TestFileDomainDAOFindAll(t *testing.T) {
+	domainDAO, dir := newFileDomainDAO(t)
+	defer os.RemoveAll(dir)
+
+	var domains []*model.Domain
+	for i := 0; i < 25; i++ {
+		domains = append(domains, &model.Domain{
+			FQDN: fmt.Sprintf("example%02d.com.br.", i),
+			Nameservers: []model.Nameserver{
+				{Host: "ns1.example.com.br.", LastStatus: model.NameserverStatusTimeout},
+			},
+		})
+	}
+
+	for _, result := range domainDAO.SaveMany(domains) {
+		if result.Error != nil {
+			t.Fatal("Error saving domains. Details:", result.Error)
+		}
+	}
+
+	pagination := DomainDAOPagination{
+		PageSize: 10,
+		Page:     3,
+		OrderBy: []DomainDAOSort{
+			{Field: DomainDAOOrderByFieldFQDN, Direction: DAOOrderByDirectionDescending},
+		},
+	}
+
+	domainsRetrieved, err := domainDAO.FindAll(&pagination, false, DomainDAOFilter{})
+	if err != nil {
+		t.Fatal("Error retrieving domains. Details:", err)
+	}
+
+	if pagination.NumberOfItems != 25 || pagination.NumberOfPages != 3 || len(domainsRetrieved) != 5 {
+		t.Error("Not paginating the domains properly")
+	}
+
+	if len(domainsRetrieved) > 0 && domainsRetrieved[0].FQDN != "example04.com.br." {
+		t.Error("Not sorting the domains properly")
+	}
+
+	if len(domainsRetrieved) > 0 && (len(domainsRetrieved[0].Nameservers) != 1 ||
+		len(domainsRetrieved[0].Nameservers[0].Host) > 0 ||
+		domainsRetrieved[0].Nameservers[0].LastStatus != model.NameserverStatusTimeout) {
+
+		t.Error("Not compressing the domains when the expand flag is disabled")
+	}
+
+	pagination = DomainDAOPagination{}
+	domainsRetrieved, err = domainDAO.FindAll(&pagination, true, DomainDAOFilter{FQDN: "EXAMPLE1"})
+	if err != nil {
+		t.Fatal("Error retrieving domains. Details:", err)
+	}
+
+	if pagination.NumberOfItems != 10 || len(domainsRetrieved) != 10 {
+		t.Error("Not filtering the domains properly")
+	}
+
+	if _, err := domainDAO.FindAll(nil, true, DomainDAOFilter{}); err != ErrDomainDAOPaginationUndefined {
+		t.Error("Not detecting undefined pagination")
+	}
+}
+
+func TestFileDomainDAOFindAllFilters(t *testing.T) {
+	domainDAO, dir := newFileDomainDAO(t)
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+
+	domains := []*model.Domain{
+		{
+			FQDN: "example1.com.br.",
+			Nameservers: []model.Nameserver{
+				{Host: "ns1.example.com.br.", LastStatus: model.NameserverStatusOK, LastCheckAt: now},
+			},
+			DSSet: []model.DS{
+				{
+					Keytag:     1234,
+					LastStatus: model.DSStatusExpiredSignature,
+					ExpiresAt:  now.Add(-24 * time.Hour),
+				},
+			},
+			Owners: []model.Owner{
+				{Email: &mail.Address{Address: "alice@example.com.br"}},
+			},
+		},
+		{
+			FQDN: "example2.com.br.",
+			Nameservers: []model.Nameserver{
+				{Host: "ns2.example.com.br.", LastStatus: model.NameserverStatusTimeout, LastCheckAt: now.Add(-10 * 24 * time.Hour)},
+			},
+			DSSet: []model.DS{
+				{
+					Keytag:     4321,
+					LastStatus: model.DSStatusOK,
+					ExpiresAt:  now.Add(30 * 24 * time.Hour),
+				},
+			},
+		},
+		{
+			FQDN: "example3.com.br.",
+			Nameservers: []model.Nameserver{
+				{Host: "ns1.example.com.br.", LastStatus: model.NameserverStatusUnknownHost, LastCheckAt: now},
+			},
+		},
+	}
+
+	for _, result := range domainDAO.SaveMany(domains) {
+		if result.Error != nil {
+			t.Fatal("Error saving domains. Details:", result.Error)
+		}
+	}
+
+	withDNSSEC, withoutDNSSEC := true, false
+
+	data := []struct {
+		filter   DomainDAOFilter
+		expected []string
+	}{
+		{
+			filter:   DomainDAOFilter{},
+			expected: []string{"example1.com.br.", "example2.com.br.", "example3.com.br."},
+		},
+		{
+			filter: DomainDAOFilter{NameserverStatus: []model.NameserverStatus{
+				model.NameserverStatusTimeout, model.NameserverStatusUnknownHost,
+			}},
+			expected: []string{"example2.com.br.", "example3.com.br."},
+		},
+		{
+			filter:   DomainDAOFilter{DSStatus: []model.DSStatus{model.DSStatusExpiredSignature}},
+			expected: []string{"example1.com.br."},
+		},
+		{
+			filter:   DomainDAOFilter{DNSSEC: &withDNSSEC},
+			expected: []string{"example1.com.br.", "example2.com.br."},
+		},
+		{
+			filter:   DomainDAOFilter{DNSSEC: &withoutDNSSEC},
+			expected: []string{"example3.com.br."},
+		},
+		{
+			filter:   DomainDAOFilter{DSExpiresIn: 7},
+			expected: []string{"example1.com.br."},
+		},
+		{
+			filter:   DomainDAOFilter{NotCheckedSince: now.Add(-24 * time.Hour)},
+			expected: []string{"example2.com.br."},
+		},
+		{
+			filter:   DomainDAOFilter{OwnerEmail: "alice@example.com.br"},
+			expected: []string{"example1.com.br."},
+		},
+		{
+			filter:   DomainDAOFilter{NameserverHost: "ns1.example.com.br.", FQDN: "example3"},
+			expected: []string{"example3.com.br."},
+		},
+		{
+			filter:   DomainDAOFilter{Suffixes: []string{"example2.com.br.", "example1.com.br."}},
+			expected: []string{"example1.com.br.", "example2.com.br."},
+		},
+		{
+			filter:   DomainDAOFilter{Suffixes: []string{"com.br."}, FQDN: "example3"},
+			expected: []string{"example3.com.br."},
+		},
+		{
+			filter:   DomainDAOFilter{Suffixes: []string{"ample1.com.br."}},
+			expected: nil,
+		},
+	}
+
+	for i, item := range data {
+		var pagination DomainDAOPagination
+		domainsRetrieved, err := domainDAO.FindAll(&pagination, false, item.filter)
+		if err != nil {
+			t.Fatal("Error retrieving domains. Details:", err)
+		}
+
+		var fqdns []string
+		for _, domain := range domainsRetrieved {
+			fqdns = append(fqdns, domain.FQDN)
+		}
+
+		if fmt.Sprint(fqdns) != fmt.Sprint(item.expected) {
+			t.Errorf("Item %d: Not filtering the domains properly. Expected %v and got %v",
+				i, item.expected, fqdns)
+		}
+	}
+
+	pagination := DomainDAOPagination{
+		OrderBy: []DomainDAOSort{
+			{Field: DomainDAOOrderByFieldNameserverStatus, Direction: DAOOrderByDirectionDescending},
+		},
+	}
+
+	domainsRetrieved, err := domainDAO.FindAll(&pagination, false, DomainDAOFilter{})
+	if err != nil {
+		t.Fatal("Error retrieving domains. Details:", err)
+	}
+
+	if len(domainsRetrieved) != 3 || domainsRetrieved[0].FQDN != "example3.com.br." ||
+		domainsRetrieved[1].FQDN != "example2.com.br." ||
+		domainsRetrieved[2].FQDN != "example1.com.br." {
+
+		t.Error("Not sorting the domains by nameserver status properly")
+	}
+}
+
+func TestFileDomainDAOOwners(t *testing.T) {
+	domainDAO, dir := newFileDomainDAO(t)
+	defer os.RemoveAll(dir)
+
+	alice := model.Owner{Email: &mail.Address{Address: "alice@example.com.br"}}
+	bob := model.Owner{Email: &mail.Address{Address: "bob@example.com.br"}}
+
+	domains := []*model.Domain{
+		{FQDN: "example2.com.br.", Owners: []model.Owner{alice, bob}},
+		{FQDN: "example1.com.br.", Owners: []model.Owner{alice}},
+		{FQDN: "example3.com.br."},
+	}
+
+	for _, result := range domainDAO.SaveMany(domains) {
+		if result.Error != nil {
+			t.Fatal("Error saving domains. Details:", result.Error)
+		}
+	}
+
+	pagination := OwnerDAOPagination{
+		OrderBy: []OwnerDAOSort{
+			{Field: OwnerDAOOrderByFieldDomains, Direction: DAOOrderByDirectionDescending},
+		},
+	}
+
+	owners, err := domainDAO.FindOwners(&pagination, "")
+	if err != nil {
+		t.Fatal("Error retrieving owners. Details:", err)
+	}
+
+	if pagination.NumberOfItems != 2 || len(owners) != 2 ||
+		owners[0].Email != "alice@example.com.br" || owners[0].Domains != 2 ||
+		owners[1].Email != "bob@example.com.br" || owners[1].Domains != 1 {
+
+		t.Errorf("Not aggregating the owners properly: %#v", owners)
+	}
+
+	pagination = OwnerDAOPagination{}
+	if owners, err := domainDAO.FindOwners(&pagination, "^BOB"); err != nil ||
+		len(owners) != 1 || owners[0].Email != "bob@example.com.br" {
+
+		t.Error("Not filtering the owners properly")
+	}
+
+	ownerDomains, err := domainDAO.FindByOwner("alice@example.com.br")
+	if err != nil {
+		t.Fatal("Error retrieving domains of the owner. Details:", err)
+	}
+
+	if len(ownerDomains) != 2 || ownerDomains[0].FQDN != "example1.com.br." ||
+		ownerDomains[1].FQDN != "example2.com.br." {
+
+		t.Error("Not retrieving the domains of the owner properly")
+	}
+
+	if ownerDomains, err := domainDAO.FindByOwner("carol@example.com.br"); err != nil ||
+		len(ownerDomains) > 0 {
+
+		t.Error("Retrieving domains of an unknown owner")
+	}
+}
+
+func TestFileDomainDAOFindAllAsyncToBeNotified(t *testing.T) {
+	domainDAO, dir := newFileDomainDAO(t)
+	defer os.RemoveAll(dir)
+
+	domains := []*model.Domain{
+		{
+			FQDN: "ok.com.br.",
+			Nameservers: []model.Nameserver{
+				{Host: "ns1.ok.com.br.", LastStatus: model.NameserverStatusOK, LastOKAt: time.Now()},
+			},
+		},
+		{
+			FQDN: "timeout.com.br.",
+			Nameservers: []model.Nameserver{
+				{
+					Host:       "ns1.timeout.com.br.",
+					LastStatus: model.NameserverStatusTimeout,
+					LastOKAt:   time.Now().Add(-10 * 24 * time.Hour),
+				},
+			},
+		},
+		{
+			FQDN: "recent-error.com.br.",
+			Nameservers: []model.Nameserver{
+				{
+					Host:       "ns1.recent-error.com.br.",
+					LastStatus: model.NameserverStatusServerFailure,
+					LastOKAt:   time.Now().Add(-1 * time.Hour),
+				},
+			},
+		},
+		{
+			// DNSSEC timeout in the first scan, without a signature expiration date
+			FQDN: "recent-ds-timeout.com.br.",
+			Nameservers: []model.Nameserver{
+				{Host: "ns1.recent-ds-timeout.com.br.", LastStatus: model.NameserverStatusOK, LastOKAt: time.Now()},
+			},
+			DSSet: []model.DS{
+				{
+					Keytag:     1234,
+					LastStatus: model.DSStatusTimeout,
+					LastOKAt:   time.Now().Add(-1 * time.Hour),
+				},
+			},
+		},
+	}
+
+	for _, result := range domainDAO.SaveMany(domains) {
+		if result.Error != nil {
+			t.Fatal("Error saving domains. Details:", result.Error)
+		}
+	}
+
+	domainChannel, err := domainDAO.FindAllAsyncToBeNotified(3, 7, 1, 7, 5)
+	if err != nil {
+		t.Fatal("Error retrieving domains to be notified. Details:", err)
+	}
+
+	var fqdns []string
+	for {
+		domainResult := <-domainChannel
+
+		if domainResult.Error != nil {
+			t.Fatal("Error retrieving domains to be notified. Details:", domainResult.Error)
+		}
+
+		if domainResult.Domain == nil {
+			break
+		}
+
+		fqdns = append(fqdns, domainResult.Domain.FQDN)
+	}
+
+	if len(fqdns) != 1 || fqdns[0] != "timeout.com.br." {
+		t.Errorf("Not selecting the domains to be notified properly: %v", fqdns)
+	}
+}
+
+func newFileDomainDAO(t *testing.T) (FileDomainDAO, string) {
+	dir, err := ioutil.TempDir("", "shelter-file-domain-dao")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	database, err := file.Open(filepath.Join(dir, "shelter.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return FileDomainDAO{Database: database}, dir
+}
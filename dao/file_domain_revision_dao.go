@@ -0,0 +1,228 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2/bson"
+	"github.com/rafaeljusto/shelter/database/file"
+	"github.com/rafaeljusto/shelter/model"
+	"sort"
+)
+
+// FileDomainRevisionDAO is the structure responsable for keeping the configuration
+// changes of the domains in the embedded file database. The revisions are indexed by
+// FQDN and revision number, that are unique as in the MongoDB backend
+type FileDomainRevisionDAO struct {
+	Database *file.Database // Embedded file database
+}
+
+// Save the domain revision in the file database. The revision always receives a new id,
+// to be compatible with the MongoDB backend, and the same revision cannot be stored twice
+func (dao FileDomainRevisionDAO) Save(revision *model.DomainRevision) error {
+	// Check if the programmer forgot to set the database in FileDomainRevisionDAO object
+	if dao.Database == nil {
+		return ErrDomainRevisionDAOUndefinedDatabase
+	}
+
+	revision.Id = bson.NewObjectId()
+
+	return dao.Database.Update(domainRevisionDAOCollection,
+		fileDomainRevisionKey(revision.FQDN, revision.Revision),
+		func(current []byte) (interface{}, error) {
+			if current != nil {
+				return nil, ErrDAORevisionConflict
+			}
+
+			return revision, nil
+		})
+}
+
+// Retrieve the stored revisions of a domain using pagination control. When pagination
+// values are not informed, default values are adopted, returning the most recent
+// revisions first
+func (dao FileDomainRevisionDAO) FindByFQDN(fqdn string,
+	pagination *DomainRevisionDAOPagination) ([]model.DomainRevision, error) {
+
+	// Check if the programmer forgot to set the database in FileDomainRevisionDAO object
+	if dao.Database == nil {
+		return nil, ErrDomainRevisionDAOUndefinedDatabase
+	}
+
+	if pagination == nil {
+		return nil, ErrDomainRevisionDAOPaginationUndefined
+	}
+
+	if len(pagination.OrderBy) == 0 {
+		pagination.OrderBy = domainRevisionDAODefaultPaginationOrderBy
+	}
+
+	if pagination.PageSize == 0 {
+		pagination.PageSize = defaultPaginationPageSize
+	}
+
+	if pagination.Page == 0 {
+		pagination.Page = defaultPaginationPage
+	}
+
+	revisions, err := dao.findByFQDN(fqdn)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Sort(domainRevisionsSorter{revisions: revisions, orderBy: pagination.OrderBy})
+
+	var start, end int
+	pagination.NumberOfItems = len(revisions)
+	pagination.Page, pagination.NumberOfPages, start, end =
+		paginate(pagination.NumberOfItems, pagination.PageSize, pagination.Page)
+
+	return revisions[start:end], nil
+}
+
+// Retrieve a specific revision of a domain. If the revision wasn't stored mgo.ErrNotFound
+// is returned, to be compatible with the MongoDB backend
+func (dao FileDomainRevisionDAO) FindByRevision(fqdn string, revision int) (model.DomainRevision, error) {
+	var domainRevision model.DomainRevision
+
+	// Check if the programmer forgot to set the database in FileDomainRevisionDAO object
+	if dao.Database == nil {
+		return domainRevision, ErrDomainRevisionDAOUndefinedDatabase
+	}
+
+	err := dao.Database.Get(domainRevisionDAOCollection,
+		fileDomainRevisionKey(fqdn, revision), &domainRevision)
+
+	if err == file.ErrNotFound {
+		err = mgo.ErrNotFound
+	}
+
+	return domainRevision, err
+}
+
+// Retrieve the most recent revision stored for a domain. If there's no revision
+// mgo.ErrNotFound is returned, to be compatible with the MongoDB backend
+func (dao FileDomainRevisionDAO) FindLast(fqdn string) (model.DomainRevision, error) {
+	var domainRevision model.DomainRevision
+
+	// Check if the programmer forgot to set the database in FileDomainRevisionDAO object
+	if dao.Database == nil {
+		return domainRevision, ErrDomainRevisionDAOUndefinedDatabase
+	}
+
+	revisions, err := dao.findByFQDN(fqdn)
+	if err != nil {
+		return domainRevision, err
+	}
+
+	if len(revisions) == 0 {
+		return domainRevision, mgo.ErrNotFound
+	}
+
+	for _, revision := range revisions {
+		if revision.Revision > domainRevision.Revision {
+			domainRevision = revision
+		}
+	}
+
+	return domainRevision, nil
+}
+
+// Remove all the revisions of a domain, writing the file only once
+func (dao FileDomainRevisionDAO) RemoveByFQDN(fqdn string) error {
+	// Check if the programmer forgot to set the database in FileDomainRevisionDAO object
+	if dao.Database == nil {
+		return ErrDomainRevisionDAOUndefinedDatabase
+	}
+
+	revisions, err := dao.findByFQDN(fqdn)
+	if err != nil || len(revisions) == 0 {
+		return err
+	}
+
+	var keys []string
+	for _, revision := range revisions {
+		keys = append(keys, fileDomainRevisionKey(revision.FQDN, revision.Revision))
+	}
+
+	for _, err := range dao.Database.RemoveMany(domainRevisionDAOCollection, keys) {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Remove all domain revision entries from the file database. This is a DANGEROUS method,
+// use with caution
+func (dao FileDomainRevisionDAO) RemoveAll() error {
+	// Check if the programmer forgot to set the database in FileDomainRevisionDAO object
+	if dao.Database == nil {
+		return ErrDomainRevisionDAOUndefinedDatabase
+	}
+
+	return dao.Database.RemoveAll(domainRevisionDAOCollection)
+}
+
+// Load all revisions of a domain from the file database
+func (dao FileDomainRevisionDAO) findByFQDN(fqdn string) ([]model.DomainRevision, error) {
+	var revisions []model.DomainRevision
+	err := dao.Database.ForEach(domainRevisionDAOCollection, func(key string, content []byte) error {
+		var revision model.DomainRevision
+		if err := json.Unmarshal(content, &revision); err != nil {
+			return err
+		}
+
+		if revision.FQDN == fqdn {
+			revisions = append(revisions, revision)
+		}
+		return nil
+	})
+
+	return revisions, err
+}
+
+// Build the key of the domain revision in the file database
+func fileDomainRevisionKey(fqdn string, revision int) string {
+	return fmt.Sprintf("%s/%d", fqdn, revision)
+}
+
+// domainRevisionsSorter sorts a list of domain revisions using the order by fields of the
+// pagination
+type domainRevisionsSorter struct {
+	revisions []model.DomainRevision
+	orderBy   []DomainRevisionDAOSort
+}
+
+func (s domainRevisionsSorter) Len() int {
+	return len(s.revisions)
+}
+
+func (s domainRevisionsSorter) Swap(i, j int) {
+	s.revisions[i], s.revisions[j] = s.revisions[j], s.revisions[i]
+}
+
+func (s domainRevisionsSorter) Less(i, j int) bool {
+	for _, orderBy := range s.orderBy {
+		var comparison int
+
+		switch orderBy.Field {
+		case DomainRevisionDAOOrderByFieldRevision:
+			comparison = compareUint64(uint64(s.revisions[i].Revision), uint64(s.revisions[j].Revision))
+		case DomainRevisionDAOOrderByFieldSavedAt:
+			comparison = compareTimes(s.revisions[i].SavedAt, s.revisions[j].SavedAt)
+		}
+
+		if comparison != 0 {
+			return comparison*int(orderBy.Direction) < 0
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,87 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/database/file"
+	"github.com/rafaeljusto/shelter/model"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileDomainRevisionDAO(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shelter-file-domain-revision-dao")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	database, err := file.Open(filepath.Join(dir, "shelter.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	domainRevisionDAO := FileDomainRevisionDAO{Database: database}
+
+	for i := 1; i <= 3; i++ {
+		revision := model.NewDomainRevision(model.Domain{
+			FQDN:     "example.com.br.",
+			Revision: i,
+		})
+
+		if err := domainRevisionDAO.Save(&revision); err != nil {
+			t.Fatal("Error saving domain revision. Details:", err)
+		}
+	}
+
+	revision := model.NewDomainRevision(model.Domain{FQDN: "example.com.br.", Revision: 3})
+	if err := domainRevisionDAO.Save(&revision); err != ErrDAORevisionConflict {
+		t.Error("Not detecting a duplicated domain revision")
+	}
+
+	var pagination DomainRevisionDAOPagination
+	revisions, err := domainRevisionDAO.FindByFQDN("example.com.br.", &pagination)
+	if err != nil {
+		t.Fatal("Error retrieving domain revisions. Details:", err)
+	}
+
+	if pagination.NumberOfItems != 3 || len(revisions) != 3 || revisions[0].Revision != 3 {
+		t.Error("Not retrieving the domain revisions with the most recent first")
+	}
+
+	if revision, err := domainRevisionDAO.FindByRevision("example.com.br.", 2); err != nil ||
+		revision.Domain.Revision != 2 {
+
+		t.Error("Not retrieving a specific domain revision")
+	}
+
+	if _, err := domainRevisionDAO.FindByRevision("example.com.br.", 4); err != mgo.ErrNotFound {
+		t.Error("Not returning not found for an unknown domain revision")
+	}
+
+	if revision, err := domainRevisionDAO.FindLast("example.com.br."); err != nil ||
+		revision.Revision != 3 {
+
+		t.Error("Not retrieving the last domain revision")
+	}
+
+	if _, err := domainRevisionDAO.FindLast("example.net.br."); err != mgo.ErrNotFound {
+		t.Error("Not returning not found for a domain without revisions")
+	}
+
+	if err := domainRevisionDAO.RemoveByFQDN("example.com.br."); err != nil {
+		t.Fatal("Error removing domain revisions. Details:", err)
+	}
+
+	pagination = DomainRevisionDAOPagination{}
+	revisions, err = domainRevisionDAO.FindByFQDN("example.com.br.", &pagination)
+	if err != nil || len(revisions) > 0 {
+		t.Error("Not removing the domain revisions")
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"encoding/json"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2/bson"
+	"github.com/rafaeljusto/shelter/database/file"
+	"github.com/rafaeljusto/shelter/model"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileNotificationDAO is the structure responsable for keeping the alerts sent to the
+// domains' owners in the embedded file database. The notifications are indexed by id, as
+// they are never updated
+type FileNotificationDAO struct {
+	Database *file.Database // Embedded file database
+}
+
+// Save the notification in the file database. The notification always receives a new id,
+// to be compatible with the MongoDB backend
+func (dao FileNotificationDAO) Save(notification *model.Notification) error {
+	// Check if the programmer forgot to set the database in FileNotificationDAO object
+	if dao.Database == nil {
+		return ErrNotificationDAOUndefinedDatabase
+	}
+
+	notification.Id = bson.NewObjectId()
+	return dao.Database.Put(notificationDAOCollection, notification.Id.Hex(), notification)
+}
+
+// Retrieve the alerts of the domain problems that were delivered (or postponed) using the
+// given channel after a date, the most recent first. Alerts of solved problems and digests
+// are ignored, as it works in the MongoDB backend
+func (dao FileNotificationDAO) FindDelivered(fqdn, channel string,
+	since time.Time) ([]model.Notification, error) {
+
+	// Check if the programmer forgot to set the database in FileNotificationDAO object
+	if dao.Database == nil {
+		return nil, ErrNotificationDAOUndefinedDatabase
+	}
+
+	notifications, err := dao.find(func(notification model.Notification) bool {
+		return notification.FQDN == fqdn &&
+			notification.Channel == channel &&
+			(notification.Delivered || notification.Postponed) &&
+			!notification.Recovery &&
+			!notification.Digest &&
+			notification.SentAt.After(since)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Sort(notificationsSorter{
+		notifications: notifications,
+		orderBy:       notificationDAODefaultPaginationOrderBy,
+	})
+
+	return notifications, nil
+}
+
+// Retrieve the most recent digest delivered to the recipient. If there's no digest
+// mgo.ErrNotFound is returned, to be compatible with the MongoDB backend
+func (dao FileNotificationDAO) FindLastDigest(recipient string) (model.Notification, error) {
+	var lastDigest model.Notification
+
+	// Check if the programmer forgot to set the database in FileNotificationDAO object
+	if dao.Database == nil {
+		return lastDigest, ErrNotificationDAOUndefinedDatabase
+	}
+
+	digests, err := dao.find(func(notification model.Notification) bool {
+		if !notification.Digest || !notification.Delivered {
+			return false
+		}
+
+		for _, notificationRecipient := range notification.Recipients {
+			if notificationRecipient == recipient {
+				return true
+			}
+		}
+
+		return false
+	})
+
+	if err != nil {
+		return lastDigest, err
+	}
+
+	if len(digests) == 0 {
+		return lastDigest, mgo.ErrNotFound
+	}
+
+	for i, digest := range digests {
+		if i == 0 || digest.SentAt.After(lastDigest.SentAt) {
+			lastDigest = digest
+		}
+	}
+
+	return lastDigest, nil
+}
+
+// Retrieve the notifications of a domain using pagination control. When pagination values
+// are not informed, default values are adopted, returning the most recent alerts first
+func (dao FileNotificationDAO) FindByFQDN(fqdn string,
+	pagination *NotificationDAOPagination) ([]model.Notification, error) {
+
+	// Check if the programmer forgot to set the database in FileNotificationDAO object
+	if dao.Database == nil {
+		return nil, ErrNotificationDAOUndefinedDatabase
+	}
+
+	if pagination == nil {
+		return nil, ErrNotificationDAOPaginationUndefined
+	}
+
+	if len(pagination.OrderBy) == 0 {
+		pagination.OrderBy = notificationDAODefaultPaginationOrderBy
+	}
+
+	if pagination.PageSize == 0 {
+		pagination.PageSize = defaultPaginationPageSize
+	}
+
+	if pagination.Page == 0 {
+		pagination.Page = defaultPaginationPage
+	}
+
+	notifications, err := dao.find(func(notification model.Notification) bool {
+		return notification.FQDN == fqdn
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Sort(notificationsSorter{notifications: notifications, orderBy: pagination.OrderBy})
+
+	var start, end int
+	pagination.NumberOfItems = len(notifications)
+	pagination.Page, pagination.NumberOfPages, start, end =
+		paginate(pagination.NumberOfItems, pagination.PageSize, pagination.Page)
+
+	return notifications[start:end], nil
+}
+
+// Retrieve the postponed alerts that can be delivered, because the quiet hours of the
+// recipients ended before the given date
+func (dao FileNotificationDAO) FindPostponed(until time.Time) ([]model.Notification, error) {
+	// Check if the programmer forgot to set the database in FileNotificationDAO object
+	if dao.Database == nil {
+		return nil, ErrNotificationDAOUndefinedDatabase
+	}
+
+	return dao.find(func(notification model.Notification) bool {
+		return notification.Postponed && !notification.PostponedUntil.After(until)
+	})
+}
+
+// Remove a notification using its id. If the notification doesn't exist mgo.ErrNotFound is
+// returned, to be compatible with the MongoDB backend
+func (dao FileNotificationDAO) Remove(notification *model.Notification) error {
+	// Check if the programmer forgot to set the database in FileNotificationDAO object
+	if dao.Database == nil {
+		return ErrNotificationDAOUndefinedDatabase
+	}
+
+	err := dao.Database.Remove(notificationDAOCollection, notification.Id.Hex())
+	if err == file.ErrNotFound {
+		err = mgo.ErrNotFound
+	}
+
+	return err
+}
+
+// Remove all notifications of a domain, writing the file only once
+func (dao FileNotificationDAO) RemoveByFQDN(fqdn string) error {
+	// Check if the programmer forgot to set the database in FileNotificationDAO object
+	if dao.Database == nil {
+		return ErrNotificationDAOUndefinedDatabase
+	}
+
+	notifications, err := dao.find(func(notification model.Notification) bool {
+		return notification.FQDN == fqdn
+	})
+
+	if err != nil || len(notifications) == 0 {
+		return err
+	}
+
+	var keys []string
+	for _, notification := range notifications {
+		keys = append(keys, notification.Id.Hex())
+	}
+
+	for _, err := range dao.Database.RemoveMany(notificationDAOCollection, keys) {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Remove all notification entries from the file database. This is a DANGEROUS method, use
+// with caution
+func (dao FileNotificationDAO) RemoveAll() error {
+	// Check if the programmer forgot to set the database in FileNotificationDAO object
+	if dao.Database == nil {
+		return ErrNotificationDAOUndefinedDatabase
+	}
+
+	return dao.Database.RemoveAll(notificationDAOCollection)
+}
+
+// Load the notifications that match the given condition from the file database
+func (dao FileNotificationDAO) find(match func(notification model.Notification) bool) ([]model.Notification, error) {
+	var notifications []model.Notification
+	err := dao.Database.ForEach(notificationDAOCollection, func(key string, content []byte) error {
+		var notification model.Notification
+		if err := json.Unmarshal(content, &notification); err != nil {
+			return err
+		}
+
+		if match(notification) {
+			notifications = append(notifications, notification)
+		}
+		return nil
+	})
+
+	return notifications, err
+}
+
+// notificationsSorter sorts a list of notifications using the order by fields of the
+// pagination
+type notificationsSorter struct {
+	notifications []model.Notification
+	orderBy       []NotificationDAOSort
+}
+
+func (s notificationsSorter) Len() int {
+	return len(s.notifications)
+}
+
+func (s notificationsSorter) Swap(i, j int) {
+	s.notifications[i], s.notifications[j] = s.notifications[j], s.notifications[i]
+}
+
+func (s notificationsSorter) Less(i, j int) bool {
+	for _, orderBy := range s.orderBy {
+		var comparison int
+
+		switch orderBy.Field {
+		case NotificationDAOOrderByFieldSentAt:
+			comparison = compareTimes(s.notifications[i].SentAt, s.notifications[j].SentAt)
+		case NotificationDAOOrderByFieldChannel:
+			comparison = strings.Compare(s.notifications[i].Channel, s.notifications[j].Channel)
+		}
+
+		if comparison != 0 {
+			return comparison*int(orderBy.Direction) < 0
+		}
+	}
+
+	return false
+}
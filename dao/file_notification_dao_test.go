@@ -0,0 +1,172 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/database/file"
+	"github.com/rafaeljusto/shelter/model"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileNotificationDAO(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shelter-file-notification-dao")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	database, err := file.Open(filepath.Join(dir, "shelter.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notificationDAO := FileNotificationDAO{Database: database}
+
+	if _, err := notificationDAO.FindLastDigest("owner@example.com.br"); err != mgo.ErrNotFound {
+		t.Error("Not returning not found when there's no digest")
+	}
+
+	now := time.Now()
+	notifications := []model.Notification{
+		{FQDN: "example.com.br.", Channel: "smtp", Delivered: true, SentAt: now.Add(-3 * time.Hour)},
+		{FQDN: "example.com.br.", Channel: "smtp", Delivered: true, SentAt: now.Add(-1 * time.Hour)},
+		{FQDN: "example.com.br.", Channel: "smtp", Delivered: false, SentAt: now},
+		{FQDN: "example.com.br.", Channel: "smtp", Delivered: true, Recovery: true, SentAt: now},
+		{FQDN: "example.com.br.", Channel: "webhook", Delivered: true, SentAt: now},
+		{
+			FQDN:       "example.com.br.",
+			Channel:    "smtp",
+			Recipients: []string{"owner@example.com.br"},
+			Digest:     true,
+			Delivered:  true,
+			SentAt:     now.Add(-2 * time.Hour),
+		},
+		{
+			FQDN:       "example2.com.br.",
+			Channel:    "smtp",
+			Recipients: []string{"owner@example.com.br"},
+			Digest:     true,
+			Delivered:  true,
+			SentAt:     now.Add(-1 * time.Hour),
+		},
+	}
+
+	for i := range notifications {
+		if err := notificationDAO.Save(&notifications[i]); err != nil {
+			t.Fatal("Error saving notification. Details:", err)
+		}
+	}
+
+	delivered, err := notificationDAO.FindDelivered("example.com.br.", "smtp", now.Add(-4*time.Hour))
+	if err != nil {
+		t.Fatal("Error retrieving delivered notifications. Details:", err)
+	}
+
+	if len(delivered) != 2 || !delivered[0].SentAt.After(delivered[1].SentAt) {
+		t.Error("Not retrieving the delivered alerts with the most recent first")
+	}
+
+	digest, err := notificationDAO.FindLastDigest("owner@example.com.br")
+	if err != nil || digest.FQDN != "example2.com.br." {
+		t.Error("Not retrieving the last digest of the recipient")
+	}
+
+	pagination := NotificationDAOPagination{PageSize: 4}
+	domainNotifications, err := notificationDAO.FindByFQDN("example.com.br.", &pagination)
+	if err != nil {
+		t.Fatal("Error retrieving domain notifications. Details:", err)
+	}
+
+	if pagination.NumberOfItems != 6 || pagination.NumberOfPages != 2 || len(domainNotifications) != 4 {
+		t.Error("Not paginating the domain notifications properly")
+	}
+
+	if err := notificationDAO.RemoveByFQDN("example.com.br."); err != nil {
+		t.Fatal("Error removing domain notifications. Details:", err)
+	}
+
+	pagination = NotificationDAOPagination{}
+	domainNotifications, err = notificationDAO.FindByFQDN("example.com.br.", &pagination)
+	if err != nil || len(domainNotifications) > 0 {
+		t.Error("Not removing the domain notifications")
+	}
+
+	if _, err := notificationDAO.FindLastDigest("owner@example.com.br"); err != nil {
+		t.Error("Removing notifications of other domains")
+	}
+}
+
+func TestFileNotificationDAOPostponed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shelter-file-notification-dao")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	database, err := file.Open(filepath.Join(dir, "shelter.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notificationDAO := FileNotificationDAO{Database: database}
+
+	now := time.Now()
+	notifications := []model.Notification{
+		{
+			FQDN:           "example.com.br.",
+			Channel:        "smtp",
+			Postponed:      true,
+			PostponedUntil: now.Add(-1 * time.Hour),
+			SentAt:         now.Add(-2 * time.Hour),
+		},
+		{
+			FQDN:           "example.com.br.",
+			Channel:        "smtp",
+			Postponed:      true,
+			PostponedUntil: now.Add(1 * time.Hour),
+			SentAt:         now.Add(-2 * time.Hour),
+		},
+	}
+
+	for i := range notifications {
+		if err := notificationDAO.Save(&notifications[i]); err != nil {
+			t.Fatal("Error saving notification. Details:", err)
+		}
+	}
+
+	delivered, err := notificationDAO.FindDelivered("example.com.br.", "smtp", now.Add(-4*time.Hour))
+	if err != nil || len(delivered) != 2 {
+		t.Error("Not considering the postponed alerts as delivered")
+	}
+
+	postponed, err := notificationDAO.FindPostponed(now)
+	if err != nil {
+		t.Fatal("Error retrieving postponed notifications. Details:", err)
+	}
+
+	if len(postponed) != 1 || postponed[0].Id != notifications[0].Id {
+		t.Fatalf("Not retrieving only the postponed alerts after the quiet hours: %#v", postponed)
+	}
+
+	if err := notificationDAO.Remove(&postponed[0]); err != nil {
+		t.Fatal("Error removing notification. Details:", err)
+	}
+
+	if err := notificationDAO.Remove(&postponed[0]); err != mgo.ErrNotFound {
+		t.Error("Not returning not found when removing an unknown notification")
+	}
+
+	if postponed, err := notificationDAO.FindPostponed(now.Add(2 * time.Hour)); err != nil ||
+		len(postponed) != 1 || postponed[0].Id != notifications[1].Id {
+
+		t.Error("Not removing the postponed alert")
+	}
+}
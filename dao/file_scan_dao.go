@@ -0,0 +1,199 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"encoding/json"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2/bson"
+	"github.com/rafaeljusto/shelter/database/file"
+	"github.com/rafaeljusto/shelter/model"
+	"sort"
+	"time"
+)
+
+// FileScanDAO is the structure responsable for keeping the scans in the embedded file
+// database. The scans are indexed by the start date, that identifies the scan in the
+// system
+type FileScanDAO struct {
+	Database *file.Database // Embedded file database
+}
+
+// Save the scan object in the file database. On creation the scan object is going to
+// receive an id, to be compatible with the MongoDB backend
+func (dao FileScanDAO) Save(scan *model.Scan) error {
+	// Check if the programmer forgot to set the database in FileScanDAO object
+	if dao.Database == nil {
+		return ErrScanDAOUndefinedDatabase
+	}
+
+	if len(scan.Id.Hex()) == 0 {
+		scan.Id = bson.NewObjectId()
+	}
+
+	scan.Revision += 1
+	scan.LastModifiedAt = time.Now().UTC()
+
+	return dao.Database.Update(scanDAOCollection, fileScanKey(scan.StartedAt),
+		func(current []byte) (interface{}, error) {
+			if current == nil {
+				return scan, nil
+			}
+
+			var storedScan model.Scan
+			if err := json.Unmarshal(current, &storedScan); err != nil {
+				return nil, err
+			}
+
+			// Avoid concurrency problems in the same way that we do with MongoDB, checking if
+			// the object wasn't modified since it was loaded
+			if storedScan.Id != scan.Id || storedScan.Revision != scan.Revision-1 {
+				return nil, ErrDAORevisionConflict
+			}
+
+			return scan, nil
+		})
+}
+
+// Try to find the scan using the startedAt time attribute. If the scan doesn't exist
+// mgo.ErrNotFound is returned, to be compatible with the MongoDB backend
+func (dao FileScanDAO) FindByStartedAt(startedAt time.Time) (model.Scan, error) {
+	scan := model.Scan{
+		NameserverStatistics: make(map[string]uint64),
+		DSStatistics:         make(map[string]uint64),
+	}
+
+	// Check if the programmer forgot to set the database in FileScanDAO object
+	if dao.Database == nil {
+		return scan, ErrScanDAOUndefinedDatabase
+	}
+
+	err := dao.Database.Get(scanDAOCollection, fileScanKey(startedAt), &scan)
+
+	if err == file.ErrNotFound {
+		err = mgo.ErrNotFound
+	}
+
+	return scan, err
+}
+
+// Retrieve all scans using pagination control. When pagination values are not informed,
+// default values are adopted
+func (dao FileScanDAO) FindAll(pagination *ScanDAOPagination, expand bool) ([]model.Scan, error) {
+	// Check if the programmer forgot to set the database in FileScanDAO object
+	if dao.Database == nil {
+		return nil, ErrScanDAOUndefinedDatabase
+	}
+
+	if pagination == nil {
+		return nil, ErrScanDAOPaginationUndefined
+	}
+
+	if len(pagination.OrderBy) == 0 {
+		pagination.OrderBy = scanDAODefaultPaginationOrderBy
+	}
+
+	if pagination.PageSize == 0 {
+		pagination.PageSize = defaultPaginationPageSize
+	}
+
+	if pagination.Page == 0 {
+		pagination.Page = defaultPaginationPage
+	}
+
+	var scans []model.Scan
+	err := dao.Database.ForEach(scanDAOCollection, func(key string, content []byte) error {
+		var scan model.Scan
+		if err := json.Unmarshal(content, &scan); err != nil {
+			return err
+		}
+
+		scans = append(scans, scan)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Sort(scansSorter{scans: scans, orderBy: pagination.OrderBy})
+
+	var start, end int
+	pagination.NumberOfItems = len(scans)
+	pagination.Page, pagination.NumberOfPages, start, end =
+		paginate(pagination.NumberOfItems, pagination.PageSize, pagination.Page)
+
+	scans = scans[start:end]
+
+	if !expand {
+		compressScans(scans)
+	}
+
+	return scans, nil
+}
+
+// Remove the scan that have the given startedAt time from the file database
+func (dao FileScanDAO) RemoveByStartedAt(startedAt time.Time) error {
+	// Check if the programmer forgot to set the database in FileScanDAO object
+	if dao.Database == nil {
+		return ErrScanDAOUndefinedDatabase
+	}
+
+	return dao.Database.Remove(scanDAOCollection, fileScanKey(startedAt))
+}
+
+// Remove all scan entries from the file database. This is a DANGEROUS method, use with
+// caution
+func (dao FileScanDAO) RemoveAll() error {
+	// Check if the programmer forgot to set the database in FileScanDAO object
+	if dao.Database == nil {
+		return ErrScanDAOUndefinedDatabase
+	}
+
+	return dao.Database.RemoveAll(scanDAOCollection)
+}
+
+// Build the key of the scan in the file database. We use the UTC representation so that
+// the same date in different timezones identifies the same scan
+func fileScanKey(startedAt time.Time) string {
+	return startedAt.UTC().Format(time.RFC3339Nano)
+}
+
+// scansSorter sorts a list of scans using the order by fields of the pagination
+type scansSorter struct {
+	scans   []model.Scan
+	orderBy []ScanDAOSort
+}
+
+func (s scansSorter) Len() int {
+	return len(s.scans)
+}
+
+func (s scansSorter) Swap(i, j int) {
+	s.scans[i], s.scans[j] = s.scans[j], s.scans[i]
+}
+
+func (s scansSorter) Less(i, j int) bool {
+	for _, orderBy := range s.orderBy {
+		var comparison int
+
+		switch orderBy.Field {
+		case ScanDAOOrderByFieldStartedAt:
+			comparison = compareTimes(s.scans[i].StartedAt, s.scans[j].StartedAt)
+		case ScanDAOOrderByFieldDomainsScanned:
+			comparison = compareUint64(s.scans[i].DomainsScanned, s.scans[j].DomainsScanned)
+		case ScanDAOOrderByFieldDomainsWithDNSSECScanned:
+			comparison = compareUint64(s.scans[i].DomainsWithDNSSECScanned,
+				s.scans[j].DomainsWithDNSSECScanned)
+		}
+
+		if comparison != 0 {
+			return comparison*int(orderBy.Direction) < 0
+		}
+	}
+
+	return false
+}
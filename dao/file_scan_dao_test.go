@@ -0,0 +1,86 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/database/file"
+	"github.com/rafaeljusto/shelter/model"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileScanDAO(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shelter-file-scan-dao")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	database, err := file.Open(filepath.Join(dir, "shelter.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scanDAO := FileScanDAO{Database: database}
+
+	startedAt := time.Now().Add(-10 * time.Hour)
+	for i := 0; i < 5; i++ {
+		scan := model.Scan{
+			StartedAt:      startedAt.Add(time.Duration(i) * time.Hour),
+			DomainsScanned: uint64(i),
+		}
+
+		if err := scanDAO.Save(&scan); err != nil {
+			t.Fatal("Error saving scan. Details:", err)
+		}
+	}
+
+	// The scan must be found in any timezone
+	scan, err := scanDAO.FindByStartedAt(startedAt.In(time.FixedZone("BRT", -3*60*60)))
+	if err != nil {
+		t.Fatal("Error retrieving scan. Details:", err)
+	}
+
+	if scan.Revision != 1 || scan.DomainsScanned != 0 {
+		t.Error("Scan is being persisted wrongly")
+	}
+
+	pagination := ScanDAOPagination{
+		OrderBy: []ScanDAOSort{
+			{Field: ScanDAOOrderByFieldDomainsScanned, Direction: DAOOrderByDirectionDescending},
+		},
+	}
+
+	scans, err := scanDAO.FindAll(&pagination, true)
+	if err != nil {
+		t.Fatal("Error retrieving scans. Details:", err)
+	}
+
+	if pagination.NumberOfItems != 5 || len(scans) != 5 || scans[0].DomainsScanned != 4 {
+		t.Error("Not retrieving the scans properly")
+	}
+
+	if err := scanDAO.RemoveByStartedAt(startedAt); err != nil {
+		t.Error("Error removing scan. Details:", err)
+	}
+
+	if _, err := scanDAO.FindByStartedAt(startedAt); err != mgo.ErrNotFound {
+		t.Error("Scan was not removed")
+	}
+
+	if err := scanDAO.RemoveAll(); err != nil {
+		t.Error("Error removing all scans. Details:", err)
+	}
+
+	scans, err = scanDAO.FindAll(&ScanDAOPagination{}, false)
+	if err != nil || len(scans) != 0 {
+		t.Error("Not removing all scans")
+	}
+}
@@ -0,0 +1,304 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"errors"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2/bson"
+	"github.com/rafaeljusto/shelter/database/mongodb"
+	"github.com/rafaeljusto/shelter/model"
+	"strings"
+	"time"
+)
+
+// List of possible errors that can occur in this DAO. There can be also other errors from
+// low level drivers.
+var (
+	// Programmer must set the Database attribute from NameserverSummaryDAO with a valid
+	// connection before using this object
+	ErrNameserverSummaryDAOUndefinedDatabase = errors.New("No database defined for NameserverSummaryDAO")
+
+	// Pagination attribute is mandatory, and it's a pointer only to fill some query
+	// informations in it. For the user that wants all records without pagination for a B2B
+	// integration need to pass zero in the page size
+	ErrNameserverSummaryDAOPaginationUndefined = errors.New("Pagination was not defined")
+
+	// An invalid order by field was given to be converted in one of the known order by
+	// fields of the NameserverSummary DAO
+	ErrNameserverSummaryDAOOrderByFieldUnknown = errors.New("Unknown order by field")
+)
+
+const (
+	nameserverSummaryDAOCollection = "nameserversummary" // Collection used to store all nameserver summaries in the MongoDB database
+)
+
+// List of possible fields that can be used to order a result set
+const (
+	NameserverSummaryDAOOrderByFieldHost     NameserverSummaryDAOOrderByField = 0 // Order by nameserver's name
+	NameserverSummaryDAOOrderByFieldDomains  NameserverSummaryDAOOrderByField = 1 // Order by the number of domains that delegate to the nameserver
+	NameserverSummaryDAOOrderByFieldTimeouts NameserverSummaryDAOOrderByField = 2 // Order by the number of timeouts of the nameserver
+	NameserverSummaryDAOOrderByFieldLastOKAt NameserverSummaryDAOOrderByField = 3 // Order by the last time that the nameserver was OK
+)
+
+// Enumerate definition for the OrderBy so that we can limit the fields that the user can
+// use in a query
+type NameserverSummaryDAOOrderByField int
+
+// Convert the NameserverSummaryDAO order by field from string into enum. If the string is
+// unknown an error will be returned. The string is case insensitive and spaces around it
+// are ignored
+func NameserverSummaryDAOOrderByFieldFromString(value string) (NameserverSummaryDAOOrderByField, error) {
+	value = strings.ToLower(value)
+	value = strings.TrimSpace(value)
+
+	switch value {
+	case "host":
+		return NameserverSummaryDAOOrderByFieldHost, nil
+	case "domains":
+		return NameserverSummaryDAOOrderByFieldDomains, nil
+	case "timeouts":
+		return NameserverSummaryDAOOrderByFieldTimeouts, nil
+	case "lastokat":
+		return NameserverSummaryDAOOrderByFieldLastOKAt, nil
+	}
+
+	return NameserverSummaryDAOOrderByFieldHost, ErrNameserverSummaryDAOOrderByFieldUnknown
+}
+
+// Convert the NameserverSummaryDAO order by field from enum into string. If the enum is
+// unknown this method will return an empty string
+func NameserverSummaryDAOOrderByFieldToString(value NameserverSummaryDAOOrderByField) string {
+	switch value {
+	case NameserverSummaryDAOOrderByFieldHost:
+		return "host"
+
+	case NameserverSummaryDAOOrderByFieldDomains:
+		return "domains"
+
+	case NameserverSummaryDAOOrderByFieldTimeouts:
+		return "timeouts"
+
+	case NameserverSummaryDAOOrderByFieldLastOKAt:
+		return "lastokat"
+	}
+
+	return ""
+}
+
+// Default values when the user don't define pagination
+var (
+	nameserverSummaryDAODefaultPaginationOrderBy = []NameserverSummaryDAOSort{
+		{
+			Field:     NameserverSummaryDAOOrderByFieldHost, // Default ordering is by host
+			Direction: DAOOrderByDirectionAscending,         // Default ordering is ascending
+		},
+	}
+)
+
+func init() {
+	// Add index on host to speed up searchs. Host will be a unique field in database
+	mongodb.RegisterIndexFunction(func(database *mgo.Database) error {
+		index := mgo.Index{
+			Name:     "host",
+			Key:      []string{"host"},
+			Unique:   true,
+			DropDups: true,
+		}
+
+		return database.C(nameserverSummaryDAOCollection).EnsureIndex(index)
+	})
+}
+
+// NameserverSummaryDAO is the structure responsable for keeping the database connection
+// to save the nameserver summaries after every scan
+type NameserverSummaryDAO struct {
+	Database *mgo.Database // MongoDB Database
+}
+
+// Save the nameserver summary in the database. On creation the nameserver summary object
+// is going to receive the id that refers to the entry in the database
+func (dao NameserverSummaryDAO) Save(summary *model.NameserverSummary) error {
+	// Check if the programmer forgot to set the database in NameserverSummaryDAO object
+	if dao.Database == nil {
+		return ErrNameserverSummaryDAOUndefinedDatabase
+	}
+
+	// When creating a new nameserver summary object, the id will be probably nil (or kind of
+	// new according to bson.ObjectId), so we must initialize it
+	if len(summary.Id.Hex()) == 0 {
+		summary.Id = bson.NewObjectId()
+	}
+
+	// Every time we modified a nameserver summary object we increase the revision counter
+	// to identify changes in high level structures
+	summary.Revision += 1
+
+	// Store the last time that the object was modified
+	summary.LastModifiedAt = time.Now().UTC()
+
+	// Upsert try to update the collection entry if exists, if not, it creates a new entry.
+	// We also avoid concurency adding the revision as a paremeter for updating the entry
+	_, err := dao.Database.C(nameserverSummaryDAOCollection).Upsert(bson.M{
+		"_id":      summary.Id,
+		"revision": summary.Revision - 1,
+	}, summary)
+
+	return err
+}
+
+// Retrieve all nameserver summaries using pagination control. When pagination values are
+// not informed, default values are adopted
+func (dao NameserverSummaryDAO) FindAll(
+	pagination *NameserverSummaryDAOPagination,
+) ([]model.NameserverSummary, error) {
+
+	// Check if the programmer forgot to set the database in NameserverSummaryDAO object
+	if dao.Database == nil {
+		return nil, ErrNameserverSummaryDAOUndefinedDatabase
+	}
+
+	if pagination == nil {
+		return nil, ErrNameserverSummaryDAOPaginationUndefined
+	}
+
+	if len(pagination.OrderBy) == 0 {
+		pagination.OrderBy = nameserverSummaryDAODefaultPaginationOrderBy
+	}
+
+	if pagination.PageSize == 0 {
+		pagination.PageSize = defaultPaginationPageSize
+	}
+
+	if pagination.Page == 0 {
+		pagination.Page = defaultPaginationPage
+	}
+
+	var sortList []string
+	for _, sort := range pagination.OrderBy {
+		var sortTmp string
+
+		if sort.Direction == DAOOrderByDirectionDescending {
+			sortTmp = "-"
+		}
+
+		switch sort.Field {
+		case NameserverSummaryDAOOrderByFieldHost:
+			sortTmp += "host"
+		case NameserverSummaryDAOOrderByFieldDomains:
+			sortTmp += "domains"
+		case NameserverSummaryDAOOrderByFieldTimeouts:
+			sortTmp += "timeouts"
+		case NameserverSummaryDAOOrderByFieldLastOKAt:
+			sortTmp += "lastokat"
+		}
+
+		sortList = append(sortList, sortTmp)
+	}
+
+	query := dao.Database.C(nameserverSummaryDAOCollection).Find(bson.M{})
+
+	// We store the number of items before applying pagination, if we do this after we get
+	// only the number of items of a page size
+	var err error
+	if pagination.NumberOfItems, err = query.Count(); err != nil {
+		return nil, err
+	}
+
+	// Safety check to don't allow to set a page higher than the number of pages
+	maxNumberOfPages := pagination.NumberOfItems / pagination.PageSize
+	if pagination.NumberOfItems%pagination.PageSize > 0 {
+		maxNumberOfPages++
+	}
+
+	if maxNumberOfPages == 0 {
+		// When there's no item, we should stay on the first page (don't skip)
+		pagination.Page = 1
+
+	} else if pagination.Page > maxNumberOfPages {
+		pagination.Page = maxNumberOfPages
+	}
+
+	query.
+		Sort(sortList...).
+		Skip(pagination.PageSize * (pagination.Page - 1)).
+		Limit(pagination.PageSize)
+
+	var summaries []model.NameserverSummary
+	if err := query.All(&summaries); err != nil {
+		return nil, err
+	}
+
+	if pagination.PageSize > 0 {
+		pagination.NumberOfPages = pagination.NumberOfItems / pagination.PageSize
+		if pagination.NumberOfItems%pagination.PageSize > 0 {
+			pagination.NumberOfPages += 1
+		}
+	}
+
+	return summaries, nil
+}
+
+// Try to find the nameserver summary using the host attribute. The database should be
+// prepared (with indexes) to search faster when using host as condition
+func (dao NameserverSummaryDAO) FindByHost(host string) (model.NameserverSummary, error) {
+	summary := model.NameserverSummary{
+		Statistics: make(map[string]uint64),
+	}
+
+	// Check if the programmer forgot to set the database in NameserverSummaryDAO object
+	if dao.Database == nil {
+		return summary, ErrNameserverSummaryDAOUndefinedDatabase
+	}
+
+	err := dao.Database.C(nameserverSummaryDAOCollection).Find(bson.M{
+		"host": host,
+	}).One(&summary)
+
+	return summary, err
+}
+
+// Remove all nameserver summaries that weren't modified since the given date. After
+// aggregating all domains, the hosts that aren't used by any domain anymore are the ones
+// that weren't updated
+func (dao NameserverSummaryDAO) RemoveNotModifiedSince(date time.Time) error {
+	// Check if the programmer forgot to set the database in NameserverSummaryDAO object
+	if dao.Database == nil {
+		return ErrNameserverSummaryDAOUndefinedDatabase
+	}
+
+	_, err := dao.Database.C(nameserverSummaryDAOCollection).RemoveAll(bson.M{
+		"lastmodifiedat": bson.M{"$lt": date},
+	})
+
+	return err
+}
+
+// Remove all nameserver summary entries from the database. This is a DANGEROUS method,
+// use with caution. For now is used only by the integration test enviroments to clear the
+// database before starting a new test
+func (dao NameserverSummaryDAO) RemoveAll() error {
+	_, err := dao.Database.C(nameserverSummaryDAOCollection).RemoveAll(bson.M{})
+	return err
+}
+
+// NameserverSummaryDAOPagination was created as a necessity for big result sets that
+// needs to be sent for an end-user. With pagination we can control the size of the data
+// and make it faster for the user to interact with it in a web interface as example
+type NameserverSummaryDAOPagination struct {
+	OrderBy       []NameserverSummaryDAOSort // Sort the list before the pagination
+	PageSize      int                        // Number of items that are going to be considered in one page
+	Page          int                        // Current page that will be returned
+	NumberOfItems int                        // Total number of items in the result set
+	NumberOfPages int                        // Total number of pages calculated for the current result set
+}
+
+// NameserverSummaryDAOSort is an object responsable to relate the order by field and
+// direction. Each field used for sort, can be sorted in both directions
+type NameserverSummaryDAOSort struct {
+	Field     NameserverSummaryDAOOrderByField // Field to be sorted
+	Direction DAOOrderByDirection              // Direction used in the sort
+}
@@ -0,0 +1,54 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"testing"
+)
+
+func TestNameserverSummaryDAOOrderByFieldFromString(t *testing.T) {
+	if _, err := NameserverSummaryDAOOrderByFieldFromString("xxx"); err == nil {
+		t.Error("Accepting an invalid order by field")
+	}
+
+	if field, err := NameserverSummaryDAOOrderByFieldFromString("  HOST  "); err != nil || field != NameserverSummaryDAOOrderByFieldHost {
+		t.Error("Not accepting a valid order by field Host")
+	}
+
+	if field, err := NameserverSummaryDAOOrderByFieldFromString("Domains"); err != nil || field != NameserverSummaryDAOOrderByFieldDomains {
+		t.Error("Not accepting a valid order by field Domains")
+	}
+
+	if field, err := NameserverSummaryDAOOrderByFieldFromString("timeOUTS"); err != nil || field != NameserverSummaryDAOOrderByFieldTimeouts {
+		t.Error("Not accepting a valid order by field Timeouts")
+	}
+
+	if field, err := NameserverSummaryDAOOrderByFieldFromString("lastOKAt"); err != nil || field != NameserverSummaryDAOOrderByFieldLastOKAt {
+		t.Error("Not accepting a valid order by field LastOKAt")
+	}
+}
+
+func TestNameserverSummaryDAOOrderByFieldToString(t *testing.T) {
+	if field := NameserverSummaryDAOOrderByFieldToString(NameserverSummaryDAOOrderByField(9999)); len(field) > 0 {
+		t.Error("Not returning empty string when is an unknown order by field")
+	}
+
+	if field := NameserverSummaryDAOOrderByFieldToString(NameserverSummaryDAOOrderByFieldHost); field != "host" {
+		t.Error("Not returning the correct order by field for Host")
+	}
+
+	if field := NameserverSummaryDAOOrderByFieldToString(NameserverSummaryDAOOrderByFieldDomains); field != "domains" {
+		t.Error("Not returning the correct order by field for Domains")
+	}
+
+	if field := NameserverSummaryDAOOrderByFieldToString(NameserverSummaryDAOOrderByFieldTimeouts); field != "timeouts" {
+		t.Error("Not returning the correct order by field for Timeouts")
+	}
+
+	if field := NameserverSummaryDAOOrderByFieldToString(NameserverSummaryDAOOrderByFieldLastOKAt); field != "lastokat" {
+		t.Error("Not returning the correct order by field for LastOKAt")
+	}
+}
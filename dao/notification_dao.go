@@ -0,0 +1,348 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"errors"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2/bson"
+	"github.com/rafaeljusto/shelter/database/mongodb"
+	"github.com/rafaeljusto/shelter/model"
+	"strings"
+	"time"
+)
+
+// List of possible errors that can occur in this DAO. There can be also other errors from
+// low level drivers.
+var (
+	// Programmer must set the Database attribute from NotificationDAO with a valid
+	// connection before using this object
+	ErrNotificationDAOUndefinedDatabase = errors.New("No database defined for NotificationDAO")
+
+	// Pagination attribute is mandatory, and it's a pointer only to fill some query
+	// informations in it. For the user that wants all records without pagination for a B2B
+	// integration need to pass zero in the page size
+	ErrNotificationDAOPaginationUndefined = errors.New("Pagination was not defined")
+
+	// An invalid order by field was given to be converted in one of the known order by
+	// fields of the Notification DAO
+	ErrNotificationDAOOrderByFieldUnknown = errors.New("Unknown order by field")
+)
+
+const (
+	notificationDAOCollection = "notification" // Collection used to store all notifications in the MongoDB database
+)
+
+// List of possible fields that can be used to order a result set
+const (
+	NotificationDAOOrderByFieldSentAt  NotificationDAOOrderByField = 0 // Order by the date that the alert was sent
+	NotificationDAOOrderByFieldChannel NotificationDAOOrderByField = 1 // Order by the channel used to send the alert
+)
+
+// Enumerate definition for the OrderBy so that we can limit the fields that the user can
+// use in a query
+type NotificationDAOOrderByField int
+
+// Convert the NotificationDAO order by field from string into enum. If the string is
+// unknown an error will be returned. The string is case insensitive and spaces around it
+// are ignored
+func NotificationDAOOrderByFieldFromString(value string) (NotificationDAOOrderByField, error) {
+	value = strings.ToLower(value)
+	value = strings.TrimSpace(value)
+
+	switch value {
+	case "sentat":
+		return NotificationDAOOrderByFieldSentAt, nil
+	case "channel":
+		return NotificationDAOOrderByFieldChannel, nil
+	}
+
+	return NotificationDAOOrderByFieldSentAt, ErrNotificationDAOOrderByFieldUnknown
+}
+
+// Convert the NotificationDAO order by field from enum into string. If the enum is
+// unknown this method will return an empty string
+func NotificationDAOOrderByFieldToString(value NotificationDAOOrderByField) string {
+	switch value {
+	case NotificationDAOOrderByFieldSentAt:
+		return "sentat"
+
+	case NotificationDAOOrderByFieldChannel:
+		return "channel"
+	}
+
+	return ""
+}
+
+// Default values when the user don't define pagination. The notifications of a domain
+// are usually analyzed from the most recent alert to the oldest one, so the default
+// ordering is descending
+var (
+	notificationDAODefaultPaginationOrderBy = []NotificationDAOSort{
+		{
+			Field:     NotificationDAOOrderByFieldSentAt, // Default ordering is by sent date
+			Direction: DAOOrderByDirectionDescending,     // Default ordering is descending
+		},
+	}
+)
+
+func init() {
+	// Add index on FQDN, channel and sent date to speed up the retrieval of the last alert
+	// of a domain in each channel, that is checked before every new alert
+	mongodb.RegisterIndexFunction(func(database *mgo.Database) error {
+		index := mgo.Index{
+			Name: "fqdn_channel_sentat",
+			Key:  []string{"fqdn", "channel", "-sentat"},
+		}
+
+		return database.C(notificationDAOCollection).EnsureIndex(index)
+	})
+
+	// Add index on recipients and sent date to speed up the retrieval of the last digest of
+	// an owner
+	mongodb.RegisterIndexFunction(func(database *mgo.Database) error {
+		index := mgo.Index{
+			Name: "recipients_sentat",
+			Key:  []string{"recipients", "-sentat"},
+		}
+
+		return database.C(notificationDAOCollection).EnsureIndex(index)
+	})
+
+	// Add index on the end of the quiet hours to speed up the retrieval of the postponed
+	// alerts, that is checked periodically
+	mongodb.RegisterIndexFunction(func(database *mgo.Database) error {
+		index := mgo.Index{
+			Name: "postponed_postponeduntil",
+			Key:  []string{"postponed", "postponeduntil"},
+		}
+
+		return database.C(notificationDAOCollection).EnsureIndex(index)
+	})
+}
+
+// NotificationDAO is the structure responsable for keeping the database connection to
+// store the alerts sent to the domains' owners
+type NotificationDAO struct {
+	Database *mgo.Database // MongoDB Database
+}
+
+// Save the notification in the database. Notifications are never updated, they represent
+// an alert sent in the past, so the object is always inserted receiving a new id
+func (dao NotificationDAO) Save(notification *model.Notification) error {
+	// Check if the programmer forgot to set the database in NotificationDAO object
+	if dao.Database == nil {
+		return ErrNotificationDAOUndefinedDatabase
+	}
+
+	notification.Id = bson.NewObjectId()
+	return dao.Database.C(notificationDAOCollection).Insert(notification)
+}
+
+// Retrieve the alerts of the domain problems that were delivered (or postponed to be
+// delivered after the quiet hours) using the given channel after a date, the most recent
+// first. Alerts of solved problems and digests are ignored, because they don't represent
+// the immediate alert of the current problems
+func (dao NotificationDAO) FindDelivered(fqdn, channel string,
+	since time.Time) ([]model.Notification, error) {
+
+	// Check if the programmer forgot to set the database in NotificationDAO object
+	if dao.Database == nil {
+		return nil, ErrNotificationDAOUndefinedDatabase
+	}
+
+	var notifications []model.Notification
+	err := dao.Database.C(notificationDAOCollection).Find(bson.M{
+		"fqdn":    fqdn,
+		"channel": channel,
+		"$or": []bson.M{
+			{"delivered": true},
+			{"postponed": true},
+		},
+		"recovery": bson.M{"$ne": true},
+		"digest":   bson.M{"$ne": true},
+		"sentat":   bson.M{"$gt": since},
+	}).Sort("-sentat").All(&notifications)
+
+	return notifications, err
+}
+
+// Retrieve the most recent digest delivered to the recipient. If there's no digest
+// mgo.ErrNotFound is returned
+func (dao NotificationDAO) FindLastDigest(recipient string) (model.Notification, error) {
+	var notification model.Notification
+
+	// Check if the programmer forgot to set the database in NotificationDAO object
+	if dao.Database == nil {
+		return notification, ErrNotificationDAOUndefinedDatabase
+	}
+
+	err := dao.Database.C(notificationDAOCollection).Find(bson.M{
+		"recipients": recipient,
+		"digest":     true,
+		"delivered":  true,
+	}).Sort("-sentat").One(&notification)
+
+	return notification, err
+}
+
+// Retrieve the notifications of a domain using pagination control. When pagination values
+// are not informed, default values are adopted, returning the most recent alerts first
+func (dao NotificationDAO) FindByFQDN(fqdn string,
+	pagination *NotificationDAOPagination) ([]model.Notification, error) {
+
+	// Check if the programmer forgot to set the database in NotificationDAO object
+	if dao.Database == nil {
+		return nil, ErrNotificationDAOUndefinedDatabase
+	}
+
+	if pagination == nil {
+		return nil, ErrNotificationDAOPaginationUndefined
+	}
+
+	if len(pagination.OrderBy) == 0 {
+		pagination.OrderBy = notificationDAODefaultPaginationOrderBy
+	}
+
+	if pagination.PageSize == 0 {
+		pagination.PageSize = defaultPaginationPageSize
+	}
+
+	if pagination.Page == 0 {
+		pagination.Page = defaultPaginationPage
+	}
+
+	var sortList []string
+	for _, sort := range pagination.OrderBy {
+		var sortTmp string
+
+		if sort.Direction == DAOOrderByDirectionDescending {
+			sortTmp = "-"
+		}
+
+		switch sort.Field {
+		case NotificationDAOOrderByFieldSentAt:
+			sortTmp += "sentat"
+		case NotificationDAOOrderByFieldChannel:
+			sortTmp += "channel"
+		}
+
+		sortList = append(sortList, sortTmp)
+	}
+
+	query := dao.Database.C(notificationDAOCollection).Find(bson.M{
+		"fqdn": fqdn,
+	})
+
+	// We store the number of items before applying pagination, if we do this after we get
+	// only the number of items of a page size
+	var err error
+	if pagination.NumberOfItems, err = query.Count(); err != nil {
+		return nil, err
+	}
+
+	// Safety check to don't allow to set a page higher than the number of pages
+	maxNumberOfPages := pagination.NumberOfItems / pagination.PageSize
+	if pagination.NumberOfItems%pagination.PageSize > 0 {
+		maxNumberOfPages++
+	}
+
+	if maxNumberOfPages == 0 {
+		// When there's no item, we should stay on the first page (don't skip)
+		pagination.Page = 1
+
+	} else if pagination.Page > maxNumberOfPages {
+		pagination.Page = maxNumberOfPages
+	}
+
+	query.
+		Sort(sortList...).
+		Skip(pagination.PageSize * (pagination.Page - 1)).
+		Limit(pagination.PageSize)
+
+	var notifications []model.Notification
+	if err := query.All(&notifications); err != nil {
+		return nil, err
+	}
+
+	if pagination.PageSize > 0 {
+		pagination.NumberOfPages = pagination.NumberOfItems / pagination.PageSize
+		if pagination.NumberOfItems%pagination.PageSize > 0 {
+			pagination.NumberOfPages += 1
+		}
+	}
+
+	return notifications, nil
+}
+
+// Retrieve the postponed alerts that can be delivered, because the quiet hours of the
+// recipients ended before the given date
+func (dao NotificationDAO) FindPostponed(until time.Time) ([]model.Notification, error) {
+	// Check if the programmer forgot to set the database in NotificationDAO object
+	if dao.Database == nil {
+		return nil, ErrNotificationDAOUndefinedDatabase
+	}
+
+	var notifications []model.Notification
+	err := dao.Database.C(notificationDAOCollection).Find(bson.M{
+		"postponed":      true,
+		"postponeduntil": bson.M{"$lte": until},
+	}).Sort("postponeduntil").All(&notifications)
+
+	return notifications, err
+}
+
+// Remove a notification using its id. Used to remove a postponed alert after the delivery,
+// because the result of the delivery is stored in a new record
+func (dao NotificationDAO) Remove(notification *model.Notification) error {
+	// Check if the programmer forgot to set the database in NotificationDAO object
+	if dao.Database == nil {
+		return ErrNotificationDAOUndefinedDatabase
+	}
+
+	return dao.Database.C(notificationDAOCollection).RemoveId(notification.Id)
+}
+
+// Remove all notifications of a domain. Should be called when the domain is removed from
+// the system, because the alerts are useless without the domain
+func (dao NotificationDAO) RemoveByFQDN(fqdn string) error {
+	// Check if the programmer forgot to set the database in NotificationDAO object
+	if dao.Database == nil {
+		return ErrNotificationDAOUndefinedDatabase
+	}
+
+	_, err := dao.Database.C(notificationDAOCollection).RemoveAll(bson.M{
+		"fqdn": fqdn,
+	})
+
+	return err
+}
+
+// Remove all notification entries from the database. This is a DANGEROUS method, use
+// with caution. For now is used only by the integration test enviroments to clear the
+// database before starting a new test
+func (dao NotificationDAO) RemoveAll() error {
+	_, err := dao.Database.C(notificationDAOCollection).RemoveAll(bson.M{})
+	return err
+}
+
+// NotificationDAOPagination was created as a necessity for big result sets that needs
+// to be sent for an end-user. A domain with problems for a long time can receive many
+// alerts
+type NotificationDAOPagination struct {
+	OrderBy       []NotificationDAOSort // Sort the list before the pagination
+	PageSize      int                   // Number of items that are going to be considered in one page
+	Page          int                   // Current page that will be returned
+	NumberOfItems int                   // Total number of items in the result set
+	NumberOfPages int                   // Total number of pages calculated for the current result set
+}
+
+// NotificationDAOSort is an object responsable to relate the order by field and
+// direction. Each field used for sort, can be sorted in both directions
+type NotificationDAOSort struct {
+	Field     NotificationDAOOrderByField // Field to be sorted
+	Direction DAOOrderByDirection         // Direction used in the sort
+}
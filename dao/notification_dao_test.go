@@ -0,0 +1,38 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"testing"
+)
+
+func TestNotificationDAOOrderByFieldFromString(t *testing.T) {
+	if _, err := NotificationDAOOrderByFieldFromString("xxx"); err == nil {
+		t.Error("Accepting an invalid order by field")
+	}
+
+	if field, err := NotificationDAOOrderByFieldFromString("  SENTAT  "); err != nil || field != NotificationDAOOrderByFieldSentAt {
+		t.Error("Not accepting a valid order by field SentAt")
+	}
+
+	if field, err := NotificationDAOOrderByFieldFromString("Channel"); err != nil || field != NotificationDAOOrderByFieldChannel {
+		t.Error("Not accepting a valid order by field Channel")
+	}
+}
+
+func TestNotificationDAOOrderByFieldToString(t *testing.T) {
+	if field := NotificationDAOOrderByFieldToString(NotificationDAOOrderByField(9999)); len(field) > 0 {
+		t.Error("Not returning empty string when is an unknown order by field")
+	}
+
+	if field := NotificationDAOOrderByFieldToString(NotificationDAOOrderByFieldSentAt); field != "sentat" {
+		t.Error("Not returning the correct order by field for SentAt")
+	}
+
+	if field := NotificationDAOOrderByFieldToString(NotificationDAOOrderByFieldChannel); field != "channel" {
+		t.Error("Not returning the correct order by field for Channel")
+	}
+}
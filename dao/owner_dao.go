@@ -0,0 +1,221 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"errors"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2/bson"
+	"github.com/rafaeljusto/shelter/database/mongodb"
+	"github.com/rafaeljusto/shelter/model"
+	"strings"
+)
+
+// List of possible errors that can occur in the owner queries. There can be also other
+// errors from low level drivers.
+var (
+	// An invalid order by field was given to be converted in one of the known order by
+	// fields of the owner queries
+	ErrOwnerDAOOrderByFieldUnknown = errors.New("Unknown order by field")
+)
+
+// List of possible fields that can be used to order a result set
+const (
+	OwnerDAOOrderByFieldEmail   OwnerDAOOrderByField = 0 // Order by owner's e-mail
+	OwnerDAOOrderByFieldDomains OwnerDAOOrderByField = 1 // Order by the number of domains of the owner
+)
+
+// Enumerate definition for the OrderBy so that we can limit the fields that the user can
+// use in a query
+type OwnerDAOOrderByField int
+
+// Convert the owner order by field from string into enum. If the string is unknown an
+// error will be returned. The string is case insensitive and spaces around it are ignored
+func OwnerDAOOrderByFieldFromString(value string) (OwnerDAOOrderByField, error) {
+	value = strings.ToLower(value)
+	value = strings.TrimSpace(value)
+
+	switch value {
+	case "email":
+		return OwnerDAOOrderByFieldEmail, nil
+	case "domains":
+		return OwnerDAOOrderByFieldDomains, nil
+	}
+
+	return OwnerDAOOrderByFieldEmail, ErrOwnerDAOOrderByFieldUnknown
+}
+
+// Convert the owner order by field from enum into string. If the enum is unknown this
+// method will return an empty string
+func OwnerDAOOrderByFieldToString(value OwnerDAOOrderByField) string {
+	switch value {
+	case OwnerDAOOrderByFieldEmail:
+		return "email"
+
+	case OwnerDAOOrderByFieldDomains:
+		return "domains"
+	}
+
+	return ""
+}
+
+// Default values when the user don't define pagination
+var (
+	ownerDAODefaultPaginationOrderBy = []OwnerDAOSort{
+		{
+			Field:     OwnerDAOOrderByFieldEmail,    // Default ordering is by e-mail
+			Direction: DAOOrderByDirectionAscending, // Default ordering is ascending
+		},
+	}
+)
+
+func init() {
+	// Add index on the owners' e-mails to speed up the search of the domains of an owner
+	mongodb.RegisterIndexFunction(func(database *mgo.Database) error {
+		index := mgo.Index{
+			Name: "owners",
+			Key:  []string{"owners.email.address"},
+		}
+
+		return database.C(domainDAOCollection).EnsureIndex(index)
+	})
+}
+
+// Retrieve all owners of the domains using pagination control. The owners are stored
+// inside the domains, so the list is built aggregating the domains by the owners'
+// e-mails. The filter is a regular expression (case insensitive) applied over the e-mail
+func (dao DomainDAO) FindOwners(pagination *OwnerDAOPagination, filter string) ([]model.OwnerSummary, error) {
+	// Check if the programmer forgot to set the database in DomainDAO object
+	if dao.Database == nil {
+		return nil, ErrDomainDAOUndefinedDatabase
+	}
+
+	if pagination == nil {
+		return nil, ErrDomainDAOPaginationUndefined
+	}
+
+	pagination.defaults()
+
+	pipeline := []bson.M{
+		{"$unwind": "$owners"},
+	}
+
+	if len(filter) > 0 {
+		pipeline = append(pipeline, bson.M{
+			"$match": bson.M{
+				"owners.email.address": bson.RegEx{Pattern: filter, Options: "i"},
+			},
+		})
+	}
+
+	pipeline = append(pipeline, bson.M{
+		"$group": bson.M{
+			"_id":            "$owners.email.address",
+			"domains":        bson.M{"$sum": 1},
+			"lastmodifiedat": bson.M{"$max": "$lastmodifiedat"},
+		},
+	})
+
+	// We store the number of items before applying pagination, if we do this after we get
+	// only the number of items of a page size
+	var count struct {
+		Count int
+	}
+
+	countPipeline := append([]bson.M{}, pipeline...)
+	countPipeline = append(countPipeline, bson.M{
+		"$group": bson.M{
+			"_id":   nil,
+			"count": bson.M{"$sum": 1},
+		},
+	})
+
+	err := dao.Database.C(domainDAOCollection).Pipe(countPipeline).One(&count)
+	if err != nil && err != mgo.ErrNotFound {
+		return nil, err
+	}
+
+	var start int
+	pagination.NumberOfItems = count.Count
+	pagination.Page, pagination.NumberOfPages, start, _ =
+		paginate(pagination.NumberOfItems, pagination.PageSize, pagination.Page)
+
+	sort := bson.D{}
+	for _, orderBy := range pagination.OrderBy {
+		var field string
+
+		switch orderBy.Field {
+		case OwnerDAOOrderByFieldEmail:
+			field = "_id"
+		case OwnerDAOOrderByFieldDomains:
+			field = "domains"
+		}
+
+		sort = append(sort, bson.DocElem{Name: field, Value: int(orderBy.Direction)})
+	}
+
+	pipeline = append(pipeline,
+		bson.M{"$sort": sort},
+		bson.M{"$skip": start},
+		bson.M{"$limit": pagination.PageSize},
+	)
+
+	var owners []model.OwnerSummary
+	if err := dao.Database.C(domainDAOCollection).Pipe(pipeline).All(&owners); err != nil {
+		return nil, err
+	}
+
+	return owners, nil
+}
+
+// Retrieve all domains that have the owner identified by the e-mail address, sorted by
+// FQDN. The database should be prepared (with indexes) to search faster when using the
+// owners' e-mails as condition
+func (dao DomainDAO) FindByOwner(email string) ([]model.Domain, error) {
+	// Check if the programmer forgot to set the database in DomainDAO object
+	if dao.Database == nil {
+		return nil, ErrDomainDAOUndefinedDatabase
+	}
+
+	var domains []model.Domain
+	err := dao.Database.C(domainDAOCollection).Find(bson.M{
+		"owners.email.address": email,
+	}).Sort("fqdn").All(&domains)
+
+	return domains, err
+}
+
+// OwnerDAOPagination controls the size of the owners' list sent to the end-user, as it
+// works with the domains' list
+type OwnerDAOPagination struct {
+	OrderBy       []OwnerDAOSort // Sort the list before the pagination
+	PageSize      int            // Number of items that are going to be considered in one page
+	Page          int            // Current page that will be returned
+	NumberOfItems int            // Total number of items in the result set
+	NumberOfPages int            // Total number of pages calculated for the current result set
+}
+
+// Fill the pagination attributes that weren't informed by the user
+func (p *OwnerDAOPagination) defaults() {
+	if len(p.OrderBy) == 0 {
+		p.OrderBy = ownerDAODefaultPaginationOrderBy
+	}
+
+	if p.PageSize == 0 {
+		p.PageSize = defaultPaginationPageSize
+	}
+
+	if p.Page == 0 {
+		p.Page = defaultPaginationPage
+	}
+}
+
+// OwnerDAOSort is an object responsable to relate the order by field and direction. Each
+// field used for sort, can be sorted in both directions
+type OwnerDAOSort struct {
+	Field     OwnerDAOOrderByField // Field to be sorted
+	Direction DAOOrderByDirection  // Direction used in the sort
+}
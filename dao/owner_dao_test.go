@@ -0,0 +1,38 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"testing"
+)
+
+func TestOwnerDAOOrderByFieldFromString(t *testing.T) {
+	if _, err := OwnerDAOOrderByFieldFromString("xxx"); err == nil {
+		t.Error("Accepting an invalid order by field")
+	}
+
+	if field, err := OwnerDAOOrderByFieldFromString("  EMAIL  "); err != nil || field != OwnerDAOOrderByFieldEmail {
+		t.Error("Not accepting a valid order by field Email")
+	}
+
+	if field, err := OwnerDAOOrderByFieldFromString("domains"); err != nil || field != OwnerDAOOrderByFieldDomains {
+		t.Error("Not accepting a valid order by field Domains")
+	}
+}
+
+func TestOwnerDAOOrderByFieldToString(t *testing.T) {
+	if field := OwnerDAOOrderByFieldToString(OwnerDAOOrderByField(9999)); len(field) > 0 {
+		t.Error("Not returning empty string when is an unknown order by field")
+	}
+
+	if field := OwnerDAOOrderByFieldToString(OwnerDAOOrderByFieldEmail); field != "email" {
+		t.Error("Not returning the correct order by field for Email")
+	}
+
+	if field := OwnerDAOOrderByFieldToString(OwnerDAOOrderByFieldDomains); field != "domains" {
+		t.Error("Not returning the correct order by field for Domains")
+	}
+}
@@ -261,16 +261,8 @@ func (dao ScanDAO) FindAll(pagination *ScanDAOPagination, expand bool) ([]model.
 		}
 	}
 
-	// When the expand flag if not defined, we should compress the scan object so the
-	// network data isn't too big. For now the compressed object will have the start date
-	// and the last modification date
 	if !expand {
-		for i := range scans {
-			scans[i] = model.Scan{
-				StartedAt:      scans[i].StartedAt,
-				LastModifiedAt: scans[i].LastModifiedAt,
-			}
-		}
+		compressScans(scans)
 	}
 
 	return scans, nil
@@ -301,6 +293,18 @@ func (dao ScanDAO) RemoveAll() error {
 	return err
 }
 
+// When the expand flag if not defined, we should compress the scan object so the network
+// data isn't too big. For now the compressed object will have the start date and the
+// last modification date
+func compressScans(scans []model.Scan) {
+	for i := range scans {
+		scans[i] = model.Scan{
+			StartedAt:      scans[i].StartedAt,
+			LastModifiedAt: scans[i].LastModifiedAt,
+		}
+	}
+}
+
 // ScanDAOPagination was created as a necessity for big result sets that needs to be
 // sent for an end-user. With pagination we can control the size of the data and make it
 // faster for the user to interact with it in a web interface as example
@@ -0,0 +1,149 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dao manage the objects persistence layer
+package dao
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/database/file"
+	"github.com/rafaeljusto/shelter/model"
+	"time"
+)
+
+// DomainStorage defines the operations that a persistence backend must support to store
+// the domain objects. The system should always use this interface instead of a specific
+// implementation, so that the backend can be changed in the configuration file
+type DomainStorage interface {
+	Save(domain *model.Domain) error
+	SaveMany(domains []*model.Domain) []DomainResult
+	FindAll(pagination *DomainDAOPagination, expand bool, filter DomainDAOFilter) ([]model.Domain, error)
+	FindAllAsync() (chan DomainResult, error)
+	FindAllAsyncToBeNotified(nameserverErrorAlertDays, nameserverTimeoutAlertDays,
+		dsErrorAlertDays, dsTimeoutAlertDays, maxExpirationAlertDays int) (chan DomainResult, error)
+	FindByFQDN(fqdn string) (model.Domain, error)
+	FindByFQDNs(fqdns []string) ([]model.Domain, error)
+	FindOwners(pagination *OwnerDAOPagination, filter string) ([]model.OwnerSummary, error)
+	FindByOwner(email string) ([]model.Domain, error)
+	Remove(domain *model.Domain) error
+	RemoveByFQDN(fqdn string) error
+	RemoveMany(domains []*model.Domain) []DomainResult
+	RemoveAll() error
+}
+
+// ScanStorage defines the operations that a persistence backend must support to store the
+// scan objects
+type ScanStorage interface {
+	Save(scan *model.Scan) error
+	FindByStartedAt(startedAt time.Time) (model.Scan, error)
+	FindAll(pagination *ScanDAOPagination, expand bool) ([]model.Scan, error)
+	RemoveByStartedAt(startedAt time.Time) error
+	RemoveAll() error
+}
+
+// AuditStorage defines the operations that a persistence backend must support to store
+// the audit log of the domains' changes
+type AuditStorage interface {
+	Save(entry *model.AuditEntry) error
+	FindAll(filter AuditDAOFilter, pagination *AuditDAOPagination) ([]model.AuditEntry, error)
+	RemoveAll() error
+}
+
+// DomainRevisionStorage defines the operations that a persistence backend must support to
+// store the configuration revisions of the domains
+type DomainRevisionStorage interface {
+	Save(revision *model.DomainRevision) error
+	FindByFQDN(fqdn string, pagination *DomainRevisionDAOPagination) ([]model.DomainRevision, error)
+	FindByRevision(fqdn string, revision int) (model.DomainRevision, error)
+	FindLast(fqdn string) (model.DomainRevision, error)
+	RemoveByFQDN(fqdn string) error
+	RemoveAll() error
+}
+
+// NotificationStorage defines the operations that a persistence backend must support to
+// store the alerts sent to the domains' owners
+type NotificationStorage interface {
+	Save(notification *model.Notification) error
+	FindDelivered(fqdn, channel string, since time.Time) ([]model.Notification, error)
+	FindLastDigest(recipient string) (model.Notification, error)
+	FindByFQDN(fqdn string, pagination *NotificationDAOPagination) ([]model.Notification, error)
+	FindPostponed(until time.Time) ([]model.Notification, error)
+	Remove(notification *model.Notification) error
+	RemoveByFQDN(fqdn string) error
+	RemoveAll() error
+}
+
+// Storage is a persistence backend of the system. It builds the DAOs of the main objects
+// (domains, scans, audit log, revisions and notifications) for the selected backend.
+// Other objects (domain history and nameserver summaries) are only persisted in MongoDB
+// for now
+type Storage interface {
+	DomainDAO() DomainStorage
+	ScanDAO() ScanStorage
+	AuditDAO() AuditStorage
+	DomainRevisionDAO() DomainRevisionStorage
+	NotificationDAO() NotificationStorage
+}
+
+// MongoDBStorage is the persistence backend that uses a MongoDB database. It's the
+// recommended backend for big installations
+type MongoDBStorage struct {
+	Database *mgo.Database // MongoDB Database
+}
+
+// DomainDAO returns the domain persistence layer over MongoDB
+func (s MongoDBStorage) DomainDAO() DomainStorage {
+	return DomainDAO{Database: s.Database}
+}
+
+// ScanDAO returns the scan persistence layer over MongoDB
+func (s MongoDBStorage) ScanDAO() ScanStorage {
+	return ScanDAO{Database: s.Database}
+}
+
+// AuditDAO returns the audit log persistence layer over MongoDB
+func (s MongoDBStorage) AuditDAO() AuditStorage {
+	return AuditDAO{Database: s.Database}
+}
+
+// DomainRevisionDAO returns the domain revision persistence layer over MongoDB
+func (s MongoDBStorage) DomainRevisionDAO() DomainRevisionStorage {
+	return DomainRevisionDAO{Database: s.Database}
+}
+
+// NotificationDAO returns the notification persistence layer over MongoDB
+func (s MongoDBStorage) NotificationDAO() NotificationStorage {
+	return NotificationDAO{Database: s.Database}
+}
+
+// FileStorage is the persistence backend that stores everything in a local file. It's
+// useful for small registries that don't want to maintain a MongoDB cluster
+type FileStorage struct {
+	Database *file.Database // Embedded file database
+}
+
+// DomainDAO returns the domain persistence layer over the local file
+func (s FileStorage) DomainDAO() DomainStorage {
+	return FileDomainDAO{Database: s.Database}
+}
+
+// ScanDAO returns the scan persistence layer over the local file
+func (s FileStorage) ScanDAO() ScanStorage {
+	return FileScanDAO{Database: s.Database}
+}
+
+// AuditDAO returns the audit log persistence layer over the local file
+func (s FileStorage) AuditDAO() AuditStorage {
+	return FileAuditDAO{Database: s.Database}
+}
+
+// DomainRevisionDAO returns the domain revision persistence layer over the local file
+func (s FileStorage) DomainRevisionDAO() DomainRevisionStorage {
+	return FileDomainRevisionDAO{Database: s.Database}
+}
+
+// NotificationDAO returns the notification persistence layer over the local file
+func (s FileStorage) NotificationDAO() NotificationStorage {
+	return FileNotificationDAO{Database: s.Database}
+}
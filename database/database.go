@@ -0,0 +1,59 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package database opens the persistence backend selected in the configuration
+package database
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/config"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/database/file"
+	"github.com/rafaeljusto/shelter/database/mongodb"
+	"github.com/rafaeljusto/shelter/log"
+	"path/filepath"
+)
+
+// Open the persistence backend defined in the configuration file. When the backend is
+// MongoDB the database and session are also returned, because some objects are only
+// persisted there, for other backends they will be nil. The caller must close the session
+// after use when it isn't nil
+func Open() (dao.Storage, *mgo.Database, *mgo.Session, error) {
+	if config.ShelterConfig.Database.Backend == config.DatabaseBackendFile {
+		path := filepath.Join(
+			config.ShelterConfig.BasePath,
+			config.ShelterConfig.Database.Path,
+		)
+
+		log.Debugf("Initializing file database with the parameters: Path - %s", path)
+
+		database, err := file.Open(path)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		return dao.FileStorage{Database: database}, nil, nil, nil
+	}
+
+	log.Debugf("Initializing database with the parameters: URIS - %v | Name - %s | Auth - %t | Username - %s",
+		config.ShelterConfig.Database.URIs,
+		config.ShelterConfig.Database.Name,
+		config.ShelterConfig.Database.Auth.Enabled,
+		config.ShelterConfig.Database.Auth.Username,
+	)
+
+	database, databaseSession, err := mongodb.Open(
+		config.ShelterConfig.Database.URIs,
+		config.ShelterConfig.Database.Name,
+		config.ShelterConfig.Database.Auth.Enabled,
+		config.ShelterConfig.Database.Auth.Username,
+		config.ShelterConfig.Database.Auth.Password,
+	)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return dao.MongoDBStorage{Database: database}, database, databaseSession, nil
+}
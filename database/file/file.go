@@ -0,0 +1,311 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package file is an embedded document database persisted in a single file
+package file
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	// ErrNotFound is returned when the document doesn't exist in the collection
+	ErrNotFound = errors.New("Document not found")
+
+	// List of databases already opened. As the whole file is loaded in memory we must
+	// share the same object between all the callers that use the same path, otherwise one
+	// caller would overwrite the changes of the other
+	databases     map[string]*Database
+	databasesLock sync.Mutex
+)
+
+func init() {
+	databases = make(map[string]*Database)
+}
+
+// Database keeps all collections in memory and rewrites the file on every change (or
+// batch of changes). The documents are stored in JSON format, indexed by a key defined by
+// the caller. This was created for small installations that don't want to maintain a
+// MongoDB cluster, so it wasn't designed to store millions of documents
+type Database struct {
+	path        string                                // File where the collections are persisted
+	collections map[string]map[string]json.RawMessage // Documents of each collection indexed by key
+	lock        sync.RWMutex                          // Control concurrent access to the collections
+}
+
+// Open loads the database from the given file. If the file doesn't exist it will be
+// created on the first change (with the parent directories). Opening the same path more
+// than once returns the same object
+func Open(path string) (*Database, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	databasesLock.Lock()
+	defer databasesLock.Unlock()
+
+	if database, found := databases[path]; found {
+		return database, nil
+	}
+
+	database := &Database{
+		path:        path,
+		collections: make(map[string]map[string]json.RawMessage),
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err == nil {
+		if err := json.Unmarshal(content, &database.collections); err != nil {
+			return nil, err
+		}
+
+	} else if os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, err
+		}
+
+	} else {
+		return nil, err
+	}
+
+	databases[path] = database
+	return database, nil
+}
+
+// Get retrieves a document from a collection, filling the given object
+func (d *Database) Get(collection, key string, document interface{}) error {
+	d.lock.RLock()
+	content, found := d.collections[collection][key]
+	d.lock.RUnlock()
+
+	if !found {
+		return ErrNotFound
+	}
+
+	return json.Unmarshal(content, document)
+}
+
+// Update replaces a document of a collection. The update function receives the current
+// content of the document (nil when it doesn't exist) and returns the new document to be
+// stored. This is useful to check the document before replacing it without the risk of
+// another caller changing it in the middle of the operation
+func (d *Database) Update(collection, key string,
+	update func(current []byte) (interface{}, error)) error {
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	document, err := update(d.collections[collection][key])
+	if err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+
+	previous := d.backup(collection)
+
+	if _, found := d.collections[collection]; !found {
+		d.collections[collection] = make(map[string]json.RawMessage)
+	}
+
+	d.collections[collection][key] = content
+	return d.commit(collection, previous)
+}
+
+// UpdateMany replaces many documents of a collection writing the file only once, useful
+// to store a batch of documents without rewriting the whole file for each one. The update
+// function works as in Update, receiving the position of the key in the list. The errors
+// are returned in the same order of the keys, and when the file can't be written all
+// changed documents receive the error
+func (d *Database) UpdateMany(collection string, keys []string,
+	update func(index int, current []byte) (interface{}, error)) []error {
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	errs := make([]error, len(keys))
+	changed := false
+	previous := d.backup(collection)
+
+	for index, key := range keys {
+		document, err := update(index, d.collections[collection][key])
+		if err != nil {
+			errs[index] = err
+			continue
+		}
+
+		content, err := json.Marshal(document)
+		if err != nil {
+			errs[index] = err
+			continue
+		}
+
+		if _, found := d.collections[collection]; !found {
+			d.collections[collection] = make(map[string]json.RawMessage)
+		}
+
+		d.collections[collection][key] = content
+		changed = true
+	}
+
+	if !changed {
+		return errs
+	}
+
+	if err := d.commit(collection, previous); err != nil {
+		for index := range errs {
+			if errs[index] == nil {
+				errs[index] = err
+			}
+		}
+	}
+
+	return errs
+}
+
+// Put stores a document in a collection, replacing the old one if it exists
+func (d *Database) Put(collection, key string, document interface{}) error {
+	return d.Update(collection, key, func(current []byte) (interface{}, error) {
+		return document, nil
+	})
+}
+
+// Remove a document from a collection
+func (d *Database) Remove(collection, key string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if _, found := d.collections[collection][key]; !found {
+		return ErrNotFound
+	}
+
+	previous := d.backup(collection)
+	delete(d.collections[collection], key)
+	return d.commit(collection, previous)
+}
+
+// RemoveMany removes many documents from a collection writing the file only once. The
+// errors are returned in the same order of the keys
+func (d *Database) RemoveMany(collection string, keys []string) []error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	errs := make([]error, len(keys))
+	changed := false
+	previous := d.backup(collection)
+
+	for index, key := range keys {
+		if _, found := d.collections[collection][key]; !found {
+			errs[index] = ErrNotFound
+			continue
+		}
+
+		delete(d.collections[collection], key)
+		changed = true
+	}
+
+	if !changed {
+		return errs
+	}
+
+	if err := d.commit(collection, previous); err != nil {
+		for index := range errs {
+			if errs[index] == nil {
+				errs[index] = err
+			}
+		}
+	}
+
+	return errs
+}
+
+// RemoveAll removes all documents from a collection
+func (d *Database) RemoveAll(collection string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	previous := d.backup(collection)
+	delete(d.collections, collection)
+	return d.commit(collection, previous)
+}
+
+// ForEach walks through all documents of a collection. The documents are given in JSON
+// format, and the caller is responsable for decoding them. The walk stops on the first
+// error returned by the function. The collection must not be changed inside the function,
+// or we will get a deadlock
+func (d *Database) ForEach(collection string, function func(key string, content []byte) error) error {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	for key, content := range d.collections[collection] {
+		if err := function(key, content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Copy the documents of a collection before changing it, so that the changes can be
+// undone when the file can't be written. The stored documents are never changed in
+// place, so we don't need to copy their content. Returns nil when the collection doesn't
+// exist. The caller must hold the write lock
+func (d *Database) backup(collection string) map[string]json.RawMessage {
+	documents, found := d.collections[collection]
+	if !found {
+		return nil
+	}
+
+	previous := make(map[string]json.RawMessage, len(documents))
+	for key, content := range documents {
+		previous[key] = content
+	}
+
+	return previous
+}
+
+// Write all collections into the file. When the file can't be written the changed
+// collection goes back to the previous documents, so that we don't return or persist
+// later a change that the caller believes that failed. The caller must hold the write
+// lock
+func (d *Database) commit(collection string, previous map[string]json.RawMessage) error {
+	err := d.flush()
+	if err == nil {
+		return nil
+	}
+
+	if previous == nil {
+		delete(d.collections, collection)
+	} else {
+		d.collections[collection] = previous
+	}
+
+	return err
+}
+
+// Write all collections into the file. To avoid a corrupted file when the system stops
+// in the middle of the operation, we write to a temporary file and rename it. The caller
+// must hold the write lock
+func (d *Database) flush() error {
+	content, err := json.Marshal(d.collections)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := d.path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, content, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, d.path)
+}
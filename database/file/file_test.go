@@ -0,0 +1,230 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package file is an embedded document database persisted in a single file
+package file
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type document struct {
+	Name  string
+	Value int
+}
+
+func TestDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shelter-file-database")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "shelter.db")
+
+	database, err := Open(path)
+	if err != nil {
+		t.Fatal("Error opening a new database. Details:", err)
+	}
+
+	if otherDatabase, err := Open(path); err != nil || otherDatabase != database {
+		t.Error("Not reusing the database already opened")
+	}
+
+	if err := database.Get("test", "a", new(document)); err != ErrNotFound {
+		t.Error("Not returning not found for an unknown document")
+	}
+
+	if err := database.Put("test", "a", document{Name: "a", Value: 1}); err != nil {
+		t.Fatal("Error storing a document. Details:", err)
+	}
+
+	if err := database.Put("test", "b", document{Name: "b", Value: 2}); err != nil {
+		t.Fatal("Error storing a document. Details:", err)
+	}
+
+	var doc document
+	if err := database.Get("test", "a", &doc); err != nil || doc.Name != "a" || doc.Value != 1 {
+		t.Error("Not retrieving the stored document")
+	}
+
+	errConflict := errors.New("conflict")
+	err = database.Update("test", "a", func(current []byte) (interface{}, error) {
+		if current == nil {
+			t.Error("Not sending the current document in the update")
+		}
+		return nil, errConflict
+	})
+
+	if err != errConflict {
+		t.Error("Not returning the update function error")
+	}
+
+	// Simulate a restart of the system, loading the data from the file
+	delete(databases, path)
+
+	database, err = Open(path)
+	if err != nil {
+		t.Fatal("Error loading the database from the file. Details:", err)
+	}
+
+	total := 0
+	database.ForEach("test", func(key string, content []byte) error {
+		total++
+		return nil
+	})
+
+	if total != 2 {
+		t.Errorf("Not persisting the documents in the file. Expected 2 and got %d", total)
+	}
+
+	if err := database.Remove("test", "a"); err != nil {
+		t.Error("Error removing a document. Details:", err)
+	}
+
+	if err := database.Remove("test", "a"); err != ErrNotFound {
+		t.Error("Not returning not found when removing an unknown document")
+	}
+
+	if err := database.RemoveAll("test"); err != nil {
+		t.Error("Error removing all documents. Details:", err)
+	}
+
+	if err := database.Get("test", "b", &doc); err != ErrNotFound {
+		t.Error("Not removing all documents of the collection")
+	}
+}
+
+func TestDatabaseMany(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shelter-file-database")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "shelter.db")
+
+	database, err := Open(path)
+	if err != nil {
+		t.Fatal("Error opening a new database. Details:", err)
+	}
+
+	errConflict := errors.New("conflict")
+	keys := []string{"a", "b", "c"}
+
+	errs := database.UpdateMany("test", keys, func(index int, current []byte) (interface{}, error) {
+		if index == 1 {
+			return nil, errConflict
+		}
+		return document{Name: keys[index], Value: index}, nil
+	})
+
+	if len(errs) != 3 || errs[0] != nil || errs[1] != errConflict || errs[2] != nil {
+		t.Errorf("Not returning the errors of each document. Got %v", errs)
+	}
+
+	// Simulate a restart of the system, loading the data from the file
+	delete(databases, path)
+
+	database, err = Open(path)
+	if err != nil {
+		t.Fatal("Error loading the database from the file. Details:", err)
+	}
+
+	var doc document
+	if err := database.Get("test", "c", &doc); err != nil || doc.Value != 2 {
+		t.Error("Not persisting the documents of the batch in the file")
+	}
+
+	if err := database.Get("test", "b", &doc); err != ErrNotFound {
+		t.Error("Storing a document that had an error in the batch")
+	}
+
+	errs = database.RemoveMany("test", keys)
+	if len(errs) != 3 || errs[0] != nil || errs[1] != ErrNotFound || errs[2] != nil {
+		t.Errorf("Not returning the errors of each removed document. Got %v", errs)
+	}
+
+	if err := database.Get("test", "a", &doc); err != ErrNotFound {
+		t.Error("Not removing the documents of the batch")
+	}
+}
+
+func TestDatabaseFlushError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shelter-file-database")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "shelter.db")
+
+	database, err := Open(path)
+	if err != nil {
+		t.Fatal("Error opening a new database. Details:", err)
+	}
+
+	if err := database.Put("test", "a", document{Name: "a", Value: 1}); err != nil {
+		t.Fatal("Error storing a document. Details:", err)
+	}
+
+	// A directory in the place of the temporary file makes the file unwritable, even for
+	// the root user
+	if err := os.Mkdir(path+".tmp", 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.Put("test", "a", document{Name: "a", Value: 2}); err == nil {
+		t.Error("Not returning the error when the file can't be written")
+	}
+
+	if err := database.Put("test", "b", document{Name: "b", Value: 1}); err == nil {
+		t.Error("Not returning the error when the file can't be written")
+	}
+
+	if err := database.Put("other", "a", document{Name: "a", Value: 1}); err == nil {
+		t.Error("Not returning the error when the file can't be written")
+	}
+
+	errs := database.UpdateMany("test", []string{"c", "d"}, func(index int, current []byte) (interface{}, error) {
+		return document{Name: "many", Value: index}, nil
+	})
+
+	for _, err := range errs {
+		if err == nil {
+			t.Error("Not returning the error when the file can't be written")
+		}
+	}
+
+	if err := database.Remove("test", "a"); err == nil {
+		t.Error("Not returning the error when the file can't be written")
+	}
+
+	if errs := database.RemoveMany("test", []string{"a"}); errs[0] == nil {
+		t.Error("Not returning the error when the file can't be written")
+	}
+
+	if err := database.RemoveAll("test"); err == nil {
+		t.Error("Not returning the error when the file can't be written")
+	}
+
+	var stored document
+	if err := database.Get("test", "a", &stored); err != nil || stored.Value != 1 {
+		t.Error("Not restoring the document after a failed write")
+	}
+
+	for _, key := range []string{"b", "c", "d"} {
+		if err := database.Get("test", key, new(document)); err != ErrNotFound {
+			t.Errorf("Keeping the document %s of a failed write", key)
+		}
+	}
+
+	if err := database.Get("other", "a", new(document)); err != ErrNotFound {
+		t.Error("Keeping the collection of a failed write")
+	}
+}
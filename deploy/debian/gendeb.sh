@@ -70,6 +70,7 @@ mkdir -p $tmp_dir$install_path
 mkdir -p $tmp_dir$install_path/bin
 mkdir -p $tmp_dir$install_path/etc
 mkdir -p $tmp_dir$install_path/var/log
+mkdir -p $tmp_dir$install_path/var/db
 
 cp -r $workspace/templates $project_root/
 cp $workspace/etc/messages.conf $project_root/etc/
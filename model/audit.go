@@ -0,0 +1,161 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package model describes the objects of the system
+package model
+
+import (
+	"fmt"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2/bson"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuditEntry stores a change in the configuration of a domain made through the REST
+// server. The entries are never updated or removed, even when the domain is removed, so
+// it's possible to know who changed a domain, from where and what was the previous state
+type AuditEntry struct {
+	Id            bson.ObjectId `bson:"_id"` // Database identification
+	FQDN          string        // Domain name that was changed
+	Principal     string        // Secret id that signed the request
+	ClientAddress string        // IP address of the client that sent the request
+	Method        string        // HTTP method of the request
+	Date          time.Time     // Date and time of the change
+	Before        *Domain       // State of the domain before the change, nil when created
+	After         *Domain       // State of the domain after the change, nil when removed
+	Changes       []AuditChange // Configuration items that changed
+}
+
+// AuditChange represents one configuration item of the domain that was added, removed or
+// changed. The values are in text format for easy interpretation
+type AuditChange struct {
+	Field  string // Changed item, like "nameserver ns1.example.com.br."
+	Before string // Value before the change, empty when the item was added
+	After  string // Value after the change, empty when the item was removed
+}
+
+// NewAuditEntry builds the audit entry of a domain change, comparing the states before
+// and after the change. The domain was created when there's no previous state and was
+// removed when there's no state after. The request information must be filled by the
+// caller
+func NewAuditEntry(before, after *Domain) AuditEntry {
+	entry := AuditEntry{
+		Date: time.Now(),
+	}
+
+	// Copy the domains, because they can be changed by the caller after the audit entry
+	// is created
+	if before != nil {
+		domain := *before
+		entry.Before = &domain
+		entry.FQDN = before.FQDN
+	}
+
+	if after != nil {
+		domain := *after
+		entry.After = &domain
+		entry.FQDN = after.FQDN
+	}
+
+	entry.Changes = diffDomains(before, after)
+	return entry
+}
+
+// diffDomains compares only the configuration of the domains, ignoring the results of
+// the checks. Nameservers are identified by the host, DS records by the keytag and owners
+// by the e-mail address
+func diffDomains(before, after *Domain) []AuditChange {
+	var changes []AuditChange
+
+	beforeItems, beforeOrder := domainConfigItems(before)
+	afterItems, afterOrder := domainConfigItems(after)
+
+	for _, field := range beforeOrder {
+		if beforeItems[field] != afterItems[field] {
+			changes = append(changes, AuditChange{
+				Field:  field,
+				Before: beforeItems[field],
+				After:  afterItems[field],
+			})
+		}
+	}
+
+	for _, field := range afterOrder {
+		if _, ok := beforeItems[field]; !ok {
+			changes = append(changes, AuditChange{
+				Field: field,
+				After: afterItems[field],
+			})
+		}
+	}
+
+	return changes
+}
+
+// domainConfigItems converts each configuration item of the domain into text, identified
+// by the field name. The order of the items is also returned, so that the changes follow
+// the same order of the domain object
+func domainConfigItems(domain *Domain) (map[string]string, []string) {
+	items := make(map[string]string)
+	var order []string
+
+	if domain == nil {
+		return items, order
+	}
+
+	add := func(field, value string) {
+		if _, ok := items[field]; !ok {
+			order = append(order, field)
+		}
+		items[field] = value
+	}
+
+	for _, nameserver := range domain.Nameservers {
+		value := nameserver.Host
+		if nameserver.IPv4 != nil {
+			value += " " + nameserver.IPv4.String()
+		}
+		if nameserver.IPv6 != nil {
+			value += " " + nameserver.IPv6.String()
+		}
+
+		add("nameserver "+nameserver.Host, value)
+	}
+
+	for _, ds := range domain.DSSet {
+		keytag := strconv.Itoa(int(ds.Keytag))
+		add("ds "+keytag, fmt.Sprintf("%s %d %d %s", keytag, ds.Algorithm, ds.DigestType, ds.Digest))
+	}
+
+	for _, owner := range domain.Owners {
+		if owner.Email == nil {
+			continue
+		}
+
+		value := []string{owner.Email.Address, owner.Language, DigestToString(owner.Digest)}
+
+		if len(owner.Webhook) > 0 {
+			value = append(value, owner.Webhook)
+		}
+
+		if len(owner.ProblemTypes) > 0 {
+			var problemTypes []string
+			for _, problemType := range owner.ProblemTypes {
+				problemTypes = append(problemTypes, ProblemTypeToString(problemType))
+			}
+			value = append(value, strings.Join(problemTypes, ","))
+		}
+
+		if owner.QuietHours.Enabled() {
+			value = append(value, fmt.Sprintf("%02d:%02d-%02d:%02d",
+				owner.QuietHours.Start/60, owner.QuietHours.Start%60,
+				owner.QuietHours.End/60, owner.QuietHours.End%60))
+		}
+
+		add("owner "+owner.Email.Address, strings.Join(value, " "))
+	}
+
+	return items, order
+}
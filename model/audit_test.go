@@ -0,0 +1,91 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package model describes the objects of the system
+package model
+
+import (
+	"net"
+	"net/mail"
+	"testing"
+)
+
+func TestNewAuditEntry(t *testing.T) {
+	before := Domain{
+		FQDN: "example.com.br.",
+		Nameservers: []Nameserver{
+			{Host: "ns1.example.com.br.", IPv4: net.ParseIP("127.0.0.1")},
+			{Host: "ns2.example.net.", LastStatus: NameserverStatusTimeout},
+		},
+		DSSet: []DS{
+			{Keytag: 1234, Algorithm: DSAlgorithmRSASHA1, DigestType: DSDigestTypeSHA1, Digest: "EAA0978F38879DB70A53F9FF1ACF21D046A98B5C"},
+		},
+		Owners: []Owner{
+			{Email: &mail.Address{Address: "alice@example.com.br"}, Language: "pt-BR"},
+		},
+	}
+
+	after := before
+	after.Nameservers = []Nameserver{
+		{Host: "ns1.example.com.br.", IPv4: net.ParseIP("127.0.0.2")},
+		{Host: "ns2.example.net.", LastStatus: NameserverStatusOK},
+		{Host: "ns3.example.net."},
+	}
+	after.DSSet = nil
+
+	entry := NewAuditEntry(&before, &after)
+
+	if entry.FQDN != "example.com.br." || entry.Before == nil || entry.After == nil {
+		t.Fatal("Not storing the domain states in the audit entry")
+	}
+
+	expected := []AuditChange{
+		{
+			Field:  "nameserver ns1.example.com.br.",
+			Before: "ns1.example.com.br. 127.0.0.1",
+			After:  "ns1.example.com.br. 127.0.0.2",
+		},
+		{
+			Field:  "ds 1234",
+			Before: "1234 5 1 EAA0978F38879DB70A53F9FF1ACF21D046A98B5C",
+		},
+		{
+			Field: "nameserver ns3.example.net.",
+			After: "ns3.example.net.",
+		},
+	}
+
+	if len(entry.Changes) != len(expected) {
+		t.Fatalf("Wrong number of changes. Expected %d and got %d: %v",
+			len(expected), len(entry.Changes), entry.Changes)
+	}
+
+	for i, change := range entry.Changes {
+		if change != expected[i] {
+			t.Errorf("Wrong change %d. Expected %v and got %v", i, expected[i], change)
+		}
+	}
+
+	// The audit entry must keep the state of the domain in the moment of the change
+	after.FQDN = "changed.com.br."
+	if entry.After.FQDN != "example.com.br." {
+		t.Error("Audit entry is sharing the domain object with the caller")
+	}
+
+	entry = NewAuditEntry(nil, &before)
+	if entry.Before != nil || len(entry.Changes) != 4 {
+		t.Errorf("Not auditing a domain creation properly: %v", entry.Changes)
+	}
+
+	entry = NewAuditEntry(&before, nil)
+	if entry.After != nil || entry.FQDN != "example.com.br." || len(entry.Changes) != 4 {
+		t.Errorf("Not auditing a domain removal properly: %v", entry.Changes)
+	}
+
+	for _, change := range entry.Changes {
+		if len(change.After) > 0 {
+			t.Errorf("Removal with a value after the change: %v", change)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package model describes the objects of the system
+package model
+
+import (
+	"time"
+)
+
+// List of possible compliance status
+const (
+	ComplianceStatusNotChecked        = iota // Compliance test not executed yet
+	ComplianceStatusOK                       // Nameserver answered the test correctly
+	ComplianceStatusTimeout                  // Network timeout while waiting for the test response
+	ComplianceStatusConnectionRefused        // Connection refused by firewall or nameserver
+	ComplianceStatusNoEDNS                   // Nameserver ignored or rejected the EDNS0 query
+	ComplianceStatusNoDO                     // Nameserver did not set the DNSSEC OK bit in the response
+	ComplianceStatusOptionEcho               // Nameserver echoed an unknown EDNS0 option
+	ComplianceStatusBadRcode                 // Nameserver answered with an unexpected response code
+	ComplianceStatusError                    // Generic error found in the test
+)
+
+// ComplianceStatus is a number that represents one of the possible compliance status
+// listed in the constant group above
+type ComplianceStatus int
+
+// Convert the compliance status enum to text for printing in reports or debugging
+func ComplianceStatusToString(status ComplianceStatus) string {
+	switch status {
+	case ComplianceStatusNotChecked:
+		return "NOTCHECKED"
+	case ComplianceStatusOK:
+		return "OK"
+	case ComplianceStatusTimeout:
+		return "TIMEOUT"
+	case ComplianceStatusConnectionRefused:
+		return "CREFUSED"
+	case ComplianceStatusNoEDNS:
+		return "NOEDNS"
+	case ComplianceStatusNoDO:
+		return "NODO"
+	case ComplianceStatusOptionEcho:
+		return "OPTECHO"
+	case ComplianceStatusBadRcode:
+		return "BADRCODE"
+	case ComplianceStatusError:
+		return "ERROR"
+	}
+
+	return ""
+}
+
+// NameserverCompliance stores the results of the EDNS and TCP compliance tests of a
+// nameserver. Each test sends a different kind of query to the nameserver, so a
+// nameserver can answer correctly the basic DNS queries and still cause resolution
+// failures for resolvers that use EDNS0 or TCP
+type NameserverCompliance struct {
+	Plain       ComplianceStatus // Result of the query without EDNS0
+	EDNS        ComplianceStatus // Result of the query with EDNS0
+	EDNSDO      ComplianceStatus // Result of the query with EDNS0 and the DNSSEC OK bit
+	EDNSOption  ComplianceStatus // Result of the query with an unknown EDNS0 option
+	TCP         ComplianceStatus // Result of the query sent only over TCP
+	LastCheckAt time.Time        // Time of the last compliance check
+}
+
+// Compliant returns true when all the executed compliance tests were OK. Tests that
+// weren't executed are ignored
+func (c NameserverCompliance) Compliant() bool {
+	for _, status := range []ComplianceStatus{c.Plain, c.EDNS, c.EDNSDO, c.EDNSOption, c.TCP} {
+		if status != ComplianceStatusNotChecked && status != ComplianceStatusOK {
+			return false
+		}
+	}
+
+	return true
+}
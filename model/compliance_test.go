@@ -0,0 +1,70 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package model describes the objects of the system
+package model
+
+import (
+	"testing"
+)
+
+func TestNameserverCompliant(t *testing.T) {
+	var compliance NameserverCompliance
+	if !compliance.Compliant() {
+		t.Error("Not ignoring compliance tests that weren't executed")
+	}
+
+	compliance.Plain = ComplianceStatusOK
+	compliance.EDNS = ComplianceStatusOK
+	if !compliance.Compliant() {
+		t.Error("Not detecting when all compliance tests are OK")
+	}
+
+	compliance.TCP = ComplianceStatusTimeout
+	if compliance.Compliant() {
+		t.Error("Not detecting when a compliance test failed")
+	}
+}
+
+func TestComplianceStatusToString(t *testing.T) {
+	if ComplianceStatusToString(ComplianceStatusNotChecked) != "NOTCHECKED" {
+		t.Error("Compliance status NOTCHECKED not converting correctly to string")
+	}
+
+	if ComplianceStatusToString(ComplianceStatusOK) != "OK" {
+		t.Error("Compliance status OK not converting correctly to string")
+	}
+
+	if ComplianceStatusToString(ComplianceStatusTimeout) != "TIMEOUT" {
+		t.Error("Compliance status TIMEOUT not converting correctly to string")
+	}
+
+	if ComplianceStatusToString(ComplianceStatusConnectionRefused) != "CREFUSED" {
+		t.Error("Compliance status CREFUSED not converting correctly to string")
+	}
+
+	if ComplianceStatusToString(ComplianceStatusNoEDNS) != "NOEDNS" {
+		t.Error("Compliance status NOEDNS not converting correctly to string")
+	}
+
+	if ComplianceStatusToString(ComplianceStatusNoDO) != "NODO" {
+		t.Error("Compliance status NODO not converting correctly to string")
+	}
+
+	if ComplianceStatusToString(ComplianceStatusOptionEcho) != "OPTECHO" {
+		t.Error("Compliance status OPTECHO not converting correctly to string")
+	}
+
+	if ComplianceStatusToString(ComplianceStatusBadRcode) != "BADRCODE" {
+		t.Error("Compliance status BADRCODE not converting correctly to string")
+	}
+
+	if ComplianceStatusToString(ComplianceStatusError) != "ERROR" {
+		t.Error("Compliance status ERROR not converting correctly to string")
+	}
+
+	if ComplianceStatusToString(9999) != "" {
+		t.Error("Unknown compliance status not converting correctly to string")
+	}
+}
@@ -29,6 +29,47 @@ type Domain struct {
 	Nameservers    []Nameserver  // Nameservers that asnwer with authority for this domain
 	DSSet          []DS          // Records for the DNS tree chain of trust
 	Owners         []Owner       // Responsables for the domains that will receive alerts
+	CreatedBy      string        // Principal (secret id) that created the domain
+}
+
+// ReplaceOwner replaces the owner identified by the e-mail address with the new owner,
+// keeping the position of the owner in the list. When the new owner is nil the owner is
+// only removed. If the new owner already exists in the domain, the old entry is removed
+// to avoid duplicated alerts. It returns false when the domain doesn't have the owner
+func (d *Domain) ReplaceOwner(email string, owner *Owner) bool {
+	found := false
+	var owners []Owner
+
+	for _, current := range d.Owners {
+		if current.Email != nil && current.Email.Address == email {
+			if owner != nil && !found {
+				newOwner := *owner
+
+				// The webhook secret isn't returned to the user, so it's kept while the owner
+				// has the same webhook
+				if len(newOwner.WebhookSecret) == 0 && newOwner.Webhook == current.Webhook {
+					newOwner.WebhookSecret = current.WebhookSecret
+				}
+
+				owners = append(owners, newOwner)
+			}
+
+			found = true
+			continue
+		}
+
+		if current.Email != nil && owner != nil && current.Email.Address == owner.Email.Address {
+			continue
+		}
+
+		owners = append(owners, current)
+	}
+
+	if found {
+		d.Owners = owners
+	}
+
+	return found
 }
 
 // ShouldBeScanned method is responsable for telling if the domain can be scanned or not
@@ -62,6 +103,25 @@ func (d Domain) ShouldBeScanned(maxOKVerificationDays,
 	return true
 }
 
+// IsFailing checks if the last scan detected a DNS or DNSSEC problem in the domain.
+// Nameservers and DS records that weren't checked yet aren't considered problems
+func (d Domain) IsFailing() bool {
+	for _, nameserver := range d.Nameservers {
+		if nameserver.LastStatus != NameserverStatusNotChecked &&
+			nameserver.LastStatus != NameserverStatusOK {
+			return true
+		}
+	}
+
+	for _, ds := range d.DSSet {
+		if ds.LastStatus != DSStatusNotChecked && ds.LastStatus != DSStatusOK {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Check if all nameservers are configured correctly with DNS
 func (d Domain) allNameserversOK() bool {
 	for i := 0; i < len(d.Nameservers); i++ {
@@ -110,9 +170,10 @@ func (d Domain) daysSinceLastCheck() int {
 	return int(hours) / int(24*time.Hour.Hours())
 }
 
-// Check the DS set to see if the expiration date of the DNSKEYs signatures are near. The
-// alert period is defined by the parameter daysBefore, that is the number of days before
-// the expiration date that we will consider near
+// Check the DS set to see if the expiration date of the zone signatures are near. Each DS
+// stores the earliest expiration date of the DNSKEY, SOA and NS signatures. The alert
+// period is defined by the parameter daysBefore, that is the number of days before the
+// expiration date that we will consider near
 func (d Domain) isNearDNSSECExpirationDate(daysBefore int) bool {
 	// Lets look for the oldest expiration date of the DS set, the it's probably the most
 	// problematic one
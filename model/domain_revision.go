@@ -0,0 +1,41 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package model describes the objects of the system
+package model
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2/bson"
+	"time"
+)
+
+// DomainRevision stores the state of a domain after a configuration change, so that the
+// user can compare old revisions and restore one of them when a wrong configuration was
+// sent. The revisions produced only by scans aren't stored, because they don't change
+// the configuration of the domain, so the revision numbers can have gaps
+type DomainRevision struct {
+	Id       bson.ObjectId `bson:"_id"` // Database identification
+	FQDN     string        // Domain name of the revision
+	Revision int           // Revision number of the domain after the change
+	SavedAt  time.Time     // Date and time that the revision was stored
+	Domain   Domain        // State of the domain in this revision
+}
+
+// NewDomainRevision copies the current state of the domain into a revision. The domain
+// must be already persisted, so that the revision number is defined
+func NewDomainRevision(domain Domain) DomainRevision {
+	return DomainRevision{
+		FQDN:     domain.FQDN,
+		Revision: domain.Revision,
+		SavedAt:  time.Now(),
+		Domain:   domain,
+	}
+}
+
+// ChangesFrom compares the configuration of the given domain with the configuration of
+// this revision, returning what would change when moving from the domain to this
+// revision
+func (r DomainRevision) ChangesFrom(domain Domain) []AuditChange {
+	return diffDomains(&domain, &r.Domain)
+}
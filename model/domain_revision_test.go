@@ -0,0 +1,62 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package model describes the objects of the system
+package model
+
+import (
+	"net"
+	"testing"
+)
+
+func TestNewDomainRevision(t *testing.T) {
+	domain := Domain{
+		FQDN:     "example.com.br.",
+		Revision: 3,
+		Nameservers: []Nameserver{
+			{Host: "ns1.example.com.br.", IPv4: net.ParseIP("127.0.0.1")},
+		},
+	}
+
+	revision := NewDomainRevision(domain)
+
+	if revision.FQDN != "example.com.br." || revision.Revision != 3 ||
+		revision.SavedAt.IsZero() || len(revision.Domain.Nameservers) != 1 {
+
+		t.Error("Not copying the domain into the revision properly")
+	}
+}
+
+func TestDomainRevisionChangesFrom(t *testing.T) {
+	current := Domain{
+		FQDN: "example.com.br.",
+		Nameservers: []Nameserver{
+			{Host: "ns1.example.com.br.", LastStatus: NameserverStatusTimeout},
+		},
+		DSSet: []DS{
+			{Keytag: 4321, Algorithm: DSAlgorithmRSASHA1, DigestType: DSDigestTypeSHA1, Digest: "EAA0978F38879DB70A53F9FF1ACF21D046A98B5C"},
+		},
+	}
+
+	old := current
+	old.Nameservers = []Nameserver{
+		{Host: "ns1.example.com.br.", LastStatus: NameserverStatusOK},
+	}
+	old.DSSet = nil
+
+	revision := NewDomainRevision(old)
+
+	changes := revision.ChangesFrom(current)
+	if len(changes) != 1 {
+		t.Fatalf("Expected 1 change and got %d", len(changes))
+	}
+
+	if changes[0].Field != "ds 4321" || len(changes[0].Before) == 0 || len(changes[0].After) > 0 {
+		t.Error("Not detecting the removal of the DS record")
+	}
+
+	if changes := revision.ChangesFrom(old); len(changes) > 0 {
+		t.Error("Detecting changes in the same configuration")
+	}
+}
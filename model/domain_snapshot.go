@@ -0,0 +1,79 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package model describes the objects of the system
+package model
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2/bson"
+	"time"
+)
+
+// DomainSnapshot stores the result of a scan for a specific domain. The domain object only
+// keeps the result of the last check, so the snapshots are used to build the status
+// timeline of the domain, that shows when a problem started or how often the
+// configuration changes. The snapshot is linked to the scan that produced it by the scan's
+// start date, that also identifies the scan
+type DomainSnapshot struct {
+	Id            bson.ObjectId        `bson:"_id"` // Database identification
+	FQDN          string               // Domain name of the snapshot
+	ScanStartedAt time.Time            // Start date and time of the scan that checked the domain
+	CheckedAt     time.Time            // Date and time that the domain was checked
+	Nameservers   []NameserverSnapshot // Result of the nameservers' checks
+	DSSet         []DSSnapshot         // Result of the DS records' checks
+}
+
+// NameserverSnapshot stores only the result of a nameserver check, without the
+// information that is already in the domain object
+type NameserverSnapshot struct {
+	Host       string           // Nameserver's name
+	Status     NameserverStatus // Result of the configuration check
+	StatusIPv4 NameserverStatus // Result of the configuration check over IPv4
+	StatusIPv6 NameserverStatus // Result of the configuration check over IPv6
+}
+
+// DSSnapshot stores only the result of a DS record check, without the information that is
+// already in the domain object
+type DSSnapshot struct {
+	Keytag    uint16    // DNSKEY's identification number
+	Status    DSStatus  // Result of the DNSSEC configuration check
+	ExpiresAt time.Time // DNSKEY's signature expiration date found in the check
+}
+
+// NewDomainSnapshot copies the results of the last check of the domain into a snapshot,
+// that is linked to the scan identified by the start date
+func NewDomainSnapshot(domain Domain, scanStartedAt time.Time) DomainSnapshot {
+	snapshot := DomainSnapshot{
+		FQDN:          domain.FQDN,
+		ScanStartedAt: scanStartedAt,
+	}
+
+	for _, nameserver := range domain.Nameservers {
+		snapshot.Nameservers = append(snapshot.Nameservers, NameserverSnapshot{
+			Host:       nameserver.Host,
+			Status:     nameserver.LastStatus,
+			StatusIPv4: nameserver.LastStatusIPv4,
+			StatusIPv6: nameserver.LastStatusIPv6,
+		})
+
+		// The domain check date is the date of the most recent nameserver check
+		if nameserver.LastCheckAt.After(snapshot.CheckedAt) {
+			snapshot.CheckedAt = nameserver.LastCheckAt
+		}
+	}
+
+	for _, ds := range domain.DSSet {
+		snapshot.DSSet = append(snapshot.DSSet, DSSnapshot{
+			Keytag:    ds.Keytag,
+			Status:    ds.LastStatus,
+			ExpiresAt: ds.ExpiresAt,
+		})
+
+		if ds.LastCheckAt.After(snapshot.CheckedAt) {
+			snapshot.CheckedAt = ds.LastCheckAt
+		}
+	}
+
+	return snapshot
+}
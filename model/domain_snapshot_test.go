@@ -0,0 +1,70 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package model describes the objects of the system
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewDomainSnapshot(t *testing.T) {
+	scanStartedAt := time.Now().Add(-1 * time.Hour)
+	checkedAt := time.Now()
+
+	domain := Domain{
+		FQDN: "example.com.br.",
+		Nameservers: []Nameserver{
+			{
+				Host:           "ns1.example.com.br.",
+				LastStatus:     NameserverStatusTimeout,
+				LastStatusIPv4: NameserverStatusOK,
+				LastStatusIPv6: NameserverStatusTimeout,
+				LastCheckAt:    checkedAt.Add(-1 * time.Minute),
+			},
+			{
+				Host:        "ns2.example.com.br.",
+				LastStatus:  NameserverStatusOK,
+				LastCheckAt: checkedAt,
+			},
+		},
+		DSSet: []DS{
+			{
+				Keytag:      1234,
+				LastStatus:  DSStatusExpiredSignature,
+				ExpiresAt:   checkedAt.Add(-24 * time.Hour),
+				LastCheckAt: checkedAt.Add(-2 * time.Minute),
+			},
+		},
+	}
+
+	snapshot := NewDomainSnapshot(domain, scanStartedAt)
+
+	if snapshot.FQDN != domain.FQDN || !snapshot.ScanStartedAt.Equal(scanStartedAt) {
+		t.Error("Not linking the snapshot to the domain and scan correctly")
+	}
+
+	if !snapshot.CheckedAt.Equal(checkedAt) {
+		t.Error("Not using the most recent check date in the snapshot")
+	}
+
+	if len(snapshot.Nameservers) != 2 ||
+		snapshot.Nameservers[0].Host != "ns1.example.com.br." ||
+		snapshot.Nameservers[0].Status != NameserverStatusTimeout ||
+		snapshot.Nameservers[0].StatusIPv4 != NameserverStatusOK ||
+		snapshot.Nameservers[0].StatusIPv6 != NameserverStatusTimeout ||
+		snapshot.Nameservers[1].Status != NameserverStatusOK {
+
+		t.Error("Not copying the nameservers' results correctly")
+	}
+
+	if len(snapshot.DSSet) != 1 ||
+		snapshot.DSSet[0].Keytag != 1234 ||
+		snapshot.DSSet[0].Status != DSStatusExpiredSignature ||
+		!snapshot.DSSet[0].ExpiresAt.Equal(domain.DSSet[0].ExpiresAt) {
+
+		t.Error("Not copying the DS records' results correctly")
+	}
+}
@@ -6,11 +6,77 @@
 package model
 
 import (
+	"net/mail"
 	"strconv"
 	"testing"
 	"time"
 )
 
+func TestReplaceOwner(t *testing.T) {
+	newDomain := func() Domain {
+		return Domain{
+			FQDN: "example.com.br.",
+			Owners: []Owner{
+				{Email: &mail.Address{Address: "alice@example.com.br"}, Language: "en-US"},
+				{Email: &mail.Address{Address: "bob@example.com.br"}, Language: "pt-BR"},
+				{Email: &mail.Address{Address: "carol@example.com.br"}, Language: "es-ES"},
+			},
+		}
+	}
+
+	domain := newDomain()
+	if domain.ReplaceOwner("dave@example.com.br", nil) || len(domain.Owners) != 3 {
+		t.Error("Changing a domain that doesn't have the owner")
+	}
+
+	domain = newDomain()
+	dave := Owner{Email: &mail.Address{Address: "dave@example.com.br"}, Language: "en-US"}
+	if !domain.ReplaceOwner("bob@example.com.br", &dave) || len(domain.Owners) != 3 ||
+		domain.Owners[1].Email.Address != "dave@example.com.br" {
+
+		t.Errorf("Not replacing the owner in the same position: %#v", domain.Owners)
+	}
+
+	domain = newDomain()
+	carol := Owner{Email: &mail.Address{Address: "carol@example.com.br"}, Language: "pt-BR"}
+	if !domain.ReplaceOwner("alice@example.com.br", &carol) || len(domain.Owners) != 2 ||
+		domain.Owners[0].Email.Address != "carol@example.com.br" ||
+		domain.Owners[0].Language != "pt-BR" ||
+		domain.Owners[1].Email.Address != "bob@example.com.br" {
+
+		t.Errorf("Not removing the duplicated owner: %#v", domain.Owners)
+	}
+
+	domain = newDomain()
+	if !domain.ReplaceOwner("alice@example.com.br", nil) || len(domain.Owners) != 2 ||
+		domain.Owners[0].Email.Address != "bob@example.com.br" {
+
+		t.Errorf("Not removing the owner: %#v", domain.Owners)
+	}
+
+	domain = newDomain()
+	domain.Owners[0].Webhook = "https://example.com.br/alerts"
+	domain.Owners[0].WebhookSecret = "abc123"
+	alice := Owner{
+		Email:    &mail.Address{Address: "alice@example.com.br"},
+		Language: "pt-BR",
+		Webhook:  "https://example.com.br/alerts",
+	}
+
+	if !domain.ReplaceOwner("alice@example.com.br", &alice) ||
+		domain.Owners[0].WebhookSecret != "abc123" {
+
+		t.Errorf("Not keeping the webhook secret of the owner: %#v", domain.Owners)
+	}
+
+	alice.Webhook = "https://example.com.br/other-alerts"
+	if !domain.ReplaceOwner("alice@example.com.br", &alice) ||
+		len(domain.Owners[0].WebhookSecret) > 0 {
+
+		t.Errorf("Keeping the webhook secret of another webhook: %#v", domain.Owners)
+	}
+}
+
 func TestShouldBeScanned(t *testing.T) {
 	var (
 		maxOKVerificationDays    = 7
@@ -138,6 +204,34 @@ func TestShouldBeScanned(t *testing.T) {
 	}
 }
 
+func TestIsFailing(t *testing.T) {
+	domain := Domain{
+		FQDN: "example.com.br.",
+		Nameservers: []Nameserver{
+			{Host: "ns1.example.com.br.", LastStatus: NameserverStatusOK},
+			{Host: "ns2.example.com.br.", LastStatus: NameserverStatusNotChecked},
+		},
+		DSSet: []DS{
+			{Keytag: 1234, LastStatus: DSStatusOK},
+		},
+	}
+
+	if domain.IsFailing() {
+		t.Error("Considering a domain without problems as failing")
+	}
+
+	domain.Nameservers[1].LastStatus = NameserverStatusTimeout
+	if !domain.IsFailing() {
+		t.Error("Not detecting nameserver problems")
+	}
+
+	domain.Nameservers[1].LastStatus = NameserverStatusOK
+	domain.DSSet[0].LastStatus = DSStatusExpiredSignature
+	if !domain.IsFailing() {
+		t.Error("Not detecting DS problems")
+	}
+}
+
 func TestAllNameserversOK(t *testing.T) {
 	d := Domain{
 		Nameservers: []Nameserver{
@@ -6,6 +6,8 @@
 package model
 
 import (
+	"errors"
+	"strings"
 	"time"
 )
 
@@ -91,17 +93,27 @@ func IsValidDSDigestType(digestType uint8) bool {
 	}
 }
 
+var (
+	// Error returned when the DS status isn't one of the known values
+	ErrInvalidDSStatus = errors.New("DS status is not valid")
+)
+
 // List of possible DS status
 const (
-	DSStatusNotChecked       = iota // DS record not checked yet
-	DSStatusOK                      // DNSSEC configuration for this DS is OK
-	DSStatusTimeout                 // Network timeout while trying to retrieve the DNSKEY
-	DSStatusNoSignature             // No RRSIG records found for the related DNSKEY
-	DSStatusExpiredSignature        // At least one RRSIG record was expired
-	DSStatusNoKey                   // No DNSKEY was found with the keytag of the DS
-	DSStatusNoSEP                   // DNSKEY related to DS does not have the bit SEP on
-	DSStatusSignatureError          // Error while checking DNSKEY signatures
-	DSStatusDNSError                // DNS error (check nameserver status)
+	DSStatusNotChecked        = iota // DS record not checked yet
+	DSStatusOK                       // DNSSEC configuration for this DS is OK
+	DSStatusTimeout                  // Network timeout while trying to retrieve the DNSKEY
+	DSStatusNoSignature              // No RRSIG records found for the related DNSKEY
+	DSStatusExpiredSignature         // At least one RRSIG record was expired
+	DSStatusNoKey                    // No DNSKEY was found with the keytag of the DS
+	DSStatusNoSEP                    // DNSKEY related to DS does not have the bit SEP on
+	DSStatusSignatureError           // Error while checking DNSKEY signatures
+	DSStatusDNSError                 // DNS error (check nameserver status)
+	DSStatusDigestMismatch           // DS digest doesn't match the DNSKEY or the parent's DS
+	DSStatusNotPublished             // DS record was not found in the parent zone
+	DSStatusNoDenial                 // No NSEC/NSEC3 records proving the non-existence of a name
+	DSStatusDenialNoSignature        // NSEC/NSEC3 records without a valid signature
+	DSStatusDenialParameters         // NSEC3 records with unsafe parameters (iterations, opt-out)
 )
 
 // DSStatus is a number that represents one of the possible DS status listed in the
@@ -129,11 +141,36 @@ func DSStatusToString(status DSStatus) string {
 		return "SIGERR"
 	case DSStatusDNSError:
 		return "DNSERR"
+	case DSStatusDigestMismatch:
+		return "DIGESTERR"
+	case DSStatusNotPublished:
+		return "NOTPUBLISHED"
+	case DSStatusNoDenial:
+		return "NODENIAL"
+	case DSStatusDenialNoSignature:
+		return "DENIALNOSIG"
+	case DSStatusDenialParameters:
+		return "NSEC3PARAM"
 	}
 
 	return ""
 }
 
+// Convert the DS status from text into enum, using the same text of the DSStatusToString
+// function. The text is case insensitive and spaces around it are ignored
+func DSStatusFromString(value string) (DSStatus, error) {
+	value = strings.ToUpper(value)
+	value = strings.TrimSpace(value)
+
+	for status := DSStatus(DSStatusNotChecked); status <= DSStatusDenialParameters; status++ {
+		if DSStatusToString(status) == value {
+			return status, nil
+		}
+	}
+
+	return DSStatusNotChecked, ErrInvalidDSStatus
+}
+
 // DS store the information necessary to validate if a domain is configured correctly with
 // DNSSEC, and it also stores the results of the validations. When the hosts have multiple
 // DNSSEC problems, the worst problem (using a priority algorithm) will be stored in the
@@ -143,12 +180,19 @@ type DS struct {
 	Algorithm   DSAlgorithm  // DNSKEY's algorithm
 	Digest      string       // Hash of the DNSKEY content
 	DigestType  DSDigestType // Hash type decided by user when generating the DS
-	ExpiresAt   time.Time    // DNSKEY's signature expiration date
+	ExpiresAt   time.Time    // Earliest signature expiration date (DNSKEY, SOA or NS)
+	ExpiresFrom string       // RRset type of the signature that expires first
 	LastStatus  DSStatus     // Result of the last configuration check
 	LastCheckAt time.Time    // Time of the last configuration check
 	LastOKAt    time.Time    // Last time that the DNSSEC configuration was OK
 }
 
+// ExpiresUntil checks if the earliest signature of the DS expires until the given limit.
+// A DS without a signature expiration date (never checked successfully) doesn't expire
+func (d DS) ExpiresUntil(limit time.Time) bool {
+	return !d.ExpiresAt.IsZero() && !d.ExpiresAt.After(limit)
+}
+
 // ChangeStatus is a easy way to change the status of a DS because it also updates the
 // last check date
 func (d *DS) ChangeStatus(status DSStatus) {
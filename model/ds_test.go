@@ -70,11 +70,48 @@ func TestDSStatusToString(t *testing.T) {
 		t.Error("DS status DNSERR not converting correctly to string")
 	}
 
+	if DSStatusToString(DSStatusDigestMismatch) != "DIGESTERR" {
+		t.Error("DS status DIGESTERR not converting correctly to string")
+	}
+
+	if DSStatusToString(DSStatusNotPublished) != "NOTPUBLISHED" {
+		t.Error("DS status NOTPUBLISHED not converting correctly to string")
+	}
+
+	if DSStatusToString(DSStatusNoDenial) != "NODENIAL" {
+		t.Error("DS status NODENIAL not converting correctly to string")
+	}
+
+	if DSStatusToString(DSStatusDenialNoSignature) != "DENIALNOSIG" {
+		t.Error("DS status DENIALNOSIG not converting correctly to string")
+	}
+
+	if DSStatusToString(DSStatusDenialParameters) != "NSEC3PARAM" {
+		t.Error("DS status NSEC3PARAM not converting correctly to string")
+	}
+
 	if DSStatusToString(999999) != "" {
 		t.Error("Unknown DS status associated to some existing status")
 	}
 }
 
+func TestDSStatusFromString(t *testing.T) {
+	for status := DSStatus(DSStatusNotChecked); status <= DSStatusDenialParameters; status++ {
+		converted, err := DSStatusFromString(DSStatusToString(status))
+		if err != nil || converted != status {
+			t.Errorf("DS status %s not converting correctly from string", DSStatusToString(status))
+		}
+	}
+
+	if status, err := DSStatusFromString(" expsig "); err != nil || status != DSStatusExpiredSignature {
+		t.Error("Not ignoring case and spaces when converting DS status")
+	}
+
+	if _, err := DSStatusFromString("XXX"); err != ErrInvalidDSStatus {
+		t.Error("Accepting an unknown DS status")
+	}
+}
+
 func TestValidDSAlgorithm(t *testing.T) {
 	if !IsValidDSAlgorithm(1) || !IsValidDSAlgorithm(8) || !IsValidDSAlgorithm(254) {
 		t.Error("Not accepting valid DS algorithms")
@@ -6,11 +6,17 @@
 package model
 
 import (
+	"errors"
 	"net"
 	"strings"
 	"time"
 )
 
+var (
+	// Error returned when the nameserver status isn't one of the known values
+	ErrInvalidNameserverStatus = errors.New("Nameserver status is not valid")
+)
+
 // List of possible nameserver status
 const (
 	NameserverStatusNotChecked        = iota // Nameserver not checked yet
@@ -25,6 +31,12 @@ const (
 	NameserverStatusCanonicalName            // Domain name is a link in the zone APEX
 	NameserverStatusNotSynchronized          // Nameservers of this domain have a different version of the zone files
 	NameserverStatusError                    // Generic error found in the nameserver
+	NameserverStatusSOAMismatch              // Nameserver has the same zone version, but different SOA fields
+	NameserverStatusNSSetMismatch            // Nameserver NS records are different from the delegation
+	NameserverStatusDNSKEYMismatch           // Nameserver DNSKEY records are different from other nameservers
+	NameserverStatusNotInParent              // Nameserver is not in the delegation of the parent zone
+	NameserverStatusGlueMissing              // Parent zone doesn't have the glue record of the nameserver
+	NameserverStatusGlueDiffers              // Parent zone glue record is different from the nameserver addresses
 )
 
 // NameserverStatus is a number that represents one of the possible nameserver status
@@ -58,20 +70,75 @@ func NameserverStatusToString(status NameserverStatus) string {
 		return "NOTSYNCH"
 	case NameserverStatusError:
 		return "ERROR"
+	case NameserverStatusSOAMismatch:
+		return "SOAMISMATCH"
+	case NameserverStatusNSSetMismatch:
+		return "NSMISMATCH"
+	case NameserverStatusDNSKEYMismatch:
+		return "DNSKEYMISMATCH"
+	case NameserverStatusNotInParent:
+		return "NOTINPARENT"
+	case NameserverStatusGlueMissing:
+		return "NOGLUE"
+	case NameserverStatusGlueDiffers:
+		return "GLUEDIFF"
 	}
 
 	return ""
 }
 
+// Convert the nameserver status from text into enum, using the same text of the
+// NameserverStatusToString function. The text is case insensitive and spaces around it
+// are ignored
+func NameserverStatusFromString(value string) (NameserverStatus, error) {
+	value = strings.ToUpper(value)
+	value = strings.TrimSpace(value)
+
+	for status := NameserverStatus(NameserverStatusNotChecked); status <= NameserverStatusGlueDiffers; status++ {
+		if NameserverStatusToString(status) == value {
+			return status, nil
+		}
+	}
+
+	return NameserverStatusNotChecked, ErrInvalidNameserverStatus
+}
+
 // Nameserver store the information necessary to send the requests for a specific host and
 // store the results of this requests
 type Nameserver struct {
-	Host        string           // Nameserver's name
-	IPv4        net.IP           // Host's IPv4 (optional when don't need glue)
-	IPv6        net.IP           // Host's IPv6 (optional)
-	LastStatus  NameserverStatus // Result of the last configuration check
-	LastCheckAt time.Time        // Time of the last configuration check
-	LastOKAt    time.Time        // Last time that the DNS configuration was OK
+	Host           string               // Nameserver's name
+	IPv4           net.IP               // Host's IPv4 (optional when don't need glue)
+	IPv6           net.IP               // Host's IPv6 (optional)
+	LastStatus     NameserverStatus     // Result of the last configuration check
+	LastStatusIPv4 NameserverStatus     // Result of the last configuration check over IPv4
+	LastStatusIPv6 NameserverStatus     // Result of the last configuration check over IPv6
+	LastCheckAt    time.Time            // Time of the last configuration check
+	LastOKAt       time.Time            // Last time that the DNS configuration was OK
+	Compliance     NameserverCompliance // Result of the last EDNS and TCP compliance check
+}
+
+// ResetAddressStatus clears the status of both address families. It must be called before
+// checking the nameserver's addresses, so that the results of the last check don't hide
+// the results of the current one
+func (n *Nameserver) ResetAddressStatus() {
+	n.LastStatusIPv4 = NameserverStatusNotChecked
+	n.LastStatusIPv6 = NameserverStatusNotChecked
+}
+
+// ChangeAddressStatus stores the result of the configuration check over one of the
+// nameserver's addresses. The address family (IPv4 or IPv6) defines which status is
+// going to be updated. When the family has more than one address, the first problem
+// found in the check is kept, so an address that works doesn't hide one that fails. The
+// overall status should be changed with ChangeStatus
+func (n *Nameserver) ChangeAddressStatus(address net.IP, status NameserverStatus) {
+	familyStatus := &n.LastStatusIPv6
+	if address.To4() != nil {
+		familyStatus = &n.LastStatusIPv4
+	}
+
+	if *familyStatus == NameserverStatusNotChecked || *familyStatus == NameserverStatusOK {
+		*familyStatus = status
+	}
 }
 
 // Method to check if the nameserver needs glue for a given domain name. A namerserver
@@ -0,0 +1,56 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package model describes the objects of the system
+package model
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2/bson"
+	"time"
+)
+
+// NameserverSummary aggregates the information of a nameserver host across all domains
+// that delegate to it. Many domains are hosted in the same provider, so when a host is
+// broken it's better to contact the provider than each domain's owner. The statistics
+// attribute cannot use the ENUM format because we cannot have a non-string key in the
+// JSON format when saving into the database
+type NameserverSummary struct {
+	Id             bson.ObjectId     `bson:"_id"` // Database identification
+	Revision       int               // Version of the object
+	LastModifiedAt time.Time         // Last time the object was modified
+	Host           string            // Nameserver's name
+	Domains        uint64            // Number of domains that delegate to this nameserver
+	Statistics     map[string]uint64 // Number of domains for each status (text format) of the nameserver
+	Timeouts       uint64            // Number of timeouts detected by the scan in this host
+	LastCheckAt    time.Time         // Most recent configuration check of the host in any domain
+	LastOKAt       time.Time         // Most recent time that the host was OK in any domain
+}
+
+// NewNameserverSummary initializes the summary of a nameserver host with the statistics
+// map allocated
+func NewNameserverSummary(host string) NameserverSummary {
+	return NameserverSummary{
+		Host:       host,
+		Statistics: make(map[string]uint64),
+	}
+}
+
+// AddNameserver adds the result of the nameserver of one domain to the summary of the
+// host. The dates are always the most recent ones from all domains
+func (n *NameserverSummary) AddNameserver(nameserver Nameserver) {
+	n.Domains += 1
+
+	if n.Statistics == nil {
+		n.Statistics = make(map[string]uint64)
+	}
+	n.Statistics[NameserverStatusToString(nameserver.LastStatus)] += 1
+
+	if nameserver.LastCheckAt.After(n.LastCheckAt) {
+		n.LastCheckAt = nameserver.LastCheckAt
+	}
+
+	if nameserver.LastOKAt.After(n.LastOKAt) {
+		n.LastOKAt = nameserver.LastOKAt
+	}
+}
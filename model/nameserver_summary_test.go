@@ -0,0 +1,64 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package model describes the objects of the system
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNameserverSummaryAddNameserver(t *testing.T) {
+	now := time.Now()
+
+	summary := NewNameserverSummary("ns1.example.com.br.")
+
+	summary.AddNameserver(Nameserver{
+		Host:        "ns1.example.com.br.",
+		LastStatus:  NameserverStatusOK,
+		LastCheckAt: now.Add(-1 * time.Hour),
+		LastOKAt:    now.Add(-1 * time.Hour),
+	})
+
+	summary.AddNameserver(Nameserver{
+		Host:        "ns1.example.com.br.",
+		LastStatus:  NameserverStatusTimeout,
+		LastCheckAt: now,
+		LastOKAt:    now.Add(-48 * time.Hour),
+	})
+
+	summary.AddNameserver(Nameserver{
+		Host:        "ns1.example.com.br.",
+		LastStatus:  NameserverStatusTimeout,
+		LastCheckAt: now.Add(-2 * time.Hour),
+	})
+
+	if summary.Domains != 3 {
+		t.Error("Not counting the number of domains of the nameserver")
+	}
+
+	if len(summary.Statistics) != 2 ||
+		summary.Statistics["OK"] != 1 ||
+		summary.Statistics["TIMEOUT"] != 2 {
+
+		t.Error("Not counting the status of the nameserver correctly")
+	}
+
+	if !summary.LastCheckAt.Equal(now) {
+		t.Error("Not storing the most recent check date")
+	}
+
+	if !summary.LastOKAt.Equal(now.Add(-1 * time.Hour)) {
+		t.Error("Not storing the most recent OK date")
+	}
+
+	// Summary objects created without the constructor should also work
+	var otherSummary NameserverSummary
+	otherSummary.AddNameserver(Nameserver{LastStatus: NameserverStatusOK})
+
+	if otherSummary.Statistics["OK"] != 1 {
+		t.Error("Not initializing the statistics map")
+	}
+}
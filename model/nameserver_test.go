@@ -6,6 +6,7 @@
 package model
 
 import (
+	"net"
 	"testing"
 	"time"
 )
@@ -73,6 +74,48 @@ func TestNameserverChangeStatus(t *testing.T) {
 	}
 }
 
+func TestNameserverChangeAddressStatus(t *testing.T) {
+	var nameserver Nameserver
+
+	nameserver.ChangeAddressStatus(net.ParseIP("127.0.0.1"), NameserverStatusOK)
+	nameserver.ChangeAddressStatus(net.ParseIP("::1"), NameserverStatusTimeout)
+
+	if nameserver.LastStatusIPv4 != NameserverStatusOK {
+		t.Error("ChangeAddressStatus method did not change the IPv4 status")
+	}
+
+	if nameserver.LastStatusIPv6 != NameserverStatusTimeout {
+		t.Error("ChangeAddressStatus method did not change the IPv6 status")
+	}
+
+	if nameserver.LastStatus != NameserverStatusNotChecked {
+		t.Error("ChangeAddressStatus method changed the overall status")
+	}
+
+	// Two addresses of the same family, where only the second one answers
+	nameserver.ResetAddressStatus()
+	nameserver.ChangeAddressStatus(net.ParseIP("127.0.0.1"), NameserverStatusTimeout)
+	nameserver.ChangeAddressStatus(net.ParseIP("127.0.0.2"), NameserverStatusOK)
+
+	if nameserver.LastStatusIPv4 != NameserverStatusTimeout {
+		t.Error("ChangeAddressStatus method hid the problem of an IPv4 address")
+	}
+
+	if nameserver.LastStatusIPv6 != NameserverStatusNotChecked {
+		t.Error("ResetAddressStatus method did not clear the IPv6 status")
+	}
+
+	// The first problem found is kept when the family has different problems
+	nameserver.ResetAddressStatus()
+	nameserver.ChangeAddressStatus(net.ParseIP("::1"), NameserverStatusOK)
+	nameserver.ChangeAddressStatus(net.ParseIP("::2"), NameserverStatusTimeout)
+	nameserver.ChangeAddressStatus(net.ParseIP("::3"), NameserverStatusServerFailure)
+
+	if nameserver.LastStatusIPv6 != NameserverStatusTimeout {
+		t.Error("ChangeAddressStatus method did not keep the first IPv6 problem")
+	}
+}
+
 func TestNameserverStatusToString(t *testing.T) {
 	if NameserverStatusToString(NameserverStatusNotChecked) != "NOTCHECKED" {
 		t.Error("Nameserver status NOTCHECKED not converting correctly to string")
@@ -122,7 +165,50 @@ func TestNameserverStatusToString(t *testing.T) {
 		t.Error("Nameserver status ERROR not converting correctly to string")
 	}
 
+	if NameserverStatusToString(NameserverStatusSOAMismatch) != "SOAMISMATCH" {
+		t.Error("Nameserver status SOAMISMATCH not converting correctly to string")
+	}
+
+	if NameserverStatusToString(NameserverStatusNSSetMismatch) != "NSMISMATCH" {
+		t.Error("Nameserver status NSMISMATCH not converting correctly to string")
+	}
+
+	if NameserverStatusToString(NameserverStatusDNSKEYMismatch) != "DNSKEYMISMATCH" {
+		t.Error("Nameserver status DNSKEYMISMATCH not converting correctly to string")
+	}
+
+	if NameserverStatusToString(NameserverStatusNotInParent) != "NOTINPARENT" {
+		t.Error("Nameserver status NOTINPARENT not converting correctly to string")
+	}
+
+	if NameserverStatusToString(NameserverStatusGlueMissing) != "NOGLUE" {
+		t.Error("Nameserver status NOGLUE not converting correctly to string")
+	}
+
+	if NameserverStatusToString(NameserverStatusGlueDiffers) != "GLUEDIFF" {
+		t.Error("Nameserver status GLUEDIFF not converting correctly to string")
+	}
+
 	if NameserverStatusToString(999999) != "" {
 		t.Error("Unknown nameserver status associated to some existing status")
 	}
 }
+
+func TestNameserverStatusFromString(t *testing.T) {
+	for status := NameserverStatus(NameserverStatusNotChecked); status <= NameserverStatusGlueDiffers; status++ {
+		converted, err := NameserverStatusFromString(NameserverStatusToString(status))
+		if err != nil || converted != status {
+			t.Errorf("Nameserver status %s not converting correctly from string",
+				NameserverStatusToString(status))
+		}
+	}
+
+	if status, err := NameserverStatusFromString(" timeout "); err != nil ||
+		status != NameserverStatusTimeout {
+		t.Error("Not ignoring case and spaces when converting nameserver status")
+	}
+
+	if _, err := NameserverStatusFromString("XXX"); err != ErrInvalidNameserverStatus {
+		t.Error("Accepting an unknown nameserver status")
+	}
+}
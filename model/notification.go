@@ -0,0 +1,94 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package model describes the objects of the system
+package model
+
+import (
+	"fmt"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2/bson"
+	"sort"
+	"time"
+)
+
+// Notification stores the result of an alert sent to the owners of a domain. A record is
+// created for each group of recipients of a channel (the e-mails of the same language or
+// a webhook), so we can know exactly who was alerted, about what and if the delivery
+// worked. The problems are also used to avoid sending the same alert on every
+// notification run. Alerts of owners in their quiet hours are stored as postponed, with
+// the message ready to be delivered when the quiet hours end
+type Notification struct {
+	Id             bson.ObjectId `bson:"_id"` // Database identification
+	FQDN           string        // Domain name that was notified
+	Channel        string        // Channel used to send the alert (smtp, webhook)
+	Recipients     []string      // E-mails or URLs that received the alert
+	Language       string        // Language of the template used in the alert
+	Problems       []string      // Problems of the domain at the moment of the alert
+	Recovery       bool          // Flag that indicates if the alert informs problems that were solved
+	Digest         bool          // Flag that indicates if the alert was grouped with other domains of the owner
+	Delivered      bool          // Flag that indicates if the alert was accepted by the destination
+	Postponed      bool          // Flag that indicates if the alert is waiting for the end of the quiet hours
+	PostponedUntil time.Time     // End of the recipients' quiet hours, when the alert can be delivered
+	Message        []byte        // Alert built from the template, only kept while it's postponed
+	Error          string        // Reason of the delivery failure
+	SentAt         time.Time     // Date and time of the alert
+}
+
+// NotificationProblems builds a sorted list that identifies the problems of the domain
+// that will be sent in the alerts. Each problem is composed by the nameserver or DS
+// record and its status, and the DS records with signatures expiring before the given
+// limit are also considered a problem. The list can be compared with the one from the
+// last alert to detect if the problems changed
+func NotificationProblems(domain Domain, expirationLimit time.Time) []string {
+	var problems []string
+
+	for _, nameserver := range domain.Nameservers {
+		if nameserver.LastStatus == NameserverStatusNotChecked ||
+			nameserver.LastStatus == NameserverStatusOK {
+			continue
+		}
+
+		problems = append(problems, nameserverProblem(nameserver))
+	}
+
+	for _, ds := range domain.DSSet {
+		if ds.LastStatus != DSStatusNotChecked && ds.LastStatus != DSStatusOK {
+			problems = append(problems, dsProblem(ds))
+		}
+
+		if ds.ExpiresUntil(expirationLimit) {
+			problems = append(problems, fmt.Sprintf("ds %d EXPIRING", ds.Keytag))
+		}
+	}
+
+	sort.Strings(problems)
+	return problems
+}
+
+// Identify the problem of the nameserver using the last status
+func nameserverProblem(nameserver Nameserver) string {
+	return fmt.Sprintf("nameserver %s %s",
+		nameserver.Host, NameserverStatusToString(nameserver.LastStatus))
+}
+
+// Identify the problem of the DS record using the last status
+func dsProblem(ds DS) string {
+	return fmt.Sprintf("ds %d %s", ds.Keytag, DSStatusToString(ds.LastStatus))
+}
+
+// SameProblems checks if two sorted lists of problems built with NotificationProblems
+// are equal
+func SameProblems(problems1, problems2 []string) bool {
+	if len(problems1) != len(problems2) {
+		return false
+	}
+
+	for i := range problems1 {
+		if problems1[i] != problems2[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,87 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package model describes the objects of the system
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNotificationProblems(t *testing.T) {
+	now := time.Now()
+
+	domain := Domain{
+		FQDN: "example.com.br.",
+		Nameservers: []Nameserver{
+			{
+				Host:       "ns2.example.com.br.",
+				LastStatus: NameserverStatusTimeout,
+			},
+			{
+				Host:       "ns1.example.com.br.",
+				LastStatus: NameserverStatusOK,
+			},
+			{
+				Host:       "ns3.example.com.br.",
+				LastStatus: NameserverStatusNotChecked,
+			},
+		},
+		DSSet: []DS{
+			{
+				Keytag:     1234,
+				LastStatus: DSStatusOK,
+				ExpiresAt:  now.Add(24 * time.Hour),
+			},
+			{
+				Keytag:     4321,
+				LastStatus: DSStatusExpiredSignature,
+				ExpiresAt:  now.Add(-24 * time.Hour),
+			},
+		},
+	}
+
+	problems := NotificationProblems(domain, now.Add(48*time.Hour))
+
+	expected := []string{
+		"ds 1234 EXPIRING",
+		"ds 4321 EXPIRING",
+		"ds 4321 EXPSIG",
+		"nameserver ns2.example.com.br. TIMEOUT",
+	}
+
+	if !SameProblems(problems, expected) {
+		t.Errorf("Not detecting the problems correctly. Expected %v and got %v",
+			expected, problems)
+	}
+
+	if problems := NotificationProblems(domain, now); len(problems) != 3 {
+		t.Errorf("Not respecting the expiration limit. Found problems %v", problems)
+	}
+
+	if problems := NotificationProblems(Domain{}, now); len(problems) != 0 {
+		t.Error("Detecting problems in a domain without nameservers and DS records")
+	}
+}
+
+func TestSameProblems(t *testing.T) {
+	data := []struct {
+		problems1 []string
+		problems2 []string
+		expected  bool
+	}{
+		{nil, nil, true},
+		{nil, []string{}, true},
+		{[]string{"a", "b"}, []string{"a", "b"}, true},
+		{[]string{"a", "b"}, []string{"a"}, false},
+		{[]string{"a", "b"}, []string{"a", "c"}, false},
+	}
+
+	for _, item := range data {
+		if SameProblems(item.problems1, item.problems2) != item.expected {
+			t.Errorf("Wrong result comparing problems %v and %v", item.problems1, item.problems2)
+		}
+	}
+}
@@ -7,6 +7,7 @@ package model
 
 import (
 	"errors"
+	"net"
 	"net/mail"
 	"strings"
 	"sync"
@@ -27,13 +28,22 @@ var (
 	// As we are going to add and read the array of languages on-the-fly we need a lock
 	// mechanism to allow concurrent access
 	languagesLock sync.RWMutex
+
+	// Shared address space used by carrier-grade NAT (RFC 6598), that isn't covered by the
+	// private addresses of the standard library
+	sharedAddressSpace = net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
 )
 
 // Owner represents the responsable for the domain that can be alerted if any
 // configuration problem is detected
 type Owner struct {
-	Email    *mail.Address // E-mail that will be alerted on any problem
-	Language string        // Language used to send alerts
+	Email         *mail.Address // E-mail that will be alerted on any problem
+	Language      string        // Language used to send alerts
+	Webhook       string        // URL that will receive the alerts as JSON (optional)
+	WebhookSecret string        // Secret used to sign the requests sent to the webhook (optional)
+	Digest        Digest        // Frequency of the e-mail alerts
+	ProblemTypes  []ProblemType // Types of problems alerted by e-mail, empty for all
+	QuietHours    QuietHours    // Period of the day without e-mail alerts
 }
 
 // AddLanguage is a safe way to add a supported language for the owner
@@ -77,3 +87,17 @@ func LanguageExists(language string) bool {
 
 	return false
 }
+
+// IsPrivateAddress checks if the address can't be reached from the Internet (loopback,
+// private networks, link-local, etc). The webhooks can't point to these addresses, or
+// anyone with access to the owners could send requests to the internal network
+func IsPrivateAddress(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() ||
+		sharedAddressSpace.Contains(ip)
+}
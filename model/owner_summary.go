@@ -0,0 +1,19 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package model describes the objects of the system
+package model
+
+import (
+	"time"
+)
+
+// OwnerSummary aggregates an owner across all domains that he is responsable for. The
+// owners are stored inside each domain, so the summary is built on demand from the
+// domains using the owner's e-mail as identification
+type OwnerSummary struct {
+	Email          string    `bson:"_id"` // E-mail address of the owner
+	Domains        int       // Number of domains that have the owner
+	LastModifiedAt time.Time // Most recent modification of the owner's domains
+}
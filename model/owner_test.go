@@ -6,6 +6,7 @@
 package model
 
 import (
+	"net"
 	"testing"
 )
 
@@ -36,3 +37,37 @@ func TestLanguageExists(t *testing.T) {
 		t.Error("Did not find a language that exists")
 	}
 }
+
+func TestIsPrivateAddress(t *testing.T) {
+	privateAddresses := []string{
+		"127.0.0.1",
+		"10.1.2.3",
+		"172.16.0.1",
+		"192.168.1.1",
+		"169.254.169.254",
+		"100.64.0.1",
+		"0.0.0.0",
+		"::1",
+		"fc00::1",
+		"fe80::1",
+		"::ffff:127.0.0.1",
+	}
+
+	for _, address := range privateAddresses {
+		if !IsPrivateAddress(net.ParseIP(address)) {
+			t.Errorf("Not detecting private address %s", address)
+		}
+	}
+
+	publicAddresses := []string{
+		"200.160.2.3",
+		"8.8.8.8",
+		"2001:12ff::10",
+	}
+
+	for _, address := range publicAddresses {
+		if IsPrivateAddress(net.ParseIP(address)) {
+			t.Errorf("Detecting public address %s as private", address)
+		}
+	}
+}
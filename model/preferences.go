@@ -0,0 +1,253 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package model describes the objects of the system
+package model
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// Error returned when the digest frequency isn't one of the known values
+	ErrInvalidDigest = errors.New("Digest frequency is not valid")
+
+	// Error returned when the problem type isn't one of the known values
+	ErrInvalidProblemType = errors.New("Problem type is not valid")
+)
+
+// List of possible frequencies that the owner can receive the alerts
+const (
+	DigestImmediate Digest = iota // One alert for each domain on every notification
+	DigestDaily                   // One alert per day with all domains of the owner
+	DigestWeekly                  // One alert per week with all domains of the owner
+)
+
+// Digest is a number that represents one of the possible alert frequencies listed in the
+// constant group above
+type Digest int
+
+// Convert the digest enum to text for printing in reports or in the REST protocol
+func DigestToString(digest Digest) string {
+	switch digest {
+	case DigestImmediate:
+		return "immediate"
+	case DigestDaily:
+		return "daily"
+	case DigestWeekly:
+		return "weekly"
+	}
+
+	return ""
+}
+
+// Convert the digest from text into enum. The text is case insensitive and spaces around
+// it are ignored. An empty text is considered immediate, that is the default behaviour
+func DigestFromString(value string) (Digest, error) {
+	value = strings.ToLower(value)
+	value = strings.TrimSpace(value)
+
+	switch value {
+	case "", "immediate":
+		return DigestImmediate, nil
+	case "daily":
+		return DigestDaily, nil
+	case "weekly":
+		return DigestWeekly, nil
+	}
+
+	return DigestImmediate, ErrInvalidDigest
+}
+
+// Period returns the minimum time between two digests of the owner. For immediate alerts
+// there's no period
+func (d Digest) Period() time.Duration {
+	switch d {
+	case DigestDaily:
+		return 24 * time.Hour
+	case DigestWeekly:
+		return 7 * 24 * time.Hour
+	}
+
+	return 0
+}
+
+// List of possible types of problems that the owner can choose to be alerted
+const (
+	ProblemTypeTimeout          ProblemType = iota // Nameservers or DNSKEYs that don't answer
+	ProblemTypeLame                                // Nameservers without authority over the domain (lame delegation)
+	ProblemTypeDNS                                 // Other DNS configuration problems
+	ProblemTypeDNSSECExpiration                    // Expired signatures or signatures near the expiration date
+	ProblemTypeDNSSEC                              // Other DNSSEC configuration problems
+)
+
+// ProblemType is a number that represents one of the possible groups of problems listed
+// in the constant group above
+type ProblemType int
+
+// Convert the problem type enum to text for printing in reports or in the REST protocol
+func ProblemTypeToString(problemType ProblemType) string {
+	switch problemType {
+	case ProblemTypeTimeout:
+		return "timeout"
+	case ProblemTypeLame:
+		return "lame"
+	case ProblemTypeDNS:
+		return "dns"
+	case ProblemTypeDNSSECExpiration:
+		return "dnssec-expiration"
+	case ProblemTypeDNSSEC:
+		return "dnssec"
+	}
+
+	return ""
+}
+
+// Convert the problem type from text into enum. The text is case insensitive and spaces
+// around it are ignored
+func ProblemTypeFromString(value string) (ProblemType, error) {
+	value = strings.ToLower(value)
+	value = strings.TrimSpace(value)
+
+	switch value {
+	case "timeout":
+		return ProblemTypeTimeout, nil
+	case "lame":
+		return ProblemTypeLame, nil
+	case "dns":
+		return ProblemTypeDNS, nil
+	case "dnssec-expiration":
+		return ProblemTypeDNSSECExpiration, nil
+	case "dnssec":
+		return ProblemTypeDNSSEC, nil
+	}
+
+	return ProblemTypeDNS, ErrInvalidProblemType
+}
+
+// NameserverProblemType classifies the status of a nameserver with problem
+func NameserverProblemType(status NameserverStatus) ProblemType {
+	switch status {
+	case NameserverStatusTimeout, NameserverStatusConnectionRefused:
+		return ProblemTypeTimeout
+
+	case NameserverStatusNoAuthority, NameserverStatusUnknownDomainName,
+		NameserverStatusQueryRefused:
+		return ProblemTypeLame
+	}
+
+	return ProblemTypeDNS
+}
+
+// DSProblemType classifies the status of a DS record with problem
+func DSProblemType(status DSStatus) ProblemType {
+	switch status {
+	case DSStatusTimeout:
+		return ProblemTypeTimeout
+	case DSStatusExpiredSignature:
+		return ProblemTypeDNSSECExpiration
+	}
+
+	return ProblemTypeDNSSEC
+}
+
+// QuietHours is a period of the day that the owner doesn't want to receive alerts. The
+// period is stored in minutes of the day in UTC and can cross midnight (e.g. from 22:00
+// to 07:00). When the start and the end are equal there's no quiet period
+type QuietHours struct {
+	Start int // Minute of the day (UTC) when the quiet period starts
+	End   int // Minute of the day (UTC) when the quiet period ends
+}
+
+// Enabled returns true when the owner defined a quiet period
+func (q QuietHours) Enabled() bool {
+	return q.Start != q.End
+}
+
+// Contains checks if the given moment is inside the quiet period
+func (q QuietHours) Contains(moment time.Time) bool {
+	if !q.Enabled() {
+		return false
+	}
+
+	moment = moment.UTC()
+	minute := moment.Hour()*60 + moment.Minute()
+
+	if q.Start < q.End {
+		return minute >= q.Start && minute < q.End
+	}
+
+	// The quiet period crosses midnight
+	return minute >= q.Start || minute < q.End
+}
+
+// EndAfter returns the moment that the quiet period containing the given moment ends. When
+// the moment isn't inside the quiet period it is returned unchanged
+func (q QuietHours) EndAfter(moment time.Time) time.Time {
+	if !q.Contains(moment) {
+		return moment
+	}
+
+	moment = moment.UTC()
+	day := time.Date(moment.Year(), moment.Month(), moment.Day(), 0, 0, 0, 0, time.UTC)
+
+	end := day.Add(time.Duration(q.End) * time.Minute)
+	if !end.After(moment) {
+		// The quiet period crosses midnight and ends tomorrow
+		end = end.Add(24 * time.Hour)
+	}
+
+	return end
+}
+
+// WantsProblem checks if the owner wants to be alerted about the type of problem. When
+// the owner didn't choose any type, all problems are alerted
+func (o Owner) WantsProblem(problemType ProblemType) bool {
+	if len(o.ProblemTypes) == 0 {
+		return true
+	}
+
+	for _, wantedProblemType := range o.ProblemTypes {
+		if wantedProblemType == problemType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FilterProblems builds a copy of the domain only with the nameservers and DS records
+// that have problems that the owner wants to be alerted. DS records with signatures
+// expiring before the given limit are considered a DNSSEC expiration problem. It returns
+// false when there's nothing to alert to the owner
+func (o Owner) FilterProblems(domain Domain, expirationLimit time.Time) (Domain, bool) {
+	filtered := domain
+	filtered.Nameservers = nil
+	filtered.DSSet = nil
+
+	for _, nameserver := range domain.Nameservers {
+		if nameserver.LastStatus == NameserverStatusNotChecked ||
+			nameserver.LastStatus == NameserverStatusOK {
+			continue
+		}
+
+		if o.WantsProblem(NameserverProblemType(nameserver.LastStatus)) {
+			filtered.Nameservers = append(filtered.Nameservers, nameserver)
+		}
+	}
+
+	for _, ds := range domain.DSSet {
+		problem := ds.LastStatus != DSStatusNotChecked && ds.LastStatus != DSStatusOK
+
+		if (problem && o.WantsProblem(DSProblemType(ds.LastStatus))) ||
+			(ds.ExpiresUntil(expirationLimit) && o.WantsProblem(ProblemTypeDNSSECExpiration)) {
+
+			filtered.DSSet = append(filtered.DSSet, ds)
+		}
+	}
+
+	return filtered, len(filtered.Nameservers) > 0 || len(filtered.DSSet) > 0
+}
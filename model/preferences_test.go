@@ -0,0 +1,190 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package model describes the objects of the system
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDigestFromString(t *testing.T) {
+	data := []struct {
+		value    string
+		expected Digest
+	}{
+		{"", DigestImmediate},
+		{"immediate", DigestImmediate},
+		{"  DAILY  ", DigestDaily},
+		{"Weekly", DigestWeekly},
+	}
+
+	for _, item := range data {
+		if digest, err := DigestFromString(item.value); err != nil || digest != item.expected {
+			t.Errorf("Not converting digest %q correctly", item.value)
+		}
+	}
+
+	if _, err := DigestFromString("monthly"); err != ErrInvalidDigest {
+		t.Error("Accepting an invalid digest")
+	}
+}
+
+func TestDigestToString(t *testing.T) {
+	if DigestToString(DigestDaily) != "daily" || DigestToString(DigestWeekly) != "weekly" ||
+		DigestToString(DigestImmediate) != "immediate" {
+
+		t.Error("Not converting digest to text correctly")
+	}
+
+	if len(DigestToString(Digest(9999))) > 0 {
+		t.Error("Not returning empty string for an unknown digest")
+	}
+}
+
+func TestDigestPeriod(t *testing.T) {
+	if DigestImmediate.Period() != 0 ||
+		DigestDaily.Period() != 24*time.Hour ||
+		DigestWeekly.Period() != 7*24*time.Hour {
+
+		t.Error("Not returning the correct digest periods")
+	}
+}
+
+func TestProblemTypeFromString(t *testing.T) {
+	for _, problemType := range []ProblemType{
+		ProblemTypeTimeout,
+		ProblemTypeLame,
+		ProblemTypeDNS,
+		ProblemTypeDNSSECExpiration,
+		ProblemTypeDNSSEC,
+	} {
+		value := ProblemTypeToString(problemType)
+		if converted, err := ProblemTypeFromString(" " + value + " "); err != nil || converted != problemType {
+			t.Errorf("Not converting problem type %q correctly", value)
+		}
+	}
+
+	if _, err := ProblemTypeFromString("xxx"); err != ErrInvalidProblemType {
+		t.Error("Accepting an invalid problem type")
+	}
+
+	if len(ProblemTypeToString(ProblemType(9999))) > 0 {
+		t.Error("Not returning empty string for an unknown problem type")
+	}
+}
+
+func TestProblemTypeClassification(t *testing.T) {
+	if NameserverProblemType(NameserverStatusTimeout) != ProblemTypeTimeout ||
+		NameserverProblemType(NameserverStatusNoAuthority) != ProblemTypeLame ||
+		NameserverProblemType(NameserverStatusServerFailure) != ProblemTypeDNS {
+
+		t.Error("Not classifying the nameserver problems correctly")
+	}
+
+	if DSProblemType(DSStatusTimeout) != ProblemTypeTimeout ||
+		DSProblemType(DSStatusExpiredSignature) != ProblemTypeDNSSECExpiration ||
+		DSProblemType(DSStatusNoKey) != ProblemTypeDNSSEC {
+
+		t.Error("Not classifying the DS problems correctly")
+	}
+}
+
+func TestQuietHoursContains(t *testing.T) {
+	day := time.Date(2014, time.March, 10, 0, 0, 0, 0, time.UTC)
+
+	data := []struct {
+		quietHours QuietHours
+		moment     time.Time
+		expected   bool
+	}{
+		{QuietHours{}, day.Add(3 * time.Hour), false},
+		{QuietHours{Start: 60, End: 180}, day.Add(2 * time.Hour), true},
+		{QuietHours{Start: 60, End: 180}, day.Add(3 * time.Hour), false},
+		{QuietHours{Start: 22 * 60, End: 7 * 60}, day.Add(23 * time.Hour), true},
+		{QuietHours{Start: 22 * 60, End: 7 * 60}, day.Add(6 * time.Hour), true},
+		{QuietHours{Start: 22 * 60, End: 7 * 60}, day.Add(12 * time.Hour), false},
+	}
+
+	for _, item := range data {
+		if item.quietHours.Contains(item.moment) != item.expected {
+			t.Errorf("Wrong result checking quiet hours %#v at %s",
+				item.quietHours, item.moment)
+		}
+	}
+
+	// Other timezones must be converted to UTC
+	location := time.FixedZone("BRT", -3*60*60)
+	quietHours := QuietHours{Start: 60, End: 180}
+	if !quietHours.Contains(time.Date(2014, time.March, 9, 22, 30, 0, 0, location)) {
+		t.Error("Not converting the moment to UTC")
+	}
+}
+
+func TestQuietHoursEndAfter(t *testing.T) {
+	day := time.Date(2014, time.March, 10, 0, 0, 0, 0, time.UTC)
+
+	data := []struct {
+		quietHours QuietHours
+		moment     time.Time
+		expected   time.Time
+	}{
+		{QuietHours{}, day.Add(3 * time.Hour), day.Add(3 * time.Hour)},
+		{QuietHours{Start: 60, End: 180}, day.Add(2 * time.Hour), day.Add(3 * time.Hour)},
+		{QuietHours{Start: 60, End: 180}, day.Add(4 * time.Hour), day.Add(4 * time.Hour)},
+		{QuietHours{Start: 22 * 60, End: 7 * 60}, day.Add(23 * time.Hour), day.Add(31 * time.Hour)},
+		{QuietHours{Start: 22 * 60, End: 7 * 60}, day.Add(6 * time.Hour), day.Add(7 * time.Hour)},
+	}
+
+	for _, item := range data {
+		if end := item.quietHours.EndAfter(item.moment); !end.Equal(item.expected) {
+			t.Errorf("Wrong end of quiet hours %#v at %s. Expected %s and got %s",
+				item.quietHours, item.moment, item.expected, end)
+		}
+	}
+}
+
+func TestOwnerFilterProblems(t *testing.T) {
+	now := time.Now()
+
+	domain := Domain{
+		FQDN: "example.com.br.",
+		Nameservers: []Nameserver{
+			{Host: "ns1.example.com.br.", LastStatus: NameserverStatusTimeout},
+			{Host: "ns2.example.com.br.", LastStatus: NameserverStatusNoAuthority},
+			{Host: "ns3.example.com.br.", LastStatus: NameserverStatusOK},
+		},
+		DSSet: []DS{
+			{Keytag: 1234, LastStatus: DSStatusOK, ExpiresAt: now.Add(time.Hour)},
+			{Keytag: 4321, LastStatus: DSStatusNoKey, ExpiresAt: now.Add(240 * time.Hour)},
+		},
+	}
+
+	expirationLimit := now.Add(24 * time.Hour)
+
+	var owner Owner
+	filtered, ok := owner.FilterProblems(domain, expirationLimit)
+	if !ok || len(filtered.Nameservers) != 2 || len(filtered.DSSet) != 2 {
+		t.Error("Not alerting all problems when the owner didn't choose the problem types")
+	}
+
+	owner.ProblemTypes = []ProblemType{ProblemTypeLame, ProblemTypeDNSSECExpiration}
+	filtered, ok = owner.FilterProblems(domain, expirationLimit)
+	if !ok || filtered.FQDN != "example.com.br." ||
+		len(filtered.Nameservers) != 1 || filtered.Nameservers[0].Host != "ns2.example.com.br." ||
+		len(filtered.DSSet) != 1 || filtered.DSSet[0].Keytag != 1234 {
+
+		t.Errorf("Not filtering the problems correctly: %#v", filtered)
+	}
+
+	if len(domain.Nameservers) != 3 || len(domain.DSSet) != 2 {
+		t.Error("Changing the original domain while filtering")
+	}
+
+	owner.ProblemTypes = []ProblemType{ProblemTypeDNS}
+	if _, ok := owner.FilterProblems(domain, expirationLimit); ok {
+		t.Error("Alerting a domain without the problems that the owner wants")
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package model describes the objects of the system
+package model
+
+import (
+	"errors"
+	"strings"
+)
+
+// List of possible roles of a principal of the REST server
+const (
+	RoleReadOnly     Role = iota // Can only retrieve information
+	RoleDomainWriter             // Can also create, update and remove domains
+	RoleAdmin                    // Can also manage scans, owners and other global resources
+)
+
+// List of possible errors that can occur when calling functions from this file. Other
+// erros can also occurs from low level layers
+var (
+	// Error returned when the role text is unknown
+	ErrInvalidRole = errors.New("Invalid principal role")
+)
+
+// Role defines the actions that a principal is allowed to execute
+type Role int
+
+// Convert the role enum to text for printing in reports or debugging
+func RoleToString(role Role) string {
+	switch role {
+	case RoleReadOnly:
+		return "read-only"
+	case RoleDomainWriter:
+		return "domain-writer"
+	case RoleAdmin:
+		return "admin"
+	}
+
+	return ""
+}
+
+// Convert the role from text into enum, using the same text of the RoleToString
+// function. The text is case insensitive and spaces around it are ignored
+func RoleFromString(value string) (Role, error) {
+	value = strings.ToLower(value)
+	value = strings.TrimSpace(value)
+
+	for role := RoleReadOnly; role <= RoleAdmin; role++ {
+		if RoleToString(role) == value {
+			return role, nil
+		}
+	}
+
+	return RoleReadOnly, ErrInvalidRole
+}
+
+// Principal is the identity of who is signing the requests sent to the REST server. Each
+// shared secret belongs to a principal, that can be restricted to a set of domains, so
+// that many registrars can use the same system without seeing each other domains
+type Principal struct {
+	Id      string   // Secret id used to sign the requests
+	Role    Role     // Actions allowed to the principal
+	Domains []string // Domains and suffixes (with subdomains) managed by the principal, empty for all
+}
+
+// Restricted returns true when the principal can only access some domains
+func (p Principal) Restricted() bool {
+	return len(p.Domains) > 0
+}
+
+// Owns checks if the principal manages the domain. The FQDN must be normalized, and it
+// belongs to the principal when it is one of the principal's domains or a subdomain of
+// them. An unrestricted principal owns all domains
+func (p Principal) Owns(fqdn string) bool {
+	if !p.Restricted() {
+		return true
+	}
+
+	for _, domain := range p.Domains {
+		if fqdn == domain || strings.HasSuffix(fqdn, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,67 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package model describes the objects of the system
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRoleFromString(t *testing.T) {
+	for role := RoleReadOnly; role <= RoleAdmin; role++ {
+		converted, err := RoleFromString(" " + strings.ToUpper(RoleToString(role)) + " ")
+		if err != nil {
+			t.Errorf("Not converting role %d. Details: %s", role, err)
+
+		} else if converted != role {
+			t.Errorf("Wrong role conversion. Expected %d and got %d", role, converted)
+		}
+	}
+
+	if _, err := RoleFromString("root"); err != ErrInvalidRole {
+		t.Error("Accepting an unknown role")
+	}
+
+	if RoleToString(Role(99)) != "" {
+		t.Error("Converting an unknown role to text")
+	}
+}
+
+func TestPrincipalOwns(t *testing.T) {
+	principal := Principal{
+		Id:   "1",
+		Role: RoleDomainWriter,
+	}
+
+	if principal.Restricted() || !principal.Owns("example.com.br.") {
+		t.Error("Restricting a principal without domains")
+	}
+
+	principal.Domains = []string{"example.com.br.", "net."}
+
+	data := []struct {
+		FQDN     string
+		Expected bool
+	}{
+		{FQDN: "example.com.br.", Expected: true},
+		{FQDN: "www.example.com.br.", Expected: true},
+		{FQDN: "example.net.", Expected: true},
+		{FQDN: "net.", Expected: true},
+		{FQDN: "otherexample.com.br.", Expected: false},
+		{FQDN: "com.br.", Expected: false},
+		{FQDN: "example.network.", Expected: false},
+	}
+
+	if !principal.Restricted() {
+		t.Error("Not restricting a principal with domains")
+	}
+
+	for _, item := range data {
+		if principal.Owns(item.FQDN) != item.Expected {
+			t.Errorf("Wrong ownership of domain %s. Expected %t", item.FQDN, item.Expected)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package model describes the objects of the system
+package model
+
+import (
+	"sort"
+)
+
+// DomainRecovery stores the problems of a domain that were solved in a scan, so that the
+// owners can be informed that their fix worked. The nameservers and DS records keep the
+// state before the scan, when they still had problems
+type DomainRecovery struct {
+	Domain      Domain       // Domain with the state after the scan
+	Nameservers []Nameserver // Previous state of the nameservers that are now OK
+	DSSet       []DS         // Previous state of the DS records that are now OK
+}
+
+// NewDomainRecovery compares the saved state of the domain with the state after the scan
+// to detect the nameservers and DS records that went from a problem to OK. The
+// nameservers are matched by host and the DS records by keytag, so objects that were
+// added or removed between both states are ignored
+func NewDomainRecovery(previous, current Domain) DomainRecovery {
+	recovery := DomainRecovery{
+		Domain: current,
+	}
+
+	for _, nameserver := range current.Nameservers {
+		if nameserver.LastStatus != NameserverStatusOK {
+			continue
+		}
+
+		for _, previousNameserver := range previous.Nameservers {
+			if previousNameserver.Host == nameserver.Host &&
+				previousNameserver.LastStatus != NameserverStatusNotChecked &&
+				previousNameserver.LastStatus != NameserverStatusOK {
+
+				recovery.Nameservers = append(recovery.Nameservers, previousNameserver)
+				break
+			}
+		}
+	}
+
+	for _, ds := range current.DSSet {
+		if ds.LastStatus != DSStatusOK {
+			continue
+		}
+
+		for _, previousDS := range previous.DSSet {
+			if previousDS.Keytag == ds.Keytag &&
+				previousDS.LastStatus != DSStatusNotChecked &&
+				previousDS.LastStatus != DSStatusOK {
+
+				recovery.DSSet = append(recovery.DSSet, previousDS)
+				break
+			}
+		}
+	}
+
+	return recovery
+}
+
+// Empty returns true when no problem was solved
+func (r DomainRecovery) Empty() bool {
+	return len(r.Nameservers) == 0 && len(r.DSSet) == 0
+}
+
+// Problems builds a sorted list that identifies the solved problems, in the same format
+// used by NotificationProblems
+func (r DomainRecovery) Problems() []string {
+	var problems []string
+
+	for _, nameserver := range r.Nameservers {
+		problems = append(problems, nameserverProblem(nameserver))
+	}
+
+	for _, ds := range r.DSSet {
+		problems = append(problems, dsProblem(ds))
+	}
+
+	sort.Strings(problems)
+	return problems
+}
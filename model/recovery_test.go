@@ -0,0 +1,82 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package model describes the objects of the system
+package model
+
+import (
+	"testing"
+)
+
+func TestNewDomainRecovery(t *testing.T) {
+	previous := Domain{
+		FQDN: "example.com.br.",
+		Nameservers: []Nameserver{
+			{Host: "ns1.example.com.br.", LastStatus: NameserverStatusTimeout},
+			{Host: "ns2.example.com.br.", LastStatus: NameserverStatusOK},
+			{Host: "ns3.example.com.br.", LastStatus: NameserverStatusNotChecked},
+			{Host: "ns4.example.com.br.", LastStatus: NameserverStatusServerFailure},
+			{Host: "ns5.example.com.br.", LastStatus: NameserverStatusServerFailure},
+		},
+		DSSet: []DS{
+			{Keytag: 1234, LastStatus: DSStatusExpiredSignature},
+			{Keytag: 4321, LastStatus: DSStatusNoSignature},
+		},
+	}
+
+	current := Domain{
+		FQDN: "example.com.br.",
+		Nameservers: []Nameserver{
+			{Host: "ns1.example.com.br.", LastStatus: NameserverStatusOK},
+			{Host: "ns2.example.com.br.", LastStatus: NameserverStatusOK},
+			{Host: "ns3.example.com.br.", LastStatus: NameserverStatusOK},
+			{Host: "ns4.example.com.br.", LastStatus: NameserverStatusTimeout},
+			{Host: "ns6.example.com.br.", LastStatus: NameserverStatusOK},
+		},
+		DSSet: []DS{
+			{Keytag: 1234, LastStatus: DSStatusOK},
+			{Keytag: 4321, LastStatus: DSStatusNoSignature},
+		},
+	}
+
+	recovery := NewDomainRecovery(previous, current)
+
+	if recovery.Empty() {
+		t.Fatal("Not detecting the solved problems")
+	}
+
+	if recovery.Domain.FQDN != "example.com.br." ||
+		recovery.Domain.Nameservers[0].LastStatus != NameserverStatusOK {
+
+		t.Error("Not keeping the current state of the domain")
+	}
+
+	if len(recovery.Nameservers) != 1 ||
+		recovery.Nameservers[0].Host != "ns1.example.com.br." ||
+		recovery.Nameservers[0].LastStatus != NameserverStatusTimeout {
+
+		t.Errorf("Not detecting the recovered nameservers correctly: %#v", recovery.Nameservers)
+	}
+
+	if len(recovery.DSSet) != 1 ||
+		recovery.DSSet[0].Keytag != 1234 ||
+		recovery.DSSet[0].LastStatus != DSStatusExpiredSignature {
+
+		t.Errorf("Not detecting the recovered DS records correctly: %#v", recovery.DSSet)
+	}
+
+	expected := []string{
+		"ds 1234 EXPSIG",
+		"nameserver ns1.example.com.br. TIMEOUT",
+	}
+
+	if problems := recovery.Problems(); !SameProblems(problems, expected) {
+		t.Errorf("Not listing the solved problems correctly. Expected %v and got %v",
+			expected, problems)
+	}
+
+	if !NewDomainRecovery(current, current).Empty() {
+		t.Error("Detecting solved problems when nothing changed")
+	}
+}
@@ -6,6 +6,7 @@
 package model
 
 import (
+	"errors"
 	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2/bson"
 	"github.com/rafaeljusto/shelter/scheduler"
 	"sync"
@@ -18,16 +19,44 @@ import (
 var (
 	shelterCurrentScan     CurrentScan // Store all data of the current scan
 	shelterCurrentScanLock sync.Mutex  // Make current scan thread safe
+
+	// Control of the scan in progress. The scan steps wait in the condition while the scan
+	// is paused, and the status before the pause is restored when the scan is resumed
+	shelterCurrentScanControl      = sync.NewCond(&shelterCurrentScanLock)
+	shelterCurrentScanCancelled    bool
+	shelterCurrentScanResumeStatus ScanStatus
+	shelterCurrentScanPauses       uint64 // Number of pauses, to identify the current one
+)
+
+var (
+	// MaxScanPauseDuration is the maximum time that a scan stays paused, after that the
+	// scan is resumed automatically. The injector keeps the database cursor of the domains
+	// open while the scan is paused, and MongoDB closes idle cursors after 10 minutes,
+	// which would finish the scan with errors
+	MaxScanPauseDuration = 5 * time.Minute
+)
+
+var (
+	// Error returned when a scan is requested while other scan is loading data or running.
+	// Two scans at the same time would overload the nameservers and mix the statistics
+	ErrScanRunning = errors.New("There's already a scan running")
+
+	// Error returned when trying to pause, resume or cancel a scan that isn't in a state
+	// that allows the action
+	ErrScanNotRunning = errors.New("There's no scan in a state that allows this action")
 )
 
 // List of possible values of a scan status. The status ScanStatusWaitingExecution,
-// ScanStatusLoadingData, ScanStatusRunning will only be visible in a CurrentScan struct
+// ScanStatusLoadingData, ScanStatusRunning and ScanStatusPaused will only be visible in a
+// CurrentScan struct
 const (
 	ScanStatusWaitingExecution   ScanStatus = 0 // The scan is going to be executed in the future
 	ScanStatusLoadingData        ScanStatus = 1 // Loading the domains objects for Scan
 	ScanStatusRunning            ScanStatus = 2 // The scan is current scanning the system
 	ScanStatusExecuted           ScanStatus = 3 // Scan alredy finished succesfully
 	ScanStatusExecutedWithErrors ScanStatus = 4 // Scan had problems during the execution
+	ScanStatusCancelled          ScanStatus = 5 // Scan was cancelled by the user, only part of the domains were checked
+	ScanStatusPaused             ScanStatus = 6 // Scan is waiting for the user to resume it
 )
 
 // We keep a state from the scan to identify scans that had problem or not, and the current
@@ -47,6 +76,10 @@ func ScanStatusToString(status ScanStatus) string {
 		return "EXECUTED"
 	case ScanStatusExecutedWithErrors:
 		return "EXECUTEDWITHERRORS"
+	case ScanStatusCancelled:
+		return "CANCELLED"
+	case ScanStatusPaused:
+		return "PAUSED"
 	}
 
 	return ""
@@ -108,6 +141,29 @@ func StartNewScan() {
 	shelterCurrentScanLock.Lock()
 	defer shelterCurrentScanLock.Unlock()
 
+	startNewScan()
+}
+
+// StartNewScanIfIdle alerts that a new scan is going to be started, as StartNewScan does,
+// but only when there's no other scan loading data or running. Otherwise the current scan
+// information is kept and ErrScanRunning is returned
+func StartNewScanIfIdle() error {
+	shelterCurrentScanLock.Lock()
+	defer shelterCurrentScanLock.Unlock()
+
+	if inProgress(shelterCurrentScan.Status) {
+		return ErrScanRunning
+	}
+
+	startNewScan()
+	return nil
+}
+
+// Reset the current scan information for a scan that is starting now. The caller must
+// hold the current scan lock
+func startNewScan() {
+	shelterCurrentScanCancelled = false
+
 	shelterCurrentScan = CurrentScan{
 		Scan: Scan{
 			Status:               ScanStatusLoadingData,
@@ -117,6 +173,8 @@ func StartNewScan() {
 		},
 		LastModifiedAt: time.Now(),
 	}
+
+	publishScanStatusEvent()
 }
 
 // FinishAndSaveScan was created to alert that the scan being executed finished. This
@@ -128,13 +186,20 @@ func FinishAndSaveScan(hadErrors bool, f func(*Scan) error) error {
 	shelterCurrentScanLock.Lock()
 	defer shelterCurrentScanLock.Unlock()
 
-	if hadErrors {
+	if shelterCurrentScanCancelled {
+		shelterCurrentScan.Status = ScanStatusCancelled
+	} else if hadErrors {
 		shelterCurrentScan.Status = ScanStatusExecutedWithErrors
 	} else {
 		shelterCurrentScan.Status = ScanStatusExecuted
 	}
 
+	// Release any scan step that could still be waiting for a resume
+	shelterCurrentScanCancelled = false
+	shelterCurrentScanControl.Broadcast()
+
 	shelterCurrentScan.FinishedAt = time.Now()
+	publishScanStatusEvent()
 
 	// Save the scan
 	err := f(&shelterCurrentScan.Scan)
@@ -183,8 +248,116 @@ func FinishLoadingDomainsForScan() {
 	shelterCurrentScanLock.Lock()
 	defer shelterCurrentScanLock.Unlock()
 
-	shelterCurrentScan.Status = ScanStatusRunning
+	// When the scan is paused, the new status is only visible after resuming it
+	if shelterCurrentScan.Status == ScanStatusPaused {
+		shelterCurrentScanResumeStatus = ScanStatusRunning
+	} else {
+		shelterCurrentScan.Status = ScanStatusRunning
+	}
+
+	shelterCurrentScan.LastModifiedAt = time.Now()
+	publishScanStatusEvent()
+}
+
+// PauseScan stops the scan in progress until ResumeScan or CancelScan is called. The
+// domains that are already being checked are finished, but no new domain is checked while
+// the scan is paused
+func PauseScan() error {
+	shelterCurrentScanLock.Lock()
+	defer shelterCurrentScanLock.Unlock()
+
+	if shelterCurrentScanCancelled ||
+		(shelterCurrentScan.Status != ScanStatusLoadingData &&
+			shelterCurrentScan.Status != ScanStatusRunning) {
+		return ErrScanNotRunning
+	}
+
+	shelterCurrentScanResumeStatus = shelterCurrentScan.Status
+	shelterCurrentScan.Status = ScanStatusPaused
+	shelterCurrentScan.LastModifiedAt = time.Now()
+	publishScanStatusEvent()
+
+	// Only the pause that started the timer can be ended by it, the scan could be resumed
+	// and paused again in the meantime
+	shelterCurrentScanPauses++
+	pause := shelterCurrentScanPauses
+
+	time.AfterFunc(MaxScanPauseDuration, func() {
+		shelterCurrentScanLock.Lock()
+		defer shelterCurrentScanLock.Unlock()
+
+		if shelterCurrentScan.Status == ScanStatusPaused && shelterCurrentScanPauses == pause {
+			resumeScan()
+		}
+	})
+
+	return nil
+}
+
+// ResumeScan continues a paused scan from where it stopped. A paused scan is also
+// resumed automatically after MaxScanPauseDuration
+func ResumeScan() error {
+	shelterCurrentScanLock.Lock()
+	defer shelterCurrentScanLock.Unlock()
+
+	if shelterCurrentScan.Status != ScanStatusPaused {
+		return ErrScanNotRunning
+	}
+
+	resumeScan()
+	return nil
+}
+
+// Restore the status of the scan before the pause and release the scan steps. The caller
+// must hold the scan lock
+func resumeScan() {
+	shelterCurrentScan.Status = shelterCurrentScanResumeStatus
+	shelterCurrentScan.LastModifiedAt = time.Now()
+	shelterCurrentScanControl.Broadcast()
+	publishScanStatusEvent()
+}
+
+// CancelScan alerts the scan steps to stop checking domains. The scan finishes after the
+// domains already checked are saved, and it's stored with the status
+// ScanStatusCancelled, so that the partial results can be analyzed later
+func CancelScan() error {
+	shelterCurrentScanLock.Lock()
+	defer shelterCurrentScanLock.Unlock()
+
+	if shelterCurrentScanCancelled || !inProgress(shelterCurrentScan.Status) {
+		return ErrScanNotRunning
+	}
+
+	if shelterCurrentScan.Status == ScanStatusPaused {
+		shelterCurrentScan.Status = shelterCurrentScanResumeStatus
+	}
+
+	shelterCurrentScanCancelled = true
 	shelterCurrentScan.LastModifiedAt = time.Now()
+	shelterCurrentScanControl.Broadcast()
+	publishScanStatusEvent()
+	return nil
+}
+
+// ContinueScan is called by the scan steps before checking each domain. It blocks while
+// the scan is paused and returns false when the scan was cancelled, in this case the
+// domain must be ignored
+func ContinueScan() bool {
+	shelterCurrentScanLock.Lock()
+	defer shelterCurrentScanLock.Unlock()
+
+	for shelterCurrentScan.Status == ScanStatusPaused && !shelterCurrentScanCancelled {
+		shelterCurrentScanControl.Wait()
+	}
+
+	return !shelterCurrentScanCancelled
+}
+
+// Check if the scan status represents a scan that started and didn't finish yet
+func inProgress(status ScanStatus) bool {
+	return status == ScanStatusLoadingData ||
+		status == ScanStatusRunning ||
+		status == ScanStatusPaused
 }
 
 // When the collector receives a domain it tells the scan information structure to help
@@ -0,0 +1,109 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package model describes the objects of the system
+package model
+
+import (
+	"sync"
+)
+
+// Number of events that are stored for each subscriber while it's busy. When the buffer
+// is full the new events are discarded for the subscriber, so that a slow subscriber
+// never blocks the scan
+const scanEventBufferSize = 100
+
+// Global variables that will store the subscribers of the scan events. They are
+// protected by a different lock from the current scan, because the events are published
+// while the current scan lock is held
+var (
+	scanEventSubscribers     = make(map[chan ScanEvent]bool)
+	scanEventSubscribersLock sync.RWMutex
+)
+
+// List of possible types of scan events
+const (
+	ScanEventTypeStatus ScanEventType = 0 // The status of the current scan changed
+	ScanEventTypeDomain ScanEventType = 1 // A domain was checked and saved by the scan
+)
+
+// ScanEventType identifies what happened in the scan, defining which fields of the event
+// are filled
+type ScanEventType int
+
+// Convert the scan event type enum to text, that is used as the name of the event when
+// sending it to the user
+func ScanEventTypeToString(eventType ScanEventType) string {
+	switch eventType {
+	case ScanEventTypeStatus:
+		return "status"
+	case ScanEventTypeDomain:
+		return "domain"
+	}
+
+	return ""
+}
+
+// ScanEvent is something that happened in the current scan, used to follow the scan
+// while it runs without checking the current scan all the time
+type ScanEvent struct {
+	Type   ScanEventType   // Type of the event
+	Scan   CurrentScan     // State of the current scan when the event happened
+	Domain *DomainSnapshot // Result of the domain check, only for domain events
+}
+
+// SubscribeScanEvents registers a new subscriber of the scan events. The returned
+// function must be called when the subscriber doesn't want to receive more events, it
+// removes the subscriber and closes the channel
+func SubscribeScanEvents() (<-chan ScanEvent, func()) {
+	events := make(chan ScanEvent, scanEventBufferSize)
+
+	scanEventSubscribersLock.Lock()
+	scanEventSubscribers[events] = true
+	scanEventSubscribersLock.Unlock()
+
+	var once sync.Once
+	return events, func() {
+		once.Do(func() {
+			scanEventSubscribersLock.Lock()
+			delete(scanEventSubscribers, events)
+			scanEventSubscribersLock.Unlock()
+
+			close(events)
+		})
+	}
+}
+
+// PublishDomainScanEvent alerts the subscribers that a domain was checked and saved by
+// the current scan
+func PublishDomainScanEvent(snapshot DomainSnapshot) {
+	publishScanEvent(ScanEvent{
+		Type:   ScanEventTypeDomain,
+		Scan:   GetCurrentScan(),
+		Domain: &snapshot,
+	})
+}
+
+// Alert the subscribers that the status of the current scan changed. The caller must
+// hold the current scan lock
+func publishScanStatusEvent() {
+	publishScanEvent(ScanEvent{
+		Type: ScanEventTypeStatus,
+		Scan: shelterCurrentScan,
+	})
+}
+
+// Send the event to all subscribers without blocking. Subscribers with the buffer full
+// lose the event
+func publishScanEvent(event ScanEvent) {
+	scanEventSubscribersLock.RLock()
+	defer scanEventSubscribersLock.RUnlock()
+
+	for subscriber := range scanEventSubscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package model describes the objects of the system
+package model
+
+import (
+	"testing"
+)
+
+func TestScanEventTypeToString(t *testing.T) {
+	if text := ScanEventTypeToString(ScanEventType(9999)); len(text) > 0 {
+		t.Error("Not returning empty string when is an unknown scan event type")
+	}
+
+	if text := ScanEventTypeToString(ScanEventTypeDomain); text != "domain" {
+		t.Error("Not returning the correct text for the domain event")
+	}
+}
+
+func TestSubscribeScanEvents(t *testing.T) {
+	events, unsubscribe := SubscribeScanEvents()
+
+	StartNewScan()
+	FinishLoadingDomainsForScan()
+	PublishDomainScanEvent(DomainSnapshot{FQDN: "example.com.br."})
+
+	if err := FinishAndSaveScan(false, func(s *Scan) error { return nil }); err == nil {
+		t.Error("Not alerting about scheduler without scan job")
+	}
+
+	expectedEvents := []struct {
+		Type   ScanEventType
+		Status ScanStatus
+	}{
+		{Type: ScanEventTypeStatus, Status: ScanStatusLoadingData},
+		{Type: ScanEventTypeStatus, Status: ScanStatusRunning},
+		{Type: ScanEventTypeDomain, Status: ScanStatusRunning},
+		{Type: ScanEventTypeStatus, Status: ScanStatusExecuted},
+	}
+
+	for i, expected := range expectedEvents {
+		select {
+		case event := <-events:
+			if event.Type != expected.Type || event.Scan.Status != expected.Status {
+				t.Errorf("Wrong event %d. Expected type %d with status %d and got type %d "+
+					"with status %d", i, expected.Type, expected.Status, event.Type, event.Scan.Status)
+			}
+
+			if event.Type == ScanEventTypeDomain &&
+				(event.Domain == nil || event.Domain.FQDN != "example.com.br.") {
+				t.Error("Not sending the domain result in the event")
+			}
+
+		default:
+			t.Fatalf("Event %d was not published", i)
+		}
+	}
+
+	// Subscribers that aren't reading the events cannot block the scan
+	for i := 0; i < scanEventBufferSize+10; i++ {
+		PublishDomainScanEvent(DomainSnapshot{})
+	}
+
+	unsubscribe()
+	unsubscribe()
+
+	if _, ok := <-events; !ok {
+		t.Error("Discarding the events that were already published")
+	}
+
+	PublishDomainScanEvent(DomainSnapshot{})
+
+	scanEventSubscribersLock.RLock()
+	numberOfSubscribers := len(scanEventSubscribers)
+	scanEventSubscribersLock.RUnlock()
+
+	if numberOfSubscribers > 0 {
+		t.Error("Not removing the subscriber")
+	}
+}
+
+func TestScanEventsPauseAndCancel(t *testing.T) {
+	events, unsubscribe := SubscribeScanEvents()
+	defer unsubscribe()
+
+	StartNewScan()
+
+	if err := PauseScan(); err != nil {
+		t.Fatal("Not pausing a scan that is loading data. Details:", err)
+	}
+
+	if err := CancelScan(); err != nil {
+		t.Fatal("Not cancelling a paused scan. Details:", err)
+	}
+
+	// The cancelled scan leaves the pause, returning to the status before it
+	expectedStatus := []ScanStatus{
+		ScanStatusLoadingData,
+		ScanStatusPaused,
+		ScanStatusLoadingData,
+	}
+
+	for i, expected := range expectedStatus {
+		select {
+		case event := <-events:
+			if event.Type != ScanEventTypeStatus || event.Scan.Status != expected {
+				t.Errorf("Wrong event %d. Expected status %d and got type %d with status %d",
+					i, expected, event.Type, event.Scan.Status)
+			}
+
+		default:
+			t.Fatalf("Event %d was not published", i)
+		}
+	}
+}
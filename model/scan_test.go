@@ -58,6 +58,207 @@ func TestStartNewScan(t *testing.T) {
 	}
 }
 
+func TestStartNewScanIfIdle(t *testing.T) {
+	shelterCurrentScan.Status = ScanStatusWaitingExecution
+
+	if err := StartNewScanIfIdle(); err != nil {
+		t.Fatal("Not starting a scan when there's no scan running. Details:", err)
+	}
+
+	if shelterCurrentScan.Status != ScanStatusLoadingData {
+		t.Error("Not setting start scan information correctly")
+	}
+
+	LoadedDomainForScan()
+
+	if err := StartNewScanIfIdle(); err != ErrScanRunning {
+		t.Error("Starting a scan while other scan is loading data")
+	}
+
+	FinishLoadingDomainsForScan()
+
+	if err := StartNewScanIfIdle(); err != ErrScanRunning {
+		t.Error("Starting a scan while other scan is running")
+	}
+
+	if shelterCurrentScan.DomainsToBeScanned != 1 {
+		t.Error("Changing the information of the scan in progress")
+	}
+
+	shelterCurrentScan.Status = ScanStatusExecuted
+
+	if err := StartNewScanIfIdle(); err != nil {
+		t.Error("Not starting a scan after the last one finished. Details:", err)
+	}
+}
+
+func TestPauseAndResumeScan(t *testing.T) {
+	shelterCurrentScan.Status = ScanStatusWaitingExecution
+
+	if err := PauseScan(); err != ErrScanNotRunning {
+		t.Error("Pausing a scan that isn't running")
+	}
+
+	StartNewScan()
+
+	if err := PauseScan(); err != nil {
+		t.Fatal("Not pausing a scan that is loading data. Details:", err)
+	}
+
+	if err := StartNewScanIfIdle(); err != ErrScanRunning {
+		t.Error("Starting a scan while other scan is paused")
+	}
+
+	continued := make(chan bool)
+	go func() {
+		continued <- ContinueScan()
+	}()
+
+	select {
+	case <-continued:
+		t.Fatal("Scan step not waiting while the scan is paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The injector finished while paused, the scan must be resumed as running
+	FinishLoadingDomainsForScan()
+
+	if GetCurrentScan().Status != ScanStatusPaused {
+		t.Error("Losing the pause when the domains finished loading")
+	}
+
+	if err := ResumeScan(); err != nil {
+		t.Fatal("Not resuming a paused scan. Details:", err)
+	}
+
+	select {
+	case ok := <-continued:
+		if !ok {
+			t.Error("Scan step not continuing after resuming the scan")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Scan step still waiting after resuming the scan")
+	}
+
+	if GetCurrentScan().Status != ScanStatusRunning {
+		t.Error("Not restoring the scan status after resuming it")
+	}
+
+	if err := ResumeScan(); err != ErrScanNotRunning {
+		t.Error("Resuming a scan that isn't paused")
+	}
+}
+
+func TestPauseScanMaxDuration(t *testing.T) {
+	maxScanPauseDuration := MaxScanPauseDuration
+	MaxScanPauseDuration = 200 * time.Millisecond
+	defer func() {
+		MaxScanPauseDuration = maxScanPauseDuration
+	}()
+
+	shelterCurrentScan.Status = ScanStatusWaitingExecution
+	StartNewScan()
+
+	if err := PauseScan(); err != nil {
+		t.Fatal("Not pausing a scan that is loading data. Details:", err)
+	}
+
+	// Resuming and pausing again must not be affected by the timer of the first pause
+	if err := ResumeScan(); err != nil {
+		t.Fatal("Not resuming a paused scan. Details:", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if err := PauseScan(); err != nil {
+		t.Fatal("Not pausing a scan that is loading data. Details:", err)
+	}
+
+	time.Sleep(140 * time.Millisecond)
+
+	if GetCurrentScan().Status != ScanStatusPaused {
+		t.Error("Resuming the scan with the timer of an old pause")
+	}
+
+	continued := make(chan bool)
+	go func() {
+		continued <- ContinueScan()
+	}()
+
+	select {
+	case ok := <-continued:
+		if !ok {
+			t.Error("Scan step not continuing after the maximum pause")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Scan still paused after the maximum pause")
+	}
+
+	if GetCurrentScan().Status != ScanStatusLoadingData {
+		t.Error("Not restoring the scan status after the maximum pause")
+	}
+}
+
+func TestCancelScan(t *testing.T) {
+	scheduler.Register(scheduler.Job{
+		Type:          scheduler.JobTypeScan,
+		NextExecution: time.Now().Add(10 * time.Minute),
+		Task:          func() {},
+	})
+	defer scheduler.Clear()
+
+	shelterCurrentScan.Status = ScanStatusWaitingExecution
+
+	if err := CancelScan(); err != ErrScanNotRunning {
+		t.Error("Cancelling a scan that isn't running")
+	}
+
+	StartNewScan()
+
+	if err := PauseScan(); err != nil {
+		t.Fatal("Not pausing a scan that is loading data. Details:", err)
+	}
+
+	continued := make(chan bool)
+	go func() {
+		continued <- ContinueScan()
+	}()
+
+	if err := CancelScan(); err != nil {
+		t.Fatal("Not cancelling a paused scan. Details:", err)
+	}
+
+	select {
+	case ok := <-continued:
+		if ok {
+			t.Error("Scan step continuing after cancelling the scan")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Scan step still waiting after cancelling the scan")
+	}
+
+	if err := CancelScan(); err != ErrScanNotRunning {
+		t.Error("Cancelling a scan twice")
+	}
+
+	if err := PauseScan(); err != ErrScanNotRunning {
+		t.Error("Pausing a cancelled scan")
+	}
+
+	if err := FinishAndSaveScan(false, func(s *Scan) error {
+		if s.Status != ScanStatusCancelled {
+			t.Error("Not saving the scan as cancelled")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !ContinueScan() {
+		t.Error("Keeping the cancellation after the scan finished")
+	}
+}
+
 func TestFinishAndSaveScan(t *testing.T) {
 	scheduler.Register(scheduler.Job{
 		Type:          scheduler.JobTypeScan,
@@ -246,6 +447,14 @@ func TestScanStatusToString(t *testing.T) {
 		t.Error("Scan status WAITINGEXECUTION not converting correctly to string")
 	}
 
+	if ScanStatusToString(ScanStatusCancelled) != "CANCELLED" {
+		t.Error("Scan status CANCELLED not converting correctly to string")
+	}
+
+	if ScanStatusToString(ScanStatusPaused) != "PAUSED" {
+		t.Error("Scan status PAUSED not converting correctly to string")
+	}
+
 	if ScanStatusToString(999999) != "" {
 		t.Error("Unknown scan status associated to some existing status")
 	}
@@ -81,9 +81,15 @@ func signAndSend(r *http.Request, content []byte) (*http.Response, error) {
 		r.Header.Set("Content-MD5", hashBase64)
 	}
 
+	// The web client manages all domains, so it prefers a secret without principal, that
+	// is an admin of all domains
 	var key, s string
-	for key, s = range config.ShelterConfig.RESTServer.Secrets {
-		break
+	for currentKey, currentSecret := range config.ShelterConfig.RESTServer.Secrets {
+		key, s = currentKey, currentSecret
+
+		if _, hasPrincipal := config.ShelterConfig.RESTServer.Principals[key]; !hasPrincipal {
+			break
+		}
 	}
 
 	if len(key) == 0 || len(s) == 0 {
@@ -56,6 +56,11 @@ func TestRetrieveRESTAddress(t *testing.T) {
 
 			ACL     []string
 			Secrets map[string]string
+
+			Principals map[string]struct {
+				Role    string
+				Domains []string
+			}
 		}{
 			Listeners: listeners,
 		},
@@ -102,6 +107,11 @@ func TestRetrieveRESTAddress(t *testing.T) {
 
 			ACL     []string
 			Secrets map[string]string
+
+			Principals map[string]struct {
+				Role    string
+				Domains []string
+			}
 		}{
 			Listeners: listeners,
 		},
@@ -147,6 +157,11 @@ func TestSignAndSend(t *testing.T) {
 
 			ACL     []string
 			Secrets map[string]string
+
+			Principals map[string]struct {
+				Role    string
+				Domains []string
+			}
 		}{
 			Secrets: map[string]string{
 				"key01": "ohV43/9bKlVNaXeNTqEuHQp57LCPCQ==",
@@ -22,6 +22,15 @@ const (
 	// the idea is to support in a near future XML
 	SupportedContentType = "application/vnd.shelter+json"
 
+	// Content types of the patch documents (RFC 7396 and RFC 6902) that are also accepted in
+	// PATCH requests
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+
+	// Content type of the resources that send Server-Sent Events, like the progress of the
+	// current scan
+	EventStreamContentType = "text/event-stream"
+
 	// Define the supported charset of the system. For now we use for everything utf-8, from database
 	// to data manipulation. There's no conversion for any special charset
 	SupportedCharset = "utf-8"
@@ -69,6 +78,27 @@ func HTTPAccept(r *http.Request) bool {
 	return false
 }
 
+// Resources that send Server-Sent Events also answer in the event stream format. This
+// method check if the user explicitly supports this format
+func HTTPAcceptEventStream(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	accept = strings.ToLower(accept)
+
+	for _, acceptPart := range strings.Split(accept, ",") {
+		acceptPart = strings.TrimSpace(acceptPart)
+
+		if idx := strings.Index(acceptPart, ";"); idx > 0 {
+			acceptPart = acceptPart[0:idx]
+		}
+
+		if acceptPart == EventStreamContentType {
+			return true
+		}
+	}
+
+	return false
+}
+
 // The accept language check beyond verifying if the language exists in out system, set
 // the first language found in the context
 func HTTPAcceptLanguage(r *http.Request) (*messages.LanguagePack, bool) {
@@ -129,7 +159,8 @@ func HTTPAcceptCharset(r *http.Request) bool {
 }
 
 // Check the user current content type format. For now we only accept JSON content respecting the
-// Shelter protocol, but in a near future we plan to accept XML too
+// Shelter protocol, and the JSON patch formats in PATCH requests, but in a near future we plan to
+// accept XML too
 func HTTPContentType(r *http.Request) bool {
 	contentType := getHTTPContentType(r)
 	if len(contentType) == 0 {
@@ -167,6 +198,11 @@ func HTTPContentType(r *http.Request) bool {
 		contentType = contentType[0:idx]
 	}
 
+	if r.Method == "PATCH" &&
+		(contentType == MergePatchContentType || contentType == JSONPatchContentType) {
+		return true
+	}
+
 	return contentType == SupportedContentType
 }
 
@@ -49,6 +49,28 @@ func TestHTTPAccept(t *testing.T) {
 	}
 }
 
+func TestHTTPAcceptEventStream(t *testing.T) {
+	r, err := http.NewRequest("", "", nil)
+	if err != nil {
+		t.Fatal("Error creating the request. Details:", err)
+	}
+
+	r.Header.Set("Accept", "")
+	if HTTPAcceptEventStream(r) {
+		t.Error("Accepting event stream when there's no HTTP Accept header field")
+	}
+
+	r.Header.Set("Accept", SupportedContentType)
+	if HTTPAcceptEventStream(r) {
+		t.Error("Accepting event stream when only the system format is supported")
+	}
+
+	r.Header.Set("Accept", SupportedContentType+",  TEXT/EVENT-STREAM;q=0.9 ")
+	if !HTTPAcceptEventStream(r) {
+		t.Error("Not accepting event stream with different case, spaces and options")
+	}
+}
+
 func TestHTTPAcceptLanguage(t *testing.T) {
 	r, err := http.NewRequest("", "", nil)
 	if err != nil {
@@ -168,6 +190,19 @@ func TestHTTPContentType(t *testing.T) {
 	if !HTTPContentType(r) {
 		t.Error("Not accepting a valid charset")
 	}
+
+	r.Header.Set("Content-Type", JSONPatchContentType)
+	if HTTPContentType(r) {
+		t.Error("Accepting a patch content type outside a PATCH request")
+	}
+
+	r.Method = "PATCH"
+	for _, contentType := range []string{MergePatchContentType, JSONPatchContentType + "; charset=utf-8"} {
+		r.Header.Set("Content-Type", contentType)
+		if !HTTPContentType(r) {
+			t.Errorf("Not accepting the patch content type %s", contentType)
+		}
+	}
 }
 
 func TestHTTPContentMD5(t *testing.T) {
@@ -0,0 +1,339 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package handler store the REST handlers of specific URI
+package handler
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/http/rest/interceptor"
+	"github.com/rafaeljusto/shelter/net/http/rest/messages"
+	"github.com/rafaeljusto/shelter/net/http/rest/protocol"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	HandleFunc("/audit", func() handy.Handler {
+		return new(AuditHandler)
+	})
+}
+
+// AuditHandler is responsable for keeping the state of a /audit resource, that returns
+// the changes made in the domains through the REST server. The changes can be filtered
+// by domain, principal and period
+type AuditHandler struct {
+	handy.DefaultHandler                           // Inject the HTTP methods that this resource does not implement
+	database             *mgo.Database             // Database connection of the MongoDB session
+	databaseSession      *mgo.Session              // MongoDB session
+	storage              dao.Storage               // Persistence backend of the domains and scans
+	language             *messages.LanguagePack    // User preferred language based on HTTP header
+	principal            model.Principal           // Identity that signed the request
+	lastModifiedAt       time.Time                 // Most recent change date of the list
+	Response             *protocol.AuditResponse   `response:"get"` // Audit entries sent back to the user
+	Message              *protocol.MessageResponse `error`          // Message on error sent to the user
+}
+
+func (h *AuditHandler) SetDatabaseSession(session *mgo.Session) {
+	h.databaseSession = session
+}
+
+func (h *AuditHandler) GetDatabaseSession() *mgo.Session {
+	return h.databaseSession
+}
+
+func (h *AuditHandler) SetDatabase(database *mgo.Database) {
+	h.database = database
+}
+
+func (h *AuditHandler) GetDatabase() *mgo.Database {
+	return h.database
+}
+
+func (h *AuditHandler) SetStorage(storage dao.Storage) {
+	h.storage = storage
+}
+
+func (h *AuditHandler) GetStorage() dao.Storage {
+	return h.storage
+}
+
+func (h *AuditHandler) GetLastModifiedAt() time.Time {
+	return h.lastModifiedAt
+}
+
+// The ETag header will be the hash of the content on list services
+func (h *AuditHandler) GetETag() string {
+	body, err := json.Marshal(h.Response)
+	if err != nil {
+		return ""
+	}
+
+	hash := md5.New()
+	if _, err := hash.Write(body); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (h *AuditHandler) SetPrincipal(principal model.Principal) {
+	h.principal = principal
+}
+
+func (h *AuditHandler) SetLanguage(language *messages.LanguagePack) {
+	h.language = language
+}
+
+func (h *AuditHandler) GetLanguage() *messages.LanguagePack {
+	return h.language
+}
+
+func (h *AuditHandler) MessageResponse(messageId string, roid string) error {
+	var err error
+	h.Message, err = protocol.NewMessageResponse(messageId, roid, h.language)
+	return err
+}
+
+func (h *AuditHandler) ClearResponse() {
+	h.Response = nil
+}
+
+func (h *AuditHandler) Get(w http.ResponseWriter, r *http.Request) {
+	h.retrieveAudit(w, r)
+}
+
+func (h *AuditHandler) Head(w http.ResponseWriter, r *http.Request) {
+	h.retrieveAudit(w, r)
+}
+
+// The HEAD method is identical to GET except that the server MUST NOT return a message-
+// body in the response. But now the responsability for don't adding the body is from the
+// mux while writing the response
+func (h *AuditHandler) retrieveAudit(w http.ResponseWriter, r *http.Request) {
+	var pagination dao.AuditDAOPagination
+	var filter dao.AuditDAOFilter
+
+	for key, values := range r.URL.Query() {
+		key = strings.TrimSpace(key)
+		key = strings.ToLower(key)
+
+		// A key can have multiple values in a query string, we are going to always consider
+		// the last one (overwrite strategy)
+		for _, value := range values {
+			value = strings.TrimSpace(value)
+			value = strings.ToLower(value)
+
+			messageId := ""
+
+			switch key {
+			case "orderby":
+				// OrderBy parameter will store the fields that the user want to be the keys of the sort
+				// algorithm in the result set and the direction that each sort field will have. The format
+				// that will be used is:
+				//
+				// <field1>:<direction1>@<field2>:<direction2>@...@<fieldN>:<directionN>
+
+				for _, orderByPart := range strings.Split(value, "@") {
+					orderByPart = strings.TrimSpace(orderByPart)
+					orderByAndDirection := strings.Split(orderByPart, ":")
+
+					var field, direction string
+
+					if len(orderByAndDirection) == 1 {
+						field, direction = orderByAndDirection[0], "desc"
+
+					} else if len(orderByAndDirection) == 2 {
+						field, direction = orderByAndDirection[0], orderByAndDirection[1]
+
+					} else {
+						messageId = "invalid-query-order-by"
+						break
+					}
+
+					orderByField, err := dao.AuditDAOOrderByFieldFromString(field)
+					if err != nil {
+						messageId = "invalid-query-order-by"
+						break
+					}
+
+					orderByDirection, err := dao.DAOOrderByDirectionFromString(direction)
+					if err != nil {
+						messageId = "invalid-query-order-by"
+						break
+					}
+
+					pagination.OrderBy = append(pagination.OrderBy, dao.AuditDAOSort{
+						Field:     orderByField,
+						Direction: orderByDirection,
+					})
+				}
+
+			case "pagesize":
+				var err error
+				if pagination.PageSize, err = strconv.Atoi(value); err != nil {
+					messageId = "invalid-query-page-size"
+				}
+
+			case "page":
+				var err error
+				if pagination.Page, err = strconv.Atoi(value); err != nil {
+					messageId = "invalid-query-page"
+				}
+
+			case "fqdn":
+				var err error
+				if filter.FQDN, err = model.NormalizeDomainName(value); err != nil {
+					messageId = "invalid-query-filter"
+				}
+
+			case "principal":
+				filter.Principal = value
+
+			case "since":
+				var err error
+				if filter.Since, err = parseQueryDate(value); err != nil {
+					messageId = "invalid-query-filter"
+				}
+
+			case "until":
+				var err error
+				if filter.Until, err = parseQueryDate(value); err != nil {
+					messageId = "invalid-query-filter"
+				}
+			}
+
+			if len(messageId) > 0 {
+				if err := h.MessageResponse(messageId, ""); err == nil {
+					w.WriteHeader(http.StatusBadRequest)
+
+				} else {
+					log.Println("Error while writing response. Details:", err)
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+
+				return
+			}
+		}
+	}
+
+	// Principals restricted to some domains can only see the changes of their own domains
+	filter.Suffixes = h.principal.Domains
+
+	auditDAO := h.GetStorage().AuditDAO()
+
+	entries, err := auditDAO.FindAll(filter, &pagination)
+	if err != nil {
+		log.Println("Error while searching audit entries. Details:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	auditResponse := protocol.ToAuditResponse(entries, pagination, filter)
+	h.Response = &auditResponse
+
+	// Last-Modified is going to be the most recent date of the list
+	for _, entry := range entries {
+		if entry.Date.After(h.lastModifiedAt) {
+			h.lastModifiedAt = entry.Date
+		}
+	}
+
+	w.Header().Add("ETag", h.GetETag())
+	w.Header().Add("Last-Modified", h.lastModifiedAt.Format(time.RFC1123))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *AuditHandler) Interceptors() handy.InterceptorChain {
+	return handy.NewInterceptorChain().
+		Chain(new(interceptor.Permission)).
+		Chain(interceptor.NewValidator(h)).
+		Chain(interceptor.NewDatabase(h)).
+		Chain(interceptor.NewJSONCodec(h)).
+		Chain(interceptor.NewHTTPCacheAfter(h))
+}
+
+// recordDomainChange stores the change of a domain in the audit log, with the principal
+// and the client address of the request, and keeps the new configuration as a revision
+// that can be restored later. The domain was already changed, so errors are only logged
+func recordDomainChange(storage dao.Storage, r *http.Request, principal model.Principal,
+	before, after *model.Domain) {
+
+	domainChangeRecorder(r, principal)(storage, before, after)
+}
+
+// domainChangeRecorder works as recordDomainChange, but the information of the request is
+// copied when the recorder is built, so the changes can be recorded after the end of the
+// request (e.g. by the zone import job)
+func domainChangeRecorder(r *http.Request, principal model.Principal) func(storage dao.Storage,
+	before, after *model.Domain) {
+
+	var clientAddress string
+	if address, err := interceptor.ClientAddress(r); err == nil {
+		clientAddress = address.String()
+	} else {
+		log.Println("Error while identifying client address for audit. Details:", err)
+	}
+
+	method := r.Method
+
+	return func(storage dao.Storage, before, after *model.Domain) {
+		storeDomainChange(storage, method, clientAddress, principal, before, after)
+	}
+}
+
+// Store the audit entry and the revision of the domain change
+func storeDomainChange(storage dao.Storage, method, clientAddress string,
+	principal model.Principal, before, after *model.Domain) {
+
+	entry := model.NewAuditEntry(before, after)
+
+	// Updates that didn't change the configuration of the domain aren't stored
+	if before != nil && after != nil && len(entry.Changes) == 0 {
+		return
+	}
+
+	entry.Principal = principal.Id
+	entry.Method = method
+	entry.ClientAddress = clientAddress
+
+	auditDAO := storage.AuditDAO()
+	if err := auditDAO.Save(&entry); err != nil {
+		log.Println("Error while storing audit entry. Details:", err)
+	}
+
+	if after != nil {
+		domainRevisionDAO := storage.DomainRevisionDAO()
+
+		revision := model.NewDomainRevision(*after)
+		if err := domainRevisionDAO.Save(&revision); err != nil {
+			log.Println("Error while storing domain revision. Details:", err)
+		}
+	}
+}
+
+// The revisions of removed domains are kept, so that a domain created again can restore
+// an old configuration. To don't replace them, the new domain continues the numbering
+// after the last stored revision
+func continueDomainRevisions(storage dao.Storage, domain *model.Domain) error {
+	revision, err := storage.DomainRevisionDAO().FindLast(domain.FQDN)
+	if err == mgo.ErrNotFound {
+		return nil
+
+	} else if err != nil {
+		return err
+	}
+
+	domain.Revision = revision.Revision
+	return nil
+}
@@ -6,14 +6,17 @@
 package handler
 
 import (
+	"encoding/json"
 	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy"
 	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
 	"github.com/rafaeljusto/shelter/dao"
 	"github.com/rafaeljusto/shelter/log"
 	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/http/rest/check"
 	"github.com/rafaeljusto/shelter/net/http/rest/interceptor"
 	"github.com/rafaeljusto/shelter/net/http/rest/messages"
 	"github.com/rafaeljusto/shelter/net/http/rest/protocol"
+	"mime"
 	"net/http"
 	"strconv"
 	"strings"
@@ -31,12 +34,15 @@ type DomainHandler struct {
 	handy.DefaultHandler                           // Inject the HTTP methods that this resource does not implement
 	database             *mgo.Database             // Database connection of the MongoDB session
 	databaseSession      *mgo.Session              // MongoDB session
+	storage              dao.Storage               // Persistence backend of the domains and scans
 	domain               model.Domain              // Domain object related to the resource
 	language             *messages.LanguagePack    // User preferred language based on HTTP header
-	FQDN                 string                    `param:"fqdn"`   // FQDN defined in the URI
-	Request              protocol.DomainRequest    `request:"put"`  // Domain request sent by the user
-	Response             *protocol.DomainResponse  `response:"get"` // Domain response sent back to the user
-	Message              *protocol.MessageResponse `error`          // Message on error sent to the user
+	principal            model.Principal           // Identity that signed the request
+	FQDN                 string                    `param:"fqdn"`    // FQDN defined in the URI
+	Request              protocol.DomainRequest    `request:"put"`   // Domain request sent by the user
+	PatchRequest         json.RawMessage           `request:"patch"` // Merge patch or patch operations sent by the user
+	Response             *protocol.DomainResponse  `response:"get"`  // Domain response sent back to the user
+	Message              *protocol.MessageResponse `error`           // Message on error sent to the user
 }
 
 func (h *DomainHandler) SetDatabaseSession(session *mgo.Session) {
@@ -55,6 +61,14 @@ func (h *DomainHandler) GetDatabase() *mgo.Database {
 	return h.database
 }
 
+func (h *DomainHandler) SetStorage(storage dao.Storage) {
+	h.storage = storage
+}
+
+func (h *DomainHandler) GetStorage() dao.Storage {
+	return h.storage
+}
+
 func (h *DomainHandler) SetFQDN(fqdn string) {
 	h.FQDN = fqdn
 }
@@ -75,6 +89,10 @@ func (h *DomainHandler) GetETag() string {
 	return strconv.Itoa(h.domain.Revision)
 }
 
+func (h *DomainHandler) SetPrincipal(principal model.Principal) {
+	h.principal = principal
+}
+
 func (h *DomainHandler) SetLanguage(language *messages.LanguagePack) {
 	h.language = language
 }
@@ -105,6 +123,7 @@ func (h *DomainHandler) Head(w http.ResponseWriter, r *http.Request) {
 // body in the response. But now the responsability for don't adding the body is from the
 // mux while writing the response
 func (h *DomainHandler) retrieveDomain(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Accept-Patch", check.MergePatchContentType+", "+check.JSONPatchContentType)
 	w.Header().Add("ETag", h.GetETag())
 	w.Header().Add("Last-Modified", h.GetLastModifiedAt().Format(time.RFC1123))
 	w.WriteHeader(http.StatusOK)
@@ -117,26 +136,96 @@ func (h *DomainHandler) Put(w http.ResponseWriter, r *http.Request) {
 	// We need to set the FQDN in the domain request object because it is sent only in the
 	// URI and not in the domain request body to avoid information redudancy
 	h.Request.FQDN = h.GetFQDN()
+	h.saveDomain(w, r, h.Request)
+}
 
-	var err error
-	if h.domain, err = protocol.Merge(h.domain, h.Request); err != nil {
-		messageId := ""
+// Change only some parts of the domain, using a JSON merge patch (RFC 7396) or a list of
+// JSON patch operations (RFC 6902), identified by the content type of the request. The
+// patch is applied over the current configuration of the domain, and the result is
+// validated and stored as a normal update
+func (h *DomainHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	var patchType protocol.PatchType
+
+	// The content type was already checked, but it can be empty or the generic one
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch contentType {
+	case check.MergePatchContentType:
+		patchType = protocol.PatchTypeMerge
+	case check.JSONPatchContentType:
+		patchType = protocol.PatchTypeJSON
+	default:
+		h.writeUnsupportedPatchType(w, r)
+		return
+	}
+
+	request, err := protocol.PatchDomainRequest(protocol.ToDomainRequest(h.domain),
+		patchType, h.PatchRequest)
+
+	if err != nil {
+		var messageId string
+		status := http.StatusBadRequest
 
 		switch err {
-		case model.ErrInvalidFQDN:
-			messageId = "invalid-fqdn"
-		case protocol.ErrInvalidDNSKEY:
-			messageId = "invalid-dnskey"
-		case protocol.ErrInvalidDSAlgorithm:
-			messageId = "invalid-ds-algorithm"
-		case protocol.ErrInvalidDSDigestType:
-			messageId = "invalid-ds-digest-type"
-		case protocol.ErrInvalidIP:
-			messageId = "invalid-ip"
-		case protocol.ErrInvalidLanguage:
-			messageId = "invalid-language"
+		case protocol.ErrPatchTypeMismatch:
+			h.writeUnsupportedPatchType(w, r)
+			return
+		case protocol.ErrInvalidPatch:
+			messageId = "invalid-patch"
+		case protocol.ErrInvalidPatchPath:
+			messageId = "invalid-patch-path"
+		case protocol.ErrPatchTestFailed:
+			messageId = "patch-test-failed"
+			status = http.StatusConflict
+		default:
+			log.Println("Error while applying patch on domain object. Details:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
 		}
 
+		if err := h.MessageResponse(messageId, r.URL.RequestURI()); err == nil {
+			w.WriteHeader(status)
+
+		} else {
+			log.Println("Error while writing response. Details:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.saveDomain(w, r, request)
+}
+
+// Reject a patch document that isn't sent with the content type of its format, informing
+// the supported patch formats as described in RFC 5789
+func (h *DomainHandler) writeUnsupportedPatchType(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Accept-Patch", check.MergePatchContentType+", "+check.JSONPatchContentType)
+
+	if err := h.MessageResponse("unsupported-patch-type", r.URL.RequestURI()); err == nil {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+
+	} else {
+		log.Println("Error while writing response. Details:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// Merge the domain request with the current domain and store the result. Used to create
+// or update the domain
+func (h *DomainHandler) saveDomain(w http.ResponseWriter, r *http.Request,
+	request protocol.DomainRequest) {
+
+	// Keep the current state of the domain for the audit log and revisions
+	var before *model.Domain
+	if h.domain.Revision > 0 {
+		domain := h.domain
+		before = &domain
+	}
+
+	var err error
+	if h.domain, err = protocol.Merge(h.domain, request); err != nil {
+		messageId := getMergeErrorMessageId(err)
+
 		if len(messageId) == 0 {
 			log.Println("Error while merging domain objects for create or "+
 				"update operation. Details:", err)
@@ -154,12 +243,23 @@ func (h *DomainHandler) Put(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	domainDAO := dao.DomainDAO{
-		Database: h.GetDatabase(),
+	// Domains that don't exist yet are created by the principal that signed the request
+	if before == nil {
+		h.domain.CreatedBy = h.principal.Id
+
+		if err := continueDomainRevisions(h.GetStorage(), &h.domain); err != nil {
+			log.Println("Error while retrieving the last domain revision. Details:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 	}
 
+	domainDAO := h.GetStorage().DomainDAO()
+
 	if err := domainDAO.Save(&h.domain); err != nil {
-		if strings.Index(err.Error(), "duplicate key error index") != -1 {
+		if err == dao.ErrDAORevisionConflict ||
+			strings.Index(err.Error(), "duplicate key error index") != -1 {
+
 			if err := h.MessageResponse("conflict", r.URL.RequestURI()); err == nil {
 				w.WriteHeader(http.StatusConflict)
 
@@ -177,10 +277,12 @@ func (h *DomainHandler) Put(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordDomainChange(h.GetStorage(), r, h.principal, before, &h.domain)
+
 	w.Header().Add("ETag", h.GetETag())
 	w.Header().Add("Last-Modified", h.GetLastModifiedAt().Format(time.RFC1123))
 
-	if h.domain.Revision == 1 {
+	if before == nil {
 		w.Header().Add("Location", "/domain/"+h.domain.FQDN)
 		w.WriteHeader(http.StatusCreated)
 
@@ -190,9 +292,7 @@ func (h *DomainHandler) Put(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *DomainHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	domainDAO := dao.DomainDAO{
-		Database: h.GetDatabase(),
-	}
+	domainDAO := h.GetStorage().DomainDAO()
 
 	if err := domainDAO.Remove(&h.domain); err != nil {
 		log.Println("Error while removing domain object. Details:", err)
@@ -200,6 +300,28 @@ func (h *DomainHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The audit log and the revisions are kept after the domain removal, so we still know
+	// who removed it and the domain can be restored when created again
+	recordDomainChange(h.GetStorage(), r, h.principal, &h.domain, nil)
+
+	// The domain history is only stored when using MongoDB
+	if h.GetDatabase() != nil {
+		domainSnapshotDAO := dao.DomainSnapshotDAO{
+			Database: h.GetDatabase(),
+		}
+
+		// The domain history is useless without the domain, but the domain was already
+		// removed, so we only log if something went wrong
+		if err := domainSnapshotDAO.RemoveByFQDN(h.domain.FQDN); err != nil {
+			log.Println("Error while removing domain history. Details:", err)
+		}
+	}
+
+	notificationDAO := h.GetStorage().NotificationDAO()
+	if err := notificationDAO.RemoveByFQDN(h.domain.FQDN); err != nil {
+		log.Println("Error while removing domain notifications. Details:", err)
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -0,0 +1,297 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package handler store the REST handlers of specific URI
+package handler
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/http/rest/interceptor"
+	"github.com/rafaeljusto/shelter/net/http/rest/messages"
+	"github.com/rafaeljusto/shelter/net/http/rest/protocol"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	HandleFunc("/domain/{fqdn}/history", func() handy.Handler {
+		return new(DomainHistoryHandler)
+	})
+}
+
+// DomainHistoryHandler is responsable for keeping the state of a /domain/{fqdn}/history
+// resource, that returns the result of each scan for the domain
+type DomainHistoryHandler struct {
+	handy.DefaultHandler                                 // Inject the HTTP methods that this resource does not implement
+	database             *mgo.Database                   // Database connection of the MongoDB session
+	databaseSession      *mgo.Session                    // MongoDB session
+	storage              dao.Storage                     // Persistence backend of the domains and scans
+	domain               model.Domain                    // Domain object related to the resource
+	language             *messages.LanguagePack          // User preferred language based on HTTP header
+	principal            model.Principal                 // Identity that signed the request
+	lastModifiedAt       time.Time                       // Most recent check date of the history
+	FQDN                 string                          `param:"fqdn"`   // FQDN defined in the URI
+	Response             *protocol.DomainHistoryResponse `response:"get"` // Domain history sent back to the user
+	Message              *protocol.MessageResponse       `error`          // Message on error sent to the user
+}
+
+func (h *DomainHistoryHandler) SetDatabaseSession(session *mgo.Session) {
+	h.databaseSession = session
+}
+
+func (h *DomainHistoryHandler) GetDatabaseSession() *mgo.Session {
+	return h.databaseSession
+}
+
+func (h *DomainHistoryHandler) SetDatabase(database *mgo.Database) {
+	h.database = database
+}
+
+func (h *DomainHistoryHandler) GetDatabase() *mgo.Database {
+	return h.database
+}
+
+func (h *DomainHistoryHandler) SetStorage(storage dao.Storage) {
+	h.storage = storage
+}
+
+func (h *DomainHistoryHandler) GetStorage() dao.Storage {
+	return h.storage
+}
+
+func (h *DomainHistoryHandler) SetFQDN(fqdn string) {
+	h.FQDN = fqdn
+}
+
+func (h *DomainHistoryHandler) GetFQDN() string {
+	return h.FQDN
+}
+
+func (h *DomainHistoryHandler) SetDomain(domain model.Domain) {
+	h.domain = domain
+}
+
+func (h *DomainHistoryHandler) GetLastModifiedAt() time.Time {
+	return h.lastModifiedAt
+}
+
+// The ETag header will be the hash of the content on list services
+func (h *DomainHistoryHandler) GetETag() string {
+	body, err := json.Marshal(h.Response)
+	if err != nil {
+		return ""
+	}
+
+	hash := md5.New()
+	if _, err := hash.Write(body); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (h *DomainHistoryHandler) SetPrincipal(principal model.Principal) {
+	h.principal = principal
+}
+
+func (h *DomainHistoryHandler) SetLanguage(language *messages.LanguagePack) {
+	h.language = language
+}
+
+func (h *DomainHistoryHandler) GetLanguage() *messages.LanguagePack {
+	return h.language
+}
+
+func (h *DomainHistoryHandler) MessageResponse(messageId string, roid string) error {
+	var err error
+	h.Message, err = protocol.NewMessageResponse(messageId, roid, h.language)
+	return err
+}
+
+func (h *DomainHistoryHandler) ClearResponse() {
+	h.Response = nil
+}
+
+func (h *DomainHistoryHandler) Get(w http.ResponseWriter, r *http.Request) {
+	h.retrieveDomainHistory(w, r)
+}
+
+func (h *DomainHistoryHandler) Head(w http.ResponseWriter, r *http.Request) {
+	h.retrieveDomainHistory(w, r)
+}
+
+// The HEAD method is identical to GET except that the server MUST NOT return a message-
+// body in the response. But now the responsability for don't adding the body is from the
+// mux while writing the response
+func (h *DomainHistoryHandler) retrieveDomainHistory(w http.ResponseWriter, r *http.Request) {
+	// The domain history is only stored when using MongoDB, the file backend doesn't
+	// support this resource
+	if h.GetDatabase() == nil {
+		if err := h.MessageResponse("unsupported-backend", r.URL.RequestURI()); err == nil {
+			w.WriteHeader(http.StatusNotImplemented)
+
+		} else {
+			log.Println("Error while writing response. Details:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		return
+	}
+
+	var pagination dao.DomainSnapshotDAOPagination
+
+	for key, values := range r.URL.Query() {
+		key = strings.TrimSpace(key)
+		key = strings.ToLower(key)
+
+		// A key can have multiple values in a query string, we are going to always consider
+		// the last one (overwrite strategy)
+		for _, value := range values {
+			value = strings.TrimSpace(value)
+			value = strings.ToLower(value)
+
+			switch key {
+			case "orderby":
+				// OrderBy parameter will store the fields that the user want to be the keys of the sort
+				// algorithm in the result set and the direction that each sort field will have. The format
+				// that will be used is:
+				//
+				// <field1>:<direction1>@<field2>:<direction2>@...@<fieldN>:<directionN>
+
+				orderByParts := strings.Split(value, "@")
+
+				for _, orderByPart := range orderByParts {
+					orderByPart = strings.TrimSpace(orderByPart)
+					orderByAndDirection := strings.Split(orderByPart, ":")
+
+					var field, direction string
+
+					if len(orderByAndDirection) == 1 {
+						field, direction = orderByAndDirection[0], "desc"
+
+					} else if len(orderByAndDirection) == 2 {
+						field, direction = orderByAndDirection[0], orderByAndDirection[1]
+
+					} else {
+						if err := h.MessageResponse("invalid-query-order-by", ""); err == nil {
+							w.WriteHeader(http.StatusBadRequest)
+
+						} else {
+							log.Println("Error while writing response. Details:", err)
+							w.WriteHeader(http.StatusInternalServerError)
+						}
+
+						return
+					}
+
+					orderByField, err := dao.DomainSnapshotDAOOrderByFieldFromString(field)
+					if err != nil {
+						if err := h.MessageResponse("invalid-query-order-by", ""); err == nil {
+							w.WriteHeader(http.StatusBadRequest)
+
+						} else {
+							log.Println("Error while writing response. Details:", err)
+							w.WriteHeader(http.StatusInternalServerError)
+						}
+
+						return
+					}
+
+					orderByDirection, err := dao.DAOOrderByDirectionFromString(direction)
+					if err != nil {
+						if err := h.MessageResponse("invalid-query-order-by", ""); err == nil {
+							w.WriteHeader(http.StatusBadRequest)
+
+						} else {
+							log.Println("Error while writing response. Details:", err)
+							w.WriteHeader(http.StatusInternalServerError)
+						}
+
+						return
+					}
+
+					pagination.OrderBy = append(pagination.OrderBy, dao.DomainSnapshotDAOSort{
+						Field:     orderByField,
+						Direction: orderByDirection,
+					})
+				}
+
+			case "pagesize":
+				var err error
+				pagination.PageSize, err = strconv.Atoi(value)
+				if err != nil {
+					if err := h.MessageResponse("invalid-query-page-size", ""); err == nil {
+						w.WriteHeader(http.StatusBadRequest)
+
+					} else {
+						log.Println("Error while writing response. Details:", err)
+						w.WriteHeader(http.StatusInternalServerError)
+					}
+
+					return
+				}
+
+			case "page":
+				var err error
+				pagination.Page, err = strconv.Atoi(value)
+				if err != nil {
+					if err := h.MessageResponse("invalid-query-page", ""); err == nil {
+						w.WriteHeader(http.StatusBadRequest)
+
+					} else {
+						log.Println("Error while writing response. Details:", err)
+						w.WriteHeader(http.StatusInternalServerError)
+					}
+
+					return
+				}
+			}
+		}
+	}
+
+	domainSnapshotDAO := dao.DomainSnapshotDAO{
+		Database: h.GetDatabase(),
+	}
+
+	snapshots, err := domainSnapshotDAO.FindByFQDN(h.domain.FQDN, &pagination)
+	if err != nil {
+		log.Println("Error while searching domain history. Details:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	historyResponse := protocol.ToDomainHistoryResponse(h.domain.FQDN, snapshots, pagination)
+	h.Response = &historyResponse
+
+	// Last-Modified is going to be the most recent date of the list
+	for _, snapshot := range snapshots {
+		if snapshot.CheckedAt.After(h.lastModifiedAt) {
+			h.lastModifiedAt = snapshot.CheckedAt
+		}
+	}
+
+	w.Header().Add("ETag", h.GetETag())
+	w.Header().Add("Last-Modified", h.lastModifiedAt.Format(time.RFC1123))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *DomainHistoryHandler) Interceptors() handy.InterceptorChain {
+	return handy.NewInterceptorChain().
+		Chain(new(interceptor.Permission)).
+		Chain(interceptor.NewFQDN(h)).
+		Chain(interceptor.NewValidator(h)).
+		Chain(interceptor.NewDatabase(h)).
+		Chain(interceptor.NewDomain(h)).
+		Chain(interceptor.NewJSONCodec(h)).
+		Chain(interceptor.NewHTTPCacheAfter(h))
+}
@@ -0,0 +1,251 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package handler store the REST handlers of specific URI
+package handler
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/http/rest/interceptor"
+	"github.com/rafaeljusto/shelter/net/http/rest/messages"
+	"github.com/rafaeljusto/shelter/net/http/rest/protocol"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	HandleFunc("/domain/{fqdn}/revision/{revision}", func() handy.Handler {
+		return new(DomainRevisionHandler)
+	})
+}
+
+// DomainRevisionHandler is responsable for keeping the state of a
+// /domain/{fqdn}/revision/{revision} resource, that compares an old configuration of the
+// domain with another revision and restores it. The cache headers are from the current
+// domain, so that the restore has the same preconditions of an update
+type DomainRevisionHandler struct {
+	handy.DefaultHandler                                  // Inject the HTTP methods that this resource does not implement
+	database             *mgo.Database                    // Database connection of the MongoDB session
+	databaseSession      *mgo.Session                     // MongoDB session
+	storage              dao.Storage                      // Persistence backend of the domains and scans
+	domain               model.Domain                     // Domain object related to the resource
+	domainRevision       model.DomainRevision             // Old configuration of the domain
+	language             *messages.LanguagePack           // User preferred language based on HTTP header
+	principal            model.Principal                  // Identity that signed the request
+	FQDN                 string                           `param:"fqdn"`     // FQDN defined in the URI
+	Revision             string                           `param:"revision"` // Revision number defined in the URI
+	Response             *protocol.DomainRevisionResponse `response:"get"`   // Domain revision sent back to the user
+	Message              *protocol.MessageResponse        `error`            // Message on error sent to the user
+}
+
+func (h *DomainRevisionHandler) SetDatabaseSession(session *mgo.Session) {
+	h.databaseSession = session
+}
+
+func (h *DomainRevisionHandler) GetDatabaseSession() *mgo.Session {
+	return h.databaseSession
+}
+
+func (h *DomainRevisionHandler) SetDatabase(database *mgo.Database) {
+	h.database = database
+}
+
+func (h *DomainRevisionHandler) GetDatabase() *mgo.Database {
+	return h.database
+}
+
+func (h *DomainRevisionHandler) SetStorage(storage dao.Storage) {
+	h.storage = storage
+}
+
+func (h *DomainRevisionHandler) GetStorage() dao.Storage {
+	return h.storage
+}
+
+func (h *DomainRevisionHandler) SetFQDN(fqdn string) {
+	h.FQDN = fqdn
+}
+
+func (h *DomainRevisionHandler) GetFQDN() string {
+	return h.FQDN
+}
+
+func (h *DomainRevisionHandler) SetDomain(domain model.Domain) {
+	h.domain = domain
+}
+
+func (h *DomainRevisionHandler) GetRevision() string {
+	return h.Revision
+}
+
+func (h *DomainRevisionHandler) SetDomainRevision(domainRevision model.DomainRevision) {
+	h.domainRevision = domainRevision
+}
+
+func (h *DomainRevisionHandler) GetLastModifiedAt() time.Time {
+	return h.domain.LastModifiedAt
+}
+
+func (h *DomainRevisionHandler) GetETag() string {
+	return strconv.Itoa(h.domain.Revision)
+}
+
+func (h *DomainRevisionHandler) SetPrincipal(principal model.Principal) {
+	h.principal = principal
+}
+
+func (h *DomainRevisionHandler) SetLanguage(language *messages.LanguagePack) {
+	h.language = language
+}
+
+func (h *DomainRevisionHandler) GetLanguage() *messages.LanguagePack {
+	return h.language
+}
+
+func (h *DomainRevisionHandler) MessageResponse(messageId string, roid string) error {
+	var err error
+	h.Message, err = protocol.NewMessageResponse(messageId, roid, h.language)
+	return err
+}
+
+func (h *DomainRevisionHandler) ClearResponse() {
+	h.Response = nil
+}
+
+func (h *DomainRevisionHandler) Get(w http.ResponseWriter, r *http.Request) {
+	h.retrieveDomainRevision(w, r)
+}
+
+func (h *DomainRevisionHandler) Head(w http.ResponseWriter, r *http.Request) {
+	h.retrieveDomainRevision(w, r)
+}
+
+// The HEAD method is identical to GET except that the server MUST NOT return a message-
+// body in the response. But now the responsability for don't adding the body is from the
+// mux while writing the response
+func (h *DomainRevisionHandler) retrieveDomainRevision(w http.ResponseWriter, r *http.Request) {
+	// By default the revision is compared with the current configuration of the domain, but
+	// the user can choose another stored revision with the compare parameter
+	compared := h.domain
+
+	if value := strings.TrimSpace(r.URL.Query().Get("compare")); len(value) > 0 {
+		revision, err := strconv.Atoi(value)
+		if err != nil || revision <= 0 {
+			h.invalidCompare(w)
+			return
+		}
+
+		domainRevisionDAO := h.GetStorage().DomainRevisionDAO()
+
+		comparedRevision, err := domainRevisionDAO.FindByRevision(h.domain.FQDN, revision)
+		if err == mgo.ErrNotFound {
+			h.invalidCompare(w)
+			return
+
+		} else if err != nil {
+			log.Println("Error while searching domain revision. Details:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		compared = comparedRevision.Domain
+	}
+
+	w.Header().Add("ETag", h.GetETag())
+	w.Header().Add("Last-Modified", h.GetLastModifiedAt().Format(time.RFC1123))
+	w.WriteHeader(http.StatusOK)
+
+	revisionResponse := protocol.ToDomainRevisionResponse(h.domainRevision,
+		compared.Revision, h.domainRevision.ChangesFrom(compared))
+	h.Response = &revisionResponse
+}
+
+// The revision used in the comparison must be a number of a stored revision of the same
+// domain
+func (h *DomainRevisionHandler) invalidCompare(w http.ResponseWriter) {
+	if err := h.MessageResponse("invalid-query-compare", ""); err == nil {
+		w.WriteHeader(http.StatusBadRequest)
+
+	} else {
+		log.Println("Error while writing response. Details:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// Restore the configuration of the domain stored in the revision. The old configuration
+// is converted into a domain request, so it's merged and validated as an update sent by
+// the user, and the restore is stored as a new revision
+func (h *DomainRevisionHandler) Post(w http.ResponseWriter, r *http.Request) {
+	before := h.domain
+	request := protocol.ToDomainRequest(h.domainRevision.Domain)
+
+	var err error
+	if h.domain, err = protocol.Merge(h.domain, request); err != nil {
+		messageId := getMergeErrorMessageId(err)
+
+		if len(messageId) == 0 {
+			log.Println("Error while merging domain objects for restore "+
+				"operation. Details:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+
+		} else {
+			if err := h.MessageResponse(messageId, r.URL.RequestURI()); err == nil {
+				w.WriteHeader(http.StatusBadRequest)
+
+			} else {
+				log.Println("Error while writing response. Details:", err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}
+		return
+	}
+
+	domainDAO := h.GetStorage().DomainDAO()
+
+	if err := domainDAO.Save(&h.domain); err != nil {
+		if err == dao.ErrDAORevisionConflict ||
+			strings.Index(err.Error(), "duplicate key error index") != -1 {
+
+			if err := h.MessageResponse("conflict", r.URL.RequestURI()); err == nil {
+				w.WriteHeader(http.StatusConflict)
+
+			} else {
+				log.Println("Error while writing response. Details:", err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+
+		} else {
+			log.Println("Error while saving domain object for restore "+
+				"operation. Details:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		return
+	}
+
+	recordDomainChange(h.GetStorage(), r, h.principal, &before, &h.domain)
+
+	w.Header().Add("ETag", h.GetETag())
+	w.Header().Add("Last-Modified", h.GetLastModifiedAt().Format(time.RFC1123))
+	w.Header().Add("Location", "/domain/"+h.domain.FQDN)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *DomainRevisionHandler) Interceptors() handy.InterceptorChain {
+	return handy.NewInterceptorChain().
+		Chain(new(interceptor.Permission)).
+		Chain(interceptor.NewFQDN(h)).
+		Chain(interceptor.NewValidator(h)).
+		Chain(interceptor.NewDatabase(h)).
+		Chain(interceptor.NewDomain(h)).
+		Chain(interceptor.NewDomainRevision(h)).
+		Chain(interceptor.NewHTTPCacheBefore(h)).
+		Chain(interceptor.NewJSONCodec(h))
+}
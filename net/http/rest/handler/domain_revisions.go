@@ -0,0 +1,281 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package handler store the REST handlers of specific URI
+package handler
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/http/rest/interceptor"
+	"github.com/rafaeljusto/shelter/net/http/rest/messages"
+	"github.com/rafaeljusto/shelter/net/http/rest/protocol"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	HandleFunc("/domain/{fqdn}/revisions", func() handy.Handler {
+		return new(DomainRevisionsHandler)
+	})
+}
+
+// DomainRevisionsHandler is responsable for keeping the state of a
+// /domain/{fqdn}/revisions resource, that returns the configuration changes of the domain
+type DomainRevisionsHandler struct {
+	handy.DefaultHandler                                   // Inject the HTTP methods that this resource does not implement
+	database             *mgo.Database                     // Database connection of the MongoDB session
+	databaseSession      *mgo.Session                      // MongoDB session
+	storage              dao.Storage                       // Persistence backend of the domains and scans
+	domain               model.Domain                      // Domain object related to the resource
+	language             *messages.LanguagePack            // User preferred language based on HTTP header
+	principal            model.Principal                   // Identity that signed the request
+	lastModifiedAt       time.Time                         // Most recent storage date of the revisions
+	FQDN                 string                            `param:"fqdn"`   // FQDN defined in the URI
+	Response             *protocol.DomainRevisionsResponse `response:"get"` // Domain revisions sent back to the user
+	Message              *protocol.MessageResponse         `error`          // Message on error sent to the user
+}
+
+func (h *DomainRevisionsHandler) SetDatabaseSession(session *mgo.Session) {
+	h.databaseSession = session
+}
+
+func (h *DomainRevisionsHandler) GetDatabaseSession() *mgo.Session {
+	return h.databaseSession
+}
+
+func (h *DomainRevisionsHandler) SetDatabase(database *mgo.Database) {
+	h.database = database
+}
+
+func (h *DomainRevisionsHandler) GetDatabase() *mgo.Database {
+	return h.database
+}
+
+func (h *DomainRevisionsHandler) SetStorage(storage dao.Storage) {
+	h.storage = storage
+}
+
+func (h *DomainRevisionsHandler) GetStorage() dao.Storage {
+	return h.storage
+}
+
+func (h *DomainRevisionsHandler) SetFQDN(fqdn string) {
+	h.FQDN = fqdn
+}
+
+func (h *DomainRevisionsHandler) GetFQDN() string {
+	return h.FQDN
+}
+
+func (h *DomainRevisionsHandler) SetDomain(domain model.Domain) {
+	h.domain = domain
+}
+
+func (h *DomainRevisionsHandler) GetLastModifiedAt() time.Time {
+	return h.lastModifiedAt
+}
+
+// The ETag header will be the hash of the content on list services
+func (h *DomainRevisionsHandler) GetETag() string {
+	body, err := json.Marshal(h.Response)
+	if err != nil {
+		return ""
+	}
+
+	hash := md5.New()
+	if _, err := hash.Write(body); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (h *DomainRevisionsHandler) SetPrincipal(principal model.Principal) {
+	h.principal = principal
+}
+
+func (h *DomainRevisionsHandler) SetLanguage(language *messages.LanguagePack) {
+	h.language = language
+}
+
+func (h *DomainRevisionsHandler) GetLanguage() *messages.LanguagePack {
+	return h.language
+}
+
+func (h *DomainRevisionsHandler) MessageResponse(messageId string, roid string) error {
+	var err error
+	h.Message, err = protocol.NewMessageResponse(messageId, roid, h.language)
+	return err
+}
+
+func (h *DomainRevisionsHandler) ClearResponse() {
+	h.Response = nil
+}
+
+func (h *DomainRevisionsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	h.retrieveDomainRevisions(w, r)
+}
+
+func (h *DomainRevisionsHandler) Head(w http.ResponseWriter, r *http.Request) {
+	h.retrieveDomainRevisions(w, r)
+}
+
+// The HEAD method is identical to GET except that the server MUST NOT return a message-
+// body in the response. But now the responsability for don't adding the body is from the
+// mux while writing the response
+func (h *DomainRevisionsHandler) retrieveDomainRevisions(w http.ResponseWriter, r *http.Request) {
+	var pagination dao.DomainRevisionDAOPagination
+
+	for key, values := range r.URL.Query() {
+		key = strings.TrimSpace(key)
+		key = strings.ToLower(key)
+
+		// A key can have multiple values in a query string, we are going to always consider
+		// the last one (overwrite strategy)
+		for _, value := range values {
+			value = strings.TrimSpace(value)
+			value = strings.ToLower(value)
+
+			switch key {
+			case "orderby":
+				// OrderBy parameter will store the fields that the user want to be the keys of the sort
+				// algorithm in the result set and the direction that each sort field will have. The format
+				// that will be used is:
+				//
+				// <field1>:<direction1>@<field2>:<direction2>@...@<fieldN>:<directionN>
+
+				orderByParts := strings.Split(value, "@")
+
+				for _, orderByPart := range orderByParts {
+					orderByPart = strings.TrimSpace(orderByPart)
+					orderByAndDirection := strings.Split(orderByPart, ":")
+
+					var field, direction string
+
+					if len(orderByAndDirection) == 1 {
+						field, direction = orderByAndDirection[0], "desc"
+
+					} else if len(orderByAndDirection) == 2 {
+						field, direction = orderByAndDirection[0], orderByAndDirection[1]
+
+					} else {
+						if err := h.MessageResponse("invalid-query-order-by", ""); err == nil {
+							w.WriteHeader(http.StatusBadRequest)
+
+						} else {
+							log.Println("Error while writing response. Details:", err)
+							w.WriteHeader(http.StatusInternalServerError)
+						}
+
+						return
+					}
+
+					orderByField, err := dao.DomainRevisionDAOOrderByFieldFromString(field)
+					if err != nil {
+						if err := h.MessageResponse("invalid-query-order-by", ""); err == nil {
+							w.WriteHeader(http.StatusBadRequest)
+
+						} else {
+							log.Println("Error while writing response. Details:", err)
+							w.WriteHeader(http.StatusInternalServerError)
+						}
+
+						return
+					}
+
+					orderByDirection, err := dao.DAOOrderByDirectionFromString(direction)
+					if err != nil {
+						if err := h.MessageResponse("invalid-query-order-by", ""); err == nil {
+							w.WriteHeader(http.StatusBadRequest)
+
+						} else {
+							log.Println("Error while writing response. Details:", err)
+							w.WriteHeader(http.StatusInternalServerError)
+						}
+
+						return
+					}
+
+					pagination.OrderBy = append(pagination.OrderBy, dao.DomainRevisionDAOSort{
+						Field:     orderByField,
+						Direction: orderByDirection,
+					})
+				}
+
+			case "pagesize":
+				var err error
+				pagination.PageSize, err = strconv.Atoi(value)
+				if err != nil {
+					if err := h.MessageResponse("invalid-query-page-size", ""); err == nil {
+						w.WriteHeader(http.StatusBadRequest)
+
+					} else {
+						log.Println("Error while writing response. Details:", err)
+						w.WriteHeader(http.StatusInternalServerError)
+					}
+
+					return
+				}
+
+			case "page":
+				var err error
+				pagination.Page, err = strconv.Atoi(value)
+				if err != nil {
+					if err := h.MessageResponse("invalid-query-page", ""); err == nil {
+						w.WriteHeader(http.StatusBadRequest)
+
+					} else {
+						log.Println("Error while writing response. Details:", err)
+						w.WriteHeader(http.StatusInternalServerError)
+					}
+
+					return
+				}
+			}
+		}
+	}
+
+	domainRevisionDAO := h.GetStorage().DomainRevisionDAO()
+
+	revisions, err := domainRevisionDAO.FindByFQDN(h.domain.FQDN, &pagination)
+	if err != nil {
+		log.Println("Error while searching domain revisions. Details:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	revisionsResponse := protocol.ToDomainRevisionsResponse(h.domain.FQDN, revisions, pagination)
+	h.Response = &revisionsResponse
+
+	// Last-Modified is going to be the most recent date of the list
+	for _, revision := range revisions {
+		if revision.SavedAt.After(h.lastModifiedAt) {
+			h.lastModifiedAt = revision.SavedAt
+		}
+	}
+
+	w.Header().Add("ETag", h.GetETag())
+	w.Header().Add("Last-Modified", h.lastModifiedAt.Format(time.RFC1123))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *DomainRevisionsHandler) Interceptors() handy.InterceptorChain {
+	return handy.NewInterceptorChain().
+		Chain(new(interceptor.Permission)).
+		Chain(interceptor.NewFQDN(h)).
+		Chain(interceptor.NewValidator(h)).
+		Chain(interceptor.NewDatabase(h)).
+		Chain(interceptor.NewDomain(h)).
+		Chain(interceptor.NewJSONCodec(h)).
+		Chain(interceptor.NewHTTPCacheAfter(h))
+}
@@ -28,7 +28,9 @@ type DomainVerificationHandler struct {
 	handy.DefaultHandler
 	database        *mgo.Database
 	databaseSession *mgo.Session
+	storage         dao.Storage
 	language        *messages.LanguagePack
+	principal       model.Principal
 	FQDN            string                    `param:"fqdn"`
 	Request         protocol.DomainRequest    `request:"put"`
 	Response        *protocol.DomainResponse  `response:"put,get"`
@@ -51,6 +53,14 @@ func (h *DomainVerificationHandler) GetDatabase() *mgo.Database {
 	return h.database
 }
 
+func (h *DomainVerificationHandler) SetStorage(storage dao.Storage) {
+	h.storage = storage
+}
+
+func (h *DomainVerificationHandler) GetStorage() dao.Storage {
+	return h.storage
+}
+
 func (h *DomainVerificationHandler) SetFQDN(fqdn string) {
 	h.FQDN = fqdn
 }
@@ -59,6 +69,10 @@ func (h *DomainVerificationHandler) GetFQDN() string {
 	return h.FQDN
 }
 
+func (h *DomainVerificationHandler) SetPrincipal(principal model.Principal) {
+	h.principal = principal
+}
+
 func (h *DomainVerificationHandler) SetLanguage(language *messages.LanguagePack) {
 	h.language = language
 }
@@ -107,21 +121,7 @@ func (h *DomainVerificationHandler) Put(w http.ResponseWriter, r *http.Request)
 	var err error
 
 	if domain, err = protocol.Merge(domain, h.Request); err != nil {
-		messageId := ""
-
-		switch err {
-		case protocol.ErrInvalidDNSKEY:
-			messageId = "invalid-dnskey"
-		case protocol.ErrInvalidDSAlgorithm:
-			messageId = "invalid-ds-algorithm"
-		case protocol.ErrInvalidDSDigestType:
-			messageId = "invalid-ds-digest-type"
-		case protocol.ErrInvalidIP:
-			messageId = "invalid-ip"
-		case protocol.ErrInvalidLanguage:
-			messageId = "invalid-language"
-		}
-
+		messageId := getMergeErrorMessageId(err)
 		if len(messageId) == 0 {
 			log.Println("Error while merging domain objects for domain verification "+
 				"operation. Details:", err)
@@ -144,9 +144,7 @@ func (h *DomainVerificationHandler) Put(w http.ResponseWriter, r *http.Request)
 	// As we alredy did the scan, if the domain is registered in the system, we update it for this
 	// results. This also gives a more intuitive design for when the user wants to force a check a
 	// specific domain in the Shelter system
-	domainDAO := dao.DomainDAO{
-		Database: h.GetDatabase(),
-	}
+	domainDAO := h.GetStorage().DomainDAO()
 
 	if dbDomain, err := domainDAO.FindByFQDN(domain.FQDN); err == nil {
 		update := true
@@ -13,6 +13,7 @@ import (
 	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
 	"github.com/rafaeljusto/shelter/dao"
 	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
 	"github.com/rafaeljusto/shelter/net/http/rest/interceptor"
 	"github.com/rafaeljusto/shelter/net/http/rest/messages"
 	"github.com/rafaeljusto/shelter/net/http/rest/protocol"
@@ -32,7 +33,9 @@ type DomainsHandler struct {
 	handy.DefaultHandler
 	database        *mgo.Database
 	databaseSession *mgo.Session
+	storage         dao.Storage
 	language        *messages.LanguagePack
+	principal       model.Principal
 	Response        *protocol.DomainsResponse `response:"get"`
 	Message         *protocol.MessageResponse `error`
 	lastModifiedAt  time.Time
@@ -54,6 +57,14 @@ func (h *DomainsHandler) GetDatabase() *mgo.Database {
 	return h.database
 }
 
+func (h *DomainsHandler) SetStorage(storage dao.Storage) {
+	h.storage = storage
+}
+
+func (h *DomainsHandler) GetStorage() dao.Storage {
+	return h.storage
+}
+
 func (h *DomainsHandler) GetLastModifiedAt() time.Time {
 	return h.lastModifiedAt
 }
@@ -73,6 +84,10 @@ func (h *DomainsHandler) GetETag() string {
 	return hex.EncodeToString(hash.Sum(nil))
 }
 
+func (h *DomainsHandler) SetPrincipal(principal model.Principal) {
+	h.principal = principal
+}
+
 func (h *DomainsHandler) SetLanguage(language *messages.LanguagePack) {
 	h.language = language
 }
@@ -104,8 +119,8 @@ func (h *DomainsHandler) Head(w http.ResponseWriter, r *http.Request) {
 // mux while writing the response
 func (h *DomainsHandler) retrieveDomains(w http.ResponseWriter, r *http.Request) {
 	var pagination dao.DomainDAOPagination
+	var filter dao.DomainDAOFilter
 	expand := false
-	filter := ""
 
 	for key, values := range r.URL.Query() {
 		key = strings.TrimSpace(key)
@@ -115,6 +130,7 @@ func (h *DomainsHandler) retrieveDomains(w http.ResponseWriter, r *http.Request)
 		// the last one (overwrite strategy)
 		for _, value := range values {
 			value = strings.TrimSpace(value)
+			originalValue := value
 			value = strings.ToLower(value)
 
 			switch key {
@@ -212,14 +228,29 @@ func (h *DomainsHandler) retrieveDomains(w http.ResponseWriter, r *http.Request)
 				expand = true
 
 			case "filter":
-				filter = value
+				filter.FQDN = value
+
+			default:
+				// The other keys are conditions over the status and the contents of the domains.
+				// Unknown keys are ignored as before
+				if err := parseDomainsFilter(&filter, key, value, originalValue); err != nil {
+					if err := h.MessageResponse("invalid-query-filter", ""); err == nil {
+						w.WriteHeader(http.StatusBadRequest)
+
+					} else {
+						log.Println("Error while writing response. Details:", err)
+						w.WriteHeader(http.StatusInternalServerError)
+					}
+					return
+				}
 			}
 		}
 	}
 
-	domainDAO := dao.DomainDAO{
-		Database: h.GetDatabase(),
-	}
+	// Principals restricted to some domains can only see their own domains
+	filter.Suffixes = h.principal.Domains
+
+	domainDAO := h.GetStorage().DomainDAO()
 
 	domains, err := domainDAO.FindAll(&pagination, expand, filter)
 	if err != nil {
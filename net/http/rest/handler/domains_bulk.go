@@ -0,0 +1,244 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package handler store the REST handlers of specific URI
+package handler
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/http/rest/interceptor"
+	"github.com/rafaeljusto/shelter/net/http/rest/messages"
+	"github.com/rafaeljusto/shelter/net/http/rest/protocol"
+	"net/http"
+	"strings"
+)
+
+func init() {
+	HandleFunc("/domains/bulk", func() handy.Handler {
+		return new(DomainsBulkHandler)
+	})
+}
+
+// DomainsBulkHandler is responsable for keeping the state of a /domains/bulk resource,
+// that creates or updates many domains in a single request. The body is a JSON array or
+// a NDJSON of domains, and each domain has its own result in the response
+type DomainsBulkHandler struct {
+	handy.DefaultHandler                               // Inject the HTTP methods that this resource does not implement
+	database             *mgo.Database                 // Database connection of the MongoDB session
+	databaseSession      *mgo.Session                  // MongoDB session
+	storage              dao.Storage                   // Persistence backend of the domains and scans
+	language             *messages.LanguagePack        // User preferred language based on HTTP header
+	principal            model.Principal               // Identity that signed the request
+	Response             *protocol.DomainsBulkResponse `response:"post"` // Result of each domain sent back to the user
+	Message              *protocol.MessageResponse     `error`           // Message on error sent to the user
+}
+
+func (h *DomainsBulkHandler) SetDatabaseSession(session *mgo.Session) {
+	h.databaseSession = session
+}
+
+func (h *DomainsBulkHandler) GetDatabaseSession() *mgo.Session {
+	return h.databaseSession
+}
+
+func (h *DomainsBulkHandler) SetDatabase(database *mgo.Database) {
+	h.database = database
+}
+
+func (h *DomainsBulkHandler) GetDatabase() *mgo.Database {
+	return h.database
+}
+
+func (h *DomainsBulkHandler) SetStorage(storage dao.Storage) {
+	h.storage = storage
+}
+
+func (h *DomainsBulkHandler) GetStorage() dao.Storage {
+	return h.storage
+}
+
+func (h *DomainsBulkHandler) SetPrincipal(principal model.Principal) {
+	h.principal = principal
+}
+
+func (h *DomainsBulkHandler) SetLanguage(language *messages.LanguagePack) {
+	h.language = language
+}
+
+func (h *DomainsBulkHandler) GetLanguage() *messages.LanguagePack {
+	return h.language
+}
+
+func (h *DomainsBulkHandler) MessageResponse(messageId string, roid string) error {
+	var err error
+	h.Message, err = protocol.NewMessageResponse(messageId, roid, h.language)
+	return err
+}
+
+func (h *DomainsBulkHandler) ClearResponse() {
+	h.Response = nil
+}
+
+func (h *DomainsBulkHandler) Post(w http.ResponseWriter, r *http.Request) {
+	// The JSONCodec interceptor can only decode a single JSON object, so we decode the
+	// batch here to support the NDJSON format
+	requests, err := protocol.DecodeDomainBulkRequests(r.Body)
+	if err != nil {
+		log.Println("Received an invalid bulk content. Details:", err)
+
+		if err := h.MessageResponse("invalid-json-content", r.URL.RequestURI()); err == nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+		} else {
+			log.Println("Error while writing response. Details:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	domainDAO := h.GetStorage().DomainDAO()
+
+	results := make([]protocol.DomainBulkResult, len(requests))
+	resultsIndex := make(map[*model.Domain]int)
+	previousStates := make(map[*model.Domain]*model.Domain)
+	fqdns := make(map[string]bool)
+	var domains []*model.Domain
+
+	for i, request := range requests {
+		results[i].FQDN = request.FQDN
+
+		// The FQDN is sent in the body of bulk operations, so we need to copy it to the
+		// domain request object that is used in the merge
+		request.DomainRequest.FQDN = request.FQDN
+
+		fqdn, err := model.NormalizeDomainName(request.FQDN)
+		if err != nil {
+			h.setBulkResultMessage(&results[i], http.StatusBadRequest, "invalid-fqdn", r)
+			continue
+		}
+
+		if !h.principal.Owns(fqdn) {
+			h.setBulkResultMessage(&results[i], http.StatusForbidden, "access-denied", r)
+			continue
+		}
+
+		// The same domain cannot appear twice in the batch, because we would have two
+		// different versions of the object being saved at the same time
+		if fqdns[fqdn] {
+			h.setBulkResultMessage(&results[i], http.StatusConflict, "conflict", r)
+			continue
+		}
+		fqdns[fqdn] = true
+
+		// If the domain does not exist yet thats alright because we will create it, as we
+		// do in the PUT method of the domain resource
+		domain, err := domainDAO.FindByFQDN(fqdn)
+		if err != nil && err != mgo.ErrNotFound {
+			log.Println("Error while retrieving domain object for bulk create or "+
+				"update operation. Details:", err)
+			results[i].Status = http.StatusInternalServerError
+			continue
+		}
+
+		// Keep the current state of the domain for the audit log
+		var before *model.Domain
+		if domain.Revision > 0 {
+			previousDomain := domain
+			before = &previousDomain
+		}
+
+		if domain, err = protocol.Merge(domain, request.DomainRequest); err != nil {
+			if messageId := getMergeErrorMessageId(err); len(messageId) > 0 {
+				h.setBulkResultMessage(&results[i], http.StatusBadRequest, messageId, r)
+
+			} else {
+				log.Println("Error while merging domain objects for bulk create or "+
+					"update operation. Details:", err)
+				results[i].Status = http.StatusInternalServerError
+			}
+			continue
+		}
+
+		if before == nil {
+			domain.CreatedBy = h.principal.Id
+
+			if err := continueDomainRevisions(h.GetStorage(), &domain); err != nil {
+				log.Println("Error while retrieving the last domain revision. Details:", err)
+				results[i].Status = http.StatusInternalServerError
+				continue
+			}
+		}
+
+		resultsIndex[&domain] = i
+		previousStates[&domain] = before
+		domains = append(domains, &domain)
+	}
+
+	for _, domainResult := range domainDAO.SaveMany(domains) {
+		i := resultsIndex[domainResult.Domain]
+
+		if domainResult.Error == nil {
+			recordDomainChange(h.GetStorage(), r, h.principal,
+				previousStates[domainResult.Domain], domainResult.Domain)
+
+			if previousStates[domainResult.Domain] == nil {
+				results[i].Status = http.StatusCreated
+			} else {
+				results[i].Status = http.StatusNoContent
+			}
+
+			results[i].Links = []protocol.Link{
+				{
+					Types: []protocol.LinkType{protocol.LinkTypeSelf},
+					HRef:  "/domain/" + domainResult.Domain.FQDN,
+				},
+			}
+
+		} else if domainResult.Error == dao.ErrDAORevisionConflict ||
+			strings.Index(domainResult.Error.Error(), "duplicate key error index") != -1 {
+
+			h.setBulkResultMessage(&results[i], http.StatusConflict, "conflict", r)
+
+		} else {
+			log.Println("Error while saving domain object for bulk create or "+
+				"update operation. Details:", domainResult.Error)
+			results[i].Status = http.StatusInternalServerError
+		}
+	}
+
+	var response protocol.DomainsBulkResponse
+	for _, result := range results {
+		response.AddResult(result)
+	}
+
+	h.Response = &response
+	w.WriteHeader(http.StatusOK)
+}
+
+// setBulkResultMessage fills the result of a domain with a message in the user's
+// language. The message is related to the domain resource
+func (h *DomainsBulkHandler) setBulkResultMessage(result *protocol.DomainBulkResult,
+	status int, messageId string, r *http.Request) {
+
+	var err error
+	result.Status = status
+	if result.Message, err = protocol.NewMessageResponse(messageId, "/domain/"+result.FQDN, h.language); err != nil {
+		// The FQDN sent by the user could be an invalid URI, so we link the message to the
+		// bulk resource instead
+		log.Println("Error while building bulk result message. Details:", err)
+		result.Message, _ = protocol.NewMessageResponse(messageId, r.URL.RequestURI(), h.language)
+	}
+}
+
+func (h *DomainsBulkHandler) Interceptors() handy.InterceptorChain {
+	return handy.NewInterceptorChain().
+		Chain(new(interceptor.Permission)).
+		Chain(interceptor.NewValidator(h)).
+		Chain(interceptor.NewDatabase(h)).
+		Chain(interceptor.NewJSONCodec(h))
+}
@@ -0,0 +1,201 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package handler store the REST handlers of specific URI
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/http/rest/check"
+	"github.com/rafaeljusto/shelter/net/http/rest/interceptor"
+	"github.com/rafaeljusto/shelter/net/http/rest/messages"
+	"github.com/rafaeljusto/shelter/net/http/rest/protocol"
+	"net/http"
+	"time"
+)
+
+func init() {
+	HandleFunc("/domains/export", func() handy.Handler {
+		return new(DomainsExportHandler)
+	})
+}
+
+// DomainsExportHandler is responsable for keeping the state of a /domains/export
+// resource, that sends all domains of the system, one JSON object per line (NDJSON). The
+// domains are written as soon as they are loaded from the database, so we don't need to
+// keep all of them in memory. The output can be sent back to the /domains/bulk resource.
+// When the export fails in the middle, the last line is a message instead of a domain
+type DomainsExportHandler struct {
+	handy.DefaultHandler                           // Inject the HTTP methods that this resource does not implement
+	database             *mgo.Database             // Database connection of the MongoDB session
+	databaseSession      *mgo.Session              // MongoDB session
+	storage              dao.Storage               // Persistence backend of the domains and scans
+	language             *messages.LanguagePack    // User preferred language based on HTTP header
+	principal            model.Principal           // Identity that signed the request
+	Message              *protocol.MessageResponse `error` // Message on error sent to the user
+}
+
+func (h *DomainsExportHandler) SetDatabaseSession(session *mgo.Session) {
+	h.databaseSession = session
+}
+
+func (h *DomainsExportHandler) GetDatabaseSession() *mgo.Session {
+	return h.databaseSession
+}
+
+func (h *DomainsExportHandler) SetDatabase(database *mgo.Database) {
+	h.database = database
+}
+
+func (h *DomainsExportHandler) GetDatabase() *mgo.Database {
+	return h.database
+}
+
+func (h *DomainsExportHandler) SetStorage(storage dao.Storage) {
+	h.storage = storage
+}
+
+func (h *DomainsExportHandler) GetStorage() dao.Storage {
+	return h.storage
+}
+
+func (h *DomainsExportHandler) SetPrincipal(principal model.Principal) {
+	h.principal = principal
+}
+
+func (h *DomainsExportHandler) SetLanguage(language *messages.LanguagePack) {
+	h.language = language
+}
+
+func (h *DomainsExportHandler) GetLanguage() *messages.LanguagePack {
+	return h.language
+}
+
+func (h *DomainsExportHandler) MessageResponse(messageId string, roid string) error {
+	var err error
+	h.Message, err = protocol.NewMessageResponse(messageId, roid, h.language)
+	return err
+}
+
+func (h *DomainsExportHandler) ClearResponse() {
+}
+
+func (h *DomainsExportHandler) Get(w http.ResponseWriter, r *http.Request) {
+	domainDAO := h.GetStorage().DomainDAO()
+
+	domainChannel, err := domainDAO.FindAllAsync()
+	if err != nil {
+		log.Println("Error while retrieving all domains for export. Details:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// The export lasts while there are domains to send, so the write timeout of the server
+	// cannot be applied to it
+	controller := responseController(r)
+	if controller != nil {
+		if err := controller.SetWriteDeadline(time.Time{}); err != nil {
+			log.Println("Error removing write timeout of domains export. Details:", err)
+		}
+	}
+
+	// The mux buffers the response until the end of the request, so we flush it after
+	// each domain to send it to the user immediately. After the first flush the headers
+	// cannot be changed anymore
+	flusher, _ := w.(http.Flusher)
+	flush := func() error {
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if controller != nil {
+			return controller.Flush()
+		}
+
+		return nil
+	}
+
+	w.Header().Set("Content-Type", fmt.Sprintf("%s; charset=%s",
+		check.SupportedContentType, check.SupportedCharset))
+	w.WriteHeader(http.StatusOK)
+
+	if err := flush(); err != nil {
+		log.Println("Error while starting the domains export. Details:", err)
+		return
+	}
+
+	failed := false
+	for {
+		domainResult := <-domainChannel
+
+		// Even after a failure we need to read all domains from the channel, otherwise the
+		// goroutine that loads the domains would be blocked forever
+		if domainResult.Error != nil {
+			log.Println("Error while retrieving a domain for export. Details:", domainResult.Error)
+			failed = true
+			break
+
+		} else if domainResult.Domain == nil {
+			break
+
+		} else if failed || !h.principal.Owns(domainResult.Domain.FQDN) {
+			continue
+		}
+
+		body, err := json.Marshal(protocol.ToDomainResponse(*domainResult.Domain, true))
+		if err != nil {
+			log.Println("Error while encoding a domain for export. Details:", err)
+			failed = true
+			continue
+		}
+
+		w.Write(append(body, '\n'))
+
+		if err := flush(); err != nil {
+			log.Println("Error while writing a domain for export. Details:", err)
+			failed = true
+			continue
+		}
+	}
+
+	// The status code was already sent, so the only way to inform the user that the export
+	// is incomplete is adding a message as the last line
+	if failed {
+		h.writeIncomplete(w)
+		flush()
+	}
+}
+
+// Write the message of an incomplete export as the last line of the response. The
+// message doesn't have the fields of a domain, so it's rejected if the export is sent back
+// to the /domains/bulk resource
+func (h *DomainsExportHandler) writeIncomplete(w http.ResponseWriter) {
+	message, err := protocol.NewMessageResponse("export-incomplete", "", h.language)
+	if err != nil {
+		log.Println("Error while building the incomplete export message. Details:", err)
+		return
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		log.Println("Error while encoding the incomplete export message. Details:", err)
+		return
+	}
+
+	w.Write(append(body, '\n'))
+}
+
+func (h *DomainsExportHandler) Interceptors() handy.InterceptorChain {
+	return handy.NewInterceptorChain().
+		Chain(new(interceptor.Permission)).
+		Chain(interceptor.NewValidator(h)).
+		Chain(interceptor.NewDatabase(h)).
+		Chain(interceptor.NewJSONCodec(h))
+}
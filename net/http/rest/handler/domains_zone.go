@@ -0,0 +1,134 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package handler store the REST handlers of specific URI
+package handler
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy"
+	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/http/rest/interceptor"
+	"github.com/rafaeljusto/shelter/net/http/rest/messages"
+	"github.com/rafaeljusto/shelter/net/http/rest/protocol"
+	"github.com/rafaeljusto/shelter/zone"
+	"net/http"
+	"strings"
+)
+
+func init() {
+	HandleFunc("/domains/zone", func() handy.Handler {
+		return new(DomainsZoneHandler)
+	})
+}
+
+// DomainsZoneHandler is responsable for keeping the state of a /domains/zone resource,
+// that imports the delegations of a master zone file. The import runs in background, so
+// the POST method only starts the job and the GET method returns the result of the last
+// import. The job opens its own database connection, so there's no database interceptor
+type DomainsZoneHandler struct {
+	handy.DefaultHandler                              // Inject the HTTP methods that this resource does not implement
+	language             *messages.LanguagePack       // User preferred language based on HTTP header
+	principal            model.Principal              // Identity that signed the request
+	Request              protocol.ZoneImportRequest   `request:"post"`      // Zone file sent by the user
+	Response             *protocol.ZoneImportResponse `response:"get,post"` // Import job sent back to the user
+	Message              *protocol.MessageResponse    `error`               // Message on error sent to the user
+}
+
+func (h *DomainsZoneHandler) SetPrincipal(principal model.Principal) {
+	h.principal = principal
+}
+
+func (h *DomainsZoneHandler) SetLanguage(language *messages.LanguagePack) {
+	h.language = language
+}
+
+func (h *DomainsZoneHandler) GetLanguage() *messages.LanguagePack {
+	return h.language
+}
+
+func (h *DomainsZoneHandler) MessageResponse(messageId string, roid string) error {
+	var err error
+	h.Message, err = protocol.NewMessageResponse(messageId, roid, h.language)
+	return err
+}
+
+func (h *DomainsZoneHandler) ClearResponse() {
+	h.Response = nil
+}
+
+func (h *DomainsZoneHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if h.principal.Restricted() {
+		h.accessDenied(w, r)
+		return
+	}
+
+	zoneImportResponse := protocol.ZoneImportJobToZoneImportResponse(zone.GetJob())
+	h.Response = &zoneImportResponse
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *DomainsZoneHandler) Post(w http.ResponseWriter, r *http.Request) {
+	// The zone file can contain any domain, so principals restricted to some domains
+	// cannot import it
+	if h.principal.Restricted() {
+		h.accessDenied(w, r)
+		return
+	}
+
+	if len(strings.TrimSpace(h.Request.Content)) == 0 {
+		if err := h.MessageResponse("invalid-zone-content", r.URL.RequestURI()); err == nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+		} else {
+			log.Println("Error while writing response. Details:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	err := zone.StartJob(strings.NewReader(h.Request.Content), h.Request.Origin, h.principal.Id,
+		domainChangeRecorder(r, h.principal))
+
+	if err == zone.ErrJobRunning {
+		if err := h.MessageResponse("zone-import-running", r.URL.RequestURI()); err == nil {
+			w.WriteHeader(http.StatusConflict)
+
+		} else {
+			log.Println("Error while writing response. Details:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+
+	} else if err != nil {
+		log.Println("Error while starting zone import. Details:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	zoneImportResponse := protocol.ZoneImportJobToZoneImportResponse(zone.GetJob())
+	h.Response = &zoneImportResponse
+
+	w.Header().Add("Location", "/domains/zone")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// The import job is shared by all principals, so it is also hidden from the principals
+// restricted to some domains
+func (h *DomainsZoneHandler) accessDenied(w http.ResponseWriter, r *http.Request) {
+	if err := h.MessageResponse("access-denied", r.URL.RequestURI()); err == nil {
+		w.WriteHeader(http.StatusForbidden)
+
+	} else {
+		log.Println("Error while writing response. Details:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (h *DomainsZoneHandler) Interceptors() handy.InterceptorChain {
+	return handy.NewInterceptorChain().
+		Chain(new(interceptor.Permission)).
+		Chain(interceptor.NewValidator(h)).
+		Chain(interceptor.NewJSONCodec(h))
+}
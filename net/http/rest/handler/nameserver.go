@@ -0,0 +1,127 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package handler store the REST handlers of specific URI
+package handler
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/http/rest/interceptor"
+	"github.com/rafaeljusto/shelter/net/http/rest/messages"
+	"github.com/rafaeljusto/shelter/net/http/rest/protocol"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func init() {
+	HandleFunc("/nameserver/{host}", func() handy.Handler {
+		return new(NameserverHandler)
+	})
+}
+
+// NameserverHandler is responsable for keeping the state of a /nameserver/{host}
+// resource, that summarizes the nameserver host in all domains that delegate to it
+type NameserverHandler struct {
+	handy.DefaultHandler                                     // Inject the HTTP methods that this resource does not implement
+	database             *mgo.Database                       // Database connection of the MongoDB session
+	databaseSession      *mgo.Session                        // MongoDB session
+	storage              dao.Storage                         // Persistence backend of the domains and scans
+	nameserverSummary    model.NameserverSummary             // Nameserver summary object related to the resource
+	language             *messages.LanguagePack              // User preferred language based on HTTP header
+	Host                 string                              `param:"host"`   // Nameserver's name in the URI
+	Response             *protocol.NameserverSummaryResponse `response:"get"` // Nameserver summary sent back to the user
+	Message              *protocol.MessageResponse           `error`          // Message on error sent to the user
+}
+
+func (h *NameserverHandler) SetDatabaseSession(session *mgo.Session) {
+	h.databaseSession = session
+}
+
+func (h *NameserverHandler) GetDatabaseSession() *mgo.Session {
+	return h.databaseSession
+}
+
+func (h *NameserverHandler) SetDatabase(database *mgo.Database) {
+	h.database = database
+}
+
+func (h *NameserverHandler) GetDatabase() *mgo.Database {
+	return h.database
+}
+
+func (h *NameserverHandler) SetStorage(storage dao.Storage) {
+	h.storage = storage
+}
+
+func (h *NameserverHandler) GetStorage() dao.Storage {
+	return h.storage
+}
+
+func (h *NameserverHandler) GetHost() string {
+	return h.Host
+}
+
+func (h *NameserverHandler) SetNameserverSummary(summary model.NameserverSummary) {
+	h.nameserverSummary = summary
+}
+
+func (h *NameserverHandler) GetLastModifiedAt() time.Time {
+	return h.nameserverSummary.LastModifiedAt
+}
+
+func (h *NameserverHandler) GetETag() string {
+	return strconv.Itoa(h.nameserverSummary.Revision)
+}
+
+func (h *NameserverHandler) SetLanguage(language *messages.LanguagePack) {
+	h.language = language
+}
+
+func (h *NameserverHandler) GetLanguage() *messages.LanguagePack {
+	return h.language
+}
+
+func (h *NameserverHandler) MessageResponse(messageId string, roid string) error {
+	var err error
+	h.Message, err = protocol.NewMessageResponse(messageId, roid, h.language)
+	return err
+}
+
+func (h *NameserverHandler) ClearResponse() {
+	h.Response = nil
+}
+
+func (h *NameserverHandler) Get(w http.ResponseWriter, r *http.Request) {
+	h.retrieveNameserver(w, r)
+}
+
+func (h *NameserverHandler) Head(w http.ResponseWriter, r *http.Request) {
+	h.retrieveNameserver(w, r)
+}
+
+// The HEAD method is identical to GET except that the server MUST NOT return a message-
+// body in the response. But now the responsability for don't adding the body is from the
+// mux while writing the response
+func (h *NameserverHandler) retrieveNameserver(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("ETag", h.GetETag())
+	w.Header().Add("Last-Modified", h.GetLastModifiedAt().Format(time.RFC1123))
+	w.WriteHeader(http.StatusOK)
+
+	summaryResponse := protocol.NameserverSummaryToNameserverSummaryResponse(h.nameserverSummary)
+	h.Response = &summaryResponse
+}
+
+func (h *NameserverHandler) Interceptors() handy.InterceptorChain {
+	return handy.NewInterceptorChain().
+		Chain(new(interceptor.Permission)).
+		Chain(interceptor.NewValidator(h)).
+		Chain(interceptor.NewDatabase(h)).
+		Chain(interceptor.NewJSONCodec(h)).
+		Chain(interceptor.NewNameserverSummary(h)).
+		Chain(interceptor.NewHTTPCacheBefore(h))
+}
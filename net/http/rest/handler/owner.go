@@ -0,0 +1,265 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package handler store the REST handlers of specific URI
+package handler
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/http/rest/interceptor"
+	"github.com/rafaeljusto/shelter/net/http/rest/messages"
+	"github.com/rafaeljusto/shelter/net/http/rest/protocol"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func init() {
+	HandleFunc("/owner/{email}", func() handy.Handler {
+		return new(OwnerHandler)
+	})
+}
+
+// OwnerHandler is responsable for keeping the state of a /owner/{email} resource, that
+// lists the domains of an owner and allows to replace or remove the owner from all of them
+// at once (e.g. when the contact leaves the company)
+type OwnerHandler struct {
+	handy.DefaultHandler                                // Inject the HTTP methods that this resource does not implement
+	database             *mgo.Database                  // Database connection of the MongoDB session
+	databaseSession      *mgo.Session                   // MongoDB session
+	storage              dao.Storage                    // Persistence backend of the domains and scans
+	domains              []model.Domain                 // Domains of the owner
+	language             *messages.LanguagePack         // User preferred language based on HTTP header
+	principal            model.Principal                // Identity that signed the request
+	Email                string                         `param:"email"`  // Owner's e-mail in the URI
+	Request              protocol.OwnerRequest          `request:"put"`  // Owner that will replace the current one
+	Response             *protocol.OwnerDomainsResponse `response:"get"` // Domains of the owner sent back to the user
+	Message              *protocol.MessageResponse      `error`          // Message on error sent to the user
+}
+
+func (h *OwnerHandler) SetPrincipal(principal model.Principal) {
+	h.principal = principal
+}
+
+func (h *OwnerHandler) GetPrincipal() model.Principal {
+	return h.principal
+}
+
+func (h *OwnerHandler) SetDatabaseSession(session *mgo.Session) {
+	h.databaseSession = session
+}
+
+func (h *OwnerHandler) GetDatabaseSession() *mgo.Session {
+	return h.databaseSession
+}
+
+func (h *OwnerHandler) SetDatabase(database *mgo.Database) {
+	h.database = database
+}
+
+func (h *OwnerHandler) GetDatabase() *mgo.Database {
+	return h.database
+}
+
+func (h *OwnerHandler) SetStorage(storage dao.Storage) {
+	h.storage = storage
+}
+
+func (h *OwnerHandler) GetStorage() dao.Storage {
+	return h.storage
+}
+
+func (h *OwnerHandler) GetEmail() string {
+	return h.Email
+}
+
+func (h *OwnerHandler) SetEmail(email string) {
+	h.Email = email
+}
+
+func (h *OwnerHandler) SetDomains(domains []model.Domain) {
+	h.domains = domains
+}
+
+// Last-Modified is going to be the most recent modification of the owner's domains
+func (h *OwnerHandler) GetLastModifiedAt() time.Time {
+	var lastModifiedAt time.Time
+	for _, domain := range h.domains {
+		if domain.LastModifiedAt.After(lastModifiedAt) {
+			lastModifiedAt = domain.LastModifiedAt
+		}
+	}
+
+	return lastModifiedAt
+}
+
+// The ETag header will be the hash of the owner's domains revisions, so any change in one
+// of the domains changes the ETag
+func (h *OwnerHandler) GetETag() string {
+	hash := md5.New()
+	for _, domain := range h.domains {
+		if _, err := fmt.Fprintf(hash, "%s%d", domain.FQDN, domain.Revision); err != nil {
+			return ""
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (h *OwnerHandler) SetLanguage(language *messages.LanguagePack) {
+	h.language = language
+}
+
+func (h *OwnerHandler) GetLanguage() *messages.LanguagePack {
+	return h.language
+}
+
+func (h *OwnerHandler) MessageResponse(messageId string, roid string) error {
+	var err error
+	h.Message, err = protocol.NewMessageResponse(messageId, roid, h.language)
+	return err
+}
+
+func (h *OwnerHandler) ClearResponse() {
+	h.Response = nil
+}
+
+func (h *OwnerHandler) Get(w http.ResponseWriter, r *http.Request) {
+	h.retrieveOwner(w, r)
+}
+
+func (h *OwnerHandler) Head(w http.ResponseWriter, r *http.Request) {
+	h.retrieveOwner(w, r)
+}
+
+// The HEAD method is identical to GET except that the server MUST NOT return a message-
+// body in the response. But now the responsability for don't adding the body is from the
+// mux while writing the response
+func (h *OwnerHandler) retrieveOwner(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("ETag", h.GetETag())
+	w.Header().Add("Last-Modified", h.GetLastModifiedAt().Format(time.RFC1123))
+	w.WriteHeader(http.StatusOK)
+
+	ownerDomainsResponse := protocol.ToOwnerDomainsResponse(h.Email, h.domains)
+	h.Response = &ownerDomainsResponse
+}
+
+// Replace the owner in all his domains. When the e-mail isn't informed in the request
+// the owner keeps the same e-mail, and only the preferences are changed
+func (h *OwnerHandler) Put(w http.ResponseWriter, r *http.Request) {
+	if len(h.Request.Email) == 0 {
+		h.Request.Email = h.Email
+	}
+
+	owner, err := protocol.ToOwnerModel(h.Request)
+	if err != nil {
+		messageId := getMergeErrorMessageId(err)
+
+		if len(messageId) == 0 {
+			log.Println("Error while converting owner object for replace "+
+				"operation. Details:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+
+		} else {
+			if err := h.MessageResponse(messageId, r.URL.RequestURI()); err == nil {
+				w.WriteHeader(http.StatusBadRequest)
+
+			} else {
+				log.Println("Error while writing response. Details:", err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}
+		return
+	}
+
+	if !h.replaceOwner(w, r, &owner) {
+		return
+	}
+
+	if owner.Email.Address != h.Email {
+		w.Header().Add("Location", "/owner/"+owner.Email.Address)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Remove the owner from all his domains
+func (h *OwnerHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if !h.replaceOwner(w, r, nil) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Replace or remove the owner in all domains and save them. The domains are saved even
+// if one of them fails, so when a domain was modified by someone else the user receives a
+// conflict and can repeat the request to change the remaining domains. Each saved domain
+// is recorded in the audit log and receives a new revision
+func (h *OwnerHandler) replaceOwner(w http.ResponseWriter, r *http.Request, owner *model.Owner) bool {
+	var domains []*model.Domain
+	previousStates := make(map[*model.Domain]*model.Domain)
+
+	for i := range h.domains {
+		before := h.domains[i]
+		if h.domains[i].ReplaceOwner(h.Email, owner) {
+			domains = append(domains, &h.domains[i])
+			previousStates[&h.domains[i]] = &before
+		}
+	}
+
+	domainDAO := h.GetStorage().DomainDAO()
+	recorder := domainChangeRecorder(r, h.principal)
+
+	conflict := false
+	for _, result := range domainDAO.SaveMany(domains) {
+		if result.Error == nil {
+			recorder(h.GetStorage(), previousStates[result.Domain], result.Domain)
+			continue
+		}
+
+		if result.Error == dao.ErrDAORevisionConflict ||
+			strings.Index(result.Error.Error(), "duplicate key error index") != -1 {
+
+			conflict = true
+			continue
+		}
+
+		log.Printf("Error while saving domain %s for owner %s replace "+
+			"operation. Details: %s", result.Domain.FQDN, h.Email, result.Error)
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+
+	if conflict {
+		if err := h.MessageResponse("conflict", r.URL.RequestURI()); err == nil {
+			w.WriteHeader(http.StatusConflict)
+
+		} else {
+			log.Println("Error while writing response. Details:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		return false
+	}
+
+	return true
+}
+
+func (h *OwnerHandler) Interceptors() handy.InterceptorChain {
+	return handy.NewInterceptorChain().
+		Chain(new(interceptor.Permission)).
+		Chain(interceptor.NewValidator(h)).
+		Chain(interceptor.NewDatabase(h)).
+		Chain(interceptor.NewOwner(h)).
+		Chain(interceptor.NewHTTPCacheBefore(h)).
+		Chain(interceptor.NewJSONCodec(h))
+}
@@ -6,7 +6,9 @@
 package handler
 
 import (
+	"context"
 	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy"
+	"net/http"
 )
 
 // Routes is responsable for storing the link beteween an URI and a handler. It uses a
@@ -26,3 +28,21 @@ func HandleFunc(pattern string, handler func() handy.Handler) {
 
 	Routes[pattern] = handler
 }
+
+// responseControllerKey identifies the controller of the connection in the request
+// context
+type responseControllerKey struct{}
+
+// WithResponseController stores the controller of the connection in the request context.
+// Handy buffers the response, so the handlers that stream data use the controller to send
+// it while it's written and to remove the write timeout of the server
+func WithResponseController(r *http.Request, controller *http.ResponseController) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), responseControllerKey{}, controller))
+}
+
+// Retrieve the controller of the connection from the request context. Returns nil when
+// the controller wasn't stored
+func responseController(r *http.Request) *http.ResponseController {
+	controller, _ := r.Context().Value(responseControllerKey{}).(*http.ResponseController)
+	return controller
+}
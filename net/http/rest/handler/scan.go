@@ -8,12 +8,15 @@ package handler
 import (
 	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy"
 	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/log"
 	"github.com/rafaeljusto/shelter/model"
 	"github.com/rafaeljusto/shelter/net/http/rest/interceptor"
 	"github.com/rafaeljusto/shelter/net/http/rest/messages"
 	"github.com/rafaeljusto/shelter/net/http/rest/protocol"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -23,16 +26,21 @@ func init() {
 	})
 }
 
-// ScanHandler is responsable for keeping the state of a /scan/{started-at} resource
+// ScanHandler is responsable for keeping the state of a /scan/{started-at} resource. The
+// special resource /scan/current represents the scan in progress, that can be paused,
+// resumed (PUT) or cancelled (DELETE)
 type ScanHandler struct {
-	handy.DefaultHandler                           // Inject the HTTP methods that this resource does not implement
-	database             *mgo.Database             // Database connection of the MongoDB session
-	databaseSession      *mgo.Session              // MongoDB session
-	scan                 model.Scan                // Scan object related to the resource
-	language             *messages.LanguagePack    // User preferred language based on HTTP header
-	StartedAt            string                    `param:"started-at"` // Scan start date in the URI
-	Response             *protocol.ScanResponse    `response:"get"`     // Scan response sent back to the user
-	Message              *protocol.MessageResponse `error`              // Message on error sent to the user
+	handy.DefaultHandler                             // Inject the HTTP methods that this resource does not implement
+	database             *mgo.Database               // Database connection of the MongoDB session
+	databaseSession      *mgo.Session                // MongoDB session
+	storage              dao.Storage                 // Persistence backend of the domains and scans
+	scan                 model.Scan                  // Scan object related to the resource
+	currentScan          *model.CurrentScan          // Scan in progress, when the resource is /scan/current
+	language             *messages.LanguagePack      // User preferred language based on HTTP header
+	StartedAt            string                      `param:"started-at"` // Scan start date in the URI
+	Request              protocol.CurrentScanRequest `request:"put"`      // Desired status of the scan in progress
+	Response             *protocol.ScanResponse      `response:"get,put"` // Scan response sent back to the user
+	Message              *protocol.MessageResponse   `error`              // Message on error sent to the user
 }
 
 func (h *ScanHandler) SetDatabaseSession(session *mgo.Session) {
@@ -51,15 +59,37 @@ func (h *ScanHandler) GetDatabase() *mgo.Database {
 	return h.database
 }
 
+func (h *ScanHandler) SetStorage(storage dao.Storage) {
+	h.storage = storage
+}
+
+func (h *ScanHandler) GetStorage() dao.Storage {
+	return h.storage
+}
+
 func (h *ScanHandler) SetScan(scan model.Scan) {
 	h.scan = scan
 }
 
+func (h *ScanHandler) SetCurrentScan(scan model.CurrentScan) {
+	h.currentScan = &scan
+}
+
 func (h *ScanHandler) GetLastModifiedAt() time.Time {
+	if h.currentScan != nil {
+		return h.currentScan.LastModifiedAt
+	}
+
 	return h.scan.LastModifiedAt
 }
 
+// The scan in progress doesn't have a revision, so the ETag is based on the last time it
+// changed
 func (h *ScanHandler) GetETag() string {
+	if h.currentScan != nil {
+		return strconv.FormatInt(h.currentScan.LastModifiedAt.UnixNano(), 10)
+	}
+
 	return strconv.Itoa(h.scan.Revision)
 }
 
@@ -93,12 +123,78 @@ func (h *ScanHandler) Head(w http.ResponseWriter, r *http.Request) {
 	h.retrieveScan(w, r)
 }
 
+// Pause or resume the scan in progress. Executed scans cannot be changed
+func (h *ScanHandler) Put(w http.ResponseWriter, r *http.Request) {
+	if h.currentScan == nil {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var err error
+	switch strings.ToUpper(strings.TrimSpace(h.Request.Status)) {
+	case model.ScanStatusToString(model.ScanStatusPaused):
+		err = model.PauseScan()
+
+	case model.ScanStatusToString(model.ScanStatusRunning):
+		err = model.ResumeScan()
+
+	default:
+		if err := h.MessageResponse("invalid-scan-status", r.URL.RequestURI()); err == nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+		} else {
+			log.Println("Error while writing response. Details:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err != nil {
+		h.scanNotRunning(w, r)
+		return
+	}
+
+	h.SetCurrentScan(model.GetCurrentScan())
+	h.retrieveScan(w, r)
+}
+
+// Cancel the scan in progress. The scan only finishes after saving the domains already
+// checked, so the cancellation is accepted but not finished when the response is sent
+func (h *ScanHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if h.currentScan == nil {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := model.CancelScan(); err != nil {
+		h.scanNotRunning(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *ScanHandler) scanNotRunning(w http.ResponseWriter, r *http.Request) {
+	if err := h.MessageResponse("scan-not-running", r.URL.RequestURI()); err == nil {
+		w.WriteHeader(http.StatusConflict)
+
+	} else {
+		log.Println("Error while writing response. Details:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
 func (h *ScanHandler) retrieveScan(w http.ResponseWriter, r *http.Request) {
-	w.Header().Add("ETag", strconv.Itoa(h.scan.Revision))
-	w.Header().Add("Last-Modified", h.scan.LastModifiedAt.Format(time.RFC1123))
+	w.Header().Add("ETag", h.GetETag())
+	w.Header().Add("Last-Modified", h.GetLastModifiedAt().Format(time.RFC1123))
 	w.WriteHeader(http.StatusOK)
 
-	scanResponse := protocol.ScanToScanResponse(h.scan)
+	var scanResponse protocol.ScanResponse
+	if h.currentScan != nil {
+		scanResponse = protocol.CurrentScanToScanResponse(*h.currentScan)
+	} else {
+		scanResponse = protocol.ScanToScanResponse(h.scan)
+	}
 	h.Response = &scanResponse
 }
 
@@ -0,0 +1,166 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package handler store the REST handlers of specific URI
+package handler
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy"
+	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/http/rest/check"
+	"github.com/rafaeljusto/shelter/net/http/rest/interceptor"
+	"github.com/rafaeljusto/shelter/net/http/rest/messages"
+	"github.com/rafaeljusto/shelter/net/http/rest/protocol"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// Interval to check the progress of the current scan. The progress is only sent when
+	// it changed since the last event
+	scanEventsProgressInterval = 1 * time.Second
+
+	// Maximum time without sending anything to the user. Comments are sent to keep the
+	// connection alive and to detect users that aren't following the scan anymore
+	scanEventsKeepAliveInterval = 15 * time.Second
+)
+
+// Name of the event sent when the number of domains scanned or to be scanned changed. The
+// other names come from the scan event types
+const scanEventProgress = "progress"
+
+func init() {
+	HandleFunc("/scan/current/events", func() handy.Handler {
+		return new(ScanEventsHandler)
+	})
+}
+
+// ScanEventsHandler is responsable for the /scan/current/events resource, that sends the
+// progress, the status changes and the domains' results of the current scan as
+// Server-Sent Events, so that the user can follow the scan without polling /scan/current
+type ScanEventsHandler struct {
+	handy.DefaultHandler                           // Inject the HTTP methods that this resource does not implement
+	language             *messages.LanguagePack    // User preferred language based on HTTP header
+	Message              *protocol.MessageResponse `error` // Message on error sent to the user
+}
+
+func (h *ScanEventsHandler) SetLanguage(language *messages.LanguagePack) {
+	h.language = language
+}
+
+func (h *ScanEventsHandler) GetLanguage() *messages.LanguagePack {
+	return h.language
+}
+
+func (h *ScanEventsHandler) MessageResponse(messageId string, roid string) error {
+	var err error
+	h.Message, err = protocol.NewMessageResponse(messageId, roid, h.language)
+	return err
+}
+
+func (h *ScanEventsHandler) EventStream() {
+}
+
+// Send the events of the current scan until the user closes the connection. The first
+// event is always the status of the current scan, so that the user doesn't need to
+// retrieve /scan/current before following the events
+func (h *ScanEventsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		log.Println("Response writer cannot send the scan events while they happen")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Subscribe before retrieving the current scan, so that no event is lost between them
+	events, unsubscribe := model.SubscribeScanEvents()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", check.EventStreamContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	var id uint64
+	current := model.GetCurrentScan()
+
+	writeEvent := func(name string, event model.ScanEvent) bool {
+		id++
+		if err := protocol.WriteScanEvent(w, id, name, protocol.ToScanEventResponse(event)); err != nil {
+			log.Println("Error while writing scan event. Details:", err)
+			return false
+		}
+
+		current = event.Scan
+		return true
+	}
+
+	initialEvent := model.ScanEvent{
+		Type: model.ScanEventTypeStatus,
+		Scan: current,
+	}
+
+	if !writeEvent(model.ScanEventTypeToString(initialEvent.Type), initialEvent) {
+		return
+	}
+	flusher.Flush()
+
+	progressTicker := time.NewTicker(scanEventsProgressInterval)
+	defer progressTicker.Stop()
+
+	lastWriteAt := time.Now()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			if !writeEvent(model.ScanEventTypeToString(event.Type), event) {
+				return
+			}
+
+		case <-progressTicker.C:
+			scan := model.GetCurrentScan()
+
+			if scan.DomainsScanned != current.DomainsScanned ||
+				scan.DomainsToBeScanned != current.DomainsToBeScanned {
+
+				progressEvent := model.ScanEvent{
+					Type: model.ScanEventTypeStatus,
+					Scan: scan,
+				}
+
+				if !writeEvent(scanEventProgress, progressEvent) {
+					return
+				}
+
+			} else if time.Since(lastWriteAt) >= scanEventsKeepAliveInterval {
+				// Lines starting with colon are comments, ignored by the user
+				if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+					log.Println("Error while writing scan event. Details:", err)
+					return
+				}
+
+			} else {
+				continue
+			}
+		}
+
+		lastWriteAt = time.Now()
+		flusher.Flush()
+	}
+}
+
+func (h *ScanEventsHandler) Interceptors() handy.InterceptorChain {
+	return handy.NewInterceptorChain().
+		Chain(new(interceptor.Permission)).
+		Chain(interceptor.NewValidator(h)).
+		Chain(interceptor.NewJSONCodec(h))
+}
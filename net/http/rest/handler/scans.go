@@ -17,7 +17,9 @@ import (
 	"github.com/rafaeljusto/shelter/net/http/rest/interceptor"
 	"github.com/rafaeljusto/shelter/net/http/rest/messages"
 	"github.com/rafaeljusto/shelter/net/http/rest/protocol"
+	"github.com/rafaeljusto/shelter/net/scan"
 	"net/http"
+	"regexp/syntax"
 	"strconv"
 	"strings"
 	"time"
@@ -33,8 +35,11 @@ type ScansHandler struct {
 	handy.DefaultHandler
 	database        *mgo.Database
 	databaseSession *mgo.Session
+	storage         dao.Storage
 	language        *messages.LanguagePack
+	Request         protocol.ScanRequest      `request:"post"`
 	Response        *protocol.ScansResponse   `response:"get"`
+	ScanResponse    *protocol.ScanResponse    `response:"post"`
 	Message         *protocol.MessageResponse `error`
 	lastModifiedAt  time.Time
 }
@@ -55,6 +60,14 @@ func (h *ScansHandler) GetDatabase() *mgo.Database {
 	return h.database
 }
 
+func (h *ScansHandler) SetStorage(storage dao.Storage) {
+	h.storage = storage
+}
+
+func (h *ScansHandler) GetStorage() dao.Storage {
+	return h.storage
+}
+
 func (h *ScansHandler) GetLastModifiedAt() time.Time {
 	return h.lastModifiedAt
 }
@@ -90,6 +103,7 @@ func (h *ScansHandler) MessageResponse(messageId string, roid string) error {
 
 func (h *ScansHandler) ClearResponse() {
 	h.Response = nil
+	h.ScanResponse = nil
 }
 
 func (h *ScansHandler) Get(w http.ResponseWriter, r *http.Request) {
@@ -100,6 +114,49 @@ func (h *ScansHandler) Head(w http.ResponseWriter, r *http.Request) {
 	h.retrieveScans(w, r)
 }
 
+// Start a scan now, without waiting for the scheduled scan. The scan runs in background,
+// so the response is the current scan information that can be followed in /scan/current
+func (h *ScansHandler) Post(w http.ResponseWriter, r *http.Request) {
+	err := scan.StartScan(scan.ScanSelection{
+		FQDN:        h.Request.FQDN,
+		OnlyFailing: h.Request.OnlyFailing,
+	})
+
+	if err == model.ErrScanRunning {
+		if err := h.MessageResponse("scan-running", r.URL.RequestURI()); err == nil {
+			w.WriteHeader(http.StatusConflict)
+
+		} else {
+			log.Println("Error while writing response. Details:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+
+	} else if _, ok := err.(*syntax.Error); ok {
+		if err := h.MessageResponse("invalid-scan-fqdn", r.URL.RequestURI()); err == nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+		} else {
+			log.Println("Error while writing response. Details:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+
+	} else if err != nil {
+		log.Println("Error while starting scan. Details:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	current := model.GetCurrentScan()
+	scanResponse := protocol.CurrentScanToScanResponse(current)
+	h.ScanResponse = &scanResponse
+	h.lastModifiedAt = current.LastModifiedAt
+
+	w.Header().Add("Location", "/scan/current")
+	w.WriteHeader(http.StatusAccepted)
+}
+
 // The HEAD method is identical to GET except that the server MUST NOT return a message-
 // body in the response. But now the responsability for don't adding the body is from the
 // mux while writing the response
@@ -224,9 +281,7 @@ func (h *ScansHandler) retrieveScans(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	scanDAO := dao.ScanDAO{
-		Database: h.GetDatabase(),
-	}
+	scanDAO := h.GetStorage().ScanDAO()
 
 	// As we need to inform the user about the number of items, we always try to retrieve the scan
 	// objects even if is requested only the current object
@@ -7,7 +7,12 @@ package handler
 
 import (
 	"errors"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/http/rest/protocol"
+	"net/mail"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -32,6 +37,9 @@ var (
 var (
 	// Error throw when we don't find any valid date in the URI
 	ErrDateNotFound = errors.New("Did not found a valid RFC 3339 date in the URI")
+
+	// Error throw when a condition of the domains' filter has an invalid value
+	ErrInvalidDomainsFilter = errors.New("Invalid condition in the domains' filter")
 )
 
 // Retrieve the FQDN from the URI. When there're multiple FQDNs in the URI it will return the last
@@ -71,3 +79,115 @@ func getScanIdFromURI(uri string) (date time.Time, current bool, err error) {
 
 	return
 }
+
+// Retrieve the message identifier of an error that occurred while merging a domain request
+// into a domain object. When the error isn't caused by the user input an empty string is
+// returned
+func getMergeErrorMessageId(err error) string {
+	switch err {
+	case model.ErrInvalidFQDN:
+		return "invalid-fqdn"
+	case protocol.ErrInvalidDNSKEY:
+		return "invalid-dnskey"
+	case protocol.ErrInvalidDSAlgorithm:
+		return "invalid-ds-algorithm"
+	case protocol.ErrInvalidDSDigestType:
+		return "invalid-ds-digest-type"
+	case protocol.ErrInvalidIP:
+		return "invalid-ip"
+	case protocol.ErrInvalidEmail:
+		return "invalid-email"
+	case protocol.ErrInvalidLanguage:
+		return "invalid-language"
+	case protocol.ErrInvalidWebhook:
+		return "invalid-webhook"
+	case protocol.ErrInvalidDigest:
+		return "invalid-digest"
+	case protocol.ErrInvalidProblemType:
+		return "invalid-problem-type"
+	case protocol.ErrInvalidQuietHours:
+		return "invalid-quiet-hours"
+	}
+
+	return ""
+}
+
+// Fill the filter condition identified by the query string key. The lists of status are
+// separated by commas and the dates can be informed in RFC 3339 format or only with the
+// day (YYYY-MM-DD). The original value is used for the owner's e-mail, that is
+// case sensitive
+func parseDomainsFilter(filter *dao.DomainDAOFilter, key, value, originalValue string) error {
+	switch key {
+	case "nameserverstatus":
+		filter.NameserverStatus = nil
+		for _, statusText := range strings.Split(value, ",") {
+			status, err := model.NameserverStatusFromString(statusText)
+			if err != nil {
+				return err
+			}
+			filter.NameserverStatus = append(filter.NameserverStatus, status)
+		}
+
+	case "dsstatus":
+		filter.DSStatus = nil
+		for _, statusText := range strings.Split(value, ",") {
+			status, err := model.DSStatusFromString(statusText)
+			if err != nil {
+				return err
+			}
+			filter.DSStatus = append(filter.DSStatus, status)
+		}
+
+	case "dnssec":
+		dnssec, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		filter.DNSSEC = &dnssec
+
+	case "dsexpiresin":
+		days, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+
+		if days <= 0 {
+			return ErrInvalidDomainsFilter
+		}
+		filter.DSExpiresIn = days
+
+	case "notcheckedsince":
+		date, err := parseQueryDate(value)
+		if err != nil {
+			return err
+		}
+		filter.NotCheckedSince = date
+
+	case "owner":
+		email, err := mail.ParseAddress(originalValue)
+		if err != nil {
+			return err
+		}
+		filter.OwnerEmail = email.Address
+
+	case "nameserver":
+		host, err := model.NormalizeDomainName(value)
+		if err != nil {
+			return err
+		}
+		filter.NameserverHost = host
+	}
+
+	return nil
+}
+
+// Parse a date informed in the query string, that can be in RFC 3339 format or only with
+// the day (YYYY-MM-DD)
+func parseQueryDate(value string) (time.Time, error) {
+	date, err := time.Parse(time.RFC3339Nano, strings.ToUpper(value))
+	if err != nil {
+		return time.Parse("2006-01-02", value)
+	}
+
+	return date, nil
+}
@@ -6,6 +6,10 @@
 package handler
 
 import (
+	"errors"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/http/rest/protocol"
 	"testing"
 	"time"
 )
@@ -66,3 +70,96 @@ func BenchmarkGetScanIdFromURI(b *testing.B) {
 		getScanIdFromURI("/scan/2014-02-06T22:16:04.865737-02:00")
 	}
 }
+
+func TestGetMergeErrorMessageId(t *testing.T) {
+	if getMergeErrorMessageId(model.ErrInvalidFQDN) != "invalid-fqdn" {
+		t.Error("Not identifying invalid FQDN errors")
+	}
+
+	if getMergeErrorMessageId(protocol.ErrInvalidIP) != "invalid-ip" {
+		t.Error("Not identifying invalid IP errors")
+	}
+
+	if getMergeErrorMessageId(protocol.ErrInvalidEmail) != "invalid-email" {
+		t.Error("Not identifying invalid e-mail errors")
+	}
+
+	if getMergeErrorMessageId(protocol.ErrInvalidQuietHours) != "invalid-quiet-hours" {
+		t.Error("Not identifying invalid quiet hours errors")
+	}
+
+	if len(getMergeErrorMessageId(errors.New("low level error"))) != 0 {
+		t.Error("Identifying errors that weren't caused by the user input")
+	}
+}
+
+func TestParseDomainsFilter(t *testing.T) {
+	var filter dao.DomainDAOFilter
+
+	data := []struct {
+		key, value string
+	}{
+		{"nameserverstatus", "timeout,uh"},
+		{"dsstatus", "expsig"},
+		{"dnssec", "true"},
+		{"dsexpiresin", "7"},
+		{"notcheckedsince", "2014-03-01"},
+		{"owner", "Admin@Example.com.br"},
+		{"nameserver", "NS1.Example.com.br"},
+		{"unknown", "whatever"},
+	}
+
+	for _, item := range data {
+		if err := parseDomainsFilter(&filter, item.key, item.value, item.value); err != nil {
+			t.Errorf("Not accepting a valid %s filter. Details: %s", item.key, err)
+		}
+	}
+
+	if len(filter.NameserverStatus) != 2 ||
+		filter.NameserverStatus[0] != model.NameserverStatusTimeout ||
+		filter.NameserverStatus[1] != model.NameserverStatusUnknownHost {
+		t.Error("Not parsing the nameserver status filter properly")
+	}
+
+	if len(filter.DSStatus) != 1 || filter.DSStatus[0] != model.DSStatusExpiredSignature {
+		t.Error("Not parsing the DS status filter properly")
+	}
+
+	if filter.DNSSEC == nil || !*filter.DNSSEC {
+		t.Error("Not parsing the DNSSEC filter properly")
+	}
+
+	if filter.DSExpiresIn != 7 {
+		t.Error("Not parsing the DS expiration filter properly")
+	}
+
+	if !filter.NotCheckedSince.Equal(time.Date(2014, time.March, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Not parsing the last check filter properly")
+	}
+
+	if filter.OwnerEmail != "Admin@Example.com.br" {
+		t.Error("Not parsing the owner filter properly")
+	}
+
+	if filter.NameserverHost != "ns1.example.com.br." {
+		t.Error("Not normalizing the nameserver filter")
+	}
+
+	invalidData := []struct {
+		key, value string
+	}{
+		{"nameserverstatus", "ok,xxx"},
+		{"dsstatus", "xxx"},
+		{"dnssec", "maybe"},
+		{"dsexpiresin", "-1"},
+		{"notcheckedsince", "yesterday"},
+		{"owner", "not an e-mail"},
+		{"nameserver", "ns1..example"},
+	}
+
+	for _, item := range invalidData {
+		if err := parseDomainsFilter(&filter, item.key, item.value, item.value); err == nil {
+			t.Errorf("Accepting an invalid %s filter", item.key)
+		}
+	}
+}
@@ -9,8 +9,8 @@ import (
 	"net/http"
 
 	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
-	"github.com/rafaeljusto/shelter/config"
-	"github.com/rafaeljusto/shelter/database/mongodb"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/database"
 	"github.com/rafaeljusto/shelter/log"
 )
 
@@ -19,6 +19,8 @@ type DatabaseHandler interface {
 	GetDatabaseSession() *mgo.Session
 	SetDatabase(*mgo.Database)
 	GetDatabase() *mgo.Database
+	SetStorage(dao.Storage)
+	GetStorage() dao.Storage
 }
 
 type Database struct {
@@ -30,20 +32,7 @@ func NewDatabase(h DatabaseHandler) *Database {
 }
 
 func (i *Database) Before(w http.ResponseWriter, r *http.Request) {
-	log.Debugf("Initializing database with the parameters: URIS - %v | Name - %s | Auth - %t | Username - %s",
-		config.ShelterConfig.Database.URIs,
-		config.ShelterConfig.Database.Name,
-		config.ShelterConfig.Database.Auth.Enabled,
-		config.ShelterConfig.Database.Auth.Username,
-	)
-
-	database, databaseSession, err := mongodb.Open(
-		config.ShelterConfig.Database.URIs,
-		config.ShelterConfig.Database.Name,
-		config.ShelterConfig.Database.Auth.Enabled,
-		config.ShelterConfig.Database.Auth.Username,
-		config.ShelterConfig.Database.Auth.Password,
-	)
+	storage, mongoDatabase, databaseSession, err := database.Open()
 
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -51,8 +40,9 @@ func (i *Database) Before(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	i.databaseHandler.SetStorage(storage)
 	i.databaseHandler.SetDatabaseSession(databaseSession)
-	i.databaseHandler.SetDatabase(database)
+	i.databaseHandler.SetDatabase(mongoDatabase)
 }
 
 func (i *Database) After(w http.ResponseWriter, r *http.Request) {
@@ -7,7 +7,6 @@ package interceptor
 
 import (
 	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy/interceptor"
-	"github.com/rafaeljusto/shelter/dao"
 	"github.com/rafaeljusto/shelter/model"
 	"net/http"
 )
@@ -28,9 +27,7 @@ func NewDomain(h DomainHandler) *Domain {
 }
 
 func (i *Domain) Before(w http.ResponseWriter, r *http.Request) {
-	domainDAO := dao.DomainDAO{
-		Database: i.domainHandler.GetDatabase(),
-	}
+	domainDAO := i.domainHandler.GetStorage().DomainDAO()
 
 	domain, err := domainDAO.FindByFQDN(i.domainHandler.GetFQDN())
 
@@ -0,0 +1,63 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// interceptor add steps to the REST request before calling the handler
+package interceptor
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy/interceptor"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type DomainRevisionHandler interface {
+	DatabaseHandler
+	GetFQDN() string
+	GetRevision() string
+	SetDomainRevision(revision model.DomainRevision)
+	MessageResponse(string, string) error
+}
+
+type DomainRevision struct {
+	interceptor.NoAfterInterceptor
+	domainRevisionHandler DomainRevisionHandler
+}
+
+func NewDomainRevision(h DomainRevisionHandler) *DomainRevision {
+	return &DomainRevision{domainRevisionHandler: h}
+}
+
+func (i *DomainRevision) Before(w http.ResponseWriter, r *http.Request) {
+	revision, err := strconv.Atoi(strings.TrimSpace(i.domainRevisionHandler.GetRevision()))
+	if err != nil || revision <= 0 {
+		if err := i.domainRevisionHandler.MessageResponse("invalid-uri", r.URL.RequestURI()); err == nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+		} else {
+			log.Println("Error while writing response. Details:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		return
+	}
+
+	domainRevisionDAO := i.domainRevisionHandler.GetStorage().DomainRevisionDAO()
+
+	domainRevision, err := domainRevisionDAO.FindByRevision(i.domainRevisionHandler.GetFQDN(), revision)
+	if err == mgo.ErrNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		return
+
+	} else if err != nil {
+		log.Println("Error while searching domain revision. Details:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	i.domainRevisionHandler.SetDomainRevision(domainRevision)
+}
@@ -0,0 +1,71 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// interceptor add steps to the REST request before calling the handler
+package interceptor
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy/interceptor"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
+	"net/http"
+)
+
+type NameserverSummaryHandler interface {
+	DatabaseHandler
+	GetHost() string
+	SetNameserverSummary(summary model.NameserverSummary)
+	MessageResponse(string, string) error
+}
+
+type NameserverSummary struct {
+	interceptor.NoAfterInterceptor
+	nameserverSummaryHandler NameserverSummaryHandler
+}
+
+func NewNameserverSummary(h NameserverSummaryHandler) *NameserverSummary {
+	return &NameserverSummary{nameserverSummaryHandler: h}
+}
+
+func (i *NameserverSummary) Before(w http.ResponseWriter, r *http.Request) {
+	host, err := model.NormalizeDomainName(i.nameserverSummaryHandler.GetHost())
+	if err != nil {
+		if err := i.nameserverSummaryHandler.MessageResponse("invalid-uri", r.URL.RequestURI()); err == nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+		} else {
+			log.Println("Error while writing response. Details:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		return
+	}
+
+	// The nameserver summaries are only stored when using MongoDB, the file backend doesn't
+	// support this resource
+	if i.nameserverSummaryHandler.GetDatabase() == nil {
+		if err := i.nameserverSummaryHandler.MessageResponse("unsupported-backend", r.URL.RequestURI()); err == nil {
+			w.WriteHeader(http.StatusNotImplemented)
+
+		} else {
+			log.Println("Error while writing response. Details:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		return
+	}
+
+	nameserverSummaryDAO := dao.NameserverSummaryDAO{
+		Database: i.nameserverSummaryHandler.GetDatabase(),
+	}
+
+	summary, err := nameserverSummaryDAO.FindByHost(host)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	i.nameserverSummaryHandler.SetNameserverSummary(summary)
+}
@@ -0,0 +1,79 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// interceptor add steps to the REST request before calling the handler
+package interceptor
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type MockNameserverSummaryHandler struct {
+	Storage   dao.Storage
+	Database  *mgo.Database
+	Host      string
+	Summary   model.NameserverSummary
+	MessageId string
+}
+
+func (h *MockNameserverSummaryHandler) SetDatabaseSession(session *mgo.Session) {}
+
+func (h *MockNameserverSummaryHandler) GetDatabaseSession() *mgo.Session {
+	return nil
+}
+
+func (h *MockNameserverSummaryHandler) SetDatabase(database *mgo.Database) {
+	h.Database = database
+}
+
+func (h *MockNameserverSummaryHandler) GetDatabase() *mgo.Database {
+	return h.Database
+}
+
+func (h *MockNameserverSummaryHandler) SetStorage(storage dao.Storage) {
+	h.Storage = storage
+}
+
+func (h *MockNameserverSummaryHandler) GetStorage() dao.Storage {
+	return h.Storage
+}
+
+func (h *MockNameserverSummaryHandler) GetHost() string {
+	return h.Host
+}
+
+func (h *MockNameserverSummaryHandler) SetNameserverSummary(summary model.NameserverSummary) {
+	h.Summary = summary
+}
+
+func (h *MockNameserverSummaryHandler) MessageResponse(messageId string, roid string) error {
+	h.MessageId = messageId
+	return nil
+}
+
+func TestNameserverSummaryBeforeWithoutMongoDB(t *testing.T) {
+	nameserverSummaryHandler := MockNameserverSummaryHandler{
+		Host: "ns1.example.com.br.",
+	}
+
+	r, err := http.NewRequest("GET", "/nameserver/ns1.example.com.br.", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	NewNameserverSummary(&nameserverSummaryHandler).Before(w, r)
+
+	if w.Code != http.StatusNotImplemented ||
+		nameserverSummaryHandler.MessageId != "unsupported-backend" {
+
+		t.Errorf("Not rejecting the nameserver summary without MongoDB: %d %s",
+			w.Code, nameserverSummaryHandler.MessageId)
+	}
+}
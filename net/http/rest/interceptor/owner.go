@@ -0,0 +1,77 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// interceptor add steps to the REST request before calling the handler
+package interceptor
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy/interceptor"
+	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
+	"net/http"
+	"net/mail"
+)
+
+type OwnerHandler interface {
+	DatabaseHandler
+	GetEmail() string
+	SetEmail(email string)
+	GetPrincipal() model.Principal
+	SetDomains(domains []model.Domain)
+	MessageResponse(string, string) error
+}
+
+type Owner struct {
+	interceptor.NoAfterInterceptor
+	ownerHandler OwnerHandler
+}
+
+func NewOwner(h OwnerHandler) *Owner {
+	return &Owner{ownerHandler: h}
+}
+
+func (i *Owner) Before(w http.ResponseWriter, r *http.Request) {
+	email, err := mail.ParseAddress(i.ownerHandler.GetEmail())
+	if err != nil {
+		if err := i.ownerHandler.MessageResponse("invalid-uri", r.URL.RequestURI()); err == nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+		} else {
+			log.Println("Error while writing response. Details:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		return
+	}
+
+	domainDAO := i.ownerHandler.GetStorage().DomainDAO()
+
+	domains, err := domainDAO.FindByOwner(email.Address)
+	if err != nil {
+		log.Println("Error while searching domains of the owner. Details:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Principals restricted to some domains can only see and change the owner in their
+	// domains, the domains of other registrars are ignored
+	principal := i.ownerHandler.GetPrincipal()
+
+	var principalDomains []model.Domain
+	for _, domain := range domains {
+		if principal.Owns(domain.FQDN) {
+			principalDomains = append(principalDomains, domain)
+		}
+	}
+	domains = principalDomains
+
+	// The owners only exist inside the domains, so an owner without domains doesn't exist
+	if len(domains) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	i.ownerHandler.SetEmail(email.Address)
+	i.ownerHandler.SetDomains(domains)
+}
@@ -0,0 +1,170 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// interceptor add steps to the REST request before calling the handler
+package interceptor
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/database/file"
+	"github.com/rafaeljusto/shelter/model"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type MockOwnerHandler struct {
+	Storage   dao.Storage
+	Email     string
+	Principal model.Principal
+	Domains   []model.Domain
+	MessageId string
+}
+
+func (h *MockOwnerHandler) SetDatabaseSession(session *mgo.Session) {}
+
+func (h *MockOwnerHandler) GetDatabaseSession() *mgo.Session {
+	return nil
+}
+
+func (h *MockOwnerHandler) SetDatabase(database *mgo.Database) {}
+
+func (h *MockOwnerHandler) GetDatabase() *mgo.Database {
+	return nil
+}
+
+func (h *MockOwnerHandler) SetStorage(storage dao.Storage) {
+	h.Storage = storage
+}
+
+func (h *MockOwnerHandler) GetStorage() dao.Storage {
+	return h.Storage
+}
+
+func (h *MockOwnerHandler) GetEmail() string {
+	return h.Email
+}
+
+func (h *MockOwnerHandler) SetEmail(email string) {
+	h.Email = email
+}
+
+func (h *MockOwnerHandler) GetPrincipal() model.Principal {
+	return h.Principal
+}
+
+func (h *MockOwnerHandler) SetDomains(domains []model.Domain) {
+	h.Domains = domains
+}
+
+func (h *MockOwnerHandler) MessageResponse(messageId string, roid string) error {
+	h.MessageId = messageId
+	return nil
+}
+
+func TestOwnerBefore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shelter-interceptor-owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	database, err := file.Open(filepath.Join(dir, "shelter.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage := dao.FileStorage{Database: database}
+
+	email, err := mail.ParseAddress("admin@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fqdn := range []string{"example.com.br.", "example.net.br.", "example.com."} {
+		domain := model.Domain{
+			FQDN:   fqdn,
+			Owners: []model.Owner{{Email: email, Language: "en-US"}},
+		}
+
+		if err := storage.DomainDAO().Save(&domain); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data := []struct {
+		Principal       model.Principal
+		ExpectedCode    int
+		ExpectedDomains []string
+	}{
+		{
+			Principal:       model.Principal{Id: "admin", Role: model.RoleAdmin},
+			ExpectedCode:    http.StatusOK,
+			ExpectedDomains: []string{"example.com.br.", "example.net.br.", "example.com."},
+		},
+		{
+			Principal: model.Principal{
+				Id:      "registrar",
+				Role:    model.RoleDomainWriter,
+				Domains: []string{"com.br."},
+			},
+			ExpectedCode:    http.StatusOK,
+			ExpectedDomains: []string{"example.com.br."},
+		},
+		{
+			Principal: model.Principal{
+				Id:      "other-registrar",
+				Role:    model.RoleDomainWriter,
+				Domains: []string{"org.br."},
+			},
+			ExpectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, item := range data {
+		ownerHandler := MockOwnerHandler{
+			Storage:   storage,
+			Email:     email.Address,
+			Principal: item.Principal,
+		}
+
+		r, err := http.NewRequest("PUT", "/owner/admin@example.com", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		NewOwner(&ownerHandler).Before(w, r)
+
+		if w.Code != item.ExpectedCode {
+			t.Errorf("Principal %s: expected code %d and got %d",
+				item.Principal.Id, item.ExpectedCode, w.Code)
+		}
+
+		if len(ownerHandler.Domains) != len(item.ExpectedDomains) {
+			t.Errorf("Principal %s: expected %d domains and got %d",
+				item.Principal.Id, len(item.ExpectedDomains), len(ownerHandler.Domains))
+			continue
+		}
+
+		for _, fqdn := range item.ExpectedDomains {
+			found := false
+			for _, domain := range ownerHandler.Domains {
+				if domain.FQDN == fqdn {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				t.Errorf("Principal %s: domain %s not returned", item.Principal.Id, fqdn)
+			}
+		}
+	}
+}
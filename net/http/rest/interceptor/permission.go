@@ -6,6 +6,7 @@
 package interceptor
 
 import (
+	"fmt"
 	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy/interceptor"
 	"github.com/rafaeljusto/shelter/log"
 	"net"
@@ -29,6 +30,29 @@ func (i *Permission) Before(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ip, err := ClientAddress(r)
+	if err != nil {
+		// Something wrong, because the REST server could not identify the remote address
+		// properly. This is really awkward, because this is a responsability of the server,
+		// maybe this error will never be throw
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Printf("Error checking CIDR whitelist. Details: %s", err)
+		return
+	}
+
+	for _, cidr := range ACL {
+		if cidr.Contains(ip) {
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusForbidden)
+}
+
+// ClientAddress identifies the IP address of the client that sent the request. When the
+// REST server is behind a proxy, the address is retrieved from the X-Forwarded-For or
+// X-Real-IP HTTP headers
+func ClientAddress(r *http.Request) (net.IP, error) {
 	var clientAddress string
 
 	xff := r.Header.Get("X-Forwarded-For")
@@ -50,28 +74,14 @@ func (i *Permission) Before(w http.ResponseWriter, r *http.Request) {
 		var err error
 		clientAddress, _, err = net.SplitHostPort(r.RemoteAddr)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			log.Printf("Error checking CIDR whitelist. Details: Remote IP address '%s' could not be parsed. %s", r.RemoteAddr, err)
-			return
+			return nil, fmt.Errorf("Remote IP address '%s' could not be parsed. %s", r.RemoteAddr, err)
 		}
-
 	}
 
 	ip := net.ParseIP(clientAddress)
 	if ip == nil {
-		// Something wrong, because the REST server could not identify the remote address properly. This
-		// is really awkward, because this is a responsability of the server, maybe this error will
-		// never be throw
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Printf("Error checking CIDR whitelist. Details: IP address '%s' could not be parsed.", clientAddress)
-		return
-	}
-
-	for _, cidr := range ACL {
-		if cidr.Contains(ip) {
-			return
-		}
+		return nil, fmt.Errorf("IP address '%s' could not be parsed.", clientAddress)
 	}
 
-	w.WriteHeader(http.StatusForbidden)
+	return ip, nil
 }
@@ -7,7 +7,6 @@ package interceptor
 
 import (
 	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy/interceptor"
-	"github.com/rafaeljusto/shelter/dao"
 	"github.com/rafaeljusto/shelter/log"
 	"github.com/rafaeljusto/shelter/model"
 	"net/http"
@@ -27,6 +26,7 @@ type ScanHandler interface {
 	DatabaseHandler
 	GetStartedAt() string
 	SetScan(scan model.Scan)
+	SetCurrentScan(scan model.CurrentScan)
 	MessageResponse(string, string) error
 }
 
@@ -40,6 +40,12 @@ func NewScan(h ScanHandler) *Scan {
 }
 
 func (i *Scan) Before(w http.ResponseWriter, r *http.Request) {
+	// The scan in progress isn't stored in the database yet
+	if isCurrentScan.MatchString(i.scanHandler.GetStartedAt()) {
+		i.scanHandler.SetCurrentScan(model.GetCurrentScan())
+		return
+	}
+
 	date, err := time.Parse(time.RFC3339Nano, strings.ToUpper(i.scanHandler.GetStartedAt()))
 
 	if err != nil {
@@ -52,9 +58,7 @@ func (i *Scan) Before(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	scanDAO := dao.ScanDAO{
-		Database: i.scanHandler.GetDatabase(),
-	}
+	scanDAO := i.scanHandler.GetStorage().ScanDAO()
 
 	scan, err := scanDAO.FindByStartedAt(date)
 	if err != nil {
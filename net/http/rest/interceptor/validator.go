@@ -14,6 +14,7 @@ import (
 	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy/interceptor"
 	"github.com/rafaeljusto/shelter/config"
 	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
 	"github.com/rafaeljusto/shelter/net/http/rest/check"
 	"github.com/rafaeljusto/shelter/net/http/rest/messages"
 	"github.com/rafaeljusto/shelter/secret"
@@ -25,11 +26,30 @@ var (
 	ErrSecretNotFound = errors.New("Secret related to Authorization's secret id not found")
 )
 
+var (
+	// Principals stores the role and the domains of each secret id. A secret id without
+	// principal is an admin of all domains, as it was before the roles existed
+	Principals map[string]model.Principal
+)
+
 type ValidatorHandler interface {
 	SetLanguage(*messages.LanguagePack)
 	MessageResponse(string, string) error
 }
 
+// PrincipalHandler is implemented by the resources that manage domains. Only these
+// resources can be used by principals restricted to some domains, and by principals with
+// the domain-writer role to change data
+type PrincipalHandler interface {
+	SetPrincipal(model.Principal)
+}
+
+// EventStreamHandler is implemented by the resources that send Server-Sent Events. These
+// resources also accept users that only support the event stream format
+type EventStreamHandler interface {
+	EventStream()
+}
+
 type Validator struct {
 	interceptor.NoAfterInterceptor
 	validatorHandler ValidatorHandler
@@ -60,7 +80,9 @@ func (i *Validator) Before(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !check.HTTPAccept(r) {
+	_, isEventStream := i.validatorHandler.(EventStreamHandler)
+
+	if !check.HTTPAccept(r) && !(isEventStream && check.HTTPAcceptEventStream(r)) {
 		if err := i.validatorHandler.MessageResponse("accept-error", r.RequestURI); err == nil {
 			w.WriteHeader(http.StatusNotAcceptable)
 
@@ -145,7 +167,10 @@ func (i *Validator) Before(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var principal model.Principal
+
 	authorized, err := check.HTTPAuthorization(r, func(secretId string) (string, error) {
+		principal = findPrincipal(secretId)
 		s, ok := config.ShelterConfig.RESTServer.Secrets[secretId]
 
 		if !ok {
@@ -165,6 +190,7 @@ func (i *Validator) Before(w http.ResponseWriter, r *http.Request) {
 		return
 
 	} else if authorized {
+		i.checkPrincipal(w, r, principal)
 		return
 	}
 
@@ -199,3 +225,59 @@ func (i *Validator) Before(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 }
+
+// Verify if the principal that signed the request is allowed to execute it. Principals
+// restricted to some domains can only access resources of their domains, and only the
+// admin role can change resources that aren't domains
+func (i *Validator) checkPrincipal(w http.ResponseWriter, r *http.Request, principal model.Principal) {
+	principalHandler, isDomainResource := i.validatorHandler.(PrincipalHandler)
+
+	var allowed bool
+	if !isDomainResource {
+		allowed = !principal.Restricted() &&
+			(isReadOnlyMethod(r.Method) || principal.Role == model.RoleAdmin)
+
+	} else {
+		allowed = isReadOnlyMethod(r.Method) || principal.Role != model.RoleReadOnly
+
+		// When the resource is related to a specific domain, the FQDN was already
+		// normalized by the FQDN interceptor
+		if fqdnHandler, ok := i.validatorHandler.(FQDNHandler); ok {
+			allowed = allowed && principal.Owns(fqdnHandler.GetFQDN())
+		}
+	}
+
+	if !allowed {
+		if err := i.validatorHandler.MessageResponse("access-denied", r.RequestURI); err == nil {
+			w.WriteHeader(http.StatusForbidden)
+
+		} else {
+			log.Println("Error while writing response. Details:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		return
+	}
+
+	if isDomainResource {
+		principalHandler.SetPrincipal(principal)
+	}
+}
+
+// findPrincipal returns the principal related to the secret id, or an unrestricted admin
+// when there's no principal configured
+func findPrincipal(secretId string) model.Principal {
+	if principal, ok := Principals[secretId]; ok {
+		return principal
+	}
+
+	return model.Principal{
+		Id:   secretId,
+		Role: model.RoleAdmin,
+	}
+}
+
+// isReadOnlyMethod returns true for the HTTP methods that don't change resources
+func isReadOnlyMethod(method string) bool {
+	return method == "GET" || method == "HEAD"
+}
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/rafaeljusto/shelter/config"
+	"github.com/rafaeljusto/shelter/model"
 	"github.com/rafaeljusto/shelter/net/http/rest/check"
 	"github.com/rafaeljusto/shelter/net/http/rest/messages"
 	"net/http"
@@ -628,3 +629,193 @@ func TestValidatorBefore(t *testing.T) {
 		}
 	}
 }
+
+type MockDomainValidatorHandler struct {
+	MockValidatorHandler
+	FQDN      string
+	Principal model.Principal
+}
+
+func (h *MockDomainValidatorHandler) SetFQDN(fqdn string) {
+	h.FQDN = fqdn
+}
+
+func (h *MockDomainValidatorHandler) GetFQDN() string {
+	return h.FQDN
+}
+
+func (h *MockDomainValidatorHandler) SetPrincipal(principal model.Principal) {
+	h.Principal = principal
+}
+
+func TestValidatorPrincipal(t *testing.T) {
+	config.ShelterConfig.RESTServer.Secrets = map[string]string{
+		"1": "ohV43/9bKlVNaXeNTqEuHQp57LCPCQ==",
+		"2": "ohV43/9bKlVNaXeNTqEuHQp57LCPCQ==",
+		"3": "ohV43/9bKlVNaXeNTqEuHQp57LCPCQ==",
+		"4": "ohV43/9bKlVNaXeNTqEuHQp57LCPCQ==",
+	}
+
+	Principals = map[string]model.Principal{
+		"2": {Id: "2", Role: model.RoleReadOnly},
+		"3": {Id: "3", Role: model.RoleDomainWriter, Domains: []string{"example.com.br."}},
+		"4": {Id: "4", Role: model.RoleAdmin, Domains: []string{"example.com.br."}},
+	}
+	defer func() {
+		Principals = nil
+	}()
+
+	messages.ShelterRESTLanguagePacks = messages.LanguagePacks{
+		Default: "en-US",
+		Packs: []messages.LanguagePack{
+			{
+				GenericName:  "en",
+				SpecificName: "en-US",
+			},
+		},
+	}
+	messages.ShelterRESTLanguagePack =
+		messages.ShelterRESTLanguagePacks.Select(messages.ShelterRESTLanguagePacks.Default)
+
+	data := []struct {
+		SecretId       string
+		Method         string
+		DomainResource bool
+		FQDN           string
+		ExpectedCode   int
+	}{
+		// Secret id without principal is an admin of all domains
+		{SecretId: "1", Method: "DELETE", ExpectedCode: http.StatusOK},
+		{SecretId: "1", Method: "PUT", DomainResource: true, FQDN: "other.com.br.", ExpectedCode: http.StatusOK},
+		{SecretId: "2", Method: "GET", ExpectedCode: http.StatusOK},
+		{SecretId: "2", Method: "GET", DomainResource: true, FQDN: "other.com.br.", ExpectedCode: http.StatusOK},
+		{SecretId: "2", Method: "PUT", DomainResource: true, ExpectedCode: http.StatusForbidden},
+		{SecretId: "2", Method: "POST", ExpectedCode: http.StatusForbidden},
+		{SecretId: "3", Method: "GET", ExpectedCode: http.StatusForbidden},
+		{SecretId: "3", Method: "POST", DomainResource: true, FQDN: "example.com.br.", ExpectedCode: http.StatusOK},
+		{SecretId: "3", Method: "PUT", DomainResource: true, FQDN: "www.example.com.br.", ExpectedCode: http.StatusOK},
+		{SecretId: "3", Method: "GET", DomainResource: true, FQDN: "other.com.br.", ExpectedCode: http.StatusForbidden},
+		{SecretId: "4", Method: "DELETE", DomainResource: true, FQDN: "example.com.br.", ExpectedCode: http.StatusOK},
+		{SecretId: "4", Method: "POST", ExpectedCode: http.StatusForbidden},
+	}
+
+	for i, item := range data {
+		r, err := http.NewRequest(item.Method, "/test", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.Header.Set("Date", time.Now().Format(time.RFC1123))
+
+		stringToSign, err := check.BuildStringToSign(r, item.SecretId)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		signature := check.GenerateSignature(stringToSign, "abc123")
+		r.Header.Set("Authorization", fmt.Sprintf("shelter %s:%s", item.SecretId, signature))
+
+		var validator *Validator
+		domainHandler := MockDomainValidatorHandler{FQDN: item.FQDN}
+
+		if item.DomainResource {
+			validator = NewValidator(&domainHandler)
+		} else {
+			validator = NewValidator(&domainHandler.MockValidatorHandler)
+		}
+
+		w := httptest.NewRecorder()
+		validator.Before(w, r)
+
+		if w.Code != item.ExpectedCode {
+			t.Errorf("Item %d: Wrong status code. Expected %d and got %d",
+				i, item.ExpectedCode, w.Code)
+		}
+
+		if w.Code == http.StatusForbidden && domainHandler.MessageId != "access-denied" {
+			t.Errorf("Item %d: Wrong message id. Expected access-denied and got %s",
+				i, domainHandler.MessageId)
+		}
+
+		if item.DomainResource && w.Code == http.StatusOK && domainHandler.Principal.Id != item.SecretId {
+			t.Errorf("Item %d: Principal not sent to the handler", i)
+		}
+	}
+}
+
+type MockEventStreamValidatorHandler struct {
+	MockValidatorHandler
+}
+
+func (h *MockEventStreamValidatorHandler) EventStream() {
+}
+
+func TestValidatorEventStream(t *testing.T) {
+	config.ShelterConfig.RESTServer.Secrets = map[string]string{
+		"1": "ohV43/9bKlVNaXeNTqEuHQp57LCPCQ==",
+	}
+
+	messages.ShelterRESTLanguagePacks = messages.LanguagePacks{
+		Default: "en-US",
+		Packs: []messages.LanguagePack{
+			{
+				GenericName:  "en",
+				SpecificName: "en-US",
+			},
+		},
+	}
+	messages.ShelterRESTLanguagePack =
+		messages.ShelterRESTLanguagePacks.Select(messages.ShelterRESTLanguagePacks.Default)
+
+	data := []struct {
+		Accept            string
+		EventStream       bool
+		ExpectedCode      int
+		ExpectedMessageId string
+	}{
+		{Accept: "text/event-stream", EventStream: true, ExpectedCode: http.StatusOK},
+		{Accept: "application/vnd.shelter+json", EventStream: true, ExpectedCode: http.StatusOK},
+		{Accept: "text/html", EventStream: true, ExpectedCode: http.StatusNotAcceptable, ExpectedMessageId: "accept-error"},
+		{Accept: "text/event-stream", ExpectedCode: http.StatusNotAcceptable, ExpectedMessageId: "accept-error"},
+	}
+
+	for i, item := range data {
+		r, err := http.NewRequest("GET", "/test", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.Header.Set("Accept", item.Accept)
+		r.Header.Set("Date", time.Now().Format(time.RFC1123))
+
+		stringToSign, err := check.BuildStringToSign(r, "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		signature := check.GenerateSignature(stringToSign, "abc123")
+		r.Header.Set("Authorization", fmt.Sprintf("shelter 1:%s", signature))
+
+		var validator *Validator
+		eventStreamHandler := MockEventStreamValidatorHandler{}
+
+		if item.EventStream {
+			validator = NewValidator(&eventStreamHandler)
+		} else {
+			validator = NewValidator(&eventStreamHandler.MockValidatorHandler)
+		}
+
+		w := httptest.NewRecorder()
+		validator.Before(w, r)
+
+		if w.Code != item.ExpectedCode {
+			t.Errorf("Item %d: Wrong status code. Expected %d and got %d",
+				i, item.ExpectedCode, w.Code)
+		}
+
+		if eventStreamHandler.MessageId != item.ExpectedMessageId {
+			t.Errorf("Item %d: Wrong message id. Expected %s and got %s",
+				i, item.ExpectedMessageId, eventStreamHandler.MessageId)
+		}
+	}
+}
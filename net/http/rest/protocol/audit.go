@@ -0,0 +1,190 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the REST protocol
+package protocol
+
+import (
+	"fmt"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/model"
+	"net/url"
+	"time"
+)
+
+// AuditResponse store the changes made in the domains with pagination support
+type AuditResponse struct {
+	Page          int                  `json:"page"`              // Current page selected
+	PageSize      int                  `json:"pageSize"`          // Number of entries in a page
+	NumberOfPages int                  `json:"numberOfPages"`     // Total number of pages for the result set
+	NumberOfItems int                  `json:"numberOfItems"`     // Total number of entries in the result set
+	Entries       []AuditEntryResponse `json:"entries,omitempty"` // List of entries for the current page
+	Links         []Link               `json:"links,omitempty"`   // Links for pagination managment
+}
+
+// AuditEntryResponse represents a change made in a domain, with the state of the domain
+// before and after the change
+type AuditEntryResponse struct {
+	FQDN          string                `json:"fqdn"`                    // Domain name that was changed
+	Principal     string                `json:"principal,omitempty"`     // Secret id that signed the request
+	ClientAddress string                `json:"clientAddress,omitempty"` // IP address of the client
+	Method        string                `json:"method"`                  // HTTP method of the request
+	Date          PreciseTime           `json:"date"`                    // Date and time of the change
+	Before        *DomainResponse       `json:"before,omitempty"`        // Domain before the change
+	After         *DomainResponse       `json:"after,omitempty"`         // Domain after the change
+	Changes       []AuditChangeResponse `json:"changes,omitempty"`       // Configuration items that changed
+	Links         []Link                `json:"links,omitempty"`         // Link to the changed domain
+}
+
+// AuditChangeResponse represents a configuration item that was added, removed or changed
+type AuditChangeResponse struct {
+	Field  string `json:"field"`            // Changed item
+	Before string `json:"before,omitempty"` // Value before the change
+	After  string `json:"after,omitempty"`  // Value after the change
+}
+
+// Convert the audit entries into protocol format with pagination support
+func ToAuditResponse(entries []model.AuditEntry, pagination dao.AuditDAOPagination,
+	filter dao.AuditDAOFilter) AuditResponse {
+
+	var entriesResponse []AuditEntryResponse
+	for _, entry := range entries {
+		entriesResponse = append(entriesResponse, toAuditEntryResponse(entry))
+	}
+
+	var orderBy string
+	for _, sort := range pagination.OrderBy {
+		if len(orderBy) > 0 {
+			orderBy += "@"
+		}
+
+		orderBy += fmt.Sprintf("%s:%s",
+			dao.AuditDAOOrderByFieldToString(sort.Field),
+			dao.DAOOrderByDirectionToString(sort.Direction),
+		)
+	}
+
+	filterParameters := toAuditFilterParameters(filter)
+
+	// Add pagination managment links to the response. The URI is hard coded, I didn't have
+	// any idea on how can we do this dynamically yet. We cannot get the URI from the
+	// handler because we are going to have a cross-reference problem
+	var links []Link
+
+	// Only add fast backward if we aren't in the first page
+	if pagination.Page > 1 {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypeFirst},
+			HRef: fmt.Sprintf("/audit?pagesize=%d&page=%d&orderby=%s%s",
+				pagination.PageSize, 1, orderBy, filterParameters),
+		})
+	}
+
+	// Only add previous if theres a previous page
+	if pagination.Page-1 >= 1 {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypePrev},
+			HRef: fmt.Sprintf("/audit?pagesize=%d&page=%d&orderby=%s%s",
+				pagination.PageSize, pagination.Page-1, orderBy, filterParameters),
+		})
+	}
+
+	// Only add next if there's a next page
+	if pagination.Page+1 <= pagination.NumberOfPages {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypeNext},
+			HRef: fmt.Sprintf("/audit?pagesize=%d&page=%d&orderby=%s%s",
+				pagination.PageSize, pagination.Page+1, orderBy, filterParameters),
+		})
+	}
+
+	// Only add the fast forward if we aren't on the last page
+	if pagination.Page < pagination.NumberOfPages {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypeLast},
+			HRef: fmt.Sprintf("/audit?pagesize=%d&page=%d&orderby=%s%s",
+				pagination.PageSize, pagination.NumberOfPages, orderBy, filterParameters),
+		})
+	}
+
+	return AuditResponse{
+		Page:          pagination.Page,
+		PageSize:      pagination.PageSize,
+		NumberOfPages: pagination.NumberOfPages,
+		NumberOfItems: pagination.NumberOfItems,
+		Entries:       entriesResponse,
+		Links:         links,
+	}
+}
+
+// Convert the filter conditions back into query string parameters, so that the
+// pagination links keep the same result set. The domain suffixes are defined by the
+// principal and not by the user, so they aren't added
+func toAuditFilterParameters(filter dao.AuditDAOFilter) string {
+	var parameters string
+
+	if len(filter.FQDN) > 0 {
+		parameters += "&fqdn=" + url.QueryEscape(filter.FQDN)
+	}
+
+	if len(filter.Principal) > 0 {
+		parameters += "&principal=" + url.QueryEscape(filter.Principal)
+	}
+
+	if !filter.Since.IsZero() {
+		parameters += "&since=" + url.QueryEscape(filter.Since.UTC().Format(time.RFC3339Nano))
+	}
+
+	if !filter.Until.IsZero() {
+		parameters += "&until=" + url.QueryEscape(filter.Until.UTC().Format(time.RFC3339Nano))
+	}
+
+	return parameters
+}
+
+// Convert an audit entry into a format easy to interpret by the user. The domain states
+// don't have links, because they could not exist anymore
+func toAuditEntryResponse(entry model.AuditEntry) AuditEntryResponse {
+	response := AuditEntryResponse{
+		FQDN:          entry.FQDN,
+		Principal:     entry.Principal,
+		ClientAddress: entry.ClientAddress,
+		Method:        entry.Method,
+		Date:          PreciseTime{entry.Date},
+	}
+
+	if entry.Before != nil {
+		before := ToDomainResponse(*entry.Before, false)
+		response.Before = &before
+	}
+
+	if entry.After != nil {
+		after := ToDomainResponse(*entry.After, false)
+		response.After = &after
+
+		response.Links = []Link{
+			{
+				Types: []LinkType{LinkTypeRelated},
+				HRef:  fmt.Sprintf("/domain/%s", entry.FQDN),
+			},
+		}
+	}
+
+	response.Changes = toAuditChangesResponse(entry.Changes)
+	return response
+}
+
+// Convert the changed configuration items of a domain into protocol format
+func toAuditChangesResponse(changes []model.AuditChange) []AuditChangeResponse {
+	var changesResponse []AuditChangeResponse
+	for _, change := range changes {
+		changesResponse = append(changesResponse, AuditChangeResponse{
+			Field:  change.Field,
+			Before: change.Before,
+			After:  change.After,
+		})
+	}
+
+	return changesResponse
+}
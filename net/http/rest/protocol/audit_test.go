@@ -0,0 +1,82 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the REST protocol
+package protocol
+
+import (
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/model"
+	"testing"
+	"time"
+)
+
+func TestToAuditResponse(t *testing.T) {
+	domain := model.Domain{
+		FQDN: "example.com.br.",
+		Nameservers: []model.Nameserver{
+			{Host: "ns1.example.com.br."},
+		},
+	}
+
+	created := model.NewAuditEntry(nil, &domain)
+	created.Principal = "1"
+	created.ClientAddress = "127.0.0.1"
+	created.Method = "PUT"
+
+	removed := model.NewAuditEntry(&domain, nil)
+	removed.Method = "DELETE"
+
+	pagination := dao.AuditDAOPagination{
+		PageSize: 1,
+		Page:     2,
+		OrderBy: []dao.AuditDAOSort{
+			{
+				Field:     dao.AuditDAOOrderByFieldDate,
+				Direction: dao.DAOOrderByDirectionDescending,
+			},
+		},
+		NumberOfItems: 3,
+		NumberOfPages: 3,
+	}
+
+	filter := dao.AuditDAOFilter{
+		FQDN:     "example.com.br.",
+		Since:    time.Date(2014, time.March, 1, 10, 0, 0, 0, time.UTC),
+		Suffixes: []string{"com.br."},
+	}
+
+	auditResponse := ToAuditResponse([]model.AuditEntry{created, removed}, pagination, filter)
+
+	if len(auditResponse.Entries) != 2 {
+		t.Fatal("Not converting audit entries properly")
+	}
+
+	if len(auditResponse.Links) != 4 {
+		t.Fatalf("Not adding all pagination links. Expected 4 and got %d", len(auditResponse.Links))
+	}
+
+	expectedHRef := "/audit?pagesize=1&page=3&orderby=date:desc" +
+		"&fqdn=example.com.br.&since=2014-03-01T10%3A00%3A00Z"
+	if auditResponse.Links[2].HRef != expectedHRef {
+		t.Errorf("Wrong pagination link. Expected %s and got %s",
+			expectedHRef, auditResponse.Links[2].HRef)
+	}
+
+	createdResponse := auditResponse.Entries[0]
+	if createdResponse.Before != nil || createdResponse.After == nil ||
+		createdResponse.After.FQDN != "example.com.br." ||
+		createdResponse.Principal != "1" || createdResponse.ClientAddress != "127.0.0.1" ||
+		len(createdResponse.Changes) != 1 || len(createdResponse.Links) != 1 {
+
+		t.Error("Not converting the domain creation properly")
+	}
+
+	removedResponse := auditResponse.Entries[1]
+	if removedResponse.Before == nil || removedResponse.After != nil ||
+		len(removedResponse.Before.Links) > 0 || len(removedResponse.Links) > 0 {
+
+		t.Error("Not converting the domain removal properly")
+	}
+}
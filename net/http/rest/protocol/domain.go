@@ -94,16 +94,45 @@ func Merge(domain model.Domain, domainRequest DomainRequest) (model.Domain, erro
 	}
 	domain.DSSet = dsSet
 
-	// We can replace the whole structure of the e-mail every time that a new UPDATE arrives
-	// because there's no extra information in server side that we need to keep
-	domain.Owners, err = toOwnersModel(domainRequest.Owners)
+	// We can replace the whole structure of the e-mail every time that a new UPDATE arrives.
+	// The only extra information in server side is the webhook secret, that isn't returned
+	// to the user, so it's kept while the owner has the same webhook
+	owners, err := toOwnersModel(domainRequest.Owners)
 	if err != nil {
 		return domain, err
 	}
 
+	for index, userOwner := range owners {
+		if len(userOwner.WebhookSecret) > 0 {
+			continue
+		}
+
+		for _, owner := range domain.Owners {
+			if owner.Email != nil && owner.Email.Address == userOwner.Email.Address &&
+				owner.Webhook == userOwner.Webhook {
+
+				owners[index].WebhookSecret = owner.WebhookSecret
+				break
+			}
+		}
+	}
+	domain.Owners = owners
+
 	return domain, nil
 }
 
+// ToDomainRequest converts a domain object of the database back into a domain request
+// object, as if the user had sent it. Useful to restore an old state of the domain using
+// the same merge and validation rules of a user request
+func ToDomainRequest(domain model.Domain) DomainRequest {
+	return DomainRequest{
+		FQDN:        domain.FQDN,
+		Nameservers: toNameserversRequest(domain.Nameservers),
+		DSSet:       toDSSetRequest(domain.DSSet),
+		Owners:      toOwnersRequest(domain.Owners),
+	}
+}
+
 // Domain object from the protocol used to determinate what the user can see. The last
 // modified field is not here because it is sent in HTTP header field as it is with
 // revision (ETag)
@@ -112,6 +141,7 @@ type DomainResponse struct {
 	Nameservers []NameserverResponse `json:"nameservers,omitempty"` // Nameservers that asnwer with authority for this domain
 	DSSet       []DSResponse         `json:"dsset,omitempty"`       // Records for the DNS tree chain of trust
 	Owners      []OwnerResponse      `json:"owners,omitempty"`      // E-mails that will be alerted on any problem
+	CreatedBy   string               `json:"createdBy,omitempty"`   // Principal that created the domain
 	Links       []Link               `json:"links,omitempty"`       // Links to manipulate object
 }
 
@@ -150,6 +180,7 @@ func ToDomainResponse(domain model.Domain, persisted bool) DomainResponse {
 		Nameservers: toNameserversResponse(domain.Nameservers),
 		DSSet:       toDSSetResponse(domain.DSSet),
 		Owners:      toOwnersResponse(domain.Owners),
+		CreatedBy:   domain.CreatedBy,
 		Links:       links,
 	}
 }
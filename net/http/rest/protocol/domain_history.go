@@ -0,0 +1,161 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the REST protocol
+package protocol
+
+import (
+	"fmt"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/model"
+	"time"
+)
+
+// DomainHistoryResponse store the status timeline of a domain with pagination support
+type DomainHistoryResponse struct {
+	Page          int                      `json:"page"`                // Current page selected
+	PageSize      int                      `json:"pageSize"`            // Number of snapshots in a page
+	NumberOfPages int                      `json:"numberOfPages"`       // Total number of pages for the result set
+	NumberOfItems int                      `json:"numberOfItems"`       // Total number of snapshots in the result set
+	Snapshots     []DomainSnapshotResponse `json:"snapshots,omitempty"` // List of snapshots for the current page
+	Links         []Link                   `json:"links,omitempty"`     // Links for pagination managment
+}
+
+// DomainSnapshotResponse represents the result of a scan for the domain. The status was
+// converted to text format for easy interpretation
+type DomainSnapshotResponse struct {
+	ScanStartedAt PreciseTime                  `json:"scanStartedAt"`         // Start date of the scan that checked the domain
+	CheckedAt     time.Time                    `json:"checkedAt,omitempty"`   // Date that the domain was checked
+	Nameservers   []NameserverSnapshotResponse `json:"nameservers,omitempty"` // Result of the nameservers' checks
+	DSSet         []DSSnapshotResponse         `json:"dsset,omitempty"`       // Result of the DS records' checks
+	Links         []Link                       `json:"links,omitempty"`       // Link to the scan that checked the domain
+}
+
+// NameserverSnapshotResponse represents the result of a nameserver check in a scan
+type NameserverSnapshotResponse struct {
+	Host       string `json:"host,omitempty"`       // Nameserver's name
+	Status     string `json:"status,omitempty"`     // Result of the configuration check
+	StatusIPv4 string `json:"statusIPv4,omitempty"` // Result of the configuration check over IPv4
+	StatusIPv6 string `json:"statusIPv6,omitempty"` // Result of the configuration check over IPv6
+}
+
+// DSSnapshotResponse represents the result of a DS record check in a scan
+type DSSnapshotResponse struct {
+	Keytag    uint16    `json:"keytag"`              // DNSKEY's identification number
+	Status    string    `json:"status,omitempty"`    // Result of the DNSSEC configuration check
+	ExpiresAt time.Time `json:"expiresAt,omitempty"` // DNSKEY's signature expiration date found in the check
+}
+
+// Convert the history of a domain into protocol format with pagination support
+func ToDomainHistoryResponse(fqdn string, snapshots []model.DomainSnapshot,
+	pagination dao.DomainSnapshotDAOPagination) DomainHistoryResponse {
+
+	var snapshotsResponse []DomainSnapshotResponse
+	for _, snapshot := range snapshots {
+		snapshotsResponse = append(snapshotsResponse, toDomainSnapshotResponse(snapshot))
+	}
+
+	var orderBy string
+	for _, sort := range pagination.OrderBy {
+		if len(orderBy) > 0 {
+			orderBy += "@"
+		}
+
+		orderBy += fmt.Sprintf("%s:%s",
+			dao.DomainSnapshotDAOOrderByFieldToString(sort.Field),
+			dao.DAOOrderByDirectionToString(sort.Direction),
+		)
+	}
+
+	// Add pagination managment links to the response. The URI is hard coded, I didn't have
+	// any idea on how can we do this dynamically yet. We cannot get the URI from the
+	// handler because we are going to have a cross-reference problem
+	links := []Link{
+		{
+			Types: []LinkType{LinkTypeUp},
+			HRef:  fmt.Sprintf("/domain/%s", fqdn),
+		},
+	}
+
+	// Only add fast backward if we aren't in the first page
+	if pagination.Page > 1 {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypeFirst},
+			HRef: fmt.Sprintf("/domain/%s/history?pagesize=%d&page=%d&orderby=%s",
+				fqdn, pagination.PageSize, 1, orderBy),
+		})
+	}
+
+	// Only add previous if theres a previous page
+	if pagination.Page-1 >= 1 {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypePrev},
+			HRef: fmt.Sprintf("/domain/%s/history?pagesize=%d&page=%d&orderby=%s",
+				fqdn, pagination.PageSize, pagination.Page-1, orderBy),
+		})
+	}
+
+	// Only add next if there's a next page
+	if pagination.Page+1 <= pagination.NumberOfPages {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypeNext},
+			HRef: fmt.Sprintf("/domain/%s/history?pagesize=%d&page=%d&orderby=%s",
+				fqdn, pagination.PageSize, pagination.Page+1, orderBy),
+		})
+	}
+
+	// Only add the fast forward if we aren't on the last page
+	if pagination.Page < pagination.NumberOfPages {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypeLast},
+			HRef: fmt.Sprintf("/domain/%s/history?pagesize=%d&page=%d&orderby=%s",
+				fqdn, pagination.PageSize, pagination.NumberOfPages, orderBy),
+		})
+	}
+
+	return DomainHistoryResponse{
+		Page:          pagination.Page,
+		PageSize:      pagination.PageSize,
+		NumberOfPages: pagination.NumberOfPages,
+		NumberOfItems: pagination.NumberOfItems,
+		Snapshots:     snapshotsResponse,
+		Links:         links,
+	}
+}
+
+// Convert a domain snapshot into a format easy to interpret by the user, with a link to
+// the scan that produced it
+func toDomainSnapshotResponse(snapshot model.DomainSnapshot) DomainSnapshotResponse {
+	var nameservers []NameserverSnapshotResponse
+	for _, nameserver := range snapshot.Nameservers {
+		nameservers = append(nameservers, NameserverSnapshotResponse{
+			Host:       nameserver.Host,
+			Status:     model.NameserverStatusToString(nameserver.Status),
+			StatusIPv4: model.NameserverStatusToString(nameserver.StatusIPv4),
+			StatusIPv6: model.NameserverStatusToString(nameserver.StatusIPv6),
+		})
+	}
+
+	var dsSet []DSSnapshotResponse
+	for _, ds := range snapshot.DSSet {
+		dsSet = append(dsSet, DSSnapshotResponse{
+			Keytag:    ds.Keytag,
+			Status:    model.DSStatusToString(ds.Status),
+			ExpiresAt: ds.ExpiresAt,
+		})
+	}
+
+	return DomainSnapshotResponse{
+		ScanStartedAt: PreciseTime{snapshot.ScanStartedAt},
+		CheckedAt:     snapshot.CheckedAt,
+		Nameservers:   nameservers,
+		DSSet:         dsSet,
+		Links: []Link{
+			{
+				Types: []LinkType{LinkTypeRelated},
+				HRef:  fmt.Sprintf("/scan/%s", snapshot.ScanStartedAt.Format(time.RFC3339Nano)),
+			},
+		},
+	}
+}
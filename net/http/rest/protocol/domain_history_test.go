@@ -0,0 +1,105 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the REST protocol
+package protocol
+
+import (
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/model"
+	"testing"
+	"time"
+)
+
+func TestToDomainHistoryResponse(t *testing.T) {
+	scanStartedAt := time.Now().Add(-1 * time.Hour)
+
+	snapshots := []model.DomainSnapshot{
+		{
+			FQDN:          "example.com.br.",
+			ScanStartedAt: scanStartedAt,
+			CheckedAt:     scanStartedAt.Add(time.Minute),
+			Nameservers: []model.NameserverSnapshot{
+				{
+					Host:       "ns1.example.com.br.",
+					Status:     model.NameserverStatusTimeout,
+					StatusIPv4: model.NameserverStatusTimeout,
+				},
+			},
+			DSSet: []model.DSSnapshot{
+				{
+					Keytag: 1234,
+					Status: model.DSStatusOK,
+				},
+			},
+		},
+		{
+			FQDN:          "example.com.br.",
+			ScanStartedAt: scanStartedAt.Add(-24 * time.Hour),
+		},
+	}
+
+	pagination := dao.DomainSnapshotDAOPagination{
+		PageSize: 10,
+		Page:     1,
+		OrderBy: []dao.DomainSnapshotDAOSort{
+			{
+				Field:     dao.DomainSnapshotDAOOrderByFieldCheckedAt,
+				Direction: dao.DAOOrderByDirectionDescending,
+			},
+		},
+		NumberOfItems: len(snapshots),
+		NumberOfPages: 1,
+	}
+
+	historyResponse := ToDomainHistoryResponse("example.com.br.", snapshots, pagination)
+
+	if len(historyResponse.Snapshots) != len(snapshots) {
+		t.Fatal("Not converting domain snapshots properly")
+	}
+
+	if historyResponse.PageSize != 10 || historyResponse.Page != 1 ||
+		historyResponse.NumberOfItems != len(snapshots) || historyResponse.NumberOfPages != 1 {
+
+		t.Error("Pagination not storing the information properly")
+	}
+
+	// We should show only the domain link when there's only one page
+	if len(historyResponse.Links) != 1 || historyResponse.Links[0].HRef != "/domain/example.com.br." {
+		t.Error("Response not adding the necessary links when there is only one page")
+	}
+
+	snapshotResponse := historyResponse.Snapshots[0]
+	if len(snapshotResponse.Nameservers) != 1 ||
+		snapshotResponse.Nameservers[0].Status != "TIMEOUT" ||
+		snapshotResponse.Nameservers[0].StatusIPv4 != "TIMEOUT" ||
+		snapshotResponse.Nameservers[0].StatusIPv6 != "NOTCHECKED" {
+
+		t.Error("Not converting the nameservers' results properly")
+	}
+
+	if len(snapshotResponse.DSSet) != 1 ||
+		snapshotResponse.DSSet[0].Keytag != 1234 ||
+		snapshotResponse.DSSet[0].Status != "OK" {
+
+		t.Error("Not converting the DS records' results properly")
+	}
+
+	if len(snapshotResponse.Links) != 1 ||
+		snapshotResponse.Links[0].HRef != "/scan/"+scanStartedAt.Format(time.RFC3339Nano) {
+
+		t.Error("Not linking the snapshot to the scan")
+	}
+
+	pagination.PageSize = 1
+	pagination.Page = 2
+	pagination.NumberOfPages = 3
+
+	historyResponse = ToDomainHistoryResponse("example.com.br.", snapshots, pagination)
+
+	// Show all actions when navigating in the middle of the pagination
+	if len(historyResponse.Links) != 5 {
+		t.Error("Response not adding the necessary links when we are navigating")
+	}
+}
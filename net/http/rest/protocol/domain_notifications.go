@@ -0,0 +1,124 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the REST protocol
+package protocol
+
+import (
+	"fmt"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/model"
+	"time"
+)
+
+// DomainNotificationsResponse store the alerts sent to the owners of a domain with
+// pagination support
+type DomainNotificationsResponse struct {
+	Page          int                    `json:"page"`                    // Current page selected
+	PageSize      int                    `json:"pageSize"`                // Number of notifications in a page
+	NumberOfPages int                    `json:"numberOfPages"`           // Total number of pages for the result set
+	NumberOfItems int                    `json:"numberOfItems"`           // Total number of notifications in the result set
+	Notifications []NotificationResponse `json:"notifications,omitempty"` // List of notifications for the current page
+	Links         []Link                 `json:"links,omitempty"`         // Links for pagination managment
+}
+
+// NotificationResponse represents an alert sent to a group of recipients of the domain
+type NotificationResponse struct {
+	Channel    string    `json:"channel"`              // Channel used to send the alert (smtp, webhook)
+	Recipients []string  `json:"recipients,omitempty"` // E-mails or URLs that received the alert
+	Language   string    `json:"language,omitempty"`   // Language of the template used in the alert
+	Problems   []string  `json:"problems,omitempty"`   // Problems of the domain in the alert
+	Recovery   bool      `json:"recovery,omitempty"`   // Flag that indicates if the problems were solved
+	Delivered  bool      `json:"delivered"`            // Flag that indicates if the alert was delivered
+	Postponed  bool      `json:"postponed,omitempty"`  // Flag that indicates if the alert waits for the end of the quiet hours
+	Error      string    `json:"error,omitempty"`      // Reason of the delivery failure
+	SentAt     time.Time `json:"sentAt"`               // Date and time of the alert
+}
+
+// Convert the notifications of a domain into protocol format with pagination support
+func ToDomainNotificationsResponse(fqdn string, notifications []model.Notification,
+	pagination dao.NotificationDAOPagination) DomainNotificationsResponse {
+
+	var notificationsResponse []NotificationResponse
+	for _, notification := range notifications {
+		notificationsResponse = append(notificationsResponse, NotificationResponse{
+			Channel:    notification.Channel,
+			Recipients: notification.Recipients,
+			Language:   notification.Language,
+			Problems:   notification.Problems,
+			Recovery:   notification.Recovery,
+			Delivered:  notification.Delivered,
+			Postponed:  notification.Postponed,
+			Error:      notification.Error,
+			SentAt:     notification.SentAt,
+		})
+	}
+
+	var orderBy string
+	for _, sort := range pagination.OrderBy {
+		if len(orderBy) > 0 {
+			orderBy += "@"
+		}
+
+		orderBy += fmt.Sprintf("%s:%s",
+			dao.NotificationDAOOrderByFieldToString(sort.Field),
+			dao.DAOOrderByDirectionToString(sort.Direction),
+		)
+	}
+
+	// Add pagination managment links to the response. The URI is hard coded, I didn't have
+	// any idea on how can we do this dynamically yet. We cannot get the URI from the
+	// handler because we are going to have a cross-reference problem
+	links := []Link{
+		{
+			Types: []LinkType{LinkTypeUp},
+			HRef:  fmt.Sprintf("/domain/%s", fqdn),
+		},
+	}
+
+	// Only add fast backward if we aren't in the first page
+	if pagination.Page > 1 {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypeFirst},
+			HRef: fmt.Sprintf("/domain/%s/notifications?pagesize=%d&page=%d&orderby=%s",
+				fqdn, pagination.PageSize, 1, orderBy),
+		})
+	}
+
+	// Only add previous if theres a previous page
+	if pagination.Page-1 >= 1 {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypePrev},
+			HRef: fmt.Sprintf("/domain/%s/notifications?pagesize=%d&page=%d&orderby=%s",
+				fqdn, pagination.PageSize, pagination.Page-1, orderBy),
+		})
+	}
+
+	// Only add next if there's a next page
+	if pagination.Page+1 <= pagination.NumberOfPages {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypeNext},
+			HRef: fmt.Sprintf("/domain/%s/notifications?pagesize=%d&page=%d&orderby=%s",
+				fqdn, pagination.PageSize, pagination.Page+1, orderBy),
+		})
+	}
+
+	// Only add the fast forward if we aren't on the last page
+	if pagination.Page < pagination.NumberOfPages {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypeLast},
+			HRef: fmt.Sprintf("/domain/%s/notifications?pagesize=%d&page=%d&orderby=%s",
+				fqdn, pagination.PageSize, pagination.NumberOfPages, orderBy),
+		})
+	}
+
+	return DomainNotificationsResponse{
+		Page:          pagination.Page,
+		PageSize:      pagination.PageSize,
+		NumberOfPages: pagination.NumberOfPages,
+		NumberOfItems: pagination.NumberOfItems,
+		Notifications: notificationsResponse,
+		Links:         links,
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the REST protocol
+package protocol
+
+import (
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/model"
+	"testing"
+	"time"
+)
+
+func TestToDomainNotificationsResponse(t *testing.T) {
+	sentAt := time.Now().Add(-1 * time.Hour)
+
+	notifications := []model.Notification{
+		{
+			FQDN:       "example.com.br.",
+			Channel:    "smtp",
+			Recipients: []string{"admin@example.com.br"},
+			Language:   "en-US",
+			Problems:   []string{"nameserver ns1.example.com.br. TIMEOUT"},
+			Delivered:  true,
+			SentAt:     sentAt,
+		},
+		{
+			FQDN:       "example.com.br.",
+			Channel:    "webhook",
+			Recipients: []string{"https://example.com.br/alerts"},
+			Problems:   []string{"nameserver ns1.example.com.br. TIMEOUT"},
+			Recovery:   true,
+			Error:      "Webhook answered with HTTP status 500",
+			SentAt:     sentAt,
+		},
+	}
+
+	pagination := dao.NotificationDAOPagination{
+		PageSize: 10,
+		Page:     1,
+		OrderBy: []dao.NotificationDAOSort{
+			{
+				Field:     dao.NotificationDAOOrderByFieldSentAt,
+				Direction: dao.DAOOrderByDirectionDescending,
+			},
+		},
+		NumberOfItems: len(notifications),
+		NumberOfPages: 1,
+	}
+
+	notificationsResponse := ToDomainNotificationsResponse("example.com.br.", notifications, pagination)
+
+	if len(notificationsResponse.Notifications) != len(notifications) {
+		t.Fatal("Not converting notifications properly")
+	}
+
+	if notificationsResponse.PageSize != 10 || notificationsResponse.Page != 1 ||
+		notificationsResponse.NumberOfItems != len(notifications) ||
+		notificationsResponse.NumberOfPages != 1 {
+
+		t.Error("Pagination not storing the information properly")
+	}
+
+	// We should show only the domain link when there's only one page
+	if len(notificationsResponse.Links) != 1 ||
+		notificationsResponse.Links[0].HRef != "/domain/example.com.br." {
+
+		t.Error("Response not adding the necessary links when there is only one page")
+	}
+
+	notificationResponse := notificationsResponse.Notifications[0]
+	if notificationResponse.Channel != "smtp" ||
+		len(notificationResponse.Recipients) != 1 ||
+		notificationResponse.Language != "en-US" ||
+		len(notificationResponse.Problems) != 1 ||
+		!notificationResponse.Delivered ||
+		!notificationResponse.SentAt.Equal(sentAt) {
+
+		t.Error("Not converting the notification properly")
+	}
+
+	if !notificationsResponse.Notifications[1].Recovery {
+		t.Error("Not converting the recovery flag properly")
+	}
+
+	if notificationsResponse.Notifications[1].Delivered ||
+		notificationsResponse.Notifications[1].Error != "Webhook answered with HTTP status 500" {
+
+		t.Error("Not converting the delivery failure properly")
+	}
+
+	pagination.PageSize = 1
+	pagination.Page = 2
+	pagination.NumberOfPages = 3
+
+	notificationsResponse = ToDomainNotificationsResponse("example.com.br.", notifications, pagination)
+
+	// Show all actions when navigating in the middle of the pagination
+	if len(notificationsResponse.Links) != 5 {
+		t.Error("Response not adding the necessary links when we are navigating")
+	}
+}
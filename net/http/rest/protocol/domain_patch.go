@@ -0,0 +1,481 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the REST protocol
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// List of possible errors that can occur when patching a domain. Other erros can also
+// occurs from low level layers
+var (
+	// Error returned when the patch isn't a JSON merge patch object (RFC 7396) or a list of
+	// JSON patch operations (RFC 6902), or when the patched domain has invalid types
+	ErrInvalidPatch = errors.New("Invalid patch document")
+
+	// Error returned when the patch tries to change something that isn't the nameservers,
+	// DS set, DNSKEYs or owners of the domain, or when the path doesn't exist
+	ErrInvalidPatchPath = errors.New("Invalid patch path")
+
+	// Error returned when a test operation of a JSON patch (RFC 6902) fails
+	ErrPatchTestFailed = errors.New("Patch test operation failed")
+
+	// Error returned when the patch document doesn't match the patch type, like a list of
+	// operations sent as a JSON merge patch
+	ErrPatchTypeMismatch = errors.New("Patch document doesn't match the patch type")
+)
+
+// List of possible patch formats, identified by the content type of the request
+const (
+	PatchTypeMerge PatchType = 0 // JSON merge patch object (RFC 7396)
+	PatchTypeJSON  PatchType = 1 // List of JSON patch operations (RFC 6902)
+)
+
+// PatchType defines how the patch document is applied on the domain
+type PatchType int
+
+// List of domain fields that can be patched. They are the same fields that the user can
+// update with a domain request
+var patchableDomainFields = []string{"nameservers", "dsset", "dnskeys", "owners"}
+
+// PatchOperation is an operation of a JSON patch (RFC 6902). The value is kept in the
+// JSON format to detect when it's null or missing
+type PatchOperation struct {
+	Op    string          `json:"op"`             // Operation (add, remove, replace, move, copy or test)
+	Path  string          `json:"path"`           // JSON pointer (RFC 6901) of the changed value
+	From  string          `json:"from,omitempty"` // JSON pointer of the source value for move and copy
+	Value json.RawMessage `json:"value"`          // Value used in add, replace and test
+}
+
+// PatchDomainRequest applies a patch document sent by the user on a domain request, that
+// should be built from the current domain. The patch type defines if the document is a
+// JSON merge patch object (RFC 7396) or a list of JSON patch operations (RFC 6902), and
+// a document of the other format is rejected. Only the fields of the domain request can
+// be patched, and the patched request must be merged with the domain as any other update
+func PatchDomainRequest(domainRequest DomainRequest, patchType PatchType,
+	patch []byte) (DomainRequest, error) {
+
+	document, err := toPatchDocument(domainRequest)
+	if err != nil {
+		return domainRequest, err
+	}
+
+	patch = bytes.TrimSpace(patch)
+	if len(patch) == 0 {
+		return domainRequest, ErrInvalidPatch
+	}
+
+	// A JSON object can only be a merge patch and a JSON array can only be a list of
+	// operations, so we detect when the document was sent with the wrong type
+	if (patch[0] == '{' && patchType != PatchTypeMerge) ||
+		(patch[0] == '[' && patchType != PatchTypeJSON) {
+		return domainRequest, ErrPatchTypeMismatch
+	}
+
+	switch patchType {
+	case PatchTypeMerge:
+		var mergePatch map[string]interface{}
+		if err := json.Unmarshal(patch, &mergePatch); err != nil {
+			return domainRequest, ErrInvalidPatch
+		}
+
+		for field := range mergePatch {
+			if !isPatchableDomainField(field) {
+				return domainRequest, ErrInvalidPatchPath
+			}
+		}
+
+		document = applyMergePatch(document, mergePatch)
+
+	case PatchTypeJSON:
+		var operations []PatchOperation
+		if err := json.Unmarshal(patch, &operations); err != nil {
+			return domainRequest, ErrInvalidPatch
+		}
+
+		for _, operation := range operations {
+			if document, err = applyPatchOperation(document, operation); err != nil {
+				return domainRequest, err
+			}
+		}
+
+	default:
+		return domainRequest, ErrInvalidPatch
+	}
+
+	body, err := json.Marshal(document)
+	if err != nil {
+		return domainRequest, err
+	}
+
+	// The FQDN isn't part of the JSON representation, it's always defined by the URI
+	patchedRequest := DomainRequest{
+		FQDN: domainRequest.FQDN,
+	}
+
+	if err := json.Unmarshal(body, &patchedRequest); err != nil {
+		return domainRequest, ErrInvalidPatch
+	}
+
+	return patchedRequest, nil
+}
+
+// Convert the domain request into a generic JSON document, that can be changed by the
+// patch. Empty lists are added, so that the user can add items to them using the JSON
+// patch operations
+func toPatchDocument(domainRequest DomainRequest) (interface{}, error) {
+	body, err := json.Marshal(domainRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	var document map[string]interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, err
+	}
+
+	for _, field := range patchableDomainFields {
+		if _, ok := document[field]; !ok {
+			document[field] = []interface{}{}
+		}
+	}
+
+	return document, nil
+}
+
+// Check if the field is one of the domain request fields that can be changed by a patch
+func isPatchableDomainField(field string) bool {
+	for _, patchableField := range patchableDomainFields {
+		if field == patchableField {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Apply the JSON merge patch (RFC 7396) in the target. Objects are merged recursively and
+// null values remove the member from the target, any other value replaces the target
+func applyMergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = applyMergePatch(targetObject[key], value)
+		}
+	}
+
+	return targetObject
+}
+
+// Apply one JSON patch operation (RFC 6902) in the document, returning the changed
+// document
+func applyPatchOperation(document interface{}, operation PatchOperation) (interface{}, error) {
+	path, err := toPatchPath(operation.Path)
+	if err != nil {
+		return document, err
+	}
+
+	op := strings.ToLower(strings.TrimSpace(operation.Op))
+
+	switch op {
+	case "add":
+		value, err := operation.value()
+		if err != nil {
+			return document, err
+		}
+
+		return addPatchValue(document, path, value)
+
+	case "remove":
+		document, _, err = removePatchValue(document, path)
+		return document, err
+
+	case "replace":
+		value, err := operation.value()
+		if err != nil {
+			return document, err
+		}
+
+		if document, _, err = removePatchValue(document, path); err != nil {
+			return document, err
+		}
+
+		return addPatchValue(document, path, value)
+
+	case "move", "copy":
+		from, err := toPatchPath(operation.From)
+		if err != nil {
+			return document, err
+		}
+
+		var value interface{}
+
+		if op == "move" {
+			// A value cannot be moved into one of its children
+			if len(from) < len(path) && reflect.DeepEqual(from, path[:len(from)]) {
+				return document, ErrInvalidPatchPath
+			}
+
+			if document, value, err = removePatchValue(document, from); err != nil {
+				return document, err
+			}
+
+		} else {
+			if value, err = findPatchValue(document, from); err != nil {
+				return document, err
+			}
+
+			// The copied value must not share objects or arrays with the original one
+			if value, err = copyPatchValue(value); err != nil {
+				return document, err
+			}
+		}
+
+		return addPatchValue(document, path, value)
+
+	case "test":
+		value, err := operation.value()
+		if err != nil {
+			return document, err
+		}
+
+		current, err := findPatchValue(document, path)
+		if err != nil {
+			return document, err
+		}
+
+		if !reflect.DeepEqual(current, value) {
+			return document, ErrPatchTestFailed
+		}
+
+		return document, nil
+	}
+
+	return document, ErrInvalidPatch
+}
+
+// Decode the value of the operation into a generic JSON value. The value is mandatory
+// for the operations that use it, but it can be null
+func (o PatchOperation) value() (interface{}, error) {
+	if len(o.Value) == 0 {
+		return nil, ErrInvalidPatch
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(o.Value, &value); err != nil {
+		return nil, ErrInvalidPatch
+	}
+
+	return value, nil
+}
+
+// Convert the JSON pointer (RFC 6901) into the list of reference tokens. The pointer must
+// reference one of the patchable fields of the domain or something inside them
+func toPatchPath(pointer string) ([]string, error) {
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, ErrInvalidPatchPath
+	}
+
+	path := strings.Split(pointer[1:], "/")
+	for i, token := range path {
+		token = strings.Replace(token, "~1", "/", -1)
+		token = strings.Replace(token, "~0", "~", -1)
+		path[i] = token
+	}
+
+	if !isPatchableDomainField(path[0]) {
+		return nil, ErrInvalidPatchPath
+	}
+
+	return path, nil
+}
+
+// Convert the reference token into an array index. When the end of the array is
+// allowed, the "-" token references the position after the last element
+func toPatchIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+
+	index, err := strconv.Atoi(token)
+
+	// Indexes with leading zeros or signs are not allowed
+	if err != nil || index < 0 || strconv.Itoa(index) != token {
+		return 0, ErrInvalidPatchPath
+	}
+
+	if index > length || (!allowEnd && index == length) {
+		return 0, ErrInvalidPatchPath
+	}
+
+	return index, nil
+}
+
+// Retrieve the value referenced by the path
+func findPatchValue(node interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch container := node.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, ErrInvalidPatchPath
+			}
+			node = value
+
+		case []interface{}:
+			index, err := toPatchIndex(token, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			node = container[index]
+
+		default:
+			return nil, ErrInvalidPatchPath
+		}
+	}
+
+	return node, nil
+}
+
+// Add the value in the position referenced by the path. Object members are created or
+// replaced and array elements are inserted, returning the changed node
+func addPatchValue(node interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	token := path[0]
+
+	switch container := node.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			container[token] = value
+			return container, nil
+		}
+
+		child, ok := container[token]
+		if !ok {
+			return node, ErrInvalidPatchPath
+		}
+
+		child, err := addPatchValue(child, path[1:], value)
+		if err != nil {
+			return node, err
+		}
+
+		container[token] = child
+		return container, nil
+
+	case []interface{}:
+		if len(path) == 1 {
+			index, err := toPatchIndex(token, len(container), true)
+			if err != nil {
+				return node, err
+			}
+
+			container = append(container, nil)
+			copy(container[index+1:], container[index:])
+			container[index] = value
+			return container, nil
+		}
+
+		index, err := toPatchIndex(token, len(container), false)
+		if err != nil {
+			return node, err
+		}
+
+		child, err := addPatchValue(container[index], path[1:], value)
+		if err != nil {
+			return node, err
+		}
+
+		container[index] = child
+		return container, nil
+	}
+
+	return node, ErrInvalidPatchPath
+}
+
+// Remove the value referenced by the path, returning the changed node and the removed
+// value
+func removePatchValue(node interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return node, nil, ErrInvalidPatchPath
+	}
+
+	token := path[0]
+
+	switch container := node.(type) {
+	case map[string]interface{}:
+		child, ok := container[token]
+		if !ok {
+			return node, nil, ErrInvalidPatchPath
+		}
+
+		if len(path) == 1 {
+			delete(container, token)
+			return container, child, nil
+		}
+
+		child, removed, err := removePatchValue(child, path[1:])
+		if err != nil {
+			return node, nil, err
+		}
+
+		container[token] = child
+		return container, removed, nil
+
+	case []interface{}:
+		index, err := toPatchIndex(token, len(container), false)
+		if err != nil {
+			return node, nil, err
+		}
+
+		if len(path) == 1 {
+			removed := container[index]
+			container = append(container[:index], container[index+1:]...)
+			return container, removed, nil
+		}
+
+		child, removed, err := removePatchValue(container[index], path[1:])
+		if err != nil {
+			return node, nil, err
+		}
+
+		container[index] = child
+		return container, removed, nil
+	}
+
+	return node, nil, ErrInvalidPatchPath
+}
+
+// Duplicate a generic JSON value
+func copyPatchValue(value interface{}) (interface{}, error) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var duplicated interface{}
+	err = json.Unmarshal(body, &duplicated)
+	return duplicated, err
+}
@@ -0,0 +1,143 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the REST protocol
+package protocol
+
+import (
+	"testing"
+)
+
+func TestPatchDomainRequestMergePatch(t *testing.T) {
+	domainRequest := DomainRequest{
+		FQDN: "example.com.br.",
+		Nameservers: []NameserverRequest{
+			{Host: "ns1.example.com.br."},
+		},
+		DSSet: []DSRequest{
+			{Keytag: 41674, Algorithm: 5, DigestType: 1, Digest: "eaa0978f38879db70a53f9ff1acf21d046a98b5c"},
+		},
+		Owners: []OwnerRequest{
+			{Email: "example@example.com.br", Language: "pt-BR"},
+		},
+	}
+
+	patched, err := PatchDomainRequest(domainRequest, PatchTypeMerge, []byte(`{
+		"owners": [{"email": "other@example.com.br", "language": "en-US"}],
+		"dsset": null
+	}`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if patched.FQDN != "example.com.br." || len(patched.Nameservers) != 1 ||
+		len(patched.DSSet) != 0 || len(patched.Owners) != 1 ||
+		patched.Owners[0].Email != "other@example.com.br" {
+
+		t.Error("Not applying the JSON merge patch properly")
+	}
+
+	if _, err := PatchDomainRequest(domainRequest, PatchTypeMerge, []byte(`{"fqdn": "other.com.br."}`)); err != ErrInvalidPatchPath {
+		t.Error("Allowing to patch a field that isn't in the domain request")
+	}
+
+	if _, err := PatchDomainRequest(domainRequest, PatchTypeMerge, []byte(`{"nameservers": "ns1"}`)); err != ErrInvalidPatch {
+		t.Error("Accepting a patch that produces an invalid domain request")
+	}
+
+	if _, err := PatchDomainRequest(domainRequest, PatchTypeMerge, []byte(`[{"op": "remove", "path": "/dsset"}]`)); err != ErrPatchTypeMismatch {
+		t.Error("Accepting a list of JSON patch operations as a JSON merge patch")
+	}
+
+	for _, patch := range []string{"", "  ", `"text"`, `{"owners": [}`} {
+		if _, err := PatchDomainRequest(domainRequest, PatchTypeMerge, []byte(patch)); err != ErrInvalidPatch {
+			t.Errorf("Accepting an invalid patch document '%s'", patch)
+		}
+	}
+}
+
+func TestPatchDomainRequestJSONPatch(t *testing.T) {
+	domainRequest := DomainRequest{
+		FQDN: "example.com.br.",
+		Nameservers: []NameserverRequest{
+			{Host: "ns1.example.com.br."},
+			{Host: "ns2.example.com.br."},
+		},
+		Owners: []OwnerRequest{
+			{Email: "example@example.com.br", Language: "pt-BR"},
+		},
+	}
+
+	patched, err := PatchDomainRequest(domainRequest, PatchTypeJSON, []byte(`[
+		{"op": "test", "path": "/nameservers/0/host", "value": "ns1.example.com.br."},
+		{"op": "add", "path": "/dsset/-", "value": {"keytag": 41674, "algorithm": 5, "digestType": 1, "digest": "eaa0978f38879db70a53f9ff1acf21d046a98b5c"}},
+		{"op": "replace", "path": "/nameservers/1/host", "value": "ns3.example.com.br."},
+		{"op": "add", "path": "/nameservers/0/ipv4", "value": "127.0.0.1"},
+		{"op": "move", "from": "/nameservers/0", "path": "/nameservers/-"},
+		{"op": "copy", "from": "/owners/0", "path": "/owners/0"},
+		{"op": "replace", "path": "/owners/1/email", "value": "other@example.com.br"},
+		{"op": "remove", "path": "/owners/0/language"}
+	]`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(patched.Nameservers) != 2 ||
+		patched.Nameservers[0].Host != "ns3.example.com.br." ||
+		patched.Nameservers[1].Host != "ns1.example.com.br." ||
+		patched.Nameservers[1].IPv4 != "127.0.0.1" {
+
+		t.Error("Not patching the nameservers properly")
+	}
+
+	if len(patched.DSSet) != 1 || patched.DSSet[0].Keytag != 41674 {
+		t.Error("Not patching the DS set properly")
+	}
+
+	if len(patched.Owners) != 2 ||
+		patched.Owners[0].Email != "example@example.com.br" ||
+		len(patched.Owners[0].Language) > 0 ||
+		patched.Owners[1].Email != "other@example.com.br" ||
+		patched.Owners[1].Language != "pt-BR" {
+
+		t.Error("Not patching the owners properly")
+	}
+
+	data := []struct {
+		Patch         string
+		ExpectedError error
+	}{
+		{Patch: `[{"op": "test", "path": "/nameservers/0/host", "value": "ns9.example.com.br."}]`, ExpectedError: ErrPatchTestFailed},
+		{Patch: `[{"op": "replace", "path": "/fqdn", "value": "other.com.br."}]`, ExpectedError: ErrInvalidPatchPath},
+		{Patch: `[{"op": "add", "path": "", "value": {}}]`, ExpectedError: ErrInvalidPatchPath},
+		{Patch: `[{"op": "remove", "path": "/nameservers/2"}]`, ExpectedError: ErrInvalidPatchPath},
+		{Patch: `[{"op": "remove", "path": "/nameservers/01"}]`, ExpectedError: ErrInvalidPatchPath},
+		{Patch: `[{"op": "remove", "path": "/nameservers/-"}]`, ExpectedError: ErrInvalidPatchPath},
+		{Patch: `[{"op": "add", "path": "/nameservers/5", "value": {}}]`, ExpectedError: ErrInvalidPatchPath},
+		{Patch: `[{"op": "move", "from": "/owners", "path": "/owners/0/email"}]`, ExpectedError: ErrInvalidPatchPath},
+		{Patch: `[{"op": "add", "path": "/dsset/-"}]`, ExpectedError: ErrInvalidPatch},
+		{Patch: `[{"op": "xxx", "path": "/dsset"}]`, ExpectedError: ErrInvalidPatch},
+		{Patch: `[{"op": "add", "path": "/dsset/-", "value": {"keytag": "abc"}}]`, ExpectedError: ErrInvalidPatch},
+		{Patch: `{"dsset": null}`, ExpectedError: ErrPatchTypeMismatch},
+		{Patch: `"text"`, ExpectedError: ErrInvalidPatch},
+	}
+
+	for _, item := range data {
+		if _, err := PatchDomainRequest(domainRequest, PatchTypeJSON, []byte(item.Patch)); err != item.ExpectedError {
+			t.Errorf("Unexpected error for patch %s. Expected '%v' and got '%v'",
+				item.Patch, item.ExpectedError, err)
+		}
+	}
+
+	// Null values are allowed in JSON patch operations
+	patched, err = PatchDomainRequest(domainRequest, PatchTypeJSON, []byte(`[
+		{"op": "replace", "path": "/owners", "value": null}
+	]`))
+
+	if err != nil || len(patched.Owners) > 0 {
+		t.Error("Not accepting a null value in the operation")
+	}
+}
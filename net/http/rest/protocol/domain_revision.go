@@ -0,0 +1,139 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the REST protocol
+package protocol
+
+import (
+	"fmt"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/model"
+)
+
+// DomainRevisionsResponse store the configuration changes of a domain with pagination
+// support
+type DomainRevisionsResponse struct {
+	Page          int                      `json:"page"`                // Current page selected
+	PageSize      int                      `json:"pageSize"`            // Number of revisions in a page
+	NumberOfPages int                      `json:"numberOfPages"`       // Total number of pages for the result set
+	NumberOfItems int                      `json:"numberOfItems"`       // Total number of revisions in the result set
+	Revisions     []DomainRevisionResponse `json:"revisions,omitempty"` // List of revisions for the current page
+	Links         []Link                   `json:"links,omitempty"`     // Links for pagination managment
+}
+
+// DomainRevisionResponse represents the state of the domain in a revision. When
+// retrieving a specific revision, the changes needed to move from the compared revision
+// to this one are also returned
+type DomainRevisionResponse struct {
+	Revision     int                   `json:"revision"`               // Revision number of the domain
+	SavedAt      PreciseTime           `json:"savedAt"`                // Date and time that the revision was stored
+	Domain       DomainResponse        `json:"domain"`                 // State of the domain in this revision
+	ComparedWith int                   `json:"comparedWith,omitempty"` // Revision used to detect the changes
+	Changes      []AuditChangeResponse `json:"changes,omitempty"`      // Configuration items that are different
+	Links        []Link                `json:"links,omitempty"`        // Link to the revision
+}
+
+// Convert the revisions of a domain into protocol format with pagination support
+func ToDomainRevisionsResponse(fqdn string, revisions []model.DomainRevision,
+	pagination dao.DomainRevisionDAOPagination) DomainRevisionsResponse {
+
+	var revisionsResponse []DomainRevisionResponse
+	for _, revision := range revisions {
+		revisionsResponse = append(revisionsResponse, toDomainRevisionResponse(revision))
+	}
+
+	var orderBy string
+	for _, sort := range pagination.OrderBy {
+		if len(orderBy) > 0 {
+			orderBy += "@"
+		}
+
+		orderBy += fmt.Sprintf("%s:%s",
+			dao.DomainRevisionDAOOrderByFieldToString(sort.Field),
+			dao.DAOOrderByDirectionToString(sort.Direction),
+		)
+	}
+
+	// Add pagination managment links to the response. The URI is hard coded, I didn't have
+	// any idea on how can we do this dynamically yet. We cannot get the URI from the
+	// handler because we are going to have a cross-reference problem
+	links := []Link{
+		{
+			Types: []LinkType{LinkTypeUp},
+			HRef:  fmt.Sprintf("/domain/%s", fqdn),
+		},
+	}
+
+	// Only add fast backward if we aren't in the first page
+	if pagination.Page > 1 {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypeFirst},
+			HRef: fmt.Sprintf("/domain/%s/revisions?pagesize=%d&page=%d&orderby=%s",
+				fqdn, pagination.PageSize, 1, orderBy),
+		})
+	}
+
+	// Only add previous if theres a previous page
+	if pagination.Page-1 >= 1 {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypePrev},
+			HRef: fmt.Sprintf("/domain/%s/revisions?pagesize=%d&page=%d&orderby=%s",
+				fqdn, pagination.PageSize, pagination.Page-1, orderBy),
+		})
+	}
+
+	// Only add next if there's a next page
+	if pagination.Page+1 <= pagination.NumberOfPages {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypeNext},
+			HRef: fmt.Sprintf("/domain/%s/revisions?pagesize=%d&page=%d&orderby=%s",
+				fqdn, pagination.PageSize, pagination.Page+1, orderBy),
+		})
+	}
+
+	// Only add the fast forward if we aren't on the last page
+	if pagination.Page < pagination.NumberOfPages {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypeLast},
+			HRef: fmt.Sprintf("/domain/%s/revisions?pagesize=%d&page=%d&orderby=%s",
+				fqdn, pagination.PageSize, pagination.NumberOfPages, orderBy),
+		})
+	}
+
+	return DomainRevisionsResponse{
+		Page:          pagination.Page,
+		PageSize:      pagination.PageSize,
+		NumberOfPages: pagination.NumberOfPages,
+		NumberOfItems: pagination.NumberOfItems,
+		Revisions:     revisionsResponse,
+		Links:         links,
+	}
+}
+
+// Convert a specific revision of the domain into protocol format, with the changes
+// needed to move from the compared revision to this one
+func ToDomainRevisionResponse(revision model.DomainRevision, comparedWith int,
+	changes []model.AuditChange) DomainRevisionResponse {
+
+	response := toDomainRevisionResponse(revision)
+	response.ComparedWith = comparedWith
+	response.Changes = toAuditChangesResponse(changes)
+	return response
+}
+
+// Convert a revision of the domain into a format easy to interpret by the user. The
+// domain state doesn't have links, because it's not the current state of the domain
+func toDomainRevisionResponse(revision model.DomainRevision) DomainRevisionResponse {
+	return DomainRevisionResponse{
+		Revision: revision.Revision,
+		SavedAt:  PreciseTime{revision.SavedAt},
+		Domain:   ToDomainResponse(revision.Domain, false),
+		Links: []Link{
+			{
+				Types: []LinkType{LinkTypeSelf},
+				HRef:  fmt.Sprintf("/domain/%s/revision/%d", revision.FQDN, revision.Revision),
+			},
+		},
+	}
+}
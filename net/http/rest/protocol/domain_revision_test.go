@@ -0,0 +1,82 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the REST protocol
+package protocol
+
+import (
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/model"
+	"testing"
+)
+
+func TestToDomainRevisionsResponse(t *testing.T) {
+	revisions := []model.DomainRevision{
+		model.NewDomainRevision(model.Domain{
+			FQDN:     "example.com.br.",
+			Revision: 4,
+		}),
+		model.NewDomainRevision(model.Domain{
+			FQDN:     "example.com.br.",
+			Revision: 2,
+		}),
+	}
+
+	pagination := dao.DomainRevisionDAOPagination{
+		PageSize: 2,
+		Page:     1,
+		OrderBy: []dao.DomainRevisionDAOSort{
+			{
+				Field:     dao.DomainRevisionDAOOrderByFieldRevision,
+				Direction: dao.DAOOrderByDirectionDescending,
+			},
+		},
+		NumberOfItems: 3,
+		NumberOfPages: 2,
+	}
+
+	revisionsResponse := ToDomainRevisionsResponse("example.com.br.", revisions, pagination)
+
+	if len(revisionsResponse.Revisions) != 2 {
+		t.Fatal("Not converting domain revisions properly")
+	}
+
+	if len(revisionsResponse.Links) != 3 {
+		t.Fatalf("Not adding all links. Expected 3 and got %d", len(revisionsResponse.Links))
+	}
+
+	expectedHRef := "/domain/example.com.br./revisions?pagesize=2&page=2&orderby=revision:desc"
+	if revisionsResponse.Links[1].HRef != expectedHRef {
+		t.Errorf("Wrong pagination link. Expected %s and got %s",
+			expectedHRef, revisionsResponse.Links[1].HRef)
+	}
+
+	revisionResponse := revisionsResponse.Revisions[0]
+	if revisionResponse.Revision != 4 || len(revisionResponse.Domain.Links) > 0 ||
+		len(revisionResponse.Links) != 1 ||
+		revisionResponse.Links[0].HRef != "/domain/example.com.br./revision/4" {
+
+		t.Error("Not converting the domain revision properly")
+	}
+}
+
+func TestToDomainRevisionResponse(t *testing.T) {
+	revision := model.NewDomainRevision(model.Domain{
+		FQDN:     "example.com.br.",
+		Revision: 2,
+	})
+
+	changes := []model.AuditChange{
+		{Field: "nameserver ns1.example.com.br.", Before: "ns1.example.com.br."},
+	}
+
+	revisionResponse := ToDomainRevisionResponse(revision, 4, changes)
+
+	if revisionResponse.Revision != 2 || revisionResponse.ComparedWith != 4 ||
+		len(revisionResponse.Changes) != 1 ||
+		revisionResponse.Changes[0].Field != "nameserver ns1.example.com.br." {
+
+		t.Error("Not converting the domain revision with changes properly")
+	}
+}
@@ -7,6 +7,7 @@ package protocol
 
 import (
 	"fmt"
+	"net"
 	"net/mail"
 	"strings"
 	"testing"
@@ -226,6 +227,85 @@ func TestMerge(t *testing.T) {
 	}
 }
 
+func TestToDomainRequest(t *testing.T) {
+	email, err := mail.ParseAddress("example@example.com.br")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revision := model.Domain{
+		FQDN: "example.com.br.",
+		Nameservers: []model.Nameserver{
+			{
+				Host: "ns1.example.com.br.",
+				IPv4: net.ParseIP("127.0.0.1"),
+				IPv6: net.ParseIP("::1"),
+			},
+		},
+		DSSet: []model.DS{
+			{
+				Keytag:     41674,
+				Algorithm:  5,
+				Digest:     "eaa0978f38879db70a53f9ff1acf21d046a98b5c",
+				DigestType: 1,
+			},
+		},
+		Owners: []model.Owner{
+			{
+				Email:        email,
+				Language:     "pt-BR",
+				Digest:       model.DigestDaily,
+				ProblemTypes: []model.ProblemType{model.ProblemTypeDNSSEC},
+				QuietHours:   model.QuietHours{Start: 22 * 60, End: 6 * 60},
+			},
+			{
+				// Owner without e-mail should be ignored
+				Language: "en-US",
+			},
+		},
+	}
+
+	domainRequest := ToDomainRequest(revision)
+
+	if domainRequest.FQDN != "example.com.br." ||
+		len(domainRequest.Nameservers) != 1 ||
+		domainRequest.Nameservers[0].IPv4 != "127.0.0.1" ||
+		domainRequest.Nameservers[0].IPv6 != "::1" ||
+		len(domainRequest.DSSet) != 1 ||
+		len(domainRequest.Owners) != 1 ||
+		domainRequest.Owners[0].QuietHours == nil {
+
+		t.Fatal("Not converting the domain into a request properly")
+	}
+
+	current := model.Domain{
+		FQDN:     "example.com.br.",
+		Revision: 5,
+		Nameservers: []model.Nameserver{
+			{
+				Host:       "ns1.example.com.br.",
+				LastStatus: model.NameserverStatusTimeout,
+			},
+			{
+				Host: "ns2.example.com.br.",
+			},
+		},
+	}
+
+	restored, err := Merge(current, domainRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.Revision != 5 || restored.Nameservers[0].LastStatus != model.NameserverStatusTimeout {
+		t.Error("Not keeping the domain state while restoring the configuration")
+	}
+
+	if changes := model.NewAuditEntry(&revision, &restored).Changes; len(changes) > 0 {
+		t.Errorf("Not restoring the configuration properly. Changes: %v", changes)
+	}
+}
+
 func TestToDomainResponse(t *testing.T) {
 	email, err := mail.ParseAddress("example@example.com.br")
 	if err != nil {
@@ -9,6 +9,10 @@ import (
 	"fmt"
 	"github.com/rafaeljusto/shelter/dao"
 	"github.com/rafaeljusto/shelter/model"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // DomainsResponse store multiple domains objects with pagination support
@@ -26,7 +30,7 @@ func ToDomainsResponse(
 	domains []model.Domain,
 	pagination dao.DomainDAOPagination,
 	expand bool,
-	filter string,
+	filter dao.DomainDAOFilter,
 ) DomainsResponse {
 
 	var domainsResponses []DomainResponse
@@ -51,6 +55,8 @@ func ToDomainsResponse(
 		expandParameter = "&expand"
 	}
 
+	filterParameters := toDomainsFilterParameters(filter)
+
 	// Add pagination managment links to the response. The URI is hard coded, I didn't have
 	// any idea on how can we do this dynamically yet. We cannot get the URI from the
 	// handler because we are going to have a cross-reference problem
@@ -60,8 +66,8 @@ func ToDomainsResponse(
 	if pagination.Page > 1 {
 		links = append(links, Link{
 			Types: []LinkType{LinkTypeFirst},
-			HRef: fmt.Sprintf("/domains/?pagesize=%d&page=%d&orderby=%s&filter=%s%s",
-				pagination.PageSize, 1, orderBy, filter, expandParameter),
+			HRef: fmt.Sprintf("/domains/?pagesize=%d&page=%d&orderby=%s%s%s",
+				pagination.PageSize, 1, orderBy, filterParameters, expandParameter),
 		})
 	}
 
@@ -69,8 +75,8 @@ func ToDomainsResponse(
 	if pagination.Page-1 >= 1 {
 		links = append(links, Link{
 			Types: []LinkType{LinkTypePrev},
-			HRef: fmt.Sprintf("/domains/?pagesize=%d&page=%d&orderby=%s&filter=%s%s",
-				pagination.PageSize, pagination.Page-1, orderBy, filter, expandParameter),
+			HRef: fmt.Sprintf("/domains/?pagesize=%d&page=%d&orderby=%s%s%s",
+				pagination.PageSize, pagination.Page-1, orderBy, filterParameters, expandParameter),
 		})
 	}
 
@@ -78,8 +84,8 @@ func ToDomainsResponse(
 	if pagination.Page+1 <= pagination.NumberOfPages {
 		links = append(links, Link{
 			Types: []LinkType{LinkTypeNext},
-			HRef: fmt.Sprintf("/domains/?pagesize=%d&page=%d&orderby=%s&filter=%s%s",
-				pagination.PageSize, pagination.Page+1, orderBy, filter, expandParameter),
+			HRef: fmt.Sprintf("/domains/?pagesize=%d&page=%d&orderby=%s%s%s",
+				pagination.PageSize, pagination.Page+1, orderBy, filterParameters, expandParameter),
 		})
 	}
 
@@ -87,8 +93,8 @@ func ToDomainsResponse(
 	if pagination.Page < pagination.NumberOfPages {
 		links = append(links, Link{
 			Types: []LinkType{LinkTypeLast},
-			HRef: fmt.Sprintf("/domains/?pagesize=%d&page=%d&orderby=%s&filter=%s%s",
-				pagination.PageSize, pagination.NumberOfPages, orderBy, filter, expandParameter),
+			HRef: fmt.Sprintf("/domains/?pagesize=%d&page=%d&orderby=%s%s%s",
+				pagination.PageSize, pagination.NumberOfPages, orderBy, filterParameters, expandParameter),
 		})
 	}
 
@@ -101,3 +107,49 @@ func ToDomainsResponse(
 		Links:         links,
 	}
 }
+
+// Convert the filter conditions back into query string parameters, so that the
+// pagination links keep the same result set. The FQDN filter is always present, as it
+// was before the other conditions were created
+func toDomainsFilterParameters(filter dao.DomainDAOFilter) string {
+	parameters := "&filter=" + filter.FQDN
+
+	if len(filter.NameserverStatus) > 0 {
+		var status []string
+		for _, nameserverStatus := range filter.NameserverStatus {
+			status = append(status, model.NameserverStatusToString(nameserverStatus))
+		}
+		parameters += "&nameserverstatus=" + strings.Join(status, ",")
+	}
+
+	if len(filter.DSStatus) > 0 {
+		var status []string
+		for _, dsStatus := range filter.DSStatus {
+			status = append(status, model.DSStatusToString(dsStatus))
+		}
+		parameters += "&dsstatus=" + strings.Join(status, ",")
+	}
+
+	if filter.DNSSEC != nil {
+		parameters += "&dnssec=" + strconv.FormatBool(*filter.DNSSEC)
+	}
+
+	if filter.DSExpiresIn > 0 {
+		parameters += "&dsexpiresin=" + strconv.Itoa(filter.DSExpiresIn)
+	}
+
+	if !filter.NotCheckedSince.IsZero() {
+		parameters += "&notcheckedsince=" +
+			url.QueryEscape(filter.NotCheckedSince.UTC().Format(time.RFC3339))
+	}
+
+	if len(filter.OwnerEmail) > 0 {
+		parameters += "&owner=" + url.QueryEscape(filter.OwnerEmail)
+	}
+
+	if len(filter.NameserverHost) > 0 {
+		parameters += "&nameserver=" + url.QueryEscape(filter.NameserverHost)
+	}
+
+	return parameters
+}
@@ -0,0 +1,109 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the REST protocol
+package protocol
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"unicode"
+)
+
+// DomainBulkRequest is a domain request used in bulk operations. As there's no URI for
+// each domain in a batch, the FQDN must be sent together with the domain data
+type DomainBulkRequest struct {
+	FQDN          string `json:"fqdn"` // Actual domain name
+	DomainRequest        // Domain data that the user can update
+}
+
+// DomainBulkResult stores the result of a domain in a bulk operation. The status is the
+// HTTP status code that the user would receive if the domain was sent alone
+type DomainBulkResult struct {
+	FQDN    string           `json:"fqdn"`              // Domain name as sent by the user
+	Status  int              `json:"status"`            // HTTP status code of the operation
+	Message *MessageResponse `json:"message,omitempty"` // Details when the operation failed
+	Links   []Link           `json:"links,omitempty"`   // Links to the domain resource
+}
+
+// DomainsBulkResponse stores the results of all domains of a bulk operation, in the same
+// order that they were sent by the user
+type DomainsBulkResponse struct {
+	Created int                `json:"created"` // Number of new domains
+	Updated int                `json:"updated"` // Number of domains that already existed
+	Failed  int                `json:"failed"`  // Number of domains that couldn't be stored
+	Results []DomainBulkResult `json:"results"` // Result of each domain
+}
+
+// DecodeDomainBulkRequests reads a batch of domain requests. The batch can be a JSON
+// array or a sequence of JSON objects, one per line (NDJSON). We check the first
+// non-space character to detect the format, so that the NDJSON content doesn't need to
+// be loaded into memory at once
+func DecodeDomainBulkRequests(r io.Reader) ([]DomainBulkRequest, error) {
+	reader := bufio.NewReader(r)
+
+	isArray := false
+	for {
+		c, _, err := reader.ReadRune()
+		if err == io.EOF {
+			return nil, nil
+
+		} else if err != nil {
+			return nil, err
+		}
+
+		if unicode.IsSpace(c) {
+			continue
+		}
+
+		isArray = (c == '[')
+		if err := reader.UnreadRune(); err != nil {
+			return nil, err
+		}
+		break
+	}
+
+	var requests []DomainBulkRequest
+	decoder := json.NewDecoder(reader)
+
+	if isArray {
+		if err := decoder.Decode(&requests); err != nil {
+			return nil, err
+		}
+
+		return requests, nil
+	}
+
+	for {
+		var request DomainBulkRequest
+		if err := decoder.Decode(&request); err == io.EOF {
+			break
+
+		} else if err != nil {
+			return nil, err
+		}
+
+		requests = append(requests, request)
+	}
+
+	return requests, nil
+}
+
+// AddResult appends the result of a domain, updating the counters of the response
+// according to the HTTP status code of the result
+func (d *DomainsBulkResponse) AddResult(result DomainBulkResult) {
+	if result.Status >= http.StatusBadRequest {
+		d.Failed += 1
+
+	} else if result.Status == http.StatusCreated {
+		d.Created += 1
+
+	} else {
+		d.Updated += 1
+	}
+
+	d.Results = append(d.Results, result)
+}
@@ -0,0 +1,89 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the REST protocol
+package protocol
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDecodeDomainBulkRequests(t *testing.T) {
+	data := []struct {
+		content string
+		fqdns   []string
+		err     bool
+	}{
+		{
+			content: `[{"fqdn": "example1.com.br.", "nameservers": [{"host": "ns1.example1.com.br."}]},` +
+				`{"fqdn": "example2.com.br."}]`,
+			fqdns: []string{"example1.com.br.", "example2.com.br."},
+		},
+		{
+			content: "  \n" + `{"fqdn": "example1.com.br.", "nameservers": [{"host": "ns1.example1.com.br."}]}` +
+				"\n" + `{"fqdn": "example2.com.br."}` + "\n",
+			fqdns: []string{"example1.com.br.", "example2.com.br."},
+		},
+		{
+			content: "",
+		},
+		{
+			content: `[{"fqdn": "example1.com.br."}`,
+			err:     true,
+		},
+		{
+			content: `{"fqdn": "example1.com.br."}` + "\n" + `{"fqdn": `,
+			err:     true,
+		},
+	}
+
+	for i, item := range data {
+		requests, err := DecodeDomainBulkRequests(strings.NewReader(item.content))
+		if item.err {
+			if err == nil {
+				t.Errorf("Item %d: Not detecting invalid bulk content", i)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Item %d: Unexpected error. Details: %s", i, err)
+			continue
+		}
+
+		if len(requests) != len(item.fqdns) {
+			t.Errorf("Item %d: Expected %d requests and got %d", i, len(item.fqdns), len(requests))
+			continue
+		}
+
+		for j, request := range requests {
+			if request.FQDN != item.fqdns[j] {
+				t.Errorf("Item %d: Expected FQDN %s and got %s", i, item.fqdns[j], request.FQDN)
+			}
+		}
+
+		if len(requests) > 0 &&
+			(len(requests[0].Nameservers) != 1 || requests[0].Nameservers[0].Host != "ns1.example1.com.br.") {
+			t.Errorf("Item %d: Not decoding the domain data", i)
+		}
+	}
+}
+
+func TestDomainsBulkResponseAddResult(t *testing.T) {
+	var response DomainsBulkResponse
+	response.AddResult(DomainBulkResult{FQDN: "example1.com.br.", Status: http.StatusCreated})
+	response.AddResult(DomainBulkResult{FQDN: "example2.com.br.", Status: http.StatusNoContent})
+	response.AddResult(DomainBulkResult{FQDN: "example3.com.br.", Status: http.StatusBadRequest})
+	response.AddResult(DomainBulkResult{FQDN: "example4.com.br.", Status: http.StatusConflict})
+
+	if response.Created != 1 || response.Updated != 1 || response.Failed != 2 {
+		t.Error("Not counting the bulk results properly")
+	}
+
+	if len(response.Results) != 4 || response.Results[3].FQDN != "example4.com.br." {
+		t.Error("Not keeping the order of the bulk results")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/rafaeljusto/shelter/dao"
 	"github.com/rafaeljusto/shelter/model"
 	"testing"
+	"time"
 )
 
 func TestToDomainsResponse(t *testing.T) {
@@ -47,7 +48,7 @@ func TestToDomainsResponse(t *testing.T) {
 		NumberOfPages: len(domains) / 10,
 	}
 
-	domainsResponse := ToDomainsResponse(domains, pagination, true, "example")
+	domainsResponse := ToDomainsResponse(domains, pagination, true, dao.DomainDAOFilter{FQDN: "example"})
 
 	if len(domainsResponse.Domains) != len(domains) {
 		t.Error("Not converting domain model objects properly")
@@ -111,7 +112,7 @@ func TestToDomainsResponseLinks(t *testing.T) {
 		NumberOfPages: 3,
 	}
 
-	domainsResponse := ToDomainsResponse(domains, pagination, true, "example")
+	domainsResponse := ToDomainsResponse(domains, pagination, true, dao.DomainDAOFilter{FQDN: "example"})
 
 	// Show all actions when navigating in the middle of the pagination
 	if len(domainsResponse.Links) != 4 {
@@ -135,7 +136,7 @@ func TestToDomainsResponseLinks(t *testing.T) {
 		NumberOfPages: 3,
 	}
 
-	domainsResponse = ToDomainsResponse(domains, pagination, true, "example")
+	domainsResponse = ToDomainsResponse(domains, pagination, true, dao.DomainDAOFilter{FQDN: "example"})
 
 	// Don't show previous or fast backward when we are in the first page
 	if len(domainsResponse.Links) != 2 {
@@ -159,10 +160,52 @@ func TestToDomainsResponseLinks(t *testing.T) {
 		NumberOfPages: 3,
 	}
 
-	domainsResponse = ToDomainsResponse(domains, pagination, true, "example")
+	domainsResponse = ToDomainsResponse(domains, pagination, true, dao.DomainDAOFilter{FQDN: "example"})
 
 	// Don't show next or fast foward when we are in the last page
 	if len(domainsResponse.Links) != 2 {
 		t.Error("Response not adding the necessary links when we are in the last page")
 	}
 }
+
+func TestToDomainsResponseFilterLinks(t *testing.T) {
+	pagination := dao.DomainDAOPagination{
+		PageSize: 2,
+		Page:     1,
+		OrderBy: []dao.DomainDAOSort{
+			{
+				Field:     dao.DomainDAOOrderByFieldDSStatus,
+				Direction: dao.DAOOrderByDirectionDescending,
+			},
+		},
+		NumberOfItems: 3,
+		NumberOfPages: 2,
+	}
+
+	dnssec := true
+	filter := dao.DomainDAOFilter{
+		FQDN:             "example",
+		NameserverStatus: []model.NameserverStatus{model.NameserverStatusTimeout, model.NameserverStatusUnknownHost},
+		DSStatus:         []model.DSStatus{model.DSStatusExpiredSignature},
+		DNSSEC:           &dnssec,
+		DSExpiresIn:      7,
+		NotCheckedSince:  time.Date(2014, time.March, 1, 0, 0, 0, 0, time.UTC),
+		OwnerEmail:       "admin+dns@example.com.br",
+		NameserverHost:   "ns1.example.com.br.",
+	}
+
+	domainsResponse := ToDomainsResponse([]model.Domain{}, pagination, false, filter)
+	if len(domainsResponse.Links) != 2 {
+		t.Fatal("Response not adding the necessary links when we are at the first page")
+	}
+
+	expected := "/domains/?pagesize=2&page=2&orderby=dsstatus:desc&filter=example" +
+		"&nameserverstatus=TIMEOUT,UH&dsstatus=EXPSIG&dnssec=true&dsexpiresin=7" +
+		"&notcheckedsince=2014-03-01T00%3A00%3A00Z&owner=admin%2Bdns%40example.com.br" +
+		"&nameserver=ns1.example.com.br."
+
+	if domainsResponse.Links[0].HRef != expected {
+		t.Errorf("Not keeping the filter in the pagination links. Expected '%s' and got '%s'",
+			expected, domainsResponse.Links[0].HRef)
+	}
+}
@@ -67,6 +67,22 @@ func toDSSetModel(dsSetRequest []DSRequest) ([]model.DS, error) {
 	return dsSet, nil
 }
 
+// Convert a list of DS model objects back into DS requests objects, keeping only the
+// information that the user can update
+func toDSSetRequest(dsSet []model.DS) []DSRequest {
+	var dsSetRequest []DSRequest
+	for _, ds := range dsSet {
+		dsSetRequest = append(dsSetRequest, DSRequest{
+			Keytag:     ds.Keytag,
+			Algorithm:  uint8(ds.Algorithm),
+			Digest:     ds.Digest,
+			DigestType: uint8(ds.DigestType),
+		})
+	}
+
+	return dsSetRequest
+}
+
 // DS object used in the protocol to determinate what the user can see. The status was
 // converted to text format for easy interpretation
 type DSResponse struct {
@@ -74,7 +90,8 @@ type DSResponse struct {
 	Algorithm   uint8     `json:"algorithm,omitempty"`   // DNSKEY's algorithm
 	Digest      string    `json:"digest,omitempty"`      // Hash of the DNSKEY content
 	DigestType  uint8     `json:"digestType,omitempty"`  // Hash type decided by user when generating the DS
-	ExpiresAt   time.Time `json:"expiresAt,omitempty"`   // DNSKEY's signature expiration date
+	ExpiresAt   time.Time `json:"expiresAt,omitempty"`   // Earliest signature expiration date
+	ExpiresFrom string    `json:"expiresFrom,omitempty"` // RRset of the earliest signature expiration
 	LastStatus  string    `json:"lastStatus,omitempty"`  // Result of the last configuration check
 	LastCheckAt time.Time `json:"lastCheckAt,omitempty"` // Time of the last configuration check
 	LastOKAt    time.Time `json:"lastOKAt,omitempty"`    // Last time that the DNSSEC configuration was OK
@@ -89,6 +106,7 @@ func toDSResponse(ds model.DS) DSResponse {
 		Digest:      ds.Digest,
 		DigestType:  uint8(ds.DigestType),
 		ExpiresAt:   ds.ExpiresAt,
+		ExpiresFrom: ds.ExpiresFrom,
 		LastStatus:  model.DSStatusToString(ds.LastStatus),
 		LastCheckAt: ds.LastCheckAt,
 		LastOKAt:    ds.LastOKAt,
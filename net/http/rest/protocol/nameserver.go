@@ -75,15 +75,53 @@ func toNameserversModel(nameserversRequest []NameserverRequest) ([]model.Nameser
 	return nameservers, nil
 }
 
+// Convert a list of nameserver model objects back into nameserver requests objects,
+// keeping only the information that the user can update
+func toNameserversRequest(nameservers []model.Nameserver) []NameserverRequest {
+	var nameserversRequest []NameserverRequest
+	for _, nameserver := range nameservers {
+		nameserverRequest := NameserverRequest{
+			Host: nameserver.Host,
+		}
+
+		if len(nameserver.IPv4) > 0 {
+			nameserverRequest.IPv4 = nameserver.IPv4.String()
+		}
+
+		if len(nameserver.IPv6) > 0 {
+			nameserverRequest.IPv6 = nameserver.IPv6.String()
+		}
+
+		nameserversRequest = append(nameserversRequest, nameserverRequest)
+	}
+
+	return nameserversRequest
+}
+
 // Namerserver object used in the protocol to determinate what the user can see. The
 // status was converted to text format for easy interpretation
 type NameserverResponse struct {
-	Host        string    `json:"host,omitempty"`        // Nameserver's name
-	IPv4        string    `json:"ipv4,omitempty"`        // Host's IPv4 (optional when don't need glue)
-	IPv6        string    `json:"ipv6,omitempty"`        // Host's IPv6 (optional)
-	LastStatus  string    `json:"lastStatus,omitempty"`  // Result of the last configuration check
-	LastCheckAt time.Time `json:"lastCheckAt,omitempty"` // Time of the last configuration check
-	LastOKAt    time.Time `json:"lastOKAt,omitempty"`    // Last time that the DNS configuration was OK
+	Host           string              `json:"host,omitempty"`           // Nameserver's name
+	IPv4           string              `json:"ipv4,omitempty"`           // Host's IPv4 (optional when don't need glue)
+	IPv6           string              `json:"ipv6,omitempty"`           // Host's IPv6 (optional)
+	LastStatus     string              `json:"lastStatus,omitempty"`     // Result of the last configuration check
+	LastStatusIPv4 string              `json:"lastStatusIPv4,omitempty"` // Result of the last configuration check over IPv4
+	LastStatusIPv6 string              `json:"lastStatusIPv6,omitempty"` // Result of the last configuration check over IPv6
+	LastCheckAt    time.Time           `json:"lastCheckAt,omitempty"`    // Time of the last configuration check
+	LastOKAt       time.Time           `json:"lastOKAt,omitempty"`       // Last time that the DNS configuration was OK
+	Compliance     *ComplianceResponse `json:"compliance,omitempty"`     // Result of the last EDNS and TCP compliance check
+}
+
+// Compliance object used in the protocol to show the results of the EDNS and TCP
+// compliance tests of a nameserver. The status was converted to text format for easy
+// interpretation
+type ComplianceResponse struct {
+	Plain       string    `json:"plain,omitempty"`       // Result of the query without EDNS0
+	EDNS        string    `json:"edns,omitempty"`        // Result of the query with EDNS0
+	EDNSDO      string    `json:"ednsDO,omitempty"`      // Result of the query with EDNS0 and the DNSSEC OK bit
+	EDNSOption  string    `json:"ednsOption,omitempty"`  // Result of the query with an unknown EDNS0 option
+	TCP         string    `json:"tcp,omitempty"`         // Result of the query sent only over TCP
+	LastCheckAt time.Time `json:"lastCheckAt,omitempty"` // Time of the last compliance check
 }
 
 // Convert a nameserver of the system into a format with limited information to return it
@@ -100,12 +138,33 @@ func toNameserverResponse(nameserver model.Nameserver) NameserverResponse {
 	}
 
 	return NameserverResponse{
-		Host:        nameserver.Host,
-		IPv4:        ipv4,
-		IPv6:        ipv6,
-		LastStatus:  model.NameserverStatusToString(nameserver.LastStatus),
-		LastCheckAt: nameserver.LastCheckAt,
-		LastOKAt:    nameserver.LastOKAt,
+		Host:           nameserver.Host,
+		IPv4:           ipv4,
+		IPv6:           ipv6,
+		LastStatus:     model.NameserverStatusToString(nameserver.LastStatus),
+		LastStatusIPv4: model.NameserverStatusToString(nameserver.LastStatusIPv4),
+		LastStatusIPv6: model.NameserverStatusToString(nameserver.LastStatusIPv6),
+		LastCheckAt:    nameserver.LastCheckAt,
+		LastOKAt:       nameserver.LastOKAt,
+		Compliance:     toComplianceResponse(nameserver.Compliance),
+	}
+}
+
+// Convert the compliance report of a nameserver into a format with the status in text.
+// The compliance checks are optional, so when they were never executed nil is returned
+// and the report is omitted from the response
+func toComplianceResponse(compliance model.NameserverCompliance) *ComplianceResponse {
+	if compliance.LastCheckAt.IsZero() {
+		return nil
+	}
+
+	return &ComplianceResponse{
+		Plain:       model.ComplianceStatusToString(compliance.Plain),
+		EDNS:        model.ComplianceStatusToString(compliance.EDNS),
+		EDNSDO:      model.ComplianceStatusToString(compliance.EDNSDO),
+		EDNSOption:  model.ComplianceStatusToString(compliance.EDNSOption),
+		TCP:         model.ComplianceStatusToString(compliance.TCP),
+		LastCheckAt: compliance.LastCheckAt,
 	}
 }
 
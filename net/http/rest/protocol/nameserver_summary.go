@@ -0,0 +1,129 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the REST protocol
+package protocol
+
+import (
+	"fmt"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/model"
+	"time"
+)
+
+// NameserversResponse store multiple nameserver summaries with pagination support
+type NameserversResponse struct {
+	Page          int                         `json:"page"`                  // Current page selected
+	PageSize      int                         `json:"pageSize"`              // Number of nameservers in a page
+	NumberOfPages int                         `json:"numberOfPages"`         // Total number of pages for the result set
+	NumberOfItems int                         `json:"numberOfItems"`         // Total number of nameservers in the result set
+	Nameservers   []NameserverSummaryResponse `json:"nameservers,omitempty"` // List of nameserver summaries for the current page
+	Links         []Link                      `json:"links,omitempty"`       // Links for pagination managment
+}
+
+// NameserverSummaryResponse represents the situation of a nameserver host in all domains
+// that delegate to it
+type NameserverSummaryResponse struct {
+	Host        string            `json:"host,omitempty"`        // Nameserver's name
+	Domains     uint64            `json:"domains"`               // Number of domains that delegate to the nameserver
+	Statistics  map[string]uint64 `json:"statistics,omitempty"`  // Number of domains for each status of the nameserver
+	Timeouts    uint64            `json:"timeouts"`              // Number of timeouts detected by the scan
+	LastCheckAt time.Time         `json:"lastCheckAt,omitempty"` // Most recent configuration check of the nameserver
+	LastOKAt    time.Time         `json:"lastOKAt,omitempty"`    // Most recent time that the nameserver was OK
+	Links       []Link            `json:"links,omitempty"`       // Links to manipulate object
+}
+
+// Convert a list of nameserver summaries into protocol format with pagination support
+func NameserverSummariesToNameserversResponse(summaries []model.NameserverSummary,
+	pagination dao.NameserverSummaryDAOPagination) NameserversResponse {
+
+	var nameserversResponse []NameserverSummaryResponse
+	for _, summary := range summaries {
+		nameserversResponse = append(nameserversResponse, NameserverSummaryToNameserverSummaryResponse(summary))
+	}
+
+	var orderBy string
+	for _, sort := range pagination.OrderBy {
+		if len(orderBy) > 0 {
+			orderBy += "@"
+		}
+
+		orderBy += fmt.Sprintf("%s:%s",
+			dao.NameserverSummaryDAOOrderByFieldToString(sort.Field),
+			dao.DAOOrderByDirectionToString(sort.Direction),
+		)
+	}
+
+	// Add pagination managment links to the response. The URI is hard coded, I didn't have
+	// any idea on how can we do this dynamically yet. We cannot get the URI from the
+	// handler because we are going to have a cross-reference problem
+	var links []Link
+
+	// Only add fast backward if we aren't in the first page
+	if pagination.Page > 1 {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypeFirst},
+			HRef: fmt.Sprintf("/nameservers/?pagesize=%d&page=%d&orderby=%s",
+				pagination.PageSize, 1, orderBy,
+			),
+		})
+	}
+
+	// Only add previous if theres a previous page
+	if pagination.Page-1 >= 1 {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypePrev},
+			HRef: fmt.Sprintf("/nameservers/?pagesize=%d&page=%d&orderby=%s",
+				pagination.PageSize, pagination.Page-1, orderBy,
+			),
+		})
+	}
+
+	// Only add next if there's a next page
+	if pagination.Page+1 <= pagination.NumberOfPages {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypeNext},
+			HRef: fmt.Sprintf("/nameservers/?pagesize=%d&page=%d&orderby=%s",
+				pagination.PageSize, pagination.Page+1, orderBy,
+			),
+		})
+	}
+
+	// Only add the fast forward if we aren't on the last page
+	if pagination.Page < pagination.NumberOfPages {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypeLast},
+			HRef: fmt.Sprintf("/nameservers/?pagesize=%d&page=%d&orderby=%s",
+				pagination.PageSize, pagination.NumberOfPages, orderBy,
+			),
+		})
+	}
+
+	return NameserversResponse{
+		Page:          pagination.Page,
+		PageSize:      pagination.PageSize,
+		NumberOfPages: pagination.NumberOfPages,
+		NumberOfItems: pagination.NumberOfItems,
+		Nameservers:   nameserversResponse,
+		Links:         links,
+	}
+}
+
+// Convert a nameserver summary of the system into a format easy to interpret by the user
+func NameserverSummaryToNameserverSummaryResponse(summary model.NameserverSummary) NameserverSummaryResponse {
+	return NameserverSummaryResponse{
+		Host:        summary.Host,
+		Domains:     summary.Domains,
+		Statistics:  summary.Statistics,
+		Timeouts:    summary.Timeouts,
+		LastCheckAt: summary.LastCheckAt,
+		LastOKAt:    summary.LastOKAt,
+		Links: []Link{
+			{
+				Types: []LinkType{LinkTypeSelf},
+				HRef:  fmt.Sprintf("/nameserver/%s", summary.Host),
+			},
+		},
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the REST protocol
+package protocol
+
+import (
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/model"
+	"testing"
+	"time"
+)
+
+func TestNameserverSummariesToNameserversResponse(t *testing.T) {
+	summaries := []model.NameserverSummary{
+		{
+			Host:    "ns1.example.com.br.",
+			Domains: 10,
+		},
+		{
+			Host:    "ns2.example.com.br.",
+			Domains: 5,
+		},
+	}
+
+	pagination := dao.NameserverSummaryDAOPagination{
+		PageSize: 10,
+		Page:     1,
+		OrderBy: []dao.NameserverSummaryDAOSort{
+			{
+				Field:     dao.NameserverSummaryDAOOrderByFieldDomains,
+				Direction: dao.DAOOrderByDirectionDescending,
+			},
+		},
+		NumberOfItems: len(summaries),
+		NumberOfPages: 1,
+	}
+
+	nameserversResponse := NameserverSummariesToNameserversResponse(summaries, pagination)
+
+	if len(nameserversResponse.Nameservers) != len(summaries) {
+		t.Error("Not converting nameserver summary model objects properly")
+	}
+
+	if nameserversResponse.PageSize != 10 || nameserversResponse.Page != 1 ||
+		nameserversResponse.NumberOfItems != len(summaries) || nameserversResponse.NumberOfPages != 1 {
+
+		t.Error("Pagination not storing the information properly")
+	}
+
+	// We shouldn't show any link when there's only one page
+	if len(nameserversResponse.Links) != 0 {
+		t.Error("Response adding links when there is only one page")
+	}
+
+	pagination.PageSize = 1
+	pagination.Page = 2
+	pagination.NumberOfPages = 3
+
+	nameserversResponse = NameserverSummariesToNameserversResponse(summaries, pagination)
+
+	// Show all actions when navigating in the middle of the pagination
+	if len(nameserversResponse.Links) != 4 {
+		t.Error("Response not adding the necessary links when we are navigating")
+	}
+
+	if nameserversResponse.Links[0].HRef != "/nameservers/?pagesize=1&page=1&orderby=domains:desc" {
+		t.Error("Not building the pagination links properly")
+	}
+}
+
+func TestNameserverSummaryToNameserverSummaryResponse(t *testing.T) {
+	now := time.Now()
+
+	summary := model.NameserverSummary{
+		Host:    "ns1.example.com.br.",
+		Domains: 3,
+		Statistics: map[string]uint64{
+			"OK":      1,
+			"TIMEOUT": 2,
+		},
+		Timeouts:    25,
+		LastCheckAt: now,
+		LastOKAt:    now.Add(-1 * time.Hour),
+	}
+
+	summaryResponse := NameserverSummaryToNameserverSummaryResponse(summary)
+
+	if summaryResponse.Host != "ns1.example.com.br." {
+		t.Error("Not converting the host properly")
+	}
+
+	if summaryResponse.Domains != 3 || summaryResponse.Timeouts != 25 {
+		t.Error("Not converting the counters properly")
+	}
+
+	if summaryResponse.Statistics["OK"] != 1 || summaryResponse.Statistics["TIMEOUT"] != 2 {
+		t.Error("Not converting the statistics properly")
+	}
+
+	if !summaryResponse.LastCheckAt.Equal(now) || !summaryResponse.LastOKAt.Equal(now.Add(-1*time.Hour)) {
+		t.Error("Not converting the dates properly")
+	}
+
+	if len(summaryResponse.Links) != 1 ||
+		summaryResponse.Links[0].HRef != "/nameserver/ns1.example.com.br." {
+
+		t.Error("Not adding the self link")
+	}
+}
@@ -7,48 +7,166 @@ package protocol
 
 import (
 	"errors"
+	"fmt"
+	"net"
 	"net/mail"
+	"net/url"
+	"strings"
+	"time"
 
+	"github.com/rafaeljusto/shelter/config"
 	"github.com/rafaeljusto/shelter/model"
 )
 
 // List of possible errors that can occur when calling methods from this object. Other
 // erros can also occurs from low level layers
 var (
+	// Error when the e-mail address doesn't follow the RFC 5322 format
+	ErrInvalidEmail = errors.New("Invalid owner e-mail")
+
 	// Error when an invalid language is given. List of possible values can be found in IANA
 	// website
 	ErrInvalidLanguage = errors.New("Invalid owner language")
+
+	// Error when the webhook isn't an absolute HTTP or HTTPS URL, or when it points to a
+	// loopback or private address that isn't allowed
+	ErrInvalidWebhook = errors.New("Invalid owner webhook")
+
+	// Error when the digest isn't one of the possible frequencies (immediate, daily or
+	// weekly)
+	ErrInvalidDigest = errors.New("Invalid owner digest")
+
+	// Error when one of the problem types isn't known
+	ErrInvalidProblemType = errors.New("Invalid owner problem type")
+
+	// Error when the quiet hours aren't in the format "15:04 -0700"
+	ErrInvalidQuietHours = errors.New("Invalid owner quiet hours")
 )
 
+// Format of the start and end of the quiet hours. The timezone is necessary because the
+// quiet hours are stored in UTC
+const quietHoursFormat = "15:04 -0700"
+
 // Owner object used in the protocol to determinate what the user can update, for this
 // case, everything
 type OwnerRequest struct {
-	Email    string `json:"email,omitempty"`    // E-mail that the owner wants to be alerted
-	Language string `json:"language,omitempty"` // Language that the owner wants to receive the messages
+	Email         string             `json:"email,omitempty"`         // E-mail that the owner wants to be alerted
+	Language      string             `json:"language,omitempty"`      // Language that the owner wants to receive the messages
+	Webhook       string             `json:"webhook,omitempty"`       // URL that the owner wants to receive the alerts as JSON
+	WebhookSecret string             `json:"webhookSecret,omitempty"` // Secret used to sign the webhook requests, never returned
+	Digest        string             `json:"digest,omitempty"`        // Frequency of the e-mail alerts (immediate, daily or weekly)
+	ProblemTypes  []string           `json:"problemTypes,omitempty"`  // Types of problems alerted by e-mail, empty for all
+	QuietHours    *QuietHoursRequest `json:"quietHours,omitempty"`    // Period of the day without e-mail alerts
+}
+
+// QuietHoursRequest is the period of the day that the owner doesn't want to receive
+// e-mail alerts. The start and end are in the format "15:04 -0700" (e.g. 22:00 -0300)
+type QuietHoursRequest struct {
+	Start string `json:"start"` // Beginning of the quiet period
+	End   string `json:"end"`   // End of the quiet period
 }
 
 // Convert a owner request object into a owner model object. It can return errors related
-// to the e-mail format
+// to the e-mail format and to the preferences of the owner
 func (o *OwnerRequest) toOwnerModel() (model.Owner, error) {
 	var owner model.Owner
 
 	email, err := mail.ParseAddress(o.Email)
 	if err != nil {
-		return owner, err
+		return owner, ErrInvalidEmail
 	}
 
 	if !model.IsValidLanguage(o.Language) {
 		return owner, ErrInvalidLanguage
 	}
 
+	if len(o.Webhook) > 0 && !isValidWebhook(o.Webhook) {
+		return owner, ErrInvalidWebhook
+	}
+
+	digest, err := model.DigestFromString(o.Digest)
+	if err != nil {
+		return owner, ErrInvalidDigest
+	}
+
+	var problemTypes []model.ProblemType
+	for _, problemTypeText := range o.ProblemTypes {
+		problemType, err := model.ProblemTypeFromString(problemTypeText)
+		if err != nil {
+			return owner, ErrInvalidProblemType
+		}
+
+		problemTypes = append(problemTypes, problemType)
+	}
+
+	var quietHours model.QuietHours
+	if o.QuietHours != nil {
+		if quietHours.Start, err = quietHoursToMinutes(o.QuietHours.Start); err != nil {
+			return owner, ErrInvalidQuietHours
+		}
+
+		if quietHours.End, err = quietHoursToMinutes(o.QuietHours.End); err != nil {
+			return owner, ErrInvalidQuietHours
+		}
+	}
+
 	owner = model.Owner{
-		Email:    email,
-		Language: model.NormalizeLanguage(o.Language),
+		Email:         email,
+		Language:      model.NormalizeLanguage(o.Language),
+		Webhook:       o.Webhook,
+		WebhookSecret: o.WebhookSecret,
+		Digest:        digest,
+		ProblemTypes:  problemTypes,
+		QuietHours:    quietHours,
 	}
 
 	return owner, nil
 }
 
+// Check if the webhook is an absolute HTTP or HTTPS URL. Hosts that are obviously internal
+// (loopback or private IP addresses and localhost) are rejected here, unless they are in
+// the allowed hosts of the configuration. The names that resolve to private addresses are
+// only detected when the notification is sent
+func isValidWebhook(value string) bool {
+	webhook, err := url.Parse(value)
+	if err != nil || !webhook.IsAbs() ||
+		(webhook.Scheme != "http" && webhook.Scheme != "https") {
+		return false
+	}
+
+	host := strings.ToLower(webhook.Hostname())
+	if len(host) == 0 {
+		return false
+	}
+
+	for _, allowedHost := range config.ShelterConfig.Notification.Webhook.AllowedHosts {
+		if strings.ToLower(allowedHost) == host {
+			return true
+		}
+	}
+
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+
+	if ip := net.ParseIP(host); ip != nil && model.IsPrivateAddress(ip) {
+		return false
+	}
+
+	return true
+}
+
+// Convert the start or end of the quiet hours into minutes of the day in UTC
+func quietHoursToMinutes(value string) (int, error) {
+	moment, err := time.Parse(quietHoursFormat, strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+
+	moment = moment.UTC()
+	return moment.Hour()*60 + moment.Minute(), nil
+}
+
 // Convert a list of owner requests objects into a list of owner model objects. Useful
 // when merging domain object from the network with a domain object from the database. It
 // can return errors related to the e-mail format in one of the converted owners
@@ -66,19 +184,87 @@ func toOwnersModel(ownersRequest []OwnerRequest) ([]model.Owner, error) {
 	return owners, nil
 }
 
+// Convert a list of owner model objects back into owner requests objects. Owners without
+// e-mail are ignored, because they could not be converted back into model objects
+func toOwnersRequest(owners []model.Owner) []OwnerRequest {
+	var ownersRequest []OwnerRequest
+	for _, owner := range owners {
+		if owner.Email == nil {
+			continue
+		}
+
+		ownerRequest := OwnerRequest{
+			Email:         owner.Email.Address,
+			Language:      owner.Language,
+			Webhook:       owner.Webhook,
+			WebhookSecret: owner.WebhookSecret,
+			Digest:        model.DigestToString(owner.Digest),
+		}
+
+		for _, problemType := range owner.ProblemTypes {
+			ownerRequest.ProblemTypes = append(ownerRequest.ProblemTypes,
+				model.ProblemTypeToString(problemType))
+		}
+
+		if owner.QuietHours.Enabled() {
+			ownerRequest.QuietHours = &QuietHoursRequest{
+				Start: minutesToQuietHours(owner.QuietHours.Start),
+				End:   minutesToQuietHours(owner.QuietHours.End),
+			}
+		}
+
+		ownersRequest = append(ownersRequest, ownerRequest)
+	}
+
+	return ownersRequest
+}
+
 // Owner object used in the protocol to determinate what the user can see
 type OwnerResponse struct {
-	Email    string `json:"email,omitempty"`    // E-mail that the owner wants to be alerted
-	Language string `json:"language,omitempty"` // Language that the owner wants to receive the messages
+	Email        string              `json:"email,omitempty"`        // E-mail that the owner wants to be alerted
+	Language     string              `json:"language,omitempty"`     // Language that the owner wants to receive the messages
+	Webhook      string              `json:"webhook,omitempty"`      // URL that the owner wants to receive the alerts as JSON
+	Digest       string              `json:"digest,omitempty"`       // Frequency of the e-mail alerts
+	ProblemTypes []string            `json:"problemTypes,omitempty"` // Types of problems alerted by e-mail
+	QuietHours   *QuietHoursResponse `json:"quietHours,omitempty"`   // Period of the day without e-mail alerts
+}
+
+// QuietHoursResponse is the period of the day that the owner doesn't receive e-mail
+// alerts. The start and end are always in UTC
+type QuietHoursResponse struct {
+	Start string `json:"start"` // Beginning of the quiet period
+	End   string `json:"end"`   // End of the quiet period
 }
 
 // Convert a owner of the system into a format with limited information to return it to
-// the user. For now we are not limiting any information
+// the user. The webhook secret is never returned
 func toOwnerResponse(owner model.Owner) OwnerResponse {
-	return OwnerResponse{
+	ownerResponse := OwnerResponse{
 		Email:    owner.Email.Address,
 		Language: owner.Language,
+		Webhook:  owner.Webhook,
+		Digest:   model.DigestToString(owner.Digest),
 	}
+
+	for _, problemType := range owner.ProblemTypes {
+		ownerResponse.ProblemTypes = append(ownerResponse.ProblemTypes,
+			model.ProblemTypeToString(problemType))
+	}
+
+	if owner.QuietHours.Enabled() {
+		ownerResponse.QuietHours = &QuietHoursResponse{
+			Start: minutesToQuietHours(owner.QuietHours.Start),
+			End:   minutesToQuietHours(owner.QuietHours.End),
+		}
+	}
+
+	return ownerResponse
+}
+
+// Convert the minutes of the day in UTC into the format of the start or end of the quiet
+// hours
+func minutesToQuietHours(minutes int) string {
+	return fmt.Sprintf("%02d:%02d +0000", minutes/60, minutes%60)
 }
 
 // Convert a list of owners of the system into a format with limited information to return
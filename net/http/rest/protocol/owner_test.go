@@ -9,6 +9,7 @@ import (
 	"net/mail"
 	"testing"
 
+	"github.com/rafaeljusto/shelter/config"
 	"github.com/rafaeljusto/shelter/model"
 )
 
@@ -37,7 +38,7 @@ func TestToOwnerModel(t *testing.T) {
 	}
 
 	owner, err = ownerRequest.toOwnerModel()
-	if err == nil {
+	if err != ErrInvalidEmail {
 		t.Error("Not checking e-mail format on conversion")
 	}
 
@@ -97,6 +98,131 @@ func TestToOwnersModel(t *testing.T) {
 	if _, err := toOwnersModel(ownersRequest); err == nil {
 		t.Error("Not checking invalid language on conversion")
 	}
+
+	ownersRequest = []OwnerRequest{
+		{
+			Email:    "example01@example.com.br",
+			Language: "pt-br",
+			Webhook:  "https://example.com.br/alerts",
+		},
+	}
+
+	if owners, err := toOwnersModel(ownersRequest); err != nil {
+		t.Error(err)
+
+	} else if owners[0].Webhook != "https://example.com.br/alerts" {
+		t.Error("Not converting webhook properly")
+	}
+
+	invalidWebhooks := []string{
+		"/alerts",
+		"ftp://example.com.br/alerts",
+		"%zz",
+		"http://localhost:8080/alerts",
+		"http://127.0.0.1/alerts",
+		"http://10.0.0.1/alerts",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/alerts",
+	}
+
+	for _, webhook := range invalidWebhooks {
+		ownersRequest = []OwnerRequest{
+			{
+				Email:    "example01@example.com.br",
+				Language: "pt-br",
+				Webhook:  webhook,
+			},
+		}
+
+		if _, err := toOwnersModel(ownersRequest); err != ErrInvalidWebhook {
+			t.Errorf("Not checking invalid webhook %s on conversion", webhook)
+		}
+	}
+
+	config.ShelterConfig.Notification.Webhook.AllowedHosts = []string{"10.0.0.1"}
+	defer func() {
+		config.ShelterConfig.Notification.Webhook.AllowedHosts = nil
+	}()
+
+	ownersRequest = []OwnerRequest{
+		{
+			Email:         "example01@example.com.br",
+			Language:      "pt-br",
+			Webhook:       "http://10.0.0.1/alerts",
+			WebhookSecret: "abc123",
+		},
+	}
+
+	if owners, err := toOwnersModel(ownersRequest); err != nil {
+		t.Error("Not allowing a private address in the allowed hosts. Details:", err)
+
+	} else if owners[0].WebhookSecret != "abc123" {
+		t.Error("Not converting webhook secret properly")
+	}
+}
+
+func TestToOwnerModelPreferences(t *testing.T) {
+	ownerRequest := OwnerRequest{
+		Email:        "example01@example.com.br",
+		Language:     "pt-br",
+		Digest:       "Weekly",
+		ProblemTypes: []string{"lame", "dnssec-expiration"},
+		QuietHours: &QuietHoursRequest{
+			Start: "22:00 -0300",
+			End:   "07:30 -0300",
+		},
+	}
+
+	owner, err := ownerRequest.toOwnerModel()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if owner.Digest != model.DigestWeekly {
+		t.Error("Not converting digest properly")
+	}
+
+	if len(owner.ProblemTypes) != 2 ||
+		owner.ProblemTypes[0] != model.ProblemTypeLame ||
+		owner.ProblemTypes[1] != model.ProblemTypeDNSSECExpiration {
+
+		t.Error("Not converting problem types properly")
+	}
+
+	if owner.QuietHours.Start != 1*60 || owner.QuietHours.End != 10*60+30 {
+		t.Errorf("Not converting quiet hours to UTC properly: %#v", owner.QuietHours)
+	}
+
+	data := []struct {
+		ownerRequest OwnerRequest
+		expectedErr  error
+	}{
+		{
+			ownerRequest: OwnerRequest{Digest: "monthly"},
+			expectedErr:  ErrInvalidDigest,
+		},
+		{
+			ownerRequest: OwnerRequest{ProblemTypes: []string{"timeout", "xxx"}},
+			expectedErr:  ErrInvalidProblemType,
+		},
+		{
+			ownerRequest: OwnerRequest{QuietHours: &QuietHoursRequest{Start: "22h", End: "07:00 -0300"}},
+			expectedErr:  ErrInvalidQuietHours,
+		},
+		{
+			ownerRequest: OwnerRequest{QuietHours: &QuietHoursRequest{Start: "22:00 -0300"}},
+			expectedErr:  ErrInvalidQuietHours,
+		},
+	}
+
+	for _, item := range data {
+		item.ownerRequest.Email = "example01@example.com.br"
+		item.ownerRequest.Language = "pt-br"
+
+		if _, err := item.ownerRequest.toOwnerModel(); err != item.expectedErr {
+			t.Errorf("Expected error %v and got %v", item.expectedErr, err)
+		}
+	}
 }
 
 func TestToOwnerResponse(t *testing.T) {
@@ -119,6 +245,31 @@ func TestToOwnerResponse(t *testing.T) {
 	if ownerResponse.Language != "en-US" {
 		t.Error("Not converting language properly")
 	}
+
+	if ownerResponse.Digest != "immediate" || len(ownerResponse.ProblemTypes) > 0 ||
+		ownerResponse.QuietHours != nil {
+
+		t.Error("Not converting default preferences properly")
+	}
+
+	owner.Digest = model.DigestDaily
+	owner.ProblemTypes = []model.ProblemType{model.ProblemTypeTimeout}
+	owner.QuietHours = model.QuietHours{Start: 60, End: 10*60 + 30}
+
+	ownerResponse = toOwnerResponse(owner)
+
+	if ownerResponse.Digest != "daily" ||
+		len(ownerResponse.ProblemTypes) != 1 || ownerResponse.ProblemTypes[0] != "timeout" {
+
+		t.Error("Not converting preferences properly")
+	}
+
+	if ownerResponse.QuietHours == nil ||
+		ownerResponse.QuietHours.Start != "01:00 +0000" ||
+		ownerResponse.QuietHours.End != "10:30 +0000" {
+
+		t.Error("Not converting quiet hours properly")
+	}
 }
 
 func TestToOwnersResponse(t *testing.T) {
@@ -0,0 +1,143 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the REST protocol
+package protocol
+
+import (
+	"fmt"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/model"
+)
+
+// OwnersResponse store multiple owner summaries with pagination support
+type OwnersResponse struct {
+	Page          int                    `json:"page"`             // Current page selected
+	PageSize      int                    `json:"pageSize"`         // Number of owners in a page
+	NumberOfPages int                    `json:"numberOfPages"`    // Total number of pages for the result set
+	NumberOfItems int                    `json:"numberOfItems"`    // Total number of owners in the result set
+	Owners        []OwnerSummaryResponse `json:"owners,omitempty"` // List of owner summaries for the current page
+	Links         []Link                 `json:"links,omitempty"`  // Links for pagination managment
+}
+
+// OwnerSummaryResponse represents an owner in all domains that he is responsable for
+type OwnerSummaryResponse struct {
+	Email   string `json:"email"`           // E-mail address of the owner
+	Domains int    `json:"domains"`         // Number of domains of the owner
+	Links   []Link `json:"links,omitempty"` // Links to manipulate object
+}
+
+// OwnerDomainsResponse lists all domains of an owner with their current status
+type OwnerDomainsResponse struct {
+	Email   string           `json:"email"`             // E-mail address of the owner
+	Domains []DomainResponse `json:"domains,omitempty"` // Domains of the owner
+	Links   []Link           `json:"links,omitempty"`   // Links to manipulate object
+}
+
+// Convert a list of owner summaries into protocol format with pagination support
+func OwnerSummariesToOwnersResponse(owners []model.OwnerSummary,
+	pagination dao.OwnerDAOPagination, filter string) OwnersResponse {
+
+	var ownersResponse []OwnerSummaryResponse
+	for _, owner := range owners {
+		ownersResponse = append(ownersResponse, OwnerSummaryResponse{
+			Email:   owner.Email,
+			Domains: owner.Domains,
+			Links: []Link{
+				{
+					Types: []LinkType{LinkTypeSelf},
+					HRef:  fmt.Sprintf("/owner/%s", owner.Email),
+				},
+			},
+		})
+	}
+
+	var orderBy string
+	for _, sort := range pagination.OrderBy {
+		if len(orderBy) > 0 {
+			orderBy += "@"
+		}
+
+		orderBy += fmt.Sprintf("%s:%s",
+			dao.OwnerDAOOrderByFieldToString(sort.Field),
+			dao.DAOOrderByDirectionToString(sort.Direction),
+		)
+	}
+
+	// Add pagination managment links to the response. The URI is hard coded, I didn't have
+	// any idea on how can we do this dynamically yet. We cannot get the URI from the
+	// handler because we are going to have a cross-reference problem
+	var links []Link
+
+	// Only add fast backward if we aren't in the first page
+	if pagination.Page > 1 {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypeFirst},
+			HRef: fmt.Sprintf("/owners/?pagesize=%d&page=%d&orderby=%s&filter=%s",
+				pagination.PageSize, 1, orderBy, filter),
+		})
+	}
+
+	// Only add previous if theres a previous page
+	if pagination.Page-1 >= 1 {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypePrev},
+			HRef: fmt.Sprintf("/owners/?pagesize=%d&page=%d&orderby=%s&filter=%s",
+				pagination.PageSize, pagination.Page-1, orderBy, filter),
+		})
+	}
+
+	// Only add next if there's a next page
+	if pagination.Page+1 <= pagination.NumberOfPages {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypeNext},
+			HRef: fmt.Sprintf("/owners/?pagesize=%d&page=%d&orderby=%s&filter=%s",
+				pagination.PageSize, pagination.Page+1, orderBy, filter),
+		})
+	}
+
+	// Only add the fast forward if we aren't on the last page
+	if pagination.Page < pagination.NumberOfPages {
+		links = append(links, Link{
+			Types: []LinkType{LinkTypeLast},
+			HRef: fmt.Sprintf("/owners/?pagesize=%d&page=%d&orderby=%s&filter=%s",
+				pagination.PageSize, pagination.NumberOfPages, orderBy, filter),
+		})
+	}
+
+	return OwnersResponse{
+		Page:          pagination.Page,
+		PageSize:      pagination.PageSize,
+		NumberOfPages: pagination.NumberOfPages,
+		NumberOfItems: pagination.NumberOfItems,
+		Owners:        ownersResponse,
+		Links:         links,
+	}
+}
+
+// Convert the domains of an owner into protocol format. Each domain has the link to its
+// own resource
+func ToOwnerDomainsResponse(email string, domains []model.Domain) OwnerDomainsResponse {
+	var domainsResponse []DomainResponse
+	for _, domain := range domains {
+		domainsResponse = append(domainsResponse, ToDomainResponse(domain, true))
+	}
+
+	return OwnerDomainsResponse{
+		Email:   email,
+		Domains: domainsResponse,
+		Links: []Link{
+			{
+				Types: []LinkType{LinkTypeSelf},
+				HRef:  fmt.Sprintf("/owner/%s", email),
+			},
+		},
+	}
+}
+
+// Convert the owner request into the owner model object, checking all the fields as it's
+// done when the owner is sent inside a domain
+func ToOwnerModel(ownerRequest OwnerRequest) (model.Owner, error) {
+	return ownerRequest.toOwnerModel()
+}
@@ -0,0 +1,100 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the REST protocol
+package protocol
+
+import (
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/model"
+	"testing"
+)
+
+func TestOwnerSummariesToOwnersResponse(t *testing.T) {
+	owners := []model.OwnerSummary{
+		{Email: "alice@example.com.br", Domains: 10},
+		{Email: "bob@example.com.br", Domains: 5},
+	}
+
+	pagination := dao.OwnerDAOPagination{
+		PageSize: 10,
+		Page:     1,
+		OrderBy: []dao.OwnerDAOSort{
+			{
+				Field:     dao.OwnerDAOOrderByFieldDomains,
+				Direction: dao.DAOOrderByDirectionDescending,
+			},
+		},
+		NumberOfItems: len(owners),
+		NumberOfPages: 1,
+	}
+
+	ownersResponse := OwnerSummariesToOwnersResponse(owners, pagination, "")
+
+	if len(ownersResponse.Owners) != len(owners) {
+		t.Fatal("Not converting owner summaries properly")
+	}
+
+	if ownersResponse.PageSize != 10 || ownersResponse.Page != 1 ||
+		ownersResponse.NumberOfItems != len(owners) || ownersResponse.NumberOfPages != 1 {
+
+		t.Error("Pagination not storing the information properly")
+	}
+
+	if ownersResponse.Owners[0].Email != "alice@example.com.br" ||
+		ownersResponse.Owners[0].Domains != 10 ||
+		len(ownersResponse.Owners[0].Links) != 1 ||
+		ownersResponse.Owners[0].Links[0].HRef != "/owner/alice@example.com.br" {
+
+		t.Error("Not converting the owner summary properly")
+	}
+
+	// We shouldn't show any link when there's only one page
+	if len(ownersResponse.Links) != 0 {
+		t.Error("Response adding links when there is only one page")
+	}
+
+	pagination.PageSize = 1
+	pagination.Page = 2
+	pagination.NumberOfPages = 3
+
+	ownersResponse = OwnerSummariesToOwnersResponse(owners, pagination, "example")
+
+	// Show all actions when navigating in the middle of the pagination
+	if len(ownersResponse.Links) != 4 {
+		t.Fatal("Response not adding the necessary links when we are navigating")
+	}
+
+	if ownersResponse.Links[0].HRef != "/owners/?pagesize=1&page=1&orderby=domains:desc&filter=example" {
+		t.Error("Not building the pagination links properly")
+	}
+}
+
+func TestToOwnerDomainsResponse(t *testing.T) {
+	domains := []model.Domain{
+		{
+			FQDN: "example.com.br.",
+			Nameservers: []model.Nameserver{
+				{Host: "ns1.example.com.br.", LastStatus: model.NameserverStatusTimeout},
+			},
+		},
+	}
+
+	ownerDomainsResponse := ToOwnerDomainsResponse("alice@example.com.br", domains)
+
+	if ownerDomainsResponse.Email != "alice@example.com.br" ||
+		len(ownerDomainsResponse.Domains) != 1 ||
+		ownerDomainsResponse.Domains[0].FQDN != "example.com.br." ||
+		len(ownerDomainsResponse.Domains[0].Nameservers) != 1 ||
+		ownerDomainsResponse.Domains[0].Nameservers[0].LastStatus != "TIMEOUT" {
+
+		t.Error("Not converting the domains of the owner properly")
+	}
+
+	if len(ownerDomainsResponse.Links) != 1 ||
+		ownerDomainsResponse.Links[0].HRef != "/owner/alice@example.com.br" {
+
+		t.Error("Not adding the owner link")
+	}
+}
@@ -11,6 +11,19 @@ import (
 	"time"
 )
 
+// ScanRequest stores the domains selection of an on-demand scan. An empty request
+// selects all domains of the system
+type ScanRequest struct {
+	FQDN        string `json:"fqdn,omitempty"`        // Regular expression (case insensitive) applied over the FQDN
+	OnlyFailing bool   `json:"onlyFailing,omitempty"` // Select only the domains with DNS or DNSSEC problems
+}
+
+// CurrentScanRequest stores the status desired by the user for the scan in progress. It
+// can be PAUSED to pause the scan or RUNNING to resume it
+type CurrentScanRequest struct {
+	Status string `json:"status"` // Desired scan situation
+}
+
 // ScanResponse structure represents the system Scan object to be returned via protocol. With this
 // object the user can retrieve information about executed scans or current progress of a specific
 // scan
@@ -0,0 +1,51 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the REST protocol
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/rafaeljusto/shelter/model"
+	"io"
+)
+
+// ScanEventResponse is the data of a Server-Sent Event about the current scan. Every
+// event has the state of the current scan, and the domain events also have the result of
+// the domain check
+type ScanEventResponse struct {
+	Scan   ScanResponse            `json:"scan"`             // State of the current scan when the event happened
+	FQDN   string                  `json:"fqdn,omitempty"`   // Domain checked by the scan (domain event)
+	Result *DomainSnapshotResponse `json:"result,omitempty"` // Result of the domain check (domain event)
+}
+
+// Convert a scan event of the system into a format easy to interpret by the user
+func ToScanEventResponse(event model.ScanEvent) ScanEventResponse {
+	scanEventResponse := ScanEventResponse{
+		Scan: CurrentScanToScanResponse(event.Scan),
+	}
+
+	if event.Domain != nil {
+		result := toDomainSnapshotResponse(*event.Domain)
+		scanEventResponse.FQDN = event.Domain.FQDN
+		scanEventResponse.Result = &result
+	}
+
+	return scanEventResponse
+}
+
+// WriteScanEvent writes the scan event in the Server-Sent Events format. The identifier
+// allows the user to detect lost events and the name is the type of the event
+func WriteScanEvent(w io.Writer, id uint64, name string, event ScanEventResponse) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	// JSON encoding never adds line breaks, so the data fits in a single line as the
+	// format requires
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, name, data)
+	return err
+}
@@ -0,0 +1,91 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the REST protocol
+package protocol
+
+import (
+	"bytes"
+	"github.com/rafaeljusto/shelter/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToScanEventResponse(t *testing.T) {
+	event := model.ScanEvent{
+		Type: model.ScanEventTypeStatus,
+		Scan: model.CurrentScan{
+			DomainsToBeScanned: 10,
+			Scan: model.Scan{
+				Status:         model.ScanStatusRunning,
+				DomainsScanned: 4,
+			},
+		},
+	}
+
+	scanEventResponse := ToScanEventResponse(event)
+
+	if scanEventResponse.Scan.Status != "RUNNING" ||
+		scanEventResponse.Scan.DomainsToBeScanned != 10 ||
+		scanEventResponse.Scan.DomainsScanned != 4 {
+		t.Error("Not converting the current scan of the event")
+	}
+
+	if len(scanEventResponse.FQDN) > 0 || scanEventResponse.Result != nil {
+		t.Error("Adding a domain result in a status event")
+	}
+
+	event.Type = model.ScanEventTypeDomain
+	event.Domain = &model.DomainSnapshot{
+		FQDN:          "example.com.br.",
+		ScanStartedAt: time.Now(),
+		Nameservers: []model.NameserverSnapshot{
+			{
+				Host:   "ns1.example.com.br.",
+				Status: model.NameserverStatusTimeout,
+			},
+		},
+	}
+
+	scanEventResponse = ToScanEventResponse(event)
+
+	if scanEventResponse.FQDN != "example.com.br." {
+		t.Error("Not converting the FQDN of the domain event")
+	}
+
+	if scanEventResponse.Result == nil ||
+		len(scanEventResponse.Result.Nameservers) != 1 ||
+		scanEventResponse.Result.Nameservers[0].Status != "TIMEOUT" {
+		t.Error("Not converting the domain result of the domain event")
+	}
+}
+
+func TestWriteScanEvent(t *testing.T) {
+	var buffer bytes.Buffer
+
+	err := WriteScanEvent(&buffer, 3, "status", ScanEventResponse{
+		Scan: ScanResponse{
+			Status: "RUNNING",
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := buffer.String()
+
+	if !strings.HasPrefix(event, "id: 3\nevent: status\ndata: {") {
+		t.Errorf("Wrong event header. Got '%s'", event)
+	}
+
+	if !strings.HasSuffix(event, "}\n\n") || strings.Count(event, "\n") != 4 {
+		t.Errorf("Event data is not in a single line. Got '%s'", event)
+	}
+
+	if !strings.Contains(event, `"status":"RUNNING"`) {
+		t.Errorf("Not writing the scan in the event data. Got '%s'", event)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the REST protocol
+package protocol
+
+import (
+	"github.com/rafaeljusto/shelter/zone"
+)
+
+// ZoneImportRequest stores a master zone file sent by the user to create or update the
+// delegated domains. The zone is sent as text inside the JSON content because the REST
+// server only accepts the Shelter content type
+type ZoneImportRequest struct {
+	Origin  string `json:"origin,omitempty"` // Origin used for relative names when the zone has no $ORIGIN
+	Content string `json:"content"`          // Master zone file (RFC 1035)
+}
+
+// ZoneImportResponse represents the zone import job to be returned via protocol. With
+// this object the user can check the result of the last import
+type ZoneImportResponse struct {
+	Status     string      `json:"status"`               // Current import job situation
+	Origin     string      `json:"origin,omitempty"`     // Origin informed by the user
+	StartedAt  PreciseTime `json:"startedAt,omitempty"`  // Start date and time of the import
+	FinishedAt PreciseTime `json:"finishedAt,omitempty"` // Finish date and time of the import
+	Created    int         `json:"created"`              // Number of new domains
+	Updated    int         `json:"updated"`              // Number of domains that had the delegation changed
+	Unchanged  int         `json:"unchanged"`            // Number of domains that already had the same delegation
+	Failed     int         `json:"failed"`               // Number of domains that couldn't be saved
+	Error      string      `json:"error,omitempty"`      // Reason of the failure when the job failed
+	Links      []Link      `json:"links,omitempty"`      // Links to the import job
+}
+
+// Convert the zone import job into a format easy to interpret by the user
+func ZoneImportJobToZoneImportResponse(job zone.Job) ZoneImportResponse {
+	return ZoneImportResponse{
+		Status:     zone.JobStatusToString(job.Status),
+		Origin:     job.Origin,
+		StartedAt:  PreciseTime{job.StartedAt},
+		FinishedAt: PreciseTime{job.FinishedAt},
+		Created:    job.Created,
+		Updated:    job.Updated,
+		Unchanged:  job.Unchanged,
+		Failed:     job.Failed,
+		Error:      job.Error,
+		Links: []Link{
+			{
+				Types: []LinkType{LinkTypeSelf},
+				HRef:  "/domains/zone",
+			},
+		},
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the REST protocol
+package protocol
+
+import (
+	"github.com/rafaeljusto/shelter/zone"
+	"testing"
+	"time"
+)
+
+func TestZoneImportJobToZoneImportResponse(t *testing.T) {
+	job := zone.Job{
+		ImportResult: zone.ImportResult{
+			Created:   10,
+			Updated:   5,
+			Unchanged: 2,
+			Failed:    1,
+		},
+		Status:     zone.JobStatusExecuted,
+		Origin:     "br.",
+		StartedAt:  time.Now().Add(-time.Minute),
+		FinishedAt: time.Now(),
+	}
+
+	zoneImportResponse := ZoneImportJobToZoneImportResponse(job)
+
+	if zoneImportResponse.Status != "EXECUTED" {
+		t.Error("Not converting the job status to text")
+	}
+
+	if zoneImportResponse.Origin != "br." ||
+		!zoneImportResponse.StartedAt.Equal(job.StartedAt) ||
+		!zoneImportResponse.FinishedAt.Equal(job.FinishedAt) {
+
+		t.Error("Not copying the job information")
+	}
+
+	if zoneImportResponse.Created != 10 ||
+		zoneImportResponse.Updated != 5 ||
+		zoneImportResponse.Unchanged != 2 ||
+		zoneImportResponse.Failed != 1 {
+
+		t.Error("Not copying the import result")
+	}
+
+	if len(zoneImportResponse.Links) != 1 || zoneImportResponse.Links[0].HRef != "/domains/zone" {
+		t.Error("Not adding the link to the import job")
+	}
+}
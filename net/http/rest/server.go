@@ -10,6 +10,8 @@ import (
 	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy"
 	"github.com/rafaeljusto/shelter/config"
 	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/http/rest/check"
 	"github.com/rafaeljusto/shelter/net/http/rest/handler"
 	"github.com/rafaeljusto/shelter/net/http/rest/interceptor"
 	"github.com/rafaeljusto/shelter/net/http/rest/messages"
@@ -18,6 +20,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -78,6 +81,11 @@ func Start(listeners []net.Listener) error {
 		return err
 	}
 
+	// Initialize roles and domains of the API keys
+	if err := loadPrincipals(); err != nil {
+		return err
+	}
+
 	// Handy logger should use the same logger of the Shelter system
 	handy.Logger = log.Logger
 
@@ -94,7 +102,10 @@ func Start(listeners []net.Listener) error {
 	}
 
 	server := http.Server{
-		Handler:      mux,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			streamWriter := newEventStreamResponseWriter(w)
+			mux.ServeHTTP(streamWriter, handler.WithResponseController(r, streamWriter.controller))
+		}),
 		ReadTimeout:  time.Duration(config.ShelterConfig.RESTServer.Timeouts.ReadSeconds) * time.Second,
 		WriteTimeout: time.Duration(config.ShelterConfig.RESTServer.Timeouts.WriteSeconds) * time.Second,
 	}
@@ -132,3 +143,74 @@ func loadACL() error {
 
 	return nil
 }
+
+func loadPrincipals() error {
+	principals := make(map[string]model.Principal)
+
+	for secretId, principalConfig := range config.ShelterConfig.RESTServer.Principals {
+		role, err := model.RoleFromString(principalConfig.Role)
+		if err != nil {
+			return err
+		}
+
+		// Secret ids are case insensitive in the Authorization header
+		principal := model.Principal{
+			Id:   strings.ToLower(strings.TrimSpace(secretId)),
+			Role: role,
+		}
+
+		for _, domain := range principalConfig.Domains {
+			fqdn, err := model.NormalizeDomainName(domain)
+			if err != nil {
+				return err
+			}
+
+			principal.Domains = append(principal.Domains, fqdn)
+		}
+
+		principals[principal.Id] = principal
+	}
+
+	interceptor.Principals = principals
+	return nil
+}
+
+// eventStreamResponseWriter sends the data to the user as soon as it's written when the
+// response is an event stream. Handy buffers the response and writes it to the connection
+// only once for the other resources, so for them nothing changes
+type eventStreamResponseWriter struct {
+	http.ResponseWriter
+	controller *http.ResponseController
+}
+
+func newEventStreamResponseWriter(w http.ResponseWriter) *eventStreamResponseWriter {
+	return &eventStreamResponseWriter{
+		ResponseWriter: w,
+		controller:     http.NewResponseController(w),
+	}
+}
+
+// The event stream lasts while the user is following the events, so the write timeout of
+// the server cannot be applied to it
+func (w *eventStreamResponseWriter) WriteHeader(code int) {
+	if w.isEventStream() {
+		if err := w.controller.SetWriteDeadline(time.Time{}); err != nil {
+			log.Println("Error removing write timeout of event stream. Details:", err)
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *eventStreamResponseWriter) Write(data []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(data)
+	if err != nil || !w.isEventStream() {
+		return n, err
+	}
+
+	return n, w.controller.Flush()
+}
+
+func (w *eventStreamResponseWriter) isEventStream() bool {
+	return strings.HasPrefix(w.Header().Get("Content-Type"), check.EventStreamContentType)
+}
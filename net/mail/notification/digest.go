@@ -0,0 +1,116 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package notification is the notification service
+package notification
+
+import (
+	"time"
+
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/config"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
+)
+
+const (
+	// The notification job doesn't run exactly at the same time every day, so we accept a
+	// digest a little before the end of the period. Without it a daily digest could be
+	// postponed to the next day because it was sent a few seconds late yesterday
+	digestTolerance = time.Hour
+)
+
+// ownerDigest stores the domains with problems of an owner that receives digests
+type ownerDigest struct {
+	Owner   model.Owner    // Owner preferences from the first domain found
+	Domains []model.Domain // Domains only with the problems that the owner wants to be alerted
+}
+
+// Add the domain to the digests of its owners that don't want to be alerted immediately.
+// The domain is only added when it has problems that the owner wants to receive
+func collectDigests(domain *model.Domain, digests map[string]*ownerDigest, now time.Time) {
+	expirationLimit := now.Add(time.Duration(
+		config.ShelterConfig.Scan.VerificationIntervals.MaxExpirationAlertDays*24) * time.Hour)
+
+	for _, owner := range domain.Owners {
+		if owner.Digest == model.DigestImmediate {
+			continue
+		}
+
+		filtered, ok := owner.FilterProblems(*domain, expirationLimit)
+		if !ok {
+			continue
+		}
+
+		digest, found := digests[owner.Email.Address]
+		if !found {
+			digest = &ownerDigest{Owner: owner}
+			digests[owner.Email.Address] = digest
+		}
+
+		digest.Domains = append(digest.Domains, filtered)
+	}
+}
+
+// Send the digests that are due using the channels that support them. Owners in their
+// quiet hours will receive the digest in the next notification. When the alerts are
+// tracked, one record is stored for each domain of the digest
+func notifyDigests(digests map[string]*ownerDigest, notifiers []Notifier,
+	notificationDAO dao.NotificationStorage, now time.Time) {
+
+	expirationLimit := now.Add(time.Duration(
+		config.ShelterConfig.Scan.VerificationIntervals.MaxExpirationAlertDays*24) * time.Hour)
+
+	for email, digest := range digests {
+		if digest.Owner.QuietHours.Contains(now) {
+			log.Debugf("Owner %s is in quiet hours, postponing the digest", email)
+			continue
+		}
+
+		if !digestDue(digest.Owner, notificationDAO, now) {
+			continue
+		}
+
+		for _, notifier := range notifiers {
+			digestNotifier, ok := notifier.(DigestNotifier)
+			if !ok {
+				continue
+			}
+
+			notification := digestNotifier.NotifyDigest(digest.Owner, digest.Domains)
+			for _, domain := range digest.Domains {
+				storeNotifications(domain.FQDN, []model.Notification{notification},
+					model.NotificationProblems(domain, expirationLimit), false, now, notificationDAO)
+			}
+		}
+	}
+}
+
+// Check if the period of the owner's digest passed since the last digest delivered. When
+// the alerts aren't tracked the digest is always sent
+func digestDue(owner model.Owner, notificationDAO dao.NotificationStorage, now time.Time) bool {
+	if notificationDAO == nil {
+		return true
+	}
+
+	last, err := notificationDAO.FindLastDigest(owner.Email.Address)
+	if err == mgo.ErrNotFound {
+		return true
+
+	} else if err != nil {
+		// We prefer to alert the owner again than to lose an alert
+		log.Printf("Error retrieving last digest of owner %s. Details: %s",
+			owner.Email.Address, err)
+		return true
+	}
+
+	if now.Sub(last.SentAt) < owner.Digest.Period()-digestTolerance {
+		log.Debugf("Owner %s already received a digest at %s",
+			owner.Email.Address, last.SentAt.Format(time.RFC3339))
+		return false
+	}
+
+	return true
+}
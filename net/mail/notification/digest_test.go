@@ -0,0 +1,69 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package notification is the notification service
+package notification
+
+import (
+	"net/mail"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/shelter/config"
+	"github.com/rafaeljusto/shelter/model"
+)
+
+func TestCollectDigests(t *testing.T) {
+	config.ShelterConfig.Scan.VerificationIntervals.MaxExpirationAlertDays = 10
+
+	immediate := model.Owner{Email: &mail.Address{Address: "immediate@example.com.br"}}
+	daily := model.Owner{Email: &mail.Address{Address: "daily@example.com.br"}, Digest: model.DigestDaily}
+	weeklyLame := model.Owner{
+		Email:        &mail.Address{Address: "weekly@example.com.br"},
+		Digest:       model.DigestWeekly,
+		ProblemTypes: []model.ProblemType{model.ProblemTypeLame},
+	}
+
+	domains := []model.Domain{
+		{
+			FQDN: "example1.com.br.",
+			Nameservers: []model.Nameserver{
+				{Host: "ns1.example1.com.br.", LastStatus: model.NameserverStatusTimeout},
+			},
+			Owners: []model.Owner{immediate, daily, weeklyLame},
+		},
+		{
+			FQDN: "example2.com.br.",
+			Nameservers: []model.Nameserver{
+				{Host: "ns1.example2.com.br.", LastStatus: model.NameserverStatusNoAuthority},
+			},
+			Owners: []model.Owner{daily, weeklyLame},
+		},
+	}
+
+	digests := make(map[string]*ownerDigest)
+	for i := range domains {
+		collectDigests(&domains[i], digests, time.Now())
+	}
+
+	if len(digests) != 2 {
+		t.Fatalf("Expected 2 digests and got %d", len(digests))
+	}
+
+	if digest := digests["daily@example.com.br"]; digest == nil || len(digest.Domains) != 2 {
+		t.Error("Not grouping all domains of the owner in the digest")
+	}
+
+	digest := digests["weekly@example.com.br"]
+	if digest == nil || len(digest.Domains) != 1 || digest.Domains[0].FQDN != "example2.com.br." {
+		t.Error("Adding domains without the problems that the owner wants to the digest")
+	}
+}
+
+func TestDigestDueWithoutDatabase(t *testing.T) {
+	owner := model.Owner{Email: &mail.Address{Address: "daily@example.com.br"}, Digest: model.DigestDaily}
+	if !digestDue(owner, nil, time.Now()) {
+		t.Error("Not sending the digest when the alerts aren't tracked")
+	}
+}
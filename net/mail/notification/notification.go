@@ -6,22 +6,16 @@
 package notification
 
 import (
-	"bytes"
 	"errors"
-	"fmt"
-	"net/smtp"
 	"regexp"
 	"runtime"
-	"strings"
 	"time"
 
 	"github.com/rafaeljusto/shelter/config"
 	"github.com/rafaeljusto/shelter/dao"
-	"github.com/rafaeljusto/shelter/database/mongodb"
+	"github.com/rafaeljusto/shelter/database"
 	"github.com/rafaeljusto/shelter/log"
 	"github.com/rafaeljusto/shelter/model"
-	"github.com/rafaeljusto/shelter/net/mail/notification/protocol"
-	"github.com/rafaeljusto/shelter/secret"
 )
 
 // List of possible errors that can occur when calling functions from this file. Other
@@ -59,31 +53,24 @@ func Notify() {
 		log.Info("End notification job")
 	}()
 
-	log.Debugf("Initializing database with the parameters: URIS - %v | Name - %s | Auth - %t | Username - %s",
-		config.ShelterConfig.Database.URIs,
-		config.ShelterConfig.Database.Name,
-		config.ShelterConfig.Database.Auth.Enabled,
-		config.ShelterConfig.Database.Auth.Username,
-	)
-
-	database, databaseSession, err := mongodb.Open(
-		config.ShelterConfig.Database.URIs,
-		config.ShelterConfig.Database.Name,
-		config.ShelterConfig.Database.Auth.Enabled,
-		config.ShelterConfig.Database.Auth.Username,
-		config.ShelterConfig.Database.Auth.Password,
-	)
+	notifiers, err := newNotifiers()
+	if err != nil {
+		log.Println("Error while initializing notifiers. Details:", err)
+		return
+	}
 
+	storage, _, databaseSession, err := database.Open()
 	if err != nil {
 		log.Println("Error while initializing database. Details:", err)
 		return
 	}
-	defer databaseSession.Close()
 
-	domainDAO := dao.DomainDAO{
-		Database: database,
+	if databaseSession != nil {
+		defer databaseSession.Close()
 	}
 
+	domainDAO := storage.DomainDAO()
+
 	domainChannel, err := domainDAO.FindAllAsyncToBeNotified(
 		config.ShelterConfig.Notification.NameserverErrorAlertDays,
 		config.ShelterConfig.Notification.NameserverTimeoutAlertDays,
@@ -100,6 +87,11 @@ func Notify() {
 		return
 	}
 
+	notificationDAO := storage.NotificationDAO()
+
+	// Domains of the owners that receive digests, grouped by the owner's e-mail
+	digests := make(map[string]*ownerDigest)
+
 	// Dispatch the asynchronous part of the method
 	for {
 		// Get domain from the database (one-by-one)
@@ -117,108 +109,93 @@ func Notify() {
 			break
 		}
 
-		if err := notifyDomain(domainResult.Domain); err != nil {
-			log.Println("Error notifying a domain. Details:", err)
-		}
-	}
-}
-
-// Function used to notify a single domain. It can return error if there's a problem while
-// filling the template or sending the e-mail
-func notifyDomain(domain *model.Domain) error {
-	from := config.ShelterConfig.Notification.From
-
-	emailsPerLanguage := make(map[string][]string)
-	for _, owner := range domain.Owners {
-		emailsPerLanguage[owner.Language] =
-			append(emailsPerLanguage[owner.Language], owner.Email.Address)
+		notifyDomain(domainResult.Domain, notifiers, notificationDAO)
+		collectDigests(domainResult.Domain, digests, time.Now())
 	}
 
-	if len(emailsPerLanguage) == 0 {
-		log.Infof("There's no owner to notify domain %s", domain.FQDN)
-	}
+	notifyDigests(digests, notifiers, notificationDAO, time.Now())
+}
 
-	server := fmt.Sprintf("%s:%d",
-		config.ShelterConfig.Notification.SMTPServer.Server,
-		config.ShelterConfig.Notification.SMTPServer.Port,
-	)
+// Function used to notify a single domain using all enabled channels. A failure in one
+// channel doesn't stop the others, and each failure is logged with the domain. When the
+// alerts are tracked, the recipients that already received an alert with the same
+// problems inside the re-notify interval are skipped, and all alerts sent are stored
+func notifyDomain(domain *model.Domain, notifiers []Notifier, notificationDAO dao.NotificationStorage) {
+	now := time.Now()
+	expirationLimit := now.Add(time.Duration(
+		config.ShelterConfig.Scan.VerificationIntervals.MaxExpirationAlertDays*24) * time.Hour)
+	renotifyInterval := time.Duration(
+		config.ShelterConfig.Notification.RenotifyIntervalHours) * time.Hour
+
+	problems := model.NotificationProblems(*domain, expirationLimit)
+
+	for _, notifier := range notifiers {
+		skip := make(map[string]bool)
+
+		if notificationDAO != nil && renotifyInterval > 0 {
+			notifications, err := notificationDAO.FindDelivered(domain.FQDN, notifier.Name(),
+				now.Add(-renotifyInterval))
+
+			if err != nil {
+				// We prefer to alert the owners again than to lose an alert
+				log.Printf("Error retrieving notifications of domain %s via %s. Details: %s",
+					domain.FQDN, notifier.Name(), err)
+			}
 
-	password := config.ShelterConfig.Notification.SMTPServer.Auth.Password
-	if len(password) > 0 {
-		var err error
-		password, err = secret.Decrypt(password)
-		if err != nil {
-			return err
+			// Only the most recent alert of each recipient is considered, so a recipient is
+			// alerted again when the problems changed after its last alert
+			checked := make(map[string]bool)
+			for _, notification := range notifications {
+				for _, recipient := range notification.Recipients {
+					if checked[recipient] {
+						continue
+					}
+
+					checked[recipient] = true
+					if model.SameProblems(notification.Problems, problems) {
+						log.Debugf("Domain %s was already notified via %s to %s at %s with the same problems",
+							domain.FQDN, notifier.Name(), recipient, notification.SentAt.Format(time.RFC3339))
+
+						skip[recipient] = true
+					}
+				}
+			}
 		}
+
+		storeNotifications(domain.FQDN, notifier.Notify(domain, skip), problems, false, now,
+			notificationDAO)
 	}
+}
 
-	for language, emails := range emailsPerLanguage {
-		t := getTemplate(language)
-		if t == nil {
-			return ErrTemplateNotFound
-		}
+// Log the delivery failures of the alerts sent to a domain and store them when the alerts
+// are tracked. The postponed alerts are also stored, so they can be delivered after the
+// quiet hours
+func storeNotifications(fqdn string, notifications []model.Notification, problems []string,
+	recovery bool, sentAt time.Time, notificationDAO dao.NotificationStorage) {
 
-		domainMail := protocol.Domain{
-			Domain: *domain,
-			From:   config.ShelterConfig.Notification.From,
-			To:     strings.Join(emails, ","),
-			Date:   FormatDate(time.Now()),
+	for _, notification := range notifications {
+		if !notification.Delivered && !notification.Postponed {
+			log.Printf("Error notifying domain %s via %s to %v. Details: %s",
+				fqdn, notification.Channel, notification.Recipients, notification.Error)
 		}
 
-		var msg bytes.Buffer
-		if err := t.ExecuteTemplate(&msg, "notification", domainMail); err != nil {
-			return err
+		if notificationDAO == nil {
+			continue
 		}
 
-		// Remove extra new lines that can appear because of the template execution. Special
-		// lines used for controlling the templates are removed but the new lines are left
-		// behind
-		msgBytes := bytes.TrimSpace(msg.Bytes())
-		msgBytes = extraSpaces.ReplaceAll(msgBytes, []byte("\n\n"))
-
-		switch config.ShelterConfig.Notification.SMTPServer.Auth.Type {
-		case config.AuthenticationTypePlain:
-			log.Debugf("Sending notification for domain %s to %v via server %s with plain authentication",
-				domain.FQDN, emails, server)
-
-			auth := smtp.PlainAuth("",
-				config.ShelterConfig.Notification.SMTPServer.Auth.Username,
-				password,
-				config.ShelterConfig.Notification.SMTPServer.Server,
-			)
-
-			if err := smtp.SendMail(server, auth, from, emails, msgBytes); err != nil {
-				return err
-			}
-
-		case config.AuthenticationTypeCRAMMD5Auth:
-			log.Debugf("Sending notification for domain %s to %v via server %s with CRAM MD5 authentication",
-				domain.FQDN, emails, server)
-
-			auth := smtp.CRAMMD5Auth(
-				config.ShelterConfig.Notification.SMTPServer.Auth.Username,
-				password,
-			)
-
-			if err := smtp.SendMail(server, auth, from, emails, msgBytes); err != nil {
-				return err
-			}
-
-		default:
-			log.Debugf("Sending notification for domain %s to %v via server %s without authentication",
-				domain.FQDN, emails, server)
+		notification.FQDN = fqdn
+		notification.Problems = problems
+		notification.Recovery = recovery
+		notification.SentAt = sentAt
 
-			if err := smtp.SendMail(server, nil, from, emails, msgBytes); err != nil {
-				return err
-			}
+		if err := notificationDAO.Save(&notification); err != nil {
+			log.Printf("Error storing notification of domain %s via %s. Details: %s",
+				fqdn, notification.Channel, err)
 		}
 	}
-
-	return nil
 }
 
 // FormatDate returns a compliant RFC5322 datetime
 func FormatDate(datetime time.Time) string {
-    return date.Format(datetime.RFC1123Z)
+	return datetime.Format(time.RFC1123Z)
 }
-
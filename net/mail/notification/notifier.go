@@ -0,0 +1,59 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package notification is the notification service
+package notification
+
+import (
+	"github.com/rafaeljusto/shelter/config"
+	"github.com/rafaeljusto/shelter/model"
+)
+
+// Notifier is a channel used to alert the owners about the problems of a domain. The
+// e-mail is always used, and other channels can be enabled in the configuration file
+type Notifier interface {
+	// Name identifies the channel in the log messages
+	Name() string
+
+	// Notify sends the alert of the domain. The recipients in skip were already alerted
+	// about the same problems and must be ignored. It returns one record for each group of
+	// recipients, informing if the alert was delivered to them
+	Notify(domain *model.Domain, skip map[string]bool) []model.Notification
+
+	// NotifyRecovery informs that problems of the domain were solved. It returns one record
+	// for each group of recipients, informing if the alert was delivered to them
+	NotifyRecovery(recovery *model.DomainRecovery) []model.Notification
+}
+
+// DigestNotifier is a channel that can group all domains with problems of an owner in a
+// single alert. It is used for the owners that don't want to be alerted immediately
+type DigestNotifier interface {
+	Notifier
+
+	// NotifyDigest sends the alert with all domains of the owner. It returns the record
+	// informing if the alert was delivered
+	NotifyDigest(owner model.Owner, domains []model.Domain) model.Notification
+}
+
+// Build the list of notifiers enabled in the configuration file. It can return errors
+// when there's a problem decrypting the secrets of the notifiers
+func newNotifiers() ([]Notifier, error) {
+	smtpNotifier, err := NewSMTPNotifier()
+	if err != nil {
+		return nil, err
+	}
+
+	notifiers := []Notifier{smtpNotifier}
+
+	if config.ShelterConfig.Notification.Webhook.Enabled {
+		webhookNotifier, err := NewWebhookNotifier()
+		if err != nil {
+			return nil, err
+		}
+
+		notifiers = append(notifiers, webhookNotifier)
+	}
+
+	return notifiers, nil
+}
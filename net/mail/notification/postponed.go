@@ -0,0 +1,92 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package notification is the notification service
+package notification
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/database"
+	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
+)
+
+const (
+	// Interval between the deliveries of the postponed alerts. The owners receive the
+	// alerts at most this time after the end of their quiet hours
+	PostponedInterval = 15 * time.Minute
+)
+
+// NotifyPostponed delivers the e-mails that were postponed because the owners were in their
+// quiet hours. It should run periodically, as the notification job runs only a few times a
+// day and could always fall in the same quiet period
+func NotifyPostponed() {
+	defer func() {
+		// Something went really wrong while delivering the alerts. Log the error stacktrace
+		// and move out
+		if r := recover(); r != nil {
+			const size = 64 << 10
+			buf := make([]byte, size)
+			buf = buf[:runtime.Stack(buf, false)]
+			log.Printf("Panic detected while delivering postponed alerts. Details: %v\n%s", r, buf)
+		}
+	}()
+
+	smtpNotifier, err := NewSMTPNotifier()
+	if err != nil {
+		log.Println("Error while initializing the e-mail notifier. Details:", err)
+		return
+	}
+
+	storage, _, databaseSession, err := database.Open()
+	if err != nil {
+		log.Println("Error while initializing database. Details:", err)
+		return
+	}
+
+	if databaseSession != nil {
+		defer databaseSession.Close()
+	}
+
+	notifyPostponed(smtpNotifier, storage.NotificationDAO(), time.Now())
+}
+
+// Deliver the postponed alerts whose quiet hours ended before now. The result of each
+// delivery is stored in a new record, replacing the postponed one. The postponed record is
+// removed before the delivery, because we prefer to lose an alert than to send it on
+// every execution when the database has problems
+func notifyPostponed(notifier SMTPNotifier, notificationDAO dao.NotificationStorage, now time.Time) {
+	postponedNotifications, err := notificationDAO.FindPostponed(now)
+	if err != nil {
+		log.Println("Error retrieving postponed alerts. Details:", err)
+		return
+	}
+
+	for _, postponed := range postponedNotifications {
+		if err := notificationDAO.Remove(&postponed); err != nil {
+			log.Printf("Error removing postponed alert of domain %s. Details: %s",
+				postponed.FQDN, err)
+			continue
+		}
+
+		notification := model.Notification{
+			Channel:    postponed.Channel,
+			Recipients: postponed.Recipients,
+			Language:   postponed.Language,
+		}
+
+		if err := notifier.deliver(postponed.FQDN, postponed.Recipients, postponed.Message); err == nil {
+			notification.Delivered = true
+		} else {
+			notification.Error = err.Error()
+		}
+
+		storeNotifications(postponed.FQDN, []model.Notification{notification}, postponed.Problems,
+			postponed.Recovery, now, notificationDAO)
+
+	}
+}
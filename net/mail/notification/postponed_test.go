@@ -0,0 +1,91 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package notification is the notification service
+package notification
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/database/file"
+	"github.com/rafaeljusto/shelter/model"
+)
+
+func TestNotifyPostponed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shelter-notification-postponed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	database, err := file.Open(filepath.Join(dir, "shelter.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notificationDAO := dao.FileNotificationDAO{Database: database}
+
+	now := time.Now()
+	postponedNotifications := []model.Notification{
+		{
+			FQDN:           "example.com.br.",
+			Channel:        "smtp",
+			Recipients:     []string{"admin@example.com.br"},
+			Problems:       []string{"nameserver ns1.example.com.br. TIMEOUT"},
+			Postponed:      true,
+			PostponedUntil: now.Add(-time.Minute),
+			Message:        []byte("To: admin@example.com.br"),
+		},
+		{
+			FQDN:           "example2.com.br.",
+			Channel:        "smtp",
+			Recipients:     []string{"admin@example.com.br"},
+			Postponed:      true,
+			PostponedUntil: now.Add(time.Hour),
+			Message:        []byte("To: admin@example.com.br"),
+		},
+	}
+
+	for i := range postponedNotifications {
+		if err := notificationDAO.Save(&postponedNotifications[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Use a port without SMTP server, so the delivery fails without sending e-mails
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	notifier := SMTPNotifier{Server: "127.0.0.1", Port: port}
+	notifyPostponed(notifier, notificationDAO, now)
+
+	if postponed, err := notificationDAO.FindPostponed(now.Add(2 * time.Hour)); err != nil ||
+		len(postponed) != 1 || postponed[0].FQDN != "example2.com.br." {
+
+		t.Errorf("Not removing only the postponed alerts after the quiet hours: %#v", postponed)
+	}
+
+	var pagination dao.NotificationDAOPagination
+	notifications, err := notificationDAO.FindByFQDN("example.com.br.", &pagination)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(notifications) != 1 || notifications[0].Postponed || notifications[0].Delivered ||
+		len(notifications[0].Error) == 0 || len(notifications[0].Message) > 0 ||
+		len(notifications[0].Problems) != 1 || !notifications[0].SentAt.Equal(now) {
+
+		t.Errorf("Not storing the result of the postponed alert: %#v", notifications)
+	}
+}
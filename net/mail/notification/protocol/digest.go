@@ -0,0 +1,20 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the objects used in e-mail templates
+package protocol
+
+import (
+	"github.com/rafaeljusto/shelter/model"
+)
+
+// Struct created to add the extra information necessary to build the digest block of the
+// e-mail template, that alerts the owner about all domains with problems at once
+type Digest struct {
+	Owner   model.Owner    // Owner that will receive the digest
+	Domains []model.Domain // Domains only with the problems that the owner wants to be alerted
+	From    string         // E-mails from header
+	To      string         // Owner's e-mail
+	Date    string         // E-mails date header (RFC 5322)
+}
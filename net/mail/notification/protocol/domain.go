@@ -15,4 +15,5 @@ type Domain struct {
 	model.Domain        // Domain object
 	From         string // E-mails from header
 	To           string // List of owner's e-mails to be alerted
+	Date         string // E-mails date header (RFC 5322)
 }
@@ -0,0 +1,19 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the objects used in e-mail templates
+package protocol
+
+import (
+	"github.com/rafaeljusto/shelter/model"
+)
+
+// Struct created to add the extra information necessary to build the recovery block of
+// the e-mail template, that informs the domain's owners that the problems were solved
+type Recovery struct {
+	model.DomainRecovery        // Domain and the previous state of the solved problems
+	From                 string // E-mails from header
+	To                   string // List of owner's e-mails to be informed
+	Date                 string // E-mails date header (RFC 5322)
+}
@@ -0,0 +1,44 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package protocol describes the objects used in e-mail templates
+package protocol
+
+import (
+	"github.com/rafaeljusto/shelter/model"
+	restprotocol "github.com/rafaeljusto/shelter/net/http/rest/protocol"
+	"time"
+)
+
+// List of possible events sent to the webhooks
+const (
+	WebhookEventMisconfiguration = "misconfiguration" // Domain has DNS/DNSSEC problems
+	WebhookEventRecovery         = "recovery"         // Problems of the domain were solved
+)
+
+// Webhook is the JSON content sent to the webhooks. The domain uses the same format of
+// the REST server, so the same parser can be used by the receiver
+type Webhook struct {
+	Event  string                      `json:"event"`            // Reason of the notification
+	Date   time.Time                   `json:"date"`             // When the notification was generated
+	Domain restprotocol.DomainResponse `json:"domain"`           // Domain being notified
+	Solved []string                    `json:"solved,omitempty"` // Problems solved, only in recovery events
+}
+
+// NewWebhook builds the content of the webhook for the domain. The owners' webhooks are
+// removed from the content, because the same content is sent to all webhooks of the
+// domain and we don't want to expose one owner's endpoint to another
+func NewWebhook(event string, domain model.Domain) Webhook {
+	webhook := Webhook{
+		Event:  event,
+		Date:   time.Now(),
+		Domain: restprotocol.ToDomainResponse(domain, true),
+	}
+
+	for i := range webhook.Domain.Owners {
+		webhook.Domain.Owners[i].Webhook = ""
+	}
+
+	return webhook
+}
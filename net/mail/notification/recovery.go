@@ -0,0 +1,99 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package notification is the notification service
+package notification
+
+import (
+	"time"
+
+	"github.com/rafaeljusto/shelter/config"
+	"github.com/rafaeljusto/shelter/database"
+	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
+)
+
+// NotifyRecoveries informs the domains' owners that problems detected in previous scans
+// were solved. Only the problems that lasted enough to be alerted are informed, so the
+// owners don't receive a recovery alert of a problem that they never heard of
+func NotifyRecoveries(recoveries []model.DomainRecovery) {
+	log.Info("Start recovery notification")
+	defer func() {
+		log.Info("End recovery notification")
+	}()
+
+	notifiers, err := newNotifiers()
+	if err != nil {
+		log.Println("Error while initializing notifiers. Details:", err)
+		return
+	}
+
+	storage, _, databaseSession, err := database.Open()
+	if err != nil {
+		log.Println("Error while initializing database. Details:", err)
+		return
+	}
+
+	if databaseSession != nil {
+		defer databaseSession.Close()
+	}
+
+	notificationDAO := storage.NotificationDAO()
+
+	now := time.Now()
+
+	for _, recovery := range recoveries {
+		recovery = alertedRecovery(recovery, now)
+		if recovery.Empty() {
+			continue
+		}
+
+		problems := recovery.Problems()
+
+		for _, notifier := range notifiers {
+			storeNotifications(recovery.Domain.FQDN, notifier.NotifyRecovery(&recovery),
+				problems, true, now, notificationDAO)
+		}
+	}
+}
+
+// Remove from the recovery the problems that didn't last enough to be alerted, using the
+// same tolerance of the notification job. The previous state of the nameservers and DS
+// records keeps the last time that they were OK before the problem
+func alertedRecovery(recovery model.DomainRecovery, now time.Time) model.DomainRecovery {
+	notificationConfig := config.ShelterConfig.Notification
+
+	nameserverErrorLimit := now.Add(time.Duration(-notificationConfig.NameserverErrorAlertDays*24) * time.Hour)
+	nameserverTimeoutLimit := now.Add(time.Duration(-notificationConfig.NameserverTimeoutAlertDays*24) * time.Hour)
+	dsErrorLimit := now.Add(time.Duration(-notificationConfig.DSErrorAlertDays*24) * time.Hour)
+	dsTimeoutLimit := now.Add(time.Duration(-notificationConfig.DSTimeoutAlertDays*24) * time.Hour)
+
+	alerted := model.DomainRecovery{
+		Domain: recovery.Domain,
+	}
+
+	for _, nameserver := range recovery.Nameservers {
+		limit := nameserverErrorLimit
+		if nameserver.LastStatus == model.NameserverStatusTimeout {
+			limit = nameserverTimeoutLimit
+		}
+
+		if !nameserver.LastOKAt.After(limit) {
+			alerted.Nameservers = append(alerted.Nameservers, nameserver)
+		}
+	}
+
+	for _, ds := range recovery.DSSet {
+		limit := dsErrorLimit
+		if ds.LastStatus == model.DSStatusTimeout {
+			limit = dsTimeoutLimit
+		}
+
+		if !ds.LastOKAt.After(limit) {
+			alerted.DSSet = append(alerted.DSSet, ds)
+		}
+	}
+
+	return alerted
+}
@@ -0,0 +1,71 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package notification is the notification service
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/shelter/config"
+	"github.com/rafaeljusto/shelter/model"
+)
+
+func TestAlertedRecovery(t *testing.T) {
+	config.ShelterConfig.Notification.NameserverErrorAlertDays = 7
+	config.ShelterConfig.Notification.NameserverTimeoutAlertDays = 30
+	config.ShelterConfig.Notification.DSErrorAlertDays = 1
+	config.ShelterConfig.Notification.DSTimeoutAlertDays = 7
+
+	now := time.Now()
+	day := 24 * time.Hour
+
+	recovery := model.DomainRecovery{
+		Domain: model.Domain{FQDN: "example.com.br."},
+		Nameservers: []model.Nameserver{
+			{
+				Host:       "ns1.example.com.br.",
+				LastStatus: model.NameserverStatusServerFailure,
+				LastOKAt:   now.Add(-8 * day),
+			},
+			{
+				Host:       "ns2.example.com.br.",
+				LastStatus: model.NameserverStatusServerFailure,
+				LastOKAt:   now.Add(-2 * day),
+			},
+			{
+				Host:       "ns3.example.com.br.",
+				LastStatus: model.NameserverStatusTimeout,
+				LastOKAt:   now.Add(-8 * day),
+			},
+		},
+		DSSet: []model.DS{
+			{
+				Keytag:     1234,
+				LastStatus: model.DSStatusExpiredSignature,
+				LastOKAt:   now.Add(-2 * day),
+			},
+			{
+				Keytag:     4321,
+				LastStatus: model.DSStatusTimeout,
+				LastOKAt:   now.Add(-2 * day),
+			},
+		},
+	}
+
+	alerted := alertedRecovery(recovery, now)
+
+	if alerted.Domain.FQDN != "example.com.br." {
+		t.Error("Not keeping the domain in the recovery")
+	}
+
+	if len(alerted.Nameservers) != 1 || alerted.Nameservers[0].Host != "ns1.example.com.br." {
+		t.Errorf("Not filtering the nameservers that were alerted: %#v", alerted.Nameservers)
+	}
+
+	if len(alerted.DSSet) != 1 || alerted.DSSet[0].Keytag != 1234 {
+		t.Errorf("Not filtering the DS records that were alerted: %#v", alerted.DSSet)
+	}
+}
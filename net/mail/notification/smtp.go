@@ -0,0 +1,303 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package notification is the notification service
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/rafaeljusto/shelter/config"
+	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/mail/notification/protocol"
+	"github.com/rafaeljusto/shelter/secret"
+)
+
+// SMTPNotifier sends the alerts by e-mail, using the templates of each owner's language
+type SMTPNotifier struct {
+	From     string                    // E-mails from header
+	Server   string                    // Name or IP address of the SMTP server
+	Port     int                       // Port of the SMTP server
+	AuthType config.AuthenticationType // Type of authentication in the SMTP server
+	Username string                    // Username used for authentication
+	Password string                    // Password (decrypted) used for authentication
+}
+
+// NewSMTPNotifier builds the e-mail notifier using the configuration file. It can return
+// an error if the password of the SMTP server couldn't be decrypted
+func NewSMTPNotifier() (SMTPNotifier, error) {
+	notifier := SMTPNotifier{
+		From:     config.ShelterConfig.Notification.From,
+		Server:   config.ShelterConfig.Notification.SMTPServer.Server,
+		Port:     config.ShelterConfig.Notification.SMTPServer.Port,
+		AuthType: config.ShelterConfig.Notification.SMTPServer.Auth.Type,
+		Username: config.ShelterConfig.Notification.SMTPServer.Auth.Username,
+		Password: config.ShelterConfig.Notification.SMTPServer.Auth.Password,
+	}
+
+	if len(notifier.Password) > 0 {
+		var err error
+		notifier.Password, err = secret.Decrypt(notifier.Password)
+		if err != nil {
+			return notifier, err
+		}
+	}
+
+	return notifier, nil
+}
+
+func (n SMTPNotifier) Name() string {
+	return "smtp"
+}
+
+// Notify sends one e-mail for each language of the domain's owners that want to be
+// alerted immediately. The e-mails of owners in their quiet hours are postponed, and owners
+// that chose the types of problems receive only them. A problem while filling the template or sending
+// the e-mail of one group doesn't stop the others, it is only stored in the group's record
+func (n SMTPNotifier) Notify(domain *model.Domain, skip map[string]bool) []model.Notification {
+	now := time.Now()
+	expirationLimit := now.Add(time.Duration(
+		config.ShelterConfig.Scan.VerificationIntervals.MaxExpirationAlertDays*24) * time.Hour)
+
+	groups := groupOwners(domain, now, func(owner model.Owner) (model.Domain, bool) {
+		if skip[owner.Email.Address] {
+			return *domain, false
+		}
+
+		// Owners without preferences receive the whole domain, as the templates only
+		// describe the nameservers and DS records with problems
+		if len(owner.ProblemTypes) == 0 {
+			return *domain, true
+		}
+
+		return owner.FilterProblems(*domain, expirationLimit)
+	})
+
+	return n.notifyOwners(domain.FQDN, "notification", groups, func(domain model.Domain, to string) interface{} {
+		return protocol.Domain{
+			Domain: domain,
+			From:   n.From,
+			To:     to,
+			Date:   FormatDate(time.Now()),
+		}
+	})
+}
+
+// NotifyRecovery sends one e-mail for each language of the domain's owners that want to
+// be alerted immediately using the recovery block of the templates. The owners that
+// receive digests will notice that the problems were solved in the next digest
+func (n SMTPNotifier) NotifyRecovery(recovery *model.DomainRecovery) []model.Notification {
+	groups := groupOwners(&recovery.Domain, time.Now(), func(owner model.Owner) (model.Domain, bool) {
+		return recovery.Domain, true
+	})
+
+	return n.notifyOwners(recovery.Domain.FQDN, "recovery", groups, func(domain model.Domain, to string) interface{} {
+		return protocol.Recovery{
+			DomainRecovery: *recovery,
+			From:           n.From,
+			To:             to,
+			Date:           FormatDate(time.Now()),
+		}
+	})
+}
+
+// NotifyDigest sends one e-mail to the owner with all the domains with problems, using the
+// digest block of the owner's language template
+func (n SMTPNotifier) NotifyDigest(owner model.Owner, domains []model.Domain) model.Notification {
+	notification := model.Notification{
+		Channel:    n.Name(),
+		Recipients: []string{owner.Email.Address},
+		Language:   owner.Language,
+		Digest:     true,
+	}
+
+	err := n.send(owner.Email.Address, owner.Language, "digest", notification.Recipients,
+		protocol.Digest{
+			Owner:   owner,
+			Domains: domains,
+			From:    n.From,
+			To:      owner.Email.Address,
+			Date:    FormatDate(time.Now()),
+		})
+
+	if err == nil {
+		notification.Delivered = true
+	} else {
+		notification.Error = err.Error()
+	}
+
+	return notification
+}
+
+// ownersGroup stores the owners that will receive the same e-mail
+type ownersGroup struct {
+	language       string       // Language of the template
+	domain         model.Domain // Domain with the problems that the owners want to be alerted
+	emails         []string     // E-mails of the owners
+	postponedUntil time.Time    // End of the quiet hours when the e-mail must be postponed
+}
+
+// Group the owners of the domain that want to be alerted immediately by language and by
+// the problems that they want to receive. Each owner in the quiet hours has its own group,
+// because the e-mail will be postponed until the end of the owner's quiet period. The
+// selection function returns the domain that the owner will receive or false when the
+// owner must be ignored
+func groupOwners(domain *model.Domain, now time.Time,
+	selection func(owner model.Owner) (model.Domain, bool)) []*ownersGroup {
+
+	var groups []*ownersGroup
+	groupsByKey := make(map[string]*ownersGroup)
+
+	for _, owner := range domain.Owners {
+		if owner.Digest != model.DigestImmediate {
+			continue
+		}
+
+		ownerDomain, ok := selection(owner)
+		if !ok {
+			continue
+		}
+
+		if owner.QuietHours.Contains(now) {
+			log.Debugf("Owner %s of domain %s is in quiet hours", owner.Email.Address, domain.FQDN)
+
+			groups = append(groups, &ownersGroup{
+				language:       owner.Language,
+				domain:         ownerDomain,
+				emails:         []string{owner.Email.Address},
+				postponedUntil: owner.QuietHours.EndAfter(now),
+			})
+
+			continue
+		}
+
+		key := owner.Language
+		for _, problemType := range owner.ProblemTypes {
+			key += " " + model.ProblemTypeToString(problemType)
+		}
+
+		group, found := groupsByKey[key]
+		if !found {
+			group = &ownersGroup{
+				language: owner.Language,
+				domain:   ownerDomain,
+			}
+
+			groupsByKey[key] = group
+			groups = append(groups, group)
+		}
+
+		group.emails = append(group.emails, owner.Email.Address)
+	}
+
+	return groups
+}
+
+// Send the e-mails of the domain for each group of owners. The content of the template is
+// built for each group of recipients. The e-mails of the groups in quiet hours are only
+// built, and returned in postponed records to be delivered later
+func (n SMTPNotifier) notifyOwners(fqdn, templateName string, groups []*ownersGroup,
+	content func(domain model.Domain, to string) interface{}) []model.Notification {
+
+	if len(groups) == 0 {
+		log.Infof("There's no owner to notify domain %s", fqdn)
+	}
+
+	var notifications []model.Notification
+	for _, group := range groups {
+		notification := model.Notification{
+			Channel:    n.Name(),
+			Recipients: group.emails,
+			Language:   group.language,
+		}
+
+		msg, err := n.build(group.language, templateName,
+			content(group.domain, strings.Join(group.emails, ",")))
+
+		if err == nil && !group.postponedUntil.IsZero() {
+			notification.Postponed = true
+			notification.PostponedUntil = group.postponedUntil
+			notification.Message = msg
+
+		} else {
+			if err == nil {
+				err = n.deliver(fqdn, group.emails, msg)
+			}
+
+			if err == nil {
+				notification.Delivered = true
+			} else {
+				notification.Error = err.Error()
+			}
+		}
+
+		notifications = append(notifications, notification)
+	}
+
+	return notifications
+}
+
+// Send the e-mail to the owners of the same language, using the given block of the
+// language's template. The subject identifies the e-mail in the log messages
+func (n SMTPNotifier) send(subject, language, templateName string, emails []string,
+	content interface{}) error {
+
+	msg, err := n.build(language, templateName, content)
+	if err != nil {
+		return err
+	}
+
+	return n.deliver(subject, emails, msg)
+}
+
+// Build the e-mail using the given block of the language's template
+func (n SMTPNotifier) build(language, templateName string, content interface{}) ([]byte, error) {
+	t := getTemplate(language)
+	if t == nil || t.Lookup(templateName) == nil {
+		return nil, ErrTemplateNotFound
+	}
+
+	var msg bytes.Buffer
+	if err := t.ExecuteTemplate(&msg, templateName, content); err != nil {
+		return nil, err
+	}
+
+	// Remove extra new lines that can appear because of the template execution. Special
+	// lines used for controlling the templates are removed but the new lines are left
+	// behind
+	msgBytes := bytes.TrimSpace(msg.Bytes())
+	return extraSpaces.ReplaceAll(msgBytes, []byte("\n\n")), nil
+}
+
+// Send the e-mail already built to the owners using the SMTP server. The subject
+// identifies the e-mail in the log messages
+func (n SMTPNotifier) deliver(subject string, emails []string, msgBytes []byte) error {
+	server := fmt.Sprintf("%s:%d", n.Server, n.Port)
+
+	switch n.AuthType {
+	case config.AuthenticationTypePlain:
+		log.Debugf("Sending notification of %s to %v via server %s with plain authentication",
+			subject, emails, server)
+
+		auth := smtp.PlainAuth("", n.Username, n.Password, n.Server)
+		return smtp.SendMail(server, auth, n.From, emails, msgBytes)
+
+	case config.AuthenticationTypeCRAMMD5Auth:
+		log.Debugf("Sending notification of %s to %v via server %s with CRAM MD5 authentication",
+			subject, emails, server)
+
+		auth := smtp.CRAMMD5Auth(n.Username, n.Password)
+		return smtp.SendMail(server, auth, n.From, emails, msgBytes)
+	}
+
+	log.Debugf("Sending notification of %s to %v via server %s without authentication",
+		subject, emails, server)
+
+	return smtp.SendMail(server, nil, n.From, emails, msgBytes)
+}
@@ -0,0 +1,113 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package notification is the notification service
+package notification
+
+import (
+	"net/mail"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/mail/notification/protocol"
+)
+
+func TestGroupOwners(t *testing.T) {
+	now := time.Date(2014, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	domain := model.Domain{
+		FQDN: "example.com.br.",
+		Owners: []model.Owner{
+			{Email: &mail.Address{Address: "admin1@example.com.br"}, Language: "en-US"},
+			{Email: &mail.Address{Address: "admin2@example.com.br"}, Language: "en-US"},
+			{Email: &mail.Address{Address: "admin3@example.com.br"}, Language: "pt-BR"},
+			{
+				Email:        &mail.Address{Address: "admin4@example.com.br"},
+				Language:     "en-US",
+				ProblemTypes: []model.ProblemType{model.ProblemTypeLame},
+			},
+			{
+				Email:    &mail.Address{Address: "admin5@example.com.br"},
+				Language: "en-US",
+				Digest:   model.DigestDaily,
+			},
+			{
+				Email:      &mail.Address{Address: "admin6@example.com.br"},
+				Language:   "en-US",
+				QuietHours: model.QuietHours{Start: 11 * 60, End: 13 * 60},
+			},
+		},
+	}
+
+	groups := groupOwners(&domain, now, func(owner model.Owner) (model.Domain, bool) {
+		return domain, owner.Email.Address != "admin2@example.com.br"
+	})
+
+	if len(groups) != 4 {
+		t.Fatalf("Expected 4 groups of owners and got %d", len(groups))
+	}
+
+	if groups[0].language != "en-US" || len(groups[0].emails) != 1 ||
+		groups[0].emails[0] != "admin1@example.com.br" {
+
+		t.Errorf("Not grouping the owners by language correctly: %#v", groups[0])
+	}
+
+	if groups[1].language != "pt-BR" || len(groups[1].emails) != 1 {
+		t.Errorf("Not grouping the owners by language correctly: %#v", groups[1])
+	}
+
+	if len(groups[2].emails) != 1 || groups[2].emails[0] != "admin4@example.com.br" {
+		t.Errorf("Not grouping the owners by problem types correctly: %#v", groups[2])
+	}
+
+	if len(groups[3].emails) != 1 || groups[3].emails[0] != "admin6@example.com.br" ||
+		!groups[3].postponedUntil.Equal(now.Add(time.Hour)) {
+
+		t.Errorf("Not postponing the owner in quiet hours: %#v", groups[3])
+	}
+
+	for _, group := range groups[:3] {
+		if !group.postponedUntil.IsZero() {
+			t.Errorf("Postponing an owner outside the quiet hours: %#v", group)
+		}
+	}
+}
+
+func TestNotifyOwnersPostponed(t *testing.T) {
+	clearTemplates()
+	defer clearTemplates()
+
+	addTemplate("en-US", template.Must(template.New("").Parse(
+		`{{define "notification"}}To: {{.To}}{{end}}`)))
+
+	groups := []*ownersGroup{
+		{
+			language:       "en-US",
+			domain:         model.Domain{FQDN: "example.com.br."},
+			emails:         []string{"admin@example.com.br"},
+			postponedUntil: time.Date(2014, time.March, 10, 7, 0, 0, 0, time.UTC),
+		},
+	}
+
+	var notifier SMTPNotifier
+	notifications := notifier.notifyOwners("example.com.br.", "notification", groups,
+		func(domain model.Domain, to string) interface{} {
+			return protocol.Domain{Domain: domain, To: to}
+		})
+
+	if len(notifications) != 1 {
+		t.Fatalf("Expected 1 notification record and got %d", len(notifications))
+	}
+
+	notification := notifications[0]
+	if notification.Delivered || !notification.Postponed || len(notification.Error) > 0 ||
+		!notification.PostponedUntil.Equal(groups[0].postponedUntil) ||
+		string(notification.Message) != "To: admin@example.com.br" {
+
+		t.Errorf("Not postponing the e-mail of the owner in quiet hours: %#v", notification)
+	}
+}
@@ -81,7 +81,10 @@ func LoadTemplates() error {
 		}
 
 		t, err := template.New("notification").Funcs(template.FuncMap{
+			"nsStatus":         model.NameserverStatusToString,
 			"nsStatusEq":       nameserverStatusEquals,
+			"nsAddressStatus":  nameserverAddressStatus,
+			"dsStatus":         model.DSStatusToString,
 			"dsStatusEq":       dsStatusEquals,
 			"isNearExpiration": isNearExpirationDS,
 		}).Parse(string(templateContent))
@@ -133,6 +136,22 @@ func nameserverStatusEquals(nameserverStatus model.NameserverStatus,
 		strings.TrimSpace(strings.ToLower(expectedNameserverTextStatus))
 }
 
+// Auxiliary function for template that describes the status of each address family
+// (IPv4 and IPv6) of the nameserver. Address families that weren't checked are ignored
+func nameserverAddressStatus(nameserver model.Nameserver) string {
+	var status []string
+
+	if nameserver.LastStatusIPv4 != model.NameserverStatusNotChecked {
+		status = append(status, "IPv4 "+model.NameserverStatusToString(nameserver.LastStatusIPv4))
+	}
+
+	if nameserver.LastStatusIPv6 != model.NameserverStatusNotChecked {
+		status = append(status, "IPv6 "+model.NameserverStatusToString(nameserver.LastStatusIPv6))
+	}
+
+	return strings.Join(status, ", ")
+}
+
 // Auxiliary function for template that compares two DS status (case insensitive)
 func dsStatusEquals(dsStatus model.DSStatus, expectedDSTextStatus string) bool {
 	return strings.ToLower(model.DSStatusToString(dsStatus)) ==
@@ -149,5 +168,5 @@ func isNearExpirationDS(ds model.DS) bool {
 	// well configured DS far away from the expiration date can be selected when the nameserves have
 	// some configuration problems
 	expirationAlert := time.Now().Add(time.Duration(maxExpirationAlertDays*24) * time.Hour)
-	return ds.ExpiresAt.Before(expirationAlert)
+	return ds.ExpiresUntil(expirationAlert)
 }
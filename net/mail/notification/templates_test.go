@@ -6,10 +6,14 @@
 package notification
 
 import (
+	"bytes"
 	"github.com/rafaeljusto/shelter/config"
 	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/mail/notification/protocol"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"text/template"
 	"time"
@@ -33,12 +37,12 @@ func TestLoadTemplates(t *testing.T) {
 	}
 
 	config.ShelterConfig.Notification.TemplatesPath = "."
-	config.ShelterConfig.Languages = []string{file.Name()}
+	config.ShelterConfig.Languages = []string{filepath.Base(file.Name())}
 	if err := LoadTemplates(); err != nil {
 		t.Error(err)
 	}
 
-	config.ShelterConfig.Languages = []string{file.Name() + "idontexist"}
+	config.ShelterConfig.Languages = []string{filepath.Base(file.Name()) + "idontexist"}
 	if err := LoadTemplates(); err == nil {
 		t.Error("Not returnig error when a defined language doesn't have your " +
 			"corresponding template file")
@@ -50,6 +54,114 @@ func TestLoadTemplates(t *testing.T) {
 	}
 }
 
+func TestLoadTemplatesRecoveryBlock(t *testing.T) {
+	clearTemplates()
+	defer clearTemplates()
+
+	TemplateExtension = ".tmpl"
+	defer func() {
+		TemplateExtension = ""
+	}()
+
+	config.ShelterConfig.BasePath = filepath.Join("..", "..", "..")
+	config.ShelterConfig.Notification.TemplatesPath = filepath.Join("templates", "notification")
+	config.ShelterConfig.Languages = []string{"en-US", "pt-BR", "es-ES"}
+
+	if err := LoadTemplates(); err != nil {
+		t.Fatal("Error loading the templates. Details:", err)
+	}
+
+	recovery := protocol.Recovery{
+		DomainRecovery: model.DomainRecovery{
+			Domain: model.Domain{FQDN: "example.com.br."},
+			Nameservers: []model.Nameserver{
+				{Host: "ns1.example.com.br.", LastStatus: model.NameserverStatusTimeout},
+			},
+			DSSet: []model.DS{
+				{Keytag: 1234, LastStatus: model.DSStatusExpiredSignature},
+			},
+		},
+		From: "shelter@example.com.br",
+		To:   "admin@example.com.br",
+	}
+
+	for _, language := range config.ShelterConfig.Languages {
+		var msg bytes.Buffer
+		if err := getTemplate(language).ExecuteTemplate(&msg, "recovery", recovery); err != nil {
+			t.Errorf("Error executing the recovery block of language %s. Details: %s",
+				language, err)
+			continue
+		}
+
+		content := msg.String()
+		if !strings.Contains(content, "example.com.br.") ||
+			!strings.Contains(content, "ns1.example.com.br.") ||
+			!strings.Contains(content, "TIMEOUT") ||
+			!strings.Contains(content, "1234") ||
+			!strings.Contains(content, "EXPSIG") {
+
+			t.Errorf("Not informing the solved problems in language %s", language)
+		}
+	}
+}
+
+func TestLoadTemplatesDigestBlock(t *testing.T) {
+	clearTemplates()
+	defer clearTemplates()
+
+	TemplateExtension = ".tmpl"
+	defer func() {
+		TemplateExtension = ""
+	}()
+
+	config.ShelterConfig.BasePath = filepath.Join("..", "..", "..")
+	config.ShelterConfig.Notification.TemplatesPath = filepath.Join("templates", "notification")
+	config.ShelterConfig.Languages = []string{"en-US", "pt-BR", "es-ES"}
+
+	if err := LoadTemplates(); err != nil {
+		t.Fatal("Error loading the templates. Details:", err)
+	}
+
+	digest := protocol.Digest{
+		Domains: []model.Domain{
+			{
+				FQDN: "example1.com.br.",
+				Nameservers: []model.Nameserver{
+					{Host: "ns1.example1.com.br.", LastStatus: model.NameserverStatusNoAuthority},
+				},
+			},
+			{
+				FQDN: "example2.com.br.",
+				DSSet: []model.DS{
+					{Keytag: 1234, LastStatus: model.DSStatusExpiredSignature},
+				},
+			},
+		},
+		From: "shelter@example.com.br",
+		To:   "admin@example.com.br",
+	}
+
+	for _, language := range config.ShelterConfig.Languages {
+		var msg bytes.Buffer
+		if err := getTemplate(language).ExecuteTemplate(&msg, "digest", digest); err != nil {
+			t.Errorf("Error executing the digest block of language %s. Details: %s",
+				language, err)
+			continue
+		}
+
+		content := msg.String()
+		if !strings.Contains(content, "example1.com.br.") ||
+			!strings.Contains(content, "ns1.example1.com.br.") ||
+			!strings.Contains(content, "NOAA") ||
+			!strings.Contains(content, "example2.com.br.") ||
+			!strings.Contains(content, "1234") ||
+			!strings.Contains(content, "EXPSIG") {
+
+			t.Errorf("Not informing all domains of the digest in language %s", language)
+		}
+	}
+}
+
 func TestLoadTemplatesWithDirectory(t *testing.T) {
 	clearTemplates()
 
@@ -114,6 +226,22 @@ func TestNameserverStatusEquals(t *testing.T) {
 	}
 }
 
+func TestNameserverAddressStatus(t *testing.T) {
+	nameserver := model.Nameserver{
+		LastStatusIPv4: model.NameserverStatusOK,
+		LastStatusIPv6: model.NameserverStatusTimeout,
+	}
+
+	if status := nameserverAddressStatus(nameserver); status != "IPv4 OK, IPv6 TIMEOUT" {
+		t.Errorf("Not describing correctly the address families status. Got '%s'", status)
+	}
+
+	nameserver.LastStatusIPv4 = model.NameserverStatusNotChecked
+	if status := nameserverAddressStatus(nameserver); status != "IPv6 TIMEOUT" {
+		t.Errorf("Not ignoring address families that weren't checked. Got '%s'", status)
+	}
+}
+
 func TestDSStatusEquals(t *testing.T) {
 	if !dsStatusEquals(model.DSStatusNoKey, "noKey   ") {
 		t.Error("Not comparing correctly when DS status are equal")
@@ -140,4 +268,8 @@ func TestIsNearExpirationDS(t *testing.T) {
 	}) {
 		t.Error("Returning near expiration is wrong scenarios")
 	}
+
+	if isNearExpirationDS(model.DS{}) {
+		t.Error("Returning near expiration for a DS without signature expiration date")
+	}
 }
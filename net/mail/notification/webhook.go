@@ -0,0 +1,312 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package notification is the notification service
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rafaeljusto/shelter/config"
+	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/http/rest/check"
+	"github.com/rafaeljusto/shelter/net/mail/notification/protocol"
+	"github.com/rafaeljusto/shelter/secret"
+)
+
+var (
+	// ErrWebhookPrivateAddress is returned when the webhook only resolves to addresses that
+	// can't be reached from the Internet and its host isn't in the allowed list
+	ErrWebhookPrivateAddress = errors.New("Webhook resolves to a private address")
+)
+
+// WebhookNotifier sends the alerts as JSON to HTTP endpoints. The requests are signed in
+// the same way that the REST server clients sign their requests, so the receiver can use
+// the same secret and algorithm to check the Authorization HTTP header. The global webhook
+// uses the secret of the configuration file and each owner's webhook uses the owner's
+// secret, with the owner's e-mail as the secret identification
+type WebhookNotifier struct {
+	URL          string        // Webhook that receives the alerts of all domains (optional)
+	SecretId     string        // Identification of the secret in the Authorization HTTP header
+	Secret       string        // Secret (decrypted) used to sign the requests to the URL
+	AllowedHosts []string      // Hosts that can resolve to private addresses
+	Retries      int           // Number of new attempts when the delivery fails
+	Backoff      time.Duration // Time to wait before the first new attempt, doubled on each attempt
+	Client       *http.Client  // HTTP client used to send the requests (optional)
+}
+
+// NewWebhookNotifier builds the webhook notifier using the configuration file. It can
+// return an error if the secret couldn't be decrypted
+func NewWebhookNotifier() (WebhookNotifier, error) {
+	webhookConfig := config.ShelterConfig.Notification.Webhook
+
+	notifier := WebhookNotifier{
+		URL:          webhookConfig.URL,
+		SecretId:     webhookConfig.SecretId,
+		Secret:       webhookConfig.Secret,
+		AllowedHosts: webhookConfig.AllowedHosts,
+		Retries:      webhookConfig.Retries,
+		Backoff:      time.Duration(webhookConfig.BackoffSeconds) * time.Second,
+	}
+
+	notifier.Client = notifier.newClient()
+	notifier.Client.Timeout = time.Duration(webhookConfig.TimeoutSeconds) * time.Second
+
+	if len(notifier.Secret) > 0 {
+		var err error
+		notifier.Secret, err = secret.Decrypt(notifier.Secret)
+		if err != nil {
+			return notifier, err
+		}
+	}
+
+	return notifier, nil
+}
+
+func (n WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+// Notify sends the domain to the global webhook and to the owners' webhooks, except the
+// ones in skip. All webhooks are tried even when one fails, and each webhook has its own
+// record. The owners' preferences are only used for e-mails, as webhooks are consumed by
+// systems that can filter the events by themselves
+func (n WebhookNotifier) Notify(domain *model.Domain, skip map[string]bool) []model.Notification {
+	return n.send(domain, protocol.NewWebhook(protocol.WebhookEventMisconfiguration, *domain), skip)
+}
+
+// NotifyRecovery sends the domain with the solved problems to the global webhook and to
+// the owners' webhooks
+func (n WebhookNotifier) NotifyRecovery(recovery *model.DomainRecovery) []model.Notification {
+	webhook := protocol.NewWebhook(protocol.WebhookEventRecovery, recovery.Domain)
+	webhook.Solved = recovery.Problems()
+	return n.send(&recovery.Domain, webhook, nil)
+}
+
+// Send the content to all webhooks related to the domain that aren't in skip
+func (n WebhookNotifier) send(domain *model.Domain, webhook protocol.Webhook,
+	skip map[string]bool) []model.Notification {
+
+	var targets []webhookTarget
+	if len(n.URL) > 0 {
+		targets = append(targets, webhookTarget{
+			url:      n.URL,
+			secretId: n.SecretId,
+			secret:   n.Secret,
+		})
+	}
+
+	for _, owner := range domain.Owners {
+		if len(owner.Webhook) == 0 {
+			continue
+		}
+
+		found := false
+		for _, target := range targets {
+			if target.url == owner.Webhook {
+				found = true
+				break
+			}
+		}
+
+		if found {
+			continue
+		}
+
+		target := webhookTarget{url: owner.Webhook, secret: owner.WebhookSecret}
+		if owner.Email != nil {
+			target.secretId = owner.Email.Address
+		}
+
+		targets = append(targets, target)
+	}
+
+	for i := len(targets) - 1; i >= 0; i-- {
+		if skip[targets[i].url] {
+			targets = append(targets[:i], targets[i+1:]...)
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil
+	}
+
+	// A problem building the content affects all webhooks, so it's stored in all records
+	body, marshalErr := json.Marshal(webhook)
+
+	var notifications []model.Notification
+	for _, target := range targets {
+		notification := model.Notification{
+			Channel:    n.Name(),
+			Recipients: []string{target.url},
+		}
+
+		err := marshalErr
+		if err == nil {
+			log.Debugf("Sending notification for domain %s to webhook %s", domain.FQDN, target.url)
+			err = n.deliver(target, body)
+		}
+
+		if err == nil {
+			notification.Delivered = true
+		} else {
+			notification.Error = err.Error()
+		}
+
+		notifications = append(notifications, notification)
+	}
+
+	return notifications
+}
+
+// Deliver the content to the webhook, trying again with an exponential backoff when
+// there's a temporary problem
+func (n WebhookNotifier) deliver(target webhookTarget, body []byte) error {
+	backoff := n.Backoff
+
+	for attempt := 0; ; attempt++ {
+		retry, err := n.post(target, body)
+		if err == nil || !retry || attempt >= n.Retries {
+			return err
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// Send the content to the webhook. It returns if the request can be sent again, that is
+// the case for network problems, server errors and rate limits
+func (n WebhookNotifier) post(target webhookTarget, body []byte) (bool, error) {
+	r, err := http.NewRequest("POST", target.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	// The receiver will always see at least the root path, so we need to use it in the
+	// signature
+	if len(r.URL.Path) == 0 {
+		r.URL.Path = "/"
+	}
+
+	hash := md5.New()
+	hash.Write(body)
+
+	r.Header.Set("Content-Type", fmt.Sprintf("%s; charset=%s",
+		check.SupportedContentType, check.SupportedCharset))
+	r.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(hash.Sum(nil)))
+	r.Header.Set("Date", time.Now().UTC().Format(time.RFC1123))
+
+	if len(target.secret) > 0 {
+		stringToSign, err := check.BuildStringToSign(r, target.secretId)
+		if err != nil {
+			return false, err
+		}
+
+		signature := check.GenerateSignature(stringToSign, target.secret)
+		r.Header.Set("Authorization", fmt.Sprintf("%s %s:%s",
+			check.SupportedNamespace, target.secretId, signature))
+	}
+
+	client := n.Client
+	if client == nil {
+		client = n.newClient()
+	}
+
+	response, err := client.Do(r)
+	if err != nil {
+		// The address of the webhook will not change in the next attempts
+		return !errors.Is(err, ErrWebhookPrivateAddress), err
+	}
+
+	// Read the whole body to allow the connection to be reused
+	io.Copy(ioutil.Discard, response.Body)
+	response.Body.Close()
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return false, nil
+	}
+
+	retry := response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("Webhook answered with HTTP status %d", response.StatusCode)
+}
+
+// Build the HTTP client that only connects to the public addresses of the webhooks,
+// except for the allowed hosts. The proxy of the environment isn't used, because we
+// need to check the address of the webhook itself
+func (n WebhookNotifier) newClient() *http.Client {
+	dialer := webhookDialer{allowedHosts: make(map[string]bool)}
+	for _, host := range n.AllowedHosts {
+		dialer.allowedHosts[strings.ToLower(host)] = true
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: dialer.DialContext,
+		},
+	}
+}
+
+// webhookTarget is a webhook that will receive the content, with the secret used to sign
+// the requests. When there's no secret the requests aren't signed
+type webhookTarget struct {
+	url      string
+	secretId string
+	secret   string
+}
+
+// webhookDialer connects to the webhooks checking the resolved addresses, so a webhook
+// can't reach the internal network even when its name resolves to a private address
+type webhookDialer struct {
+	dialer       net.Dialer
+	allowedHosts map[string]bool
+}
+
+// DialContext resolves the host and connects to the first address that is allowed. We
+// connect to the checked address instead of the host, so the name can't be resolved again
+// to a different address
+func (d webhookDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := d.allowedHosts[strings.ToLower(host)]
+
+	err = ErrWebhookPrivateAddress
+	for _, ipAddress := range addresses {
+		if !allowed && model.IsPrivateAddress(ipAddress.IP) {
+			continue
+		}
+
+		conn, dialErr := d.dialer.DialContext(ctx, network,
+			net.JoinHostPort(ipAddress.IP.String(), port))
+
+		if dialErr == nil {
+			return conn, nil
+		}
+
+		err = dialErr
+	}
+
+	return nil, err
+}
@@ -0,0 +1,273 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package notification is the notification service
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/http/rest/check"
+	"github.com/rafaeljusto/shelter/net/mail/notification/protocol"
+)
+
+func TestWebhookNotifierNotify(t *testing.T) {
+	var requests int32
+	var content protocol.Webhook
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		stringToSign, err := check.BuildStringToSign(r, "key01")
+		if err != nil {
+			t.Error("Error building the string to sign. Details:", err)
+		}
+
+		expected := fmt.Sprintf("%s key01:%s", check.SupportedNamespace,
+			check.GenerateSignature(stringToSign, "abc123"))
+
+		if r.Header.Get("Authorization") != expected {
+			t.Error("Not signing the webhook request")
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&content); err != nil {
+			t.Error("Error decoding the webhook content. Details:", err)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	email, err := mail.ParseAddress("admin@example.com.br")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	domain := model.Domain{
+		FQDN: "example.com.br.",
+		Owners: []model.Owner{
+			{Email: email, Language: "en-US", Webhook: server.URL},
+		},
+	}
+
+	notifier := WebhookNotifier{
+		URL:          server.URL,
+		SecretId:     "key01",
+		Secret:       "abc123",
+		AllowedHosts: []string{"127.0.0.1"},
+	}
+
+	notifications := notifier.Notify(&domain, nil)
+	if len(notifications) != 1 {
+		t.Fatalf("Expected 1 notification record and got %d", len(notifications))
+	}
+
+	if !notifications[0].Delivered || notifications[0].Channel != "webhook" ||
+		len(notifications[0].Recipients) != 1 || notifications[0].Recipients[0] != server.URL {
+
+		t.Errorf("Not building the notification record correctly: %#v", notifications[0])
+	}
+
+	if requests != 1 {
+		t.Errorf("Expected 1 request and got %d, the same webhook must be notified once", requests)
+	}
+
+	if content.Event != protocol.WebhookEventMisconfiguration ||
+		content.Domain.FQDN != "example.com.br." {
+
+		t.Error("Not sending the domain in the webhook content")
+	}
+
+	if len(content.Domain.Owners) != 1 || len(content.Domain.Owners[0].Webhook) > 0 {
+		t.Error("Exposing the owners' webhooks in the content")
+	}
+
+	// Webhooks that were already alerted about the same problems must be ignored
+	if notifications := notifier.Notify(&domain, map[string]bool{server.URL: true}); len(notifications) > 0 {
+		t.Error("Notifying a webhook that should be skipped")
+	}
+
+	if requests != 1 {
+		t.Error("Sending requests to a webhook that should be skipped")
+	}
+
+	// Domains without webhooks shouldn't generate requests
+	notifier.URL = ""
+	domain.Owners[0].Webhook = ""
+
+	if notifications := notifier.Notify(&domain, nil); len(notifications) > 0 {
+		t.Error("Building notification records for a domain without webhooks")
+	}
+
+	if requests != 1 {
+		t.Error("Sending requests for a domain without webhooks")
+	}
+}
+
+func TestWebhookNotifierOwnerSecret(t *testing.T) {
+	var authorization string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stringToSign, err := check.BuildStringToSign(r, "admin@example.com.br")
+		if err != nil {
+			t.Error("Error building the string to sign. Details:", err)
+		}
+
+		authorization = fmt.Sprintf("%s admin@example.com.br:%s", check.SupportedNamespace,
+			check.GenerateSignature(stringToSign, "owner123"))
+
+		if r.Header.Get("Authorization") != authorization {
+			t.Error("Not signing the owner's webhook with the owner's secret")
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	email, err := mail.ParseAddress("admin@example.com.br")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	domain := model.Domain{
+		FQDN: "example.com.br.",
+		Owners: []model.Owner{
+			{Email: email, Language: "en-US", Webhook: server.URL, WebhookSecret: "owner123"},
+		},
+	}
+
+	notifier := WebhookNotifier{
+		SecretId:     "key01",
+		Secret:       "abc123",
+		AllowedHosts: []string{"127.0.0.1"},
+	}
+
+	notifications := notifier.Notify(&domain, nil)
+	if len(notifications) != 1 || !notifications[0].Delivered {
+		t.Fatalf("Not delivering to the owner's webhook: %#v", notifications)
+	}
+
+	if len(authorization) == 0 {
+		t.Error("Not sending the request to the owner's webhook")
+	}
+}
+
+func TestWebhookNotifierPrivateAddress(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	notifier := WebhookNotifier{
+		URL:     server.URL,
+		Retries: 2,
+	}
+
+	notifications := notifier.Notify(&model.Domain{FQDN: "example.com.br."}, nil)
+	if len(notifications) != 1 || notifications[0].Delivered ||
+		!strings.Contains(notifications[0].Error, ErrWebhookPrivateAddress.Error()) {
+
+		t.Errorf("Not blocking a webhook with a loopback address: %#v", notifications)
+	}
+
+	if requests > 0 {
+		t.Error("Sending requests to a loopback address that isn't allowed")
+	}
+}
+
+func TestWebhookNotifierRetry(t *testing.T) {
+	data := []struct {
+		status           int
+		expectedRequests int32
+	}{
+		{status: http.StatusServiceUnavailable, expectedRequests: 3},
+		{status: http.StatusTooManyRequests, expectedRequests: 3},
+		{status: http.StatusBadRequest, expectedRequests: 1},
+	}
+
+	for _, item := range data {
+		var requests int32
+		status := item.status
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			w.WriteHeader(status)
+		}))
+
+		notifier := WebhookNotifier{
+			URL:          server.URL,
+			AllowedHosts: []string{"127.0.0.1"},
+			Retries:      2,
+		}
+
+		notifications := notifier.Notify(&model.Domain{FQDN: "example.com.br."}, nil)
+		server.Close()
+
+		if len(notifications) != 1 || notifications[0].Delivered ||
+			!strings.Contains(notifications[0].Error, strconv.Itoa(item.status)) {
+
+			t.Errorf("Not informing the webhook that failed with HTTP status %d", item.status)
+		}
+
+		if requests != item.expectedRequests {
+			t.Errorf("Expected %d requests for HTTP status %d and got %d",
+				item.expectedRequests, item.status, requests)
+		}
+	}
+}
+
+func TestWebhookNotifierNotifyRecovery(t *testing.T) {
+	var content protocol.Webhook
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&content); err != nil {
+			t.Error("Error decoding the webhook content. Details:", err)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	recovery := model.DomainRecovery{
+		Domain: model.Domain{
+			FQDN: "example.com.br.",
+			Nameservers: []model.Nameserver{
+				{Host: "ns1.example.com.br.", LastStatus: model.NameserverStatusOK},
+			},
+		},
+		Nameservers: []model.Nameserver{
+			{Host: "ns1.example.com.br.", LastStatus: model.NameserverStatusTimeout},
+		},
+	}
+
+	notifier := WebhookNotifier{
+		URL:          server.URL,
+		AllowedHosts: []string{"127.0.0.1"},
+	}
+
+	notifications := notifier.NotifyRecovery(&recovery)
+	if len(notifications) != 1 || !notifications[0].Delivered {
+		t.Fatalf("Not delivering the recovery: %#v", notifications)
+	}
+
+	if content.Event != protocol.WebhookEventRecovery || content.Domain.FQDN != "example.com.br." {
+		t.Error("Not sending the recovery event in the webhook content")
+	}
+
+	if len(content.Solved) != 1 || content.Solved[0] != "nameserver ns1.example.com.br. TIMEOUT" {
+		t.Errorf("Not sending the solved problems in the webhook content: %v", content.Solved)
+	}
+}
@@ -16,13 +16,19 @@ import (
 // database. For faster approach the collector waits until it has many domains to save
 // them at once in the database
 type Collector struct {
-	Database   *mgo.Database // Low level database connection
-	SaveAtOnce int           // Number of domains to save at once
+	Storage          dao.Storage            // Persistence backend of the domains
+	Database         *mgo.Database          // Low level database connection, used for the domain history (optional)
+	SaveAtOnce       int                    // Number of domains to save at once
+	DetectRecoveries bool                   // Compare the domains with the saved state to detect solved problems
+	Recoveries       []model.DomainRecovery // Solved problems detected, available after the scan
 }
 
-// Return a new Collector object with the necessary fields for the scan filled
-func NewCollector(database *mgo.Database, saveAtOnce int) *Collector {
+// Return a new Collector object with the necessary fields for the scan filled. The
+// MongoDB database can be nil when using another persistence backend, in this case the
+// domain history isn't stored
+func NewCollector(storage dao.Storage, database *mgo.Database, saveAtOnce int) *Collector {
 	return &Collector{
+		Storage:    storage,
 		Database:   database,
 		SaveAtOnce: saveAtOnce,
 	}
@@ -41,10 +47,15 @@ func (c *Collector) Start(scanGroup *sync.WaitGroup,
 	scanGroup.Add(1)
 
 	go func() {
-		// Initialize Domain DAO using injected database connection
-		domainDAO := dao.DomainDAO{
+		// Initialize Domain DAO using injected persistence backend
+		domainDAO := c.Storage.DomainDAO()
+
+		// Each scan also stores a snapshot of the domains' results, so that we can keep the
+		// history of the domain. The snapshots are linked to the current scan
+		domainSnapshotDAO := dao.DomainSnapshotDAO{
 			Database: c.Database,
 		}
+		scanStartedAt := model.GetCurrentScan().StartedAt
 
 		// Add a safety check to avoid an infinite loop
 		if c.SaveAtOnce == 0 {
@@ -87,6 +98,39 @@ func (c *Collector) Start(scanGroup *sync.WaitGroup,
 				domains = append(domains, domain)
 			}
 
+			// The domain in the database still has the state of the previous scan, so we can
+			// detect the problems that were solved before overwriting it
+			recoveries := make(map[*model.Domain]model.DomainRecovery)
+			if c.DetectRecoveries {
+				fqdns := make([]string, 0, len(domains))
+				for _, domain := range domains {
+					fqdns = append(fqdns, domain.FQDN)
+				}
+
+				if previousDomains, err := domainDAO.FindByFQDNs(fqdns); err != nil {
+					errorsChannel <- err
+
+				} else {
+					previousByFQDN := make(map[string]model.Domain, len(previousDomains))
+					for _, previous := range previousDomains {
+						previousByFQDN[previous.FQDN] = previous
+					}
+
+					for _, domain := range domains {
+						previous, ok := previousByFQDN[domain.FQDN]
+						if !ok {
+							continue
+						}
+
+						if recovery := model.NewDomainRecovery(previous, *domain); !recovery.Empty() {
+							recoveries[domain] = recovery
+						}
+					}
+				}
+			}
+
+			snapshots := make([]*model.DomainSnapshot, 0, len(domains))
+
 			domainsResults := domainDAO.SaveMany(domains)
 			for _, domainResult := range domainsResults {
 				if domainResult.Error != nil {
@@ -95,6 +139,23 @@ func (c *Collector) Start(scanGroup *sync.WaitGroup,
 					// error, but not telling wich domain got the error, we should improve the error
 					// communication system between the go routines
 					errorsChannel <- domainResult.Error
+					continue
+				}
+
+				snapshot := model.NewDomainSnapshot(*domainResult.Domain, scanStartedAt)
+				snapshots = append(snapshots, &snapshot)
+
+				// Alert who is following the scan progress that the domain result is available
+				model.PublishDomainScanEvent(snapshot)
+
+				if recovery, ok := recoveries[domainResult.Domain]; ok {
+					c.Recoveries = append(c.Recoveries, recovery)
+				}
+			}
+
+			if c.Database != nil {
+				if err := domainSnapshotDAO.SaveMany(snapshots); err != nil {
+					errorsChannel <- err
 				}
 			}
 
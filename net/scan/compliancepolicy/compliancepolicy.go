@@ -0,0 +1,248 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package compliancepolicy store the EDNS and TCP compliance policies for nameservers
+package compliancepolicy
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/miekg/dns"
+	"github.com/rafaeljusto/shelter/model"
+	"net"
+	"syscall"
+)
+
+// unknownOptionCode is an EDNS0 option code that is not assigned by IANA
+// (http://www.iana.org/assignments/dns-parameters), used to check if the nameserver
+// ignores unknown options
+const unknownOptionCode = 100
+
+// optHeaderLength is the size of the OPT record header in the wire format: root owner
+// name, type, class (UDP size), TTL (extended flags) and data length
+const optHeaderLength = 11
+
+// unknownOption reuses the wire format of an empty option from the DNS library,
+// replacing only the option code by an unassigned one
+type unknownOption struct {
+	dns.EDNS0
+}
+
+// Option returns the unassigned option code
+func (o unknownOption) Option() uint16 {
+	return unknownOptionCode
+}
+
+// List of possible compliance tests
+const (
+	TestPlain      = iota // Query without EDNS0
+	TestEDNS              // Query with EDNS0
+	TestEDNSDO            // Query with EDNS0 and the DNSSEC OK bit (large response)
+	TestEDNSOption        // Query with EDNS0 and an unknown option
+	TestTCP               // Query without EDNS0 sent only over TCP
+)
+
+// Test is a number that represents one of the possible compliance tests listed in the
+// constant group above
+type Test int
+
+var (
+	// List of all compliance tests that are executed for each nameserver
+	Tests = []Test{
+		TestPlain,
+		TestEDNS,
+		TestEDNSDO,
+		TestEDNSOption,
+		TestTCP,
+	}
+
+	// List of policies of each compliance test that are going to be executed in the order
+	// defined here. The order is important because the policies depends on each other,
+	// assuming that something was already verified
+	compliancePolicies = map[Test][]func(*dns.Msg) model.ComplianceStatus{
+		TestPlain:      {rcodePolicy},
+		TestEDNS:       {ednsPolicy, rcodePolicy},
+		TestEDNSDO:     {ednsPolicy, rcodePolicy, doPolicy},
+		TestEDNSOption: {ednsPolicy, rcodePolicy, optionEchoPolicy},
+		TestTCP:        {rcodePolicy},
+	}
+)
+
+// NewRequest builds the DNS request of a compliance test for the given domain. The test
+// with the DNSSEC OK bit asks for the DNSKEY RRset, that together with the signatures
+// usually is bigger than 512 bytes, the other tests use the SOA record. The unknown
+// option uses a code that is not assigned by IANA, so the nameservers should ignore it
+func NewRequest(test Test, fqdn string, udpMaxSize uint16) *dns.Msg {
+	var dnsRequestMessage dns.Msg
+	dnsRequestMessage.SetQuestion(fqdn, dns.TypeSOA)
+	dnsRequestMessage.RecursionDesired = false
+
+	switch test {
+	case TestEDNS:
+		dnsRequestMessage.SetEdns0(udpMaxSize, false)
+
+	case TestEDNSDO:
+		dnsRequestMessage.SetQuestion(fqdn, dns.TypeDNSKEY)
+		dnsRequestMessage.SetEdns0(udpMaxSize, true)
+
+	case TestEDNSOption:
+		dnsRequestMessage.SetEdns0(udpMaxSize, false)
+
+		opt := dnsRequestMessage.IsEdns0()
+		opt.Option = append(opt.Option, unknownOption{&dns.EDNS0_NSID{Code: dns.EDNS0NSID}})
+	}
+
+	return &dnsRequestMessage
+}
+
+// UseTCP returns true when the compliance test must be sent only over TCP
+func UseTCP(test Test) bool {
+	return test == TestTCP
+}
+
+// UseUDP returns true when the compliance test must be sent only over UDP, without
+// retrying over TCP when the response is truncated. The DNSSEC OK test checks if the
+// nameserver can deliver a large response over UDP
+func UseUDP(test Test) bool {
+	return test == TestEDNSDO
+}
+
+// When there's a error while sending a compliance request over the network, this
+// function is responsable for detecting any usual problems. There's also some unknown
+// problems that are going to be treated as a generic error
+func CheckNetworkError(err error) model.ComplianceStatus {
+	if err == nil {
+		return model.ComplianceStatusOK
+	}
+
+	if netError, ok := err.(net.Error); ok && netError.Timeout() {
+		return model.ComplianceStatusTimeout
+	}
+
+	switch t := err.(type) {
+	case *net.OpError:
+		if t.Op == "dial" || t.Op == "read" {
+			return model.ComplianceStatusConnectionRefused
+		}
+
+	case syscall.Errno:
+		switch t {
+		case syscall.ETIMEDOUT:
+			return model.ComplianceStatusTimeout
+		case syscall.ECONNREFUSED:
+			return model.ComplianceStatusConnectionRefused
+		}
+	}
+
+	return model.ComplianceStatusError
+}
+
+// Function responsable for running all policies of a compliance test. It will return
+// the compliance status of the first error that occurred
+func Run(test Test, dnsResponseMessage *dns.Msg) model.ComplianceStatus {
+	// Something went really wrong, because if we got here there was no network error and it
+	// should have a DNS response message, but as a safety check we don't allow to continue
+	if dnsResponseMessage == nil {
+		return model.ComplianceStatusError
+	}
+
+	for _, policy := range compliancePolicies[test] {
+		if status := policy(dnsResponseMessage); status != model.ComplianceStatusOK {
+			return status
+		}
+	}
+
+	return model.ComplianceStatusOK
+}
+
+// SetStatus stores the result of a compliance test in the nameserver's compliance report
+func SetStatus(compliance *model.NameserverCompliance, test Test,
+	status model.ComplianceStatus) {
+
+	switch test {
+	case TestPlain:
+		compliance.Plain = status
+	case TestEDNS:
+		compliance.EDNS = status
+	case TestEDNSDO:
+		compliance.EDNSDO = status
+	case TestEDNSOption:
+		compliance.EDNSOption = status
+	case TestTCP:
+		compliance.TCP = status
+	}
+}
+
+// The nameserver is authoritative for the domain, so it should answer all compliance
+// tests without errors
+func rcodePolicy(dnsResponseMessage *dns.Msg) model.ComplianceStatus {
+	if dnsResponseMessage.Rcode != dns.RcodeSuccess {
+		return model.ComplianceStatusBadRcode
+	}
+
+	return model.ComplianceStatusOK
+}
+
+// Nameservers that support EDNS0 must answer with an OPT record. Old nameservers answer
+// with FORMERR when they don't understand the OPT record of the request
+func ednsPolicy(dnsResponseMessage *dns.Msg) model.ComplianceStatus {
+	if dnsResponseMessage.Rcode == dns.RcodeFormatError ||
+		dnsResponseMessage.IsEdns0() == nil {
+
+		return model.ComplianceStatusNoEDNS
+	}
+
+	return model.ComplianceStatusOK
+}
+
+// The DNSSEC OK bit must be copied to the response (RFC 3225), otherwise validating
+// resolvers could think that the nameserver doesn't support DNSSEC
+func doPolicy(dnsResponseMessage *dns.Msg) model.ComplianceStatus {
+	if opt := dnsResponseMessage.IsEdns0(); opt == nil || !opt.Do() {
+		return model.ComplianceStatusNoDO
+	}
+
+	return model.ComplianceStatusOK
+}
+
+// Unknown EDNS0 options must be ignored (RFC 6891), so the nameserver can't return them
+// in the response. The DNS library drops the options that it doesn't know when unpacking
+// the response, so we also compare the OPT record data length with the size of the
+// options that were kept
+func optionEchoPolicy(dnsResponseMessage *dns.Msg) model.ComplianceStatus {
+	opt := dnsResponseMessage.IsEdns0()
+	if opt == nil {
+		return model.ComplianceStatusOK
+	}
+
+	for _, option := range opt.Option {
+		if option.Option() == unknownOptionCode {
+			return model.ComplianceStatusOptionEcho
+		}
+	}
+
+	if opt.Hdr.Rdlength > knownOptionsLength(opt) {
+		return model.ComplianceStatusOptionEcho
+	}
+
+	return model.ComplianceStatusOK
+}
+
+// knownOptionsLength returns the data length of the OPT record considering only the
+// options that the DNS library could unpack
+func knownOptionsLength(opt *dns.OPT) uint16 {
+	knownOPT := &dns.OPT{
+		Hdr: dns.RR_Header{
+			Name:   ".",
+			Rrtype: dns.TypeOPT,
+		},
+		Option: opt.Option,
+	}
+
+	msg := make([]byte, dns.MaxMsgSize)
+	off, err := dns.PackRR(knownOPT, msg, 0, nil, false)
+	if err != nil || off < optHeaderLength {
+		return opt.Hdr.Rdlength
+	}
+
+	return uint16(off - optHeaderLength)
+}
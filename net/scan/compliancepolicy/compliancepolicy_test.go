@@ -0,0 +1,187 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package compliancepolicy store the EDNS and TCP compliance policies for nameservers
+package compliancepolicy
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/miekg/dns"
+	"github.com/rafaeljusto/shelter/model"
+	"net"
+	"testing"
+)
+
+// myErr was created only to test possible network errors
+type myErr struct {
+	err     string
+	timeout bool
+}
+
+func (e myErr) Error() string {
+	return e.err
+}
+
+func (e myErr) Timeout() bool {
+	return e.timeout
+}
+
+func (e myErr) Temporary() bool {
+	return true
+}
+
+func TestNewRequest(t *testing.T) {
+	request := NewRequest(TestPlain, "br.", 4096)
+	if request.Question[0].Qtype != dns.TypeSOA || request.IsEdns0() != nil {
+		t.Error("Not building the plain request correctly")
+	}
+
+	request = NewRequest(TestEDNS, "br.", 4096)
+	if opt := request.IsEdns0(); opt == nil || opt.Do() || opt.UDPSize() != 4096 {
+		t.Error("Not building the EDNS0 request correctly")
+	}
+
+	request = NewRequest(TestEDNSDO, "br.", 4096)
+	if opt := request.IsEdns0(); opt == nil || !opt.Do() ||
+		request.Question[0].Qtype != dns.TypeDNSKEY {
+
+		t.Error("Not building the EDNS0 request with DNSSEC OK bit correctly")
+	}
+
+	request = NewRequest(TestEDNSOption, "br.", 4096)
+	if opt := request.IsEdns0(); opt == nil || len(opt.Option) != 1 ||
+		opt.Option[0].Option() != unknownOptionCode {
+
+		t.Error("Not building the EDNS0 request with unknown option correctly")
+	}
+
+	if _, err := request.Pack(); err != nil {
+		t.Error("Not building a valid EDNS0 request with unknown option. Details:", err)
+	}
+
+	request = NewRequest(TestTCP, "br.", 4096)
+	if request.Question[0].Qtype != dns.TypeSOA || request.IsEdns0() != nil {
+		t.Error("Not building the TCP request correctly")
+	}
+
+	if !UseTCP(TestTCP) || UseTCP(TestEDNS) {
+		t.Error("Not detecting which tests should be sent over TCP")
+	}
+
+	if !UseUDP(TestEDNSDO) || UseUDP(TestEDNS) || UseUDP(TestTCP) {
+		t.Error("Not detecting which tests should be sent only over UDP")
+	}
+}
+
+func TestCheckNetworkError(t *testing.T) {
+	if CheckNetworkError(nil) != model.ComplianceStatusOK {
+		t.Error("Not returning OK when there's no network error")
+	}
+
+	if CheckNetworkError(myErr{timeout: true}) != model.ComplianceStatusTimeout {
+		t.Error("Not detecting network timeout")
+	}
+
+	if CheckNetworkError(&net.OpError{Op: "dial"}) != model.ComplianceStatusConnectionRefused {
+		t.Error("Not detecting when the connection was refused")
+	}
+
+	if CheckNetworkError(myErr{err: "unknown"}) != model.ComplianceStatusError {
+		t.Error("Not detecting unknown network errors")
+	}
+}
+
+func TestRun(t *testing.T) {
+	if Run(TestPlain, nil) != model.ComplianceStatusError {
+		t.Error("Not detecting when there's no response")
+	}
+
+	response := new(dns.Msg)
+	response.SetReply(NewRequest(TestPlain, "br.", 4096))
+
+	if Run(TestPlain, response) != model.ComplianceStatusOK {
+		t.Error("Not accepting a valid plain response")
+	}
+
+	response.Rcode = dns.RcodeServerFailure
+	if Run(TestTCP, response) != model.ComplianceStatusBadRcode {
+		t.Error("Not detecting an unexpected response code")
+	}
+
+	response.Rcode = dns.RcodeFormatError
+	if Run(TestEDNS, response) != model.ComplianceStatusNoEDNS {
+		t.Error("Not detecting when the nameserver doesn't support EDNS0")
+	}
+
+	response.Rcode = dns.RcodeSuccess
+	if Run(TestEDNS, response) != model.ComplianceStatusNoEDNS {
+		t.Error("Not detecting when the OPT record is missing in the response")
+	}
+
+	response.SetEdns0(4096, false)
+	if Run(TestEDNS, response) != model.ComplianceStatusOK {
+		t.Error("Not accepting a valid EDNS0 response")
+	}
+
+	if Run(TestEDNSDO, response) != model.ComplianceStatusNoDO {
+		t.Error("Not detecting when the DNSSEC OK bit is missing in the response")
+	}
+
+	response.IsEdns0().SetDo()
+	if Run(TestEDNSDO, response) != model.ComplianceStatusOK {
+		t.Error("Not accepting a valid EDNS0 response with DNSSEC OK bit")
+	}
+
+	if Run(TestEDNSOption, response) != model.ComplianceStatusOK {
+		t.Error("Not accepting a valid EDNS0 response without the unknown option")
+	}
+
+	opt := response.IsEdns0()
+	opt.Option = append(opt.Option, NewRequest(TestEDNSOption, "br.", 4096).IsEdns0().Option...)
+	if Run(TestEDNSOption, response) != model.ComplianceStatusOptionEcho {
+		t.Error("Not detecting when the unknown option is echoed")
+	}
+
+	// The DNS library drops unknown options when unpacking, so the echo must be detected
+	// from the OPT record data length
+	request := NewRequest(TestEDNSOption, "br.", 4096)
+	request.Response = true
+
+	wire, err := request.Pack()
+	if err != nil {
+		t.Fatal("Not building a valid response with the unknown option. Details:", err)
+	}
+
+	response = new(dns.Msg)
+	if err := response.Unpack(wire); err != nil {
+		t.Fatal("Not parsing a response with the unknown option. Details:", err)
+	}
+
+	if Run(TestEDNSOption, response) != model.ComplianceStatusOptionEcho {
+		t.Error("Not detecting when the unknown option is echoed and dropped by the parser")
+	}
+
+	response.IsEdns0().Hdr.Rdlength = 0
+	if Run(TestEDNSOption, response) != model.ComplianceStatusOK {
+		t.Error("Not accepting a response without the unknown option")
+	}
+}
+
+func TestSetStatus(t *testing.T) {
+	var compliance model.NameserverCompliance
+
+	SetStatus(&compliance, TestPlain, model.ComplianceStatusOK)
+	SetStatus(&compliance, TestEDNS, model.ComplianceStatusNoEDNS)
+	SetStatus(&compliance, TestEDNSDO, model.ComplianceStatusNoDO)
+	SetStatus(&compliance, TestEDNSOption, model.ComplianceStatusOptionEcho)
+	SetStatus(&compliance, TestTCP, model.ComplianceStatusTimeout)
+
+	if compliance.Plain != model.ComplianceStatusOK ||
+		compliance.EDNS != model.ComplianceStatusNoEDNS ||
+		compliance.EDNSDO != model.ComplianceStatusNoDO ||
+		compliance.EDNSOption != model.ComplianceStatusOptionEcho ||
+		compliance.TCP != model.ComplianceStatusTimeout {
+
+		t.Error("Not storing the compliance tests results correctly")
+	}
+}
@@ -7,6 +7,7 @@ package dnsutils
 
 import (
 	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/miekg/dns"
+	"strings"
 )
 
 // Useful function to retrieve all records of a specific type from the DNS response
@@ -22,6 +23,23 @@ func FilterRRs(rrs []dns.RR, rrType uint16) []dns.RR {
 	return filtered
 }
 
+// Useful function to retrieve the parent zone of a domain name. The parent of a top level
+// domain is the root zone, and the root zone doesn't have a parent, so an empty string is
+// returned
+func ParentZone(fqdn string) string {
+	fqdn = dns.Fqdn(fqdn)
+	if fqdn == "." {
+		return ""
+	}
+
+	labels := dns.SplitDomainName(fqdn)
+	if len(labels) <= 1 {
+		return "."
+	}
+
+	return dns.Fqdn(strings.Join(labels[1:], "."))
+}
+
 // Useful function to return the first occurence of a resource record of a specific type.
 // This method is faster than filterRRs when we are interested in only one record (like
 // SOA)
@@ -75,3 +75,22 @@ func TestFilterFirstRR(t *testing.T) {
 		t.Error("Found a RR that shouldn't exist")
 	}
 }
+
+func TestParentZone(t *testing.T) {
+	data := []struct {
+		fqdn   string
+		parent string
+	}{
+		{fqdn: "test.com.br.", parent: "com.br."},
+		{fqdn: "test.com.br", parent: "com.br."},
+		{fqdn: "br.", parent: "."},
+		{fqdn: ".", parent: ""},
+	}
+
+	for _, item := range data {
+		if parent := ParentZone(item.fqdn); parent != item.parent {
+			t.Errorf("Wrong parent zone for %s. Expected '%s' and got '%s'",
+				item.fqdn, item.parent, parent)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dspolicy store the DS record policies for DNSSEC configuration checks
+package dspolicy
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/miekg/dns"
+	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/scan/dnsutils"
+	"strings"
+	"time"
+)
+
+const (
+	// NSEC3 flag that indicates that the NSEC3 record may cover unsigned delegations (RFC
+	// 5155 - section 3.1.2.1)
+	nsec3OptOutFlag = 1
+)
+
+var (
+	// Maximum number of NSEC3 additional hash iterations that we consider safe. Many
+	// iterations increase the cost of the validation in the resolvers and don't add any real
+	// protection against zone walking (RFC 9276 - section 3.1)
+	MaxNSEC3Iterations = uint16(100)
+)
+
+// Verify if the zone can securely prove that a name does not exist. The response of a
+// query for a random name under the domain must have NSEC or NSEC3 records covering the
+// name, and these records must be signed by one of the zone's DNSKEYs. When we don't have
+// the response or the nameserver didn't answer with a name error (wildcards or DNS
+// problems) the policy is ignored. This method updates directly in the domain object
+func (d *DomainDSPolicy) denialPolicy(dnsResponseMessage *dns.Msg) bool {
+	if d.denialResponse == nil || d.denialResponse.Rcode != dns.RcodeNameError {
+		return true
+	}
+
+	// Get all DNSSEC public keys used to verify the NSEC/NSEC3 signatures
+	dnskeys := dnsutils.FilterRRs(dnsResponseMessage.Answer, dns.TypeDNSKEY)
+
+	// The denial of existence records and their signatures are in the authority section
+	nsecs := dnsutils.FilterRRs(d.denialResponse.Ns, dns.TypeNSEC)
+	nsec3s := dnsutils.FilterRRs(d.denialResponse.Ns, dns.TypeNSEC3)
+	rrsigs := dnsutils.FilterRRs(d.denialResponse.Ns, dns.TypeRRSIG)
+
+	var status model.DSStatus
+	if len(nsec3s) > 0 {
+		status = d.checkNSEC3(nsec3s, dnskeys, rrsigs)
+	} else if len(nsecs) > 0 {
+		status = d.checkNSEC(nsecs, dnskeys, rrsigs)
+	} else {
+		status = model.DSStatusNoDenial
+	}
+
+	if status == model.DSStatusOK {
+		return true
+	}
+
+	for index, _ := range d.domain.DSSet {
+		d.domain.DSSet[index].ChangeStatus(status)
+	}
+	return false
+}
+
+// Check the NSEC records of the name error response. We need a record covering the
+// queried name and a record covering the wildcard of the zone APEX, proving that the name
+// couldn't be synthesized (RFC 4035 - section 3.1.3.2)
+func (d *DomainDSPolicy) checkNSEC(nsecs []dns.RR,
+	dnskeys []dns.RR, rrsigs []dns.RR) model.DSStatus {
+
+	wildcard := "*." + d.domain.FQDN
+	nameCovered, wildcardCovered := false, false
+
+	for _, rr := range nsecs {
+		nsec, ok := rr.(*dns.NSEC)
+		if !ok {
+			continue
+		}
+
+		coversName := nsecCovers(nsec, d.denialName)
+		coversWildcard := nsecCovers(nsec, wildcard)
+
+		if !coversName && !coversWildcard {
+			continue
+		}
+
+		if !d.checkDenialSignature(nsec, dnskeys, rrsigs) {
+			return model.DSStatusDenialNoSignature
+		}
+
+		nameCovered = nameCovered || coversName
+		wildcardCovered = wildcardCovered || coversWildcard
+	}
+
+	if !nameCovered || !wildcardCovered {
+		return model.DSStatusNoDenial
+	}
+
+	return model.DSStatusOK
+}
+
+// Check the NSEC3 records of the name error response. Beyond the parameters of the NSEC3
+// records, we need the closest encloser proof, that in our case is the zone APEX, because
+// the queried name is a direct child of the domain. So we need a record matching the
+// APEX, a record covering the queried name and a record covering the wildcard of the APEX
+// (RFC 5155 - section 7.2.2)
+func (d *DomainDSPolicy) checkNSEC3(nsec3s []dns.RR,
+	dnskeys []dns.RR, rrsigs []dns.RR) model.DSStatus {
+
+	wildcard := "*." + d.domain.FQDN
+	apexMatched, nameCovered, wildcardCovered := false, false, false
+
+	for _, rr := range nsec3s {
+		nsec3, ok := rr.(*dns.NSEC3)
+		if !ok {
+			continue
+		}
+
+		if nsec3.Hash != dns.SHA1 ||
+			nsec3.Iterations > MaxNSEC3Iterations ||
+			(nsec3.Flags&nsec3OptOutFlag) != 0 {
+			return model.DSStatusDenialParameters
+		}
+
+		matchesApex := nsec3.Match(d.domain.FQDN)
+		coversName := nsec3Covers(nsec3, d.denialName)
+		coversWildcard := nsec3Covers(nsec3, wildcard)
+
+		if !matchesApex && !coversName && !coversWildcard {
+			continue
+		}
+
+		if !d.checkDenialSignature(nsec3, dnskeys, rrsigs) {
+			return model.DSStatusDenialNoSignature
+		}
+
+		apexMatched = apexMatched || matchesApex
+		nameCovered = nameCovered || coversName
+		wildcardCovered = wildcardCovered || coversWildcard
+	}
+
+	if !apexMatched || !nameCovered || !wildcardCovered {
+		return model.DSStatusNoDenial
+	}
+
+	return model.DSStatusOK
+}
+
+// Verify if the NSEC/NSEC3 record has a valid signature made by one of the zone's DNSKEYs
+func (d *DomainDSPolicy) checkDenialSignature(rr dns.RR,
+	dnskeys []dns.RR, rrsigs []dns.RR) bool {
+
+	for _, rrsigRR := range rrsigs {
+		rrsig, ok := rrsigRR.(*dns.RRSIG)
+		if !ok {
+			continue
+		}
+
+		if rrsig.TypeCovered != rr.Header().Rrtype ||
+			!strings.EqualFold(rrsig.Hdr.Name, rr.Header().Name) {
+			continue
+		}
+
+		// The base64 decode decode don't works well with spaces inside signatures blobs, so
+		// we remove them before checking with the DNSKEYs
+		rrsig.Signature = strings.Replace(rrsig.Signature, " ", "", -1)
+
+		dnskey := d.selectDNSKEY(dnskeys, rrsig.KeyTag)
+		if dnskey == nil || !rrsig.ValidityPeriod(time.Now()) {
+			continue
+		}
+
+		if err := rrsig.Verify(dnskey, []dns.RR{rr}); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check if the name is between the owner and the next name of the NSEC record, using the
+// canonical DNS name order (RFC 4034 - section 6.1). The last NSEC record of the zone
+// points to the zone APEX, so in this case the name only needs to be after the owner
+func nsecCovers(nsec *dns.NSEC, name string) bool {
+	owner, next := nsec.Hdr.Name, nsec.NextDomain
+
+	if compareCanonical(owner, next) < 0 {
+		return compareCanonical(owner, name) < 0 && compareCanonical(name, next) < 0
+	}
+
+	return compareCanonical(owner, name) < 0 || compareCanonical(name, next) < 0
+}
+
+// Check if the hash of the name is between the owner hash and the next hash of the NSEC3
+// record. The hashes are encoded in base32 with extended hex alphabet, that preserves the
+// sort order, so we can compare them as strings. Like NSEC, the last NSEC3 record of the
+// zone points to the first one
+func nsec3Covers(nsec3 *dns.NSEC3, name string) bool {
+	hashedName := dns.HashName(name, nsec3.Hash, nsec3.Iterations, nsec3.Salt)
+	if len(hashedName) == 0 {
+		return false
+	}
+
+	labels := dns.SplitDomainName(nsec3.Hdr.Name)
+	if len(labels) == 0 {
+		return false
+	}
+
+	owner := strings.ToUpper(labels[0])
+	next := strings.ToUpper(nsec3.NextDomain)
+
+	if owner < next {
+		return owner < hashedName && hashedName < next
+	}
+
+	return owner < hashedName || hashedName < next
+}
+
+// Compare two domain names using the canonical DNS name order (RFC 4034 - section 6.1).
+// The labels are compared from the rightmost to the leftmost, case insensitive. Returns a
+// negative number when the first name comes before, zero when the names are equal and a
+// positive number when the first name comes after
+func compareCanonical(name1, name2 string) int {
+	labels1 := dns.SplitDomainName(strings.ToLower(name1))
+	labels2 := dns.SplitDomainName(strings.ToLower(name2))
+
+	for i, j := len(labels1)-1, len(labels2)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if result := strings.Compare(labels1[i], labels2[j]); result != 0 {
+			return result
+		}
+	}
+
+	return len(labels1) - len(labels2)
+}
@@ -0,0 +1,298 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package dspolicy store the DS record policies for DNSSEC configuration checks
+package dspolicy
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/miekg/dns"
+	"github.com/rafaeljusto/shelter/model"
+	"testing"
+	"time"
+)
+
+func TestDenialPolicyIgnored(t *testing.T) {
+	domain := &model.Domain{
+		FQDN: "test.br.",
+		DSSet: []model.DS{
+			{
+				Keytag:     6726,
+				LastStatus: model.DSStatusOK,
+			},
+		},
+	}
+
+	domainDSPolicy := NewDomainDSPolicy(domain)
+
+	if !domainDSPolicy.denialPolicy(&dns.Msg{}) {
+		t.Error("Not ignoring the denial of existence check when there's no response")
+	}
+
+	domainDSPolicy.SetDenialResponse("shelter-1.test.br.", &dns.Msg{
+		MsgHdr: dns.MsgHdr{
+			Rcode: dns.RcodeSuccess,
+		},
+	})
+
+	if !domainDSPolicy.denialPolicy(&dns.Msg{}) ||
+		domain.DSSet[0].LastStatus != model.DSStatusOK {
+		t.Error("Not ignoring the denial of existence check when the name exists")
+	}
+}
+
+func TestDenialPolicyNoDenial(t *testing.T) {
+	domain := &model.Domain{
+		FQDN: "test.br.",
+		DSSet: []model.DS{
+			{
+				Keytag: 6726,
+			},
+		},
+	}
+
+	domainDSPolicy := NewDomainDSPolicy(domain)
+	domainDSPolicy.SetDenialResponse("shelter-1.test.br.", &dns.Msg{
+		MsgHdr: dns.MsgHdr{
+			Rcode: dns.RcodeNameError,
+		},
+	})
+
+	if domainDSPolicy.denialPolicy(&dns.Msg{}) ||
+		domain.DSSet[0].LastStatus != model.DSStatusNoDenial {
+		t.Error("Not detecting a name error without NSEC/NSEC3 records")
+	}
+}
+
+func TestDenialPolicyNSEC(t *testing.T) {
+	nsec := &dns.NSEC{
+		Hdr: dns.RR_Header{
+			Name:   "test.br.",
+			Rrtype: dns.TypeNSEC,
+		},
+		NextDomain: "www.test.br.",
+		TypeBitMap: []uint16{dns.TypeSOA, dns.TypeNS, dns.TypeDNSKEY},
+	}
+
+	dnskey, rrsig, err := generateKeyAndSignRR("test.br.", nsec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	domain := &model.Domain{
+		FQDN: "test.br.",
+		DSSet: []model.DS{
+			{
+				Keytag: dnskey.KeyTag(),
+			},
+		},
+	}
+
+	dnsResponseMessage := &dns.Msg{
+		Answer: []dns.RR{
+			dnskey,
+		},
+	}
+
+	domainDSPolicy := NewDomainDSPolicy(domain)
+	domainDSPolicy.SetDenialResponse("shelter-1.test.br.", &dns.Msg{
+		MsgHdr: dns.MsgHdr{
+			Rcode: dns.RcodeNameError,
+		},
+		Ns: []dns.RR{
+			nsec,
+			rrsig,
+		},
+	})
+
+	if !domainDSPolicy.denialPolicy(dnsResponseMessage) {
+		t.Error("Not accepting a valid NSEC denial of existence")
+	}
+
+	domainDSPolicy.SetDenialResponse("zzz.test.br.", &dns.Msg{
+		MsgHdr: dns.MsgHdr{
+			Rcode: dns.RcodeNameError,
+		},
+		Ns: []dns.RR{
+			nsec,
+			rrsig,
+		},
+	})
+
+	if domainDSPolicy.denialPolicy(dnsResponseMessage) ||
+		domain.DSSet[0].LastStatus != model.DSStatusNoDenial {
+		t.Error("Not detecting a NSEC record that doesn't cover the name")
+	}
+
+	domainDSPolicy.SetDenialResponse("shelter-1.test.br.", &dns.Msg{
+		MsgHdr: dns.MsgHdr{
+			Rcode: dns.RcodeNameError,
+		},
+		Ns: []dns.RR{
+			nsec,
+		},
+	})
+
+	if domainDSPolicy.denialPolicy(dnsResponseMessage) ||
+		domain.DSSet[0].LastStatus != model.DSStatusDenialNoSignature {
+		t.Error("Not detecting a NSEC record without signature")
+	}
+}
+
+func TestDenialPolicyNSEC3(t *testing.T) {
+	nsec3 := &dns.NSEC3{
+		Hdr: dns.RR_Header{
+			Rrtype: dns.TypeNSEC3,
+		},
+		Hash:       dns.SHA1,
+		Iterations: 10,
+		Salt:       "AABBCCDD",
+		SaltLength: 4,
+		TypeBitMap: []uint16{dns.TypeSOA, dns.TypeNS, dns.TypeDNSKEY},
+	}
+
+	// Zone with only the APEX, so the NSEC3 record points to itself
+	apexHash := dns.HashName("test.br.", nsec3.Hash, nsec3.Iterations, nsec3.Salt)
+	nsec3.Hdr.Name = apexHash + ".test.br."
+	nsec3.NextDomain = apexHash
+	nsec3.HashLength = 20
+
+	dnskey, rrsig, err := generateKeyAndSignRR("test.br.", nsec3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	domain := &model.Domain{
+		FQDN: "test.br.",
+		DSSet: []model.DS{
+			{
+				Keytag: dnskey.KeyTag(),
+			},
+		},
+	}
+
+	dnsResponseMessage := &dns.Msg{
+		Answer: []dns.RR{
+			dnskey,
+		},
+	}
+
+	domainDSPolicy := NewDomainDSPolicy(domain)
+	domainDSPolicy.SetDenialResponse("shelter-1.test.br.", &dns.Msg{
+		MsgHdr: dns.MsgHdr{
+			Rcode: dns.RcodeNameError,
+		},
+		Ns: []dns.RR{
+			nsec3,
+			rrsig,
+		},
+	})
+
+	if !domainDSPolicy.denialPolicy(dnsResponseMessage) {
+		t.Error("Not accepting a valid NSEC3 denial of existence")
+	}
+
+	nsec3.Iterations = MaxNSEC3Iterations + 1
+
+	if domainDSPolicy.denialPolicy(dnsResponseMessage) ||
+		domain.DSSet[0].LastStatus != model.DSStatusDenialParameters {
+		t.Error("Not detecting NSEC3 record with too many iterations")
+	}
+
+	nsec3.Iterations = 10
+	nsec3.Flags = nsec3OptOutFlag
+
+	if domainDSPolicy.denialPolicy(dnsResponseMessage) ||
+		domain.DSSet[0].LastStatus != model.DSStatusDenialParameters {
+		t.Error("Not detecting NSEC3 record with opt-out")
+	}
+}
+
+func TestNSECCovers(t *testing.T) {
+	nsec := &dns.NSEC{
+		Hdr: dns.RR_Header{
+			Name: "a.test.br.",
+		},
+		NextDomain: "d.test.br.",
+	}
+
+	if !nsecCovers(nsec, "b.test.br.") || !nsecCovers(nsec, "x.b.test.br.") {
+		t.Error("Not detecting names covered by the NSEC record")
+	}
+
+	if nsecCovers(nsec, "a.test.br.") || nsecCovers(nsec, "e.test.br.") ||
+		nsecCovers(nsec, "test.br.") {
+		t.Error("Detecting names that aren't covered by the NSEC record")
+	}
+
+	// Last NSEC record of the zone
+	nsec = &dns.NSEC{
+		Hdr: dns.RR_Header{
+			Name: "x.test.br.",
+		},
+		NextDomain: "test.br.",
+	}
+
+	if !nsecCovers(nsec, "z.test.br.") || nsecCovers(nsec, "b.test.br.") {
+		t.Error("Not checking the last NSEC record of the zone correctly")
+	}
+}
+
+func TestCompareCanonical(t *testing.T) {
+	// Canonical order example from RFC 4034 - section 6.1
+	names := []string{
+		"example.",
+		"a.example.",
+		"yljkjljk.a.example.",
+		"Z.a.example.",
+		"zABC.a.EXAMPLE.",
+		"z.example.",
+		"*.z.example.",
+	}
+
+	for i := 0; i < len(names)-1; i++ {
+		if compareCanonical(names[i], names[i+1]) >= 0 {
+			t.Errorf("Name %s should be before %s in canonical order", names[i], names[i+1])
+		}
+	}
+
+	if compareCanonical("Example.", "example.") != 0 {
+		t.Error("Not comparing names case insensitive")
+	}
+}
+
+func generateKeyAndSignRR(zone string, rr dns.RR) (*dns.DNSKEY, *dns.RRSIG, error) {
+	dnskey := &dns.DNSKEY{
+		Hdr: dns.RR_Header{
+			Name:   zone,
+			Rrtype: dns.TypeDNSKEY,
+		},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: dns.RSASHA1NSEC3SHA1,
+	}
+
+	privateKey, err := dnskey.Generate(1024)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rrsig := &dns.RRSIG{
+		Hdr: dns.RR_Header{
+			Name:   rr.Header().Name,
+			Rrtype: dns.TypeRRSIG,
+		},
+		TypeCovered: rr.Header().Rrtype,
+		Algorithm:   dnskey.Algorithm,
+		Expiration:  uint32(time.Now().Add(10 * time.Second).Unix()),
+		Inception:   uint32(time.Now().Unix()),
+		KeyTag:      dnskey.KeyTag(),
+		SignerName:  zone,
+	}
+
+	if err := rrsig.Sign(privateKey, []dns.RR{rr}); err != nil {
+		return nil, nil, err
+	}
+
+	return dnskey, rrsig, nil
+}
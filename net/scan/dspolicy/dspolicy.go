@@ -21,13 +21,20 @@ var (
 	dsPolicies = []func(*DomainDSPolicy, *dns.Msg) bool{
 		(*DomainDSPolicy).dnsHeaderPolicy,
 		(*DomainDSPolicy).dnssecPolicy,
+		(*DomainDSPolicy).apexPolicy,
+		(*DomainDSPolicy).parentPolicy,
+		(*DomainDSPolicy).denialPolicy,
 	}
 )
 
 // DomainDSPolicy store the domain object that is going to be updated during the policies
 // executions. The domain object cannot be null
 type DomainDSPolicy struct {
-	domain *model.Domain // Domain object that stores the last state of the DS records
+	domain         *model.Domain // Domain object that stores the last state of the DS records
+	parentResponse *dns.Msg      // DS response from the parent zone (chain of trust check)
+	denialName     string        // Non-existent name used in the denial of existence check
+	denialResponse *dns.Msg      // Response of the non-existent name query
+	apexResponses  []*dns.Msg    // Responses of the zone APEX RRsets (SOA and NS)
 }
 
 // This function initialize a DomainDSPolicy object, it was created to force the
@@ -39,6 +46,62 @@ func NewDomainDSPolicy(domain *model.Domain) DomainDSPolicy {
 	}
 }
 
+// ResetSignatureExpirations clears the signature expiration dates of the DS records,
+// returning the DS records with the previous dates. It must be called when a scan starts
+// checking the domain, because each DS stores the earliest expiration date found in the
+// scan, and the date of an old scan would stay forever after the zone is re-signed
+func ResetSignatureExpirations(domain *model.Domain) []model.DS {
+	previousDSSet := make([]model.DS, len(domain.DSSet))
+	copy(previousDSSet, domain.DSSet)
+
+	for index, _ := range domain.DSSet {
+		domain.DSSet[index].ExpiresAt = time.Time{}
+		domain.DSSet[index].ExpiresFrom = ""
+	}
+
+	return previousDSSet
+}
+
+// RestoreSignatureExpirations brings back the previous expiration dates of the DS records
+// that didn't have any signature evaluated in the scan (e.g. timeouts), so that the
+// expiration alert doesn't depend on a successful check
+func RestoreSignatureExpirations(domain *model.Domain, previousDSSet []model.DS) {
+	for index, ds := range domain.DSSet {
+		if !ds.ExpiresAt.IsZero() || index >= len(previousDSSet) ||
+			previousDSSet[index].Keytag != ds.Keytag {
+			continue
+		}
+
+		domain.DSSet[index].ExpiresAt = previousDSSet[index].ExpiresAt
+		domain.DSSet[index].ExpiresFrom = previousDSSet[index].ExpiresFrom
+	}
+}
+
+// SetParentResponse stores the DS query response retrieved from the parent zone, so that
+// the policies can verify the chain of trust. When the parent response is not informed
+// the chain of trust check is ignored
+func (d *DomainDSPolicy) SetParentResponse(parentResponse *dns.Msg) {
+	d.parentResponse = parentResponse
+}
+
+// AddApexResponse stores the response of a query for one of the zone APEX RRsets (SOA
+// or NS), so that the policies can verify the signatures of the RRset and the expiration
+// dates. Nil responses are ignored
+func (d *DomainDSPolicy) AddApexResponse(apexResponse *dns.Msg) {
+	if apexResponse != nil {
+		d.apexResponses = append(d.apexResponses, apexResponse)
+	}
+}
+
+// SetDenialResponse stores the response of a query for a name that doesn't exist in the
+// zone, so that the policies can verify the NSEC/NSEC3 records that prove the
+// non-existence. When the response is not informed the denial of existence check is
+// ignored
+func (d *DomainDSPolicy) SetDenialResponse(denialName string, denialResponse *dns.Msg) {
+	d.denialName = denialName
+	d.denialResponse = denialResponse
+}
+
 // When there's a error while sending a DS request over the network, this method is
 // responsable for detecting any usual problems, something like DNSSEC timeouts. Generic
 // kinds of errors should be visible when checking the nameserver policies
@@ -113,9 +176,9 @@ func (d *DomainDSPolicy) dnssecPolicy(dnsResponseMessage *dns.Msg) bool {
 
 	success := true
 	for index, ds := range d.domain.DSSet {
-		status, signatureExpiration := d.checkDS(ds, dnskeys, rrsigs)
+		status, signatureExpiration, expiresFrom := d.checkDS(ds, dnskeys, rrsigs)
 		d.domain.DSSet[index].ChangeStatus(status)
-		d.domain.DSSet[index].ExpiresAt = signatureExpiration
+		d.storeExpiration(index, signatureExpiration, expiresFrom)
 
 		if status != model.DSStatusOK {
 			success = false
@@ -124,24 +187,220 @@ func (d *DomainDSPolicy) dnssecPolicy(dnsResponseMessage *dns.Msg) bool {
 	return success
 }
 
+// Verify the signatures of the zone APEX RRsets (SOA and NS). Zones usually break because
+// the signatures of these RRsets expire while the DNSKEY signature is still valid. Each DS
+// stores the earliest signature expiration date of the zone and the related RRset, so
+// that the owners can be alerted before the signatures expire. This method updates
+// directly in the domain object
+func (d *DomainDSPolicy) apexPolicy(dnsResponseMessage *dns.Msg) bool {
+	// Get all DNSSEC public keys used to verify the APEX signatures
+	dnskeys := dnsutils.FilterRRs(dnsResponseMessage.Answer, dns.TypeDNSKEY)
+
+	var expiresAt time.Time
+	var expiresFrom string
+
+	for _, apexResponse := range d.apexResponses {
+		if apexResponse.Rcode != dns.RcodeSuccess || len(apexResponse.Question) == 0 {
+			continue
+		}
+
+		rrType := apexResponse.Question[0].Qtype
+		rrset := dnsutils.FilterRRs(apexResponse.Answer, rrType)
+		if len(rrset) == 0 {
+			continue
+		}
+
+		status, signatureExpiration := d.checkRRSet(rrset, rrType,
+			dnsutils.FilterRRs(apexResponse.Answer, dns.TypeRRSIG), dnskeys)
+
+		if status != model.DSStatusOK {
+			for index, _ := range d.domain.DSSet {
+				d.domain.DSSet[index].ChangeStatus(status)
+				d.storeExpiration(index, signatureExpiration, dns.TypeToString[rrType])
+			}
+			return false
+		}
+
+		if expiresAt.IsZero() || signatureExpiration.Before(expiresAt) {
+			expiresAt = signatureExpiration
+			expiresFrom = dns.TypeToString[rrType]
+		}
+	}
+
+	if expiresAt.IsZero() {
+		return true
+	}
+
+	for index, _ := range d.domain.DSSet {
+		d.storeExpiration(index, expiresAt, expiresFrom)
+	}
+
+	return true
+}
+
+// Store the signature expiration date in the DS when it's the earliest one found in the
+// current scan. The same DS is checked in all nameservers of the domain, and the dates
+// of the last scan were already cleared with ResetSignatureExpirations
+func (d *DomainDSPolicy) storeExpiration(index int, expiresAt time.Time, expiresFrom string) {
+	if expiresAt.IsZero() {
+		return
+	}
+
+	ds := &d.domain.DSSet[index]
+	if ds.ExpiresAt.IsZero() || expiresAt.Before(ds.ExpiresAt) {
+		ds.ExpiresAt = expiresAt
+		ds.ExpiresFrom = expiresFrom
+	}
+}
+
+// Check the signatures of a RRset. At least one signature must be valid, as we could have
+// more than one signature in an algorithm rollover. Beyond the status, it returns the
+// earliest expiration date of the valid signatures, or of all signatures if none of them
+// is valid
+func (d *DomainDSPolicy) checkRRSet(rrset []dns.RR, rrType uint16,
+	rrsigs []dns.RR, dnskeys []dns.RR) (model.DSStatus, time.Time) {
+
+	var validExpiration, expiration time.Time
+	expired, valid := false, false
+
+	for _, rr := range rrsigs {
+		rrsig, ok := rr.(*dns.RRSIG)
+		if !ok || rrsig.TypeCovered != rrType {
+			continue
+		}
+
+		// The base64 decode decode don't works well with spaces inside signatures blobs, so
+		// we remove them before checking with the DNSKEYs
+		rrsig.Signature = strings.Replace(rrsig.Signature, " ", "", -1)
+
+		signatureExpiration := time.Unix(int64(rrsig.Expiration), 0)
+		if expiration.IsZero() || signatureExpiration.Before(expiration) {
+			expiration = signatureExpiration
+		}
+
+		if !rrsig.ValidityPeriod(time.Now()) {
+			expired = true
+			continue
+		}
+
+		dnskey := d.selectDNSKEY(dnskeys, rrsig.KeyTag)
+		if dnskey == nil {
+			continue
+		}
+
+		if err := rrsig.Verify(dnskey, rrset); err != nil {
+			continue
+		}
+
+		valid = true
+		if validExpiration.IsZero() || signatureExpiration.Before(validExpiration) {
+			validExpiration = signatureExpiration
+		}
+	}
+
+	if valid {
+		return model.DSStatusOK, validExpiration
+
+	} else if expiration.IsZero() {
+		return model.DSStatusNoSignature, expiration
+
+	} else if expired {
+		return model.DSStatusExpiredSignature, expiration
+	}
+
+	return model.DSStatusSignatureError, expiration
+}
+
+// Verify if the DS records of the domain are really published in the parent zone, and if
+// the published digests match the DNSKEYs of the child zone, closing the chain of trust.
+// When we don't have the parent's response or the parent returned an error we can't tell
+// anything about the chain of trust, so the policy is ignored. This method updates
+// directly in the domain object
+func (d *DomainDSPolicy) parentPolicy(dnsResponseMessage *dns.Msg) bool {
+	if d.parentResponse == nil || d.parentResponse.Rcode != dns.RcodeSuccess {
+		return true
+	}
+
+	// Get all DS records published in the parent zone
+	parentDSSet := dnsutils.FilterRRs(d.parentResponse.Answer, dns.TypeDS)
+
+	// Get all DNSSEC public keys from the child zone
+	dnskeys := dnsutils.FilterRRs(dnsResponseMessage.Answer, dns.TypeDNSKEY)
+
+	success := true
+	for index, ds := range d.domain.DSSet {
+		status := d.checkParentDS(ds, parentDSSet, dnskeys)
+		if status == model.DSStatusOK {
+			continue
+		}
+
+		d.domain.DSSet[index].ChangeStatus(status)
+		success = false
+	}
+	return success
+}
+
+// For each DS of the domain object we look for the DS records with the same keytag and
+// algorithm in the parent zone. The digests of these records are compared with the digest
+// generated from the DNSKEY of the child zone and with the digest informed by the user
+func (d *DomainDSPolicy) checkParentDS(ds model.DS,
+	parentDSSet []dns.RR, dnskeys []dns.RR) model.DSStatus {
+
+	selectedDNSKEY := d.selectDNSKEY(dnskeys, ds.Keytag)
+
+	published := false
+	for _, rr := range parentDSSet {
+		parentDS, ok := rr.(*dns.DS)
+		if !ok {
+			continue
+		}
+
+		if parentDS.KeyTag != ds.Keytag || parentDS.Algorithm != uint8(ds.Algorithm) {
+			continue
+		}
+
+		published = true
+
+		// Hash generated by library is always lower case, and the parent could answer with
+		// upper case digests
+		parentDigest := strings.ToLower(parentDS.Digest)
+
+		if parentDS.DigestType == uint8(ds.DigestType) &&
+			parentDigest != strings.ToLower(ds.Digest) {
+			return model.DSStatusDigestMismatch
+		}
+
+		if selectedDNSKEY != nil &&
+			selectedDNSKEY.ToDS(parentDS.DigestType).Digest != parentDigest {
+			return model.DSStatusDigestMismatch
+		}
+	}
+
+	if !published {
+		return model.DSStatusNotPublished
+	}
+
+	return model.DSStatusOK
+}
+
 // For each DS of the domain object we verify a couple of rules with the DNS response
 // data. It will return beyond the DS status, the current expiration date retrieved from
-// the network, if the expiration date could not be retrieved, we return the current
-// expiration date of the DS object
+// the network and the RRset of the signature, if the expiration date could not be
+// retrieved, we return the current expiration date of the DS object
 func (d *DomainDSPolicy) checkDS(ds model.DS,
-	dnskeys []dns.RR, rrsigs []dns.RR) (model.DSStatus, time.Time) {
+	dnskeys []dns.RR, rrsigs []dns.RR) (model.DSStatus, time.Time, string) {
 
 	// Find the DNSSEC public key related to the DS
 	selectedDNSKEY := d.selectDNSKEY(dnskeys, ds.Keytag)
 
 	if selectedDNSKEY == nil {
-		return model.DSStatusNoKey, ds.ExpiresAt
+		return model.DSStatusNoKey, ds.ExpiresAt, ds.ExpiresFrom
 	}
 
 	// Check if the DNSSEC key related to the DS has the security entry point. Check RFCs
 	// 3755 and 4034
 	if (selectedDNSKEY.Flags & dns.SEP) == 0 {
-		return model.DSStatusNoSEP, ds.ExpiresAt
+		return model.DSStatusNoSEP, ds.ExpiresAt, ds.ExpiresFrom
 	}
 
 	// Find the signature of the DNSSEC key that signed the keyset
@@ -149,6 +408,7 @@ func (d *DomainDSPolicy) checkDS(ds model.DS,
 
 	// Keep the same expiration if we don't find a new one
 	signatureExpiration := ds.ExpiresAt
+	expiresFrom := ds.ExpiresFrom
 
 	// It's OK to have a DNSKEY without signature, as it is in a key rollover (pre-publish
 	// strategy)
@@ -157,25 +417,26 @@ func (d *DomainDSPolicy) checkDS(ds model.DS,
 		// near. There's no status in DS to define a near expiration state, because this
 		// isn't a problem
 		signatureExpiration = time.Unix(int64(selectedRRSIG.Expiration), 0)
+		expiresFrom = dns.TypeToString[dns.TypeDNSKEY]
 
 		// Check signature expiration
 		if !selectedRRSIG.ValidityPeriod(time.Now()) {
-			return model.DSStatusExpiredSignature, signatureExpiration
+			return model.DSStatusExpiredSignature, signatureExpiration, expiresFrom
 		}
 
 		// Check signature consistency
 		if err := selectedRRSIG.Verify(selectedDNSKEY, dnskeys); err != nil {
-			return model.DSStatusSignatureError, signatureExpiration
+			return model.DSStatusSignatureError, signatureExpiration, expiresFrom
 		}
 	}
 
 	// Check DNSKEY hash is the same of the DS digest, hash generated by library is always
 	// lower case
 	if selectedDNSKEY.ToDS(uint8(ds.DigestType)).Digest != strings.ToLower(ds.Digest) {
-		return model.DSStatusNoKey, signatureExpiration
+		return model.DSStatusNoKey, signatureExpiration, expiresFrom
 	}
 
-	return model.DSStatusOK, signatureExpiration
+	return model.DSStatusOK, signatureExpiration, expiresFrom
 }
 
 // selectDNSKEY is responsable for finding the DNSKEY that was used to generate the DS. We
@@ -8,6 +8,7 @@ package dspolicy
 import (
 	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/miekg/dns"
 	"github.com/rafaeljusto/shelter/model"
+	"strings"
 	"testing"
 	"time"
 )
@@ -434,6 +435,342 @@ func TestDNSSECPolicyWrongDSDigest(t *testing.T) {
 	}
 }
 
+func TestApexPolicy(t *testing.T) {
+	soa := &dns.SOA{
+		Hdr: dns.RR_Header{
+			Name:   "test.br.",
+			Rrtype: dns.TypeSOA,
+		},
+		Ns:     "ns1.test.br.",
+		Mbox:   "hostmaster.test.br.",
+		Serial: 2014010100,
+	}
+
+	dnskey, rrsig, err := generateKeyAndSignRR("test.br.", soa)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	domain := &model.Domain{
+		FQDN: "test.br.",
+		DSSet: []model.DS{
+			{
+				Keytag:      dnskey.KeyTag(),
+				ExpiresAt:   time.Now().Add(24 * time.Hour),
+				ExpiresFrom: "DNSKEY",
+			},
+		},
+	}
+
+	dnsResponseMessage := &dns.Msg{
+		Answer: []dns.RR{
+			dnskey,
+		},
+	}
+
+	apexResponse := new(dns.Msg)
+	apexResponse.SetQuestion("test.br.", dns.TypeSOA)
+	apexResponse.Answer = []dns.RR{soa, rrsig}
+
+	domainDSPolicy := NewDomainDSPolicy(domain)
+	domainDSPolicy.AddApexResponse(nil)
+	domainDSPolicy.AddApexResponse(apexResponse)
+
+	if !domainDSPolicy.apexPolicy(dnsResponseMessage) {
+		t.Error("Not accepting a valid SOA signature")
+	}
+
+	if domain.DSSet[0].ExpiresAt.Unix() != int64(rrsig.Expiration) ||
+		domain.DSSet[0].ExpiresFrom != "SOA" {
+		t.Error("Not storing the earliest signature expiration date")
+	}
+
+	apexResponse.Answer = []dns.RR{soa}
+
+	if domainDSPolicy.apexPolicy(dnsResponseMessage) ||
+		domain.DSSet[0].LastStatus != model.DSStatusNoSignature {
+		t.Error("Not detecting a SOA without signature")
+	}
+
+	apexResponse.Answer = []dns.RR{soa, rrsig}
+	soa.Serial++
+
+	if domainDSPolicy.apexPolicy(dnsResponseMessage) ||
+		domain.DSSet[0].LastStatus != model.DSStatusSignatureError {
+		t.Error("Not detecting a SOA with invalid signature")
+	}
+
+	soa.Serial--
+	rrsig.Expiration = uint32(time.Now().Add(-10 * time.Second).Unix())
+
+	if domainDSPolicy.apexPolicy(dnsResponseMessage) ||
+		domain.DSSet[0].LastStatus != model.DSStatusExpiredSignature ||
+		domain.DSSet[0].ExpiresFrom != "SOA" {
+		t.Error("Not detecting a SOA with expired signature")
+	}
+}
+
+func TestResetSignatureExpirations(t *testing.T) {
+	dnskey, rrsig, err := generateKeyAndSignZone("test.br.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := dnskey.ToDS(uint8(model.DSDigestTypeSHA1))
+
+	// Expiration date of a signature found in the last scan, before the zone was re-signed
+	domain := &model.Domain{
+		DSSet: []model.DS{
+			{
+				Keytag:      dnskey.KeyTag(),
+				Algorithm:   convertKeyAlgorithm(dnskey.Algorithm),
+				DigestType:  model.DSDigestTypeSHA1,
+				Digest:      ds.Digest,
+				ExpiresAt:   time.Now().Add(-1 * time.Hour),
+				ExpiresFrom: "SOA",
+			},
+		},
+	}
+
+	ResetSignatureExpirations(domain)
+
+	if !domain.DSSet[0].ExpiresAt.IsZero() || len(domain.DSSet[0].ExpiresFrom) > 0 {
+		t.Fatal("Not clearing the signature expiration of the last scan")
+	}
+
+	domainDSPolicy := NewDomainDSPolicy(domain)
+
+	dnsResponseMessage := &dns.Msg{
+		Answer: []dns.RR{
+			dnskey,
+			rrsig,
+		},
+	}
+
+	if !domainDSPolicy.dnssecPolicy(dnsResponseMessage) {
+		t.Fatal("Not accepting a valid DS")
+	}
+
+	if domain.DSSet[0].ExpiresAt.Unix() != int64(rrsig.Expiration) ||
+		domain.DSSet[0].ExpiresFrom != "DNSKEY" {
+		t.Error("Not storing the signature expiration of the current scan")
+	}
+
+	// Other nameserver of the same domain answered before with a signature that expires
+	// earlier, it cannot be replaced in the same scan
+	earliestExpiration := time.Unix(int64(rrsig.Expiration), 0).Add(-1 * time.Hour)
+	domain.DSSet[0].ExpiresAt = earliestExpiration
+	domain.DSSet[0].ExpiresFrom = "SOA"
+
+	if !domainDSPolicy.dnssecPolicy(dnsResponseMessage) {
+		t.Fatal("Not accepting a valid DS")
+	}
+
+	if !domain.DSSet[0].ExpiresAt.Equal(earliestExpiration) ||
+		domain.DSSet[0].ExpiresFrom != "SOA" {
+		t.Error("Not keeping the earliest signature expiration of the scan")
+	}
+}
+
+func TestRestoreSignatureExpirations(t *testing.T) {
+	lastExpiration := time.Now().Add(30 * 24 * time.Hour)
+	currentExpiration := time.Now().Add(60 * 24 * time.Hour)
+
+	domain := &model.Domain{
+		DSSet: []model.DS{
+			{
+				Keytag:      6726,
+				ExpiresAt:   lastExpiration,
+				ExpiresFrom: "DNSKEY",
+				LastStatus:  model.DSStatusOK,
+			},
+			{
+				Keytag:      6727,
+				ExpiresAt:   lastExpiration,
+				ExpiresFrom: "DNSKEY",
+				LastStatus:  model.DSStatusOK,
+			},
+		},
+	}
+
+	previousDSSet := ResetSignatureExpirations(domain)
+
+	// The first DS has a timeout, so no signature is evaluated, and the second one has a
+	// new signature
+	domainDSPolicy := NewDomainDSPolicy(domain)
+	domainDSPolicy.CheckNetworkError(myErr{timeout: true})
+	domain.DSSet[1].ExpiresAt = currentExpiration
+	domain.DSSet[1].ExpiresFrom = "SOA"
+
+	RestoreSignatureExpirations(domain, previousDSSet)
+
+	if !domain.DSSet[0].ExpiresAt.Equal(lastExpiration) ||
+		domain.DSSet[0].ExpiresFrom != "DNSKEY" {
+		t.Error("Not keeping the signature expiration when no signature was evaluated")
+	}
+
+	if !domain.DSSet[1].ExpiresAt.Equal(currentExpiration) ||
+		domain.DSSet[1].ExpiresFrom != "SOA" {
+		t.Error("Replacing the signature expiration found in the scan")
+	}
+}
+
+func TestParentPolicy(t *testing.T) {
+	dnskey, rrsig, err := generateKeyAndSignZone("test.br.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := dnskey.ToDS(uint8(model.DSDigestTypeSHA1))
+
+	domain := &model.Domain{
+		DSSet: []model.DS{
+			{
+				Keytag:     dnskey.KeyTag(),
+				Algorithm:  convertKeyAlgorithm(dnskey.Algorithm),
+				DigestType: model.DSDigestTypeSHA1,
+				Digest:     ds.Digest,
+			},
+		},
+	}
+
+	domainDSPolicy := NewDomainDSPolicy(domain)
+
+	dnsResponseMessage := &dns.Msg{
+		Answer: []dns.RR{
+			dnskey,
+			rrsig,
+		},
+	}
+
+	if !domainDSPolicy.parentPolicy(dnsResponseMessage) {
+		t.Error("Not ignoring the parent check when there's no parent response")
+	}
+
+	domainDSPolicy.SetParentResponse(&dns.Msg{
+		MsgHdr: dns.MsgHdr{
+			Rcode: dns.RcodeServerFailure,
+		},
+	})
+
+	if !domainDSPolicy.parentPolicy(dnsResponseMessage) {
+		t.Error("Not ignoring the parent check when the parent returned an error")
+	}
+
+	domainDSPolicy.SetParentResponse(&dns.Msg{
+		MsgHdr: dns.MsgHdr{
+			Rcode: dns.RcodeSuccess,
+		},
+		Answer: []dns.RR{
+			ds,
+		},
+	})
+
+	if !domainDSPolicy.parentPolicy(dnsResponseMessage) {
+		t.Error("Not accepting a DS published correctly in the parent zone")
+	}
+}
+
+func TestParentPolicyNotPublished(t *testing.T) {
+	dnskey, rrsig, err := generateKeyAndSignZone("test.br.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := dnskey.ToDS(uint8(model.DSDigestTypeSHA1))
+
+	domain := &model.Domain{
+		DSSet: []model.DS{
+			{
+				Keytag:     dnskey.KeyTag(),
+				Algorithm:  convertKeyAlgorithm(dnskey.Algorithm),
+				DigestType: model.DSDigestTypeSHA1,
+				Digest:     ds.Digest,
+			},
+		},
+	}
+
+	domainDSPolicy := NewDomainDSPolicy(domain)
+	domainDSPolicy.SetParentResponse(&dns.Msg{
+		MsgHdr: dns.MsgHdr{
+			Rcode: dns.RcodeSuccess,
+		},
+	})
+
+	dnsResponseMessage := &dns.Msg{
+		Answer: []dns.RR{
+			dnskey,
+			rrsig,
+		},
+	}
+
+	if domainDSPolicy.parentPolicy(dnsResponseMessage) ||
+		domain.DSSet[0].LastStatus != model.DSStatusNotPublished {
+		t.Error("Not detecting DS that is not published in the parent zone")
+	}
+}
+
+func TestParentPolicyDigestMismatch(t *testing.T) {
+	dnskey, rrsig, err := generateKeyAndSignZone("test.br.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := dnskey.ToDS(uint8(model.DSDigestTypeSHA1))
+
+	domain := &model.Domain{
+		DSSet: []model.DS{
+			{
+				Keytag:     dnskey.KeyTag(),
+				Algorithm:  convertKeyAlgorithm(dnskey.Algorithm),
+				DigestType: model.DSDigestTypeSHA1,
+				Digest:     ds.Digest,
+			},
+		},
+	}
+
+	parentDS := dnskey.ToDS(uint8(model.DSDigestTypeSHA1))
+	parentDS.Digest = strings.Repeat("f", len(parentDS.Digest))
+
+	domainDSPolicy := NewDomainDSPolicy(domain)
+	domainDSPolicy.SetParentResponse(&dns.Msg{
+		MsgHdr: dns.MsgHdr{
+			Rcode: dns.RcodeSuccess,
+		},
+		Answer: []dns.RR{
+			parentDS,
+		},
+	})
+
+	dnsResponseMessage := &dns.Msg{
+		Answer: []dns.RR{
+			dnskey,
+			rrsig,
+		},
+	}
+
+	if domainDSPolicy.parentPolicy(dnsResponseMessage) ||
+		domain.DSSet[0].LastStatus != model.DSStatusDigestMismatch {
+		t.Error("Not detecting DS digest mismatch in the parent zone")
+	}
+
+	// The parent DS with another digest type must match the child DNSKEY
+	parentDS = dnskey.ToDS(uint8(model.DSDigestTypeSHA256))
+	parentDS.Digest = strings.Repeat("f", len(parentDS.Digest))
+
+	domain.DSSet[0].LastStatus = model.DSStatusOK
+	domainDSPolicy.SetParentResponse(&dns.Msg{
+		MsgHdr: dns.MsgHdr{
+			Rcode: dns.RcodeSuccess,
+		},
+		Answer: []dns.RR{
+			ds,
+			parentDS,
+		},
+	})
+
+	if domainDSPolicy.parentPolicy(dnsResponseMessage) ||
+		domain.DSSet[0].LastStatus != model.DSStatusDigestMismatch {
+		t.Error("Not detecting parent DS digest that doesn't match the DNSKEY")
+	}
+}
+
 func TestSelectDNSKEY(t *testing.T) {
 	dnskey, rrsig, err := generateKeyAndSignZone("test.br.")
 	if err != nil {
@@ -6,7 +6,6 @@
 package scan
 
 import (
-	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
 	"github.com/rafaeljusto/shelter/dao"
 	"github.com/rafaeljusto/shelter/model"
 	"sync"
@@ -16,19 +15,23 @@ import (
 // selecting the domains the injector will add to a channel, so that the querier can start
 // immediately
 type Injector struct {
-	Database                 *mgo.Database // Low level database connection
-	DomainsBufferSize        int           // Size of the domains to query channel
-	MaxOKVerificationDays    int           // Maximum number of days to verify a domain configured correctly with DNS/DNSSEC
-	MaxErrorVerificationDays int           // Maximum number of days to verify a domain with problems
-	MaxExpirationAlertDays   int           // Number of days to alert for DNSSEC signatures that are near from the expiration date
+	Storage                  dao.Storage // Persistence backend of the domains
+	DomainsBufferSize        int         // Size of the domains to query channel
+	MaxOKVerificationDays    int         // Maximum number of days to verify a domain configured correctly with DNS/DNSSEC
+	MaxErrorVerificationDays int         // Maximum number of days to verify a domain with problems
+	MaxExpirationAlertDays   int         // Number of days to alert for DNSSEC signatures that are near from the expiration date
+
+	// Domains of an on-demand scan. When defined, the verification intervals are ignored
+	// and all selected domains are checked
+	Selection *ScanSelection
 }
 
 // Return a new Injector object with the necessary fields for the scan filled
-func NewInjector(database *mgo.Database, domainsBufferSize, maxOKVerificationDays,
+func NewInjector(storage dao.Storage, domainsBufferSize, maxOKVerificationDays,
 	maxErrorVerificationDays, maxExpirationAlertDays int) *Injector {
 
 	return &Injector{
-		Database:                 database,
+		Storage:                  storage,
 		DomainsBufferSize:        domainsBufferSize,
 		MaxOKVerificationDays:    maxOKVerificationDays,
 		MaxErrorVerificationDays: maxErrorVerificationDays,
@@ -52,10 +55,8 @@ func (i *Injector) Start(scanGroup *sync.WaitGroup, errorsChannel chan error) ch
 	scanGroup.Add(1)
 
 	go func() {
-		// Initialize Domain DAO using injected database connection
-		domainDAO := dao.DomainDAO{
-			Database: i.Database,
-		}
+		// Initialize Domain DAO using injected persistence backend
+		domainDAO := i.Storage.DomainDAO()
 
 		// Load all domains from database to begin the scan
 		domainChannel, err := domainDAO.FindAllAsync()
@@ -74,6 +75,10 @@ func (i *Injector) Start(scanGroup *sync.WaitGroup, errorsChannel chan error) ch
 			return
 		}
 
+		// When the scan is cancelled we keep reading the domains from the database without
+		// sending them, so that the database iterator is released
+		cancelled := false
+
 		// Dispatch the asynchronous part of the method
 		for {
 			// Get domain from the database (one-by-one)
@@ -98,10 +103,25 @@ func (i *Injector) Start(scanGroup *sync.WaitGroup, errorsChannel chan error) ch
 				return
 			}
 
+			// Wait here while the scan is paused, so that no new domain is loaded. The database
+			// iterator stays open, so the pause is limited by model.MaxScanPauseDuration
+			if cancelled || !model.ContinueScan() {
+				cancelled = true
+				continue
+			}
+
 			// The logic that decides if a domain is going to be a part of this scan or not is
 			// inside the domain object for better unit testing
-			if domainResult.Domain.ShouldBeScanned(i.MaxOKVerificationDays,
-				i.MaxErrorVerificationDays, i.MaxExpirationAlertDays) {
+			var selected bool
+			if i.Selection != nil {
+				selected = i.Selection.match(domainResult.Domain)
+
+			} else {
+				selected = domainResult.Domain.ShouldBeScanned(i.MaxOKVerificationDays,
+					i.MaxErrorVerificationDays, i.MaxExpirationAlertDays)
+			}
+
+			if selected {
 				// Send to the querier
 				domainsToQueryChannel <- domainResult.Domain
 
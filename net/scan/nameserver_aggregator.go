@@ -0,0 +1,79 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package scan is the scan service
+package scan
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/model"
+	"time"
+)
+
+// Walk through all domains of the database building the summary of each nameserver host,
+// so that the user can find broken providers without looking domain by domain. The
+// timeouts are retrieved from the querier cache, that keeps them while the system is
+// running. Hosts that aren't used by any domain anymore are removed
+func aggregateNameservers(storage dao.Storage, database *mgo.Database) error {
+	domainDAO := storage.DomainDAO()
+
+	domainChannel, err := domainDAO.FindAllAsync()
+	if err != nil {
+		return err
+	}
+
+	summaries := make(map[string]*model.NameserverSummary)
+
+	for {
+		domainResult := <-domainChannel
+
+		if domainResult.Error != nil {
+			return domainResult.Error
+		}
+
+		// The channel returns a nil domain when there's no more domains to retrieve
+		if domainResult.Domain == nil {
+			break
+		}
+
+		for _, nameserver := range domainResult.Domain.Nameservers {
+			summary, found := summaries[nameserver.Host]
+			if !found {
+				newSummary := model.NewNameserverSummary(nameserver.Host)
+				summary = &newSummary
+				summaries[nameserver.Host] = summary
+			}
+
+			summary.AddNameserver(nameserver)
+		}
+	}
+
+	nameserverSummaryDAO := dao.NameserverSummaryDAO{
+		Database: database,
+	}
+
+	// All summaries saved before this date are from hosts that aren't delegated anymore
+	aggregationStartedAt := time.Now().UTC()
+
+	for host, summary := range summaries {
+		summary.Timeouts = querierCache.NameserverTimeouts(host)
+
+		// Keep the database identification and revision of the nameserver when it was
+		// already aggregated in a previous scan
+		if existingSummary, err := nameserverSummaryDAO.FindByHost(host); err == nil {
+			summary.Id = existingSummary.Id
+			summary.Revision = existingSummary.Revision
+
+		} else if err != mgo.ErrNotFound {
+			return err
+		}
+
+		if err := nameserverSummaryDAO.Save(summary); err != nil {
+			return err
+		}
+	}
+
+	return nameserverSummaryDAO.RemoveNotModifiedSince(aggregationStartedAt)
+}
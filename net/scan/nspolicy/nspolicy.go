@@ -6,10 +6,12 @@
 package nspolicy
 
 import (
+	"fmt"
 	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/miekg/dns"
 	"github.com/rafaeljusto/shelter/model"
 	"github.com/rafaeljusto/shelter/net/scan/dnsutils"
 	"net"
+	"strings"
 	"syscall"
 )
 
@@ -22,15 +24,30 @@ var (
 		(*DomainNSPolicy).rcodePolicy,
 		(*DomainNSPolicy).authorityPolicy,
 		(*DomainNSPolicy).soaPolicy,
+		(*DomainNSPolicy).soaFieldsPolicy,
+		(*DomainNSPolicy).nsSetPolicy,
+		(*DomainNSPolicy).dnskeySetPolicy,
+	}
+
+	// List of all delegation policies, that compare each nameserver with the referral
+	// retrieved from the parent zone. As the other list, the order is important
+	delegationPolicies = []func(*DomainNSPolicy, model.Nameserver, *dns.Msg) model.NameserverStatus{
+		(*DomainNSPolicy).parentNSPolicy,
+		(*DomainNSPolicy).gluePolicy,
 	}
 )
 
 // DomainNSPolicy store the domain object and the version of the DNS zone. This is
 // necessary because we need to check the DNS zone version on each nameserver and detect
-// if they are different
+// if they are different. The same idea is used for the SOA fields and for the DNSKEY
+// RRset, the first nameserver checked is the reference for the other ones
 type DomainNSPolicy struct {
-	domain     *model.Domain // Domain object is used for glue validations
-	soaVersion uint32        // Variable used to check if all nameservers have the same zone
+	domain         *model.Domain // Domain object is used for glue validations
+	soaVersion     uint32        // Variable used to check if all nameservers have the same zone
+	soa            *dns.SOA      // SOA of the first nameserver to compare the other fields
+	dnskeys        []string      // DNSKEY RRset of the first nameserver (normalized)
+	nsResponse     *dns.Msg      // NS query response of the current nameserver
+	dnskeyResponse *dns.Msg      // DNSKEY query response of the current nameserver
 }
 
 // This function initialize a DomainNSPolicy object, it was created to force the
@@ -43,6 +60,20 @@ func NewDomainNSPolicy(domain *model.Domain) DomainNSPolicy {
 	}
 }
 
+// SetNSResponse stores the response of the NS query sent to the nameserver that is going
+// to be checked in the next Run call. When the response is not informed the NS RRset
+// check is ignored
+func (d *DomainNSPolicy) SetNSResponse(nsResponse *dns.Msg) {
+	d.nsResponse = nsResponse
+}
+
+// SetDNSKEYResponse stores the response of the DNSKEY query sent to the nameserver that
+// is going to be checked in the next Run call. When the response is not informed the
+// DNSKEY RRset check is ignored
+func (d *DomainNSPolicy) SetDNSKEYResponse(dnskeyResponse *dns.Msg) {
+	d.dnskeyResponse = dnskeyResponse
+}
+
 // When there's a error while sending a nameserver request over the network, this method
 // is responsable for detecting any usual problems. There's also some unknown problems
 // that are going to be treated as DNS error, for now we are not logging the generic
@@ -85,6 +116,13 @@ func (d *DomainNSPolicy) Run(dnsResponseMessage *dns.Msg) model.NameserverStatus
 		return model.NameserverStatusError
 	}
 
+	// The NS and DNSKEY responses are related only to the current nameserver, so we don't
+	// keep them for the next one
+	defer func() {
+		d.nsResponse = nil
+		d.dnskeyResponse = nil
+	}()
+
 	for _, policy := range nsPolicies {
 		if status := policy(d, dnsResponseMessage); status != model.NameserverStatusOK {
 			return status
@@ -94,6 +132,38 @@ func (d *DomainNSPolicy) Run(dnsResponseMessage *dns.Msg) model.NameserverStatus
 	return model.NameserverStatusOK
 }
 
+// Method responsable for running all delegation policies for a nameserver of the domain,
+// using the referral retrieved from the authoritative nameservers of the parent zone. When
+// there's no referral we can't tell anything about the delegation and the policies are
+// ignored. When the parent zone doesn't know the domain (NXDOMAIN) the domain isn't
+// delegated at all, and other errors (SERVFAIL, REFUSED) don't allow us to verify the
+// delegation. It will return the nameserver status of the first error that occurred
+func (d *DomainNSPolicy) RunDelegation(nameserver model.Nameserver,
+	referral *dns.Msg) model.NameserverStatus {
+
+	if referral == nil {
+		return model.NameserverStatusOK
+	}
+
+	switch referral.Rcode {
+	case dns.RcodeSuccess:
+		// Referral found, we can compare it with the nameserver. In Go every switch case has
+		// an implicit break
+	case dns.RcodeNameError:
+		return model.NameserverStatusNotInParent
+	default:
+		return model.NameserverStatusError
+	}
+
+	for _, policy := range delegationPolicies {
+		if status := policy(d, nameserver, referral); status != model.NameserverStatusOK {
+			return status
+		}
+	}
+
+	return model.NameserverStatusOK
+}
+
 // CNAME policy is responsable to check if there's no CNAME in the top level of the zone.
 // According to the RFC CNAME resource record cannot exist with another resource record
 // with the same name, as SOA resource record is mandatory in the top of the zone, CNAME
@@ -166,3 +236,219 @@ func (d *DomainNSPolicy) soaPolicy(dnsResponseMessage *dns.Msg) model.Nameserver
 
 	return model.NameserverStatusOK
 }
+
+// Nameservers that have the same zone version could still have different SOA fields, this
+// usually occurs when a secondary nameserver is not really transfering the zone from the
+// primary nameserver. This policy assumes that the SOA record was already verified
+func (d *DomainNSPolicy) soaFieldsPolicy(dnsResponseMessage *dns.Msg) model.NameserverStatus {
+	soaRR, ok := dnsutils.FilterFirstRR(dnsResponseMessage.Answer, dns.TypeSOA).(*dns.SOA)
+	if !ok {
+		return model.NameserverStatusUnknownDomainName
+	}
+
+	// First nameserver checked, we don't have anything to compare
+	if d.soa == nil {
+		d.soa = soaRR
+		return model.NameserverStatusOK
+	}
+
+	if !strings.EqualFold(d.soa.Ns, soaRR.Ns) ||
+		!strings.EqualFold(d.soa.Mbox, soaRR.Mbox) ||
+		d.soa.Refresh != soaRR.Refresh ||
+		d.soa.Retry != soaRR.Retry ||
+		d.soa.Expire != soaRR.Expire ||
+		d.soa.Minttl != soaRR.Minttl {
+
+		return model.NameserverStatusSOAMismatch
+	}
+
+	return model.NameserverStatusOK
+}
+
+// Check if the NS RRset returned by the authoritative nameserver is the same of the
+// delegation (nameservers of the domain object). Lame nameservers usually answer with
+// an old NS list. When there's no NS RRset in the response or the nameserver answered
+// with an error, the policy is ignored, as the other policies already check DNS errors
+func (d *DomainNSPolicy) nsSetPolicy(dnsResponseMessage *dns.Msg) model.NameserverStatus {
+	if d.nsResponse == nil || d.nsResponse.Rcode != dns.RcodeSuccess {
+		return model.NameserverStatusOK
+	}
+
+	var hosts []string
+	for _, rr := range dnsutils.FilterRRs(d.nsResponse.Answer, dns.TypeNS) {
+		if nsRR, ok := rr.(*dns.NS); ok {
+			hosts = append(hosts, normalizeName(nsRR.Ns))
+		}
+	}
+
+	// Without the NS RRset in the answer section we can't compare with the delegation
+	if len(hosts) == 0 {
+		return model.NameserverStatusOK
+	}
+
+	var delegation []string
+	for _, nameserver := range d.domain.Nameservers {
+		delegation = append(delegation, normalizeName(nameserver.Host))
+	}
+
+	if !equalSets(hosts, delegation) {
+		return model.NameserverStatusNSSetMismatch
+	}
+
+	return model.NameserverStatusOK
+}
+
+// Check if the DNSKEY RRset is the same in all nameservers of the domain. Different
+// DNSKEY RRsets break the DNSSEC validation randomly, depending on the nameserver that the
+// resolver choose. When the domain has no DS records (no DNSSEC), there's no DNSKEY
+// response or the nameserver answered with an error, the policy is ignored
+func (d *DomainNSPolicy) dnskeySetPolicy(dnsResponseMessage *dns.Msg) model.NameserverStatus {
+	if len(d.domain.DSSet) == 0 || d.dnskeyResponse == nil ||
+		d.dnskeyResponse.Rcode != dns.RcodeSuccess {
+
+		return model.NameserverStatusOK
+	}
+
+	var dnskeys []string
+	for _, rr := range dnsutils.FilterRRs(d.dnskeyResponse.Answer, dns.TypeDNSKEY) {
+		if dnskeyRR, ok := rr.(*dns.DNSKEY); ok {
+			// The base64 public key could have spaces depending on the nameserver
+			// implementation, so we remove them before comparing
+			dnskeys = append(dnskeys, fmt.Sprintf("%d %d %d %s",
+				dnskeyRR.Flags,
+				dnskeyRR.Protocol,
+				dnskeyRR.Algorithm,
+				strings.Replace(dnskeyRR.PublicKey, " ", "", -1),
+			))
+		}
+	}
+
+	// First nameserver checked, we don't have anything to compare
+	if d.dnskeys == nil {
+		if dnskeys == nil {
+			dnskeys = []string{}
+		}
+
+		d.dnskeys = dnskeys
+		return model.NameserverStatusOK
+	}
+
+	if !equalSets(d.dnskeys, dnskeys) {
+		return model.NameserverStatusDNSKEYMismatch
+	}
+
+	return model.NameserverStatusOK
+}
+
+// Convert a domain name to a format that can be compared (lower case and fully qualified)
+func normalizeName(name string) string {
+	return dns.Fqdn(strings.ToLower(name))
+}
+
+// Compare two lists of strings ignoring the order and duplicated items
+func equalSets(list1, list2 []string) bool {
+	set1 := make(map[string]bool)
+	for _, item := range list1 {
+		set1[item] = true
+	}
+
+	set2 := make(map[string]bool)
+	for _, item := range list2 {
+		set2[item] = true
+	}
+
+	if len(set1) != len(set2) {
+		return false
+	}
+
+	for item := range set1 {
+		if !set2[item] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Check if the nameserver is in the NS RRset published by the parent zone. The NS records
+// are usually in the authority section of the referral, but when the parent is also
+// authoritative for the domain they are in the answer section. Without NS records we
+// can't compare the delegation, so the policy is ignored
+func (d *DomainNSPolicy) parentNSPolicy(nameserver model.Nameserver,
+	referral *dns.Msg) model.NameserverStatus {
+
+	var hosts []string
+	for _, rr := range append(referral.Answer, referral.Ns...) {
+		nsRR, ok := rr.(*dns.NS)
+		if !ok || normalizeName(nsRR.Hdr.Name) != normalizeName(d.domain.FQDN) {
+			continue
+		}
+
+		hosts = append(hosts, normalizeName(nsRR.Ns))
+	}
+
+	if len(hosts) == 0 {
+		return model.NameserverStatusOK
+	}
+
+	for _, host := range hosts {
+		if host == normalizeName(nameserver.Host) {
+			return model.NameserverStatusOK
+		}
+	}
+
+	return model.NameserverStatusNotInParent
+}
+
+// When the nameserver needs glue, the parent zone must publish the glue records with the
+// same addresses of the nameserver. This policy assumes that the nameserver was already
+// found in the parent's delegation
+func (d *DomainNSPolicy) gluePolicy(nameserver model.Nameserver,
+	referral *dns.Msg) model.NameserverStatus {
+
+	if !nameserver.NeedsGlue(d.domain.FQDN) {
+		return model.NameserverStatusOK
+	}
+
+	var ipv4Glue, ipv6Glue []net.IP
+	for _, rr := range append(referral.Answer, referral.Extra...) {
+		if normalizeName(rr.Header().Name) != normalizeName(nameserver.Host) {
+			continue
+		}
+
+		switch glue := rr.(type) {
+		case *dns.A:
+			ipv4Glue = append(ipv4Glue, glue.A)
+		case *dns.AAAA:
+			ipv6Glue = append(ipv6Glue, glue.AAAA)
+		}
+	}
+
+	if len(ipv4Glue) == 0 && len(ipv6Glue) == 0 {
+		return model.NameserverStatusGlueMissing
+	}
+
+	if !containsAddress(ipv4Glue, nameserver.IPv4) ||
+		!containsAddress(ipv6Glue, nameserver.IPv6) {
+
+		return model.NameserverStatusGlueDiffers
+	}
+
+	return model.NameserverStatusOK
+}
+
+// Check if the address is in the glue list. When the nameserver doesn't have the address,
+// the parent zone also shouldn't have glue records of the same type
+func containsAddress(glue []net.IP, address net.IP) bool {
+	if address == nil {
+		return len(glue) == 0
+	}
+
+	for _, glueAddress := range glue {
+		if glueAddress.Equal(address) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -305,3 +305,278 @@ func TestSOAPolicy(t *testing.T) {
 		t.Error("Not detecting different versions of the same zone file")
 	}
 }
+
+func TestSOAFieldsPolicy(t *testing.T) {
+	domain := &model.Domain{}
+	domainNSPolicy := NewDomainNSPolicy(domain)
+
+	if domainNSPolicy.soaFieldsPolicy(&dns.Msg{}) !=
+		model.NameserverStatusUnknownDomainName {
+		t.Error("Not detecting when there's no SOA record")
+	}
+
+	soa := &dns.SOA{
+		Hdr: dns.RR_Header{
+			Rrtype: dns.TypeSOA,
+		},
+		Ns:      "ns1.test.br.",
+		Mbox:    "hostmaster.test.br.",
+		Serial:  1,
+		Refresh: 3600,
+	}
+
+	dnsResponseMessage := &dns.Msg{
+		Answer: []dns.RR{soa},
+	}
+
+	if domainNSPolicy.soaFieldsPolicy(dnsResponseMessage) != model.NameserverStatusOK {
+		t.Error("Returning SOA problems only with one nameserver check")
+	}
+
+	otherSOA := *soa
+	otherSOA.Ns = "NS1.test.br."
+	dnsResponseMessage = &dns.Msg{
+		Answer: []dns.RR{&otherSOA},
+	}
+
+	if domainNSPolicy.soaFieldsPolicy(dnsResponseMessage) != model.NameserverStatusOK {
+		t.Error("Not comparing the SOA names case insensitive")
+	}
+
+	otherSOA.Refresh = 7200
+
+	if domainNSPolicy.soaFieldsPolicy(dnsResponseMessage) !=
+		model.NameserverStatusSOAMismatch {
+		t.Error("Not detecting different SOA fields between nameservers")
+	}
+}
+
+func TestNSSetPolicy(t *testing.T) {
+	domain := &model.Domain{
+		FQDN: "test.br.",
+		Nameservers: []model.Nameserver{
+			{Host: "ns1.test.br."},
+			{Host: "NS2.test.br"},
+		},
+	}
+
+	domainNSPolicy := NewDomainNSPolicy(domain)
+
+	if domainNSPolicy.nsSetPolicy(&dns.Msg{}) != model.NameserverStatusOK {
+		t.Error("Not ignoring the NS RRset check when there's no NS response")
+	}
+
+	nsResponse := &dns.Msg{
+		Answer: []dns.RR{
+			&dns.NS{
+				Hdr: dns.RR_Header{
+					Rrtype: dns.TypeNS,
+				},
+				Ns: "ns2.test.br.",
+			},
+			&dns.NS{
+				Hdr: dns.RR_Header{
+					Rrtype: dns.TypeNS,
+				},
+				Ns: "ns1.test.br.",
+			},
+		},
+	}
+
+	domainNSPolicy.SetNSResponse(nsResponse)
+	if domainNSPolicy.nsSetPolicy(&dns.Msg{}) != model.NameserverStatusOK {
+		t.Error("Not accepting a NS RRset equal to the delegation")
+	}
+
+	nsResponse.Answer = nsResponse.Answer[:1]
+	if domainNSPolicy.nsSetPolicy(&dns.Msg{}) != model.NameserverStatusNSSetMismatch {
+		t.Error("Not detecting a NS RRset different from the delegation")
+	}
+}
+
+func TestDNSKEYSetPolicy(t *testing.T) {
+	domain := &model.Domain{
+		FQDN: "test.br.",
+		DSSet: []model.DS{
+			{Keytag: 1234},
+		},
+	}
+
+	domainNSPolicy := NewDomainNSPolicy(domain)
+
+	if domainNSPolicy.dnskeySetPolicy(&dns.Msg{}) != model.NameserverStatusOK {
+		t.Error("Not ignoring the DNSKEY RRset check when there's no DNSKEY response")
+	}
+
+	dnskeyResponse := &dns.Msg{
+		Answer: []dns.RR{
+			&dns.DNSKEY{
+				Hdr: dns.RR_Header{
+					Rrtype: dns.TypeDNSKEY,
+				},
+				Flags:     257,
+				Protocol:  3,
+				Algorithm: dns.RSASHA1NSEC3SHA1,
+				PublicKey: "AwEAAbQz SNWE0DY=",
+			},
+		},
+	}
+
+	domainNSPolicy.SetDNSKEYResponse(dnskeyResponse)
+	if domainNSPolicy.dnskeySetPolicy(&dns.Msg{}) != model.NameserverStatusOK {
+		t.Error("Returning DNSKEY problems only with one nameserver check")
+	}
+
+	dnskeyResponse = &dns.Msg{
+		Answer: []dns.RR{
+			&dns.DNSKEY{
+				Hdr: dns.RR_Header{
+					Rrtype: dns.TypeDNSKEY,
+				},
+				Flags:     257,
+				Protocol:  3,
+				Algorithm: dns.RSASHA1NSEC3SHA1,
+				PublicKey: "AwEAAbQzSNWE0DY=",
+			},
+		},
+	}
+
+	domainNSPolicy.SetDNSKEYResponse(dnskeyResponse)
+	if domainNSPolicy.dnskeySetPolicy(&dns.Msg{}) != model.NameserverStatusOK {
+		t.Error("Not accepting the same DNSKEY RRset in different nameservers")
+	}
+
+	dnskeyResponse.Answer = nil
+	if domainNSPolicy.dnskeySetPolicy(&dns.Msg{}) != model.NameserverStatusDNSKEYMismatch {
+		t.Error("Not detecting different DNSKEY RRsets between nameservers")
+	}
+
+	domain.DSSet = nil
+	if domainNSPolicy.dnskeySetPolicy(&dns.Msg{}) != model.NameserverStatusOK {
+		t.Error("Not ignoring the DNSKEY RRset check for a domain without DNSSEC")
+	}
+}
+
+func TestRunDelegationPolicies(t *testing.T) {
+	domain := &model.Domain{
+		FQDN: "test.br.",
+		Nameservers: []model.Nameserver{
+			{Host: "ns1.test.br.", IPv4: net.ParseIP("127.0.0.1")},
+			{Host: "ns2.example.com."},
+		},
+	}
+
+	domainNSPolicy := NewDomainNSPolicy(domain)
+
+	if domainNSPolicy.RunDelegation(domain.Nameservers[0], nil) != model.NameserverStatusOK {
+		t.Error("Not ignoring the delegation check when there's no referral")
+	}
+
+	referral := &dns.Msg{
+		Ns: []dns.RR{
+			&dns.NS{
+				Hdr: dns.RR_Header{
+					Name:   "test.br.",
+					Rrtype: dns.TypeNS,
+				},
+				Ns: "ns1.test.br.",
+			},
+		},
+		Extra: []dns.RR{
+			&dns.A{
+				Hdr: dns.RR_Header{
+					Name:   "ns1.test.br.",
+					Rrtype: dns.TypeA,
+				},
+				A: net.ParseIP("127.0.0.1"),
+			},
+		},
+	}
+
+	if domainNSPolicy.RunDelegation(domain.Nameservers[0], referral) !=
+		model.NameserverStatusOK {
+		t.Error("Not accepting a valid delegation")
+	}
+
+	if domainNSPolicy.RunDelegation(domain.Nameservers[1], referral) !=
+		model.NameserverStatusNotInParent {
+		t.Error("Not detecting a nameserver that isn't in the parent delegation")
+	}
+
+	referral.Rcode = dns.RcodeNameError
+	if domainNSPolicy.RunDelegation(domain.Nameservers[0], referral) !=
+		model.NameserverStatusNotInParent {
+		t.Error("Not detecting a domain that isn't delegated by the parent zone")
+	}
+
+	referral.Rcode = dns.RcodeServerFailure
+	if domainNSPolicy.RunDelegation(domain.Nameservers[0], referral) !=
+		model.NameserverStatusError {
+		t.Error("Not detecting a parent zone server failure")
+	}
+
+	referral.Rcode = dns.RcodeRefused
+	if domainNSPolicy.RunDelegation(domain.Nameservers[0], referral) !=
+		model.NameserverStatusError {
+		t.Error("Not detecting a parent zone that refused the query")
+	}
+}
+
+func TestGluePolicy(t *testing.T) {
+	domain := &model.Domain{
+		FQDN: "test.br.",
+	}
+
+	domainNSPolicy := NewDomainNSPolicy(domain)
+
+	referral := &dns.Msg{}
+
+	nameserver := model.Nameserver{
+		Host: "ns1.example.com.",
+	}
+
+	if domainNSPolicy.gluePolicy(nameserver, referral) != model.NameserverStatusOK {
+		t.Error("Requiring glue for a nameserver outside the domain")
+	}
+
+	nameserver = model.Nameserver{
+		Host: "ns1.test.br.",
+		IPv4: net.ParseIP("127.0.0.1"),
+		IPv6: net.ParseIP("::1"),
+	}
+
+	if domainNSPolicy.gluePolicy(nameserver, referral) != model.NameserverStatusGlueMissing {
+		t.Error("Not detecting missing glue records")
+	}
+
+	referral.Extra = []dns.RR{
+		&dns.A{
+			Hdr: dns.RR_Header{
+				Name:   "NS1.test.br.",
+				Rrtype: dns.TypeA,
+			},
+			A: net.ParseIP("127.0.0.1"),
+		},
+	}
+
+	if domainNSPolicy.gluePolicy(nameserver, referral) != model.NameserverStatusGlueDiffers {
+		t.Error("Not detecting missing IPv6 glue record")
+	}
+
+	referral.Extra = append(referral.Extra, &dns.AAAA{
+		Hdr: dns.RR_Header{
+			Name:   "ns1.test.br.",
+			Rrtype: dns.TypeAAAA,
+		},
+		AAAA: net.ParseIP("::1"),
+	})
+
+	if domainNSPolicy.gluePolicy(nameserver, referral) != model.NameserverStatusOK {
+		t.Error("Not accepting valid glue records")
+	}
+
+	nameserver.IPv4 = net.ParseIP("127.0.0.2")
+	if domainNSPolicy.gluePolicy(nameserver, referral) != model.NameserverStatusGlueDiffers {
+		t.Error("Not detecting different glue records")
+	}
+}
@@ -6,10 +6,15 @@
 package scan
 
 import (
+	"fmt"
 	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/miekg/dns"
+	"github.com/rafaeljusto/shelter/config"
 	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/scan/compliancepolicy"
+	"github.com/rafaeljusto/shelter/net/scan/dnsutils"
 	"github.com/rafaeljusto/shelter/net/scan/dspolicy"
 	"github.com/rafaeljusto/shelter/net/scan/nspolicy"
+	"math/rand"
 	"net"
 	"strconv"
 	"sync"
@@ -31,9 +36,10 @@ var (
 // queries to notify the maximum UDP package size supported in the network. This object is
 // private for this package and should only be accessed by the querier dispatcher
 type querier struct {
-	client            dns.Client // Low level DNS client for network checks
-	UDPMaxSize        uint16     // UDP max package size to pass over firewalls
-	ConnectionRetries int        // Number of retries before setting timeout
+	client            dns.Client  // Low level DNS client for network checks
+	UDPMaxSize        uint16      // UDP max package size to pass over firewalls
+	ConnectionRetries int         // Number of retries before setting timeout
+	checkpoint        func() bool // Called before checking each domain, false ignores the domain (optional)
 }
 
 // Return a new Querier object with the necessary fields for the scan filled
@@ -89,6 +95,10 @@ func (q *querier) start(queriers *sync.WaitGroup,
 				for i := 0; i < len(postponedDomains); i++ {
 					postponed := postponedDomains[i]
 
+					if !q.proceed() {
+						continue
+					}
+
 					// We also send the list to the method so it can postpone the domain again and
 					// again and again...
 					if q.checkPostponedDomains(postponedDomains, postponed) {
@@ -101,6 +111,12 @@ func (q *querier) start(queriers *sync.WaitGroup,
 				return
 			}
 
+			// When the scan was cancelled the domain is ignored, so that it isn't saved with
+			// a partial state
+			if !q.proceed() {
+				continue
+			}
+
 			if q.checkDomain(domain, postponedDomains) {
 				// Send to collector the domain with the new state
 				domainsToSaveChannel <- domain
@@ -111,76 +127,219 @@ func (q *querier) start(queriers *sync.WaitGroup,
 	return querierChannel
 }
 
+// Check if the querier can continue checking domains, waiting while the scan is paused
+func (q *querier) proceed() bool {
+	return q.checkpoint == nil || q.checkpoint()
+}
+
 // Main function to check a domain DNS/DNSSEC configuration. Returns true if domain is
 // done checking and can be saved or false otherwise, that indicates that the domain was
 // postponed
 func (q *querier) checkDomain(domain *model.Domain,
 	postponedDomains []postponedDomain) bool {
 
+	parentResponse := q.queryParentDS(domain)
+
+	// Each DS stores the earliest signature expiration date found in this scan. When no
+	// signature is evaluated the date of the last scan is kept, also when the domain is
+	// postponed and checked again later
+	previousDSSet := dspolicy.ResetSignatureExpirations(domain)
+	defer dspolicy.RestoreSignatureExpirations(domain, previousDSSet)
+
+	// The same nameserver policy is used for all nameservers of the domain, so that we can
+	// compare the zone information between them
+	domainNSPolicy := nspolicy.NewDomainNSPolicy(domain)
+
 	for index, _ := range domain.Nameservers {
-		if !q.checkNameserver(domain, index, postponedDomains) {
+		if !q.checkNameserver(domain, index, &domainNSPolicy, postponedDomains) {
 			return false
 		}
 
-		if !q.checkDS(domain, index, q.UDPMaxSize, postponedDomains) {
+		q.checkCompliance(domain, index)
+
+		if !q.checkDS(domain, index, q.UDPMaxSize, parentResponse, postponedDomains) {
 			return false
 		}
 	}
 
+	q.checkDelegation(domain, &domainNSPolicy)
 	return true
 }
 
 // Verify the DNS configuration on the nameservers. This method will send a SOA request
-// for each nameserver and verify the results. Returns true if nameserver is done checking
-// and can be saved or false otherwise, that indicates that the domain was postponed
-func (q *querier) checkNameserver(domain *model.Domain,
-	index int, postponedDomains []postponedDomain) bool {
+// for each nameserver and verify the results. The NS and DNSKEY RRsets are also retrieved
+// to check the consistency between the nameservers. Returns true if nameserver is done
+// checking and can be saved or false otherwise, that indicates that the domain was
+// postponed
+func (q *querier) checkNameserver(domain *model.Domain, index int,
+	domainNSPolicy *nspolicy.DomainNSPolicy, postponedDomains []postponedDomain) bool {
 
 	nameserver := domain.Nameservers[index]
-	domainNSPolicy := nspolicy.NewDomainNSPolicy(domain)
+	addresses := getAddresses(domain.FQDN, nameserver)
+
+	// A nameserver could be broken only in one of the address families, so we check all
+	// addresses of the nameserver. When the addresses couldn't be resolved we try to query
+	// using the hostname, and there's no status per address family
+	var hosts []string
+	for _, address := range addresses {
+		hosts = append(hosts, formatHost(address))
+	}
+
+	if len(hosts) == 0 {
+		hosts = append(hosts, nameserver.Host+":"+strconv.Itoa(DNSPort))
+	}
+
+	domain.Nameservers[index].ResetAddressStatus()
+
+	var status model.NameserverStatus = model.NameserverStatusOK
+	for i, host := range hosts {
+		// The timeouts and the queries per second are controlled per address, so that an
+		// address with problems doesn't change the results of the other addresses
+		var hostStatus model.NameserverStatus
+
+		switch querierCache.Check(host) {
+		case ErrHostTimeout:
+			hostStatus = model.NameserverStatusTimeout
+
+		case ErrHostQPSExceeded:
+			postponedDomains = append(postponedDomains, postponedDomain{
+				domain: domain,
+				index:  index,
+			})
+			return false
+
+		default:
+			// The consistency checks (NS and DNSKEY RRsets) are only performed in the first
+			// address, to avoid sending too many queries for the same nameserver
+			hostStatus = q.checkNameserverHost(domain, nameserver, host, i == 0, domainNSPolicy)
+		}
+
+		if i < len(addresses) {
+			domain.Nameservers[index].ChangeAddressStatus(addresses[i], hostStatus)
+		}
+
+		// The first problem found is the one stored in the nameserver status
+		if status == model.NameserverStatusOK {
+			status = hostStatus
+		}
+	}
+
+	domain.Nameservers[index].ChangeStatus(status)
+	return true
+}
+
+// Send the SOA request to one of the nameserver's addresses and verify the results. When
+// the consistency flag is on, the NS and DNSKEY RRsets are also retrieved to compare them
+// with the other nameservers. Returns the status of the nameserver for this address
+func (q *querier) checkNameserverHost(domain *model.Domain, nameserver model.Nameserver,
+	host string, consistency bool, domainNSPolicy *nspolicy.DomainNSPolicy) model.NameserverStatus {
 
 	// Build message to send the request
 	var dnsRequestMessage dns.Msg
 	dnsRequestMessage.SetQuestion(domain.FQDN, dns.TypeSOA)
 	dnsRequestMessage.RecursionDesired = false
 
-	host, err := getHost(domain.FQDN, nameserver)
-	if err == ErrHostTimeout {
-		domain.Nameservers[index].ChangeStatus(model.NameserverStatusTimeout)
-		return true
-
-	} else if err == ErrHostQPSExceeded {
-		postponedDomains = append(postponedDomains, postponedDomain{
-			domain: domain,
-			index:  index,
-		})
-		return false
-	}
-
 	dnsResponseMessage, err := q.sendDNSRequest(host, &dnsRequestMessage)
-	querierCache.Query(nameserver.Host)
+	querierCache.Query(host)
 
 	if status := domainNSPolicy.CheckNetworkError(err); status != model.NameserverStatusOK {
 		if status == model.NameserverStatusTimeout {
-			querierCache.Timeout(nameserver.Host)
+			querierCache.Timeout(host)
+		}
+
+		return status
+	}
+
+	if consistency {
+		// The DNSKEY RRset is only compared for domains with DNSSEC, so that no extra query
+		// is sent for the unsigned domains
+		rrTypes := []uint16{dns.TypeNS}
+		if len(domain.DSSet) > 0 {
+			rrTypes = append(rrTypes, dns.TypeDNSKEY)
 		}
 
-		domain.Nameservers[index].ChangeStatus(status)
+		// Network problems were already detected in the SOA query, so if we can't retrieve
+		// the NS or DNSKEY RRsets the consistency checks are ignored
+		for _, rrType := range rrTypes {
+			dnsRequestMessage.SetQuestion(domain.FQDN, rrType)
+			rrsetResponseMessage, err := q.sendDNSRequest(host, &dnsRequestMessage)
+			querierCache.Query(host)
+
+			if err != nil {
+				continue
+			}
 
-	} else {
-		domain.Nameservers[index].ChangeStatus(domainNSPolicy.Run(dnsResponseMessage))
+			if rrType == dns.TypeNS {
+				domainNSPolicy.SetNSResponse(rrsetResponseMessage)
+			} else {
+				domainNSPolicy.SetDNSKEYResponse(rrsetResponseMessage)
+			}
+		}
 	}
 
-	return true
+	return domainNSPolicy.Run(dnsResponseMessage)
+}
+
+// Send the EDNS and TCP compliance tests to the nameserver and store the results in the
+// nameserver's compliance report. The compliance checks are optional and are only
+// executed when they are enabled in the configuration file. Nameservers that couldn't be
+// reached in the configuration check are ignored, because all the tests would fail for
+// the same reason, and the last compliance report is kept
+func (q *querier) checkCompliance(domain *model.Domain, index int) {
+	if !config.ShelterConfig.Scan.ComplianceChecks {
+		return
+	}
+
+	nameserver := domain.Nameservers[index]
+
+	switch nameserver.LastStatus {
+	case model.NameserverStatusTimeout,
+		model.NameserverStatusUnknownHost,
+		model.NameserverStatusConnectionRefused:
+		return
+	}
+
+	host := getHost(domain.FQDN, nameserver)
+	if querierCache.Check(host) != nil {
+		return
+	}
+
+	var compliance model.NameserverCompliance
+	for _, test := range compliancepolicy.Tests {
+		dnsRequestMessage := compliancepolicy.NewRequest(test, domain.FQDN, q.UDPMaxSize)
+
+		var dnsResponseMessage *dns.Msg
+		var err error
+
+		if compliancepolicy.UseTCP(test) {
+			dnsResponseMessage, err = q.sendTCPRequest(host, dnsRequestMessage)
+		} else if compliancepolicy.UseUDP(test) {
+			dnsResponseMessage, err = q.sendUDPRequest(host, dnsRequestMessage)
+		} else {
+			dnsResponseMessage, err = q.sendDNSRequest(host, dnsRequestMessage)
+		}
+		querierCache.Query(host)
+
+		status := compliancepolicy.CheckNetworkError(err)
+		if status == model.ComplianceStatusOK {
+			status = compliancepolicy.Run(test, dnsResponseMessage)
+		}
+
+		compliancepolicy.SetStatus(&compliance, test, status)
+	}
+
+	compliance.LastCheckAt = time.Now()
+	domain.Nameservers[index].Compliance = compliance
 }
 
 // Check the DS with the domain DNSSEC keys and signatures. You need also to inform the
 // UDP max package size supported to pass into firewalls. Many firewalls don't allow
 // fragmented UDP packages or UDP packages bigger than 512 bytes. Returns true if DS set
 // is done checking and can be saved or false otherwise, that indicates that the domain
-// was postponed
+// was postponed. The DS response from the parent zone is used to verify the chain of
+// trust, and can be nil when it wasn't possible to retrieve it
 func (q *querier) checkDS(domain *model.Domain, index int, udpMaxSize uint16,
-	postponedDomains []postponedDomain) bool {
+	parentResponse *dns.Msg, postponedDomains []postponedDomain) bool {
 
 	// Check if the domain has DNSSEC, this system will work with both kinds of domain. So
 	// when the domain don't have any DS record we assume that it does not have DNSSEC
@@ -191,6 +350,7 @@ func (q *querier) checkDS(domain *model.Domain, index int, udpMaxSize uint16,
 
 	nameserver := domain.Nameservers[index]
 	domainDSPolicy := dspolicy.NewDomainDSPolicy(domain)
+	domainDSPolicy.SetParentResponse(parentResponse)
 
 	// We are going to request the DNSSEC keys to validate with the DS information that we
 	// have from the domain
@@ -199,14 +359,16 @@ func (q *querier) checkDS(domain *model.Domain, index int, udpMaxSize uint16,
 	dnsRequestMessage.RecursionDesired = false
 	dnsRequestMessage.SetEdns0(udpMaxSize, true)
 
-	host, err := getHost(domain.FQDN, nameserver)
-	if err == ErrHostTimeout {
+	host := getHost(domain.FQDN, nameserver)
+
+	switch querierCache.Check(host) {
+	case ErrHostTimeout:
 		for index, _ := range domain.DSSet {
 			domain.DSSet[index].ChangeStatus(model.DSStatusTimeout)
 		}
 		return true
 
-	} else if err == ErrHostQPSExceeded {
+	case ErrHostQPSExceeded:
 		postponedDomains = append(postponedDomains, postponedDomain{
 			domain: domain,
 			index:  index,
@@ -215,15 +377,61 @@ func (q *querier) checkDS(domain *model.Domain, index int, udpMaxSize uint16,
 	}
 
 	dnsResponseMessage, err := q.sendDNSRequest(host, &dnsRequestMessage)
-	querierCache.Query(nameserver.Host)
+	querierCache.Query(host)
 
 	if domainDSPolicy.CheckNetworkError(err) {
+		// Signatures of the zone APEX RRsets can expire before the DNSKEY signatures, so we
+		// also retrieve them to check. Network problems are already detected in the DNSKEY
+		// query, so we ignore them here
+		for _, rrType := range []uint16{dns.TypeSOA, dns.TypeNS} {
+			apexResponse, err := q.sendDNSSECRequest(host, domain.FQDN, rrType, udpMaxSize)
+			querierCache.Query(host)
+
+			if err == nil {
+				domainDSPolicy.AddApexResponse(apexResponse)
+			}
+		}
+
+		denialName, denialResponse := q.queryDenial(domain, host, udpMaxSize)
+		querierCache.Query(host)
+
+		domainDSPolicy.SetDenialResponse(denialName, denialResponse)
 		domainDSPolicy.Run(dnsResponseMessage)
 	}
 
 	return true
 }
 
+// Query a random name under the domain, that probably doesn't exist, to retrieve the
+// NSEC/NSEC3 records that prove the non-existence of the name. If there's a network error
+// the response is nil and the denial of existence check is ignored, network problems are
+// already detected in the DNSKEY query
+func (q *querier) queryDenial(domain *model.Domain,
+	host string, udpMaxSize uint16) (string, *dns.Msg) {
+
+	denialName := fmt.Sprintf("shelter-%d.%s", rand.Int63(), domain.FQDN)
+
+	dnsResponseMessage, err := q.sendDNSSECRequest(host, denialName, dns.TypeA, udpMaxSize)
+	if err != nil {
+		return denialName, nil
+	}
+
+	return denialName, dnsResponseMessage
+}
+
+// Send a non-recursive request with the DNSSEC OK bit on, so that the nameserver also
+// returns the signatures of the RRset
+func (q *querier) sendDNSSECRequest(host, name string,
+	rrType uint16, udpMaxSize uint16) (*dns.Msg, error) {
+
+	var dnsRequestMessage dns.Msg
+	dnsRequestMessage.SetQuestion(name, rrType)
+	dnsRequestMessage.RecursionDesired = false
+	dnsRequestMessage.SetEdns0(udpMaxSize, true)
+
+	return q.sendDNSRequest(host, &dnsRequestMessage)
+}
+
 // Try to check the postponed domains. Maybe we should have some protection here to avoid
 // an almost forever loop when we have a lot of domains with the same nameserver. Returns
 // true if domain is done checking and can be saved or false otherwise, that indicates
@@ -231,36 +439,169 @@ func (q *querier) checkDS(domain *model.Domain, index int, udpMaxSize uint16,
 func (q *querier) checkPostponedDomains(postponedDomains []postponedDomain,
 	postponed postponedDomain) bool {
 
+	parentResponse := q.queryParentDS(postponed.domain)
+	domainNSPolicy := nspolicy.NewDomainNSPolicy(postponed.domain)
+
 	// We only need to check from the nameserver that had a problem (exceeded the QPS), so
 	// we are directly calling the checkNameserver method instead of the checkDomain method
 	for i := postponed.index; i < len(postponed.domain.Nameservers); i++ {
-		if !q.checkNameserver(postponed.domain, i, postponedDomains) {
+		if !q.checkNameserver(postponed.domain, i, &domainNSPolicy, postponedDomains) {
 			return false
 		}
 
-		if !q.checkDS(postponed.domain, i, q.UDPMaxSize, postponedDomains) {
+		q.checkCompliance(postponed.domain, i)
+
+		if !q.checkDS(postponed.domain, i, q.UDPMaxSize, parentResponse, postponedDomains) {
 			return false
 		}
 	}
 
+	q.checkDelegation(postponed.domain, &domainNSPolicy)
 	return true
 }
 
-func (q *querier) sendDNSRequest(host string, dnsRequestMessage *dns.Msg) (dnsResponseMessage *dns.Msg, err error) {
-	for i := 0; i < q.ConnectionRetries; i++ {
-		// For now we ignore the RTT, in the future we can use this for some report
-		dnsResponseMessage, _, err = q.client.Exchange(dnsRequestMessage, host)
+// Compare the nameservers of the domain with the delegation published in the parent zone
+// (NS and glue records). Problems detected directly in the nameserver are more important,
+// so we only check the delegation of the nameservers that are OK
+func (q *querier) checkDelegation(domain *model.Domain,
+	domainNSPolicy *nspolicy.DomainNSPolicy) {
 
-		// Check if there was a timeout in the connection, if so try again a couple of times
-		// just to make it sure that we didn't lose any UDP package
-		if err == nil {
-			break
+	referral := q.queryReferral(domain)
+	if referral == nil {
+		return
+	}
 
-		} else if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
-			break
+	for index, nameserver := range domain.Nameservers {
+		if nameserver.LastStatus != model.NameserverStatusOK {
+			continue
+		}
+
+		if status := domainNSPolicy.RunDelegation(nameserver, referral); status != model.NameserverStatusOK {
+			domain.Nameservers[index].ChangeStatus(status)
+		}
+	}
+}
+
+// Retrieve the referral of the domain from one of the authoritative nameservers of the
+// parent zone. The first parent nameserver that answers with the referral or with
+// NXDOMAIN is used, other errors (like SERVFAIL) are only returned when all parent
+// nameservers failed. When the parent zone nameservers could not be found or reached,
+// nil is returned and the delegation check is ignored
+func (q *querier) queryReferral(domain *model.Domain) *dns.Msg {
+	parentZone := dnsutils.ParentZone(domain.FQDN)
+	if len(parentZone) == 0 {
+		return nil
+	}
+
+	var dnsRequestMessage dns.Msg
+	dnsRequestMessage.SetQuestion(domain.FQDN, dns.TypeNS)
+	dnsRequestMessage.RecursionDesired = false
+
+	var failedResponse *dns.Msg
+
+	for _, parentNameserver := range q.queryZoneNameservers(parentZone) {
+		host := getHost(parentZone, model.Nameserver{Host: parentNameserver})
+		if querierCache.Check(host) != nil {
+			continue
+		}
+
+		dnsResponseMessage, err := q.sendDNSRequest(host, &dnsRequestMessage)
+		querierCache.Query(host)
+
+		if err != nil {
+			continue
+		}
+
+		if dnsResponseMessage.Rcode == dns.RcodeSuccess ||
+			dnsResponseMessage.Rcode == dns.RcodeNameError {
+			return dnsResponseMessage
+		}
+
+		failedResponse = dnsResponseMessage
+	}
+
+	return failedResponse
+}
+
+// Retrieve the authoritative nameservers of a zone using the recursive DNS defined in the
+// configuration file. The nameservers are stored in cache, because many domains have the
+// same parent zone
+func (q *querier) queryZoneNameservers(zone string) []string {
+	if nameservers, found := querierCache.GetZoneNameservers(zone); found {
+		return nameservers
+	}
+
+	if len(config.ShelterConfig.Scan.Resolver.Address) == 0 {
+		return nil
+	}
+
+	resolver := fmt.Sprintf("%s:%d",
+		config.ShelterConfig.Scan.Resolver.Address,
+		config.ShelterConfig.Scan.Resolver.Port,
+	)
+
+	var dnsRequestMessage dns.Msg
+	dnsRequestMessage.SetQuestion(zone, dns.TypeNS)
+	dnsRequestMessage.RecursionDesired = true
+	dnsRequestMessage.CheckingDisabled = true
+
+	dnsResponseMessage, err := q.sendDNSRequest(resolver, &dnsRequestMessage)
+	if err != nil || dnsResponseMessage.Rcode != dns.RcodeSuccess {
+		return nil
+	}
+
+	var nameservers []string
+	for _, rr := range dnsutils.FilterRRs(dnsResponseMessage.Answer, dns.TypeNS) {
+		if nsRecord, ok := rr.(*dns.NS); ok {
+			nameservers = append(nameservers, nsRecord.Ns)
+		}
+	}
+
+	querierCache.SetZoneNameservers(zone, nameservers)
+	return nameservers
+}
+
+// Retrieve the DS records of the domain published in the parent zone. The DS records are
+// asked directly to the authoritative nameservers of the parent zone, like in the
+// delegation check, so that no cache or validation of a resolver interferes in the
+// result. The first parent nameserver that answers is used. When the domain has no DS
+// records or the parent could not be reached, nil is returned and the chain of trust
+// check is ignored
+func (q *querier) queryParentDS(domain *model.Domain) *dns.Msg {
+	if len(domain.DSSet) == 0 {
+		return nil
+	}
+
+	parentZone := dnsutils.ParentZone(domain.FQDN)
+	if len(parentZone) == 0 {
+		return nil
+	}
+
+	var dnsRequestMessage dns.Msg
+	dnsRequestMessage.SetQuestion(domain.FQDN, dns.TypeDS)
+	dnsRequestMessage.RecursionDesired = false
+	dnsRequestMessage.SetEdns0(q.UDPMaxSize, true)
+
+	for _, parentNameserver := range q.queryZoneNameservers(parentZone) {
+		host := getHost(parentZone, model.Nameserver{Host: parentNameserver})
+		if querierCache.Check(host) != nil {
+			continue
+		}
+
+		dnsResponseMessage, err := q.sendDNSRequest(host, &dnsRequestMessage)
+		querierCache.Query(host)
+
+		if err == nil {
+			return dnsResponseMessage
 		}
 	}
 
+	return nil
+}
+
+func (q *querier) sendDNSRequest(host string, dnsRequestMessage *dns.Msg) (dnsResponseMessage *dns.Msg, err error) {
+	dnsResponseMessage, err = q.sendUDPRequest(host, dnsRequestMessage)
+
 	// Message truncated, let's retry using TCP connection. TCP connection will also get the
 	// same retries chances of the UDP connection for timeouts because the UDP connection
 	// proved in some point that the server is alive
@@ -291,27 +632,86 @@ func (q *querier) sendDNSRequest(host string, dnsRequestMessage *dns.Msg) (dnsRe
 	return
 }
 
+// Send the DNS request only over UDP, returning the truncated response as it is. Used to
+// detect nameservers that can't deliver large responses over UDP
+func (q *querier) sendUDPRequest(host string, dnsRequestMessage *dns.Msg) (dnsResponseMessage *dns.Msg, err error) {
+	for i := 0; i < q.ConnectionRetries; i++ {
+		// For now we ignore the RTT, in the future we can use this for some report
+		dnsResponseMessage, _, err = q.client.Exchange(dnsRequestMessage, host)
+
+		// Check if there was a timeout in the connection, if so try again a couple of times
+		// just to make it sure that we didn't lose any UDP package
+		if err == nil {
+			break
+
+		} else if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+			break
+		}
+	}
+
+	return
+}
+
+// Send the DNS request only over TCP, without trying UDP first. Used to detect
+// nameservers that have TCP blocked by a firewall
+func (q *querier) sendTCPRequest(host string, dnsRequestMessage *dns.Msg) (dnsResponseMessage *dns.Msg, err error) {
+	q.client.Net = "tcp"
+
+	// Move back the Net value to empty so that the next package sent by this querier is via
+	// UDP connection
+	defer func() {
+		q.client.Net = ""
+	}()
+
+	for i := 0; i < q.ConnectionRetries; i++ {
+		// For now we ignore the RTT, in the future we can use this for some report
+		dnsResponseMessage, _, err = q.client.Exchange(dnsRequestMessage, host)
+
+		// Check if there was a timeout in the connection, if so try again a couple of times
+		if err == nil {
+			break
+
+		} else if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+			break
+		}
+	}
+
+	return
+}
+
 // Useful function to retrieve the proper host and port to send the request. The host can
 // change because of glue records needs or not. This function alsos resolve hostnames and
 // store the addresses in a cache
-func getHost(fqdn string, nameserver model.Nameserver) (string, error) {
-	// Using cache to store host addresses when there's no glue
-	if addresses, err := querierCache.Get(nameserver, fqdn); err == nil || len(addresses) > 0 {
-		// Found information in cache, lets use it to speed up the scan. We will try to use an IPv4 from
-		// the returned addresses. if we don't find any we will use the first IPv6 address
-		for _, address := range addresses {
-			if address.To4() != nil {
-				return "[" + address.String() + "]:" + strconv.Itoa(DNSPort), nil
-			}
+func getHost(fqdn string, nameserver model.Nameserver) string {
+	addresses := getAddresses(fqdn, nameserver)
+
+	// We will try to use an IPv4 from the addresses. if we don't find any we will use the
+	// first IPv6 address
+	for _, address := range addresses {
+		if address.To4() != nil {
+			return formatHost(address)
 		}
-		return "[" + addresses[0].String() + "]:" + strconv.Itoa(DNSPort), nil
+	}
 
-	} else if err == ErrHostTimeout || err == ErrHostQPSExceeded {
-		// Control errors were returned, we need to return them to take an action
-		return "", err
+	if len(addresses) > 0 {
+		return formatHost(addresses[0])
 	}
 
 	// Error ocurred to retrieve the information from cache. Let's query without using the
 	// cache
-	return nameserver.Host + ":" + strconv.Itoa(DNSPort), nil
+	return nameserver.Host + ":" + strconv.Itoa(DNSPort)
+}
+
+// Useful function to retrieve all addresses (IPv4 and IPv6) of a nameserver. The addresses
+// are stored in a cache. When the addresses couldn't be resolved an empty list is
+// returned, the control errors (timeouts and QPS exceeded) are checked per address with
+// the cache before sending each query
+func getAddresses(fqdn string, nameserver model.Nameserver) []net.IP {
+	addresses, _ := querierCache.Get(nameserver, fqdn)
+	return addresses
+}
+
+// Convert an address to the format used to send the DNS requests (address and port)
+func formatHost(address net.IP) string {
+	return "[" + address.String() + "]:" + strconv.Itoa(DNSPort)
 }
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"github.com/rafaeljusto/shelter/model"
 	"net"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -37,19 +38,21 @@ var (
 
 func init() {
 	querierCache = QuerierCache{
-		hosts: make(map[string]*hostCache),
+		nameservers: make(map[string][]net.IP),
+		hosts:       make(map[string]*hostCache),
+		zones:       make(map[string][]string),
 	}
 }
 
-// NameserverCache was created to store beyond the addresses, a counter of how many times
-// this host got timeout. For hosts with many timeouts we assume that their are down and
-// avoid making queries whitout necessity. We also control the number of queries per
-// second to avoid rate limit algorithms
+// NameserverCache was created to store a counter of how many times this host got
+// timeout. For hosts with many timeouts we assume that their are down and avoid making
+// queries whitout necessity. We also control the number of queries per second to avoid
+// rate limit algorithms. A host is an address of the nameserver, because a nameserver
+// can be broken only in one of its addresses (e.g. IPv6)
 type hostCache struct {
-	addresses        []net.IP // nameserver's addresses
-	lastEpoch        int64    // last query epoch
-	queriesPerSecond uint64   // number of queries per second (epoch)
-	timeouts         uint64   // counter that detects if this nameserver is down
+	lastEpoch        int64  // last query epoch
+	queriesPerSecond uint64 // number of queries per second (epoch)
+	timeouts         uint64 // counter that detects if this nameserver is down
 }
 
 // Method to detect if the number of timeouts in a host was exceeded
@@ -71,33 +74,27 @@ func (h hostCache) queriesPerSecondExceeded() bool {
 // QuerierCache was created to make the name resolution faster. Many domains use ISP the
 // same host, so if we cache the hosts addresses we are speeding up many domains scans
 type QuerierCache struct {
-	hosts      map[string]*hostCache // key-value structure that store nameserver data
-	hostsMutex sync.RWMutex          // Lock to allow concurrent access
+	nameservers      map[string][]net.IP   // key-value structure that store the nameservers addresses
+	nameserversMutex sync.RWMutex          // Lock to allow concurrent access in nameservers
+	hosts            map[string]*hostCache // key-value structure that store the data of each address
+	hostsMutex       sync.RWMutex          // Lock to allow concurrent access
+	zones            map[string][]string   // key-value structure that store the zones nameservers
+	zonesMutex       sync.RWMutex          // Lock to allow concurrent access in zones
 }
 
 // Method used to retrieve addresses of a given nameserver, if the address does not exist
 // in the local cache the system will lookup for the domain and will store the result
 func (q *QuerierCache) Get(nameserver model.Nameserver, fqdn string) ([]net.IP, error) {
-	q.hostsMutex.RLock()
-	host, found := q.hosts[nameserver.Host]
-	q.hostsMutex.RUnlock()
+	q.nameserversMutex.RLock()
+	addresses, found := q.nameservers[nameserver.Host]
+	q.nameserversMutex.RUnlock()
 
 	if found {
-		if host.timeoutsPerHostExceeded() {
-			return nil, ErrHostTimeout
-
-		} else if host.queriesPerSecondExceeded() {
-			return nil, ErrHostQPSExceeded
-
-		} else {
-			return host.addresses, nil
-		}
+		return addresses, nil
 	}
 
 	// Not found in cache, lets discover the address of this name sending DNS requests or
 	// retrieving from the namserver object (glue record)
-	var addresses []net.IP
-
 	if nameserver.NeedsGlue(fqdn) {
 		if nameserver.IPv4 != nil {
 			addresses = append(addresses, nameserver.IPv4)
@@ -117,57 +114,141 @@ func (q *QuerierCache) Get(nameserver model.Nameserver, fqdn string) ([]net.IP,
 		}
 	}
 
-	q.hostsMutex.Lock()
-	q.hosts[nameserver.Host] = &hostCache{
-		addresses:        addresses,
-		lastEpoch:        0,
-		queriesPerSecond: 0,
-		timeouts:         0,
-	}
-	q.hostsMutex.Unlock()
+	q.nameserversMutex.Lock()
+	q.nameservers[nameserver.Host] = addresses
+	q.nameserversMutex.Unlock()
 
 	return addresses, nil
 }
 
-// Method used to notify when a host got timeout for a query, after a special number of
-// timeouts we assume that every nameserver that use this host will get timeout status
-func (q *QuerierCache) Timeout(name string) {
+// Method used to check if a host (address and port) can receive a query. It returns an
+// error when the host had too many timeouts or when the maximum number of queries per
+// second was exceeded
+func (q *QuerierCache) Check(host string) error {
 	q.hostsMutex.RLock()
-	host, found := q.hosts[name]
+	h, found := q.hosts[host]
 	q.hostsMutex.RUnlock()
 
-	if found {
-		atomic.AddUint64(&host.timeouts, 1)
+	if !found {
+		return nil
 	}
+
+	if h.timeoutsPerHostExceeded() {
+		return ErrHostTimeout
+
+	} else if h.queriesPerSecondExceeded() {
+		return ErrHostQPSExceeded
+	}
+
+	return nil
 }
 
-// Method used to notify when a new query was made to a host. This is used to control the
-// maximum number of queries sent to a host, avoiding rate limit startegies
-func (q *QuerierCache) Query(name string) {
+// Method used to retrieve the nameservers of a zone. This is used to find the
+// authoritative nameservers of the parent zones, as many domains have the same parent we
+// don't need to resolve them again for each domain
+func (q *QuerierCache) GetZoneNameservers(zone string) ([]string, bool) {
+	q.zonesMutex.RLock()
+	defer q.zonesMutex.RUnlock()
+
+	nameservers, found := q.zones[zone]
+	return nameservers, found
+}
+
+// Method used to store the nameservers of a zone
+func (q *QuerierCache) SetZoneNameservers(zone string, nameservers []string) {
+	q.zonesMutex.Lock()
+	q.zones[zone] = nameservers
+	q.zonesMutex.Unlock()
+}
+
+// Method used to notify when a host got timeout for a query, after a special number of
+// timeouts we assume that every nameserver that use this host will get timeout status
+func (q *QuerierCache) Timeout(host string) {
+	h := q.getOrCreateHost(host)
+	atomic.AddUint64(&h.timeouts, 1)
+}
+
+// Method used to retrieve the number of timeouts detected in a host. If the host isn't in
+// the cache zero is returned
+func (q *QuerierCache) Timeouts(host string) uint64 {
 	q.hostsMutex.RLock()
-	host, found := q.hosts[name]
+	h, found := q.hosts[host]
 	q.hostsMutex.RUnlock()
 
 	if !found {
-		return
+		return 0
+	}
+
+	return atomic.LoadUint64(&h.timeouts)
+}
+
+// Method used to retrieve the number of timeouts detected in all addresses of a
+// nameserver. When the addresses couldn't be resolved the queries were sent using the
+// nameserver name
+func (q *QuerierCache) NameserverTimeouts(name string) uint64 {
+	q.nameserversMutex.RLock()
+	addresses := q.nameservers[name]
+	q.nameserversMutex.RUnlock()
+
+	timeouts := q.Timeouts(name + ":" + strconv.Itoa(DNSPort))
+	for _, address := range addresses {
+		timeouts += q.Timeouts(formatHost(address))
 	}
 
+	return timeouts
+}
+
+// Method used to notify when a new query was made to a host. This is used to control the
+// maximum number of queries sent to a host, avoiding rate limit startegies
+func (q *QuerierCache) Query(host string) {
+	h := q.getOrCreateHost(host)
 	now := time.Now().Unix()
 
-	if now == host.lastEpoch {
-		atomic.AddUint64(&host.queriesPerSecond, 1)
+	if now == atomic.LoadInt64(&h.lastEpoch) {
+		atomic.AddUint64(&h.queriesPerSecond, 1)
 
-	} else if now > host.lastEpoch {
-		if atomic.CompareAndSwapInt64(&host.lastEpoch, host.lastEpoch, now) {
-			atomic.StoreUint64(&host.queriesPerSecond, 1)
+	} else if lastEpoch := atomic.LoadInt64(&h.lastEpoch); now > lastEpoch {
+		if atomic.CompareAndSwapInt64(&h.lastEpoch, lastEpoch, now) {
+			atomic.StoreUint64(&h.queriesPerSecond, 1)
 		}
 	}
 }
 
+// Retrieve the control data of the host, creating it when the host receives the first
+// query
+func (q *QuerierCache) getOrCreateHost(host string) *hostCache {
+	q.hostsMutex.RLock()
+	h, found := q.hosts[host]
+	q.hostsMutex.RUnlock()
+
+	if found {
+		return h
+	}
+
+	q.hostsMutex.Lock()
+	defer q.hostsMutex.Unlock()
+
+	// Other querier could have created the host while we were waiting for the lock
+	if h, found = q.hosts[host]; !found {
+		h = new(hostCache)
+		q.hosts[host] = h
+	}
+
+	return h
+}
+
 // Clear cache. This method is for now used in integration test scenarios to get more
 // realistic results in performance reports
 func (q *QuerierCache) Clear() {
+	q.nameserversMutex.Lock()
+	q.nameservers = make(map[string][]net.IP)
+	q.nameserversMutex.Unlock()
+
 	q.hostsMutex.Lock()
 	q.hosts = make(map[string]*hostCache)
 	q.hostsMutex.Unlock()
+
+	q.zonesMutex.Lock()
+	q.zones = make(map[string][]string)
+	q.zonesMutex.Unlock()
 }
@@ -66,7 +66,7 @@ func TestQueriesPerSecondExceeded(t *testing.T) {
 }
 
 func TestQuerierCacheGet(t *testing.T) {
-	querierCache.hosts = make(map[string]*hostCache)
+	querierCache.nameservers = make(map[string][]net.IP)
 
 	addresses, err := querierCache.Get(model.Nameserver{Host: "localhost"}, "example.com.")
 	if err != nil {
@@ -95,13 +95,13 @@ func TestQuerierCacheGet(t *testing.T) {
 		t.Fatal("Not recovering correctly from local cache")
 	}
 
-	h, exists := querierCache.hosts["localhost"]
+	cachedAddresses, exists := querierCache.nameservers["localhost"]
 
 	if !exists {
 		t.Fatal("Not storing results into cache")
 	}
 
-	if len(h.addresses) != len(addresses) {
+	if len(cachedAddresses) != len(addresses) {
 		t.Error("Storing different data from the returned one")
 	}
 
@@ -126,58 +126,75 @@ func TestQuerierCacheGet(t *testing.T) {
 		t.Error("Not resolving correctly the nameserver with glue records")
 	}
 
-	h, exists = querierCache.hosts["ns1.example.com."]
+	cachedAddresses, exists = querierCache.nameservers["ns1.example.com."]
 
 	if !exists {
 		t.Fatal("Not storing results into cache for nameservers with glue records")
 	}
 
-	if len(h.addresses) != len(addresses) {
+	if len(cachedAddresses) != len(addresses) {
 		t.Error("Storing different data from the returned one on nameservers with glue records")
 	}
 
+	_, err = querierCache.Get(model.Nameserver{Host: "abc123idontexist321cba.com.br"}, "example.com.")
+	if err == nil {
+		t.Error("Resolving an unknown name")
+	}
+}
+
+func TestQuerierCacheCheck(t *testing.T) {
+	querierCache.hosts = make(map[string]*hostCache)
+
+	if err := querierCache.Check("[127.0.0.1]:53"); err != nil {
+		t.Error("Returning error for a host that didn't receive queries")
+	}
+
 	// The tests bellow are really fast, but there's a little chance to fail when the epoch
 	// from the created object is different from the current epoch. This will happen if we
 	// are creating the object in the end of a second. After some tests using shell scripts
 	// a saw that this scenario is not frequent, so I will stay with this strategy
 
-	h.lastEpoch = time.Now().Unix()
-	h.queriesPerSecond = MaxQPSPerHost + 1
-	h.timeouts = 0
-	querierCache.hosts["localhost"] = h
+	querierCache.hosts["[127.0.0.1]:53"] = &hostCache{
+		lastEpoch:        time.Now().Unix(),
+		queriesPerSecond: MaxQPSPerHost + 1,
+	}
 
-	addresses, err = querierCache.Get(model.Nameserver{Host: "localhost"}, "example.com.")
-	if err != ErrHostQPSExceeded {
+	if err := querierCache.Check("[127.0.0.1]:53"); err != ErrHostQPSExceeded {
 		t.Error("Not returning error when maximum QPS per host is exceeded")
 	}
 
-	h.lastEpoch = 0
-	h.queriesPerSecond = 0
-	h.timeouts = maxTimeoutsPerHost + 1
-	querierCache.hosts["localhost"] = h
+	querierCache.hosts["[::1]:53"] = &hostCache{
+		timeouts: maxTimeoutsPerHost + 1,
+	}
 
-	addresses, err = querierCache.Get(model.Nameserver{Host: "localhost"}, "example.com.")
-	if err != ErrHostTimeout {
+	if err := querierCache.Check("[::1]:53"); err != ErrHostTimeout {
 		t.Error("Not returning error when maximum timeouts in the host is exceeded")
 	}
 
-	_, err = querierCache.Get(model.Nameserver{Host: "abc123idontexist321cba.com.br"}, "example.com.")
-	if err == nil {
-		t.Error("Resolving an unknown name")
+	querierCache.hosts["[127.0.0.1]:53"].queriesPerSecond = 0
+
+	if err := querierCache.Check("[127.0.0.1]:53"); err != nil {
+		t.Error("Timeouts of an address are changing the other addresses of the nameserver")
 	}
 }
 
 func TestQuerierCacheTimeout(t *testing.T) {
+	querierCache.nameservers = make(map[string][]net.IP)
 	querierCache.hosts = make(map[string]*hostCache)
 
-	_, err := querierCache.Get(model.Nameserver{Host: "localhost"}, "example.com.")
+	_, err := querierCache.Get(model.Nameserver{
+		Host: "ns1.example.com.",
+		IPv4: net.ParseIP("127.0.0.1"),
+		IPv6: net.ParseIP("::1"),
+	}, "example.com.")
+
 	if err != nil {
-		t.Fatal("Not resolving a valid nameserver")
+		t.Fatal("Not resolving a nameserver with glue record")
 	}
 
-	querierCache.Timeout("localhost")
+	querierCache.Timeout("[::1]:53")
 
-	h, exists := querierCache.hosts["localhost"]
+	h, exists := querierCache.hosts["[::1]:53"]
 
 	if !exists {
 		t.Fatal("Not storing results into cache")
@@ -186,6 +203,24 @@ func TestQuerierCacheTimeout(t *testing.T) {
 	if h.timeouts != 1 {
 		t.Error("Not working well with timeouts counter")
 	}
+
+	if querierCache.Timeouts("[::1]:53") != 1 {
+		t.Error("Not returning the number of timeouts of the host")
+	}
+
+	if querierCache.Timeouts("[127.0.0.1]:53") != 0 {
+		t.Error("Timeouts of an address are changing the other addresses of the nameserver")
+	}
+
+	querierCache.Timeout("[127.0.0.1]:53")
+
+	if querierCache.NameserverTimeouts("ns1.example.com.") != 2 {
+		t.Error("Not returning the number of timeouts of all addresses of the nameserver")
+	}
+
+	if querierCache.NameserverTimeouts("unknown.example.com.") != 0 {
+		t.Error("Returning timeouts for a nameserver that isn't in the cache")
+	}
 }
 
 func TestQuerierCacheQuery(t *testing.T) {
@@ -196,20 +231,9 @@ func TestQuerierCacheQuery(t *testing.T) {
 	// are creating the object in the end of a second. After some tests using shell scripts
 	// a saw that this scenario is not frequent, so I will stay with this strategy
 
-	querierCache.Query("localhost")
-
-	if _, exists := querierCache.hosts["localhost"]; exists {
-		t.Error("Creating cache entry when alerting about a query")
-	}
-
-	_, err := querierCache.Get(model.Nameserver{Host: "localhost"}, "example.com.")
-	if err != nil {
-		t.Fatal("Not resolving a valid nameserver")
-	}
+	querierCache.Query("[127.0.0.1]:53")
 
-	querierCache.Query("localhost")
-
-	h, exists := querierCache.hosts["localhost"]
+	h, exists := querierCache.hosts["[127.0.0.1]:53"]
 
 	if !exists {
 		t.Fatal("Not creating cache entries when necessary")
@@ -223,9 +247,9 @@ func TestQuerierCacheQuery(t *testing.T) {
 		t.Error("Not counting QPS correctly")
 	}
 
-	querierCache.Query("localhost")
+	querierCache.Query("[127.0.0.1]:53")
 
-	h, exists = querierCache.hosts["localhost"]
+	h, exists = querierCache.hosts["[127.0.0.1]:53"]
 
 	if !exists {
 		t.Fatal("Removing cache entry in an awkward moment")
@@ -235,14 +259,14 @@ func TestQuerierCacheQuery(t *testing.T) {
 		t.Error("Not counting QPS correctly")
 	}
 
+	if _, exists := querierCache.hosts["[::1]:53"]; exists {
+		t.Error("Counting queries of an address in other addresses")
+	}
+
 	// Forcing epoch change
 	time.Sleep(1 * time.Second)
 
-	querierCache.Query("localhost")
-
-	if !exists {
-		t.Fatal("Removing cache entry in an awkward moment")
-	}
+	querierCache.Query("[127.0.0.1]:53")
 
 	if h.lastEpoch != time.Now().Unix() {
 		t.Error("Not replacing epoch correctly")
@@ -254,6 +278,7 @@ func TestQuerierCacheQuery(t *testing.T) {
 }
 
 func TestQuerierCacheClear(t *testing.T) {
+	querierCache.nameservers = make(map[string][]net.IP)
 	querierCache.hosts = make(map[string]*hostCache)
 
 	_, err := querierCache.Get(model.Nameserver{Host: "localhost"}, "example.com.")
@@ -261,8 +286,13 @@ func TestQuerierCacheClear(t *testing.T) {
 		t.Fatal("Not resolving a valid nameserver")
 	}
 
+	querierCache.Query("[127.0.0.1]:53")
 	querierCache.Clear()
 
+	if querierCache.nameservers == nil || len(querierCache.nameservers) > 0 {
+		t.Error("Not clearing the nameservers cache correctly")
+	}
+
 	if querierCache.hosts == nil || len(querierCache.hosts) > 0 {
 		t.Error("Not clearing the cache correctly")
 	}
@@ -25,6 +25,10 @@ type QuerierDispatcher struct {
 	ReadTimeout       time.Duration // Timeout while waiting for a response
 	WriteTimeout      time.Duration // Timeout to write a query to the DNS server
 	ConnectionRetries int           // Number of retries before setting timeout
+
+	// Called by the queriers before checking each domain. It can block to pause the
+	// queriers, and when it returns false the domain is ignored (optional)
+	Checkpoint func() bool
 }
 
 // Return a new QuerierDispatcher object with the necessary fields for the scan filled
@@ -75,6 +79,7 @@ func (q *QuerierDispatcher) Start(scanGroup *sync.WaitGroup,
 			q.ConnectionRetries,
 		)
 
+		querier.checkpoint = q.Checkpoint
 		queriersChannels[index] = querier.start(&queriers, domainsToSaveChannel)
 	}
 
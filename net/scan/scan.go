@@ -13,11 +13,13 @@ import (
 	"time"
 
 	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/miekg/dns"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
 	"github.com/rafaeljusto/shelter/config"
 	"github.com/rafaeljusto/shelter/dao"
-	"github.com/rafaeljusto/shelter/database/mongodb"
+	"github.com/rafaeljusto/shelter/database"
 	"github.com/rafaeljusto/shelter/log"
 	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/net/mail/notification"
 )
 
 // When converting a DNSKEY into a DS we need to choose wich digest type are we going to
@@ -29,8 +31,66 @@ const (
 
 // Function responsible for running the domain scan system, checking the configuration of each
 // domain in the database according to an algorithm. This method is synchronous and will return only
-// after the scan proccess is done
+// after the scan proccess is done. If there's already a scan running (started on demand) the
+// scheduled scan is skipped
 func ScanDomains() {
+	storage, mongoDatabase, databaseSession, err := database.Open()
+	if err != nil {
+		log.Println("Error while initializing database. Details:", err)
+		return
+	}
+
+	if databaseSession != nil {
+		defer databaseSession.Close()
+	}
+
+	// Create a new scan information
+	if err := model.StartNewScanIfIdle(); err != nil {
+		log.Println("Scheduled scan not executed. Details:", err)
+		return
+	}
+
+	runScan(storage, mongoDatabase, nil)
+}
+
+// StartScan runs an on-demand scan in background over the selected domains. The progress
+// can be followed in the current scan information, as in the scheduled scan. Only one
+// scan can run at a time, so model.ErrScanRunning is returned if there's a scan loading
+// data or running
+func StartScan(selection ScanSelection) error {
+	if err := selection.compile(); err != nil {
+		return err
+	}
+
+	// The scan opens its own database connection, because it continues after the end of
+	// the HTTP request that started it
+	storage, mongoDatabase, databaseSession, err := database.Open()
+	if err != nil {
+		return err
+	}
+
+	if err := model.StartNewScanIfIdle(); err != nil {
+		if databaseSession != nil {
+			databaseSession.Close()
+		}
+		return err
+	}
+
+	go func() {
+		if databaseSession != nil {
+			defer databaseSession.Close()
+		}
+
+		runScan(storage, mongoDatabase, &selection)
+	}()
+
+	return nil
+}
+
+// Execute all steps of a scan that was already registered in the current scan
+// information. When the selection is nil the verification intervals decide which domains
+// are checked
+func runScan(storage dao.Storage, mongoDatabase *mgo.Database, selection *ScanSelection) {
 	defer func() {
 		// Something went really wrong while scanning the domains. Log the error stacktrace
 		// and move out
@@ -39,6 +99,15 @@ func ScanDomains() {
 			buf := make([]byte, size)
 			buf = buf[:runtime.Stack(buf, false)]
 			log.Printf("Panic detected while scanning domains. Details: %v\n%s", r, buf)
+
+			// Release the current scan information, otherwise no other scan could start
+			status := model.GetCurrentScan().Status
+			if status == model.ScanStatusLoadingData || status == model.ScanStatusRunning ||
+				status == model.ScanStatusPaused {
+				if err := model.FinishAndSaveScan(true, storage.ScanDAO().Save); err != nil {
+					log.Println("Error while saving scan information. Details:", err)
+				}
+			}
 		}
 	}()
 
@@ -47,34 +116,14 @@ func ScanDomains() {
 		log.Info("End scan job")
 	}()
 
-	log.Debugf("Initializing database with the parameters: URIS - %v | Name - %s | Auth - %t | Username - %s",
-		config.ShelterConfig.Database.URIs,
-		config.ShelterConfig.Database.Name,
-		config.ShelterConfig.Database.Auth.Enabled,
-		config.ShelterConfig.Database.Auth.Username,
-	)
-
-	database, databaseSession, err := mongodb.Open(
-		config.ShelterConfig.Database.URIs,
-		config.ShelterConfig.Database.Name,
-		config.ShelterConfig.Database.Auth.Enabled,
-		config.ShelterConfig.Database.Auth.Username,
-		config.ShelterConfig.Database.Auth.Password,
-	)
-
-	if err != nil {
-		log.Println("Error while initializing database. Details:", err)
-		return
-	}
-	defer databaseSession.Close()
-
 	injector := NewInjector(
-		database,
+		storage,
 		config.ShelterConfig.Scan.DomainsBufferSize,
 		config.ShelterConfig.Scan.VerificationIntervals.MaxOKDays,
 		config.ShelterConfig.Scan.VerificationIntervals.MaxErrorDays,
 		config.ShelterConfig.Scan.VerificationIntervals.MaxExpirationAlertDays,
 	)
+	injector.Selection = selection
 
 	querierDispatcher := NewQuerierDispatcher(
 		config.ShelterConfig.Scan.NumberOfQueriers,
@@ -86,13 +135,17 @@ func ScanDomains() {
 		config.ShelterConfig.Scan.ConnectionRetries,
 	)
 
+	// The user can pause or cancel the scan while the domains are checked
+	querierDispatcher.Checkpoint = model.ContinueScan
+
 	collector := NewCollector(
-		database,
+		storage,
+		mongoDatabase,
 		config.ShelterConfig.Scan.SaveAtOnce,
 	)
 
-	// Create a new scan information
-	model.StartNewScan()
+	// Solved problems are only useful when someone is going to inform the owners
+	collector.DetectRecoveries = config.ShelterConfig.Notification.Enabled
 
 	var scanGroup sync.WaitGroup
 	errorsChannel := make(chan error, config.ShelterConfig.Scan.ErrorsBufferSize)
@@ -127,14 +180,25 @@ func ScanDomains() {
 	// Finish the error listener sending a poison pill
 	errorsChannel <- nil
 
-	scanDAO := dao.ScanDAO{
-		Database: database,
-	}
+	scanDAO := storage.ScanDAO()
 
 	// Save the scan information for future reports
 	if err := model.FinishAndSaveScan(errorDetected, scanDAO.Save); err != nil {
 		log.Println("Error while saving scan information. Details:", err)
 	}
+
+	// Inform the owners about the problems solved in this scan
+	if len(collector.Recoveries) > 0 {
+		notification.NotifyRecoveries(collector.Recoveries)
+	}
+
+	// Update the nameservers view with the results of this scan. The nameservers summary
+	// is only stored when using MongoDB
+	if mongoDatabase != nil {
+		if err := aggregateNameservers(storage, mongoDatabase); err != nil {
+			log.Println("Error while aggregating nameservers. Details:", err)
+		}
+	}
 }
 
 // Function created to check a single domain without persisting in database. Useful for online
@@ -0,0 +1,47 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package scan is the scan service
+package scan
+
+import (
+	"github.com/rafaeljusto/shelter/model"
+	"regexp"
+)
+
+// ScanSelection defines the domains of an on-demand scan. Differently from the scheduled
+// scan, all selected domains are checked, even if they were checked recently, because
+// the user wants to see the current situation of the domains now
+type ScanSelection struct {
+	FQDN        string // Regular expression (case insensitive) applied over the FQDN
+	OnlyFailing bool   // Select only the domains with DNS or DNSSEC problems
+
+	fqdnRegexp *regexp.Regexp // Compiled regular expression of the FQDN
+}
+
+// Compile the regular expression of the FQDN, so that we can detect an invalid
+// selection before starting the scan
+func (s *ScanSelection) compile() error {
+	if len(s.FQDN) == 0 {
+		s.fqdnRegexp = nil
+		return nil
+	}
+
+	var err error
+	s.fqdnRegexp, err = regexp.Compile("(?i)" + s.FQDN)
+	return err
+}
+
+// Check if the domain is part of the selection
+func (s ScanSelection) match(domain *model.Domain) bool {
+	if s.fqdnRegexp != nil && !s.fqdnRegexp.MatchString(domain.FQDN) {
+		return false
+	}
+
+	if s.OnlyFailing && !domain.IsFailing() {
+		return false
+	}
+
+	return true
+}
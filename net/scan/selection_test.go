@@ -0,0 +1,59 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package scan is the scan service
+package scan
+
+import (
+	"github.com/rafaeljusto/shelter/model"
+	"testing"
+)
+
+func TestScanSelectionMatch(t *testing.T) {
+	failing := &model.Domain{
+		FQDN: "example1.com.br.",
+		Nameservers: []model.Nameserver{
+			{Host: "ns1.example.com.br.", LastStatus: model.NameserverStatusTimeout},
+		},
+	}
+
+	ok := &model.Domain{
+		FQDN: "example2.com.br.",
+		Nameservers: []model.Nameserver{
+			{Host: "ns1.example.com.br.", LastStatus: model.NameserverStatusOK},
+		},
+	}
+
+	var selection ScanSelection
+	if err := selection.compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !selection.match(failing) || !selection.match(ok) {
+		t.Error("Empty selection not selecting all domains")
+	}
+
+	selection = ScanSelection{OnlyFailing: true}
+	if err := selection.compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !selection.match(failing) || selection.match(ok) {
+		t.Error("Not selecting only the failing domains")
+	}
+
+	selection = ScanSelection{FQDN: "^EXAMPLE2"}
+	if err := selection.compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	if selection.match(failing) || !selection.match(ok) {
+		t.Error("Not selecting the domains by FQDN")
+	}
+
+	selection = ScanSelection{FQDN: "example("}
+	if err := selection.compile(); err == nil {
+		t.Error("Accepting an invalid FQDN regular expression")
+	}
+}
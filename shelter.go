@@ -88,7 +88,7 @@ func init() {
 	flag.Parse()
 
 	if *showVersion {
-		fmt.Println(copyright)
+		fmt.Print(copyright)
 		os.Exit(0)
 	}
 
@@ -225,6 +225,14 @@ func main() {
 			Interval:      time.Duration(config.ShelterConfig.Notification.IntervalHours) * time.Hour,
 			Task:          notification.Notify,
 		})
+
+		// The alerts of the owners in quiet hours are delivered soon after the end of the
+		// quiet period, independent of the notification time
+		scheduler.Register(scheduler.Job{
+			Type:     scheduler.JobTypeUnknown,
+			Interval: notification.PostponedInterval,
+			Task:     notification.NotifyPostponed,
+		})
 	}
 
 	scheduler.Start()
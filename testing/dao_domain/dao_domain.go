@@ -164,6 +164,20 @@ func domainsLifeCycle(domainDAO dao.DomainDAO) {
 		}
 	}
 
+	// Search all created domains with a single query
+	var fqdns []string
+	for _, domain := range domains {
+		fqdns = append(fqdns, domain.FQDN)
+	}
+
+	if domainsRetrieved, err := domainDAO.FindByFQDNs(append(fqdns, "unknown.com.br.")); err != nil {
+		utils.Fatalln("Couldn't find created domains in database", err)
+
+	} else if len(domainsRetrieved) != len(domains) {
+		utils.Fatalln(fmt.Sprintf("FindByFQDNs method is not returning all domains we "+
+			"expected %d but got %d", len(domains), len(domainsRetrieved)), nil)
+	}
+
 	// Update domains
 	for _, domain := range domains {
 		domain.Owners = []model.Owner{}
@@ -363,7 +377,7 @@ func domainsPagination(domainDAO dao.DomainDAO) {
 		},
 	}
 
-	domains, err := domainDAO.FindAll(&pagination, true, "")
+	domains, err := domainDAO.FindAll(&pagination, true, dao.DomainDAOFilter{})
 	if err != nil {
 		utils.Fatalln("Error retrieving domains", err)
 	}
@@ -391,7 +405,7 @@ func domainsPagination(domainDAO dao.DomainDAO) {
 		},
 	}
 
-	domains, err = domainDAO.FindAll(&pagination, true, "")
+	domains, err = domainDAO.FindAll(&pagination, true, dao.DomainDAOFilter{})
 	if err != nil {
 		utils.Fatalln("Error retrieving domains", err)
 	}
@@ -584,7 +598,7 @@ func domainsExpand(domainDAO dao.DomainDAO) {
 	}
 
 	pagination := dao.DomainDAOPagination{}
-	domains, err := domainDAO.FindAll(&pagination, false, "")
+	domains, err := domainDAO.FindAll(&pagination, false, dao.DomainDAOFilter{})
 
 	if err != nil {
 		utils.Fatalln("Error retrieving domains", err)
@@ -618,7 +632,7 @@ func domainsExpand(domainDAO dao.DomainDAO) {
 		}
 	}
 
-	domains, err = domainDAO.FindAll(&pagination, true, "")
+	domains, err = domainDAO.FindAll(&pagination, true, dao.DomainDAOFilter{})
 
 	if err != nil {
 		utils.Fatalln("Error retrieving domains", err)
@@ -686,7 +700,7 @@ func domainFilter(domainDAO dao.DomainDAO) {
 		},
 	}
 
-	domains, err := domainDAO.FindAll(&pagination, true, "example1\\.com.*")
+	domains, err := domainDAO.FindAll(&pagination, true, dao.DomainDAOFilter{FQDN: "example1\\.com.*"})
 	if err != nil {
 		utils.Fatalln("Error retrieving domains", err)
 	}
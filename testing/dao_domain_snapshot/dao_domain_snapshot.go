@@ -0,0 +1,202 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/database/mongodb"
+	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/testing/utils"
+	"time"
+)
+
+// This test objective is to verify the domain history persistence. The strategy is to
+// insert snapshots of different domains and check if the history of each domain is
+// retrieved in the correct order and with pagination
+
+var (
+	configFilePath string // Path for the configuration file with the database connection information
+)
+
+// DomainSnapshotDAOTestConfigFile is a structure to store the test configuration file data
+type DomainSnapshotDAOTestConfigFile struct {
+	Database struct {
+		URI  string
+		Name string
+	}
+}
+
+func init() {
+	utils.TestName = "DomainSnapshotDAO"
+	flag.StringVar(&configFilePath, "config", "", "Configuration file for DomainSnapshotDAO test")
+}
+
+func main() {
+	flag.Parse()
+
+	var config DomainSnapshotDAOTestConfigFile
+	err := utils.ReadConfigFile(configFilePath, &config)
+
+	if err == utils.ErrConfigFileUndefined {
+		fmt.Println(err.Error())
+		fmt.Println("Usage:")
+		flag.PrintDefaults()
+		return
+
+	} else if err != nil {
+		utils.Fatalln("Error reading configuration file", err)
+	}
+
+	database, databaseSession, err := mongodb.Open(
+		[]string{config.Database.URI},
+		config.Database.Name,
+		false, "", "",
+	)
+
+	if err != nil {
+		utils.Fatalln("Error connecting the database", err)
+	}
+	defer databaseSession.Close()
+
+	domainSnapshotDAO := dao.DomainSnapshotDAO{
+		Database: database,
+	}
+
+	// If there was some problem in the last test, there could be some data in the
+	// database, so let's clear it to don't affect this test. We avoid checking the error,
+	// because if the collection does not exist yet, it will be created in the first
+	// insert
+	domainSnapshotDAO.RemoveAll()
+
+	domainSnapshotLifeCycle(domainSnapshotDAO)
+	domainSnapshotsPagination(domainSnapshotDAO)
+
+	utils.Println("SUCCESS!")
+}
+
+// Test all phases of the domain snapshot life cycle
+func domainSnapshotLifeCycle(domainSnapshotDAO dao.DomainSnapshotDAO) {
+	snapshot := newDomainSnapshot("example.com.br.", time.Now().Add(-10*time.Minute))
+	if err := domainSnapshotDAO.Save(&snapshot); err != nil {
+		utils.Fatalln("Couldn't save domain snapshot in database", err)
+	}
+
+	otherSnapshot := newDomainSnapshot("example.net.br.", time.Now().Add(-10*time.Minute))
+	if err := domainSnapshotDAO.Save(&otherSnapshot); err != nil {
+		utils.Fatalln("Couldn't save domain snapshot in database", err)
+	}
+
+	var pagination dao.DomainSnapshotDAOPagination
+	snapshots, err := domainSnapshotDAO.FindByFQDN(snapshot.FQDN, &pagination)
+	if err != nil {
+		utils.Fatalln("Couldn't find domain history in database", err)
+	}
+
+	if len(snapshots) != 1 {
+		utils.Fatalln("Not filtering the domain history by FQDN", nil)
+	}
+
+	if snapshots[0].Id != snapshot.Id ||
+		snapshots[0].ScanStartedAt.Unix() != snapshot.ScanStartedAt.Unix() ||
+		snapshots[0].CheckedAt.Unix() != snapshot.CheckedAt.Unix() ||
+		len(snapshots[0].Nameservers) != 1 ||
+		snapshots[0].Nameservers[0] != snapshot.Nameservers[0] ||
+		len(snapshots[0].DSSet) != 1 ||
+		snapshots[0].DSSet[0].Status != snapshot.DSSet[0].Status {
+
+		utils.Fatalln("Domain snapshot is being persisted wrongly", nil)
+	}
+
+	if err := domainSnapshotDAO.RemoveByFQDN(snapshot.FQDN); err != nil {
+		utils.Fatalln("Error while trying to remove the domain history", err)
+	}
+
+	snapshots, err = domainSnapshotDAO.FindByFQDN(snapshot.FQDN, &pagination)
+	if err != nil {
+		utils.Fatalln("Couldn't find domain history in database", err)
+	}
+
+	if len(snapshots) != 0 {
+		utils.Fatalln("Domain history was not removed from database", nil)
+	}
+
+	if err := domainSnapshotDAO.RemoveAll(); err != nil {
+		utils.Fatalln("Error removing domain snapshots from database", err)
+	}
+}
+
+func domainSnapshotsPagination(domainSnapshotDAO dao.DomainSnapshotDAO) {
+	numberOfItems := 100
+
+	var snapshots []*model.DomainSnapshot
+	for i := 0; i < numberOfItems; i++ {
+		snapshot := newDomainSnapshot("example.com.br.",
+			time.Now().Add(time.Duration(-i)*24*time.Hour))
+		snapshots = append(snapshots, &snapshot)
+	}
+
+	if err := domainSnapshotDAO.SaveMany(snapshots); err != nil {
+		utils.Fatalln("Error saving domain snapshots in database", err)
+	}
+
+	pagination := dao.DomainSnapshotDAOPagination{
+		PageSize: 10,
+		Page:     5,
+	}
+
+	history, err := domainSnapshotDAO.FindByFQDN("example.com.br.", &pagination)
+	if err != nil {
+		utils.Fatalln("Error retrieving domain history", err)
+	}
+
+	if pagination.NumberOfItems != numberOfItems {
+		utils.Errorln("Number of items not calculated correctly", nil)
+	}
+
+	if pagination.NumberOfPages != numberOfItems/pagination.PageSize {
+		utils.Errorln("Number of pages not calculated correctly", nil)
+	}
+
+	if len(history) != pagination.PageSize {
+		utils.Fatalln("Number of domain snapshots not following page size", nil)
+	}
+
+	// By default the most recent snapshots should be returned first
+	for i := 1; i < len(history); i++ {
+		if history[i].CheckedAt.After(history[i-1].CheckedAt) {
+			utils.Fatalln("Domain history is not ordered by the check date", nil)
+		}
+	}
+
+	if err := domainSnapshotDAO.RemoveAll(); err != nil {
+		utils.Fatalln("Error removing domain snapshots from database", err)
+	}
+}
+
+// Function to mock a domain snapshot object
+func newDomainSnapshot(fqdn string, scanStartedAt time.Time) model.DomainSnapshot {
+	return model.DomainSnapshot{
+		FQDN:          fqdn,
+		ScanStartedAt: scanStartedAt,
+		CheckedAt:     scanStartedAt.Add(time.Minute),
+		Nameservers: []model.NameserverSnapshot{
+			{
+				Host:       "ns1." + fqdn,
+				Status:     model.NameserverStatusTimeout,
+				StatusIPv4: model.NameserverStatusTimeout,
+				StatusIPv6: model.NameserverStatusOK,
+			},
+		},
+		DSSet: []model.DSSnapshot{
+			{
+				Keytag:    1234,
+				Status:    model.DSStatusExpiredSignature,
+				ExpiresAt: scanStartedAt.Add(-24 * time.Hour),
+			},
+		},
+	}
+}
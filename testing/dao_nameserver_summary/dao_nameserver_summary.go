@@ -0,0 +1,209 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/database/mongodb"
+	"github.com/rafaeljusto/shelter/model"
+	"github.com/rafaeljusto/shelter/testing/utils"
+	"time"
+)
+
+// This test objective is to verify the nameserver summary persistence. The strategy is to
+// insert, update and remove summaries, checking the pagination and the removal of the
+// hosts that weren't updated after an aggregation
+
+var (
+	configFilePath string // Path for the configuration file with the database connection information
+)
+
+// NameserverSummaryDAOTestConfigFile is a structure to store the test configuration file
+// data
+type NameserverSummaryDAOTestConfigFile struct {
+	Database struct {
+		URI  string
+		Name string
+	}
+}
+
+func init() {
+	utils.TestName = "NameserverSummaryDAO"
+	flag.StringVar(&configFilePath, "config", "", "Configuration file for NameserverSummaryDAO test")
+}
+
+func main() {
+	flag.Parse()
+
+	var config NameserverSummaryDAOTestConfigFile
+	err := utils.ReadConfigFile(configFilePath, &config)
+
+	if err == utils.ErrConfigFileUndefined {
+		fmt.Println(err.Error())
+		fmt.Println("Usage:")
+		flag.PrintDefaults()
+		return
+
+	} else if err != nil {
+		utils.Fatalln("Error reading configuration file", err)
+	}
+
+	database, databaseSession, err := mongodb.Open(
+		[]string{config.Database.URI},
+		config.Database.Name,
+		false, "", "",
+	)
+
+	if err != nil {
+		utils.Fatalln("Error connecting the database", err)
+	}
+	defer databaseSession.Close()
+
+	nameserverSummaryDAO := dao.NameserverSummaryDAO{
+		Database: database,
+	}
+
+	// If there was some problem in the last test, there could be some data in the
+	// database, so let's clear it to don't affect this test. We avoid checking the error,
+	// because if the collection does not exist yet, it will be created in the first
+	// insert
+	nameserverSummaryDAO.RemoveAll()
+
+	nameserverSummaryLifeCycle(nameserverSummaryDAO)
+	nameserverSummariesPagination(nameserverSummaryDAO)
+
+	utils.Println("SUCCESS!")
+}
+
+// Test all phases of the nameserver summary life cycle
+func nameserverSummaryLifeCycle(nameserverSummaryDAO dao.NameserverSummaryDAO) {
+	summary := newNameserverSummary("ns1.example.com.br.")
+	if err := nameserverSummaryDAO.Save(&summary); err != nil {
+		utils.Fatalln("Couldn't save nameserver summary in database", err)
+	}
+
+	summaryRetrieved, err := nameserverSummaryDAO.FindByHost(summary.Host)
+	if err != nil {
+		utils.Fatalln("Couldn't find created nameserver summary in database", err)
+	}
+
+	if summaryRetrieved.Id != summary.Id ||
+		summaryRetrieved.Revision != 1 ||
+		summaryRetrieved.Domains != summary.Domains ||
+		summaryRetrieved.Timeouts != summary.Timeouts ||
+		summaryRetrieved.Statistics["OK"] != summary.Statistics["OK"] ||
+		summaryRetrieved.Statistics["TIMEOUT"] != summary.Statistics["TIMEOUT"] {
+
+		utils.Fatalln("Nameserver summary is being persisted wrongly", nil)
+	}
+
+	otherSummary := newNameserverSummary("ns2.example.com.br.")
+	if err := nameserverSummaryDAO.Save(&otherSummary); err != nil {
+		utils.Fatalln("Couldn't save nameserver summary in database", err)
+	}
+
+	// The save method always updates the modification date, so we need to wait a little
+	// to detect the hosts that weren't updated
+	time.Sleep(10 * time.Millisecond)
+	aggregationStartedAt := time.Now().UTC()
+
+	// Simulate that only the first nameserver was updated in a new aggregation
+	summaryRetrieved.Timeouts = 100
+	if err := nameserverSummaryDAO.Save(&summaryRetrieved); err != nil {
+		utils.Fatalln("Couldn't update nameserver summary in database", err)
+	}
+
+	if err := nameserverSummaryDAO.RemoveNotModifiedSince(aggregationStartedAt); err != nil {
+		utils.Fatalln("Error while removing old nameserver summaries", err)
+	}
+
+	if _, err := nameserverSummaryDAO.FindByHost(otherSummary.Host); err == nil {
+		utils.Fatalln("Not removing nameserver summaries that weren't updated", nil)
+	}
+
+	summaryRetrieved, err = nameserverSummaryDAO.FindByHost(summary.Host)
+	if err != nil {
+		utils.Fatalln("Removing nameserver summaries that were updated", err)
+	}
+
+	if summaryRetrieved.Revision != 2 || summaryRetrieved.Timeouts != 100 {
+		utils.Fatalln("Nameserver summary is being updated wrongly", nil)
+	}
+
+	if err := nameserverSummaryDAO.RemoveAll(); err != nil {
+		utils.Fatalln("Error removing nameserver summaries from database", err)
+	}
+}
+
+func nameserverSummariesPagination(nameserverSummaryDAO dao.NameserverSummaryDAO) {
+	numberOfItems := 100
+
+	for i := 0; i < numberOfItems; i++ {
+		summary := newNameserverSummary(fmt.Sprintf("ns%d.example.com.br.", i))
+		summary.Domains = uint64(i)
+
+		if err := nameserverSummaryDAO.Save(&summary); err != nil {
+			utils.Fatalln("Error saving nameserver summary in database", err)
+		}
+	}
+
+	pagination := dao.NameserverSummaryDAOPagination{
+		PageSize: 10,
+		Page:     5,
+		OrderBy: []dao.NameserverSummaryDAOSort{
+			{
+				Field:     dao.NameserverSummaryDAOOrderByFieldDomains,
+				Direction: dao.DAOOrderByDirectionDescending,
+			},
+		},
+	}
+
+	summaries, err := nameserverSummaryDAO.FindAll(&pagination)
+	if err != nil {
+		utils.Fatalln("Error retrieving nameserver summaries", err)
+	}
+
+	if pagination.NumberOfItems != numberOfItems {
+		utils.Errorln("Number of items not calculated correctly", nil)
+	}
+
+	if pagination.NumberOfPages != numberOfItems/pagination.PageSize {
+		utils.Errorln("Number of pages not calculated correctly", nil)
+	}
+
+	if len(summaries) != pagination.PageSize {
+		utils.Fatalln("Number of nameserver summaries not following page size", nil)
+	}
+
+	for i := 1; i < len(summaries); i++ {
+		if summaries[i].Domains > summaries[i-1].Domains {
+			utils.Fatalln("Nameserver summaries are not ordered by the number of domains", nil)
+		}
+	}
+
+	if err := nameserverSummaryDAO.RemoveAll(); err != nil {
+		utils.Fatalln("Error removing nameserver summaries from database", err)
+	}
+}
+
+// Function to mock a nameserver summary object
+func newNameserverSummary(host string) model.NameserverSummary {
+	summary := model.NewNameserverSummary(host)
+	summary.AddNameserver(model.Nameserver{
+		Host:        host,
+		LastStatus:  model.NameserverStatusOK,
+		LastCheckAt: time.Now(),
+		LastOKAt:    time.Now(),
+	})
+	summary.AddNameserver(model.Nameserver{
+		Host:        host,
+		LastStatus:  model.NameserverStatusTimeout,
+		LastCheckAt: time.Now(),
+	})
+	summary.Timeouts = 10
+	return summary
+}
@@ -264,6 +264,49 @@ func simpleNotification(domainDAO dao.DomainDAO, templateName string,
 		utils.Fatalln("No mail sent", nil)
 	}
 
+	notificationDAO := dao.NotificationDAO{
+		Database: domainDAO.Database,
+	}
+
+	var pagination dao.NotificationDAOPagination
+	notifications, err := notificationDAO.FindByFQDN("example.com.br.", &pagination)
+	if err != nil {
+		utils.Fatalln("Error retrieving notifications", err)
+	}
+
+	if len(notifications) != 1 {
+		utils.Fatalln(fmt.Sprintf("Expected 1 notification stored and found %d",
+			len(notifications)), nil)
+	}
+
+	if !notifications[0].Delivered ||
+		notifications[0].Channel != "smtp" ||
+		len(notifications[0].Recipients) != 1 ||
+		notifications[0].Recipients[0] != "test@rafael.net.br" ||
+		len(notifications[0].Problems) != 1 ||
+		notifications[0].Problems[0] != "nameserver ns1.example.com.br. SERVFAIL" {
+
+		utils.Fatalln(fmt.Sprintf("Notification not stored correctly: %#v", notifications[0]), nil)
+	}
+
+	// The problems didn't change and we are inside the re-notify interval, so the owner
+	// shouldn't receive the same alert again
+	notification.Notify()
+
+	select {
+	case <-messageChannel:
+		utils.Fatalln("Sending the same alert inside the re-notify interval", nil)
+
+	case err := <-errorChannel:
+		utils.Fatalln("Error receiving message", err)
+
+	case <-time.After(2 * time.Second):
+	}
+
+	if err := notificationDAO.RemoveByFQDN("example.com.br."); err != nil {
+		utils.Fatalln("Error removing notifications", err)
+	}
+
 	if err := domainDAO.RemoveByFQDN("example.com.br."); err != nil {
 		utils.Fatalln("Error removing domain", err)
 	}
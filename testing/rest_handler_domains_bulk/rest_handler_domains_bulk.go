@@ -0,0 +1,249 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/rafaeljusto/handy"
+	"github.com/rafaeljusto/shelter/config"
+	"github.com/rafaeljusto/shelter/database/mongodb"
+	"github.com/rafaeljusto/shelter/net/http/rest/handler"
+	"github.com/rafaeljusto/shelter/net/http/rest/protocol"
+	"github.com/rafaeljusto/shelter/testing/utils"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// This test objective is to verify the bulk import and the export of domains. The
+// strategy is to import domains using both formats (JSON array and NDJSON), check the
+// result of each domain and then export all domains, importing them again
+
+var (
+	configFilePath string // Path for the config file with the connection information
+)
+
+func init() {
+	utils.TestName = "RESTHandlerDomainsBulk"
+	flag.StringVar(&configFilePath, "config", "", "Configuration file for RESTHandlerDomainsBulk test")
+}
+
+func main() {
+	flag.Parse()
+
+	err := utils.ReadConfigFile(configFilePath, &config.ShelterConfig)
+
+	if err == utils.ErrConfigFileUndefined {
+		fmt.Println(err.Error())
+		fmt.Println("Usage:")
+		flag.PrintDefaults()
+		return
+
+	} else if err != nil {
+		utils.Fatalln("Error reading configuration file", err)
+	}
+
+	database, databaseSession, err := mongodb.Open(
+		config.ShelterConfig.Database.URIs,
+		config.ShelterConfig.Database.Name,
+		false, "", "",
+	)
+
+	if err != nil {
+		utils.Fatalln("Error connecting the database", err)
+	}
+	defer databaseSession.Close()
+
+	// If there was some problem in the last test, there could be some data in the
+	// database, so let's clear it to don't affect this test. We avoid checking the error,
+	// because if the collection does not exist yet, it will be created in the first
+	// insert
+	utils.ClearDatabase(database)
+
+	importDomainsArray()
+	importDomainsNDJSON()
+	importInvalidContent()
+	exportAndImportDomains()
+
+	utils.ClearDatabase(database)
+	utils.Println("SUCCESS!")
+}
+
+func importDomainsArray() {
+	requestContent := `[
+    {
+      "fqdn": "example1.com.br.",
+      "nameservers": [
+        { "host": "ns1.example1.com.br.", "ipv4": "127.0.0.1" },
+        { "host": "ns2.example1.com.br." }
+      ]
+    },
+    {
+      "fqdn": "example2.com.br.",
+      "nameservers": [
+        { "host": "ns1.example2.com.br.", "ipv4": "xxx" }
+      ]
+    },
+    {
+      "fqdn": "example1.com.br.",
+      "nameservers": [
+        { "host": "ns1.example1.com.br." }
+      ]
+    }
+  ]`
+
+	response := importDomains(requestContent)
+
+	if response.Created != 1 || response.Updated != 0 || response.Failed != 2 {
+		utils.Fatalln(fmt.Sprintf("Wrong counters in bulk response. Created %d, "+
+			"updated %d and failed %d", response.Created, response.Updated, response.Failed), nil)
+	}
+
+	expectedStatus := []int{http.StatusCreated, http.StatusBadRequest, http.StatusConflict}
+	for i, result := range response.Results {
+		if result.Status != expectedStatus[i] {
+			utils.Fatalln(fmt.Sprintf("Expected HTTP status %d for domain %s and got %d",
+				expectedStatus[i], result.FQDN, result.Status), nil)
+		}
+	}
+
+	if response.Results[1].Message == nil || response.Results[1].Message.Id != "invalid-ip" {
+		utils.Fatalln("Not informing the reason of the failure", nil)
+	}
+}
+
+func importDomainsNDJSON() {
+	requestContent := `{"fqdn": "example1.com.br.", "nameservers": [{"host": "ns3.example1.com.br."}]}
+{"fqdn": "example3.com.br.", "nameservers": [{"host": "ns1.example3.com.br."}]}
+{"fqdn": "example..com.br."}
+`
+
+	response := importDomains(requestContent)
+
+	if response.Created != 1 || response.Updated != 1 || response.Failed != 1 {
+		utils.Fatalln(fmt.Sprintf("Wrong counters in bulk response. Created %d, "+
+			"updated %d and failed %d", response.Created, response.Updated, response.Failed), nil)
+	}
+
+	expectedStatus := []int{http.StatusNoContent, http.StatusCreated, http.StatusBadRequest}
+	for i, result := range response.Results {
+		if result.Status != expectedStatus[i] {
+			utils.Fatalln(fmt.Sprintf("Expected HTTP status %d for domain %s and got %d",
+				expectedStatus[i], result.FQDN, result.Status), nil)
+		}
+	}
+}
+
+func importInvalidContent() {
+	mux := handy.NewHandy()
+
+	h := new(handler.DomainsBulkHandler)
+	mux.Handle("/domains/bulk", func() handy.Handler {
+		return h
+	})
+
+	requestContent := `[{"fqdn": "example1.com.br."}`
+
+	r, err := http.NewRequest("POST", "/domains/bulk", strings.NewReader(requestContent))
+	if err != nil {
+		utils.Fatalln("Error creating the HTTP request", err)
+	}
+	utils.BuildHTTPHeader(r, []byte(requestContent))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		utils.Fatalln(fmt.Sprintf("Expected HTTP status %d for invalid content and got %d",
+			http.StatusBadRequest, w.Code), nil)
+	}
+}
+
+func exportAndImportDomains() {
+	mux := handy.NewHandy()
+
+	h := new(handler.DomainsExportHandler)
+	mux.Handle("/domains/export", func() handy.Handler {
+		return h
+	})
+
+	r, err := http.NewRequest("GET", "/domains/export", nil)
+	if err != nil {
+		utils.Fatalln("Error creating the HTTP request", err)
+	}
+	utils.BuildHTTPHeader(r, nil)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	responseContent, err := ioutil.ReadAll(w.Body)
+	if err != nil {
+		utils.Fatalln("Error reading response body", err)
+	}
+
+	if w.Code != http.StatusOK {
+		utils.Fatalln("Error exporting domains", errors.New(string(responseContent)))
+	}
+
+	fqdns := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(responseContent))
+	for scanner.Scan() {
+		var domainResponse protocol.DomainResponse
+		if err := json.Unmarshal(scanner.Bytes(), &domainResponse); err != nil {
+			utils.Fatalln("Error decoding exported domain", err)
+		}
+		fqdns[domainResponse.FQDN] = true
+	}
+
+	if len(fqdns) != 2 || !fqdns["example1.com.br."] || !fqdns["example3.com.br."] {
+		utils.Fatalln("Not exporting all domains", errors.New(string(responseContent)))
+	}
+
+	// The exported content must be accepted by the bulk import
+	response := importDomains(string(responseContent))
+	if response.Updated != 2 || response.Failed != 0 {
+		utils.Fatalln("Not importing the exported domains", nil)
+	}
+}
+
+func importDomains(requestContent string) protocol.DomainsBulkResponse {
+	mux := handy.NewHandy()
+
+	h := new(handler.DomainsBulkHandler)
+	mux.Handle("/domains/bulk", func() handy.Handler {
+		return h
+	})
+
+	r, err := http.NewRequest("POST", "/domains/bulk", strings.NewReader(requestContent))
+	if err != nil {
+		utils.Fatalln("Error creating the HTTP request", err)
+	}
+	utils.BuildHTTPHeader(r, []byte(requestContent))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	responseContent, err := ioutil.ReadAll(w.Body)
+	if err != nil {
+		utils.Fatalln("Error reading response body", err)
+	}
+
+	if w.Code != http.StatusOK {
+		utils.Fatalln("Error importing domains", errors.New(string(responseContent)))
+	}
+
+	var response protocol.DomainsBulkResponse
+	if err := json.Unmarshal(responseContent, &response); err != nil {
+		utils.Fatalln("Error decoding bulk response", err)
+	}
+
+	return response
+}
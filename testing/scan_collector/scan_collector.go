@@ -72,6 +72,7 @@ func main() {
 
 	domainWithErrors(config, database)
 	domainWithNoErrors(config, database)
+	domainRecovered(config, database)
 
 	utils.Println("SUCCESS!")
 }
@@ -100,7 +101,7 @@ func domainWithErrors(config ScanCollectorTestConfigFile, database *mgo.Database
 	domainsToSave <- nil
 
 	model.StartNewScan()
-	runScan(config, database, domainsToSave)
+	runScan(config, database, domainsToSave, false)
 
 	domainDAO := dao.DomainDAO{
 		Database: database,
@@ -166,7 +167,7 @@ func domainWithNoErrors(config ScanCollectorTestConfigFile, database *mgo.Databa
 	domainsToSave <- nil
 
 	model.StartNewScan()
-	runScan(config, database, domainsToSave)
+	runScan(config, database, domainsToSave, false)
 
 	domainDAO := dao.DomainDAO{
 		Database: database,
@@ -208,12 +209,72 @@ func domainWithNoErrors(config ScanCollectorTestConfigFile, database *mgo.Databa
 	}
 }
 
+func domainRecovered(config ScanCollectorTestConfigFile, database *mgo.Database) {
+	domainDAO := dao.DomainDAO{
+		Database: database,
+	}
+
+	domain := model.Domain{
+		FQDN: "br.",
+		Nameservers: []model.Nameserver{
+			{
+				Host:       "ns1.br",
+				IPv4:       net.ParseIP("127.0.0.1"),
+				LastStatus: model.NameserverStatusTimeout,
+			},
+			{
+				Host:       "ns2.br",
+				IPv4:       net.ParseIP("127.0.0.2"),
+				LastStatus: model.NameserverStatusTimeout,
+			},
+		},
+	}
+
+	if err := domainDAO.Save(&domain); err != nil {
+		utils.Fatalln("Error saving domain with problems", err)
+	}
+
+	domain.Nameservers[0].LastStatus = model.NameserverStatusOK
+
+	domainsToSave := make(chan *model.Domain, config.Scan.DomainsBufferSize)
+	domainsToSave <- &domain
+	domainsToSave <- nil
+
+	model.StartNewScan()
+	scanCollector := runScan(config, database, domainsToSave, true)
+
+	if len(scanCollector.Recoveries) != 1 {
+		utils.Fatalln(fmt.Sprintf("Expected 1 recovery and found %d",
+			len(scanCollector.Recoveries)), nil)
+	}
+
+	recovery := scanCollector.Recoveries[0]
+	if recovery.Domain.FQDN != "br." ||
+		len(recovery.Nameservers) != 1 ||
+		recovery.Nameservers[0].Host != "ns1.br" ||
+		recovery.Nameservers[0].LastStatus != model.NameserverStatusTimeout ||
+		len(recovery.DSSet) != 0 {
+
+		utils.Fatalln("Not detecting the solved problems correctly", nil)
+	}
+
+	if err := domainDAO.RemoveByFQDN("br."); err != nil {
+		utils.Fatalln("Error removing test domain", err)
+	}
+}
+
 // Method responsable to configure and start scan injector for tests
 func runScan(config ScanCollectorTestConfigFile,
 	database *mgo.Database,
-	domainsToSave chan *model.Domain) {
+	domainsToSave chan *model.Domain,
+	detectRecoveries bool) *scan.Collector {
 
-	scanCollector := scan.NewCollector(database, config.Scan.SaveAtOnce)
+	scanCollector := scan.NewCollector(
+		dao.MongoDBStorage{Database: database},
+		database,
+		config.Scan.SaveAtOnce,
+	)
+	scanCollector.DetectRecoveries = detectRecoveries
 
 	var scanGroup sync.WaitGroup
 	errorsChannel := make(chan error)
@@ -227,6 +288,7 @@ func runScan(config ScanCollectorTestConfigFile,
 	}()
 
 	scanGroup.Wait()
+	return scanCollector
 }
 
 // Function to mock a domain object
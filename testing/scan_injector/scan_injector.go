@@ -212,7 +212,7 @@ func domainWithNoErrors(config ScanInjectorTestConfigFile, domainDAO dao.DomainD
 // Method responsable to configure and start scan injector for tests
 func runScan(config ScanInjectorTestConfigFile, domainDAO dao.DomainDAO) []*model.Domain {
 	scanInjector := scan.NewInjector(
-		domainDAO.Database,
+		dao.MongoDBStorage{Database: domainDAO.Database},
 		config.Scan.DomainsBufferSize,
 		config.Scan.VerificationIntervals.MaxOKDays,
 		config.Scan.VerificationIntervals.MaxErrorDays,
@@ -28,8 +28,11 @@ func CompareDomain(d1, d2 model.Domain) bool {
 			d1.Nameservers[i].IPv4.String() != d2.Nameservers[i].IPv4.String() ||
 			d1.Nameservers[i].IPv6.String() != d2.Nameservers[i].IPv6.String() ||
 			d1.Nameservers[i].LastStatus != d2.Nameservers[i].LastStatus ||
+			d1.Nameservers[i].LastStatusIPv4 != d2.Nameservers[i].LastStatusIPv4 ||
+			d1.Nameservers[i].LastStatusIPv6 != d2.Nameservers[i].LastStatusIPv6 ||
 			d1.Nameservers[i].LastCheckAt.Unix() != d2.Nameservers[i].LastCheckAt.Unix() ||
-			d1.Nameservers[i].LastOKAt.Unix() != d2.Nameservers[i].LastOKAt.Unix() {
+			d1.Nameservers[i].LastOKAt.Unix() != d2.Nameservers[i].LastOKAt.Unix() ||
+			!compareCompliance(d1.Nameservers[i].Compliance, d2.Nameservers[i].Compliance) {
 			return false
 		}
 	}
@@ -44,6 +47,7 @@ func CompareDomain(d1, d2 model.Domain) bool {
 			d1.DSSet[i].Digest != d2.DSSet[i].Digest ||
 			d1.DSSet[i].DigestType != d2.DSSet[i].DigestType ||
 			d1.DSSet[i].ExpiresAt.Unix() != d2.DSSet[i].ExpiresAt.Unix() ||
+			d1.DSSet[i].ExpiresFrom != d2.DSSet[i].ExpiresFrom ||
 			d1.DSSet[i].Keytag != d2.DSSet[i].Keytag ||
 			d1.DSSet[i].LastCheckAt.Unix() != d2.DSSet[i].LastCheckAt.Unix() ||
 			d1.DSSet[i].LastOKAt.Unix() != d2.DSSet[i].LastOKAt.Unix() ||
@@ -80,8 +84,11 @@ func CompareProtocolDomain(d1, d2 protocol.DomainResponse) bool {
 			d1.Nameservers[i].IPv4 != d2.Nameservers[i].IPv4 ||
 			d1.Nameservers[i].IPv6 != d2.Nameservers[i].IPv6 ||
 			d1.Nameservers[i].LastStatus != d2.Nameservers[i].LastStatus ||
+			d1.Nameservers[i].LastStatusIPv4 != d2.Nameservers[i].LastStatusIPv4 ||
+			d1.Nameservers[i].LastStatusIPv6 != d2.Nameservers[i].LastStatusIPv6 ||
 			d1.Nameservers[i].LastCheckAt.Unix() != d2.Nameservers[i].LastCheckAt.Unix() ||
-			d1.Nameservers[i].LastOKAt.Unix() != d2.Nameservers[i].LastOKAt.Unix() {
+			d1.Nameservers[i].LastOKAt.Unix() != d2.Nameservers[i].LastOKAt.Unix() ||
+			!compareProtocolCompliance(d1.Nameservers[i].Compliance, d2.Nameservers[i].Compliance) {
 			return false
 		}
 	}
@@ -96,6 +103,7 @@ func CompareProtocolDomain(d1, d2 protocol.DomainResponse) bool {
 			d1.DSSet[i].Digest != d2.DSSet[i].Digest ||
 			d1.DSSet[i].DigestType != d2.DSSet[i].DigestType ||
 			d1.DSSet[i].ExpiresAt.Unix() != d2.DSSet[i].ExpiresAt.Unix() ||
+			d1.DSSet[i].ExpiresFrom != d2.DSSet[i].ExpiresFrom ||
 			d1.DSSet[i].Keytag != d2.DSSet[i].Keytag ||
 			d1.DSSet[i].LastCheckAt.Unix() != d2.DSSet[i].LastCheckAt.Unix() ||
 			d1.DSSet[i].LastOKAt.Unix() != d2.DSSet[i].LastOKAt.Unix() ||
@@ -238,3 +246,29 @@ func CompareProtocolLinks(l1 []protocol.Link, l2 []protocol.Link) bool {
 
 	return true
 }
+
+// Function to compare the compliance report of two nameservers, cannot use operator ==
+// because of the date
+func compareCompliance(c1, c2 model.NameserverCompliance) bool {
+	return c1.Plain == c2.Plain &&
+		c1.EDNS == c2.EDNS &&
+		c1.EDNSDO == c2.EDNSDO &&
+		c1.EDNSOption == c2.EDNSOption &&
+		c1.TCP == c2.TCP &&
+		c1.LastCheckAt.Unix() == c2.LastCheckAt.Unix()
+}
+
+// Function to compare the compliance report of two nameservers in the protocol format.
+// The report is optional, so both can be nil
+func compareProtocolCompliance(c1, c2 *protocol.ComplianceResponse) bool {
+	if c1 == nil || c2 == nil {
+		return c1 == c2
+	}
+
+	return c1.Plain == c2.Plain &&
+		c1.EDNS == c2.EDNS &&
+		c1.EDNSDO == c2.EDNSDO &&
+		c1.EDNSOption == c2.EDNSOption &&
+		c1.TCP == c2.TCP &&
+		c1.LastCheckAt.Unix() == c2.LastCheckAt.Unix()
+}
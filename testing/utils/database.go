@@ -22,4 +22,9 @@ func ClearDatabase(database *mgo.Database) {
 		Database: database,
 	}
 	scanDAO.RemoveAll()
+
+	notificationDAO := dao.NotificationDAO{
+		Database: database,
+	}
+	notificationDAO.RemoveAll()
 }
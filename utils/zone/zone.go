@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rafaeljusto/shelter/config"
+	"github.com/rafaeljusto/shelter/database"
+	"github.com/rafaeljusto/shelter/zone"
+)
+
+var (
+	configFilePath string
+	zoneFilePath   string
+	origin         string
+)
+
+func init() {
+	flag.StringVar(&configFilePath, "config", "", "Shelter configuration file")
+	flag.StringVar(&zoneFilePath, "zone", "", "Master zone file with the delegations")
+	flag.StringVar(&origin, "origin", "", "Origin for relative names when the zone file has no $ORIGIN")
+}
+
+func main() {
+	flag.Parse()
+
+	if len(configFilePath) == 0 {
+		fmt.Println("Configuration file not informed")
+		flag.PrintDefaults()
+		return
+	}
+
+	if len(zoneFilePath) == 0 {
+		fmt.Println("Zone file not informed")
+		flag.PrintDefaults()
+		return
+	}
+
+	if err := config.LoadConfig(configFilePath); err != nil {
+		fmt.Println("Error loading the configuration file. Details:", err)
+		return
+	}
+
+	file, err := os.Open(zoneFilePath)
+	if err != nil {
+		fmt.Println("Error opening zone file. Details:", err)
+		return
+	}
+	defer file.Close()
+
+	domains, err := zone.Parse(file, origin, zoneFilePath)
+	if err != nil {
+		fmt.Println("Error parsing zone file. Details:", err)
+		return
+	}
+
+	storage, _, databaseSession, err := database.Open()
+	if err != nil {
+		fmt.Println("Error connecting the database. Details:", err)
+		return
+	}
+
+	if databaseSession != nil {
+		defer databaseSession.Close()
+	}
+
+	result := zone.Import(storage, domains, nil)
+
+	fmt.Println("Delegations found:", len(domains))
+	fmt.Println("Created:", result.Created)
+	fmt.Println("Updated:", result.Updated)
+	fmt.Println("Unchanged:", result.Unchanged)
+	fmt.Println("Failed:", result.Failed)
+}
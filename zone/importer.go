@@ -0,0 +1,174 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package zone loads the delegations of a master zone file into the system
+package zone
+
+import (
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/gopkg.in/mgo.v2"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
+)
+
+// ImportResult stores the number of domains affected by an import. Domains that already
+// exist with the same delegation aren't saved again, so their revision doesn't change
+type ImportResult struct {
+	Created   int // Number of new domains
+	Updated   int // Number of domains that had the delegation changed
+	Unchanged int // Number of domains that already had the same delegation
+	Failed    int // Number of domains that couldn't be saved
+}
+
+// Import creates or updates the domains of a zone file. When the domain already exists
+// we keep the owners and the results of the last checks of the nameservers and DS
+// records that didn't change. The changed function (optional) is called for each saved
+// domain with the state before the import, that is nil for new domains. The revisions of
+// removed domains are kept, so a domain created again continues the numbering after the
+// last stored revision
+func Import(storage dao.Storage, domains []model.Domain,
+	changed func(before, after *model.Domain)) ImportResult {
+
+	domainDAO := storage.DomainDAO()
+	domainRevisionDAO := storage.DomainRevisionDAO()
+
+	var result ImportResult
+	var domainsToSave []*model.Domain
+	previousStates := make(map[*model.Domain]*model.Domain)
+
+	for _, zoneDomain := range domains {
+		// If the domain does not exist yet thats alright because we will create it
+		domain, err := domainDAO.FindByFQDN(zoneDomain.FQDN)
+		if err == nil && sameDelegation(domain, zoneDomain) {
+			result.Unchanged += 1
+			continue
+		}
+
+		var before *model.Domain
+		if err == nil {
+			previous := domain
+			before = &previous
+		}
+
+		domain = merge(domain, zoneDomain)
+
+		if before == nil {
+			revision, err := domainRevisionDAO.FindLast(domain.FQDN)
+			if err == nil {
+				domain.Revision = revision.Revision
+
+			} else if err != mgo.ErrNotFound {
+				log.Printf("Error while retrieving the last revision of domain %s. Details: %s",
+					domain.FQDN, err)
+				result.Failed += 1
+				continue
+			}
+		}
+
+		domainsToSave = append(domainsToSave, &domain)
+		previousStates[&domain] = before
+	}
+
+	for _, domainResult := range domainDAO.SaveMany(domainsToSave) {
+		if domainResult.Error != nil {
+			log.Printf("Error while importing domain %s. Details: %s",
+				domainResult.Domain.FQDN, domainResult.Error)
+			result.Failed += 1
+			continue
+		}
+
+		if previousStates[domainResult.Domain] == nil {
+			result.Created += 1
+		} else {
+			result.Updated += 1
+		}
+
+		if changed != nil {
+			changed(previousStates[domainResult.Domain], domainResult.Domain)
+		}
+	}
+
+	return result
+}
+
+// merge replaces the delegation of the domain with the delegation from the zone file.
+// The same strategy of the REST protocol is used to keep the state of the nameservers
+// (identified by the host) and the DS records (identified by the keytag)
+func merge(domain model.Domain, zoneDomain model.Domain) model.Domain {
+	domain.FQDN = zoneDomain.FQDN
+
+	// Only new domains are created by the principal that imported the zone
+	if domain.Revision == 0 {
+		domain.CreatedBy = zoneDomain.CreatedBy
+	}
+
+	var nameservers []model.Nameserver
+	for _, zoneNameserver := range zoneDomain.Nameservers {
+		nameserver := zoneNameserver
+
+		for _, currentNameserver := range domain.Nameservers {
+			if currentNameserver.Host == zoneNameserver.Host {
+				nameserver = currentNameserver
+				nameserver.IPv4 = zoneNameserver.IPv4
+				nameserver.IPv6 = zoneNameserver.IPv6
+				break
+			}
+		}
+
+		nameservers = append(nameservers, nameserver)
+	}
+	domain.Nameservers = nameservers
+
+	var dsSet []model.DS
+	for _, zoneDS := range zoneDomain.DSSet {
+		ds := zoneDS
+
+		for _, currentDS := range domain.DSSet {
+			if currentDS.Keytag == zoneDS.Keytag {
+				ds = currentDS
+				ds.Algorithm = zoneDS.Algorithm
+				ds.Digest = zoneDS.Digest
+				ds.DigestType = zoneDS.DigestType
+				break
+			}
+		}
+
+		dsSet = append(dsSet, ds)
+	}
+	domain.DSSet = dsSet
+
+	return domain
+}
+
+// sameDelegation checks if the nameservers and DS records of the domain are the same of
+// the zone file. The order of the records is relevant
+func sameDelegation(domain model.Domain, zoneDomain model.Domain) bool {
+	if len(domain.Nameservers) != len(zoneDomain.Nameservers) ||
+		len(domain.DSSet) != len(zoneDomain.DSSet) {
+		return false
+	}
+
+	for i, nameserver := range domain.Nameservers {
+		zoneNameserver := zoneDomain.Nameservers[i]
+
+		if nameserver.Host != zoneNameserver.Host ||
+			!nameserver.IPv4.Equal(zoneNameserver.IPv4) ||
+			!nameserver.IPv6.Equal(zoneNameserver.IPv6) {
+			return false
+		}
+	}
+
+	for i, ds := range domain.DSSet {
+		zoneDS := zoneDomain.DSSet[i]
+
+		if ds.Keytag != zoneDS.Keytag ||
+			ds.Algorithm != zoneDS.Algorithm ||
+			ds.Digest != zoneDS.Digest ||
+			ds.DigestType != zoneDS.DigestType {
+			return false
+		}
+	}
+
+	return true
+}
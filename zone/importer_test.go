@@ -0,0 +1,153 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package zone loads the delegations of a master zone file into the system
+package zone
+
+import (
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/database/file"
+	"github.com/rafaeljusto/shelter/model"
+	"io/ioutil"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shelter-zone")
+	if err != nil {
+		t.Fatal("Error creating temporary directory. Details:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	database, err := file.Open(filepath.Join(dir, "shelter.db"))
+	if err != nil {
+		t.Fatal("Error opening file database. Details:", err)
+	}
+
+	storage := dao.FileStorage{Database: database}
+	domainDAO := storage.DomainDAO()
+
+	email, err := mail.ParseAddress("admin@example.com.br")
+	if err != nil {
+		t.Fatal("Error parsing e-mail. Details:", err)
+	}
+
+	lastOKAt := time.Now().Add(-time.Hour)
+	existingDomain := model.Domain{
+		FQDN: "example.com.br.",
+		Nameservers: []model.Nameserver{
+			{
+				Host:       "ns1.example.com.br.",
+				LastStatus: model.NameserverStatusOK,
+				LastOKAt:   lastOKAt,
+			},
+		},
+		Owners: []model.Owner{
+			{Email: email, Language: "pt-BR"},
+		},
+	}
+
+	if err := domainDAO.Save(&existingDomain); err != nil {
+		t.Fatal("Error saving domain. Details:", err)
+	}
+
+	unchangedDomain := model.Domain{
+		FQDN: "example.net.br.",
+		Nameservers: []model.Nameserver{
+			{Host: "ns1.example.net.br."},
+		},
+	}
+
+	if err := domainDAO.Save(&unchangedDomain); err != nil {
+		t.Fatal("Error saving domain. Details:", err)
+	}
+
+	// Revision of a removed domain that is created again by the import
+	removedRevision := model.NewDomainRevision(model.Domain{FQDN: "example.org.br.", Revision: 5})
+	if err := storage.DomainRevisionDAO().Save(&removedRevision); err != nil {
+		t.Fatal("Error saving domain revision. Details:", err)
+	}
+
+	changes := make(map[string]*model.Domain)
+	changed := func(before, after *model.Domain) {
+		changes[after.FQDN] = before
+	}
+
+	result := Import(storage, []model.Domain{
+		{
+			FQDN: "example.com.br.",
+			Nameservers: []model.Nameserver{
+				{Host: "ns1.example.com.br.", IPv4: net.ParseIP("192.168.0.1")},
+				{Host: "ns2.example.com.br."},
+			},
+		},
+		{
+			FQDN: "example.net.br.",
+			Nameservers: []model.Nameserver{
+				{Host: "ns1.example.net.br."},
+			},
+		},
+		{
+			FQDN: "example.org.br.",
+			Nameservers: []model.Nameserver{
+				{Host: "ns1.example.org.br."},
+			},
+		},
+	}, changed)
+
+	if result.Created != 1 || result.Updated != 1 || result.Unchanged != 1 || result.Failed != 0 {
+		t.Errorf("Unexpected import result %+v", result)
+	}
+
+	if before, ok := changes["example.com.br."]; len(changes) != 2 || !ok || before == nil ||
+		len(before.Nameservers) != 1 {
+
+		t.Errorf("Not informing the previous state of the updated domain: %#v", changes)
+	}
+
+	if before, ok := changes["example.org.br."]; !ok || before != nil {
+		t.Errorf("Not informing the created domain without previous state: %#v", changes)
+	}
+
+	domain, err := domainDAO.FindByFQDN("example.com.br.")
+	if err != nil {
+		t.Fatal("Error retrieving domain. Details:", err)
+	}
+
+	if len(domain.Owners) != 1 || domain.Owners[0].Email.Address != "admin@example.com.br" {
+		t.Error("Not keeping the owners of an existing domain")
+	}
+
+	if len(domain.Nameservers) != 2 ||
+		domain.Nameservers[0].IPv4.String() != "192.168.0.1" ||
+		domain.Nameservers[0].LastStatus != model.NameserverStatusOK ||
+		!domain.Nameservers[0].LastOKAt.Equal(lastOKAt) {
+
+		t.Error("Not keeping the state of the nameservers that didn't change")
+	}
+
+	domain, err = domainDAO.FindByFQDN("example.net.br.")
+	if err != nil {
+		t.Fatal("Error retrieving domain. Details:", err)
+	}
+
+	if domain.Revision != unchangedDomain.Revision {
+		t.Error("Saving domains with the same delegation")
+	}
+
+	domain, err = domainDAO.FindByFQDN("example.org.br.")
+	if err != nil {
+		t.Fatal("Error retrieving domain. Details:", err)
+	}
+
+	if domain.Revision != 6 {
+		t.Errorf("Not continuing the revisions of a removed domain, got revision %d",
+			domain.Revision)
+	}
+}
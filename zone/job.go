@@ -0,0 +1,154 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package zone loads the delegations of a master zone file into the system
+package zone
+
+import (
+	"errors"
+	"github.com/rafaeljusto/shelter/dao"
+	"github.com/rafaeljusto/shelter/database"
+	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
+	"io"
+	"sync"
+	"time"
+)
+
+// List of possible job status. The job status is only stored in memory, so after a
+// restart the last import job is lost
+const (
+	JobStatusIdle     JobStatus = iota // No import job was executed since the system started
+	JobStatusRunning                   // Import job is parsing or saving the domains
+	JobStatusExecuted                  // Import job finished without errors
+	JobStatusFailed                    // Import job couldn't parse the zone or open the database
+)
+
+// List of possible errors that can occur when calling functions from this file. Other
+// erros can also occurs from low level layers
+var (
+	// Error returned when trying to start an import while another one is still running
+	ErrJobRunning = errors.New("There's already a zone import running")
+)
+
+var (
+	shelterImportJob     Job        // Store all data of the last import job
+	shelterImportJobLock sync.Mutex // Make the import job thread safe
+)
+
+// JobStatus stores the state of the import job
+type JobStatus int
+
+// Convert the job status enum to text for printing in reports or debugging
+func JobStatusToString(status JobStatus) string {
+	switch status {
+	case JobStatusIdle:
+		return "IDLE"
+	case JobStatusRunning:
+		return "RUNNING"
+	case JobStatusExecuted:
+		return "EXECUTED"
+	case JobStatusFailed:
+		return "FAILED"
+	}
+
+	return ""
+}
+
+// Job stores the progress of an asynchronous zone import. Importing a whole TLD zone can
+// take longer than a HTTP request, so the REST server starts a job and the user checks
+// the result later
+type Job struct {
+	ImportResult           // Number of domains affected by the import
+	Status       JobStatus // Current state of the job
+	Origin       string    // Zone origin informed by the user
+	StartedAt    time.Time // Time that the job was started
+	FinishedAt   time.Time // Time that the job finished
+	Error        string    // Reason of the failure when the job status is failed
+}
+
+// ChangeRecorder stores a domain changed by the import job (e.g. in the audit log), using
+// the database connection of the job. The domain before the import is nil for new domains
+type ChangeRecorder func(storage dao.Storage, before, after *model.Domain)
+
+// StartJob imports the zone file in background. The zone content must be completely
+// readable after the function returns, because it is only read in the job goroutine. Only
+// one import can run at a time, so an error is returned if there's a job running. The
+// new domains are created in the name of the given principal, and each saved domain is
+// sent to the recorder (optional)
+func StartJob(r io.Reader, origin, createdBy string, recorder ChangeRecorder) error {
+	shelterImportJobLock.Lock()
+	defer shelterImportJobLock.Unlock()
+
+	if shelterImportJob.Status == JobStatusRunning {
+		return ErrJobRunning
+	}
+
+	shelterImportJob = Job{
+		Status:    JobStatusRunning,
+		Origin:    origin,
+		StartedAt: time.Now(),
+	}
+
+	go func() {
+		result, err := runJob(r, origin, createdBy, recorder)
+
+		shelterImportJobLock.Lock()
+		defer shelterImportJobLock.Unlock()
+
+		shelterImportJob.ImportResult = result
+		shelterImportJob.FinishedAt = time.Now()
+
+		if err == nil {
+			shelterImportJob.Status = JobStatusExecuted
+
+		} else {
+			log.Println("Error while importing zone file. Details:", err)
+			shelterImportJob.Status = JobStatusFailed
+			shelterImportJob.Error = err.Error()
+		}
+	}()
+
+	return nil
+}
+
+// GetJob returns a copy of the last import job
+func GetJob() Job {
+	shelterImportJobLock.Lock()
+	defer shelterImportJobLock.Unlock()
+
+	return shelterImportJob
+}
+
+// runJob opens its own database connection, because the job continues after the end of
+// the HTTP request that started it. The zone file comes from the REST server, so it can't
+// use directives that read files from the server
+func runJob(r io.Reader, origin, createdBy string, recorder ChangeRecorder) (ImportResult, error) {
+	domains, err := ParseUploaded(r, origin)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	for i := range domains {
+		domains[i].CreatedBy = createdBy
+	}
+
+	storage, _, databaseSession, err := database.Open()
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	if databaseSession != nil {
+		defer databaseSession.Close()
+	}
+
+	var changed func(before, after *model.Domain)
+	if recorder != nil {
+		changed = func(before, after *model.Domain) {
+			recorder(storage, before, after)
+		}
+	}
+
+	return Import(storage, domains, changed), nil
+}
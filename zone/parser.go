@@ -0,0 +1,202 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package zone loads the delegations of a master zone file into the system
+package zone
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/rafaeljusto/shelter/Godeps/_workspace/src/github.com/miekg/dns"
+	"github.com/rafaeljusto/shelter/log"
+	"github.com/rafaeljusto/shelter/model"
+	"io"
+	"io/ioutil"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// forbiddenDirectives are the zone file directives that can't be used in a zone file
+	// sent by an user. $INCLUDE reads files from the server and $GENERATE can create a
+	// huge number of records
+	forbiddenDirectives = []string{"$INCLUDE", "$GENERATE"}
+
+	// parseErrorLine retrieves the line number from the error message of the zone parser,
+	// because the parser doesn't export it
+	parseErrorLine = regexp.MustCompile(`at line: ([0-9]+)`)
+)
+
+// ParseError is returned when a zone file sent by an user can't be parsed. Only the line
+// of the problem is informed, so that the error doesn't expose any content read by the
+// parser
+type ParseError struct {
+	Line      int    // Line of the problem in the zone file, zero when unknown
+	Directive string // Forbidden directive found in the line, if that's the problem
+}
+
+func (e ParseError) Error() string {
+	if len(e.Directive) > 0 {
+		return fmt.Sprintf("Zone file directive %s is not allowed at line %d", e.Directive, e.Line)
+
+	} else if e.Line > 0 {
+		return fmt.Sprintf("Zone file has an invalid content at line %d", e.Line)
+	}
+
+	return "Zone file has an invalid content"
+}
+
+// ParseUploaded reads a master zone file sent by an user, that can't be trusted. Before
+// parsing, the directives that read other files or generate records ($INCLUDE and
+// $GENERATE) are rejected, and any parser error is replaced by a ParseError
+func ParseUploaded(r io.Reader, origin string) ([]model.Domain, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkDirectives(content); err != nil {
+		return nil, err
+	}
+
+	domains, err := Parse(bytes.NewReader(content), origin, "")
+	if err != nil {
+		parseErr := ParseError{}
+		if match := parseErrorLine.FindStringSubmatch(err.Error()); match != nil {
+			parseErr.Line, _ = strconv.Atoi(match[1])
+		}
+
+		return nil, parseErr
+	}
+
+	return domains, nil
+}
+
+// Look for forbidden directives in the zone file. The parser only recognizes a directive
+// at the beginning of a line and discards carriage returns, so we do the same here.
+// Lines starting with blanks are also checked, to be on the safe side
+func checkDirectives(content []byte) error {
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.Replace(line, "\r", "", -1)
+		line = strings.ToUpper(strings.TrimSpace(line))
+
+		for _, directive := range forbiddenDirectives {
+			if strings.HasPrefix(line, directive) {
+				return ParseError{
+					Line:      i + 1,
+					Directive: directive,
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Parse reads a master zone file (RFC 1035) and builds a domain object for each
+// delegation found in it. The NS records are grouped by the delegated name, and the glue
+// records (A/AAAA) and DS records of the delegation are added to the domain. The names
+// that have a SOA record are the zone apex and aren't delegations, so they are ignored.
+// The origin is used for relative names when the zone file doesn't have the $ORIGIN
+// directive, and the filename is only used in error messages
+func Parse(r io.Reader, origin, filename string) ([]model.Domain, error) {
+	var fqdns []string
+	apex := make(map[string]bool)
+	nameservers := make(map[string][]string)
+	dsSets := make(map[string][]model.DS)
+	ipv4s := make(map[string]net.IP)
+	ipv6s := make(map[string]net.IP)
+
+	var parseErr error
+	for token := range dns.ParseZone(r, origin, filename) {
+		// We need to read all tokens even after an error, because the parser is running in
+		// another goroutine and could be blocked writing in the channel
+		if parseErr != nil {
+			continue
+		}
+
+		if token.Error != nil {
+			parseErr = token.Error
+			continue
+		}
+
+		name, err := model.NormalizeDomainName(token.RR.Header().Name)
+		if err != nil {
+			parseErr = err
+			continue
+		}
+
+		switch rr := token.RR.(type) {
+		case *dns.SOA:
+			apex[name] = true
+
+		case *dns.NS:
+			host, err := model.NormalizeDomainName(rr.Ns)
+			if err != nil {
+				parseErr = err
+				continue
+			}
+
+			if _, ok := nameservers[name]; !ok {
+				fqdns = append(fqdns, name)
+			}
+			nameservers[name] = append(nameservers[name], host)
+
+		case *dns.A:
+			// Only one address of each family is stored for a nameserver
+			if _, ok := ipv4s[name]; !ok {
+				ipv4s[name] = rr.A
+			}
+
+		case *dns.AAAA:
+			if _, ok := ipv6s[name]; !ok {
+				ipv6s[name] = rr.AAAA
+			}
+
+		case *dns.DS:
+			if !model.IsValidDSAlgorithm(rr.Algorithm) || !model.IsValidDSDigestType(rr.DigestType) {
+				log.Printf("Ignoring DS record of %s with unsupported algorithm %d or digest type %d",
+					name, rr.Algorithm, rr.DigestType)
+				continue
+			}
+
+			dsSets[name] = append(dsSets[name], model.DS{
+				Keytag:     rr.KeyTag,
+				Algorithm:  model.DSAlgorithm(rr.Algorithm),
+				Digest:     model.NormalizeDSDigest(rr.Digest),
+				DigestType: model.DSDigestType(rr.DigestType),
+			})
+		}
+	}
+
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	var domains []model.Domain
+	for _, fqdn := range fqdns {
+		if apex[fqdn] {
+			continue
+		}
+
+		domain := model.Domain{
+			FQDN:  fqdn,
+			DSSet: dsSets[fqdn],
+		}
+
+		for _, host := range nameservers[fqdn] {
+			domain.Nameservers = append(domain.Nameservers, model.Nameserver{
+				Host: host,
+				IPv4: ipv4s[host],
+				IPv6: ipv6s[host],
+			})
+		}
+
+		domains = append(domains, domain)
+	}
+
+	return domains, nil
+}
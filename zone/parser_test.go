@@ -0,0 +1,130 @@
+// Copyright 2014 Rafael Dantas Justo. All rights reserved.
+// Use of this source code is governed by a GPL
+// license that can be found in the LICENSE file.
+
+// Package zone loads the delegations of a master zone file into the system
+package zone
+
+import (
+	"github.com/rafaeljusto/shelter/model"
+	"strings"
+	"testing"
+)
+
+const (
+	zoneContent = `$ORIGIN br.
+$TTL 86400
+@             IN SOA a.dns.br. hostmaster.registro.br. 2014010101 1800 900 604800 86400
+@             IN NS  a.dns.br.
+a.dns.br.     IN A   200.160.0.10
+
+example.com   IN NS  ns1.example.com
+example.com   IN NS  ns2.example.net.
+ns1.example.com IN A 192.168.0.1
+ns1.example.com IN AAAA ::1
+example.com   IN DS  41674 5 1 B2C6DC2B6F5D4A0D4F4E54F3D5E5A2E6F0B6C1C6
+example.com   IN DS  41675 200 1 B2C6DC2B6F5D4A0D4F4E54F3D5E5A2E6F0B6C1C6
+
+EXAMPLE.net   IN NS  ns1.example.com.br.
+`
+)
+
+func TestParse(t *testing.T) {
+	domains, err := Parse(strings.NewReader(zoneContent), "", "")
+	if err != nil {
+		t.Fatal("Error parsing zone file. Details:", err)
+	}
+
+	if len(domains) != 2 {
+		t.Fatalf("Expected 2 delegations and got %d", len(domains))
+	}
+
+	domain := domains[0]
+	if domain.FQDN != "example.com.br." {
+		t.Errorf("Unexpected delegation %s", domain.FQDN)
+	}
+
+	if len(domain.Nameservers) != 2 ||
+		domain.Nameservers[0].Host != "ns1.example.com.br." ||
+		domain.Nameservers[0].IPv4.String() != "192.168.0.1" ||
+		domain.Nameservers[0].IPv6.String() != "::1" ||
+		domain.Nameservers[1].Host != "ns2.example.net." ||
+		domain.Nameservers[1].IPv4 != nil {
+
+		t.Error("Not grouping the nameservers and glues of the delegation")
+	}
+
+	if len(domain.DSSet) != 1 ||
+		domain.DSSet[0].Keytag != 41674 ||
+		domain.DSSet[0].Algorithm != model.DSAlgorithmRSASHA1 ||
+		domain.DSSet[0].DigestType != model.DSDigestTypeSHA1 ||
+		domain.DSSet[0].Digest != "b2c6dc2b6f5d4a0d4f4e54f3d5e5a2e6f0b6c1c6" {
+
+		t.Error("Not storing the DS records of the delegation")
+	}
+
+	if domains[1].FQDN != "example.net.br." {
+		t.Error("Not normalizing the delegated names")
+	}
+
+	if _, err := Parse(strings.NewReader("example IN A 300.0.0.1\n"), "br.", ""); err == nil {
+		t.Error("Not detecting invalid zone files")
+	}
+}
+
+func TestParseUploaded(t *testing.T) {
+	domains, err := ParseUploaded(strings.NewReader(zoneContent), "")
+	if err != nil {
+		t.Fatal("Error parsing uploaded zone file. Details:", err)
+	}
+
+	if len(domains) != 2 {
+		t.Errorf("Expected 2 delegations and got %d", len(domains))
+	}
+
+	data := []struct {
+		Content           string
+		ExpectedLine      int
+		ExpectedDirective string
+	}{
+		{
+			Content:           "$ORIGIN br.\n$include /etc/passwd\n",
+			ExpectedLine:      2,
+			ExpectedDirective: "$INCLUDE",
+		},
+		{
+			Content:           "$ORIGIN br.\r\n\r\n$INC\rLUDE /etc/passwd example.com.br.\r\n",
+			ExpectedLine:      3,
+			ExpectedDirective: "$INCLUDE",
+		},
+		{
+			Content:           "$GENERATE 1-1000000 example$ NS ns1.example.com.br.\n",
+			ExpectedLine:      1,
+			ExpectedDirective: "$GENERATE",
+		},
+		{
+			Content:      "$ORIGIN br.\nexample IN A 300.0.0.1\n",
+			ExpectedLine: 2,
+		},
+	}
+
+	for i, item := range data {
+		_, err := ParseUploaded(strings.NewReader(item.Content), "br.")
+
+		parseErr, ok := err.(ParseError)
+		if !ok {
+			t.Errorf("Item %d: Not returning a parse error. Got %v", i, err)
+			continue
+		}
+
+		if parseErr.Line != item.ExpectedLine || parseErr.Directive != item.ExpectedDirective {
+			t.Errorf("Item %d: Expected error at line %d with directive '%s' and got line %d "+
+				"with directive '%s'", i, item.ExpectedLine, item.ExpectedDirective,
+				parseErr.Line, parseErr.Directive)
+		}
+
+		if strings.Contains(parseErr.Error(), "300.0.0.1") || strings.Contains(parseErr.Error(), "passwd") {
+			t.Errorf("Item %d: Exposing the zone file content in the error: %s", i, parseErr.Error())
+		}
+	}
+}